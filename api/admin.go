@@ -0,0 +1,667 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/worsediscord/server/services/audit"
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
+)
+
+type AdminUserResponse struct {
+	// The globally unique username of the user.
+	Username string `json:"username"`
+
+	// The nickname of the user.
+	Nickname string `json:"nickname"`
+
+	// Whether the user is a server administrator.
+	Admin bool `json:"admin"`
+
+	// Whether the user is disabled and can no longer log in.
+	Disabled bool `json:"disabled"`
+}
+
+type AdminUserUpdateRequest struct {
+	// Grants or revokes the server administrator role. Omit to leave unchanged.
+	Admin *bool `json:"admin,omitempty"`
+
+	// Disables or enables the user. Disabling a user revokes all of their sessions. Omit to leave unchanged.
+	Disabled *bool `json:"disabled,omitempty"`
+}
+
+type AdminPasswordResetRequest struct {
+	// The new password. Must be at least 8 characters long.
+	Password string `json:"password" validate:"required" minLength:"8"`
+}
+
+type AdminRoomResponse struct {
+	Id     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Users  []string `json:"users"`
+	Admins []string `json:"admins"`
+
+	// The room type. Empty for named rooms and "direct" for direct message rooms.
+	Type string `json:"type,omitempty"`
+
+	// "private" for rooms that can only be joined with an invite. Empty otherwise.
+	Visibility string `json:"visibility,omitempty"`
+}
+
+type AdminRoomPromoteRequest struct {
+	// The username to grant room admin. They are added to the room if they aren't a member.
+	Username string `json:"username" validate:"required" minLength:"1"`
+}
+
+type SessionResponse struct {
+	// An identifier for the session. This is not the session token.
+	Id string `json:"id"`
+
+	// The username the session belongs to.
+	UserId string `json:"user_id"`
+
+	// Time since epoch in milliseconds.
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+type StatsResponse struct {
+	Users    int `json:"users"`
+	Rooms    int `json:"rooms"`
+	Messages int `json:"messages"`
+	Sessions int `json:"sessions"`
+
+	// Seconds since the server started.
+	Uptime int64 `json:"uptime"`
+}
+
+// handleAdminUserList lists users along with their administrative state
+//
+//	@Summary	List users (admin)
+//	@Tags		admin
+//	@Produce	json
+//	@Security	ApiKey
+//	@Success	200	{array}	AdminUserResponse
+//	@Failure	401
+//	@Failure	403
+//	@Failure	500
+//	@Router		/admin/users [get]
+func (s *Server) handleAdminUserList() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "AdminUserList"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		users, err := s.UserService.List(r.Context())
+		if err != nil {
+			logger.Error("failed to list users", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]AdminUserResponse, 0, len(users))
+		for _, u := range users {
+			response = append(response, AdminUserResponse{
+				Username: u.Username,
+				Nickname: u.Nickname,
+				Admin:    u.Admin,
+				Disabled: u.Disabled,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// handleAdminUserUpdate changes a user's administrative state
+//
+//	@Summary	Update a user (admin)
+//	@Tags		admin
+//	@Accept		json
+//	@Param		id		path	string					true	"id to update"
+//	@Param		update	body	AdminUserUpdateRequest	true	"fields to update"
+//	@Security	ApiKey
+//	@Success	200
+//	@Failure	400	{object}	Error
+//	@Failure	401
+//	@Failure	403
+//	@Failure	404
+//	@Failure	500
+//	@Router		/admin/users/{id} [patch]
+func (s *Server) handleAdminUserUpdate() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "AdminUserUpdate"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var request AdminUserUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		opts := user.UpdateUserOpts{Id: id, Admin: request.Admin, Disabled: request.Disabled}
+		if err := s.UserService.Update(r.Context(), opts); err != nil {
+			switch {
+			case errors.Is(err, user.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				logger.Error("failed to update user", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if request.Admin != nil {
+			action := audit.ActionUserDemote
+			if *request.Admin {
+				action = audit.ActionUserPromote
+			}
+
+			s.audit(r, action, id)
+		}
+
+		if request.Disabled != nil {
+			action := audit.ActionUserEnable
+			if *request.Disabled {
+				action = audit.ActionUserDisable
+			}
+
+			s.audit(r, action, id)
+
+			// The user is already locked out, since SessionAuthMiddleware rejects disabled users, so a failure here only
+			// leaves sessions behind that can't be used. It's still reported so that the request can be retried.
+			if *request.Disabled {
+				if err := s.revokeUserSessions(id); err != nil {
+					logger.Error("failed to revoke sessions", slog.String("error", err.Error()))
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+	}
+}
+
+// handleAdminUserDelete deletes any user
+//
+//	@Summary	Delete a user (admin)
+//	@Tags		admin
+//	@Param		id	path	string	true	"id to delete"
+//	@Security	ApiKey
+//	@Success	200
+//	@Failure	401
+//	@Failure	403
+//	@Failure	404
+//	@Failure	500
+//	@Router		/admin/users/{id} [delete]
+func (s *Server) handleAdminUserDelete() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "AdminUserDelete"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		if _, err := s.UserService.GetUserById(r.Context(), user.GetUserByIdOpts{Id: id}); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err := s.deleter().DeleteUser(r.Context(), user.DeleteUserOpts{Id: id}); err != nil {
+			logger.Error("failed to delete user", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := s.revokeUserSessions(id); err != nil {
+			logger.Error("failed to revoke sessions", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.audit(r, audit.ActionUserDelete, id)
+	}
+}
+
+// handleAdminUserPasswordReset sets a new password for any user
+//
+//	@Summary	Reset a user's password (admin)
+//	@Tags		admin
+//	@Accept		json
+//	@Param		id			path	string						true	"id to update"
+//	@Param		password	body	AdminPasswordResetRequest	true	"new password"
+//	@Security	ApiKey
+//	@Success	200
+//	@Failure	400	{object}	Error
+//	@Failure	401
+//	@Failure	403
+//	@Failure	404
+//	@Failure	500
+//	@Router		/admin/users/{id}/password [post]
+func (s *Server) handleAdminUserPasswordReset() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "AdminUserPasswordReset"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var request AdminPasswordResetRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := s.UserService.Update(r.Context(), user.UpdateUserOpts{Id: id, Password: &request.Password}); err != nil {
+			switch {
+			case errors.Is(err, user.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, user.ErrInvalidPassword):
+				w.WriteHeader(http.StatusBadRequest)
+			default:
+				logger.Error("failed to update user", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if err := s.revokeUserSessions(id); err != nil {
+			logger.Error("failed to revoke sessions", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.audit(r, audit.ActionPasswordReset, id)
+	}
+}
+
+// handleAdminRoomList lists rooms along with their members
+//
+//	@Summary	List rooms (admin)
+//	@Tags		admin
+//	@Produce	json
+//	@Security	ApiKey
+//	@Success	200	{array}	AdminRoomResponse
+//	@Failure	401
+//	@Failure	403
+//	@Failure	500
+//	@Router		/admin/rooms [get]
+func (s *Server) handleAdminRoomList() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "AdminRoomList"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		rooms, err := s.RoomService.List(r.Context())
+		if err != nil {
+			logger.Error("failed to list rooms", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]AdminRoomResponse, 0, len(rooms))
+		for _, rm := range rooms {
+			response = append(response, AdminRoomResponse{Id: rm.Id, Name: rm.Name, Users: rm.Users, Admins: rm.Admins, Type: string(rm.Type), Visibility: string(rm.Visibility)})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// handleAdminRoomPromote grants a user admin of any room
+//
+//	@Summary	Add a room admin (admin)
+//	@Tags		admin
+//	@Accept		json
+//	@Param		id		path	int						true	"room id"
+//	@Param		user	body	AdminRoomPromoteRequest	true	"user to promote"
+//	@Security	ApiKey
+//	@Success	200
+//	@Failure	400	{object}	Error
+//	@Failure	401
+//	@Failure	403
+//	@Failure	404
+//	@Failure	500
+//	@Router		/admin/rooms/{id}/admins [post]
+func (s *Server) handleAdminRoomPromote() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "AdminRoomPromote"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var request AdminRoomPromoteRequest
+		if err = json.NewDecoder(r.Body).Decode(&request); err != nil || request.Username == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if _, err = s.UserService.GetUserById(r.Context(), user.GetUserByIdOpts{Id: request.Username}); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		userId, _ := r.Context().Value("userID").(string)
+
+		opts := room.PromoteRoomOpts{Id: id, UserId: userId, TargetId: request.Username, Now: s.Clock.Now().UnixMilli(), Force: true}
+		if err = s.RoomService.Promote(r.Context(), opts); err != nil {
+			switch {
+			case errors.Is(err, room.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, room.ErrDirect), errors.Is(err, room.ErrNotMember):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, room.ErrBanned):
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				logger.Error("failed to promote user", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		s.audit(r, audit.ActionRoomPromote, r.PathValue("id")+"/"+request.Username)
+	}
+}
+
+// handleAdminRoomTransfer makes a user the owner of any room
+//
+//	@Summary		Transfer room ownership (admin)
+//	@Description	Makes a member of any room its owner, such as when its owner deleted their account.
+//	@Tags			admin
+//	@Accept			json
+//	@Param			id		path	int					true	"room id"
+//	@Param			user	body	RoomMemberRequest	true	"new owner"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		403
+//	@Failure		404
+//	@Failure		500
+//	@Router			/admin/rooms/{id}/owner [put]
+func (s *Server) handleAdminRoomTransfer() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "AdminRoomTransfer"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var request RoomMemberRequest
+		if err = json.NewDecoder(r.Body).Decode(&request); err != nil || request.Username == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if _, err = s.UserService.GetUserById(r.Context(), user.GetUserByIdOpts{Id: request.Username}); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		userId, _ := r.Context().Value("userID").(string)
+
+		opts := room.TransferRoomOpts{Id: id, UserId: userId, TargetId: request.Username, Now: s.Clock.Now().UnixMilli(), Force: true}
+		if err = s.RoomService.Transfer(r.Context(), opts); err != nil {
+			switch {
+			case errors.Is(err, room.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, room.ErrDirect), errors.Is(err, room.ErrNotMember):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, room.ErrBanned):
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				logger.Error("failed to transfer room", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		s.audit(r, audit.ActionRoomTransfer, r.PathValue("id")+"/"+request.Username)
+	}
+}
+
+// handleAdminRoomDelete deletes any room regardless of its admins
+//
+//	@Summary	Force delete a room (admin)
+//	@Tags		admin
+//	@Param		id	path	int	true	"id to delete"
+//	@Security	ApiKey
+//	@Success	200
+//	@Failure	400	{object}	Error
+//	@Failure	401
+//	@Failure	403
+//	@Failure	404
+//	@Failure	500
+//	@Router		/admin/rooms/{id} [delete]
+func (s *Server) handleAdminRoomDelete() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "AdminRoomDelete"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		userId, _ := r.Context().Value("userID").(string)
+
+		if err = s.deleter().DeleteRoom(r.Context(), room.DeleteRoomOpts{Id: id, UserId: userId, Force: true}); err != nil {
+			switch {
+			case errors.Is(err, room.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				logger.Error("failed to delete room", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		s.audit(r, audit.ActionRoomDelete, r.PathValue("id"))
+	}
+}
+
+// handleAdminSessionList lists all active sessions
+//
+//	@Summary	List sessions (admin)
+//	@Tags		admin
+//	@Produce	json
+//	@Security	ApiKey
+//	@Success	200	{array}	SessionResponse
+//	@Failure	401
+//	@Failure	403
+//	@Failure	500
+//	@Router		/admin/sessions [get]
+func (s *Server) handleAdminSessionList() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "AdminSessionList"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := s.AuthService.ListKeys()
+		if err != nil {
+			logger.Error("failed to list keys", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]SessionResponse, 0, len(keys))
+		for _, key := range keys {
+			userId, _ := key.Payload().(string)
+			response = append(response, SessionResponse{
+				Id:        key.Id(),
+				UserId:    userId,
+				ExpiresAt: key.ExpiresAt().UnixMilli(),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// handleAdminSessionRevoke revokes a session
+//
+//	@Summary	Revoke a session (admin)
+//	@Tags		admin
+//	@Param		id	path	string	true	"session id to revoke"
+//	@Security	ApiKey
+//	@Success	200
+//	@Failure	401
+//	@Failure	403
+//	@Failure	404
+//	@Failure	500
+//	@Router		/admin/sessions/{id} [delete]
+func (s *Server) handleAdminSessionRevoke() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "AdminSessionRevoke"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		keys, err := s.AuthService.ListKeys()
+		if err != nil {
+			logger.Error("failed to list keys", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		n := slices.IndexFunc(keys, func(key auth.ApiKey) bool { return key.Id() == id })
+		if n == -1 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err = s.AuthService.RevokeKey(keys[n].Token()); err != nil {
+			logger.Error("failed to revoke key", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.audit(r, audit.ActionSessionRevoke, id)
+	}
+}
+
+// handleAdminStats returns server-wide statistics
+//
+//	@Summary	Server statistics (admin)
+//	@Tags		admin
+//	@Produce	json
+//	@Security	ApiKey
+//	@Success	200	{object}	StatsResponse
+//	@Failure	401
+//	@Failure	403
+//	@Failure	500
+//	@Router		/admin/stats [get]
+func (s *Server) handleAdminStats() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "AdminStats"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		users, err := s.UserService.List(r.Context())
+		if err != nil {
+			logger.Error("failed to list users", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		rooms, err := s.RoomService.List(r.Context())
+		if err != nil {
+			logger.Error("failed to list rooms", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := s.MessageService.List(r.Context(), message.ListMessageOpts{})
+		if err != nil {
+			logger.Error("failed to list messages", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		keys, err := s.AuthService.ListKeys()
+		if err != nil {
+			logger.Error("failed to list keys", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := StatsResponse{
+			Users:    len(users),
+			Rooms:    len(rooms),
+			Messages: len(messages),
+			Sessions: len(keys),
+			Uptime:   int64(time.Since(s.startTime).Seconds()),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// BootstrapAdmins grants the server administrator role to usernames, which must already be registered. Nobody is
+// promoted if any of them is missing, since whoever registered a missing name first would otherwise become an admin.
+func (s *Server) BootstrapAdmins(ctx context.Context, usernames ...string) error {
+	for _, username := range usernames {
+		if _, err := s.UserService.GetUserById(ctx, user.GetUserByIdOpts{Id: username}); err != nil {
+			return fmt.Errorf("failed to bootstrap admin %s: %w", username, err)
+		}
+	}
+
+	admin := true
+	for _, username := range usernames {
+		if err := s.UserService.Update(ctx, user.UpdateUserOpts{Id: username, Admin: &admin}); err != nil {
+			return fmt.Errorf("failed to bootstrap admin %s: %w", username, err)
+		}
+
+		s.record(ctx, audit.AppendOpts{Actor: "system", Action: audit.ActionUserPromote, Target: username})
+	}
+
+	return nil
+}
+
+// revokeUserSessions revokes every key belonging to userId.
+func (s *Server) revokeUserSessions(userId string) error {
+	keys, err := s.AuthService.ListKeys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if key.Payload() == userId {
+			if err = s.AuthService.RevokeKey(key.Token()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
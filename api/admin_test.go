@@ -0,0 +1,530 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/audit"
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/fake"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/util"
+)
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		userId         any
+		userService    user.Service
+		expectedStatus int
+	}{
+		"admin": {
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "spiderman", Admin: true}},
+			expectedStatus: http.StatusOK,
+		},
+		"not admin": {
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "spiderman"}},
+			expectedStatus: http.StatusForbidden,
+		},
+		"disabled admin": {
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "spiderman", Admin: true, Disabled: true}},
+			expectedStatus: http.StatusForbidden,
+		},
+		"not found": {
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdError: user.ErrNotFound},
+			expectedStatus: http.StatusForbidden,
+		},
+		"no session": {
+			userService:    &fake.UserService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := AdminAuthMiddleware(util.NopLogHandler, input.userService)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			request := httptest.NewRequest(http.MethodGet, "/api/admin/stats", nil)
+			if input.userId != nil {
+				request = request.WithContext(context.WithValue(request.Context(), "userID", input.userId))
+			}
+
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, request)
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestServer_HandleAdminUserList(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	s.UserService = &fake.UserService{ExpectedListUsers: []*user.User{
+		{Username: "spiderman", Nickname: "spidey", Password: "uncleben123", Admin: true},
+		{Username: "venom", Nickname: "venom", Password: "wearevenom", Disabled: true},
+	}}
+
+	recorder := httptest.NewRecorder()
+	s.handleAdminUserList()(recorder, httptest.NewRequest(http.MethodGet, "/api/admin/users", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d, expected %d", recorder.Code, http.StatusOK)
+	}
+
+	var response []AdminUserResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedResponse := []AdminUserResponse{
+		{Username: "spiderman", Nickname: "spidey", Admin: true},
+		{Username: "venom", Nickname: "venom", Disabled: true},
+	}
+
+	if !reflect.DeepEqual(response, expectedResponse) {
+		t.Fatalf("got users %v, expected %v", response, expectedResponse)
+	}
+}
+
+func TestServer_HandleAdminUserUpdate(t *testing.T) {
+	disabled := true
+	session := auth.NewApiKeyExpiringAt(24, time.Now().Add(time.Hour), "venom")
+
+	tests := map[string]struct {
+		request         *http.Request
+		userService     user.Service
+		authService     *fake.AuthService
+		expectedStatus  int
+		expectedActions []string
+	}{
+		"valid": {
+			request:         httptest.NewRequest(http.MethodPatch, "/api/admin/users/venom", util.StructToReaderOrDie(AdminUserUpdateRequest{Disabled: &disabled})),
+			userService:     &fake.UserService{},
+			authService:     &fake.AuthService{ExpectedListKeysApiKeys: []auth.ApiKey{session}},
+			expectedStatus:  http.StatusOK,
+			expectedActions: []string{audit.ActionUserDisable},
+		},
+		// The user is disabled, and recorded as such, even though their sessions are left behind.
+		"revoke error": {
+			request:         httptest.NewRequest(http.MethodPatch, "/api/admin/users/venom", util.StructToReaderOrDie(AdminUserUpdateRequest{Disabled: &disabled})),
+			userService:     &fake.UserService{},
+			authService:     &fake.AuthService{ExpectedListKeysApiKeys: []auth.ApiKey{session}, ExpectedRevokeKeyError: errors.New("oops")},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedActions: []string{audit.ActionUserDisable},
+		},
+		"not found": {
+			request:         httptest.NewRequest(http.MethodPatch, "/api/admin/users/venom", util.StructToReaderOrDie(AdminUserUpdateRequest{Disabled: &disabled})),
+			userService:     &fake.UserService{ExpectedUpdateError: user.ErrNotFound},
+			authService:     &fake.AuthService{},
+			expectedStatus:  http.StatusNotFound,
+			expectedActions: []string{},
+		},
+		"invalid body": {
+			request:         httptest.NewRequest(http.MethodPatch, "/api/admin/users/venom", nil),
+			userService:     &fake.UserService{},
+			authService:     &fake.AuthService{},
+			expectedStatus:  http.StatusBadRequest,
+			expectedActions: []string{},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := NewServer(input.userService, nil, nil, input.authService, util.NopLogHandler)
+
+			recorder := httptest.NewRecorder()
+			input.request.SetPathValue("id", "venom")
+			s.handleAdminUserUpdate()(recorder, input.request)
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			entries, err := s.AuditService.List(context.Background(), audit.ListEntryOpts{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actions := make([]string, 0, len(entries))
+			for _, e := range entries {
+				actions = append(actions, e.Action)
+			}
+
+			if !reflect.DeepEqual(actions, input.expectedActions) {
+				t.Fatalf("got audited actions %v, expected %v", actions, input.expectedActions)
+			}
+		})
+	}
+}
+
+func TestServer_HandleAdminUserPasswordReset(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+
+	tests := map[string]struct {
+		request        *http.Request
+		userService    user.Service
+		expectedStatus int
+	}{
+		"valid": {
+			request:        httptest.NewRequest(http.MethodPost, "/api/admin/users/venom/password", util.StructToReaderOrDie(AdminPasswordResetRequest{Password: "wearevenom"})),
+			userService:    &fake.UserService{},
+			expectedStatus: http.StatusOK,
+		},
+		"invalid password": {
+			request:        httptest.NewRequest(http.MethodPost, "/api/admin/users/venom/password", util.StructToReaderOrDie(AdminPasswordResetRequest{Password: "venom"})),
+			userService:    &fake.UserService{ExpectedUpdateError: user.ErrInvalidPassword},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"not found": {
+			request:        httptest.NewRequest(http.MethodPost, "/api/admin/users/venom/password", util.StructToReaderOrDie(AdminPasswordResetRequest{Password: "wearevenom"})),
+			userService:    &fake.UserService{ExpectedUpdateError: user.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.UserService = input.userService
+			s.AuthService = &fake.AuthService{}
+
+			recorder := httptest.NewRecorder()
+			input.request.SetPathValue("id", "venom")
+			s.handleAdminUserPasswordReset()(recorder, input.request)
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestServer_HandleAdminRoomList(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	roomService := &fake.RoomService{ExpectedListRooms: []*room.Room{
+		{Id: 1, Name: "the big apple", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman"}},
+	}}
+	s.RoomService = roomService
+
+	recorder := httptest.NewRecorder()
+	s.handleAdminRoomList()(recorder, httptest.NewRequest(http.MethodGet, "/api/admin/rooms", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d, expected %d", recorder.Code, http.StatusOK)
+	}
+
+	if roomService.ListCalls != 1 {
+		t.Fatalf("got %d calls, expected 1", roomService.ListCalls)
+	}
+
+	var response []AdminRoomResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedResponse := []AdminRoomResponse{
+		{Id: 1, Name: "the big apple", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman"}},
+	}
+
+	if !reflect.DeepEqual(response, expectedResponse) {
+		t.Fatalf("got rooms %v, expected %v", response, expectedResponse)
+	}
+}
+
+func TestServer_HandleAdminRoomPromote(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	now := time.UnixMilli(1_000_000)
+	s.Clock = util.ClockFunc(func() time.Time { return now })
+	validRequest := AdminRoomPromoteRequest{Username: "venom"}
+
+	tests := map[string]struct {
+		id             string
+		body           any
+		userService    *fake.UserService
+		roomService    *fake.RoomService
+		expectedStatus int
+		expectedCalls  []room.PromoteRoomOpts
+	}{
+		"valid": {
+			id:             "1",
+			body:           validRequest,
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.PromoteRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000, Force: true}},
+		},
+		"missing username": {
+			id:             "1",
+			body:           AdminRoomPromoteRequest{},
+			userService:    &fake.UserService{},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"unknown user": {
+			id:             "1",
+			body:           validRequest,
+			userService:    &fake.UserService{ExpectedGetUserByIdError: user.ErrNotFound},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"banned": {
+			id:             "1",
+			body:           validRequest,
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedPromoteError: room.ErrBanned},
+			expectedStatus: http.StatusForbidden,
+			expectedCalls:  []room.PromoteRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000, Force: true}},
+		},
+		"room not found": {
+			id:             "2",
+			body:           validRequest,
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedPromoteError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCalls:  []room.PromoteRoomOpts{{Id: 2, UserId: "spiderman", TargetId: "venom", Now: 1_000_000, Force: true}},
+		},
+		"invalid id": {
+			id:             "queens",
+			body:           validRequest,
+			userService:    &fake.UserService{},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"service error": {
+			id:             "1",
+			body:           validRequest,
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedPromoteError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []room.PromoteRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000, Force: true}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.UserService = input.userService
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/api/admin/rooms/"+input.id+"/admins", util.StructToReaderOrDie(input.body))
+			request.SetPathValue("id", input.id)
+			s.handleAdminRoomPromote()(recorder, withUserId(request, "spiderman"))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.PromoteCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.PromoteCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleAdminRoomTransfer(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	now := time.UnixMilli(1_000_000)
+	s.Clock = util.ClockFunc(func() time.Time { return now })
+	validRequest := RoomMemberRequest{Username: "venom"}
+
+	tests := map[string]struct {
+		id             string
+		body           any
+		userService    *fake.UserService
+		roomService    *fake.RoomService
+		expectedStatus int
+		expectedCalls  []room.TransferRoomOpts
+	}{
+		"valid": {
+			id:             "1",
+			body:           validRequest,
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.TransferRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000, Force: true}},
+		},
+		"unknown user": {
+			id:             "1",
+			body:           validRequest,
+			userService:    &fake.UserService{ExpectedGetUserByIdError: user.ErrNotFound},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"direct message": {
+			id:             "1",
+			body:           validRequest,
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedTransferError: room.ErrDirect},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []room.TransferRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000, Force: true}},
+		},
+		"banned": {
+			id:             "1",
+			body:           validRequest,
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedTransferError: room.ErrBanned},
+			expectedStatus: http.StatusForbidden,
+			expectedCalls:  []room.TransferRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000, Force: true}},
+		},
+		"room not found": {
+			id:             "2",
+			body:           validRequest,
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedTransferError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCalls:  []room.TransferRoomOpts{{Id: 2, UserId: "spiderman", TargetId: "venom", Now: 1_000_000, Force: true}},
+		},
+		"invalid id": {
+			id:             "queens",
+			body:           validRequest,
+			userService:    &fake.UserService{},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"service error": {
+			id:             "1",
+			body:           validRequest,
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedTransferError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []room.TransferRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000, Force: true}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.UserService = input.userService
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPut, "/api/admin/rooms/"+input.id+"/owner", util.StructToReaderOrDie(input.body))
+			request.SetPathValue("id", input.id)
+			s.handleAdminRoomTransfer()(recorder, withUserId(request, "spiderman"))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.TransferCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.TransferCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleAdminRoomDelete(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	apple := &room.Room{Id: 1, Name: "the big apple", Users: []string{"venom"}, Admins: []string{"venom"}}
+
+	tests := map[string]struct {
+		id                 string
+		roomService        *fake.RoomService
+		expectedStatus     int
+		expectedCalls      []room.DeleteRoomOpts
+		expectedPurgeCalls []message.PurgeMessageOpts
+	}{
+		"valid": {
+			id:                 "1",
+			roomService:        &fake.RoomService{ExpectedGetRoomByIdRoom: apple},
+			expectedStatus:     http.StatusOK,
+			expectedCalls:      []room.DeleteRoomOpts{{Id: 1, UserId: "spiderman", Force: true}},
+			expectedPurgeCalls: []message.PurgeMessageOpts{{RoomId: 1}},
+		},
+		"not found": {
+			id:             "2",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"invalid id": {
+			id:             "queens",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"service error": {
+			id:                 "1",
+			roomService:        &fake.RoomService{ExpectedGetRoomByIdRoom: apple, ExpectedDeleteError: errors.New("oops")},
+			expectedStatus:     http.StatusInternalServerError,
+			expectedCalls:      []room.DeleteRoomOpts{{Id: 1, UserId: "spiderman", Force: true}},
+			expectedPurgeCalls: []message.PurgeMessageOpts{{RoomId: 1}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			messageService := &fake.MessageService{}
+
+			s.RoomService = input.roomService
+			s.MessageService = messageService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodDelete, "/api/admin/rooms/"+input.id, nil)
+			request.SetPathValue("id", input.id)
+			s.handleAdminRoomDelete()(recorder, withUserId(request, "spiderman"))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.DeleteCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.DeleteCalls, input.expectedCalls)
+			}
+
+			if !reflect.DeepEqual(messageService.PurgeCalls, input.expectedPurgeCalls) {
+				t.Fatalf("got purge calls %v, expected %v", messageService.PurgeCalls, input.expectedPurgeCalls)
+			}
+		})
+	}
+}
+
+func TestServer_BootstrapAdmins(t *testing.T) {
+	tests := map[string]struct {
+		usernames      []string
+		expectedAdmins []string
+		expectedErr    error
+	}{
+		"registered": {usernames: []string{"spiderman", "mj"}, expectedAdmins: []string{"spiderman", "mj"}},
+		"none":       {usernames: nil, expectedAdmins: []string{}},
+		// Nobody is promoted, so a missing name can't be claimed by whoever registers it first.
+		"unregistered": {usernames: []string{"spiderman", "jjj"}, expectedAdmins: []string{}, expectedErr: user.ErrNotFound},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			users := user.NewMap()
+			for _, username := range []string{"spiderman", "mj"} {
+				if err := users.Create(context.Background(), user.CreateUserOpts{Username: username, Password: "password123"}); err != nil {
+					t.Fatalf("failed to prepopulate map: %v", err)
+				}
+			}
+
+			s := NewServer(users, nil, nil, nil, util.NopLogHandler)
+
+			if err := s.BootstrapAdmins(context.Background(), input.usernames...); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			admins := make([]string, 0)
+			for _, username := range []string{"spiderman", "mj"} {
+				if u, _ := users.GetUserById(context.Background(), user.GetUserByIdOpts{Id: username}); u.Admin {
+					admins = append(admins, username)
+				}
+			}
+
+			if !reflect.DeepEqual(admins, input.expectedAdmins) {
+				t.Fatalf("got admins %v, expected %v", admins, input.expectedAdmins)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/worsediscord/server/services/audit"
+)
+
+type AuditEntryResponse struct {
+	Sequence int64 `json:"sequence"`
+
+	// Time since epoch in milliseconds.
+	Timestamp int64 `json:"timestamp"`
+
+	// The username that performed the action, or "system".
+	Actor string `json:"actor"`
+
+	Action   string            `json:"action"`
+	Target   string            `json:"target,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// The hash of the previous entry in the chain.
+	PrevHash string `json:"prev_hash"`
+
+	Hash string `json:"hash"`
+}
+
+// handleAdminAuditList lists audit log entries
+//
+//	@Summary	List audit log entries (admin)
+//	@Tags		admin
+//	@Produce	json
+//	@Param		actor	query	string	false	"only entries performed by this username"
+//	@Param		action	query	string	false	"only entries with this action"
+//	@Param		since	query	string	false	"only entries at or after this time, in milliseconds since epoch or RFC 3339"
+//	@Security	ApiKey
+//	@Success	200	{array}		AuditEntryResponse
+//	@Failure	400	{object}	Error
+//	@Failure	401
+//	@Failure	403
+//	@Failure	500
+//	@Router		/admin/audit [get]
+func (s *Server) handleAdminAuditList() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "AdminAuditList"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		opts := audit.ListEntryOpts{Actor: query.Get("actor"), Action: query.Get("action")}
+
+		if since := query.Get("since"); since != "" {
+			t, err := parseTimestamp(since)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			opts.Since = t
+		}
+
+		entries, err := s.AuditService.List(r.Context(), opts)
+		if err != nil {
+			logger.Error("failed to list audit entries", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]AuditEntryResponse, 0, len(entries))
+		for _, e := range entries {
+			response = append(response, AuditEntryResponse{
+				Sequence:  e.Sequence,
+				Timestamp: e.Timestamp,
+				Actor:     e.Actor,
+				Action:    e.Action,
+				Target:    e.Target,
+				Metadata:  e.Metadata,
+				PrevHash:  e.PrevHash,
+				Hash:      e.Hash,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// audit records an action performed by the requesting user against target.
+func (s *Server) audit(r *http.Request, action string, target string) {
+	actor, _ := r.Context().Value("userID").(string)
+	s.auditAs(r, actor, action, target)
+}
+
+// auditAs records an action performed by actor against target. Used when the request isn't authenticated.
+func (s *Server) auditAs(r *http.Request, actor string, action string, target string) {
+	s.record(r.Context(), audit.AppendOpts{
+		Actor:    actor,
+		Action:   action,
+		Target:   target,
+		Metadata: map[string]string{"remote_address": ClientIP(r)},
+	})
+}
+
+// record appends an entry to the audit log. Failures are logged rather than returned since the action being audited
+// has already happened.
+func (s *Server) record(ctx context.Context, opts audit.AppendOpts) {
+	s.auditLogger.Info("audit", slog.String("actor", opts.Actor), slog.String("action", opts.Action), slog.String("target", opts.Target))
+
+	if _, err := s.AuditService.Append(ctx, opts); err != nil {
+		s.auditLogger.Error("failed to append audit entry", slog.String("error", err.Error()))
+	}
+}
+
+// parseTimestamp parses either milliseconds since epoch or an RFC 3339 time into milliseconds since epoch.
+func parseTimestamp(s string) (int64, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ms, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.UnixMilli(), nil
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/worsediscord/server/services/audit"
+	"github.com/worsediscord/server/util"
+)
+
+func TestServer_HandleAdminAuditList(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+
+	for _, opts := range []audit.AppendOpts{
+		{Actor: "spiderman", Action: audit.ActionLogin},
+		{Actor: "venom", Action: audit.ActionLoginFailed},
+		{Actor: "spiderman", Action: audit.ActionRoomCreate},
+	} {
+		if _, err := s.AuditService.Append(nil, opts); err != nil {
+			t.Fatalf("failed to prepopulate audit log: %v", err)
+		}
+	}
+
+	tests := map[string]struct {
+		request        *http.Request
+		expectedStatus int
+		expectedCount  int
+	}{
+		"all": {
+			request:        httptest.NewRequest(http.MethodGet, "/api/admin/audit", nil),
+			expectedStatus: http.StatusOK,
+			expectedCount:  3,
+		},
+		"actor and action": {
+			request:        httptest.NewRequest(http.MethodGet, "/api/admin/audit?actor=spiderman&action=user.login", nil),
+			expectedStatus: http.StatusOK,
+			expectedCount:  1,
+		},
+		"since rfc3339": {
+			request:        httptest.NewRequest(http.MethodGet, "/api/admin/audit?since=2999-01-01T00:00:00Z", nil),
+			expectedStatus: http.StatusOK,
+			expectedCount:  0,
+		},
+		"invalid since": {
+			request:        httptest.NewRequest(http.MethodGet, "/api/admin/audit?since=yesterday", nil),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			s.handleAdminAuditList()(recorder, input.request)
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if recorder.Code != http.StatusOK {
+				return
+			}
+
+			var response []AuditEntryResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(response) != input.expectedCount {
+				t.Fatalf("got %d entries, expected %d", len(response), input.expectedCount)
+			}
+		})
+	}
+}
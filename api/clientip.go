@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// DefaultClientIPHeaders are the headers consulted by ClientIPMiddleware when none are configured.
+var DefaultClientIPHeaders = []string{"X-Forwarded-For", "X-Real-IP", "CF-Connecting-IP", "Forwarded"}
+
+// ClientIPMiddleware resolves the address of the client that originated a request and stores it in the request context
+// under "clientIP". Headers are only honored when the directly connected peer is within trustedProxies, in which case
+// the first header in headers that yields an address wins. Multi-hop headers (X-Forwarded-For and Forwarded) are walked
+// from right to left, skipping trusted proxies, so that a client can't spoof its address by prepending entries. The
+// X-Forwarded-Proto header of a trusted peer is stored under "clientScheme" in the same way, see ClientScheme.
+func ClientIPMiddleware(trustedProxies []netip.Prefix, headers []string) func(next http.Handler) http.Handler {
+	if len(headers) == 0 {
+		headers = DefaultClientIPHeaders
+	}
+
+	trusted := func(addr netip.Addr) bool {
+		return slices.ContainsFunc(trustedProxies, func(p netip.Prefix) bool {
+			return p.Contains(addr)
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			clientIP, ok := parseAddr(r.RemoteAddr)
+			if ok && trusted(clientIP) {
+				for _, header := range headers {
+					if addr, found := resolveHeader(r.Header, header, trusted); found {
+						clientIP = addr
+						break
+					}
+				}
+
+				if scheme, found := forwardedProto(r.Header); found {
+					ctx = context.WithValue(ctx, "clientScheme", scheme)
+				}
+			}
+
+			if clientIP.IsValid() {
+				ctx = context.WithValue(ctx, "clientIP", clientIP.String())
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClientIP returns the client address resolved by ClientIPMiddleware. If the middleware hasn't run, the host portion of
+// the request's RemoteAddr is returned instead.
+func ClientIP(r *http.Request) string {
+	if v, ok := r.Context().Value("clientIP").(string); ok {
+		return v
+	}
+
+	if addr, ok := parseAddr(r.RemoteAddr); ok {
+		return addr.String()
+	}
+
+	return r.RemoteAddr
+}
+
+// ClientScheme returns the scheme the client used to reach the server, either "http" or "https". The scheme resolved by
+// ClientIPMiddleware from a trusted proxy wins, otherwise it's taken from the request's own connection.
+func ClientScheme(r *http.Request) string {
+	if v, ok := r.Context().Value("clientScheme").(string); ok {
+		return v
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// ParseTrustedProxies parses a list of CIDRs or bare IP addresses.
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+
+	for _, v := range values {
+		if strings.Contains(v, "/") {
+			prefix, err := netip.ParsePrefix(v)
+			if err != nil {
+				return nil, err
+			}
+
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, err
+		}
+
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// resolveHeader extracts the client address from a single header.
+func resolveHeader(h http.Header, name string, trusted func(netip.Addr) bool) (netip.Addr, bool) {
+	values := h.Values(name)
+	if len(values) == 0 {
+		return netip.Addr{}, false
+	}
+
+	var hops []string
+	switch http.CanonicalHeaderKey(name) {
+	case "X-Forwarded-For":
+		for _, v := range values {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+	case "Forwarded":
+		for _, v := range values {
+			hops = append(hops, forwardedFor(v)...)
+		}
+	default:
+		// Single value headers only make sense once, so take the one closest to us.
+		return parseAddr(values[len(values)-1])
+	}
+
+	return walkHops(hops, trusted)
+}
+
+// walkHops returns the right-most hop that isn't a trusted proxy. If every hop is trusted the left-most one is returned.
+// An unparseable hop stops the walk, since nothing to its left can be trusted.
+func walkHops(hops []string, trusted func(netip.Addr) bool) (netip.Addr, bool) {
+	var last netip.Addr
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(hops[i])
+		if !ok {
+			return last, last.IsValid()
+		}
+
+		last = addr
+		if !trusted(addr) {
+			return addr, true
+		}
+	}
+
+	return last, last.IsValid()
+}
+
+// forwardedProto returns the scheme in the X-Forwarded-Proto header closest to us. Anything but http or https is ignored.
+func forwardedProto(h http.Header) (string, bool) {
+	values := h.Values("X-Forwarded-Proto")
+	if len(values) == 0 {
+		return "", false
+	}
+
+	hops := strings.Split(values[len(values)-1], ",")
+
+	switch scheme := strings.ToLower(strings.TrimSpace(hops[len(hops)-1])); scheme {
+	case "http", "https":
+		return scheme, true
+	}
+
+	return "", false
+}
+
+// forwardedFor returns the for= parameters of an RFC 7239 Forwarded header, in order.
+func forwardedFor(v string) []string {
+	var hops []string
+
+	for _, element := range strings.Split(v, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if !found || !strings.EqualFold(key, "for") {
+				continue
+			}
+
+			hops = append(hops, strings.Trim(value, `"`))
+		}
+	}
+
+	return hops
+}
+
+// parseAddr parses an address that may carry a port and IPv6 brackets, e.g. "[::1]:8080".
+func parseAddr(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap().WithZone(""), true
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
+)
+
+type DirectMessageRequest struct {
+	// Other users to add for a group conversation, besides the user in the path.
+	Users []string `json:"users,omitempty"`
+}
+
+// handleDirectMessageCreate returns the direct message room with a user, creating it if needed
+//
+//	@Summary		Open a direct message
+//	@Description	Returns the direct message room between the caller, the user in the path and any users in the body,
+//	@Description	creating it if it doesn't exist. The same set of users always gets the same room.
+//	@Tags			rooms
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	string					true	"username to message"
+//	@Param			users	body	DirectMessageRequest	false	"other users for a group conversation"
+//	@Security		ApiKey
+//	@Success		200	{object}	RoomResponse
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/users/{id}/dm [post]
+func (s *Server) handleDirectMessageCreate() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "DirectMessageCreate"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var request DirectMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if _, err := s.UserService.GetUserById(r.Context(), user.GetUserByIdOpts{Id: r.PathValue("id")}); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		for _, other := range request.Users {
+			if _, err := s.UserService.GetUserById(r.Context(), user.GetUserByIdOpts{Id: other}); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		userIds := append([]string{userId, r.PathValue("id")}, request.Users...)
+		gotRoom, err := s.RoomService.CreateDirect(r.Context(), room.CreateDirectRoomOpts{UserIds: userIds})
+		if err != nil {
+			if errors.Is(err, room.ErrInvalidDirect) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			logger.Error("failed to create direct message room", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(w).Encode(roomResponse(gotRoom)); err != nil {
+			logger.Error("failed to encode json response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		logger.Info("direct message room opened", slog.Int64("id", gotRoom.Id))
+
+		return
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/worsediscord/server/services/fake"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/util"
+)
+
+func TestServer_HandleDirectMessageCreate(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	directRoom := &room.Room{Id: 1, Type: room.TypeDirect, Users: []string{"spiderman", "venom"}}
+
+	tests := map[string]struct {
+		id               string
+		body             string
+		userId           string
+		userService      *fake.UserService
+		roomService      *fake.RoomService
+		expectedStatus   int
+		expectedResponse RoomResponse
+		expectedCalls    []room.CreateDirectRoomOpts
+	}{
+		"valid": {
+			id:               "venom",
+			userId:           "spiderman",
+			userService:      &fake.UserService{ExpectedGetUserByIdUser: &user.User{}},
+			roomService:      &fake.RoomService{ExpectedCreateDirectRoom: directRoom},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: RoomResponse{Id: 1, Type: "direct", Users: []string{"spiderman", "venom"}},
+			expectedCalls:    []room.CreateDirectRoomOpts{{UserIds: []string{"spiderman", "venom"}}},
+		},
+		"group": {
+			id:               "venom",
+			body:             `{"users":["mj"]}`,
+			userId:           "spiderman",
+			userService:      &fake.UserService{ExpectedGetUserByIdUser: &user.User{}},
+			roomService:      &fake.RoomService{ExpectedCreateDirectRoom: directRoom},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: RoomResponse{Id: 1, Type: "direct", Users: []string{"spiderman", "venom"}},
+			expectedCalls:    []room.CreateDirectRoomOpts{{UserIds: []string{"spiderman", "venom", "mj"}}},
+		},
+		"invalid users": {
+			id:             "spiderman",
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{}},
+			roomService:    &fake.RoomService{ExpectedCreateDirectError: room.ErrInvalidDirect},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []room.CreateDirectRoomOpts{{UserIds: []string{"spiderman", "spiderman"}}},
+		},
+		"invalid body": {
+			id:             "venom",
+			body:           `{"users":`,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{}},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"user not found": {
+			id:             "carnage",
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdError: user.ErrNotFound},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"unauthenticated": {
+			id:             "venom",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{}},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			id:             "venom",
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{}},
+			roomService:    &fake.RoomService{ExpectedCreateDirectError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []room.CreateDirectRoomOpts{{UserIds: []string{"spiderman", "venom"}}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.UserService = input.userService
+			s.RoomService = input.roomService
+
+			request := httptest.NewRequest(http.MethodPost, "/api/users/"+input.id+"/dm", strings.NewReader(input.body))
+			request.SetPathValue("id", input.id)
+
+			recorder := httptest.NewRecorder()
+			s.handleDirectMessageCreate()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.CreateDirectCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.CreateDirectCalls, input.expectedCalls)
+			}
+
+			if recorder.Code != http.StatusOK {
+				return
+			}
+
+			var response RoomResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(response, input.expectedResponse) {
+				t.Fatalf("got %v, expected %v", response, input.expectedResponse)
+			}
+		})
+	}
+}
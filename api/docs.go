@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"sync"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+	"github.com/worsediscord/server/api/docs"
+)
+
+// openAPISpec is docs.Spec decoded once, so that every request only has to replace the servers.
+var openAPISpec = sync.OnceValues(func() (map[string]any, error) {
+	var spec map[string]any
+	err := json.Unmarshal(docs.Spec, &spec)
+
+	return spec, err
+})
+
+// handleDocsSpec returns the OpenAPI spec of this API
+//
+//	@Summary		Returns the OpenAPI spec
+//	@Description	The server URL of the spec is the host the request was made to.
+//	@Tags			docs
+//	@Produce		json
+//	@Success		200
+//	@Failure		500
+//	@Router			/docs/openapi.json [get]
+func (s *Server) handleDocsSpec() http.HandlerFunc {
+	logger := slog.New(s.logHandler)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec, err := openAPISpec()
+		if err != nil {
+			logger.Error("failed to decode embedded openapi spec", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := maps.Clone(spec)
+		response["servers"] = []map[string]string{{"url": ClientScheme(r) + "://" + r.Host + "/api"}}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(response); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// handleDocsUI returns a Swagger UI page for the OpenAPI spec
+//
+//	@Summary	Renders the OpenAPI spec
+//	@Tags		docs
+//	@Produce	html
+//	@Success	200
+//	@Router		/docs [get]
+func (s *Server) handleDocsUI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(docs.Index)
+	}
+}
+
+// handleDocsAssets serves the scripts and stylesheets that the Swagger UI page loads.
+func (s *Server) handleDocsAssets() http.Handler {
+	return http.StripPrefix("/api/docs/", http.FileServerFS(swaggerFiles.FS))
+}
@@ -0,0 +1,14 @@
+// Package docs embeds the OpenAPI spec generated by `make swag` and the page that renders it.
+package docs
+
+import _ "embed"
+
+// Spec is the OpenAPI 3.1 spec of the api package.
+//
+//go:embed swagger.json
+var Spec []byte
+
+// Index is a Swagger UI page that loads openapi.json from the same directory.
+//
+//go:embed index.html
+var Index []byte
@@ -0,0 +1,118 @@
+//go:build ignore
+
+// fixspec copies the security schemes and servers of the Swagger 2.0 spec generated by swag into the OpenAPI 3.1 spec
+// generated from the same annotations. swag's OpenAPI 3.1 output names every security scheme after the first
+// @SecurityDefinitions annotation, always names basic auth "basic" and ignores @Host and @Schemes.
+//
+// Usage: go run fixspec.go <swagger 2.0 spec> <openapi 3.1 spec>
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type swagger2 struct {
+	Host                string                        `json:"host"`
+	BasePath            string                        `json:"basePath"`
+	Schemes             []string                      `json:"schemes"`
+	SecurityDefinitions map[string]securityDefinition `json:"securityDefinitions"`
+}
+
+type securityDefinition struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Name        string `json:"name,omitempty"`
+	In          string `json:"in,omitempty"`
+}
+
+type securityScheme struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Scheme      string `json:"scheme,omitempty"`
+	Name        string `json:"name,omitempty"`
+	In          string `json:"in,omitempty"`
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: go run fixspec.go <swagger 2.0 spec> <openapi 3.1 spec>")
+		os.Exit(2)
+	}
+
+	if err := fix(os.Args[1], os.Args[2]); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func fix(v2File string, v3File string) error {
+	b, err := os.ReadFile(v2File)
+	if err != nil {
+		return err
+	}
+
+	var v2 swagger2
+	if err = json.Unmarshal(b, &v2); err != nil {
+		return fmt.Errorf("%s: %w", v2File, err)
+	}
+
+	if b, err = os.ReadFile(v3File); err != nil {
+		return err
+	}
+
+	var v3 map[string]any
+	if err = json.Unmarshal(b, &v3); err != nil {
+		return fmt.Errorf("%s: %w", v3File, err)
+	}
+
+	schemes := make(map[string]securityScheme, len(v2.SecurityDefinitions))
+	for name, definition := range v2.SecurityDefinitions {
+		scheme := securityScheme{Type: definition.Type, Description: definition.Description}
+
+		switch definition.Type {
+		case "basic":
+			scheme.Type = "http"
+			scheme.Scheme = "basic"
+		case "apiKey":
+			scheme.Name = definition.Name
+			scheme.In = definition.In
+		default:
+			return fmt.Errorf("security definition %s: unsupported type %q", name, definition.Type)
+		}
+
+		schemes[name] = scheme
+	}
+
+	components, _ := v3["components"].(map[string]any)
+	if components == nil {
+		components = make(map[string]any)
+		v3["components"] = components
+	}
+
+	components["securitySchemes"] = schemes
+
+	servers := make([]map[string]string, 0, len(v2.Schemes))
+	for _, scheme := range v2.Schemes {
+		servers = append(servers, map[string]string{"url": scheme + "://" + v2.Host + v2.BasePath})
+	}
+
+	if len(servers) == 0 {
+		servers = append(servers, map[string]string{"url": v2.Host + v2.BasePath})
+	}
+
+	v3["servers"] = servers
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+
+	if err = encoder.Encode(v3); err != nil {
+		return err
+	}
+
+	return os.WriteFile(v3File, buf.Bytes(), 0644)
+}
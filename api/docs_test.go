@@ -0,0 +1,154 @@
+package api
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/worsediscord/server/util"
+)
+
+func TestServer_HandleDocsSpec(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+
+	trustedProxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := ClientIPMiddleware(trustedProxies, nil)(s.handleDocsSpec())
+
+	tests := map[string]struct {
+		host            string
+		remoteAddr      string
+		tls             bool
+		forwardedProto  string
+		expectedServers []map[string]string
+	}{
+		"http": {
+			host:            "localhost:8080",
+			expectedServers: []map[string]string{{"url": "http://localhost:8080/api"}},
+		},
+		"https": {
+			host:            "test.beesarecute.com",
+			tls:             true,
+			expectedServers: []map[string]string{{"url": "https://test.beesarecute.com/api"}},
+		},
+		"forwarded https": {
+			host:            "test.beesarecute.com",
+			remoteAddr:      "10.1.2.3:4321",
+			forwardedProto:  "https",
+			expectedServers: []map[string]string{{"url": "https://test.beesarecute.com/api"}},
+		},
+		"forwarded chain": {
+			host:            "test.beesarecute.com",
+			remoteAddr:      "10.1.2.3:4321",
+			forwardedProto:  "http, https",
+			expectedServers: []map[string]string{{"url": "https://test.beesarecute.com/api"}},
+		},
+		"untrusted forwarded https": {
+			host:            "test.beesarecute.com",
+			remoteAddr:      "203.0.113.7:4321",
+			forwardedProto:  "https",
+			expectedServers: []map[string]string{{"url": "http://test.beesarecute.com/api"}},
+		},
+		"invalid forwarded proto": {
+			host:            "test.beesarecute.com",
+			remoteAddr:      "10.1.2.3:4321",
+			forwardedProto:  "gopher",
+			expectedServers: []map[string]string{{"url": "http://test.beesarecute.com/api"}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/api/docs/openapi.json", nil)
+			request.Host = input.host
+			if input.remoteAddr != "" {
+				request.RemoteAddr = input.remoteAddr
+			}
+			if input.tls {
+				request.TLS = &tls.ConnectionState{}
+			}
+			if input.forwardedProto != "" {
+				request.Header.Set("X-Forwarded-Proto", input.forwardedProto)
+			}
+
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("got status %d, expected %d", recorder.Code, http.StatusOK)
+			}
+
+			var response struct {
+				OpenAPI    string              `json:"openapi"`
+				Servers    []map[string]string `json:"servers"`
+				Paths      map[string]any      `json:"paths"`
+				Components struct {
+					SecuritySchemes map[string]any `json:"securitySchemes"`
+				} `json:"components"`
+			}
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(response.OpenAPI, "3.") {
+				t.Fatalf("got openapi version %q, expected 3.x", response.OpenAPI)
+			}
+
+			if !reflect.DeepEqual(response.Servers, input.expectedServers) {
+				t.Fatalf("got servers %v, expected %v", response.Servers, input.expectedServers)
+			}
+
+			if _, ok := response.Paths["/rooms"]; !ok {
+				t.Fatalf("got paths %v, expected /rooms to be documented", response.Paths)
+			}
+
+			for _, scheme := range []string{"ApiKey", "BasicAuth"} {
+				if _, ok := response.Components.SecuritySchemes[scheme]; !ok {
+					t.Fatalf("got security schemes %v, expected %s", response.Components.SecuritySchemes, scheme)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_Docs(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+
+	tests := map[string]struct {
+		path                string
+		expectedStatus      int
+		expectedContentType string
+	}{
+		"ui":            {path: "/api/docs/", expectedStatus: http.StatusOK, expectedContentType: "text/html"},
+		"no slash":      {path: "/api/docs", expectedStatus: http.StatusTemporaryRedirect},
+		"spec":          {path: "/api/docs/openapi.json", expectedStatus: http.StatusOK, expectedContentType: "application/json"},
+		"asset":         {path: "/api/docs/swagger-ui-bundle.js", expectedStatus: http.StatusOK, expectedContentType: "text/javascript"},
+		"missing asset": {path: "/api/docs/pizza.js", expectedStatus: http.StatusNotFound},
+	}
+
+	handlers := map[string]http.Handler{"server": s, "admin listener": s.AdminHandler()}
+
+	for handlerName, handler := range handlers {
+		for name, input := range tests {
+			t.Run(handlerName+"/"+name, func(t *testing.T) {
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, input.path, nil))
+
+				if recorder.Code != input.expectedStatus {
+					t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+				}
+
+				if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, input.expectedContentType) {
+					t.Fatalf("got content type %q, expected %q", contentType, input.expectedContentType)
+				}
+			})
+		}
+	}
+}
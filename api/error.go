@@ -3,4 +3,7 @@ package api
 type Error struct {
 	Status  int    `json:"status"`
 	Message string `json:"message"`
+
+	// The fields that failed validation, if any.
+	Fields []FieldError `json:"fields,omitempty"`
 }
@@ -0,0 +1,353 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/worsediscord/server/services/audit"
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/room"
+)
+
+type InviteCreateRequest struct {
+	// How many times the invite can be used. Zero means no limit.
+	MaxUses int `json:"max_uses,omitempty" minimum:"0"`
+
+	// Seconds until the invite expires. Zero means it never expires.
+	ExpiresIn int `json:"expires_in,omitempty" minimum:"0"`
+}
+
+type InviteResponse struct {
+	// The code to accept the invite with.
+	Code string `json:"code"`
+
+	// The id of the room the invite is to.
+	RoomId int64 `json:"room_id"`
+
+	// The unique username of the user that created the invite.
+	CreatedBy string `json:"created_by"`
+
+	// Time since epoch in milliseconds that the invite was created.
+	CreatedAt int64 `json:"created_at"`
+
+	// Time since epoch in milliseconds that the invite expires. Omitted if it never expires.
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+
+	// How many times the invite can be used. Omitted if there is no limit.
+	MaxUses int `json:"max_uses,omitempty"`
+
+	// How many times the invite has been used.
+	Uses int `json:"uses"`
+}
+
+// handleInviteCreate creates an invite to a room
+//
+//	@Summary		Create an invite
+//	@Description	Only room admins can create invites. Anyone with the code can join the room until the invite expires
+//	@Description	or runs out of uses, even if the room is private.
+//	@Tags			invites
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int					true	"room id to create the invite to"
+//	@Param			invite	body	InviteCreateRequest	false	"invite limits"
+//	@Security		ApiKey
+//	@Success		200	{object}	InviteResponse
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/invites [post]
+func (s *Server) handleInviteCreate() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "InviteCreate"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		gotRoom, userId, ok := s.lookupInviteRoom(w, r)
+		if !ok {
+			return
+		}
+
+		var request InviteCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if request.MaxUses < 0 || request.ExpiresIn < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		now := s.Clock.Now()
+
+		opts := invite.CreateInviteOpts{RoomId: gotRoom.Id, UserId: userId, MaxUses: request.MaxUses, Now: now.UnixMilli()}
+		if request.ExpiresIn > 0 {
+			opts.ExpiresAt = now.Add(time.Duration(request.ExpiresIn) * time.Second).UnixMilli()
+		}
+
+		created, err := s.InviteService.Create(r.Context(), opts)
+		if err != nil {
+			logger.Error("failed to create invite", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(w).Encode(inviteResponse(created)); err != nil {
+			logger.Error("failed to encode json response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.audit(r, audit.ActionInviteCreate, strconv.FormatInt(gotRoom.Id, 10))
+
+		return
+	}
+}
+
+// handleInviteList lists the invites to a room
+//
+//	@Summary		List invites
+//	@Description	Only room admins can list invites. Expired and used up invites are listed until they are revoked.
+//	@Tags			invites
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"room id to list invites to"
+//	@Security		ApiKey
+//	@Success		200	{array}		InviteResponse
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/invites [get]
+func (s *Server) handleInviteList() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "InviteList"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		gotRoom, _, ok := s.lookupInviteRoom(w, r)
+		if !ok {
+			return
+		}
+
+		invites, err := s.InviteService.List(r.Context(), invite.ListInviteOpts{RoomId: gotRoom.Id})
+		if err != nil {
+			logger.Error("failed to list invites", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]InviteResponse, 0, len(invites))
+		for _, inv := range invites {
+			response = append(response, inviteResponse(inv))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode json response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		return
+	}
+}
+
+// handleInviteRevoke revokes an invite to a room
+//
+//	@Summary	Revoke an invite
+//	@Tags		invites
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path	int		true	"room id the invite is to"
+//	@Param		code	path	string	true	"invite code to revoke"
+//	@Security	ApiKey
+//	@Success	200
+//	@Failure	400	{object}	Error
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/rooms/{id}/invites/{code} [delete]
+func (s *Server) handleInviteRevoke() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "InviteRevoke"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		gotRoom, _, ok := s.lookupInviteRoom(w, r)
+		if !ok {
+			return
+		}
+
+		code := r.PathValue("code")
+
+		inv, err := s.InviteService.GetInviteByCode(r.Context(), invite.GetInviteByCodeOpts{Code: code})
+		if errors.Is(err, invite.ErrNotFound) || (err == nil && inv.RoomId != gotRoom.Id) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if err != nil {
+			logger.Error("failed to get invite", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err = s.InviteService.Revoke(r.Context(), invite.RevokeInviteOpts{Code: code}); err != nil {
+			if errors.Is(err, invite.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			logger.Error("failed to revoke invite", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.audit(r, audit.ActionInviteRevoke, strconv.FormatInt(gotRoom.Id, 10))
+
+		return
+	}
+}
+
+// handleInviteAccept joins the room an invite is to
+//
+//	@Summary		Accept an invite
+//	@Description	Joins the room the invite is to. Members of the room can accept it without using it up, and users
+//	@Description	banned from the room can't accept it at all.
+//	@Tags			invites
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	path	string	true	"invite code to accept"
+//	@Security		ApiKey
+//	@Success		200	{object}	RoomResponse
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		403
+//	@Failure		404
+//	@Failure		410
+//	@Failure		500
+//	@Router			/invites/{code} [post]
+func (s *Server) handleInviteAccept() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "InviteAccept"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		inv, err := s.InviteService.GetInviteByCode(r.Context(), invite.GetInviteByCodeOpts{Code: r.PathValue("code")})
+		if errors.Is(err, invite.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if err != nil {
+			logger.Error("failed to get invite", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		gotRoom, err := s.RoomService.GetRoomById(r.Context(), room.GetRoomByIdOpts{Id: inv.RoomId})
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		now := s.Clock.Now().UnixMilli()
+
+		if !slices.Contains(gotRoom.Users, userId) {
+			// Banned users are turned away before the invite is used up.
+			if gotRoom.Banned(userId, now) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			if _, err = s.InviteService.Use(r.Context(), invite.UseInviteOpts{Code: inv.Code, Now: now}); err != nil {
+				switch {
+				case errors.Is(err, invite.ErrExpired), errors.Is(err, invite.ErrUsedUp):
+					w.WriteHeader(http.StatusGone)
+				case errors.Is(err, invite.ErrNotFound):
+					w.WriteHeader(http.StatusNotFound)
+				default:
+					logger.Error("failed to use invite", slog.String("error", err.Error()))
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+
+				return
+			}
+
+			if err = s.RoomService.Join(r.Context(), room.JoinRoomOpts{Id: gotRoom.Id, UserId: userId, Now: now}); err != nil {
+				switch {
+				case errors.Is(err, room.ErrDirect):
+					w.WriteHeader(http.StatusBadRequest)
+				case errors.Is(err, room.ErrBanned):
+					w.WriteHeader(http.StatusForbidden)
+				case errors.Is(err, room.ErrNotFound):
+					w.WriteHeader(http.StatusNotFound)
+				default:
+					logger.Error("failed to join room", slog.String("error", err.Error()))
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+
+				return
+			}
+
+			s.audit(r, audit.ActionInviteAccept, strconv.FormatInt(gotRoom.Id, 10))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(w).Encode(roomResponse(gotRoom)); err != nil {
+			logger.Error("failed to encode json response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		return
+	}
+}
+
+// lookupInviteRoom returns the room in the id path value and the user making the request, who must be able to manage
+// its invites. A missing room is written as a 404, a user that can't manage invites as a 401, and ok is false.
+func (s *Server) lookupInviteRoom(w http.ResponseWriter, r *http.Request) (*room.Room, string, bool) {
+	userId, ok := r.Context().Value("userID").(string)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil, "", false
+	}
+
+	roomId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, "", false
+	}
+
+	gotRoom, err := s.RoomService.GetRoomById(r.Context(), room.GetRoomByIdOpts{Id: roomId})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, "", false
+	}
+
+	if !gotRoom.CanInvite(userId) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil, "", false
+	}
+
+	return gotRoom, userId, true
+}
+
+func inviteResponse(inv *invite.Invite) InviteResponse {
+	return InviteResponse{
+		Code:      inv.Code,
+		RoomId:    inv.RoomId,
+		CreatedBy: inv.UserId,
+		CreatedAt: inv.CreatedAt,
+		ExpiresAt: inv.ExpiresAt,
+		MaxUses:   inv.MaxUses,
+		Uses:      inv.Uses,
+	}
+}
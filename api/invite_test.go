@@ -0,0 +1,401 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/fake"
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/util"
+)
+
+func TestServer_HandleInviteCreate(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	now := time.UnixMilli(1_000_000)
+	s.Clock = util.ClockFunc(func() time.Time { return now })
+
+	privateRoom := &room.Room{Id: 1, Name: "sanctum", Visibility: room.VisibilityPrivate, Users: []string{"strange", "wong"}, Admins: []string{"strange"}}
+	created := &invite.Invite{Code: "abc", RoomId: 1, UserId: "strange", CreatedAt: 1000}
+
+	tests := map[string]struct {
+		id               string
+		body             string
+		userId           string
+		roomService      *fake.RoomService
+		inviteService    *fake.InviteService
+		expectedStatus   int
+		expectedCalls    []invite.CreateInviteOpts
+		expectedResponse InviteResponse
+	}{
+		"valid": {
+			id:               "1",
+			userId:           "strange",
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:    &fake.InviteService{ExpectedCreateInvite: created},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []invite.CreateInviteOpts{{RoomId: 1, UserId: "strange", Now: 1_000_000}},
+			expectedResponse: InviteResponse{Code: "abc", RoomId: 1, CreatedBy: "strange", CreatedAt: 1000},
+		},
+		"limits": {
+			id:               "1",
+			body:             `{"max_uses":3,"expires_in":60}`,
+			userId:           "strange",
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:    &fake.InviteService{ExpectedCreateInvite: created},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []invite.CreateInviteOpts{{RoomId: 1, UserId: "strange", MaxUses: 3, ExpiresAt: 1_060_000, Now: 1_000_000}},
+			expectedResponse: InviteResponse{Code: "abc", RoomId: 1, CreatedBy: "strange", CreatedAt: 1000},
+		},
+		"negative limits": {
+			id:             "1",
+			body:           `{"max_uses":-1}`,
+			userId:         "strange",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"member": {
+			id:             "1",
+			userId:         "wong",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"direct message": {
+			id:             "1",
+			userId:         "strange",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Type: room.TypeDirect, Users: []string{"strange", "wong"}}},
+			inviteService:  &fake.InviteService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"room not found": {
+			id:             "2",
+			userId:         "strange",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			inviteService:  &fake.InviteService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"unauthenticated": {
+			id:             "1",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			id:             "1",
+			userId:         "strange",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{ExpectedCreateError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []invite.CreateInviteOpts{{RoomId: 1, UserId: "strange", Now: 1_000_000}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+			s.InviteService = input.inviteService
+
+			request := httptest.NewRequest(http.MethodPost, "/api/rooms/"+input.id+"/invites", strings.NewReader(input.body))
+			request.SetPathValue("id", input.id)
+
+			recorder := httptest.NewRecorder()
+			s.handleInviteCreate()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.inviteService.CreateCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.inviteService.CreateCalls, input.expectedCalls)
+			}
+
+			if recorder.Code != http.StatusOK {
+				return
+			}
+
+			var response InviteResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if response != input.expectedResponse {
+				t.Fatalf("got %v, expected %v", response, input.expectedResponse)
+			}
+		})
+	}
+}
+
+func TestServer_HandleInviteList(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	privateRoom := &room.Room{Id: 1, Name: "sanctum", Visibility: room.VisibilityPrivate, Users: []string{"strange", "wong"}, Admins: []string{"strange"}}
+
+	tests := map[string]struct {
+		userId           string
+		roomService      *fake.RoomService
+		inviteService    *fake.InviteService
+		expectedStatus   int
+		expectedCalls    []invite.ListInviteOpts
+		expectedResponse []InviteResponse
+	}{
+		"valid": {
+			userId:      "strange",
+			roomService: &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService: &fake.InviteService{ExpectedListInvites: []*invite.Invite{
+				{Code: "abc", RoomId: 1, UserId: "strange", CreatedAt: 1000, ExpiresAt: 5000, MaxUses: 2, Uses: 1},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []invite.ListInviteOpts{{RoomId: 1}},
+			expectedResponse: []InviteResponse{
+				{Code: "abc", RoomId: 1, CreatedBy: "strange", CreatedAt: 1000, ExpiresAt: 5000, MaxUses: 2, Uses: 1},
+			},
+		},
+		"empty": {
+			userId:           "strange",
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:    &fake.InviteService{ExpectedListInvites: []*invite.Invite{}},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []invite.ListInviteOpts{{RoomId: 1}},
+			expectedResponse: []InviteResponse{},
+		},
+		"member": {
+			userId:         "wong",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			userId:         "strange",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{ExpectedListError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []invite.ListInviteOpts{{RoomId: 1}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+			s.InviteService = input.inviteService
+
+			request := httptest.NewRequest(http.MethodGet, "/api/rooms/1/invites", nil)
+			request.SetPathValue("id", "1")
+
+			recorder := httptest.NewRecorder()
+			s.handleInviteList()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.inviteService.ListCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.inviteService.ListCalls, input.expectedCalls)
+			}
+
+			if recorder.Code != http.StatusOK {
+				return
+			}
+
+			var response []InviteResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(response, input.expectedResponse) {
+				t.Fatalf("got %v, expected %v", response, input.expectedResponse)
+			}
+		})
+	}
+}
+
+func TestServer_HandleInviteRevoke(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	privateRoom := &room.Room{Id: 1, Name: "sanctum", Visibility: room.VisibilityPrivate, Users: []string{"strange", "wong"}, Admins: []string{"strange"}}
+	existingInvite := &invite.Invite{Code: "abc", RoomId: 1, UserId: "strange"}
+
+	tests := map[string]struct {
+		userId         string
+		roomService    *fake.RoomService
+		inviteService  *fake.InviteService
+		expectedStatus int
+		expectedCalls  []invite.RevokeInviteOpts
+	}{
+		"valid": {
+			userId:         "strange",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{ExpectedGetInviteByCodeInvite: existingInvite},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []invite.RevokeInviteOpts{{Code: "abc"}},
+		},
+		"invite to another room": {
+			userId:         "strange",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{ExpectedGetInviteByCodeInvite: &invite.Invite{Code: "abc", RoomId: 2}},
+			expectedStatus: http.StatusNotFound,
+		},
+		"invite not found": {
+			userId:         "strange",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{ExpectedGetInviteByCodeError: invite.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"member": {
+			userId:         "wong",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{ExpectedGetInviteByCodeInvite: existingInvite},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			userId:         "strange",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{ExpectedGetInviteByCodeInvite: existingInvite, ExpectedRevokeError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []invite.RevokeInviteOpts{{Code: "abc"}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+			s.InviteService = input.inviteService
+
+			request := httptest.NewRequest(http.MethodDelete, "/api/rooms/1/invites/abc", nil)
+			request.SetPathValue("id", "1")
+			request.SetPathValue("code", "abc")
+
+			recorder := httptest.NewRecorder()
+			s.handleInviteRevoke()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.inviteService.RevokeCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.inviteService.RevokeCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleInviteAccept(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	now := time.UnixMilli(1_000_000)
+	s.Clock = util.ClockFunc(func() time.Time { return now })
+
+	privateRoom := &room.Room{Id: 1, Name: "sanctum", Visibility: room.VisibilityPrivate, Users: []string{"strange", "wong"}, Admins: []string{"strange"}}
+	existingInvite := &invite.Invite{Code: "abc", RoomId: 1, UserId: "strange"}
+	bannedRoom := &room.Room{Id: 1, Name: "sanctum", Visibility: room.VisibilityPrivate, Users: []string{"strange"}, Admins: []string{"strange"}, Bans: []room.Restriction{
+		{UserId: "mordo", CreatedBy: "strange"},
+		{UserId: "wong", CreatedBy: "strange", ExpiresAt: 1_000_000},
+	}}
+
+	tests := map[string]struct {
+		userId            string
+		roomService       *fake.RoomService
+		inviteService     *fake.InviteService
+		expectedStatus    int
+		expectedUseCalls  []invite.UseInviteOpts
+		expectedJoinCalls []room.JoinRoomOpts
+	}{
+		"valid": {
+			userId:            "mordo",
+			roomService:       &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:     &fake.InviteService{ExpectedGetInviteByCodeInvite: existingInvite, ExpectedUseInvite: existingInvite},
+			expectedStatus:    http.StatusOK,
+			expectedUseCalls:  []invite.UseInviteOpts{{Code: "abc", Now: 1_000_000}},
+			expectedJoinCalls: []room.JoinRoomOpts{{Id: 1, UserId: "mordo", Now: 1_000_000}},
+		},
+		"already a member": {
+			userId:         "wong",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{ExpectedGetInviteByCodeInvite: existingInvite},
+			expectedStatus: http.StatusOK,
+		},
+		"expired": {
+			userId:           "mordo",
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:    &fake.InviteService{ExpectedGetInviteByCodeInvite: existingInvite, ExpectedUseError: invite.ErrExpired},
+			expectedStatus:   http.StatusGone,
+			expectedUseCalls: []invite.UseInviteOpts{{Code: "abc", Now: 1_000_000}},
+		},
+		"used up": {
+			userId:           "mordo",
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:    &fake.InviteService{ExpectedGetInviteByCodeInvite: existingInvite, ExpectedUseError: invite.ErrUsedUp},
+			expectedStatus:   http.StatusGone,
+			expectedUseCalls: []invite.UseInviteOpts{{Code: "abc", Now: 1_000_000}},
+		},
+		// Banned users don't use up the invite.
+		"banned": {
+			userId:         "mordo",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: bannedRoom},
+			inviteService:  &fake.InviteService{ExpectedGetInviteByCodeInvite: existingInvite},
+			expectedStatus: http.StatusForbidden,
+		},
+		"ban expired": {
+			userId:            "wong",
+			roomService:       &fake.RoomService{ExpectedGetRoomByIdRoom: bannedRoom},
+			inviteService:     &fake.InviteService{ExpectedGetInviteByCodeInvite: existingInvite, ExpectedUseInvite: existingInvite},
+			expectedStatus:    http.StatusOK,
+			expectedUseCalls:  []invite.UseInviteOpts{{Code: "abc", Now: 1_000_000}},
+			expectedJoinCalls: []room.JoinRoomOpts{{Id: 1, UserId: "wong", Now: 1_000_000}},
+		},
+		"invite not found": {
+			userId:         "mordo",
+			roomService:    &fake.RoomService{},
+			inviteService:  &fake.InviteService{ExpectedGetInviteByCodeError: invite.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"room deleted": {
+			userId:         "mordo",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			inviteService:  &fake.InviteService{ExpectedGetInviteByCodeInvite: existingInvite},
+			expectedStatus: http.StatusNotFound,
+		},
+		"unauthenticated": {
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			inviteService:  &fake.InviteService{ExpectedGetInviteByCodeInvite: existingInvite},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"join error": {
+			userId:            "mordo",
+			roomService:       &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom, ExpectedJoinError: errors.New("oops")},
+			inviteService:     &fake.InviteService{ExpectedGetInviteByCodeInvite: existingInvite, ExpectedUseInvite: existingInvite},
+			expectedStatus:    http.StatusInternalServerError,
+			expectedUseCalls:  []invite.UseInviteOpts{{Code: "abc", Now: 1_000_000}},
+			expectedJoinCalls: []room.JoinRoomOpts{{Id: 1, UserId: "mordo", Now: 1_000_000}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+			s.InviteService = input.inviteService
+
+			request := httptest.NewRequest(http.MethodPost, "/api/invites/abc", nil)
+			request.SetPathValue("code", "abc")
+
+			recorder := httptest.NewRecorder()
+			s.handleInviteAccept()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.inviteService.UseCalls, input.expectedUseCalls) {
+				t.Fatalf("got use calls %v, expected %v", input.inviteService.UseCalls, input.expectedUseCalls)
+			}
+
+			if !reflect.DeepEqual(input.roomService.JoinCalls, input.expectedJoinCalls) {
+				t.Fatalf("got join calls %v, expected %v", input.roomService.JoinCalls, input.expectedJoinCalls)
+			}
+		})
+	}
+}
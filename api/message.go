@@ -2,22 +2,36 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 
+	"github.com/worsediscord/server/services/audit"
 	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/reaction"
 	"github.com/worsediscord/server/services/room"
 	"github.com/worsediscord/server/services/user"
 )
 
+// previewLength is the most characters of a message included in a reply preview.
+const previewLength = 100
+
 type MessageCreateRequest struct {
 	// The content of the message.
-	Content string `json:"content"`
+	Content string `json:"content" validate:"required" minLength:"1"`
+
+	// The id of a message in the same room to reply to.
+	ReplyTo string `json:"reply_to,omitempty"`
 }
 
 type MessageResponse struct {
-	// The unique username of the message author.
+	// The unique id of the message.
+	Id string `json:"id,omitempty"`
+
+	// The unique username of the message author. Omitted if the author has since been deleted.
 	UserId string `json:"user_id,omitempty"`
 
 	// The content of the message.
@@ -25,22 +39,50 @@ type MessageResponse struct {
 
 	// Time since epoch in milliseconds.
 	Timestamp int64 `json:"timestamp,omitempty"`
+
+	// Every emoji the message was reacted with, in the order they were first used.
+	Reactions []ReactionCountResponse `json:"reactions,omitempty"`
+
+	// A preview of the message this one replies to.
+	ReplyTo *MessagePreviewResponse `json:"reply_to,omitempty"`
+
+	// The id of the message at the root of the thread this one is a reply in.
+	ThreadId string `json:"thread_id,omitempty"`
+
+	// How many replies are in the thread rooted at this message.
+	ReplyCount int `json:"reply_count,omitempty"`
+
+	// Time since epoch in milliseconds of the latest reply in the thread rooted at this message.
+	LastReplyTimestamp int64 `json:"last_reply_timestamp,omitempty"`
+}
+
+type MessagePreviewResponse struct {
+	// The unique id of the message.
+	Id string `json:"id"`
+
+	// The unique username of the message author. Empty if the message was deleted.
+	UserId string `json:"user_id,omitempty"`
+
+	// The start of the content of the message. Empty if the message was deleted.
+	Content string `json:"content,omitempty"`
 }
 
 // handleMessageCreate creates a message
 //
-//	@Summary	Create a message
-//	@Tags		messages
-//	@Accept		json
-//	@Produce	json
-//	@Param		id		path	string					true	"room id to create message in"
-//	@Param		content	body	MessageCreateRequest	true	"content to create message with"
-//	@Security	ApiKey
-//	@Success	200
-//	@Failure	400
-//	@Failure	401
-//	@Failure	500
-//	@Router		/rooms/{id}/messages [post]
+//	@Summary		Create a message
+//	@Description	Users banned or muted in the room can't post in it.
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int						true	"room id to create message in"
+//	@Param			content	body	MessageCreateRequest	true	"content to create message with"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		403
+//	@Failure		500
+//	@Router			/rooms/{id}/messages [post]
 func (s *Server) handleMessageCreate() http.HandlerFunc {
 	logger := slog.New(s.logHandler).With(slog.String("handler", "MessageCreate"))
 
@@ -54,7 +96,8 @@ func (s *Server) handleMessageCreate() http.HandlerFunc {
 		}
 
 		// Verify the room exists
-		if _, err = s.RoomService.GetRoomById(r.Context(), room.GetRoomByIdOpts{Id: roomId}); err != nil {
+		gotRoom, err := s.RoomService.GetRoomById(r.Context(), room.GetRoomByIdOpts{Id: roomId})
+		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
@@ -80,13 +123,29 @@ func (s *Server) handleMessageCreate() http.HandlerFunc {
 		}
 		logAttrs = append(logAttrs, slog.String("user_id", userId))
 
+		if !gotRoom.CanRead(userId) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if now := s.Clock.Now().UnixMilli(); gotRoom.Banned(userId, now) || gotRoom.Muted(userId, now) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
 		opts := message.CreateMessageOpts{
 			UserId:  userId,
 			RoomId:  roomId,
 			Content: request.Content,
+			ReplyTo: request.ReplyTo,
 		}
 
 		if _, err = s.MessageService.Create(r.Context(), opts); err != nil {
+			if errors.Is(err, message.ErrReplyNotFound) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
 			logger.LogAttrs(r.Context(), slog.LevelError, "failed to create message", slog.String("error", err.Error()))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
@@ -104,9 +163,10 @@ func (s *Server) handleMessageCreate() http.HandlerFunc {
 //	@Tags		messages
 //	@Accept		json
 //	@Produce	json
-//	@Param		id	path	string	true	"room id to list messages from"
+//	@Param		id	path	int	true	"room id to list messages from"
 //	@Security	ApiKey
-//	@Success	200	{array}	MessageResponse
+//	@Success	200	{array}		MessageResponse
+//	@Failure	400	{object}	Error
 //	@Failure	401
 //	@Failure	404
 //	@Failure	500
@@ -122,6 +182,11 @@ func (s *Server) handleMessageList() http.HandlerFunc {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
+		gotRoom, err := s.RoomService.GetRoomById(r.Context(), room.GetRoomByIdOpts{Id: roomId})
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
 		logAttrs = append(logAttrs, slog.Int64("room_id", roomId))
 
 		// Verify the user exists
@@ -138,19 +203,22 @@ func (s *Server) handleMessageList() http.HandlerFunc {
 		}
 		logAttrs = append(logAttrs, slog.String("user_id", userId))
 
+		if !gotRoom.CanRead(userId) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
 		messages, err := s.MessageService.List(r.Context(), message.ListMessageOpts{RoomId: roomId})
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		response := make([]MessageResponse, 0)
-		for _, msg := range messages {
-			response = append(response, MessageResponse{
-				UserId:    msg.UserId,
-				Content:   msg.Content,
-				Timestamp: msg.Timestamp,
-			})
+		response, err := s.messageResponses(r, messages, userId)
+		if err != nil {
+			logger.LogAttrs(r.Context(), slog.LevelError, "failed to build message responses", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -164,3 +232,238 @@ func (s *Server) handleMessageList() http.HandlerFunc {
 		return
 	}
 }
+
+// handleMessageThread returns the replies in the thread rooted at a message
+//
+//	@Summary		List a thread
+//	@Description	Replies are listed oldest first. The Link header has a rel="next" link to the next page, if there is one.
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int		true	"room id the message is in"
+//	@Param			messageId	path	string	true	"message id at the root of the thread"
+//	@Param			limit		query	int		false	"most replies to return"	minimum(1)	maximum(100)	default(50)
+//	@Param			after		query	string	false	"cursor of the last reply on the previous page"
+//	@Security		ApiKey
+//	@Success		200	{array}		MessageResponse
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/messages/{messageId}/thread [get]
+func (s *Server) handleMessageThread() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "MessageThread"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		page, err := parsePage(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		_, root, ok := s.lookupRoomMessage(w, r)
+		if !ok {
+			return
+		}
+
+		// One extra reply is listed to find out whether there's another page.
+		opts := message.ListMessageOpts{ThreadId: root.Id, After: page.after, Limit: page.limit + 1}
+
+		replies, err := s.MessageService.List(r.Context(), opts)
+		if err != nil {
+			logger.Error("failed to list thread", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if len(replies) > page.limit {
+			replies = replies[:page.limit]
+			w.Header().Set("Link", nextPageLink(r, replies[len(replies)-1].Cursor()))
+		}
+
+		response, err := s.messageResponses(r, replies, userId)
+		if err != nil {
+			logger.Error("failed to build message responses", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode json response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		return
+	}
+}
+
+// handleMessageDelete deletes a message
+//
+//	@Summary		Deletes a message
+//	@Description	Only the author of the message or an admin of the room may delete it. Its reactions are deleted with it.
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int		true	"room id the message is in"
+//	@Param			messageId	path	string	true	"message id to delete"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/messages/{messageId} [delete]
+func (s *Server) handleMessageDelete() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "MessageDelete"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		gotRoom, msg, ok := s.lookupRoomMessage(w, r)
+		if !ok {
+			return
+		}
+
+		if msg.UserId != userId && !slices.Contains(gotRoom.Admins, userId) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := s.MessageService.Delete(r.Context(), message.DeleteMessageOpts{Id: msg.Id}); err != nil {
+			if errors.Is(err, message.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			logger.Error("failed to delete message", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.audit(r, audit.ActionMessageDelete, msg.Id)
+
+		if err := s.ReactionService.Clear(r.Context(), reaction.ClearReactionOpts{MessageIds: []string{msg.Id}}); err != nil {
+			logger.Error("failed to clear reactions of deleted message", slog.String("message_id", msg.Id), slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := s.PinService.Remove(r.Context(), pin.RemovePinOpts{MessageId: msg.Id}); err != nil && !errors.Is(err, pin.ErrNotFound) {
+			logger.Error("failed to unpin deleted message", slog.String("message_id", msg.Id), slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		logger.Info("message deleted", slog.String("message_id", msg.Id), slog.Int64("room_id", msg.RoomId))
+
+		return
+	}
+}
+
+// lookupRoomMessage returns the room in the id path value and the message in the messageId path value. A missing room
+// or a message that isn't in the room is written as a 404, a room the user can't read as a 401, and ok is false.
+func (s *Server) lookupRoomMessage(w http.ResponseWriter, r *http.Request) (*room.Room, *message.Message, bool) {
+	roomId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	gotRoom, err := s.RoomService.GetRoomById(r.Context(), room.GetRoomByIdOpts{Id: roomId})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	userId, _ := r.Context().Value("userID").(string)
+	if !gotRoom.CanRead(userId) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	msg, err := s.MessageService.GetMessageById(r.Context(), message.GetMessageByIdOpts{Id: r.PathValue("messageId")})
+	if errors.Is(err, message.ErrNotFound) || (err == nil && msg.RoomId != roomId) {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, nil, false
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	return gotRoom, msg, true
+}
+
+// messageResponses converts messages into responses for userId, with their reactions and reply previews.
+func (s *Server) messageResponses(r *http.Request, messages []*message.Message, userId string) ([]MessageResponse, error) {
+	reactions, err := s.messageReactions(r, messages, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replies are usually to recent messages, so most previews come from the messages being returned.
+	byId := make(map[string]*message.Message, len(messages))
+	for _, msg := range messages {
+		byId[msg.Id] = msg
+	}
+
+	response := make([]MessageResponse, 0, len(messages))
+	for _, msg := range messages {
+		var preview *MessagePreviewResponse
+		if msg.ReplyTo != "" {
+			if preview, err = s.messagePreview(r, byId, msg.ReplyTo); err != nil {
+				return nil, err
+			}
+		}
+
+		response = append(response, MessageResponse{
+			Id:                 msg.Id,
+			UserId:             msg.UserId,
+			Content:            msg.Content,
+			Timestamp:          msg.Timestamp,
+			Reactions:          reactions[msg.Id],
+			ReplyTo:            preview,
+			ThreadId:           msg.ThreadId,
+			ReplyCount:         msg.ReplyCount,
+			LastReplyTimestamp: msg.LastReplyTimestamp,
+		})
+	}
+
+	return response, nil
+}
+
+// messagePreview previews the message with id, looking it up if it isn't in known. A deleted message is previewed by
+// its id alone.
+func (s *Server) messagePreview(r *http.Request, known map[string]*message.Message, id string) (*MessagePreviewResponse, error) {
+	msg, ok := known[id]
+	if !ok {
+		var err error
+		if msg, err = s.MessageService.GetMessageById(r.Context(), message.GetMessageByIdOpts{Id: id}); errors.Is(err, message.ErrNotFound) {
+			return &MessagePreviewResponse{Id: id}, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		known[id] = msg
+	}
+
+	content := msg.Content
+	if runes := []rune(content); len(runes) > previewLength {
+		content = string(runes[:previewLength])
+	}
+
+	return &MessagePreviewResponse{Id: msg.Id, UserId: msg.UserId, Content: content}, nil
+}
@@ -0,0 +1,696 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/worsediscord/server/services/fake"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/util"
+)
+
+func TestServer_HandleMessageCreate(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	validRequest := MessageCreateRequest{Content: "pizza time"}
+	existingRoom := &room.Room{Id: 1, Name: "the big apple"}
+	existingUser := &user.User{Username: "spiderman"}
+
+	tests := map[string]struct {
+		id             string
+		body           any
+		userId         string
+		roomService    *fake.RoomService
+		userService    *fake.UserService
+		messageService *fake.MessageService
+		expectedStatus int
+		expectedCalls  []message.CreateMessageOpts
+	}{
+		"valid": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{ExpectedCreateMessage: &message.Message{Id: "1"}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []message.CreateMessageOpts{{UserId: "spiderman", RoomId: 1, Content: "pizza time"}},
+		},
+		"reply": {
+			id:             "1",
+			body:           MessageCreateRequest{Content: "pizza time", ReplyTo: "a"},
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{ExpectedCreateMessage: &message.Message{Id: "1"}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []message.CreateMessageOpts{{UserId: "spiderman", RoomId: 1, Content: "pizza time", ReplyTo: "a"}},
+		},
+		"reply not found": {
+			id:             "1",
+			body:           MessageCreateRequest{Content: "pizza time", ReplyTo: "z"},
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{ExpectedCreateError: message.ErrReplyNotFound},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []message.CreateMessageOpts{{UserId: "spiderman", RoomId: 1, Content: "pizza time", ReplyTo: "z"}},
+		},
+		"direct message outsider": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Type: room.TypeDirect, Users: []string{"mj", "venom"}}},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"banned": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Name: "the big apple", Bans: []room.Restriction{{UserId: "spiderman"}}}},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusForbidden,
+		},
+		"muted": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Name: "the big apple", Mutes: []room.Restriction{{UserId: "spiderman"}}}},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusForbidden,
+		},
+		"mute expired": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Name: "the big apple", Mutes: []room.Restriction{{UserId: "spiderman", ExpiresAt: 1}}}},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{ExpectedCreateMessage: &message.Message{Id: "1"}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []message.CreateMessageOpts{{UserId: "spiderman", RoomId: 1, Content: "pizza time"}},
+		},
+		"room not found": {
+			id:             "2",
+			body:           validRequest,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"invalid id": {
+			id:             "queens",
+			body:           validRequest,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			userService:    &fake.UserService{},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"invalid body": {
+			id:             "1",
+			body:           "pizza time",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"user not found": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			userService:    &fake.UserService{ExpectedGetUserByIdError: user.ErrNotFound},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"unauthenticated": {
+			id:             "1",
+			body:           validRequest,
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			userService:    &fake.UserService{},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"service error": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{ExpectedCreateError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []message.CreateMessageOpts{{UserId: "spiderman", RoomId: 1, Content: "pizza time"}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+			s.UserService = input.userService
+			s.MessageService = input.messageService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/api/rooms/"+input.id+"/messages", util.StructToReaderOrDie(input.body))
+			request.SetPathValue("id", input.id)
+			s.handleMessageCreate()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.messageService.CreateCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.messageService.CreateCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleMessageList(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	existingUser := &user.User{Username: "spiderman"}
+	existingRoom := &room.Room{Id: 1, Name: "the big apple"}
+
+	tests := map[string]struct {
+		id               string
+		userId           string
+		userService      *fake.UserService
+		roomService      *fake.RoomService
+		messageService   *fake.MessageService
+		reactionService  *fake.ReactionService
+		expectedStatus   int
+		expectedCalls    []message.ListMessageOpts
+		expectedResponse []MessageResponse
+	}{
+		"valid": {
+			id:          "1",
+			userId:      "spiderman",
+			userService: &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{ExpectedListMessages: []*message.Message{
+				{Id: "a", UserId: "spiderman", RoomId: 1, Content: "pizza time", Timestamp: 1000},
+				{Id: "b", UserId: "venom", RoomId: 1, Content: "we are venom", Timestamp: 2000},
+			}},
+			reactionService: &fake.ReactionService{ExpectedListReactions: []*reaction.Reaction{
+				{MessageId: "a", UserId: "venom", Emoji: "🍕", Timestamp: 3000},
+				{MessageId: "a", UserId: "venom", Emoji: "🕷️", Timestamp: 4000},
+				{MessageId: "a", UserId: "spiderman", Emoji: "🍕", Timestamp: 5000},
+				{MessageId: "b", UserId: "venom", Emoji: "🕷️", Timestamp: 6000},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []message.ListMessageOpts{{RoomId: 1}},
+			expectedResponse: []MessageResponse{
+				{Id: "a", UserId: "spiderman", Content: "pizza time", Timestamp: 1000, Reactions: []ReactionCountResponse{
+					{Emoji: "🍕", Count: 2, Reacted: true},
+					{Emoji: "🕷️", Count: 1, Reacted: false},
+				}},
+				{Id: "b", UserId: "venom", Content: "we are venom", Timestamp: 2000, Reactions: []ReactionCountResponse{
+					{Emoji: "🕷️", Count: 1, Reacted: false},
+				}},
+			},
+		},
+		"no reactions": {
+			id:          "1",
+			userId:      "spiderman",
+			userService: &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{ExpectedListMessages: []*message.Message{
+				{Id: "a", UserId: "spiderman", RoomId: 1, Content: "pizza time", Timestamp: 1000},
+			}},
+			reactionService:  &fake.ReactionService{},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []message.ListMessageOpts{{RoomId: 1}},
+			expectedResponse: []MessageResponse{{Id: "a", UserId: "spiderman", Content: "pizza time", Timestamp: 1000}},
+		},
+		"replies": {
+			id:          "1",
+			userId:      "spiderman",
+			userService: &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{ExpectedListMessages: []*message.Message{
+				{Id: "a", UserId: "spiderman", RoomId: 1, Content: "pizza time", Timestamp: 1000, ReplyCount: 1, LastReplyTimestamp: 2000},
+				{Id: "b", UserId: "venom", RoomId: 1, Content: "we are venom", Timestamp: 2000, ReplyTo: "a", ThreadId: "a"},
+			}},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusOK,
+			expectedCalls:   []message.ListMessageOpts{{RoomId: 1}},
+			expectedResponse: []MessageResponse{
+				{Id: "a", UserId: "spiderman", Content: "pizza time", Timestamp: 1000, ReplyCount: 1, LastReplyTimestamp: 2000},
+				{Id: "b", UserId: "venom", Content: "we are venom", Timestamp: 2000, ThreadId: "a", ReplyTo: &MessagePreviewResponse{
+					Id: "a", UserId: "spiderman", Content: "pizza time",
+				}},
+			},
+		},
+		"reply to earlier message": {
+			id:          "1",
+			userId:      "spiderman",
+			userService: &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{
+				ExpectedListMessages: []*message.Message{
+					{Id: "b", UserId: "venom", RoomId: 1, Content: "we are venom", Timestamp: 2000, ReplyTo: "a", ThreadId: "a"},
+				},
+				ExpectedGetMessageByIdMessage: &message.Message{Id: "a", UserId: "spiderman", RoomId: 1, Content: strings.Repeat("🍕", 120)},
+			},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusOK,
+			expectedCalls:   []message.ListMessageOpts{{RoomId: 1}},
+			expectedResponse: []MessageResponse{
+				{Id: "b", UserId: "venom", Content: "we are venom", Timestamp: 2000, ThreadId: "a", ReplyTo: &MessagePreviewResponse{
+					Id: "a", UserId: "spiderman", Content: strings.Repeat("🍕", previewLength),
+				}},
+			},
+		},
+		"reply to deleted message": {
+			id:          "1",
+			userId:      "spiderman",
+			userService: &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{
+				ExpectedListMessages: []*message.Message{
+					{Id: "b", UserId: "venom", RoomId: 1, Content: "we are venom", Timestamp: 2000, ReplyTo: "a", ThreadId: "a"},
+				},
+				ExpectedGetMessageByIdError: message.ErrNotFound,
+			},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusOK,
+			expectedCalls:   []message.ListMessageOpts{{RoomId: 1}},
+			expectedResponse: []MessageResponse{
+				{Id: "b", UserId: "venom", Content: "we are venom", Timestamp: 2000, ThreadId: "a", ReplyTo: &MessagePreviewResponse{Id: "a"}},
+			},
+		},
+		"reaction service error": {
+			id:          "1",
+			userId:      "spiderman",
+			userService: &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{ExpectedListMessages: []*message.Message{
+				{Id: "a", UserId: "spiderman", RoomId: 1, Content: "pizza time", Timestamp: 1000},
+			}},
+			reactionService: &fake.ReactionService{ExpectedListError: errors.New("oops")},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedCalls:   []message.ListMessageOpts{{RoomId: 1}},
+		},
+		"empty": {
+			id:               "1",
+			userId:           "spiderman",
+			userService:      &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService:   &fake.MessageService{ExpectedListMessages: []*message.Message{}},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []message.ListMessageOpts{{RoomId: 1}},
+			expectedResponse: []MessageResponse{},
+		},
+		"invalid id": {
+			id:             "queens",
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"room not found": {
+			id:             "1",
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"direct message": {
+			id:               "1",
+			userId:           "spiderman",
+			userService:      &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Type: room.TypeDirect, Users: []string{"spiderman", "venom"}}},
+			messageService:   &fake.MessageService{ExpectedListMessages: []*message.Message{}},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []message.ListMessageOpts{{RoomId: 1}},
+			expectedResponse: []MessageResponse{},
+		},
+		"direct message outsider": {
+			id:             "1",
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Type: room.TypeDirect, Users: []string{"mj", "venom"}}},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"user not found": {
+			id:             "1",
+			userId:         "venom",
+			userService:    &fake.UserService{ExpectedGetUserByIdError: user.ErrNotFound},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"unauthenticated": {
+			id:             "1",
+			userService:    &fake.UserService{},
+			messageService: &fake.MessageService{},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		"service error": {
+			id:             "1",
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: existingUser},
+			messageService: &fake.MessageService{ExpectedListError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []message.ListMessageOpts{{RoomId: 1}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.UserService = input.userService
+			s.RoomService = input.roomService
+			if input.roomService == nil {
+				s.RoomService = &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom}
+			}
+			s.MessageService = input.messageService
+			s.ReactionService = input.reactionService
+			if input.reactionService == nil {
+				s.ReactionService = &fake.ReactionService{}
+			}
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/api/rooms/"+input.id+"/messages", nil)
+			request.SetPathValue("id", input.id)
+			s.handleMessageList()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.messageService.ListCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.messageService.ListCalls, input.expectedCalls)
+			}
+
+			if input.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response []MessageResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(response, input.expectedResponse) {
+				t.Fatalf("got messages %v, expected %v", response, input.expectedResponse)
+			}
+		})
+	}
+}
+
+func TestServer_HandleMessageDelete(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	existingRoom := &room.Room{Id: 1, Name: "the big apple", Users: []string{"spiderman", "venom", "mj"}, Admins: []string{"mj"}}
+	existingMessage := &message.Message{Id: "a", UserId: "spiderman", RoomId: 1, Content: "pizza time"}
+
+	tests := map[string]struct {
+		id                  string
+		messageId           string
+		userId              string
+		roomService         *fake.RoomService
+		messageService      *fake.MessageService
+		reactionService     *fake.ReactionService
+		expectedStatus      int
+		expectedDeleteCalls []message.DeleteMessageOpts
+		expectedClearCalls  []reaction.ClearReactionOpts
+		expectedUnpinCalls  []pin.RemovePinOpts
+	}{
+		"author": {
+			id:                  "1",
+			messageId:           "a",
+			userId:              "spiderman",
+			roomService:         &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:      &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService:     &fake.ReactionService{},
+			expectedStatus:      http.StatusOK,
+			expectedDeleteCalls: []message.DeleteMessageOpts{{Id: "a"}},
+			expectedClearCalls:  []reaction.ClearReactionOpts{{MessageIds: []string{"a"}}},
+			expectedUnpinCalls:  []pin.RemovePinOpts{{MessageId: "a"}},
+		},
+		"room admin": {
+			id:                  "1",
+			messageId:           "a",
+			userId:              "mj",
+			roomService:         &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:      &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService:     &fake.ReactionService{},
+			expectedStatus:      http.StatusOK,
+			expectedDeleteCalls: []message.DeleteMessageOpts{{Id: "a"}},
+			expectedClearCalls:  []reaction.ClearReactionOpts{{MessageIds: []string{"a"}}},
+			expectedUnpinCalls:  []pin.RemovePinOpts{{MessageId: "a"}},
+		},
+		"not author": {
+			id:              "1",
+			messageId:       "a",
+			userId:          "venom",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusUnauthorized,
+		},
+		"room not found": {
+			id:              "2",
+			messageId:       "a",
+			userId:          "spiderman",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			messageService:  &fake.MessageService{},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusNotFound,
+		},
+		"message not found": {
+			id:              "1",
+			messageId:       "b",
+			userId:          "spiderman",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdError: message.ErrNotFound},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusNotFound,
+		},
+		"message in another room": {
+			id:              "1",
+			messageId:       "a",
+			userId:          "spiderman",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: &message.Message{Id: "a", UserId: "spiderman", RoomId: 2}},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusNotFound,
+		},
+		"unauthenticated": {
+			id:              "1",
+			messageId:       "a",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusUnauthorized,
+		},
+		"service error": {
+			id:                  "1",
+			messageId:           "a",
+			userId:              "spiderman",
+			roomService:         &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:      &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage, ExpectedDeleteError: errors.New("oops")},
+			reactionService:     &fake.ReactionService{},
+			expectedStatus:      http.StatusInternalServerError,
+			expectedDeleteCalls: []message.DeleteMessageOpts{{Id: "a"}},
+		},
+		"reaction service error": {
+			id:                  "1",
+			messageId:           "a",
+			userId:              "spiderman",
+			roomService:         &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:      &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService:     &fake.ReactionService{ExpectedClearError: errors.New("oops")},
+			expectedStatus:      http.StatusInternalServerError,
+			expectedDeleteCalls: []message.DeleteMessageOpts{{Id: "a"}},
+			expectedClearCalls:  []reaction.ClearReactionOpts{{MessageIds: []string{"a"}}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+			s.MessageService = input.messageService
+			s.ReactionService = input.reactionService
+
+			pins := &fake.PinService{ExpectedRemoveError: pin.ErrNotFound}
+			s.PinService = pins
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodDelete, "/api/rooms/"+input.id+"/messages/"+input.messageId, nil)
+			request.SetPathValue("id", input.id)
+			request.SetPathValue("messageId", input.messageId)
+			s.handleMessageDelete()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.messageService.DeleteCalls, input.expectedDeleteCalls) {
+				t.Fatalf("got delete calls %v, expected %v", input.messageService.DeleteCalls, input.expectedDeleteCalls)
+			}
+
+			if !reflect.DeepEqual(input.reactionService.ClearCalls, input.expectedClearCalls) {
+				t.Fatalf("got clear calls %v, expected %v", input.reactionService.ClearCalls, input.expectedClearCalls)
+			}
+
+			if !reflect.DeepEqual(pins.RemoveCalls, input.expectedUnpinCalls) {
+				t.Fatalf("got unpin calls %v, expected %v", pins.RemoveCalls, input.expectedUnpinCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleMessageThread(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	existingRoom := &room.Room{Id: 1, Name: "the big apple", Users: []string{"spiderman", "venom"}}
+	root := &message.Message{Id: "a", UserId: "spiderman", RoomId: 1, Content: "pizza time", ReplyCount: 3, LastReplyTimestamp: 4000}
+	replies := []*message.Message{
+		{Id: "b", UserId: "venom", RoomId: 1, Content: "we are venom", Timestamp: 2000, ReplyTo: "a", ThreadId: "a"},
+		{Id: "c", UserId: "spiderman", RoomId: 1, Content: "no", Timestamp: 3000, ReplyTo: "b", ThreadId: "a"},
+		{Id: "d", UserId: "venom", RoomId: 1, Content: "yes", Timestamp: 4000, ReplyTo: "a", ThreadId: "a"},
+	}
+
+	tests := map[string]struct {
+		id               string
+		messageId        string
+		query            string
+		userId           string
+		roomService      *fake.RoomService
+		messageService   *fake.MessageService
+		expectedStatus   int
+		expectedCalls    []message.ListMessageOpts
+		expectedLink     string
+		expectedResponse []MessageResponse
+	}{
+		"valid": {
+			id:             "1",
+			messageId:      "a",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: root, ExpectedListMessages: replies},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []message.ListMessageOpts{{ThreadId: "a", Limit: defaultPageSize + 1}},
+			expectedResponse: []MessageResponse{
+				{Id: "b", UserId: "venom", Content: "we are venom", Timestamp: 2000, ThreadId: "a", ReplyTo: &MessagePreviewResponse{Id: "a", UserId: "spiderman", Content: "pizza time"}},
+				{Id: "c", UserId: "spiderman", Content: "no", Timestamp: 3000, ThreadId: "a", ReplyTo: &MessagePreviewResponse{Id: "b", UserId: "venom", Content: "we are venom"}},
+				{Id: "d", UserId: "venom", Content: "yes", Timestamp: 4000, ThreadId: "a", ReplyTo: &MessagePreviewResponse{Id: "a", UserId: "spiderman", Content: "pizza time"}},
+			},
+		},
+		"first page": {
+			id:             "1",
+			messageId:      "a",
+			query:          "?limit=2",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: root, ExpectedListMessages: replies},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []message.ListMessageOpts{{ThreadId: "a", Limit: 3}},
+			expectedLink:   `</api/rooms/1/messages/a/thread?after=3000-c&limit=2>; rel="next"`,
+			expectedResponse: []MessageResponse{
+				{Id: "b", UserId: "venom", Content: "we are venom", Timestamp: 2000, ThreadId: "a", ReplyTo: &MessagePreviewResponse{Id: "a", UserId: "spiderman", Content: "pizza time"}},
+				{Id: "c", UserId: "spiderman", Content: "no", Timestamp: 3000, ThreadId: "a", ReplyTo: &MessagePreviewResponse{Id: "b", UserId: "venom", Content: "we are venom"}},
+			},
+		},
+		"last page": {
+			id:             "1",
+			messageId:      "a",
+			query:          "?limit=2&after=3000-c",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: root, ExpectedListMessages: replies[2:]},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []message.ListMessageOpts{{ThreadId: "a", After: message.Cursor{Timestamp: 3000, Id: "c"}, Limit: 3}},
+			expectedResponse: []MessageResponse{
+				{Id: "d", UserId: "venom", Content: "yes", Timestamp: 4000, ThreadId: "a", ReplyTo: &MessagePreviewResponse{Id: "a", UserId: "spiderman", Content: "pizza time"}},
+			},
+		},
+		"invalid cursor": {
+			id:             "1",
+			messageId:      "a",
+			query:          "?after=queens",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: root},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"invalid limit": {
+			id:             "1",
+			messageId:      "a",
+			query:          "?limit=0",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: root},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"message not found": {
+			id:             "1",
+			messageId:      "z",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdError: message.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"unauthenticated": {
+			id:             "1",
+			messageId:      "a",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: root},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			id:             "1",
+			messageId:      "a",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: root, ExpectedListError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []message.ListMessageOpts{{ThreadId: "a", Limit: defaultPageSize + 1}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+			s.MessageService = input.messageService
+			s.ReactionService = &fake.ReactionService{}
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/api/rooms/"+input.id+"/messages/"+input.messageId+"/thread"+input.query, nil)
+			request.SetPathValue("id", input.id)
+			request.SetPathValue("messageId", input.messageId)
+			s.handleMessageThread()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.messageService.ListCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.messageService.ListCalls, input.expectedCalls)
+			}
+
+			if link := recorder.Header().Get("Link"); link != input.expectedLink {
+				t.Fatalf("got link %q, expected %q", link, input.expectedLink)
+			}
+
+			if input.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response []MessageResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(response, input.expectedResponse) {
+				t.Fatalf("got messages %v, expected %v", response, input.expectedResponse)
+			}
+		})
+	}
+}
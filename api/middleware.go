@@ -8,10 +8,17 @@ import (
 	"time"
 
 	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/util"
 )
 
 type Middleware func(http.Handler) http.Handler
 
+// InvalidSessionChallenge is sent in the WWW-Authenticate header when a request is rejected because its session is
+// missing, expired or revoked, rather than because the user isn't allowed to do what they asked. Clients can tell from
+// it whether logging in again would help.
+const InvalidSessionChallenge = `ApiKey error="invalid_token"`
+
 // writeWrapper implements http.ResponseWriter and records a few extra data points.
 type writeWrapper struct {
 	statusCode   int
@@ -28,10 +35,7 @@ func RequestLoggerMiddleware(logHandler slog.Handler, level slog.Level) func(nex
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ww := &writeWrapper{w: w}
 
-			remoteAddr := r.RemoteAddr
-			if v := r.Header.Get("CF-Connecting-IP"); v != "" {
-				remoteAddr = v
-			}
+			remoteAddr := ClientIP(r)
 
 			startTime := time.Now()
 			defer func() {
@@ -49,7 +53,9 @@ func RequestLoggerMiddleware(logHandler slog.Handler, level slog.Level) func(nex
 	}
 }
 
-func SessionAuthMiddleware(logHandler slog.Handler, authService auth.Service) func(next http.Handler) http.Handler {
+// SessionAuthMiddleware rejects requests without an unexpired x-api-key, as judged by clock, and requests from users
+// that have since been deleted or disabled. The key's payload is stored in the request context as the user ID.
+func SessionAuthMiddleware(logHandler slog.Handler, authService auth.Service, userService user.Service, clock util.Clock) func(next http.Handler) http.Handler {
 	logger := slog.New(logHandler).With(slog.String("method", "SessionAuthMiddleware"))
 
 	return func(next http.Handler) http.Handler {
@@ -58,24 +64,70 @@ func SessionAuthMiddleware(logHandler slog.Handler, authService auth.Service) fu
 
 			token := r.Header.Get("x-api-key")
 			if token == "" {
-				w.WriteHeader(http.StatusUnauthorized)
+				rejectSession(w)
 				return
 			}
 
 			key, err := authService.RetrieveKey(token)
-			if err != nil || time.Now().After(key.ExpiresAt()) {
+			if err != nil || clock.Now().After(key.ExpiresAt()) {
 				logger.Error("invalid token submitted", slog.String("path", r.URL.Path))
-				w.WriteHeader(http.StatusUnauthorized)
+				rejectSession(w)
+				return
+			}
+
+			userId, _ := key.Payload().(string)
+
+			u, err := userService.GetUserById(ctx, user.GetUserByIdOpts{Id: userId})
+			if err != nil {
+				logger.Error("token submitted for unknown user", slog.String("user_id", userId), slog.String("path", r.URL.Path))
+				rejectSession(w)
+				return
+			}
+
+			if u.Disabled {
+				logger.Warn("disabled user attempted request", slog.String("user_id", u.Username), slog.String("path", r.URL.Path))
+				w.WriteHeader(http.StatusForbidden)
 				return
 			}
 
-			ctx = context.WithValue(ctx, "userID", key.Payload())
+			ctx = context.WithValue(ctx, "userID", userId)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// rejectSession responds that the request's session isn't valid.
+func rejectSession(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", InvalidSessionChallenge)
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+// AdminAuthMiddleware rejects requests from users that aren't server administrators. It expects to be wrapped by
+// SessionAuthMiddleware.
+func AdminAuthMiddleware(logHandler slog.Handler, userService user.Service) func(next http.Handler) http.Handler {
+	logger := slog.New(logHandler).With(slog.String("method", "AdminAuthMiddleware"))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userId, ok := r.Context().Value("userID").(string)
+			if !ok {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			u, err := userService.GetUserById(r.Context(), user.GetUserByIdOpts{Id: userId})
+			if err != nil || u.Disabled || !u.Admin {
+				logger.Warn("non-admin attempted admin action", slog.String("user_id", userId), slog.String("path", r.URL.Path))
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (w *writeWrapper) Header() http.Header {
 	return w.w.Header()
 }
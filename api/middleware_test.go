@@ -0,0 +1,183 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/fake"
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/util"
+)
+
+func TestSessionAuthMiddleware(t *testing.T) {
+	now := time.UnixMilli(1_000_000)
+	clock := util.ClockFunc(func() time.Time { return now })
+	key := auth.NewApiKeyExpiringAt(24, now.Add(time.Hour), "venom")
+
+	tests := map[string]struct {
+		token          string
+		authService    *fake.AuthService
+		userService    *fake.UserService
+		expectedStatus int
+	}{
+		"valid": {
+			token:          "token",
+			authService:    &fake.AuthService{ExpectedRetrieveKeyApiKey: key},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			expectedStatus: http.StatusOK,
+		},
+		"no token": {
+			authService:    &fake.AuthService{ExpectedRetrieveKeyApiKey: key},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"unknown token": {
+			token:          "token",
+			authService:    &fake.AuthService{ExpectedRetrieveKeyError: auth.ErrNotFound},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"expired": {
+			token:          "token",
+			authService:    &fake.AuthService{ExpectedRetrieveKeyApiKey: auth.NewApiKeyExpiringAt(24, now.Add(-time.Second), "venom")},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"deleted user": {
+			token:          "token",
+			authService:    &fake.AuthService{ExpectedRetrieveKeyApiKey: key},
+			userService:    &fake.UserService{ExpectedGetUserByIdError: user.ErrNotFound},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"disabled user": {
+			token:          "token",
+			authService:    &fake.AuthService{ExpectedRetrieveKeyApiKey: key},
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom", Disabled: true}},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			var gotUserId any
+			h := SessionAuthMiddleware(util.NopLogHandler, input.authService, input.userService, clock)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserId = r.Context().Value("userID")
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, "/api/rooms", nil)
+			if input.token != "" {
+				request.Header.Set("x-api-key", input.token)
+			}
+
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, request)
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			// Only rejected sessions tell the client to log in again.
+			challenge := recorder.Header().Get("WWW-Authenticate")
+			if (input.expectedStatus == http.StatusUnauthorized) != (challenge == InvalidSessionChallenge) {
+				t.Fatalf("got WWW-Authenticate %q with status %d", challenge, recorder.Code)
+			}
+
+			if input.expectedStatus == http.StatusOK && gotUserId != "venom" {
+				t.Fatalf("got user id %v, expected venom", gotUserId)
+			}
+		})
+	}
+}
+
+func TestClientIPMiddleware(t *testing.T) {
+	trustedProxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		remoteAddr string
+		headers    map[string][]string
+		expectedIP string
+	}{
+		"no headers": {
+			remoteAddr: "203.0.113.7:4321",
+			expectedIP: "203.0.113.7",
+		},
+		"untrusted peer": {
+			remoteAddr: "203.0.113.7:4321",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}, "CF-Connecting-IP": {"198.51.100.2"}},
+			expectedIP: "203.0.113.7",
+		},
+		"trusted peer": {
+			remoteAddr: "10.1.2.3:4321",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			expectedIP: "198.51.100.1",
+		},
+		"spoofed chain": {
+			remoteAddr: "10.1.2.3:4321",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.1.1.1, 198.51.100.1, 10.0.0.5"}},
+			expectedIP: "198.51.100.1",
+		},
+		"multiple header lines": {
+			remoteAddr: "192.168.1.1:4321",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.1.1.1", "198.51.100.1"}},
+			expectedIP: "198.51.100.1",
+		},
+		"all hops trusted": {
+			remoteAddr: "10.1.2.3:4321",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.0.0.9, 10.0.0.5"}},
+			expectedIP: "10.0.0.9",
+		},
+		"garbage hop": {
+			remoteAddr: "10.1.2.3:4321",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1, nonsense, 10.0.0.5"}},
+			expectedIP: "10.0.0.5",
+		},
+		"real ip": {
+			remoteAddr: "10.1.2.3:4321",
+			headers:    map[string][]string{"X-Real-IP": {"198.51.100.1"}},
+			expectedIP: "198.51.100.1",
+		},
+		"forwarded": {
+			remoteAddr: "10.1.2.3:4321",
+			headers:    map[string][]string{"Forwarded": {`for=1.1.1.1, for="[2001:db8::1]:8080";proto=https`}},
+			expectedIP: "2001:db8::1",
+		},
+		"header preference": {
+			remoteAddr: "10.1.2.3:4321",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}, "CF-Connecting-IP": {"198.51.100.2"}},
+			expectedIP: "198.51.100.1",
+		},
+		"ipv6 peer": {
+			remoteAddr: "[2001:db8::2]:4321",
+			expectedIP: "2001:db8::2",
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			var gotIP string
+			h := ClientIPMiddleware(trustedProxies, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotIP = ClientIP(r)
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+			request.RemoteAddr = input.remoteAddr
+			for k, values := range input.headers {
+				for _, v := range values {
+					request.Header.Add(k, v)
+				}
+			}
+
+			h.ServeHTTP(httptest.NewRecorder(), request)
+
+			if gotIP != input.expectedIP {
+				t.Fatalf("got ip %q, expected %q", gotIP, input.expectedIP)
+			}
+		})
+	}
+}
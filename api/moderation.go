@@ -0,0 +1,442 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/worsediscord/server/services/audit"
+	"github.com/worsediscord/server/services/room"
+)
+
+type RoomRestrictRequest struct {
+	// Why the user is being banned or muted.
+	Reason string `json:"reason,omitempty"`
+
+	// Seconds until the ban or mute expires. Zero means it never expires.
+	ExpiresIn int `json:"expires_in,omitempty" minimum:"0"`
+}
+
+type RestrictionResponse struct {
+	// The unique username of the banned or muted user.
+	Username string `json:"username"`
+
+	// Why the user was banned or muted. Omitted if no reason was given.
+	Reason string `json:"reason,omitempty"`
+
+	// The unique username of the admin that banned or muted the user.
+	CreatedBy string `json:"created_by"`
+
+	// Time since epoch in milliseconds that the user was banned or muted.
+	CreatedAt int64 `json:"created_at"`
+
+	// Time since epoch in milliseconds that the ban or mute expires. Omitted if it never expires.
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+}
+
+// handleRoomKick removes a member from a room
+//
+//	@Summary		Kick a room member
+//	@Description	Only room admins can kick members, and only the owner can kick other admins. The owner can't be
+//	@Description	kicked. Kicked users may join again.
+//	@Tags			rooms
+//	@Param			id			path	int		true	"id of the room"
+//	@Param			username	path	string	true	"member to kick"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/members/{username} [delete]
+func (s *Server) handleRoomKick() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomKick"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		opts := room.KickRoomOpts{Id: id, UserId: userId, TargetId: r.PathValue("username")}
+		if err = s.RoomService.Kick(r.Context(), opts); err != nil {
+			writeModerationError(w, logger, "failed to kick user", err)
+			return
+		}
+
+		s.audit(r, audit.ActionRoomKick, r.PathValue("id")+"/"+opts.TargetId)
+
+		return
+	}
+}
+
+// handleRoomBan bans a user from a room
+//
+//	@Summary		Ban a user from a room
+//	@Description	Removes the user from the room and keeps them from joining or posting until the ban expires. Only
+//	@Description	room admins can ban users, and only the owner can ban other admins. Banning a user again replaces
+//	@Description	their ban.
+//	@Tags			rooms
+//	@Accept			json
+//	@Param			id			path	int					true	"id of the room"
+//	@Param			username	path	string				true	"user to ban"
+//	@Param			ban			body	RoomRestrictRequest	false	"ban reason and duration"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/bans/{username} [put]
+func (s *Server) handleRoomBan() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomBan"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		request, now, expiresAt, ok := s.decodeRestriction(w, r)
+		if !ok {
+			return
+		}
+
+		opts := room.BanRoomOpts{
+			Id:        id,
+			UserId:    userId,
+			TargetId:  r.PathValue("username"),
+			Reason:    request.Reason,
+			Now:       now,
+			ExpiresAt: expiresAt,
+		}
+
+		if err = s.RoomService.Ban(r.Context(), opts); err != nil {
+			writeModerationError(w, logger, "failed to ban user", err)
+			return
+		}
+
+		s.audit(r, audit.ActionRoomBan, r.PathValue("id")+"/"+opts.TargetId)
+
+		return
+	}
+}
+
+// handleRoomUnban lifts the ban of a user from a room
+//
+//	@Summary		Unban a user from a room
+//	@Description	Only room admins can unban users. Unbanned users have to join the room again.
+//	@Tags			rooms
+//	@Param			id			path	int		true	"id of the room"
+//	@Param			username	path	string	true	"user to unban"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/bans/{username} [delete]
+func (s *Server) handleRoomUnban() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomUnban"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		opts := room.UnbanRoomOpts{Id: id, UserId: userId, TargetId: r.PathValue("username")}
+		if err = s.RoomService.Unban(r.Context(), opts); err != nil {
+			writeModerationError(w, logger, "failed to unban user", err)
+			return
+		}
+
+		s.audit(r, audit.ActionRoomUnban, r.PathValue("id")+"/"+opts.TargetId)
+
+		return
+	}
+}
+
+// handleRoomBanList lists the bans of a room
+//
+//	@Summary		List room bans
+//	@Description	Only room admins can list bans. Expired bans are left out.
+//	@Tags			rooms
+//	@Produce		json
+//	@Param			id	path	int	true	"id of the room"
+//	@Security		ApiKey
+//	@Success		200	{array}		RestrictionResponse
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/bans [get]
+func (s *Server) handleRoomBanList() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomBanList"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		gotRoom, ok := s.lookupModeratedRoom(w, r)
+		if !ok {
+			return
+		}
+
+		s.writeRestrictions(w, logger, gotRoom.Bans)
+
+		return
+	}
+}
+
+// handleRoomMute mutes a user in a room
+//
+//	@Summary		Mute a user in a room
+//	@Description	Keeps the user from posting in the room until the mute expires. They can still read it. Only room
+//	@Description	admins can mute users, and only the owner can mute other admins. Muting a user again replaces their
+//	@Description	mute.
+//	@Tags			rooms
+//	@Accept			json
+//	@Param			id			path	int					true	"id of the room"
+//	@Param			username	path	string				true	"user to mute"
+//	@Param			mute		body	RoomRestrictRequest	false	"mute reason and duration"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/mutes/{username} [put]
+func (s *Server) handleRoomMute() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomMute"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		request, now, expiresAt, ok := s.decodeRestriction(w, r)
+		if !ok {
+			return
+		}
+
+		opts := room.MuteRoomOpts{
+			Id:        id,
+			UserId:    userId,
+			TargetId:  r.PathValue("username"),
+			Reason:    request.Reason,
+			Now:       now,
+			ExpiresAt: expiresAt,
+		}
+
+		if err = s.RoomService.Mute(r.Context(), opts); err != nil {
+			writeModerationError(w, logger, "failed to mute user", err)
+			return
+		}
+
+		s.audit(r, audit.ActionRoomMute, r.PathValue("id")+"/"+opts.TargetId)
+
+		return
+	}
+}
+
+// handleRoomUnmute lifts the mute of a user in a room
+//
+//	@Summary		Unmute a user in a room
+//	@Description	Only room admins can unmute users.
+//	@Tags			rooms
+//	@Param			id			path	int		true	"id of the room"
+//	@Param			username	path	string	true	"user to unmute"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/mutes/{username} [delete]
+func (s *Server) handleRoomUnmute() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomUnmute"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		opts := room.UnmuteRoomOpts{Id: id, UserId: userId, TargetId: r.PathValue("username")}
+		if err = s.RoomService.Unmute(r.Context(), opts); err != nil {
+			writeModerationError(w, logger, "failed to unmute user", err)
+			return
+		}
+
+		s.audit(r, audit.ActionRoomUnmute, r.PathValue("id")+"/"+opts.TargetId)
+
+		return
+	}
+}
+
+// handleRoomMuteList lists the mutes of a room
+//
+//	@Summary		List room mutes
+//	@Description	Only room admins can list mutes. Expired mutes are left out.
+//	@Tags			rooms
+//	@Produce		json
+//	@Param			id	path	int	true	"id of the room"
+//	@Security		ApiKey
+//	@Success		200	{array}		RestrictionResponse
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/mutes [get]
+func (s *Server) handleRoomMuteList() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomMuteList"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		gotRoom, ok := s.lookupModeratedRoom(w, r)
+		if !ok {
+			return
+		}
+
+		s.writeRestrictions(w, logger, gotRoom.Mutes)
+
+		return
+	}
+}
+
+// decodeRestriction decodes the optional body of a ban or mute, and returns it along with the current time and when the
+// restriction expires, both in milliseconds since epoch. An invalid body is written as a 400 and ok is false.
+func (s *Server) decodeRestriction(w http.ResponseWriter, r *http.Request) (RoomRestrictRequest, int64, int64, bool) {
+	var request RoomRestrictRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		return request, 0, 0, false
+	}
+
+	if request.ExpiresIn < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return request, 0, 0, false
+	}
+
+	now := s.Clock.Now()
+
+	var expiresAt int64
+	if request.ExpiresIn > 0 {
+		expiresAt = now.Add(time.Duration(request.ExpiresIn) * time.Second).UnixMilli()
+	}
+
+	return request, now.UnixMilli(), expiresAt, true
+}
+
+// lookupModeratedRoom returns the room in the id path value if the user making the request is one of its admins. A
+// missing room is written as a 404, a user that isn't an admin as a 401, and ok is false.
+func (s *Server) lookupModeratedRoom(w http.ResponseWriter, r *http.Request) (*room.Room, bool) {
+	userId, ok := r.Context().Value("userID").(string)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil, false
+	}
+
+	roomId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, false
+	}
+
+	gotRoom, err := s.RoomService.GetRoomById(r.Context(), room.GetRoomByIdOpts{Id: roomId})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, false
+	}
+
+	if !gotRoom.CanModerate(userId) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil, false
+	}
+
+	return gotRoom, true
+}
+
+// writeRestrictions writes the restrictions that are still active as a json response.
+func (s *Server) writeRestrictions(w http.ResponseWriter, logger *slog.Logger, restrictions []room.Restriction) {
+	now := s.Clock.Now().UnixMilli()
+
+	response := make([]RestrictionResponse, 0, len(restrictions))
+	for _, restriction := range restrictions {
+		if !restriction.Active(now) {
+			continue
+		}
+
+		response = append(response, RestrictionResponse{
+			Username:  restriction.UserId,
+			Reason:    restriction.Reason,
+			CreatedBy: restriction.CreatedBy,
+			CreatedAt: restriction.CreatedAt,
+			ExpiresAt: restriction.ExpiresAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("failed to encode json response", slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// writeModerationError writes the status for an error returned by kicking, banning or muting a user.
+func writeModerationError(w http.ResponseWriter, logger *slog.Logger, msg string, err error) {
+	switch {
+	case errors.Is(err, room.ErrOwner), errors.Is(err, room.ErrDirect), errors.Is(err, room.ErrInvalidUntil):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, room.ErrUnauthorized):
+		w.WriteHeader(http.StatusUnauthorized)
+	case errors.Is(err, room.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		logger.Error(msg, slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/fake"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/util"
+)
+
+func TestServer_HandleRoomKick(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+
+	tests := map[string]struct {
+		userId         string
+		roomService    *fake.RoomService
+		expectedStatus int
+		expectedCalls  []room.KickRoomOpts
+	}{
+		"valid": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.KickRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom"}},
+		},
+		"not an admin": {
+			userId:         "mj",
+			roomService:    &fake.RoomService{ExpectedKickError: room.ErrUnauthorized},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  []room.KickRoomOpts{{Id: 1, UserId: "mj", TargetId: "venom"}},
+		},
+		"the owner": {
+			userId:         "mj",
+			roomService:    &fake.RoomService{ExpectedKickError: room.ErrOwner},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []room.KickRoomOpts{{Id: 1, UserId: "mj", TargetId: "venom"}},
+		},
+		"not found": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedKickError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCalls:  []room.KickRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom"}},
+		},
+		"unauthenticated": {
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedKickError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []room.KickRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom"}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodDelete, "/api/rooms/1/members/venom", nil)
+			request.SetPathValue("id", "1")
+			request.SetPathValue("username", "venom")
+			s.handleRoomKick()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.KickCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.KickCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleRoomBan(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	now := time.UnixMilli(1_000_000)
+	s.Clock = util.ClockFunc(func() time.Time { return now })
+
+	tests := map[string]struct {
+		body           any
+		userId         string
+		roomService    *fake.RoomService
+		expectedStatus int
+		expectedCalls  []room.BanRoomOpts
+	}{
+		"valid": {
+			body:           RoomRestrictRequest{Reason: "we are venom", ExpiresIn: 60},
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.BanRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Reason: "we are venom", Now: 1_000_000, ExpiresAt: 1_060_000}},
+		},
+		"no body": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.BanRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+		"negative expiry": {
+			body:           RoomRestrictRequest{ExpiresIn: -1},
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"invalid body": {
+			body:           "we are venom",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"not an admin": {
+			userId:         "mj",
+			roomService:    &fake.RoomService{ExpectedBanError: room.ErrUnauthorized},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  []room.BanRoomOpts{{Id: 1, UserId: "mj", TargetId: "venom", Now: 1_000_000}},
+		},
+		"direct message": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedBanError: room.ErrDirect},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []room.BanRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+		"unauthenticated": {
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedBanError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []room.BanRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+
+			request := httptest.NewRequest(http.MethodPut, "/api/rooms/1/bans/venom", nil)
+			if input.body != nil {
+				request = httptest.NewRequest(http.MethodPut, "/api/rooms/1/bans/venom", util.StructToReaderOrDie(input.body))
+			}
+
+			request.SetPathValue("id", "1")
+			request.SetPathValue("username", "venom")
+
+			recorder := httptest.NewRecorder()
+			s.handleRoomBan()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.BanCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.BanCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleRoomMute(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	now := time.UnixMilli(1_000_000)
+	s.Clock = util.ClockFunc(func() time.Time { return now })
+
+	tests := map[string]struct {
+		body           any
+		userId         string
+		roomService    *fake.RoomService
+		expectedStatus int
+		expectedCalls  []room.MuteRoomOpts
+	}{
+		"valid": {
+			body:           RoomRestrictRequest{Reason: "too loud", ExpiresIn: 300},
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.MuteRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Reason: "too loud", Now: 1_000_000, ExpiresAt: 1_300_000}},
+		},
+		"negative expiry": {
+			body:           RoomRestrictRequest{ExpiresIn: -1},
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"admin by an admin": {
+			body:           RoomRestrictRequest{},
+			userId:         "mj",
+			roomService:    &fake.RoomService{ExpectedMuteError: room.ErrUnauthorized},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  []room.MuteRoomOpts{{Id: 1, UserId: "mj", TargetId: "venom", Now: 1_000_000}},
+		},
+		"not found": {
+			body:           RoomRestrictRequest{},
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedMuteError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCalls:  []room.MuteRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+
+			request := httptest.NewRequest(http.MethodPut, "/api/rooms/1/mutes/venom", util.StructToReaderOrDie(input.body))
+			request.SetPathValue("id", "1")
+			request.SetPathValue("username", "venom")
+
+			recorder := httptest.NewRecorder()
+			s.handleRoomMute()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.MuteCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.MuteCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleRoomUnban(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+
+	tests := map[string]struct {
+		userId         string
+		roomService    *fake.RoomService
+		expectedStatus int
+		expectedCalls  []room.UnbanRoomOpts
+	}{
+		"valid": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.UnbanRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom"}},
+		},
+		"not an admin": {
+			userId:         "mj",
+			roomService:    &fake.RoomService{ExpectedUnbanError: room.ErrUnauthorized},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  []room.UnbanRoomOpts{{Id: 1, UserId: "mj", TargetId: "venom"}},
+		},
+		"unauthenticated": {
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodDelete, "/api/rooms/1/bans/venom", nil)
+			request.SetPathValue("id", "1")
+			request.SetPathValue("username", "venom")
+			s.handleRoomUnban()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.UnbanCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.UnbanCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleRoomBanList(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	now := time.UnixMilli(1_000_000)
+	s.Clock = util.ClockFunc(func() time.Time { return now })
+
+	bannedRoom := &room.Room{Id: 1, Name: "the big apple", Users: []string{"spiderman", "mj"}, Admins: []string{"spiderman"}, Bans: []room.Restriction{
+		{UserId: "venom", Reason: "we are venom", CreatedBy: "spiderman", CreatedAt: 1000},
+		{UserId: "carnage", CreatedBy: "spiderman", CreatedAt: 2000, ExpiresAt: 1_000_000},
+		{UserId: "mysterio", CreatedBy: "spiderman", CreatedAt: 3000, ExpiresAt: 2_000_000},
+	}}
+
+	tests := map[string]struct {
+		userId           string
+		roomService      *fake.RoomService
+		expectedStatus   int
+		expectedResponse []RestrictionResponse
+	}{
+		// The expired ban of carnage is left out.
+		"valid": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: bannedRoom},
+			expectedStatus: http.StatusOK,
+			expectedResponse: []RestrictionResponse{
+				{Username: "venom", Reason: "we are venom", CreatedBy: "spiderman", CreatedAt: 1000},
+				{Username: "mysterio", CreatedBy: "spiderman", CreatedAt: 3000, ExpiresAt: 2_000_000},
+			},
+		},
+		"empty": {
+			userId:           "spiderman",
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Admins: []string{"spiderman"}}},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: []RestrictionResponse{},
+		},
+		"member": {
+			userId:         "mj",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: bannedRoom},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"not found": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"unauthenticated": {
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: bannedRoom},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+
+			request := httptest.NewRequest(http.MethodGet, "/api/rooms/1/bans", nil)
+			request.SetPathValue("id", "1")
+
+			recorder := httptest.NewRecorder()
+			s.handleRoomBanList()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if recorder.Code != http.StatusOK {
+				return
+			}
+
+			var response []RestrictionResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(response, input.expectedResponse) {
+				t.Fatalf("got %v, expected %v", response, input.expectedResponse)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/worsediscord/server/services/message"
+)
+
+const (
+	// defaultPageSize is how many items are returned when a paginated request doesn't ask for a limit.
+	defaultPageSize = 50
+
+	// maxPageSize is the most items a paginated request may ask for.
+	maxPageSize = 100
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// page is the position and size of a paginated request.
+type page struct {
+	limit int
+	after message.Cursor
+}
+
+// parsePage reads the limit and after query parameters of a paginated request.
+func parsePage(r *http.Request) (page, error) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		return page{}, err
+	}
+
+	p := page{limit: limit}
+
+	if v := r.URL.Query().Get("after"); v != "" {
+		cursor, err := parseCursor(v)
+		if err != nil {
+			return page{}, err
+		}
+
+		p.after = cursor
+	}
+
+	return p, nil
+}
+
+// parseLimit reads the limit query parameter, which defaults to defaultPageSize.
+func parseLimit(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return defaultPageSize, nil
+	}
+
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 || limit > maxPageSize {
+		return 0, fmt.Errorf("invalid limit %q", v)
+	}
+
+	return limit, nil
+}
+
+// formatCursor encodes a cursor as the timestamp and id separated by a dash.
+func formatCursor(c message.Cursor) string {
+	return strconv.FormatInt(c.Timestamp, 10) + "-" + c.Id
+}
+
+// parseCursor decodes a cursor encoded by formatCursor.
+func parseCursor(s string) (message.Cursor, error) {
+	timestamp, id, ok := strings.Cut(s, "-")
+	if !ok || id == "" {
+		return message.Cursor{}, errInvalidCursor
+	}
+
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || ms < 0 {
+		return message.Cursor{}, errInvalidCursor
+	}
+
+	return message.Cursor{Timestamp: ms, Id: id}, nil
+}
+
+// nextPageLink builds a Link header value pointing at the page after cursor, keeping the page size of r.
+func nextPageLink(r *http.Request, cursor message.Cursor) string {
+	query := r.URL.Query()
+	query.Set("after", formatCursor(cursor))
+
+	next := *r.URL
+	next.RawQuery = query.Encode()
+
+	return fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI())
+}
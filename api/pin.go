@@ -0,0 +1,298 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/worsediscord/server/services/audit"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/room"
+)
+
+type PinResponse struct {
+	// The pinned message.
+	Message MessageResponse `json:"message"`
+
+	// The unique username of the user that pinned the message.
+	PinnedBy string `json:"pinned_by"`
+
+	// Time since epoch in milliseconds that the message was pinned.
+	PinnedAt int64 `json:"pinned_at"`
+}
+
+type PinSettingsRequest struct {
+	// Users that may pin messages without being room admins. Replaces the current list.
+	Pinners []string `json:"pinners"`
+
+	// The most messages the room can have pinned. Zero resets it to the server default.
+	Limit int `json:"limit" minimum:"0" maximum:"1000"`
+}
+
+// handlePinList returns the messages pinned in a room
+//
+//	@Summary	List pinned messages
+//	@Tags		pins
+//	@Accept		json
+//	@Produce	json
+//	@Param		id	path	int	true	"room id to list pins of"
+//	@Security	ApiKey
+//	@Success	200	{array}		PinResponse
+//	@Failure	400	{object}	Error
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/rooms/{id}/pins [get]
+func (s *Server) handlePinList() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "PinList"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		roomId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		gotRoom, err := s.RoomService.GetRoomById(r.Context(), room.GetRoomByIdOpts{Id: roomId})
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if !gotRoom.CanRead(userId) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		pins, err := s.PinService.List(r.Context(), pin.ListPinOpts{RoomId: roomId})
+		if err != nil {
+			logger.Error("failed to list pins", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		messages := make([]*message.Message, 0, len(pins))
+		pinned := make([]*pin.Pin, 0, len(pins))
+		for _, p := range pins {
+			msg, err := s.MessageService.GetMessageById(r.Context(), message.GetMessageByIdOpts{Id: p.MessageId})
+			if errors.Is(err, message.ErrNotFound) {
+				// The message was deleted after the pins were listed.
+				continue
+			} else if err != nil {
+				logger.Error("failed to get pinned message", slog.String("message_id", p.MessageId), slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			messages = append(messages, msg)
+			pinned = append(pinned, p)
+		}
+
+		responses, err := s.messageResponses(r, messages, userId)
+		if err != nil {
+			logger.Error("failed to build message responses", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]PinResponse, 0, len(pinned))
+		for i, p := range pinned {
+			response = append(response, PinResponse{Message: responses[i], PinnedBy: p.UserId, PinnedAt: p.Timestamp})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode json response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		return
+	}
+}
+
+// handlePinAdd pins a message
+//
+//	@Summary		Pin a message
+//	@Description	Only room admins and users the admins allowed to pin may pin messages, unless they're banned or muted. Pinning a message that's already pinned does nothing.
+//	@Tags			pins
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int		true	"room id the message is in"
+//	@Param			messageId	path	string	true	"message id to pin"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		403
+//	@Failure		404
+//	@Failure		409
+//	@Failure		500
+//	@Router			/rooms/{id}/pins/{messageId} [put]
+func (s *Server) handlePinAdd() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "PinAdd"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		gotRoom, msg, ok := s.lookupRoomMessage(w, r)
+		if !ok {
+			return
+		}
+
+		if !gotRoom.CanPin(userId) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if now := s.Clock.Now().UnixMilli(); gotRoom.Banned(userId, now) || gotRoom.Muted(userId, now) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		opts := pin.AddPinOpts{RoomId: gotRoom.Id, MessageId: msg.Id, UserId: userId, Limit: gotRoom.MaxPins()}
+		if _, err := s.PinService.Add(r.Context(), opts); err != nil {
+			if errors.Is(err, pin.ErrLimitReached) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+
+			logger.Error("failed to pin message", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.audit(r, audit.ActionMessagePin, msg.Id)
+
+		return
+	}
+}
+
+// handlePinRemove unpins a message
+//
+//	@Summary	Unpin a message
+//	@Tags		pins
+//	@Accept		json
+//	@Produce	json
+//	@Param		id			path	int		true	"room id the message is in"
+//	@Param		messageId	path	string	true	"message id to unpin"
+//	@Security	ApiKey
+//	@Success	200
+//	@Failure	400	{object}	Error
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/rooms/{id}/pins/{messageId} [delete]
+func (s *Server) handlePinRemove() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "PinRemove"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		gotRoom, msg, ok := s.lookupRoomMessage(w, r)
+		if !ok {
+			return
+		}
+
+		if !gotRoom.CanPin(userId) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := s.PinService.Remove(r.Context(), pin.RemovePinOpts{MessageId: msg.Id}); err != nil {
+			if errors.Is(err, pin.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			logger.Error("failed to unpin message", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.audit(r, audit.ActionMessageUnpin, msg.Id)
+
+		return
+	}
+}
+
+// handlePinSettings changes who may pin messages in a room and how many
+//
+//	@Summary	Change pin settings
+//	@Tags		pins
+//	@Accept		json
+//	@Produce	json
+//	@Param		id			path	int					true	"room id to change"
+//	@Param		settings	body	PinSettingsRequest	true	"pin settings"
+//	@Security	ApiKey
+//	@Success	200
+//	@Failure	400	{object}	Error
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/rooms/{id}/pin-settings [put]
+func (s *Server) handlePinSettings() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "PinSettings"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var request PinSettingsRequest
+		if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		opts := room.ConfigurePinsRoomOpts{Id: id, UserId: userId, Pinners: request.Pinners, Limit: request.Limit}
+		if err = s.RoomService.ConfigurePins(r.Context(), opts); err != nil {
+			switch {
+			case errors.Is(err, room.ErrInvalidLimit), errors.Is(err, room.ErrDirect):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, room.ErrUnauthorized):
+				w.WriteHeader(http.StatusUnauthorized)
+			case errors.Is(err, room.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				logger.Error("failed to configure pins", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		s.audit(r, audit.ActionRoomPins, strconv.FormatInt(id, 10))
+
+		return
+	}
+}
@@ -0,0 +1,392 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/worsediscord/server/services/fake"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/util"
+)
+
+// pinRequest builds a request to the pin of messageId in room id.
+func pinRequest(method string, id string, messageId string, userId string) *http.Request {
+	request := httptest.NewRequest(method, "/api/rooms/"+id+"/pins/"+messageId, nil)
+	request.SetPathValue("id", id)
+	request.SetPathValue("messageId", messageId)
+
+	return withUserId(request, userId)
+}
+
+func TestServer_HandlePinAdd(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	existingRoom := &room.Room{Id: 1, Name: "the big apple", Users: []string{"spiderman", "venom", "mj"}, Admins: []string{"spiderman"}, Pinners: []string{"mj"}}
+	limitedRoom := &room.Room{Id: 1, Name: "the big apple", Admins: []string{"spiderman"}, PinLimit: 2}
+	existingMessage := &message.Message{Id: "a", UserId: "venom", RoomId: 1}
+
+	tests := map[string]struct {
+		id             string
+		messageId      string
+		userId         string
+		roomService    *fake.RoomService
+		messageService *fake.MessageService
+		pinService     *fake.PinService
+		expectedStatus int
+		expectedCalls  []pin.AddPinOpts
+	}{
+		"admin": {
+			id:             "1",
+			messageId:      "a",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			pinService:     &fake.PinService{ExpectedAddPin: &pin.Pin{}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []pin.AddPinOpts{{RoomId: 1, MessageId: "a", UserId: "spiderman", Limit: room.DefaultPinLimit}},
+		},
+		"pinner": {
+			id:             "1",
+			messageId:      "a",
+			userId:         "mj",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			pinService:     &fake.PinService{ExpectedAddPin: &pin.Pin{}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []pin.AddPinOpts{{RoomId: 1, MessageId: "a", UserId: "mj", Limit: room.DefaultPinLimit}},
+		},
+		"member": {
+			id:             "1",
+			messageId:      "a",
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			pinService:     &fake.PinService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"banned": {
+			id:             "1",
+			messageId:      "a",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Name: "the big apple", Admins: []string{"spiderman"}, Bans: []room.Restriction{{UserId: "spiderman"}}}},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			pinService:     &fake.PinService{},
+			expectedStatus: http.StatusForbidden,
+		},
+		"muted": {
+			id:             "1",
+			messageId:      "a",
+			userId:         "mj",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Name: "the big apple", Users: []string{"mj"}, Pinners: []string{"mj"}, Mutes: []room.Restriction{{UserId: "mj"}}}},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			pinService:     &fake.PinService{},
+			expectedStatus: http.StatusForbidden,
+		},
+		"limit reached": {
+			id:             "1",
+			messageId:      "a",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: limitedRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			pinService:     &fake.PinService{ExpectedAddError: pin.ErrLimitReached},
+			expectedStatus: http.StatusConflict,
+			expectedCalls:  []pin.AddPinOpts{{RoomId: 1, MessageId: "a", UserId: "spiderman", Limit: 2}},
+		},
+		"message not found": {
+			id:             "1",
+			messageId:      "b",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdError: message.ErrNotFound},
+			pinService:     &fake.PinService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"message in another room": {
+			id:             "1",
+			messageId:      "a",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: &message.Message{Id: "a", RoomId: 2}},
+			pinService:     &fake.PinService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"unauthenticated": {
+			id:             "1",
+			messageId:      "a",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			pinService:     &fake.PinService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			id:             "1",
+			messageId:      "a",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			pinService:     &fake.PinService{ExpectedAddError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []pin.AddPinOpts{{RoomId: 1, MessageId: "a", UserId: "spiderman", Limit: room.DefaultPinLimit}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+			s.MessageService = input.messageService
+			s.PinService = input.pinService
+
+			recorder := httptest.NewRecorder()
+			s.handlePinAdd()(recorder, pinRequest(http.MethodPut, input.id, input.messageId, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.pinService.AddCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.pinService.AddCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandlePinRemove(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	existingRoom := &room.Room{Id: 1, Name: "the big apple", Admins: []string{"spiderman"}, Pinners: []string{"mj"}}
+	existingMessage := &message.Message{Id: "a", UserId: "venom", RoomId: 1}
+
+	tests := map[string]struct {
+		userId         string
+		pinService     *fake.PinService
+		expectedStatus int
+		expectedCalls  []pin.RemovePinOpts
+	}{
+		"admin": {
+			userId:         "spiderman",
+			pinService:     &fake.PinService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []pin.RemovePinOpts{{MessageId: "a"}},
+		},
+		"pinner": {
+			userId:         "mj",
+			pinService:     &fake.PinService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []pin.RemovePinOpts{{MessageId: "a"}},
+		},
+		"author": {
+			userId:         "venom",
+			pinService:     &fake.PinService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"not pinned": {
+			userId:         "spiderman",
+			pinService:     &fake.PinService{ExpectedRemoveError: pin.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCalls:  []pin.RemovePinOpts{{MessageId: "a"}},
+		},
+		"service error": {
+			userId:         "spiderman",
+			pinService:     &fake.PinService{ExpectedRemoveError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []pin.RemovePinOpts{{MessageId: "a"}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom}
+			s.MessageService = &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage}
+			s.PinService = input.pinService
+
+			recorder := httptest.NewRecorder()
+			s.handlePinRemove()(recorder, pinRequest(http.MethodDelete, "1", "a", input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.pinService.RemoveCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.pinService.RemoveCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandlePinList(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	existingRoom := &room.Room{Id: 1, Name: "the big apple"}
+
+	tests := map[string]struct {
+		id               string
+		roomService      *fake.RoomService
+		messageService   *fake.MessageService
+		pinService       *fake.PinService
+		expectedStatus   int
+		expectedCalls    []pin.ListPinOpts
+		expectedResponse []PinResponse
+	}{
+		"valid": {
+			id:             "1",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: &message.Message{Id: "a", UserId: "venom", RoomId: 1, Content: "rules", Timestamp: 1000}},
+			pinService:     &fake.PinService{ExpectedListPins: []*pin.Pin{{RoomId: 1, MessageId: "a", UserId: "spiderman", Timestamp: 2000}}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []pin.ListPinOpts{{RoomId: 1}},
+			expectedResponse: []PinResponse{{
+				Message:  MessageResponse{Id: "a", UserId: "venom", Content: "rules", Timestamp: 1000},
+				PinnedBy: "spiderman",
+				PinnedAt: 2000,
+			}},
+		},
+		"deleted message": {
+			id:               "1",
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:   &fake.MessageService{ExpectedGetMessageByIdError: message.ErrNotFound},
+			pinService:       &fake.PinService{ExpectedListPins: []*pin.Pin{{RoomId: 1, MessageId: "a", UserId: "spiderman", Timestamp: 2000}}},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []pin.ListPinOpts{{RoomId: 1}},
+			expectedResponse: []PinResponse{},
+		},
+		"room not found": {
+			id:             "2",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			messageService: &fake.MessageService{},
+			pinService:     &fake.PinService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"direct message outsider": {
+			id:             "1",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Type: room.TypeDirect, Users: []string{"mj", "venom"}}},
+			messageService: &fake.MessageService{},
+			pinService:     &fake.PinService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			id:             "1",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService: &fake.MessageService{},
+			pinService:     &fake.PinService{ExpectedListError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []pin.ListPinOpts{{RoomId: 1}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+			s.MessageService = input.messageService
+			s.ReactionService = &fake.ReactionService{}
+			s.PinService = input.pinService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/api/rooms/"+input.id+"/pins", nil)
+			request.SetPathValue("id", input.id)
+			s.handlePinList()(recorder, withUserId(request, "spiderman"))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.pinService.ListCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.pinService.ListCalls, input.expectedCalls)
+			}
+
+			if input.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response []PinResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(response, input.expectedResponse) {
+				t.Fatalf("got pins %v, expected %v", response, input.expectedResponse)
+			}
+		})
+	}
+}
+
+func TestServer_HandlePinSettings(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+
+	tests := map[string]struct {
+		id             string
+		body           any
+		userId         string
+		roomService    *fake.RoomService
+		expectedStatus int
+		expectedCalls  []room.ConfigurePinsRoomOpts
+	}{
+		"valid": {
+			id:             "1",
+			body:           PinSettingsRequest{Pinners: []string{"mj"}, Limit: 10},
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.ConfigurePinsRoomOpts{{Id: 1, UserId: "spiderman", Pinners: []string{"mj"}, Limit: 10}},
+		},
+		"not an admin": {
+			id:             "1",
+			body:           PinSettingsRequest{Pinners: []string{"venom"}},
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedConfigurePinsError: room.ErrUnauthorized},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  []room.ConfigurePinsRoomOpts{{Id: 1, UserId: "venom", Pinners: []string{"venom"}}},
+		},
+		"invalid limit": {
+			id:             "1",
+			body:           PinSettingsRequest{Limit: -1},
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedConfigurePinsError: room.ErrInvalidLimit},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []room.ConfigurePinsRoomOpts{{Id: 1, UserId: "spiderman", Limit: -1}},
+		},
+		"room not found": {
+			id:             "2",
+			body:           PinSettingsRequest{},
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedConfigurePinsError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCalls:  []room.ConfigurePinsRoomOpts{{Id: 2, UserId: "spiderman"}},
+		},
+		"invalid body": {
+			id:             "1",
+			body:           "pizza time",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"invalid id": {
+			id:             "queens",
+			body:           PinSettingsRequest{},
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPut, "/api/rooms/"+input.id+"/pin-settings", util.StructToReaderOrDie(input.body))
+			request.SetPathValue("id", input.id)
+			s.handlePinSettings()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.ConfigurePinsCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.ConfigurePinsCalls, input.expectedCalls)
+			}
+		})
+	}
+}
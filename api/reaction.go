@@ -0,0 +1,221 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/reaction"
+)
+
+type ReactionResponse struct {
+	// The unique username of the user that reacted.
+	UserId string `json:"user_id,omitempty"`
+
+	// Time since epoch in milliseconds.
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+type ReactionCountResponse struct {
+	// The emoji the message was reacted with.
+	Emoji string `json:"emoji"`
+
+	// How many users reacted with the emoji.
+	Count int `json:"count"`
+
+	// Whether the user listing the messages reacted with the emoji.
+	Reacted bool `json:"reacted"`
+}
+
+// handleReactionAdd reacts to a message
+//
+//	@Summary		React to a message
+//	@Description	Reacting with an emoji the user already reacted with does nothing. Banned and muted users may not react.
+//	@Tags			reactions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int		true	"room id the message is in"
+//	@Param			messageId	path	string	true	"message id to react to"
+//	@Param			emoji		path	string	true	"emoji to react with"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		403
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/messages/{messageId}/reactions/{emoji} [put]
+func (s *Server) handleReactionAdd() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "ReactionAdd"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		gotRoom, msg, ok := s.lookupRoomMessage(w, r)
+		if !ok {
+			return
+		}
+
+		if now := s.Clock.Now().UnixMilli(); gotRoom.Banned(userId, now) || gotRoom.Muted(userId, now) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		opts := reaction.AddReactionOpts{MessageId: msg.Id, UserId: userId, Emoji: r.PathValue("emoji")}
+		if _, err := s.ReactionService.Add(r.Context(), opts); err != nil {
+			if errors.Is(err, reaction.ErrInvalidEmoji) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			logger.Error("failed to add reaction", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		logger.Debug("reaction added", slog.String("message_id", msg.Id), slog.String("user_id", userId))
+
+		return
+	}
+}
+
+// handleReactionRemove removes a reaction from a message
+//
+//	@Summary	Remove a reaction
+//	@Tags		reactions
+//	@Accept		json
+//	@Produce	json
+//	@Param		id			path	int		true	"room id the message is in"
+//	@Param		messageId	path	string	true	"message id to remove the reaction from"
+//	@Param		emoji		path	string	true	"emoji to remove"
+//	@Security	ApiKey
+//	@Success	200
+//	@Failure	400	{object}	Error
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/rooms/{id}/messages/{messageId}/reactions/{emoji} [delete]
+func (s *Server) handleReactionRemove() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "ReactionRemove"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, msg, ok := s.lookupRoomMessage(w, r)
+		if !ok {
+			return
+		}
+
+		opts := reaction.RemoveReactionOpts{MessageId: msg.Id, UserId: userId, Emoji: r.PathValue("emoji")}
+		if err := s.ReactionService.Remove(r.Context(), opts); err != nil {
+			if errors.Is(err, reaction.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			logger.Error("failed to remove reaction", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		logger.Debug("reaction removed", slog.String("message_id", msg.Id), slog.String("user_id", userId))
+
+		return
+	}
+}
+
+// handleReactionList returns the users that reacted to a message with an emoji
+//
+//	@Summary	List reactions
+//	@Tags		reactions
+//	@Accept		json
+//	@Produce	json
+//	@Param		id			path	int		true	"room id the message is in"
+//	@Param		messageId	path	string	true	"message id to list reactions of"
+//	@Param		emoji		path	string	true	"emoji to list reactions with"
+//	@Security	ApiKey
+//	@Success	200	{array}		ReactionResponse
+//	@Failure	400	{object}	Error
+//	@Failure	401
+//	@Failure	404
+//	@Failure	500
+//	@Router		/rooms/{id}/messages/{messageId}/reactions/{emoji} [get]
+func (s *Server) handleReactionList() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "ReactionList"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, msg, ok := s.lookupRoomMessage(w, r)
+		if !ok {
+			return
+		}
+
+		opts := reaction.ListReactionOpts{MessageIds: []string{msg.Id}, Emoji: r.PathValue("emoji")}
+
+		reactions, err := s.ReactionService.List(r.Context(), opts)
+		if err != nil {
+			logger.Error("failed to list reactions", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]ReactionResponse, 0, len(reactions))
+		for _, rct := range reactions {
+			response = append(response, ReactionResponse{UserId: rct.UserId, Timestamp: rct.Timestamp})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode json response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		return
+	}
+}
+
+// messageReactions counts the reactions to every message, keyed by message id. userId is the user the counts are
+// being returned to.
+func (s *Server) messageReactions(r *http.Request, messages []*message.Message, userId string) (map[string][]ReactionCountResponse, error) {
+	counts := make(map[string][]ReactionCountResponse)
+	if len(messages) == 0 {
+		return counts, nil
+	}
+
+	ids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		ids = append(ids, msg.Id)
+	}
+
+	reactions, err := s.ReactionService.List(r.Context(), reaction.ListReactionOpts{MessageIds: ids})
+	if err != nil {
+		return nil, err
+	}
+
+	// Reactions are listed oldest first, so each emoji is appended the first time it was used.
+	for _, rct := range reactions {
+		i := slices.IndexFunc(counts[rct.MessageId], func(c ReactionCountResponse) bool { return c.Emoji == rct.Emoji })
+		if i == -1 {
+			counts[rct.MessageId] = append(counts[rct.MessageId], ReactionCountResponse{Emoji: rct.Emoji})
+			i = len(counts[rct.MessageId]) - 1
+		}
+
+		counts[rct.MessageId][i].Count++
+		counts[rct.MessageId][i].Reacted = counts[rct.MessageId][i].Reacted || rct.UserId == userId
+	}
+
+	return counts, nil
+}
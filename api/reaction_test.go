@@ -0,0 +1,301 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/worsediscord/server/services/fake"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/util"
+)
+
+// reactionRequest builds a request to the reactions of messageId in room id.
+func reactionRequest(method string, id string, messageId string, emoji string, userId string) *http.Request {
+	request := httptest.NewRequest(method, "/api/rooms/"+id+"/messages/"+messageId+"/reactions/"+url.PathEscape(emoji), nil)
+	request.SetPathValue("id", id)
+	request.SetPathValue("messageId", messageId)
+	request.SetPathValue("emoji", emoji)
+
+	return withUserId(request, userId)
+}
+
+func TestServer_HandleReactionAdd(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	existingRoom := &room.Room{Id: 1, Name: "the big apple"}
+	existingMessage := &message.Message{Id: "a", UserId: "spiderman", RoomId: 1}
+
+	tests := map[string]struct {
+		id              string
+		messageId       string
+		emoji           string
+		userId          string
+		roomService     *fake.RoomService
+		messageService  *fake.MessageService
+		reactionService *fake.ReactionService
+		expectedStatus  int
+		expectedCalls   []reaction.AddReactionOpts
+	}{
+		"valid": {
+			id:              "1",
+			messageId:       "a",
+			emoji:           "🍕",
+			userId:          "venom",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{ExpectedAddReaction: &reaction.Reaction{}},
+			expectedStatus:  http.StatusOK,
+			expectedCalls:   []reaction.AddReactionOpts{{MessageId: "a", UserId: "venom", Emoji: "🍕"}},
+		},
+		"invalid emoji": {
+			id:              "1",
+			messageId:       "a",
+			emoji:           "pizza time",
+			userId:          "venom",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{ExpectedAddError: reaction.ErrInvalidEmoji},
+			expectedStatus:  http.StatusBadRequest,
+			expectedCalls:   []reaction.AddReactionOpts{{MessageId: "a", UserId: "venom", Emoji: "pizza time"}},
+		},
+		"banned": {
+			id:              "1",
+			messageId:       "a",
+			emoji:           "🍕",
+			userId:          "venom",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Name: "the big apple", Bans: []room.Restriction{{UserId: "venom"}}}},
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusForbidden,
+		},
+		"muted": {
+			id:              "1",
+			messageId:       "a",
+			emoji:           "🍕",
+			userId:          "venom",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Name: "the big apple", Mutes: []room.Restriction{{UserId: "venom"}}}},
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusForbidden,
+		},
+		"room not found": {
+			id:              "2",
+			messageId:       "a",
+			emoji:           "🍕",
+			userId:          "venom",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			messageService:  &fake.MessageService{},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusNotFound,
+		},
+		"message not found": {
+			id:              "1",
+			messageId:       "b",
+			emoji:           "🍕",
+			userId:          "venom",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdError: message.ErrNotFound},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusNotFound,
+		},
+		"message in another room": {
+			id:              "1",
+			messageId:       "a",
+			emoji:           "🍕",
+			userId:          "venom",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: &message.Message{Id: "a", RoomId: 2}},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusNotFound,
+		},
+		"unauthenticated": {
+			id:              "1",
+			messageId:       "a",
+			emoji:           "🍕",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusUnauthorized,
+		},
+		"service error": {
+			id:              "1",
+			messageId:       "a",
+			emoji:           "🍕",
+			userId:          "venom",
+			roomService:     &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom},
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{ExpectedAddError: errors.New("oops")},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedCalls:   []reaction.AddReactionOpts{{MessageId: "a", UserId: "venom", Emoji: "🍕"}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+			s.MessageService = input.messageService
+			s.ReactionService = input.reactionService
+
+			recorder := httptest.NewRecorder()
+			s.handleReactionAdd()(recorder, reactionRequest(http.MethodPut, input.id, input.messageId, input.emoji, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.reactionService.AddCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.reactionService.AddCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleReactionRemove(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	existingRoom := &room.Room{Id: 1, Name: "the big apple"}
+	existingMessage := &message.Message{Id: "a", UserId: "spiderman", RoomId: 1}
+
+	tests := map[string]struct {
+		userId          string
+		messageService  *fake.MessageService
+		reactionService *fake.ReactionService
+		expectedStatus  int
+		expectedCalls   []reaction.RemoveReactionOpts
+	}{
+		"valid": {
+			userId:          "venom",
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusOK,
+			expectedCalls:   []reaction.RemoveReactionOpts{{MessageId: "a", UserId: "venom", Emoji: "🍕"}},
+		},
+		"reaction not found": {
+			userId:          "venom",
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{ExpectedRemoveError: reaction.ErrNotFound},
+			expectedStatus:  http.StatusNotFound,
+			expectedCalls:   []reaction.RemoveReactionOpts{{MessageId: "a", UserId: "venom", Emoji: "🍕"}},
+		},
+		"message not found": {
+			userId:          "venom",
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdError: message.ErrNotFound},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusNotFound,
+		},
+		"unauthenticated": {
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusUnauthorized,
+		},
+		"service error": {
+			userId:          "venom",
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{ExpectedRemoveError: errors.New("oops")},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedCalls:   []reaction.RemoveReactionOpts{{MessageId: "a", UserId: "venom", Emoji: "🍕"}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom}
+			s.MessageService = input.messageService
+			s.ReactionService = input.reactionService
+
+			recorder := httptest.NewRecorder()
+			s.handleReactionRemove()(recorder, reactionRequest(http.MethodDelete, "1", "a", "🍕", input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.reactionService.RemoveCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.reactionService.RemoveCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleReactionList(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	existingRoom := &room.Room{Id: 1, Name: "the big apple"}
+	existingMessage := &message.Message{Id: "a", UserId: "spiderman", RoomId: 1}
+
+	tests := map[string]struct {
+		messageService   *fake.MessageService
+		reactionService  *fake.ReactionService
+		expectedStatus   int
+		expectedCalls    []reaction.ListReactionOpts
+		expectedResponse []ReactionResponse
+	}{
+		"valid": {
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{ExpectedListReactions: []*reaction.Reaction{
+				{MessageId: "a", UserId: "venom", Emoji: "🍕", Timestamp: 1000},
+				{MessageId: "a", UserId: "spiderman", Emoji: "🍕", Timestamp: 2000},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []reaction.ListReactionOpts{{MessageIds: []string{"a"}, Emoji: "🍕"}},
+			expectedResponse: []ReactionResponse{
+				{UserId: "venom", Timestamp: 1000},
+				{UserId: "spiderman", Timestamp: 2000},
+			},
+		},
+		"empty": {
+			messageService:   &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService:  &fake.ReactionService{ExpectedListReactions: []*reaction.Reaction{}},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []reaction.ListReactionOpts{{MessageIds: []string{"a"}, Emoji: "🍕"}},
+			expectedResponse: []ReactionResponse{},
+		},
+		"message not found": {
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdError: message.ErrNotFound},
+			reactionService: &fake.ReactionService{},
+			expectedStatus:  http.StatusNotFound,
+		},
+		"service error": {
+			messageService:  &fake.MessageService{ExpectedGetMessageByIdMessage: existingMessage},
+			reactionService: &fake.ReactionService{ExpectedListError: errors.New("oops")},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedCalls:   []reaction.ListReactionOpts{{MessageIds: []string{"a"}, Emoji: "🍕"}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = &fake.RoomService{ExpectedGetRoomByIdRoom: existingRoom}
+			s.MessageService = input.messageService
+			s.ReactionService = input.reactionService
+
+			recorder := httptest.NewRecorder()
+			s.handleReactionList()(recorder, reactionRequest(http.MethodGet, "1", "a", "🍕", "spiderman"))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.reactionService.ListCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.reactionService.ListCalls, input.expectedCalls)
+			}
+
+			if input.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response []ReactionResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(response, input.expectedResponse) {
+				t.Fatalf("got reactions %v, expected %v", response, input.expectedResponse)
+			}
+		})
+	}
+}
@@ -7,17 +7,63 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/worsediscord/server/services/audit"
 	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
 )
 
 type RoomCreateRequest struct {
 	// The name of the room to create. This does not need to be globally unique.
-	Name string `json:"name"`
+	Name string `json:"name" validate:"required" minLength:"1"`
+
+	// Who can find and read the room. Private rooms are only listed for and readable by their members, and others
+	// join them through invites. Defaults to public.
+	Visibility string `json:"visibility,omitempty" enums:"public,private"`
+}
+
+type RoomUpdateRequest struct {
+	// A new name for the room. Omit to leave it unchanged.
+	Name *string `json:"name,omitempty" minLength:"1"`
+
+	// What the room is for. Omit to leave it unchanged, or send an empty string to clear it.
+	Topic *string `json:"topic,omitempty" maxLength:"1024"`
+
+	// An emoji or image URL shown next to the room name. Omit to leave it unchanged, or send an empty string to clear it.
+	Icon *string `json:"icon,omitempty" maxLength:"2048"`
+}
+
+type RoomMemberRequest struct {
+	// The username to make a room admin or owner. They are added to the room if they aren't a member.
+	Username string `json:"username" validate:"required" minLength:"1"`
 }
 
 type RoomResponse struct {
 	Id   int64  `json:"id,omitempty"`
 	Name string `json:"name,omitempty"`
+
+	// The room type. Empty for named rooms and "direct" for direct message rooms.
+	Type string `json:"type,omitempty"`
+
+	// Either public or private. Not returned for direct message rooms.
+	Visibility string `json:"visibility,omitempty"`
+
+	Topic string `json:"topic,omitempty"`
+	Icon  string `json:"icon,omitempty"`
+
+	// The user that owns the room. Not returned for direct message rooms.
+	Owner string `json:"owner,omitempty"`
+
+	// The room's admins, owner first. Only returned when getting a single room.
+	Admins []string `json:"admins,omitempty"`
+
+	// The users in a direct message room. Not returned for named rooms.
+	Users []string `json:"users,omitempty"`
+
+	// Users that may pin messages without being room admins. Only returned when getting a single room.
+	Pinners []string `json:"pinners,omitempty"`
+
+	// The most messages the room can have pinned. Only returned when getting a single room.
+	PinLimit int `json:"pin_limit,omitempty"`
 }
 
 // handleRoomCreate creates a room
@@ -29,7 +75,7 @@ type RoomResponse struct {
 //	@Param		name	body	RoomCreateRequest	true	"room data"
 //	@Security	ApiKey
 //	@Success	200 {object} RoomResponse
-//	@Failure	400
+//	@Failure	400	{object}	Error
 //	@Failure	401
 //	@Failure	500
 //	@Router		/rooms [post]
@@ -44,11 +90,6 @@ func (s *Server) handleRoomCreate() http.HandlerFunc {
 			return
 		}
 
-		if !request.Validate() {
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-
 		userId, ok := r.Context().Value("userID").(string)
 		if !ok {
 			logger.Error("failed to lookup apikey in request context")
@@ -57,6 +98,16 @@ func (s *Server) handleRoomCreate() http.HandlerFunc {
 		}
 
 		opts := room.CreateRoomOpts{Name: request.Name, UserId: userId}
+		switch request.Visibility {
+		case "", "public":
+			opts.Visibility = room.VisibilityPublic
+		case "private":
+			opts.Visibility = room.VisibilityPrivate
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
 		createdRoom, err := s.RoomService.Create(r.Context(), opts)
 		if err != nil {
 			logger.Error("failed to create room", slog.String("error", err.Error()))
@@ -64,13 +115,13 @@ func (s *Server) handleRoomCreate() http.HandlerFunc {
 			return
 		}
 
-		response := RoomResponse{Id: createdRoom.Id, Name: createdRoom.Name}
-		if err = json.NewEncoder(w).Encode(response); err != nil {
+		if err = json.NewEncoder(w).Encode(roomResponse(createdRoom)); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
 		logger.Info("room created", slog.String("name", createdRoom.Name))
+		s.audit(r, audit.ActionRoomCreate, strconv.FormatInt(createdRoom.Id, 10))
 
 		return
 	}
@@ -78,15 +129,16 @@ func (s *Server) handleRoomCreate() http.HandlerFunc {
 
 // handleRoomList lists rooms
 //
-//	@Summary	Get all rooms
-//	@Tags		rooms
-//	@Accept		json
-//	@Produce	json
-//	@Security	ApiKey
-//	@Success	200
-//	@Failure	401
-//	@Failure	500
-//	@Router		/rooms [get]
+//	@Summary		Get all rooms
+//	@Description	Direct message rooms are not listed, and private rooms are only listed for their members.
+//	@Tags			rooms
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		401
+//	@Failure		500
+//	@Router			/rooms [get]
 func (s *Server) handleRoomList() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		rooms, err := s.RoomService.List(r.Context())
@@ -95,9 +147,15 @@ func (s *Server) handleRoomList() http.HandlerFunc {
 			return
 		}
 
+		userId, _ := r.Context().Value("userID").(string)
+
 		response := make([]RoomResponse, 0)
 		for i := range rooms {
-			response = append(response, RoomResponse{Id: rooms[i].Id, Name: rooms[i].Name})
+			if rooms[i].IsDirect() || !rooms[i].CanRead(userId) {
+				continue
+			}
+
+			response = append(response, roomResponse(rooms[i]))
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -117,9 +175,10 @@ func (s *Server) handleRoomList() http.HandlerFunc {
 //	@Tags		rooms
 //	@Accept		json
 //	@Produce	json
-//	@Param		id	path	string	true	"id to fetch"
+//	@Param		id	path	int	true	"id to fetch"
 //	@Security	ApiKey
 //	@Success	200
+//	@Failure	400	{object}	Error
 //	@Failure	401
 //	@Failure	404
 //	@Failure	500
@@ -140,9 +199,19 @@ func (s *Server) handleRoomGet() http.HandlerFunc {
 			return
 		}
 
+		userId, _ := r.Context().Value("userID").(string)
+		if !gotRoom.CanRead(userId) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 
-		if err = json.NewEncoder(w).Encode(RoomResponse{Id: gotRoom.Id, Name: gotRoom.Name}); err != nil {
+		response := roomResponse(gotRoom)
+		response.Admins = gotRoom.Admins
+		response.Pinners = gotRoom.Pinners
+		response.PinLimit = gotRoom.MaxPins()
+		if err = json.NewEncoder(w).Encode(response); err != nil {
 			logger.Error("failed to encode json response", slog.String("error", err.Error()))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
@@ -154,22 +223,216 @@ func (s *Server) handleRoomGet() http.HandlerFunc {
 
 // handleRoomDelete deletes a room
 //
-//	@Summary	Deletes a room
+//	@Summary		Deletes a room
+//	@Description	Deletes a room along with its messages, reactions, pins and invites.
+//	@Tags			rooms
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"id to delete"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id} [delete]
+func (s *Server) handleRoomDelete() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomDelete"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err = s.deleter().DeleteRoom(r.Context(), room.DeleteRoomOpts{Id: int64(id), UserId: userId}); err != nil {
+			switch {
+			case errors.Is(err, room.ErrUnauthorized):
+				w.WriteHeader(http.StatusUnauthorized)
+			case errors.Is(err, room.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				logger.Error("failed to delete room", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		logger.Info("room deleted", slog.Int("id", id))
+		s.audit(r, audit.ActionRoomDelete, strconv.Itoa(id))
+
+		return
+	}
+}
+
+// handleRoomUpdate changes a room's settings
+//
+//	@Summary		Update a room
+//	@Description	Changes the name, topic and icon of a room the caller is an admin of. Omitted fields are left as they are.
+//	@Tags			rooms
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int					true	"id of the room to update"
+//	@Param			settings	body	RoomUpdateRequest	true	"room settings"
+//	@Security		ApiKey
+//	@Success		200	{object}	RoomResponse
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id} [patch]
+func (s *Server) handleRoomUpdate() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomUpdate"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var request RoomUpdateRequest
+		if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		opts := room.UpdateRoomOpts{Id: id, UserId: userId, Name: request.Name, Topic: request.Topic, Icon: request.Icon}
+		updated, err := s.RoomService.Update(r.Context(), opts)
+		if err != nil {
+			switch {
+			case errors.Is(err, room.ErrInvalidName), errors.Is(err, room.ErrDirect):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, room.ErrUnauthorized):
+				w.WriteHeader(http.StatusUnauthorized)
+			case errors.Is(err, room.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				logger.Error("failed to update room", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(w).Encode(roomResponse(updated)); err != nil {
+			logger.Error("failed to encode json response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.audit(r, audit.ActionRoomUpdate, strconv.FormatInt(id, 10))
+
+		return
+	}
+}
+
+// handleRoomPromote makes a user a room admin
+//
+//	@Summary	Promote a room admin
 //	@Tags		rooms
 //	@Accept		json
-//	@Produce	json
-//	@Param		id	path	string	true	"id to delete"
+//	@Param		id		path	int					true	"id of the room"
+//	@Param		user	body	RoomMemberRequest	true	"user to promote"
 //	@Security	ApiKey
 //	@Success	200
+//	@Failure	400	{object}	Error
 //	@Failure	401
+//	@Failure	403
 //	@Failure	404
 //	@Failure	500
-//	@Router		/rooms/{id} [delete]
-func (s *Server) handleRoomDelete() http.HandlerFunc {
-	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomDelete"))
+//	@Router		/rooms/{id}/admins [post]
+func (s *Server) handleRoomPromote() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomPromote"))
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		id, err := strconv.Atoi(r.PathValue("id"))
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var request RoomMemberRequest
+		if err = json.NewDecoder(r.Body).Decode(&request); err != nil || request.Username == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if _, err = s.UserService.GetUserById(r.Context(), user.GetUserByIdOpts{Id: request.Username}); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		opts := room.PromoteRoomOpts{Id: id, UserId: userId, TargetId: request.Username, Now: s.Clock.Now().UnixMilli()}
+		if err = s.RoomService.Promote(r.Context(), opts); err != nil {
+			switch {
+			case errors.Is(err, room.ErrDirect), errors.Is(err, room.ErrNotMember):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, room.ErrUnauthorized):
+				w.WriteHeader(http.StatusUnauthorized)
+			case errors.Is(err, room.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, room.ErrBanned):
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				logger.Error("failed to promote user", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		s.audit(r, audit.ActionRoomPromote, r.PathValue("id")+"/"+request.Username)
+
+		return
+	}
+}
+
+// handleRoomDemote revokes room admin from a user
+//
+//	@Summary		Demote a room admin
+//	@Description	Only the room owner may demote other admins, but any admin may demote themselves. The owner can't be
+//	@Description	demoted, so ownership must be transferred first.
+//	@Tags			rooms
+//	@Param			id			path	int		true	"id of the room"
+//	@Param			username	path	string	true	"admin to demote"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/admins/{username} [delete]
+func (s *Server) handleRoomDemote() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomDemote"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 		if err != nil {
 			w.WriteHeader(http.StatusNotFound)
 			return
@@ -182,26 +445,186 @@ func (s *Server) handleRoomDelete() http.HandlerFunc {
 			return
 		}
 
-		if err = s.RoomService.Delete(r.Context(), room.DeleteRoomOpts{Id: int64(id), UserId: userId}); err != nil {
+		opts := room.DemoteRoomOpts{Id: id, UserId: userId, TargetId: r.PathValue("username")}
+		if err = s.RoomService.Demote(r.Context(), opts); err != nil {
 			switch {
+			case errors.Is(err, room.ErrOwner), errors.Is(err, room.ErrDirect):
+				w.WriteHeader(http.StatusBadRequest)
 			case errors.Is(err, room.ErrUnauthorized):
 				w.WriteHeader(http.StatusUnauthorized)
 			case errors.Is(err, room.ErrNotFound):
 				w.WriteHeader(http.StatusNotFound)
 			default:
-				logger.Error("failed to delete room", slog.String("error", err.Error()))
+				logger.Error("failed to demote user", slog.String("error", err.Error()))
 				w.WriteHeader(http.StatusInternalServerError)
 			}
 
 			return
 		}
 
-		logger.Info("room deleted", slog.Int("id", id))
+		s.audit(r, audit.ActionRoomDemote, r.PathValue("id")+"/"+opts.TargetId)
 
 		return
 	}
 }
 
-func (c RoomCreateRequest) Validate() bool {
-	return c.Name != ""
+// handleRoomTransfer transfers ownership of a room
+//
+//	@Summary		Transfer room ownership
+//	@Description	Makes another member the owner of a room the caller owns. The caller stays an admin.
+//	@Tags			rooms
+//	@Accept			json
+//	@Param			id		path	int					true	"id of the room"
+//	@Param			user	body	RoomMemberRequest	true	"new owner"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		403
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/owner [put]
+func (s *Server) handleRoomTransfer() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomTransfer"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var request RoomMemberRequest
+		if err = json.NewDecoder(r.Body).Decode(&request); err != nil || request.Username == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if _, err = s.UserService.GetUserById(r.Context(), user.GetUserByIdOpts{Id: request.Username}); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		opts := room.TransferRoomOpts{Id: id, UserId: userId, TargetId: request.Username, Now: s.Clock.Now().UnixMilli()}
+		if err = s.RoomService.Transfer(r.Context(), opts); err != nil {
+			switch {
+			case errors.Is(err, room.ErrDirect), errors.Is(err, room.ErrNotMember):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, room.ErrUnauthorized):
+				w.WriteHeader(http.StatusUnauthorized)
+			case errors.Is(err, room.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, room.ErrBanned):
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				logger.Error("failed to transfer room", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		s.audit(r, audit.ActionRoomTransfer, r.PathValue("id")+"/"+request.Username)
+
+		return
+	}
+}
+
+// handleRoomJoin joins a public room
+//
+//	@Summary		Join a room
+//	@Description	Only public rooms can be joined directly. Private rooms are joined by accepting an invite. Users
+//	@Description	banned from the room can't join it until their ban expires.
+//	@Tags			rooms
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"id of the room to join"
+//	@Security		ApiKey
+//	@Success		200	{object}	RoomResponse
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		403
+//	@Failure		404
+//	@Failure		500
+//	@Router			/rooms/{id}/join [post]
+func (s *Server) handleRoomJoin() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "RoomJoin"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		gotRoom, err := s.RoomService.GetRoomById(r.Context(), room.GetRoomByIdOpts{Id: id})
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// Members of private rooms joining again is harmless, so only outsiders are turned away.
+		if !gotRoom.IsDirect() && !gotRoom.CanRead(userId) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err = s.RoomService.Join(r.Context(), room.JoinRoomOpts{Id: id, UserId: userId, Now: s.Clock.Now().UnixMilli()}); err != nil {
+			switch {
+			case errors.Is(err, room.ErrDirect):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, room.ErrBanned):
+				w.WriteHeader(http.StatusForbidden)
+			case errors.Is(err, room.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				logger.Error("failed to join room", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(w).Encode(roomResponse(gotRoom)); err != nil {
+			logger.Error("failed to encode json response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.audit(r, audit.ActionRoomJoin, strconv.FormatInt(id, 10))
+
+		return
+	}
+}
+
+// roomResponse converts rm into a response with the fields every endpoint returns.
+func roomResponse(rm *room.Room) RoomResponse {
+	response := RoomResponse{Id: rm.Id, Name: rm.Name, Type: string(rm.Type), Topic: rm.Topic, Icon: rm.Icon, Owner: rm.Owner()}
+
+	switch {
+	case rm.IsDirect():
+		response.Users = rm.Users
+	case rm.IsPrivate():
+		response.Visibility = "private"
+	default:
+		response.Visibility = "public"
+	}
+
+	return response
 }
@@ -0,0 +1,871 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/fake"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/util"
+)
+
+// withUserId returns r as if SessionAuthMiddleware had authenticated userId. An empty userId leaves r unauthenticated.
+func withUserId(r *http.Request, userId string) *http.Request {
+	if userId == "" {
+		return r
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), "userID", userId))
+}
+
+func TestServer_HandleRoomCreate(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	validRequest := RoomCreateRequest{Name: "the big apple"}
+	createdRoom := &room.Room{Id: 1, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}}
+
+	tests := map[string]struct {
+		body             any
+		userId           string
+		roomService      *fake.RoomService
+		expectedStatus   int
+		expectedCalls    []room.CreateRoomOpts
+		expectedResponse RoomResponse
+	}{
+		"valid": {
+			body:             validRequest,
+			userId:           "spiderman",
+			roomService:      &fake.RoomService{ExpectedCreateRoom: createdRoom},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []room.CreateRoomOpts{{Name: "the big apple", UserId: "spiderman"}},
+			expectedResponse: RoomResponse{Id: 1, Name: "the big apple", Visibility: "public", Owner: "spiderman"},
+		},
+		"private": {
+			body:             RoomCreateRequest{Name: "sanctum", Visibility: "private"},
+			userId:           "strange",
+			roomService:      &fake.RoomService{ExpectedCreateRoom: &room.Room{Id: 2, Name: "sanctum", Visibility: room.VisibilityPrivate}},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []room.CreateRoomOpts{{Name: "sanctum", UserId: "strange", Visibility: room.VisibilityPrivate}},
+			expectedResponse: RoomResponse{Id: 2, Name: "sanctum", Visibility: "private"},
+		},
+		"invalid visibility": {
+			body:           RoomCreateRequest{Name: "sanctum", Visibility: "secret"},
+			userId:         "strange",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"empty name": {
+			body:           RoomCreateRequest{},
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"invalid body": {
+			body:           "the big apple",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"unauthenticated": {
+			body:           validRequest,
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			body:           validRequest,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedCreateError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []room.CreateRoomOpts{{Name: "the big apple", UserId: "spiderman"}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/api/rooms", util.StructToReaderOrDie(input.body))
+			validated(t, "POST /api/rooms", s.handleRoomCreate())(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.CreateCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.CreateCalls, input.expectedCalls)
+			}
+
+			if input.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response RoomResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(response, input.expectedResponse) {
+				t.Fatalf("got room %v, expected %v", response, input.expectedResponse)
+			}
+		})
+	}
+}
+
+func TestServer_HandleRoomList(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+
+	rooms := []*room.Room{
+		{Id: 1, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}},
+		{Id: 2, Name: "queens", Users: []string{"venom"}, Admins: []string{"venom"}},
+		{Id: 3, Type: room.TypeDirect, Users: []string{"spiderman", "venom"}},
+		{Id: 4, Name: "sanctum", Visibility: room.VisibilityPrivate, Users: []string{"strange", "venom"}, Admins: []string{"strange"}},
+	}
+
+	tests := map[string]struct {
+		userId           string
+		roomService      *fake.RoomService
+		expectedStatus   int
+		expectedResponse []RoomResponse
+	}{
+		"valid": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedListRooms: rooms},
+			expectedStatus: http.StatusOK,
+			expectedResponse: []RoomResponse{
+				{Id: 1, Name: "the big apple", Visibility: "public", Owner: "spiderman"},
+				{Id: 2, Name: "queens", Visibility: "public", Owner: "venom"},
+			},
+		},
+		"private member": {
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedListRooms: rooms},
+			expectedStatus: http.StatusOK,
+			expectedResponse: []RoomResponse{
+				{Id: 1, Name: "the big apple", Visibility: "public", Owner: "spiderman"},
+				{Id: 2, Name: "queens", Visibility: "public", Owner: "venom"},
+				{Id: 4, Name: "sanctum", Visibility: "private", Owner: "strange"},
+			},
+		},
+		"empty": {
+			roomService:      &fake.RoomService{ExpectedListRooms: []*room.Room{}},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: []RoomResponse{},
+		},
+		"service error": {
+			roomService:    &fake.RoomService{ExpectedListError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			s.handleRoomList()(recorder, withUserId(httptest.NewRequest(http.MethodGet, "/api/rooms", nil), input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if input.roomService.ListCalls != 1 {
+				t.Fatalf("got %d calls, expected 1", input.roomService.ListCalls)
+			}
+
+			if input.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response []RoomResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(response, input.expectedResponse) {
+				t.Fatalf("got rooms %v, expected %v", response, input.expectedResponse)
+			}
+		})
+	}
+}
+
+func TestServer_HandleRoomGet(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+
+	tests := map[string]struct {
+		id               string
+		userId           string
+		roomService      *fake.RoomService
+		expectedStatus   int
+		expectedCalls    []room.GetRoomByIdOpts
+		expectedResponse RoomResponse
+	}{
+		"valid": {
+			id:               "1",
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Name: "the big apple"}},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []room.GetRoomByIdOpts{{Id: 1}},
+			expectedResponse: RoomResponse{Id: 1, Name: "the big apple", Visibility: "public", PinLimit: room.DefaultPinLimit},
+		},
+		"pin settings": {
+			id:               "1",
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Name: "the big apple", Pinners: []string{"mj"}, PinLimit: 3}},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []room.GetRoomByIdOpts{{Id: 1}},
+			expectedResponse: RoomResponse{Id: 1, Name: "the big apple", Visibility: "public", Pinners: []string{"mj"}, PinLimit: 3},
+		},
+		"settings": {
+			id:               "1",
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Name: "queens", Topic: "friendly neighborhood", Icon: "🕷️", Users: []string{"spiderman", "venom"}, Admins: []string{"venom", "spiderman"}}},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []room.GetRoomByIdOpts{{Id: 1}},
+			expectedResponse: RoomResponse{Id: 1, Name: "queens", Visibility: "public", Topic: "friendly neighborhood", Icon: "🕷️", Owner: "venom", Admins: []string{"venom", "spiderman"}, PinLimit: room.DefaultPinLimit},
+		},
+		"direct message": {
+			id:               "1",
+			userId:           "spiderman",
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Type: room.TypeDirect, Users: []string{"spiderman", "venom"}}},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []room.GetRoomByIdOpts{{Id: 1}},
+			expectedResponse: RoomResponse{Id: 1, Type: "direct", Users: []string{"spiderman", "venom"}, PinLimit: room.DefaultPinLimit},
+		},
+		"private outsider": {
+			id:             "1",
+			userId:         "mj",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Name: "sanctum", Visibility: room.VisibilityPrivate, Users: []string{"strange"}}},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  []room.GetRoomByIdOpts{{Id: 1}},
+		},
+		"direct message outsider": {
+			id:             "1",
+			userId:         "mj",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 1, Type: room.TypeDirect, Users: []string{"spiderman", "venom"}}},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  []room.GetRoomByIdOpts{{Id: 1}},
+		},
+		"not found": {
+			id:             "2",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCalls:  []room.GetRoomByIdOpts{{Id: 2}},
+		},
+		"invalid id": {
+			id:             "queens",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/api/rooms/"+input.id, nil)
+			request.SetPathValue("id", input.id)
+			s.handleRoomGet()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.GetRoomByIdCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.GetRoomByIdCalls, input.expectedCalls)
+			}
+
+			if input.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response RoomResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(response, input.expectedResponse) {
+				t.Fatalf("got room %v, expected %v", response, input.expectedResponse)
+			}
+		})
+	}
+}
+
+func TestServer_HandleRoomDelete(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	apple := &room.Room{Id: 1, Name: "the big apple", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman"}}
+
+	tests := map[string]struct {
+		id                 string
+		userId             string
+		roomService        *fake.RoomService
+		expectedStatus     int
+		expectedCalls      []room.DeleteRoomOpts
+		expectedPurgeCalls []message.PurgeMessageOpts
+	}{
+		"valid": {
+			id:                 "1",
+			userId:             "spiderman",
+			roomService:        &fake.RoomService{ExpectedGetRoomByIdRoom: apple},
+			expectedStatus:     http.StatusOK,
+			expectedCalls:      []room.DeleteRoomOpts{{Id: 1, UserId: "spiderman"}},
+			expectedPurgeCalls: []message.PurgeMessageOpts{{RoomId: 1}},
+		},
+		// Nothing is deleted unless the caller may delete the room.
+		"not an admin": {
+			id:             "1",
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: apple},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"not found": {
+			id:             "2",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"service error": {
+			id:                 "1",
+			userId:             "spiderman",
+			roomService:        &fake.RoomService{ExpectedGetRoomByIdRoom: apple, ExpectedDeleteError: errors.New("oops")},
+			expectedStatus:     http.StatusInternalServerError,
+			expectedCalls:      []room.DeleteRoomOpts{{Id: 1, UserId: "spiderman"}},
+			expectedPurgeCalls: []message.PurgeMessageOpts{{RoomId: 1}},
+		},
+		"invalid id": {
+			id:             "queens",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"unauthenticated": {
+			id:             "1",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			messageService := &fake.MessageService{}
+
+			s.RoomService = input.roomService
+			s.MessageService = messageService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodDelete, "/api/rooms/"+input.id, nil)
+			request.SetPathValue("id", input.id)
+			s.handleRoomDelete()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.DeleteCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.DeleteCalls, input.expectedCalls)
+			}
+
+			if !reflect.DeepEqual(messageService.PurgeCalls, input.expectedPurgeCalls) {
+				t.Fatalf("got purge calls %v, expected %v", messageService.PurgeCalls, input.expectedPurgeCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleRoomJoin(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	now := time.UnixMilli(1_000_000)
+	s.Clock = util.ClockFunc(func() time.Time { return now })
+
+	publicRoom := &room.Room{Id: 1, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}}
+	privateRoom := &room.Room{Id: 2, Name: "sanctum", Visibility: room.VisibilityPrivate, Users: []string{"strange"}, Admins: []string{"strange"}}
+
+	tests := map[string]struct {
+		id             string
+		userId         string
+		roomService    *fake.RoomService
+		expectedStatus int
+		expectedCalls  []room.JoinRoomOpts
+	}{
+		"public": {
+			id:             "1",
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: publicRoom},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.JoinRoomOpts{{Id: 1, UserId: "venom", Now: 1_000_000}},
+		},
+		"private": {
+			id:             "2",
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"private member": {
+			id:             "2",
+			userId:         "strange",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: privateRoom},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.JoinRoomOpts{{Id: 2, UserId: "strange", Now: 1_000_000}},
+		},
+		"direct message": {
+			id:             "3",
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: &room.Room{Id: 3, Type: room.TypeDirect}, ExpectedJoinError: room.ErrDirect},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []room.JoinRoomOpts{{Id: 3, UserId: "venom", Now: 1_000_000}},
+		},
+		"banned": {
+			id:             "1",
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: publicRoom, ExpectedJoinError: room.ErrBanned},
+			expectedStatus: http.StatusForbidden,
+			expectedCalls:  []room.JoinRoomOpts{{Id: 1, UserId: "venom", Now: 1_000_000}},
+		},
+		"not found": {
+			id:             "4",
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+		},
+		"invalid id": {
+			id:             "queens",
+			userId:         "venom",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"unauthenticated": {
+			id:             "1",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: publicRoom},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			id:             "1",
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: publicRoom, ExpectedJoinError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []room.JoinRoomOpts{{Id: 1, UserId: "venom", Now: 1_000_000}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/api/rooms/"+input.id+"/join", nil)
+			request.SetPathValue("id", input.id)
+			s.handleRoomJoin()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.JoinCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.JoinCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleRoomUpdate(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	updatedRoom := &room.Room{Id: 1, Name: "queens", Topic: "friendly neighborhood", Users: []string{"spiderman"}, Admins: []string{"spiderman"}}
+	name := "queens"
+	topic := "friendly neighborhood"
+
+	tests := map[string]struct {
+		id               string
+		body             string
+		userId           string
+		roomService      *fake.RoomService
+		expectedStatus   int
+		expectedCalls    []room.UpdateRoomOpts
+		expectedResponse RoomResponse
+	}{
+		"valid": {
+			id:               "1",
+			body:             `{"name":"queens","topic":"friendly neighborhood"}`,
+			userId:           "spiderman",
+			roomService:      &fake.RoomService{ExpectedUpdateRoom: updatedRoom},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []room.UpdateRoomOpts{{Id: 1, UserId: "spiderman", Name: &name, Topic: &topic}},
+			expectedResponse: RoomResponse{Id: 1, Name: "queens", Visibility: "public", Topic: "friendly neighborhood", Owner: "spiderman"},
+		},
+		"not an admin": {
+			id:             "1",
+			body:           `{"name":"queens"}`,
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedUpdateError: room.ErrUnauthorized},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  []room.UpdateRoomOpts{{Id: 1, UserId: "venom", Name: &name}},
+		},
+		"direct message": {
+			id:             "1",
+			body:           `{"name":"queens"}`,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedUpdateError: room.ErrDirect},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []room.UpdateRoomOpts{{Id: 1, UserId: "spiderman", Name: &name}},
+		},
+		"invalid body": {
+			id:             "1",
+			body:           `{"name":`,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"not found": {
+			id:             "2",
+			body:           `{"name":"queens"}`,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedUpdateError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCalls:  []room.UpdateRoomOpts{{Id: 2, UserId: "spiderman", Name: &name}},
+		},
+		"invalid id": {
+			id:             "queens",
+			body:           `{"name":"queens"}`,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"unauthenticated": {
+			id:             "1",
+			body:           `{"name":"queens"}`,
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			id:             "1",
+			body:           `{"name":"queens"}`,
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedUpdateError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []room.UpdateRoomOpts{{Id: 1, UserId: "spiderman", Name: &name}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPatch, "/api/rooms/"+input.id, strings.NewReader(input.body))
+			request.SetPathValue("id", input.id)
+			s.handleRoomUpdate()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.UpdateCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.UpdateCalls, input.expectedCalls)
+			}
+
+			if recorder.Code != http.StatusOK {
+				return
+			}
+
+			var response RoomResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(response, input.expectedResponse) {
+				t.Fatalf("got %v, expected %v", response, input.expectedResponse)
+			}
+		})
+	}
+}
+
+func TestServer_HandleRoomPromote(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	now := time.UnixMilli(1_000_000)
+	s.Clock = util.ClockFunc(func() time.Time { return now })
+	validRequest := RoomMemberRequest{Username: "venom"}
+
+	tests := map[string]struct {
+		id             string
+		body           any
+		userId         string
+		userService    *fake.UserService
+		roomService    *fake.RoomService
+		expectedStatus int
+		expectedCalls  []room.PromoteRoomOpts
+	}{
+		"valid": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.PromoteRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+		"not an admin": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "mj",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedPromoteError: room.ErrUnauthorized},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  []room.PromoteRoomOpts{{Id: 1, UserId: "mj", TargetId: "venom", Now: 1_000_000}},
+		},
+		"missing username": {
+			id:             "1",
+			body:           RoomMemberRequest{},
+			userId:         "spiderman",
+			userService:    &fake.UserService{},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"unknown user": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdError: user.ErrNotFound},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"not a member": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedPromoteError: room.ErrNotMember},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []room.PromoteRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+		"direct message": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedPromoteError: room.ErrDirect},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []room.PromoteRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+		"banned": {
+			id:             "1",
+			body:           validRequest,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedPromoteError: room.ErrBanned},
+			expectedStatus: http.StatusForbidden,
+			expectedCalls:  []room.PromoteRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+		"room not found": {
+			id:             "2",
+			body:           validRequest,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedPromoteError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCalls:  []room.PromoteRoomOpts{{Id: 2, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+		"unauthenticated": {
+			id:             "1",
+			body:           validRequest,
+			userService:    &fake.UserService{},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.UserService = input.userService
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/api/rooms/"+input.id+"/admins", util.StructToReaderOrDie(input.body))
+			request.SetPathValue("id", input.id)
+			s.handleRoomPromote()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.PromoteCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.PromoteCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleRoomDemote(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+
+	tests := map[string]struct {
+		userId         string
+		roomService    *fake.RoomService
+		expectedStatus int
+		expectedCalls  []room.DemoteRoomOpts
+	}{
+		"valid": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.DemoteRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom"}},
+		},
+		"not the owner": {
+			userId:         "mj",
+			roomService:    &fake.RoomService{ExpectedDemoteError: room.ErrUnauthorized},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  []room.DemoteRoomOpts{{Id: 1, UserId: "mj", TargetId: "venom"}},
+		},
+		"the owner": {
+			userId:         "venom",
+			roomService:    &fake.RoomService{ExpectedDemoteError: room.ErrOwner},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []room.DemoteRoomOpts{{Id: 1, UserId: "venom", TargetId: "venom"}},
+		},
+		"not found": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedDemoteError: room.ErrNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCalls:  []room.DemoteRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom"}},
+		},
+		"unauthenticated": {
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedDemoteError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []room.DemoteRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom"}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodDelete, "/api/rooms/1/admins/venom", nil)
+			request.SetPathValue("id", "1")
+			request.SetPathValue("username", "venom")
+			s.handleRoomDemote()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.DemoteCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.DemoteCalls, input.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestServer_HandleRoomTransfer(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	now := time.UnixMilli(1_000_000)
+	s.Clock = util.ClockFunc(func() time.Time { return now })
+	validRequest := RoomMemberRequest{Username: "venom"}
+
+	tests := map[string]struct {
+		body           any
+		userId         string
+		userService    *fake.UserService
+		roomService    *fake.RoomService
+		expectedStatus int
+		expectedCalls  []room.TransferRoomOpts
+	}{
+		"valid": {
+			body:           validRequest,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []room.TransferRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+		"not the owner": {
+			body:           validRequest,
+			userId:         "mj",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedTransferError: room.ErrUnauthorized},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  []room.TransferRoomOpts{{Id: 1, UserId: "mj", TargetId: "venom", Now: 1_000_000}},
+		},
+		"unknown user": {
+			body:           validRequest,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdError: user.ErrNotFound},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"missing username": {
+			body:           RoomMemberRequest{},
+			userId:         "spiderman",
+			userService:    &fake.UserService{},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"not a member": {
+			body:           validRequest,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedTransferError: room.ErrNotMember},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []room.TransferRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+		"banned": {
+			body:           validRequest,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedTransferError: room.ErrBanned},
+			expectedStatus: http.StatusForbidden,
+			expectedCalls:  []room.TransferRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+		"direct message": {
+			body:           validRequest,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedTransferError: room.ErrDirect},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []room.TransferRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+		"unauthenticated": {
+			body:           validRequest,
+			userService:    &fake.UserService{},
+			roomService:    &fake.RoomService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			body:           validRequest,
+			userId:         "spiderman",
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom"}},
+			roomService:    &fake.RoomService{ExpectedTransferError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []room.TransferRoomOpts{{Id: 1, UserId: "spiderman", TargetId: "venom", Now: 1_000_000}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.UserService = input.userService
+			s.RoomService = input.roomService
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPut, "/api/rooms/1/owner", util.StructToReaderOrDie(input.body))
+			request.SetPathValue("id", "1")
+			s.handleRoomTransfer()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.roomService.TransferCalls, input.expectedCalls) {
+				t.Fatalf("got calls %v, expected %v", input.roomService.TransferCalls, input.expectedCalls)
+			}
+		})
+	}
+}
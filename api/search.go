@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/search"
+)
+
+type MessageSearchResponse struct {
+	// The id of the room the message is in.
+	RoomId int64 `json:"room_id"`
+
+	// The message found.
+	Message MessageResponse `json:"message"`
+
+	// How relevant the message is to the query. Higher is more relevant.
+	Score float64 `json:"score"`
+}
+
+// handleMessageSearch searches the messages of every room the caller can read
+//
+//	@Summary		Search messages
+//	@Description	Every word of the query must appear in a message for it to match. Text in double quotes matches as a
+//	@Description	phrase, and a word ending in * matches any word starting with it. Results are ranked by relevance, most
+//	@Description	relevant first. Only rooms the caller can read are searched.
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Param			q		query	string	true	"what to search for"
+//	@Param			room	query	int		false	"room id to search in"
+//	@Param			author	query	string	false	"username of the author"
+//	@Param			before	query	string	false	"only messages before this time, in milliseconds since epoch or RFC 3339"
+//	@Param			after	query	string	false	"only messages after this time, in milliseconds since epoch or RFC 3339"
+//	@Param			limit	query	int		false	"most results to return"	minimum(1)	maximum(100)	default(50)
+//	@Security		ApiKey
+//	@Success		200	{array}		MessageSearchResponse
+//	@Failure		400	{object}	Error
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/search/messages [get]
+func (s *Server) handleMessageSearch() http.HandlerFunc {
+	logger := slog.New(s.logHandler).With(slog.String("handler", "MessageSearch"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := r.Context().Value("userID").(string)
+		if !ok {
+			logger.Error("failed to lookup apikey in request context")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		query := r.URL.Query()
+		opts := search.SearchOpts{Query: query.Get("q"), UserId: query.Get("author")}
+
+		var err error
+		if opts.Limit, err = parseLimit(r); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if v := query.Get("before"); v != "" {
+			if opts.Before, err = parseTimestamp(v); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		if v := query.Get("after"); v != "" {
+			if opts.After, err = parseTimestamp(v); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		if v := query.Get("room"); v != "" {
+			roomId, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			gotRoom, err := s.RoomService.GetRoomById(r.Context(), room.GetRoomByIdOpts{Id: roomId})
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			if !gotRoom.CanRead(userId) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			opts.RoomIds = []int64{roomId}
+		} else if opts.RoomIds, err = s.readableRooms(r, userId); err != nil {
+			logger.Error("failed to list rooms", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		results, err := s.SearchService.Search(r.Context(), opts)
+		if errors.Is(err, search.ErrInvalidQuery) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		} else if err != nil {
+			logger.Error("failed to search messages", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// The index may briefly lag behind deletions, so results whose message is gone are skipped.
+		found := make([]*search.Result, 0, len(results))
+		messages := make([]*message.Message, 0, len(results))
+		for _, result := range results {
+			msg, err := s.MessageService.GetMessageById(r.Context(), message.GetMessageByIdOpts{Id: result.MessageId})
+			if errors.Is(err, message.ErrNotFound) {
+				continue
+			} else if err != nil {
+				logger.Error("failed to get message", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			found = append(found, result)
+			messages = append(messages, msg)
+		}
+
+		responses, err := s.messageResponses(r, messages, userId)
+		if err != nil {
+			logger.Error("failed to build message responses", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]MessageSearchResponse, 0, len(found))
+		for i, result := range found {
+			response = append(response, MessageSearchResponse{RoomId: result.RoomId, Message: responses[i], Score: result.Score})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode json response", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		logger.Debug("messages searched", slog.String("user_id", userId), slog.Int("results", len(response)))
+	}
+}
+
+// readableRooms returns the ids of every room userId can read.
+func (s *Server) readableRooms(r *http.Request, userId string) ([]int64, error) {
+	rooms, err := s.RoomService.List(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(rooms))
+	for _, rm := range rooms {
+		if rm.CanRead(userId) {
+			ids = append(ids, rm.Id)
+		}
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/worsediscord/server/services/fake"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/search"
+	"github.com/worsediscord/server/util"
+)
+
+func TestServer_HandleMessageSearch(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	rooms := []*room.Room{
+		{Id: 1, Name: "the big apple", Users: []string{"spiderman", "venom"}},
+		{Id: 2, Name: "sanctum", Visibility: room.VisibilityPrivate, Users: []string{"strange"}},
+		{Id: 3, Type: room.TypeDirect, Users: []string{"spiderman", "mj"}},
+	}
+	found := &message.Message{Id: "a", UserId: "venom", RoomId: 1, Content: "we are venom", Timestamp: 1000}
+
+	tests := map[string]struct {
+		query            string
+		userId           string
+		roomService      *fake.RoomService
+		messageService   *fake.MessageService
+		searchService    *fake.SearchService
+		expectedStatus   int
+		expectedCalls    []search.SearchOpts
+		expectedResponse []MessageSearchResponse
+	}{
+		"readable rooms": {
+			query:          "?q=venom",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedListRooms: rooms},
+			messageService: &fake.MessageService{ExpectedGetMessageByIdMessage: found},
+			searchService:  &fake.SearchService{ExpectedSearchResults: []*search.Result{{MessageId: "a", RoomId: 1, Timestamp: 1000, Score: 1.5}}},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  []search.SearchOpts{{Query: "venom", RoomIds: []int64{1, 3}, Limit: defaultPageSize}},
+			expectedResponse: []MessageSearchResponse{
+				{RoomId: 1, Message: MessageResponse{Id: "a", UserId: "venom", Content: "we are venom", Timestamp: 1000}, Score: 1.5},
+			},
+		},
+		"filters": {
+			query:            "?q=venom&author=venom&before=2000&after=1970-01-01T00:00:00.5Z&limit=10",
+			userId:           "strange",
+			roomService:      &fake.RoomService{ExpectedListRooms: rooms},
+			messageService:   &fake.MessageService{},
+			searchService:    &fake.SearchService{},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []search.SearchOpts{{Query: "venom", RoomIds: []int64{1, 2}, UserId: "venom", Before: 2000, After: 500, Limit: 10}},
+			expectedResponse: []MessageSearchResponse{},
+		},
+		"room": {
+			query:            "?q=venom&room=1",
+			userId:           "strange",
+			roomService:      &fake.RoomService{ExpectedGetRoomByIdRoom: rooms[0]},
+			messageService:   &fake.MessageService{},
+			searchService:    &fake.SearchService{},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []search.SearchOpts{{Query: "venom", RoomIds: []int64{1}, Limit: defaultPageSize}},
+			expectedResponse: []MessageSearchResponse{},
+		},
+		"unreadable room": {
+			query:          "?q=venom&room=2",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdRoom: rooms[1]},
+			messageService: &fake.MessageService{},
+			searchService:  &fake.SearchService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"room not found": {
+			query:          "?q=venom&room=4",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedGetRoomByIdError: room.ErrNotFound},
+			messageService: &fake.MessageService{},
+			searchService:  &fake.SearchService{},
+			expectedStatus: http.StatusNotFound,
+		},
+		"deleted message": {
+			query:            "?q=venom",
+			userId:           "spiderman",
+			roomService:      &fake.RoomService{ExpectedListRooms: rooms},
+			messageService:   &fake.MessageService{ExpectedGetMessageByIdError: message.ErrNotFound},
+			searchService:    &fake.SearchService{ExpectedSearchResults: []*search.Result{{MessageId: "a", RoomId: 1}}},
+			expectedStatus:   http.StatusOK,
+			expectedCalls:    []search.SearchOpts{{Query: "venom", RoomIds: []int64{1, 3}, Limit: defaultPageSize}},
+			expectedResponse: []MessageSearchResponse{},
+		},
+		"invalid query": {
+			query:          "?q=%22%22",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedListRooms: rooms},
+			messageService: &fake.MessageService{},
+			searchService:  &fake.SearchService{ExpectedSearchError: search.ErrInvalidQuery},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  []search.SearchOpts{{Query: `""`, RoomIds: []int64{1, 3}, Limit: defaultPageSize}},
+		},
+		"invalid room": {
+			query:          "?q=venom&room=apple",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			messageService: &fake.MessageService{},
+			searchService:  &fake.SearchService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"invalid before": {
+			query:          "?q=venom&before=yesterday",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			messageService: &fake.MessageService{},
+			searchService:  &fake.SearchService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"invalid limit": {
+			query:          "?q=venom&limit=1000",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{},
+			messageService: &fake.MessageService{},
+			searchService:  &fake.SearchService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"unauthenticated": {
+			query:          "?q=venom",
+			roomService:    &fake.RoomService{ExpectedListRooms: rooms},
+			messageService: &fake.MessageService{},
+			searchService:  &fake.SearchService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			query:          "?q=venom",
+			userId:         "spiderman",
+			roomService:    &fake.RoomService{ExpectedListRooms: rooms},
+			messageService: &fake.MessageService{},
+			searchService:  &fake.SearchService{ExpectedSearchError: errors.New("oops")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  []search.SearchOpts{{Query: "venom", RoomIds: []int64{1, 3}, Limit: defaultPageSize}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s.RoomService = input.roomService
+			s.MessageService = input.messageService
+			s.SearchService = input.searchService
+
+			request := withUserId(httptest.NewRequest(http.MethodGet, "/api/search/messages"+input.query, nil), input.userId)
+
+			recorder := httptest.NewRecorder()
+			s.handleMessageSearch()(recorder, request)
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if !reflect.DeepEqual(input.searchService.SearchCalls, input.expectedCalls) {
+				t.Fatalf("got calls %+v, expected %+v", input.searchService.SearchCalls, input.expectedCalls)
+			}
+
+			if input.expectedResponse == nil {
+				return
+			}
+
+			var got []MessageSearchResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, input.expectedResponse) {
+				t.Fatalf("got %+v, expected %+v", got, input.expectedResponse)
+			}
+		})
+	}
+}
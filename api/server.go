@@ -17,29 +17,51 @@ package api
 import (
 	"log/slog"
 	"net/http"
-	"regexp"
+	"time"
 
+	"github.com/worsediscord/server/services/audit"
 	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/cascade"
+	"github.com/worsediscord/server/services/invite"
 	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/reaction"
 	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/search"
 	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/util"
 )
 
-var alphaNumericRegex *regexp.Regexp
-
 type Server struct {
-	UserService    user.Service
-	RoomService    room.Service
-	MessageService message.Service
-	AuthService    auth.Service
-
-	mux        *http.ServeMux
-	logHandler slog.Handler
-	middleware []Middleware
-}
-
-func init() {
-	alphaNumericRegex = regexp.MustCompile("^[a-zA-Z0-9_.]*$")
+	UserService     user.Service
+	RoomService     room.Service
+	MessageService  message.Service
+	ReactionService reaction.Service
+	PinService      pin.Service
+	InviteService   invite.Service
+	AuthService     auth.Service
+	AuditService    audit.Service
+
+	// SearchService answers message searches. It's only kept up to date if MessageService indexes messages into it, as
+	// a search.MessageService does. Defaults to an empty search.Map.
+	SearchService search.Service
+
+	// Clock issues and expires sessions and invites. Defaults to util.SystemClock.
+	Clock util.Clock
+
+	// DeletedUserMessages decides whether the messages of deleted users are anonymized or deleted. Defaults to
+	// cascade.PolicyAnonymize.
+	DeletedUserMessages cascade.Policy
+
+	// AdminListenerOnly hides the /api/admin routes from ServeHTTP so that they are only reachable via AdminHandler.
+	AdminListenerOnly bool
+
+	mux         *http.ServeMux
+	adminMux    *http.ServeMux
+	logHandler  slog.Handler
+	auditLogger *slog.Logger
+	middleware  []Middleware
+	startTime   time.Time
 }
 
 func NewServer(
@@ -51,35 +73,130 @@ func NewServer(
 	middleware ...Middleware,
 ) *Server {
 	s := Server{
-		UserService:    userService,
-		RoomService:    roomService,
-		MessageService: messageService,
-		AuthService:    authService,
-		logHandler:     logHandler,
-		mux:            http.NewServeMux(),
-		middleware:     middleware,
+		UserService:     userService,
+		RoomService:     roomService,
+		MessageService:  messageService,
+		ReactionService: reaction.NewMap(),
+		PinService:      pin.NewMap(),
+		InviteService:   invite.NewMap(),
+		AuthService:     authService,
+		AuditService:    audit.NewMap(),
+		SearchService:   search.NewMap(),
+		Clock:           util.SystemClock,
+		logHandler:      logHandler,
+		auditLogger:     slog.New(logHandler).With(slog.String("component", "audit")),
+		startTime:       time.Now(),
+		mux:             http.NewServeMux(),
+		adminMux:        http.NewServeMux(),
+		middleware:      middleware,
 	}
 
-	authHandler := SessionAuthMiddleware(logHandler, authService)
+	// The clock is looked up on every request so that it can be replaced after the server is created.
+	sessionHandler := SessionAuthMiddleware(logHandler, authService, userService, util.ClockFunc(func() time.Time { return s.Clock.Now() }))
+	validateHandler, err := RequestValidationMiddleware(logHandler, MaxRequestBodyBytes)
+	if err != nil {
+		// The spec is embedded, so this only happens if the generated spec is broken. Requests are refused rather than
+		// passed through unvalidated.
+		slog.New(logHandler).Error("failed to load request validation", slog.String("error", err.Error()))
+		validateHandler = func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+		}
+	}
+	authHandler := func(h http.Handler) http.Handler {
+		return sessionHandler(validateHandler(h))
+	}
+	adminHandler := func(h http.Handler) http.Handler {
+		return sessionHandler(AdminAuthMiddleware(logHandler, userService)(validateHandler(h)))
+	}
 
 	s.mux.Handle("GET /api/health", s.handleHealth())
 
+	s.mux.Handle("GET /api/docs/{$}", s.handleDocsUI())
+	s.mux.Handle("GET /api/docs/openapi.json", s.handleDocsSpec())
+	s.mux.Handle("GET /api/docs/", s.handleDocsAssets())
+
 	s.mux.Handle("GET /api/users", authHandler(s.handleUserList()))
-	s.mux.Handle("POST /api/users", s.handleUserCreate())
+	s.mux.Handle("POST /api/users", validateHandler(s.handleUserCreate()))
 
-	s.mux.Handle("POST /api/users/login", s.handleUserLogin())
+	s.mux.Handle("POST /api/users/login", validateHandler(s.handleUserLogin()))
 
 	s.mux.Handle("GET /api/users/{id}", authHandler(s.handleUserGet()))
 	s.mux.Handle("DELETE /api/users/{id}", authHandler(s.handleUserDelete()))
+	s.mux.Handle("POST /api/users/{id}/dm", authHandler(s.handleDirectMessageCreate()))
 
 	s.mux.Handle("GET /api/rooms", authHandler(s.handleRoomList()))
 	s.mux.Handle("POST /api/rooms", authHandler(s.handleRoomCreate()))
 
 	s.mux.Handle("GET /api/rooms/{id}", authHandler(s.handleRoomGet()))
+	s.mux.Handle("PATCH /api/rooms/{id}", authHandler(s.handleRoomUpdate()))
 	s.mux.Handle("DELETE /api/rooms/{id}", authHandler(s.handleRoomDelete()))
+	s.mux.Handle("POST /api/rooms/{id}/join", authHandler(s.handleRoomJoin()))
+	s.mux.Handle("POST /api/rooms/{id}/admins", authHandler(s.handleRoomPromote()))
+	s.mux.Handle("DELETE /api/rooms/{id}/admins/{username}", authHandler(s.handleRoomDemote()))
+	s.mux.Handle("PUT /api/rooms/{id}/owner", authHandler(s.handleRoomTransfer()))
+	s.mux.Handle("DELETE /api/rooms/{id}/members/{username}", authHandler(s.handleRoomKick()))
+
+	s.mux.Handle("GET /api/rooms/{id}/bans", authHandler(s.handleRoomBanList()))
+	s.mux.Handle("PUT /api/rooms/{id}/bans/{username}", authHandler(s.handleRoomBan()))
+	s.mux.Handle("DELETE /api/rooms/{id}/bans/{username}", authHandler(s.handleRoomUnban()))
+	s.mux.Handle("GET /api/rooms/{id}/mutes", authHandler(s.handleRoomMuteList()))
+	s.mux.Handle("PUT /api/rooms/{id}/mutes/{username}", authHandler(s.handleRoomMute()))
+	s.mux.Handle("DELETE /api/rooms/{id}/mutes/{username}", authHandler(s.handleRoomUnmute()))
+
+	s.mux.Handle("GET /api/rooms/{id}/invites", authHandler(s.handleInviteList()))
+	s.mux.Handle("POST /api/rooms/{id}/invites", authHandler(s.handleInviteCreate()))
+	s.mux.Handle("DELETE /api/rooms/{id}/invites/{code}", authHandler(s.handleInviteRevoke()))
+	s.mux.Handle("POST /api/invites/{code}", authHandler(s.handleInviteAccept()))
 
 	s.mux.Handle("GET /api/rooms/{id}/messages", authHandler(s.handleMessageList()))
 	s.mux.Handle("POST /api/rooms/{id}/messages", authHandler(s.handleMessageCreate()))
+	s.mux.Handle("DELETE /api/rooms/{id}/messages/{messageId}", authHandler(s.handleMessageDelete()))
+	s.mux.Handle("GET /api/rooms/{id}/messages/{messageId}/thread", authHandler(s.handleMessageThread()))
+
+	s.mux.Handle("GET /api/rooms/{id}/messages/{messageId}/reactions/{emoji}", authHandler(s.handleReactionList()))
+	s.mux.Handle("PUT /api/rooms/{id}/messages/{messageId}/reactions/{emoji}", authHandler(s.handleReactionAdd()))
+	s.mux.Handle("DELETE /api/rooms/{id}/messages/{messageId}/reactions/{emoji}", authHandler(s.handleReactionRemove()))
+
+	s.mux.Handle("GET /api/rooms/{id}/pins", authHandler(s.handlePinList()))
+	s.mux.Handle("PUT /api/rooms/{id}/pins/{messageId}", authHandler(s.handlePinAdd()))
+	s.mux.Handle("DELETE /api/rooms/{id}/pins/{messageId}", authHandler(s.handlePinRemove()))
+	s.mux.Handle("PUT /api/rooms/{id}/pin-settings", authHandler(s.handlePinSettings()))
+
+	s.mux.Handle("GET /api/search/messages", authHandler(s.handleMessageSearch()))
+
+	s.adminMux.Handle("GET /api/health", s.handleHealth())
+
+	s.adminMux.Handle("GET /api/docs/{$}", s.handleDocsUI())
+	s.adminMux.Handle("GET /api/docs/openapi.json", s.handleDocsSpec())
+	s.adminMux.Handle("GET /api/docs/", s.handleDocsAssets())
+
+	s.adminMux.Handle("GET /api/admin/users", adminHandler(s.handleAdminUserList()))
+	s.adminMux.Handle("PATCH /api/admin/users/{id}", adminHandler(s.handleAdminUserUpdate()))
+	s.adminMux.Handle("DELETE /api/admin/users/{id}", adminHandler(s.handleAdminUserDelete()))
+	s.adminMux.Handle("POST /api/admin/users/{id}/password", adminHandler(s.handleAdminUserPasswordReset()))
+
+	s.adminMux.Handle("GET /api/admin/rooms", adminHandler(s.handleAdminRoomList()))
+	s.adminMux.Handle("DELETE /api/admin/rooms/{id}", adminHandler(s.handleAdminRoomDelete()))
+	s.adminMux.Handle("POST /api/admin/rooms/{id}/admins", adminHandler(s.handleAdminRoomPromote()))
+	s.adminMux.Handle("PUT /api/admin/rooms/{id}/owner", adminHandler(s.handleAdminRoomTransfer()))
+
+	s.adminMux.Handle("GET /api/admin/sessions", adminHandler(s.handleAdminSessionList()))
+	s.adminMux.Handle("DELETE /api/admin/sessions/{id}", adminHandler(s.handleAdminSessionRevoke()))
+
+	s.adminMux.Handle("GET /api/admin/stats", adminHandler(s.handleAdminStats()))
+
+	s.adminMux.Handle("GET /api/admin/audit", adminHandler(s.handleAdminAuditList()))
+
+	s.mux.HandleFunc("/api/admin/", func(w http.ResponseWriter, r *http.Request) {
+		if s.AdminListenerOnly {
+			http.NotFound(w, r)
+			return
+		}
+
+		s.adminMux.ServeHTTP(w, r)
+	})
 
 	return &s
 }
@@ -89,13 +206,34 @@ func (s *Server) AddMiddleware(middleware ...Middleware) {
 }
 
 func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
-	var h http.Handler
+	s.wrap(s.mux).ServeHTTP(writer, request)
+}
+
+// AdminHandler returns the handler to be served on a dedicated administrative listener.
+func (s *Server) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		s.wrap(s.adminMux).ServeHTTP(writer, request)
+	})
+}
 
-	h = s.mux
+// deleter returns a cascade.Deleter over the server's current services.
+func (s *Server) deleter() *cascade.Deleter {
+	return &cascade.Deleter{
+		User:     s.UserService,
+		Room:     s.RoomService,
+		Message:  s.MessageService,
+		Reaction: s.ReactionService,
+		Pin:      s.PinService,
+		Invite:   s.InviteService,
+		Messages: s.DeletedUserMessages,
+	}
+}
 
+// wrap applies the server's middleware to h.
+func (s *Server) wrap(h http.Handler) http.Handler {
 	for i := len(s.middleware) - 1; i >= 0; i-- {
 		h = s.middleware[i](h)
 	}
 
-	h.ServeHTTP(writer, request)
+	return h
 }
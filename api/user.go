@@ -7,16 +7,17 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/worsediscord/server/services/audit"
 	"github.com/worsediscord/server/services/auth"
 	"github.com/worsediscord/server/services/user"
 )
 
 type UserCreateRequest struct {
-	// The globally unique username of the user.
-	Username string `json:"username,omitempty"`
+	// The globally unique username of the user. Only letters, digits, underscores and dots are allowed.
+	Username string `json:"username,omitempty" validate:"required" pattern:"^[a-zA-Z0-9_.]+$"`
 
 	// The password to set. Must be at least 8 characters long.
-	Password string `json:"password,omitempty"`
+	Password string `json:"password,omitempty" validate:"required" minLength:"8"`
 }
 
 type UserResponse struct {
@@ -39,7 +40,7 @@ type UserLoginResponse struct {
 //	@Produce	json
 //	@Param		credentials	body	UserCreateRequest	true	"username and password to create user with"
 //	@Success	200
-//	@Failure	400
+//	@Failure	400	{object}	Error
 //	@Failure	409
 //	@Failure	500
 //	@Router		/users [post]
@@ -55,12 +56,6 @@ func (s *Server) handleUserCreate() http.HandlerFunc {
 			return
 		}
 
-		if !request.Validate() {
-			logger.Error("request failed validation")
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-
 		if _, err := s.UserService.GetUserById(r.Context(), user.GetUserByIdOpts{Id: request.Username}); err == nil {
 			w.WriteHeader(http.StatusConflict)
 			return
@@ -68,12 +63,19 @@ func (s *Server) handleUserCreate() http.HandlerFunc {
 
 		opts := user.CreateUserOpts{Username: request.Username, Password: request.Password}
 		if err := s.UserService.Create(r.Context(), opts); err != nil {
-			logger.Error("failed to create user", slog.String("error", err.Error()))
-			w.WriteHeader(http.StatusInternalServerError)
+			switch {
+			case errors.Is(err, user.ErrInvalidUsername), errors.Is(err, user.ErrInvalidPassword):
+				w.WriteHeader(http.StatusBadRequest)
+			default:
+				logger.Error("failed to create user", slog.String("error", err.Error()))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+
 			return
 		}
 
 		logger.Info("user created", slog.String("username", request.Username))
+		s.auditAs(r, request.Username, audit.ActionUserCreate, request.Username)
 
 		return
 	}
@@ -86,8 +88,8 @@ func (s *Server) handleUserCreate() http.HandlerFunc {
 //	@Accept		json
 //	@Produce	json
 //	@Security	ApiKey
-//	@Success	200	{array}	UserResponse
-//	@Failure	400
+//	@Success	200	{array}		UserResponse
+//	@Failure	400	{object}	Error
 //	@Failure	500
 //	@Router		/users [get]
 func (s *Server) handleUserList() http.HandlerFunc {
@@ -163,8 +165,9 @@ func (s *Server) handleUserGet() http.HandlerFunc {
 //	@Produce	json
 //	@Security	BasicAuth
 //	@Success	200	{object}	UserLoginResponse
-//	@Failure 400
+//	@Failure	400	{object}	Error
 //	@Failure	401
+//	@Failure	403
 //	@Failure	500
 //	@Router		/users/login [post]
 func (s *Server) handleUserLogin() http.HandlerFunc {
@@ -177,18 +180,27 @@ func (s *Server) handleUserLogin() http.HandlerFunc {
 			return
 		}
 
+		// Failed attempts are recorded without an actor since the claimed username hasn't been verified.
 		storedUser, err := s.UserService.GetUserById(r.Context(), user.GetUserByIdOpts{Id: username})
 		if err != nil {
+			s.auditAs(r, "", audit.ActionLoginFailed, username)
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
 		if password != storedUser.Password {
+			s.auditAs(r, "", audit.ActionLoginFailed, username)
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		key := auth.NewApiKey(24, time.Hour*1, storedUser.Username)
+		if storedUser.Disabled {
+			s.auditAs(r, "", audit.ActionLoginFailed, username)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		key := auth.NewApiKeyExpiringAt(24, s.Clock.Now().Add(time.Hour*1), storedUser.Username)
 		if err = s.AuthService.RegisterKey(key.Token(), key); err != nil {
 			logger.Error("failed to register key", slog.String("error", err.Error()))
 			w.WriteHeader(http.StatusInternalServerError)
@@ -203,6 +215,7 @@ func (s *Server) handleUserLogin() http.HandlerFunc {
 		}
 
 		logger.Info("user logged in", slog.String("username", username))
+		s.auditAs(r, username, audit.ActionLogin, username)
 
 		return
 	}
@@ -210,17 +223,19 @@ func (s *Server) handleUserLogin() http.HandlerFunc {
 
 // handleUserDelete deletes a user
 //
-//	@Summary	Deletes a user
-//	@Tags		users
-//	@Accept		json
-//	@Produce	json
-//	@Param		id	path	string	true	"id to delete"
-//	@Security	ApiKey
-//	@Success	200
-//	@Failure	401
-//	@Failure	404
-//	@Failure	500
-//	@Router		/users/{id} [delete]
+//	@Summary		Deletes a user
+//	@Description	Removes the user from every room, deleting rooms nobody else is in. Depending on the server's
+//	@Description	configuration, their messages are either kept without an author or deleted.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"id to delete"
+//	@Security		ApiKey
+//	@Success		200
+//	@Failure		401
+//	@Failure		404
+//	@Failure		500
+//	@Router			/users/{id} [delete]
 func (s *Server) handleUserDelete() http.HandlerFunc {
 	logger := slog.New(s.logHandler).With(slog.String("handler", "UserDelete"))
 
@@ -237,33 +252,22 @@ func (s *Server) handleUserDelete() http.HandlerFunc {
 			return
 		}
 
-		if err := s.UserService.Delete(r.Context(), user.DeleteUserOpts{Id: userId}); err != nil {
+		if err := s.deleter().DeleteUser(r.Context(), user.DeleteUserOpts{Id: userId}); err != nil {
 			logger.Error("failed to delete user", slog.String("error", err.Error()))
 
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		logger.Info("user deleted", slog.String("username", userId))
+		if err := s.revokeUserSessions(userId); err != nil {
+			logger.Error("failed to revoke sessions", slog.String("error", err.Error()))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-		// TODO the user's token is pretty much still valid
+		logger.Info("user deleted", slog.String("username", userId))
+		s.audit(r, audit.ActionUserDelete, userId)
 
 		return
 	}
 }
-
-func (c UserCreateRequest) Validate() bool {
-	if c.Username == "" {
-		return false
-	}
-
-	if !alphaNumericRegex.MatchString(c.Username) {
-		return false
-	}
-
-	if c.Password == "" {
-		return false
-	}
-
-	return true
-}
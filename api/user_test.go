@@ -1,13 +1,21 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/worsediscord/server/services/audit"
 	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/cascade"
 	"github.com/worsediscord/server/services/fake"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/room"
 	"github.com/worsediscord/server/services/user"
 	"github.com/worsediscord/server/util"
 )
@@ -35,6 +43,24 @@ func TestServer_HandleUserCreate(t *testing.T) {
 			userService:    &fake.UserService{},
 			expectedStatus: http.StatusBadRequest,
 		},
+		"short password": {
+			request:        httptest.NewRequest(http.MethodPost, "/api/users", util.StructToReaderOrDie(UserCreateRequest{Username: "batman", Password: "robin"})),
+			recorder:       httptest.NewRecorder(),
+			userService:    &fake.UserService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"invalid username": {
+			request:        httptest.NewRequest(http.MethodPost, "/api/users", util.StructToReaderOrDie(UserCreateRequest{Username: "bat man", Password: "iamthenight"})),
+			recorder:       httptest.NewRecorder(),
+			userService:    &fake.UserService{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"rejected by service": {
+			request:        httptest.NewRequest(http.MethodPost, "/api/users", util.StructToReaderOrDie(validRequest)),
+			recorder:       httptest.NewRecorder(),
+			userService:    &fake.UserService{ExpectedCreateError: user.ErrInvalidPassword, ExpectedGetUserByIdError: user.ErrNotFound},
+			expectedStatus: http.StatusBadRequest,
+		},
 		"conflict": {
 			request:        httptest.NewRequest(http.MethodPost, "/api/users", util.StructToReaderOrDie(validRequest)),
 			recorder:       httptest.NewRecorder(),
@@ -46,7 +72,7 @@ func TestServer_HandleUserCreate(t *testing.T) {
 	for name, input := range tests {
 		t.Run(name, func(t *testing.T) {
 			s.UserService = input.userService
-			s.handleUserCreate()(input.recorder, input.request)
+			validated(t, "POST /api/users", s.handleUserCreate())(input.recorder, input.request)
 
 			if input.recorder.Code != input.expectedStatus {
 				t.Fatalf("got status %d, expected %d", input.recorder.Code, input.expectedStatus)
@@ -212,6 +238,155 @@ func TestServer_HandleUserLogin(t *testing.T) {
 	}
 }
 
+func TestServer_HandleUserLoginFailed(t *testing.T) {
+	tests := map[string]struct {
+		userService    *fake.UserService
+		password       string
+		expectedStatus int
+	}{
+		"unknown user": {
+			userService:    &fake.UserService{ExpectedGetUserByIdError: user.ErrNotFound},
+			password:       "password123",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"wrong password": {
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom", Password: "password123"}},
+			password:       "password456",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"disabled": {
+			userService:    &fake.UserService{ExpectedGetUserByIdUser: &user.User{Username: "venom", Password: "password123", Disabled: true}},
+			password:       "password123",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := NewServer(input.userService, nil, nil, &fake.AuthService{}, util.NopLogHandler)
+
+			request := httptest.NewRequest(http.MethodPost, "/api/users/login", nil)
+			request.SetBasicAuth("venom", input.password)
+			recorder := httptest.NewRecorder()
+			s.handleUserLogin()(recorder, request)
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			entries, err := s.AuditService.List(context.Background(), audit.ListEntryOpts{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The claimed username is only the target, it isn't trusted as the actor.
+			if len(entries) != 1 || entries[0].Actor != "" || entries[0].Action != audit.ActionLoginFailed || entries[0].Target != "venom" {
+				t.Fatalf("got audit entries %v, expected one failed login targeting venom without an actor", entries)
+			}
+		})
+	}
+}
+
 func TestServer_HandleUserDelete(t *testing.T) {
+	s := NewServer(nil, nil, nil, nil, util.NopLogHandler)
+	rooms := []*room.Room{
+		{Id: 1, Name: "the big apple", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman"}},
+		{Id: 2, Name: "the daily bugle", Users: []string{"mj"}, Admins: []string{"mj"}},
+	}
+
+	tests := map[string]struct {
+		id                     string
+		userId                 string
+		policy                 cascade.Policy
+		userService            user.Service
+		expectedStatus         int
+		expectRevoked          bool
+		expectedLeaveCalls     []room.LeaveRoomOpts
+		expectedAnonymizeCalls []message.AnonymizeMessageOpts
+		expectedPurgeCalls     []message.PurgeMessageOpts
+	}{
+		"valid": {
+			id:                     "spiderman",
+			userId:                 "spiderman",
+			userService:            &fake.UserService{},
+			expectedStatus:         http.StatusOK,
+			expectRevoked:          true,
+			expectedLeaveCalls:     []room.LeaveRoomOpts{{Id: 1, UserId: "spiderman"}},
+			expectedAnonymizeCalls: []message.AnonymizeMessageOpts{{UserId: "spiderman"}},
+		},
+		"deleting messages": {
+			id:                 "spiderman",
+			userId:             "spiderman",
+			policy:             cascade.PolicyDelete,
+			userService:        &fake.UserService{},
+			expectedStatus:     http.StatusOK,
+			expectRevoked:      true,
+			expectedLeaveCalls: []room.LeaveRoomOpts{{Id: 1, UserId: "spiderman"}},
+			expectedPurgeCalls: []message.PurgeMessageOpts{{UserId: "spiderman"}},
+		},
+		"someone else": {
+			id:             "venom",
+			userId:         "spiderman",
+			userService:    &fake.UserService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"unauthenticated": {
+			id:             "spiderman",
+			userService:    &fake.UserService{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"service error": {
+			id:                     "spiderman",
+			userId:                 "spiderman",
+			userService:            &fake.UserService{ExpectedDeleteError: errors.New("oops")},
+			expectedStatus:         http.StatusBadRequest,
+			expectedLeaveCalls:     []room.LeaveRoomOpts{{Id: 1, UserId: "spiderman"}},
+			expectedAnonymizeCalls: []message.AnonymizeMessageOpts{{UserId: "spiderman"}},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			key := auth.NewApiKey(24, time.Hour, "spiderman")
+			authService := auth.NewMap()
+			if err := authService.RegisterKey(key.Token(), key); err != nil {
+				t.Fatalf("failed to prepopulate map: %v", err)
+			}
+
+			roomService := &fake.RoomService{ExpectedListRooms: rooms}
+			messageService := &fake.MessageService{}
+
+			s.UserService = input.userService
+			s.RoomService = roomService
+			s.MessageService = messageService
+			s.AuthService = authService
+			s.DeletedUserMessages = input.policy
 
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodDelete, "/api/users/"+input.id, nil)
+			request.SetPathValue("id", input.id)
+			s.handleUserDelete()(recorder, withUserId(request, input.userId))
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			_, err := authService.RetrieveKey(key.Token())
+			if revoked := errors.Is(err, auth.ErrNotFound); revoked != input.expectRevoked {
+				t.Fatalf("got revoked %v, expected %v", revoked, input.expectRevoked)
+			}
+
+			if !reflect.DeepEqual(roomService.LeaveCalls, input.expectedLeaveCalls) {
+				t.Fatalf("got leave calls %v, expected %v", roomService.LeaveCalls, input.expectedLeaveCalls)
+			}
+
+			if !reflect.DeepEqual(messageService.AnonymizeCalls, input.expectedAnonymizeCalls) {
+				t.Fatalf("got anonymize calls %v, expected %v", messageService.AnonymizeCalls, input.expectedAnonymizeCalls)
+			}
+
+			if !reflect.DeepEqual(messageService.PurgeCalls, input.expectedPurgeCalls) {
+				t.Fatalf("got purge calls %v, expected %v", messageService.PurgeCalls, input.expectedPurgeCalls)
+			}
+		})
+	}
 }
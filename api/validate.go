@@ -0,0 +1,390 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes a single part of a request that failed validation.
+type FieldError struct {
+	// Where the field was sent: path, query or body.
+	In string `json:"in"`
+
+	// The name of the field. Nested body fields are joined with dots and indexes, e.g. users[0].name. Empty if the
+	// body as a whole is invalid.
+	Field string `json:"field"`
+
+	// Why the field failed validation.
+	Message string `json:"message"`
+}
+
+// MaxRequestBodyBytes is the largest request body the server reads.
+const MaxRequestBodyBytes = 1 << 20
+
+// validator validates requests against the OpenAPI spec.
+type validator struct {
+	spec map[string]any
+
+	// patterns holds the compiled pattern of every schema in spec that has one.
+	patterns map[string]*regexp.Regexp
+}
+
+// RequestValidationMiddleware validates the path parameters, query parameters and json body of a request against the
+// operation that documents its route in the OpenAPI spec. Requests that fail are rejected with an Error listing every
+// field that failed, before next runs. The route is taken from r.Pattern, so the middleware has to wrap the handlers
+// registered on a ServeMux rather than the mux itself. Routes without an operation are passed through. Request bodies
+// are limited to maxBodyBytes, larger ones are rejected with 413. An error is returned if the spec or any pattern in it
+// is invalid.
+func RequestValidationMiddleware(logHandler slog.Handler, maxBodyBytes int64) (func(next http.Handler) http.Handler, error) {
+	logger := slog.New(logHandler).With(slog.String("method", "RequestValidationMiddleware"))
+
+	spec, err := openAPISpec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode embedded openapi spec: %w", err)
+	}
+
+	v := &validator{spec: spec, patterns: make(map[string]*regexp.Regexp)}
+	if err = v.compilePatterns(spec); err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+			operation, ok := lookupOperation(v.spec, r.Pattern)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fields := v.validateParameters(operation, r)
+
+			if body, ok := operation["requestBody"].(map[string]any); ok {
+				payload, err := io.ReadAll(r.Body)
+				if err != nil {
+					var maxBytesErr *http.MaxBytesError
+					if errors.As(err, &maxBytesErr) {
+						w.WriteHeader(http.StatusRequestEntityTooLarge)
+						return
+					}
+
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				r.Body = io.NopCloser(bytes.NewReader(payload))
+				fields = append(fields, v.validateBody(body, payload)...)
+			}
+
+			if len(fields) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logger.Info("request failed validation", slog.String("path", r.URL.Path), slog.Int("fields", len(fields)))
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(Error{Status: http.StatusBadRequest, Message: "request failed validation", Fields: fields})
+		})
+	}, nil
+}
+
+// compilePatterns compiles the pattern of every schema nested in node.
+func (v *validator) compilePatterns(node any) error {
+	switch n := node.(type) {
+	case map[string]any:
+		if pattern, ok := n["pattern"].(string); ok && v.patterns[pattern] == nil {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q in openapi spec: %w", pattern, err)
+			}
+
+			v.patterns[pattern] = re
+		}
+
+		for _, child := range n {
+			if err := v.compilePatterns(child); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, child := range n {
+			if err := v.compilePatterns(child); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// lookupOperation finds the operation documenting a ServeMux pattern such as "GET /api/rooms/{id}".
+func lookupOperation(spec map[string]any, pattern string) (map[string]any, bool) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		return nil, false
+	}
+
+	paths, _ := spec["paths"].(map[string]any)
+	item, _ := paths[strings.TrimPrefix(path, "/api")].(map[string]any)
+	operation, ok := item[strings.ToLower(method)].(map[string]any)
+
+	return operation, ok
+}
+
+func (v *validator) validateParameters(operation map[string]any, r *http.Request) []FieldError {
+	var fields []FieldError
+
+	parameters, _ := operation["parameters"].([]any)
+	for _, p := range parameters {
+		parameter, _ := p.(map[string]any)
+		name, _ := parameter["name"].(string)
+		in, _ := parameter["in"].(string)
+		schema, _ := parameter["schema"].(map[string]any)
+		required, _ := parameter["required"].(bool)
+
+		var value string
+		var present bool
+		switch in {
+		case "path":
+			value = r.PathValue(name)
+			present = value != ""
+		case "query":
+			present = r.URL.Query().Has(name)
+			value = r.URL.Query().Get(name)
+		default:
+			continue
+		}
+
+		if !present {
+			if required {
+				fields = append(fields, FieldError{In: in, Field: name, Message: "is required"})
+			}
+
+			continue
+		}
+
+		parsed, err := parseParameter(resolve(v.spec, schema), value)
+		if err != nil {
+			fields = append(fields, FieldError{In: in, Field: name, Message: err.Error()})
+			continue
+		}
+
+		fields = append(fields, v.validateValue(schema, parsed, in, name)...)
+	}
+
+	return fields
+}
+
+// parseParameter converts a path or query value into the json representation of its schema's type.
+func parseParameter(schema map[string]any, value string) (any, error) {
+	switch schema["type"] {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, errors.New(typeMessage(schema["type"]))
+		}
+
+		return json.Number(value), nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New(typeMessage("boolean"))
+		}
+
+		return b, nil
+	default:
+		return value, nil
+	}
+}
+
+func (v *validator) validateBody(body map[string]any, payload []byte) []FieldError {
+	required, _ := body["required"].(bool)
+	content, _ := body["content"].(map[string]any)
+	mediaType, _ := content["application/json"].(map[string]any)
+	schema, _ := mediaType["schema"].(map[string]any)
+
+	if len(bytes.TrimSpace(payload)) == 0 {
+		if required {
+			return []FieldError{{In: "body", Message: "is required"}}
+		}
+
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []FieldError{{In: "body", Message: "must be valid json"}}
+	}
+
+	return v.validateValue(schema, value, "body", "")
+}
+
+// validateValue validates a decoded json value against a schema. Only the keywords swag generates from struct tags are
+// checked: type, required, properties, items, enum, minLength, maxLength, pattern, minimum, maximum, minItems and
+// maxItems. A null is treated as if the field had been omitted.
+func (v *validator) validateValue(schema map[string]any, value any, in string, field string) []FieldError {
+	schema = resolve(v.spec, schema)
+	if schema == nil || value == nil {
+		return nil
+	}
+
+	fail := func(message string) []FieldError {
+		return []FieldError{{In: in, Field: field, Message: message}}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+		return fail(fmt.Sprintf("must be one of %v", enum))
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fail(typeMessage("object"))
+		}
+
+		return v.validateObject(schema, object, in, field)
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fail(typeMessage("array"))
+		}
+
+		if limit, ok := schema["minItems"].(float64); ok && float64(len(items)) < limit {
+			return fail(fmt.Sprintf("must have at least %v items", limit))
+		}
+
+		if limit, ok := schema["maxItems"].(float64); ok && float64(len(items)) > limit {
+			return fail(fmt.Sprintf("must have at most %v items", limit))
+		}
+
+		var fields []FieldError
+		itemSchema, _ := schema["items"].(map[string]any)
+		for i, item := range items {
+			fields = append(fields, v.validateValue(itemSchema, item, in, fmt.Sprintf("%s[%d]", field, i))...)
+		}
+
+		return fields
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fail(typeMessage("string"))
+		}
+
+		if limit, ok := schema["minLength"].(float64); ok && float64(utf8.RuneCountInString(s)) < limit {
+			return fail(fmt.Sprintf("must be at least %v characters long", limit))
+		}
+
+		if limit, ok := schema["maxLength"].(float64); ok && float64(utf8.RuneCountInString(s)) > limit {
+			return fail(fmt.Sprintf("must be at most %v characters long", limit))
+		}
+
+		if pattern, ok := schema["pattern"].(string); ok && !v.patterns[pattern].MatchString(s) {
+			return fail(fmt.Sprintf("must match %s", pattern))
+		}
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fail(typeMessage(schema["type"]))
+		}
+
+		f, err := n.Float64()
+		if err != nil {
+			return fail(typeMessage(schema["type"]))
+		}
+
+		if _, err = n.Int64(); err != nil && schema["type"] == "integer" {
+			return fail(typeMessage("integer"))
+		}
+
+		if limit, ok := schema["minimum"].(float64); ok && f < limit {
+			return fail(fmt.Sprintf("must be at least %v", limit))
+		}
+
+		if limit, ok := schema["maximum"].(float64); ok && f > limit {
+			return fail(fmt.Sprintf("must be at most %v", limit))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail(typeMessage("boolean"))
+		}
+	}
+
+	return nil
+}
+
+// validateObject checks the required and known properties of object. Unknown properties are ignored, as they are when
+// the body is decoded.
+func (v *validator) validateObject(schema map[string]any, object map[string]any, in string, field string) []FieldError {
+	var fields []FieldError
+
+	join := func(name string) string {
+		if field == "" {
+			return name
+		}
+
+		return field + "." + name
+	}
+
+	required, _ := schema["required"].([]any)
+	for _, r := range required {
+		name, _ := r.(string)
+		if object[name] == nil {
+			fields = append(fields, FieldError{In: in, Field: join(name), Message: "is required"})
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		property, _ := properties[name].(map[string]any)
+		fields = append(fields, v.validateValue(property, object[name], in, join(name))...)
+	}
+
+	return fields
+}
+
+// typeMessage describes a value that isn't of schema type t.
+func typeMessage(t any) string {
+	switch t {
+	case "integer", "object", "array":
+		return fmt.Sprintf("must be an %s", t)
+	default:
+		return fmt.Sprintf("must be a %s", t)
+	}
+}
+
+// resolve follows a $ref to the component schema it points at.
+func resolve(spec map[string]any, schema map[string]any) map[string]any {
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		return schema
+	}
+
+	components, _ := spec["components"].(map[string]any)
+	schemas, _ := components["schemas"].(map[string]any)
+	resolved, _ := schemas[strings.TrimPrefix(ref, "#/components/schemas/")].(map[string]any)
+
+	return resolved
+}
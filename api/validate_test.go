@@ -0,0 +1,221 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/worsediscord/server/util"
+)
+
+// validated returns h behind RequestValidationMiddleware, as if a ServeMux had routed the request with pattern.
+func validated(t *testing.T, pattern string, h http.Handler) http.HandlerFunc {
+	t.Helper()
+
+	validateHandler, err := RequestValidationMiddleware(util.NopLogHandler, MaxRequestBodyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Pattern = pattern
+		validateHandler(h).ServeHTTP(w, r)
+	}
+}
+
+func TestRequestValidationMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		pattern        string
+		path           string
+		pathValues     map[string]string
+		body           string
+		expectedStatus int
+		expectedFields []FieldError
+	}{
+		"valid": {
+			pattern:        "POST /api/rooms/{id}/messages",
+			pathValues:     map[string]string{"id": "1"},
+			body:           `{"content": "pizza time"}`,
+			expectedStatus: http.StatusOK,
+		},
+		"empty content": {
+			pattern:        "POST /api/rooms/{id}/messages",
+			pathValues:     map[string]string{"id": "1"},
+			body:           `{"content": ""}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []FieldError{{In: "body", Field: "content", Message: "must be at least 1 characters long"}},
+		},
+		"missing content": {
+			pattern:        "POST /api/rooms/{id}/messages",
+			pathValues:     map[string]string{"id": "1"},
+			body:           `{"contents": "pizza time"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []FieldError{{In: "body", Field: "content", Message: "is required"}},
+		},
+		"wrong type": {
+			pattern:        "POST /api/rooms/{id}/messages",
+			pathValues:     map[string]string{"id": "1"},
+			body:           `{"content": 42}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []FieldError{{In: "body", Field: "content", Message: "must be a string"}},
+		},
+		"not an object": {
+			pattern:        "POST /api/rooms/{id}/messages",
+			pathValues:     map[string]string{"id": "1"},
+			body:           `"pizza time"`,
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []FieldError{{In: "body", Message: "must be an object"}},
+		},
+		"invalid json": {
+			pattern:        "POST /api/rooms/{id}/messages",
+			pathValues:     map[string]string{"id": "1"},
+			body:           `{"content": `,
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []FieldError{{In: "body", Message: "must be valid json"}},
+		},
+		"missing body": {
+			pattern:        "POST /api/rooms/{id}/messages",
+			pathValues:     map[string]string{"id": "1"},
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []FieldError{{In: "body", Message: "is required"}},
+		},
+		"every failure is listed": {
+			pattern:        "POST /api/rooms/{id}/messages",
+			pathValues:     map[string]string{"id": "queens"},
+			body:           `{"content": ""}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []FieldError{
+				{In: "path", Field: "id", Message: "must be an integer"},
+				{In: "body", Field: "content", Message: "must be at least 1 characters long"},
+			},
+		},
+		"user": {
+			pattern:        "POST /api/users",
+			body:           `{"username": "spider man", "password": "mj"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []FieldError{
+				{In: "body", Field: "password", Message: "must be at least 8 characters long"},
+				{In: "body", Field: "username", Message: "must match ^[a-zA-Z0-9_.]+$"},
+			},
+		},
+		"optional fields": {
+			pattern:        "PATCH /api/admin/users/{id}",
+			pathValues:     map[string]string{"id": "venom"},
+			body:           `{"disabled": null}`,
+			expectedStatus: http.StatusOK,
+		},
+		"undocumented route": {
+			pattern:        "POST /api/pizza",
+			body:           `"pizza time"`,
+			expectedStatus: http.StatusOK,
+		},
+		"too large": {
+			pattern:        "POST /api/rooms/{id}/messages",
+			pathValues:     map[string]string{"id": "1"},
+			body:           `{"content": "` + strings.Repeat("🍕", MaxRequestBodyBytes/4) + `"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			// The handler only succeeds if the body is still there to be read.
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if body, _ := io.ReadAll(r.Body); string(body) != input.body {
+					w.WriteHeader(http.StatusTeapot)
+				}
+			})
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(input.body))
+			for k, v := range input.pathValues {
+				request.SetPathValue(k, v)
+			}
+
+			validated(t, input.pattern, next)(recorder, request)
+
+			if recorder.Code != input.expectedStatus {
+				t.Fatalf("got status %d, expected %d", recorder.Code, input.expectedStatus)
+			}
+
+			if input.expectedStatus != http.StatusBadRequest {
+				return
+			}
+
+			var response Error
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			if response.Status != http.StatusBadRequest || !reflect.DeepEqual(response.Fields, input.expectedFields) {
+				t.Fatalf("got error %v, expected fields %v", response, input.expectedFields)
+			}
+		})
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	v := &validator{spec: map[string]any{}}
+	operation := map[string]any{"parameters": []any{
+		map[string]any{"name": "limit", "in": "query", "required": true, "schema": map[string]any{"type": "integer", "minimum": 1.0, "maximum": 100.0}},
+		map[string]any{"name": "order", "in": "query", "schema": map[string]any{"type": "string", "enum": []any{"asc", "desc"}}},
+		map[string]any{"name": "pinned", "in": "query", "schema": map[string]any{"type": "boolean"}},
+	}}
+
+	tests := map[string]struct {
+		query          string
+		expectedFields []FieldError
+	}{
+		"valid":            {query: "limit=10&order=asc&pinned=true"},
+		"missing required": {query: "order=desc", expectedFields: []FieldError{{In: "query", Field: "limit", Message: "is required"}}},
+		"out of range":     {query: "limit=0", expectedFields: []FieldError{{In: "query", Field: "limit", Message: "must be at least 1"}}},
+		"not an integer":   {query: "limit=1.5", expectedFields: []FieldError{{In: "query", Field: "limit", Message: "must be an integer"}}},
+		"not in enum":      {query: "limit=10&order=sideways", expectedFields: []FieldError{{In: "query", Field: "order", Message: "must be one of [asc desc]"}}},
+		"not a boolean":    {query: "limit=10&pinned=maybe", expectedFields: []FieldError{{In: "query", Field: "pinned", Message: "must be a boolean"}}},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/?"+input.query, nil)
+
+			if fields := v.validateParameters(operation, request); !reflect.DeepEqual(fields, input.expectedFields) {
+				t.Fatalf("got fields %v, expected %v", fields, input.expectedFields)
+			}
+		})
+	}
+}
+
+func TestValidator_CompilePatterns(t *testing.T) {
+	tests := map[string]struct {
+		spec        map[string]any
+		expectedErr bool
+	}{
+		"valid": {
+			spec: map[string]any{"components": map[string]any{"schemas": map[string]any{"api.UserCreateRequest": map[string]any{
+				"properties": map[string]any{"username": map[string]any{"type": "string", "pattern": "^[a-z]+$"}},
+			}}}},
+			expectedErr: false,
+		},
+		"invalid": {
+			spec: map[string]any{"paths": map[string]any{"/users": map[string]any{"get": map[string]any{"parameters": []any{
+				map[string]any{"name": "q", "in": "query", "schema": map[string]any{"type": "string", "pattern": "[a-z"}},
+			}}}}},
+			expectedErr: true,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := &validator{spec: input.spec, patterns: make(map[string]*regexp.Regexp)}
+
+			if err := v.compilePatterns(input.spec); (err != nil) != input.expectedErr {
+				t.Fatalf("got error %v, expected error %v", err, input.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/worsediscord/server/api"
+)
+
+// AuditFilter narrows the audit log. Zero fields match everything.
+type AuditFilter struct {
+	Actor  string
+	Action string
+
+	// Since is in milliseconds since epoch.
+	Since int64
+}
+
+// AdminUsers iterates over every user along with their administrative state. Like every Admin method, it requires the
+// logged in user to be a server administrator.
+func (c *Client) AdminUsers(ctx context.Context) iter.Seq2[api.AdminUserResponse, error] {
+	return list[api.AdminUserResponse](ctx, c, c.adminURL.JoinPath("/api/admin/users"))
+}
+
+func (c *Client) AdminListUsers(ctx context.Context) ([]api.AdminUserResponse, error) {
+	return collect(c.AdminUsers(ctx))
+}
+
+func (c *Client) AdminUpdateUser(ctx context.Context, username string, request api.AdminUserUpdateRequest) error {
+	return c.do(ctx, c.adminURL, http.MethodPatch, adminUserPath(username), request, nil)
+}
+
+// AdminDeleteUser deletes any user and revokes their sessions.
+func (c *Client) AdminDeleteUser(ctx context.Context, username string) error {
+	return c.do(ctx, c.adminURL, http.MethodDelete, adminUserPath(username), nil, nil)
+}
+
+func (c *Client) AdminResetPassword(ctx context.Context, username string, request api.AdminPasswordResetRequest) error {
+	return c.do(ctx, c.adminURL, http.MethodPost, adminUserPath(username)+"/password", request, nil)
+}
+
+func (c *Client) AdminRooms(ctx context.Context) iter.Seq2[api.AdminRoomResponse, error] {
+	return list[api.AdminRoomResponse](ctx, c, c.adminURL.JoinPath("/api/admin/rooms"))
+}
+
+func (c *Client) AdminListRooms(ctx context.Context) ([]api.AdminRoomResponse, error) {
+	return collect(c.AdminRooms(ctx))
+}
+
+// AdminDeleteRoom deletes any room regardless of its admins.
+func (c *Client) AdminDeleteRoom(ctx context.Context, id int64) error {
+	return c.do(ctx, c.adminURL, http.MethodDelete, adminRoomPath(id), nil, nil)
+}
+
+func (c *Client) AdminPromoteRoomAdmin(ctx context.Context, id int64, request api.AdminRoomPromoteRequest) error {
+	return c.do(ctx, c.adminURL, http.MethodPost, adminRoomPath(id)+"/admins", request, nil)
+}
+
+// AdminTransferRoom makes a user the owner of any room.
+func (c *Client) AdminTransferRoom(ctx context.Context, id int64, request api.RoomMemberRequest) error {
+	return c.do(ctx, c.adminURL, http.MethodPut, adminRoomPath(id)+"/owner", request, nil)
+}
+
+func (c *Client) AdminSessions(ctx context.Context) iter.Seq2[api.SessionResponse, error] {
+	return list[api.SessionResponse](ctx, c, c.adminURL.JoinPath("/api/admin/sessions"))
+}
+
+func (c *Client) AdminListSessions(ctx context.Context) ([]api.SessionResponse, error) {
+	return collect(c.AdminSessions(ctx))
+}
+
+// AdminRevokeSession revokes a session by its id, as listed by AdminSessions.
+func (c *Client) AdminRevokeSession(ctx context.Context, id string) error {
+	return c.do(ctx, c.adminURL, http.MethodDelete, "/api/admin/sessions/"+url.PathEscape(id), nil, nil)
+}
+
+func (c *Client) AdminStats(ctx context.Context) (api.StatsResponse, error) {
+	var response api.StatsResponse
+	err := c.do(ctx, c.adminURL, http.MethodGet, "/api/admin/stats", nil, &response)
+
+	return response, err
+}
+
+func (c *Client) AdminAudit(ctx context.Context, filter AuditFilter) iter.Seq2[api.AuditEntryResponse, error] {
+	query := url.Values{}
+	if filter.Actor != "" {
+		query.Set("actor", filter.Actor)
+	}
+	if filter.Action != "" {
+		query.Set("action", filter.Action)
+	}
+	if filter.Since != 0 {
+		query.Set("since", strconv.FormatInt(filter.Since, 10))
+	}
+
+	u := c.adminURL.JoinPath("/api/admin/audit")
+	u.RawQuery = query.Encode()
+
+	return list[api.AuditEntryResponse](ctx, c, u)
+}
+
+func (c *Client) AdminListAudit(ctx context.Context, filter AuditFilter) ([]api.AuditEntryResponse, error) {
+	return collect(c.AdminAudit(ctx, filter))
+}
+
+func adminUserPath(username string) string {
+	return "/api/admin/users/" + url.PathEscape(username)
+}
+
+func adminRoomPath(id int64) string {
+	return "/api/admin/rooms/" + strconv.FormatInt(id, 10)
+}
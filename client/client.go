@@ -0,0 +1,294 @@
+// Package client is a Go client for the worsediscord server API. It has a method for every route the server serves,
+// using the request and response types from the api package.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/worsediscord/server/api"
+)
+
+type Opts struct {
+	// HTTPClient sends every request. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// AdminURL is where the /api/admin routes are served, for servers with a dedicated admin listener. Defaults to the
+	// base URL.
+	AdminURL string
+
+	// DisableRefresh stops the client from logging in again when its session is rejected.
+	DisableRefresh bool
+}
+
+// Client is safe for concurrent use. After Login, every request is made as the logged in user. Sessions expire after
+// an hour, so unless Opts.DisableRefresh is set, the client logs in again with the same credentials and retries once
+// when the server reports that the session is no longer valid. Requests the user just isn't allowed to make aren't
+// retried.
+type Client struct {
+	baseURL  *url.URL
+	adminURL *url.URL
+	http     *http.Client
+	refresh  bool
+
+	lock     sync.Mutex
+	token    string
+	username string
+	password string
+}
+
+// New returns a client for the server at baseURL, e.g. https://example.com. The /api prefix is added by the client.
+func New(baseURL string, opts Opts) (*Client, error) {
+	base, err := parseBaseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	admin := base
+	if opts.AdminURL != "" {
+		if admin, err = parseBaseURL(opts.AdminURL); err != nil {
+			return nil, err
+		}
+	}
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{baseURL: base, adminURL: admin, http: httpClient, refresh: !opts.DisableRefresh}, nil
+}
+
+// Token returns the current session token, or an empty string if the client isn't logged in.
+func (c *Client) Token() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.token
+}
+
+// SetToken makes every request with token, e.g. one saved from an earlier Login. The client can't refresh a session
+// it didn't log in to itself.
+func (c *Client) SetToken(token string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.token = token
+	c.username = ""
+	c.password = ""
+}
+
+// Health reports whether the server is up.
+func (c *Client) Health(ctx context.Context) (api.HealthResponse, error) {
+	var response api.HealthResponse
+	err := c.do(ctx, c.baseURL, http.MethodGet, "/api/health", nil, &response)
+
+	return response, err
+}
+
+// OpenAPI returns the server's OpenAPI spec, with its server URL pointing at the host the client is configured with.
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	var response json.RawMessage
+	err := c.do(ctx, c.baseURL, http.MethodGet, "/api/docs/openapi.json", nil, &response)
+
+	return response, err
+}
+
+// do makes a request to path under base, sending body as json and decoding the response into out. Either may be nil.
+func (c *Client) do(ctx context.Context, base *url.URL, method, path string, body any, out any) error {
+	_, err := c.doURL(ctx, method, base.JoinPath(path), body, out)
+	return err
+}
+
+// doURL is do for an absolute URL. It returns the response headers so that callers can follow pagination links.
+func (c *Client) doURL(ctx context.Context, method string, u *url.URL, body any, out any) (http.Header, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	token := c.Token()
+
+	header, err := c.send(ctx, method, u, payload, token, out)
+
+	var statusErr *StatusError
+	if token == "" || !errors.As(err, &statusErr) || !statusErr.InvalidSession {
+		return header, err
+	}
+
+	if refreshed, ok := c.refreshSession(ctx, token); ok {
+		return c.send(ctx, method, u, payload, refreshed, out)
+	}
+
+	return header, err
+}
+
+func (c *Client) send(ctx context.Context, method string, u *url.URL, payload []byte, token string, out any) (http.Header, error) {
+	request, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	if token != "" {
+		request.Header.Set("x-api-key", token)
+	}
+
+	return c.roundTrip(request, out)
+}
+
+func (c *Client) roundTrip(request *http.Request, out any) (http.Header, error) {
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		statusErr := &StatusError{Method: request.Method, Path: request.URL.Path, StatusCode: response.StatusCode}
+
+		// Validation failures describe what was wrong. Any other body is ignored.
+		var body api.Error
+		if response.StatusCode == http.StatusBadRequest && json.NewDecoder(response.Body).Decode(&body) == nil {
+			statusErr.Fields = body.Fields
+		}
+
+		statusErr.InvalidSession = response.StatusCode == http.StatusUnauthorized &&
+			response.Header.Get("WWW-Authenticate") == api.InvalidSessionChallenge
+
+		return response.Header, statusErr
+	}
+
+	if out == nil {
+		return response.Header, nil
+	}
+
+	if err = json.NewDecoder(response.Body).Decode(out); err != nil {
+		return response.Header, fmt.Errorf("failed to decode %s %s response: %w", request.Method, request.URL.Path, err)
+	}
+
+	return response.Header, nil
+}
+
+// refreshSession logs in again if the session that failed is still the current one and the client knows the
+// credentials it was created with. Concurrent requests that fail with the same session only log in once. The failed
+// session is replaced rather than revoked, since the server has already stopped accepting it.
+func (c *Client) refreshSession(ctx context.Context, failed string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.refresh || c.username == "" {
+		return "", false
+	}
+
+	if c.token != failed {
+		return c.token, true
+	}
+
+	token, err := c.login(ctx, c.username, c.password)
+	if err != nil {
+		return "", false
+	}
+
+	c.token = token
+
+	return token, true
+}
+
+// list iterates over every item of a list endpoint, following Link headers with rel="next" until there are none.
+func list[T any](ctx context.Context, c *Client, u *url.URL) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		next := u
+
+		for next != nil {
+			var page []T
+
+			header, err := c.doURL(ctx, http.MethodGet, next, nil, &page)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if next, err = nextPage(next, header); err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+		}
+	}
+}
+
+// collect gathers every item from seq, stopping at the first error.
+func collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	items := make([]T, 0)
+
+	for item, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// nextPage returns the rel="next" target of a Link header, resolved against current, or nil if there isn't one.
+func nextPage(current *url.URL, header http.Header) (*url.URL, error) {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range strings.Split(params, ";") {
+				name, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(name, "rel") && strings.Trim(v, `"`) == "next" {
+					next, err := current.Parse(strings.Trim(target, "<>"))
+					if err != nil {
+						return nil, fmt.Errorf("invalid next page link: %w", err)
+					}
+
+					return next, nil
+				}
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func parseBaseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSuffix(s, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid url %q: scheme must be http or https", s)
+	}
+
+	return u, nil
+}
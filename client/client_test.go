@@ -0,0 +1,265 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/worsediscord/server/api"
+)
+
+func newTestClient(t *testing.T, handler http.Handler, opts Opts) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestNew(t *testing.T) {
+	tests := map[string]struct {
+		baseURL     string
+		expectedErr bool
+	}{
+		"http":           {baseURL: "http://localhost:8080", expectedErr: false},
+		"https":          {baseURL: "https://example.com/", expectedErr: false},
+		"missing scheme": {baseURL: "example.com", expectedErr: true},
+		"other scheme":   {baseURL: "ftp://example.com", expectedErr: true},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := New(input.baseURL, Opts{}); (err != nil) != input.expectedErr {
+				t.Fatalf("got error %v, expected error: %v", err, input.expectedErr)
+			}
+		})
+	}
+}
+
+func TestStatusError_Unwrap(t *testing.T) {
+	tests := map[string]struct {
+		statusCode  int
+		expectedErr error
+	}{
+		"bad request":  {statusCode: http.StatusBadRequest, expectedErr: ErrBadRequest},
+		"unauthorized": {statusCode: http.StatusUnauthorized, expectedErr: ErrUnauthorized},
+		"forbidden":    {statusCode: http.StatusForbidden, expectedErr: ErrForbidden},
+		"not found":    {statusCode: http.StatusNotFound, expectedErr: ErrNotFound},
+		"conflict":     {statusCode: http.StatusConflict, expectedErr: ErrConflict},
+		"gone":         {statusCode: http.StatusGone, expectedErr: ErrGone},
+		"server error": {statusCode: http.StatusBadGateway, expectedErr: ErrServer},
+		"teapot":       {statusCode: http.StatusTeapot, expectedErr: ErrUnexpected},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(input.statusCode)
+			}), Opts{})
+
+			_, err := c.GetRoom(context.Background(), 1)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != input.statusCode || statusErr.Path != "/api/rooms/1" {
+				t.Fatalf("got error %#v, expected a StatusError for /api/rooms/1 with status %d", err, input.statusCode)
+			}
+		})
+	}
+}
+
+func TestClient_Rooms(t *testing.T) {
+	pages := map[string]struct {
+		rooms []api.RoomResponse
+		link  string
+	}{
+		"":  {rooms: []api.RoomResponse{{Id: 1, Name: "queens"}}, link: `</api/rooms?page=2>; rel="next"`},
+		"2": {rooms: []api.RoomResponse{{Id: 2, Name: "the big apple"}}, link: `<https://example.com/docs>; rel="help", </api/rooms?page=3>; rel="next"`},
+		"3": {rooms: []api.RoomResponse{{Id: 3, Name: "the daily bugle"}}},
+	}
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Query().Get("page")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if page.link != "" {
+			w.Header().Set("Link", page.link)
+		}
+
+		_ = json.NewEncoder(w).Encode(page.rooms)
+	}), Opts{})
+
+	rooms, err := c.ListRooms(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []api.RoomResponse{{Id: 1, Name: "queens"}, {Id: 2, Name: "the big apple"}, {Id: 3, Name: "the daily bugle"}}
+	if !reflect.DeepEqual(rooms, expected) {
+		t.Fatalf("got rooms %v, expected %v", rooms, expected)
+	}
+
+	// Stopping early doesn't fetch the rest.
+	for room, err := range c.Rooms(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if room.Id != 1 {
+			t.Fatalf("got room %v, expected the first room", room)
+		}
+
+		break
+	}
+}
+
+func TestClient_AddReaction(t *testing.T) {
+	tests := map[string]struct {
+		emoji string
+	}{
+		"emoji":        {emoji: "🍕"},
+		"sequence":     {emoji: "🕷️"},
+		"slash":        {emoji: "¯\\_(ツ)_/¯"},
+		"percent sign": {emoji: "100%"},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got string
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT /api/rooms/{id}/messages/{messageId}/reactions/{emoji}", func(w http.ResponseWriter, r *http.Request) {
+				got = r.PathValue("emoji")
+			})
+
+			c := newTestClient(t, mux, Opts{})
+
+			if err := c.AddReaction(context.Background(), 1, "a", input.emoji); err != nil {
+				t.Fatal(err)
+			}
+
+			if got != input.emoji {
+				t.Fatalf("got emoji %q, expected %q", got, input.emoji)
+			}
+		})
+	}
+}
+
+func TestClient_Refresh(t *testing.T) {
+	tests := map[string]struct {
+		disableRefresh bool
+		denied         bool
+		loginStatus    int
+		expectedErr    error
+		expectedLogins int
+	}{
+		"refreshed": {
+			loginStatus:    http.StatusOK,
+			expectedErr:    nil,
+			expectedLogins: 2,
+		},
+		// A valid session that isn't allowed to do something wouldn't be allowed after logging in again either.
+		"denied": {
+			denied:         true,
+			loginStatus:    http.StatusOK,
+			expectedErr:    ErrUnauthorized,
+			expectedLogins: 1,
+		},
+		"disabled": {
+			disableRefresh: true,
+			loginStatus:    http.StatusOK,
+			expectedErr:    ErrUnauthorized,
+			expectedLogins: 1,
+		},
+		"login fails": {
+			loginStatus:    http.StatusForbidden,
+			expectedErr:    ErrUnauthorized,
+			expectedLogins: 2,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			logins := 0
+			tokens := []string{"expired", "fresh"}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /api/users/login", func(w http.ResponseWriter, r *http.Request) {
+				if username, password, _ := r.BasicAuth(); username != "spiderman" || password != "uncleben123" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				logins++
+				if logins > 1 && input.loginStatus != http.StatusOK {
+					w.WriteHeader(input.loginStatus)
+					return
+				}
+
+				_ = json.NewEncoder(w).Encode(api.UserLoginResponse{Token: tokens[logins-1]})
+			})
+			mux.HandleFunc("GET /api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+				if input.denied {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				if r.Header.Get("x-api-key") != "fresh" {
+					w.Header().Set("WWW-Authenticate", api.InvalidSessionChallenge)
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				_ = json.NewEncoder(w).Encode(api.UserResponse{Username: r.PathValue("id")})
+			})
+
+			c := newTestClient(t, mux, Opts{DisableRefresh: input.disableRefresh})
+			ctx := context.Background()
+
+			if err := c.Login(ctx, "spiderman", "uncleben123"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := c.GetUser(ctx, "spiderman"); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if logins != input.expectedLogins {
+				t.Fatalf("got %d logins, expected %d", logins, input.expectedLogins)
+			}
+		})
+	}
+}
+
+func TestClient_AdminURL(t *testing.T) {
+	admin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(api.StatsResponse{Users: 1})
+	}))
+	t.Cleanup(admin.Close)
+
+	c := newTestClient(t, http.NotFoundHandler(), Opts{AdminURL: admin.URL})
+
+	stats, err := c.AdminStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Users != 1 {
+		t.Fatalf("got stats %v, expected them from the admin listener", stats)
+	}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/worsediscord/server/api"
+)
+
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrGone         = errors.New("gone")
+	ErrServer       = errors.New("server error")
+	ErrUnexpected   = errors.New("unexpected response")
+)
+
+// StatusError is returned for any response other than 200 OK. It wraps one of the Err values above, so callers can
+// check for a kind of failure with errors.Is.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+
+	// Fields lists what failed validation when the server rejected the request as a bad request.
+	Fields []api.FieldError
+
+	// InvalidSession is set when the server rejected the session itself, because it expired or was revoked, rather
+	// than what it was used for. Logging in again may help.
+	InvalidSession bool
+}
+
+func (e *StatusError) Error() string {
+	message := fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+
+	for _, field := range e.Fields {
+		name := field.In
+		if field.Field != "" {
+			name += "." + field.Field
+		}
+
+		message += fmt.Sprintf("; %s %s", name, field.Message)
+	}
+
+	return message
+}
+
+func (e *StatusError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		return ErrBadRequest
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.StatusCode == http.StatusGone:
+		return ErrGone
+	case e.StatusCode >= 500:
+		return ErrServer
+	default:
+		return ErrUnexpected
+	}
+}
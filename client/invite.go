@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+
+	"github.com/worsediscord/server/api"
+)
+
+// CreateInvite creates an invite code for a room the logged in user is an admin of.
+func (c *Client) CreateInvite(ctx context.Context, roomId int64, request api.InviteCreateRequest) (api.InviteResponse, error) {
+	var response api.InviteResponse
+	err := c.do(ctx, c.baseURL, http.MethodPost, roomPath(roomId)+"/invites", request, &response)
+
+	return response, err
+}
+
+// Invites iterates over the invites to a room the logged in user is an admin of, oldest first.
+func (c *Client) Invites(ctx context.Context, roomId int64) iter.Seq2[api.InviteResponse, error] {
+	return list[api.InviteResponse](ctx, c, c.baseURL.JoinPath(roomPath(roomId), "invites"))
+}
+
+func (c *Client) ListInvites(ctx context.Context, roomId int64) ([]api.InviteResponse, error) {
+	return collect(c.Invites(ctx, roomId))
+}
+
+func (c *Client) RevokeInvite(ctx context.Context, roomId int64, code string) error {
+	return c.do(ctx, c.baseURL, http.MethodDelete, roomPath(roomId)+"/invites/"+code, nil, nil)
+}
+
+// AcceptInvite joins the room an invite code is for. Expired and used up invites fail with ErrGone.
+func (c *Client) AcceptInvite(ctx context.Context, code string) (api.RoomResponse, error) {
+	var response api.RoomResponse
+	err := c.do(ctx, c.baseURL, http.MethodPost, "/api/invites/"+code, nil, &response)
+
+	return response, err
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/worsediscord/server/api"
+)
+
+// SearchFilter narrows a message search. Zero fields match everything.
+type SearchFilter struct {
+	RoomId int64
+	Author string
+
+	// Before and After are in milliseconds since epoch.
+	Before int64
+	After  int64
+
+	// Limit is the most results returned. The server's default if 0.
+	Limit int
+}
+
+func (c *Client) CreateMessage(ctx context.Context, roomId int64, request api.MessageCreateRequest) error {
+	return c.do(ctx, c.baseURL, http.MethodPost, roomPath(roomId)+"/messages", request, nil)
+}
+
+// Messages iterates over the messages in a room.
+func (c *Client) Messages(ctx context.Context, roomId int64) iter.Seq2[api.MessageResponse, error] {
+	return list[api.MessageResponse](ctx, c, c.baseURL.JoinPath(roomPath(roomId), "messages"))
+}
+
+func (c *Client) ListMessages(ctx context.Context, roomId int64) ([]api.MessageResponse, error) {
+	return collect(c.Messages(ctx, roomId))
+}
+
+// Thread iterates over the replies in the thread rooted at a message, oldest first.
+func (c *Client) Thread(ctx context.Context, roomId int64, messageId string) iter.Seq2[api.MessageResponse, error] {
+	return list[api.MessageResponse](ctx, c, c.baseURL.JoinPath(messagePath(roomId, messageId), "thread"))
+}
+
+func (c *Client) ListThread(ctx context.Context, roomId int64, messageId string) ([]api.MessageResponse, error) {
+	return collect(c.Thread(ctx, roomId, messageId))
+}
+
+// SearchMessages searches the messages of every room the logged in user can read, most relevant first.
+func (c *Client) SearchMessages(ctx context.Context, q string, filter SearchFilter) ([]api.MessageSearchResponse, error) {
+	query := url.Values{}
+	query.Set("q", q)
+	if filter.RoomId != 0 {
+		query.Set("room", strconv.FormatInt(filter.RoomId, 10))
+	}
+	if filter.Author != "" {
+		query.Set("author", filter.Author)
+	}
+	if filter.Before != 0 {
+		query.Set("before", strconv.FormatInt(filter.Before, 10))
+	}
+	if filter.After != 0 {
+		query.Set("after", strconv.FormatInt(filter.After, 10))
+	}
+	if filter.Limit != 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	u := c.baseURL.JoinPath("/api/search/messages")
+	u.RawQuery = query.Encode()
+
+	var response []api.MessageSearchResponse
+	_, err := c.doURL(ctx, http.MethodGet, u, nil, &response)
+
+	return response, err
+}
+
+// DeleteMessage deletes a message sent by the logged in user, or in a room they're an admin of.
+func (c *Client) DeleteMessage(ctx context.Context, roomId int64, messageId string) error {
+	return c.do(ctx, c.baseURL, http.MethodDelete, messagePath(roomId, messageId), nil, nil)
+}
+
+// AddReaction reacts to a message as the logged in user. Reacting twice with the same emoji is not an error.
+func (c *Client) AddReaction(ctx context.Context, roomId int64, messageId string, emoji string) error {
+	_, err := c.doURL(ctx, http.MethodPut, c.reactionURL(roomId, messageId, emoji), nil, nil)
+	return err
+}
+
+// RemoveReaction removes a reaction the logged in user made.
+func (c *Client) RemoveReaction(ctx context.Context, roomId int64, messageId string, emoji string) error {
+	_, err := c.doURL(ctx, http.MethodDelete, c.reactionURL(roomId, messageId, emoji), nil, nil)
+	return err
+}
+
+// Reactions iterates over the users that reacted to a message with emoji, oldest first.
+func (c *Client) Reactions(ctx context.Context, roomId int64, messageId string, emoji string) iter.Seq2[api.ReactionResponse, error] {
+	return list[api.ReactionResponse](ctx, c, c.reactionURL(roomId, messageId, emoji))
+}
+
+func (c *Client) ListReactions(ctx context.Context, roomId int64, messageId string, emoji string) ([]api.ReactionResponse, error) {
+	return collect(c.Reactions(ctx, roomId, messageId, emoji))
+}
+
+func messagePath(roomId int64, messageId string) string {
+	return roomPath(roomId) + "/messages/" + messageId
+}
+
+// reactionURL escapes emoji as a single path segment, since JoinPath would treat a slash in it as a separator.
+func (c *Client) reactionURL(roomId int64, messageId string, emoji string) *url.URL {
+	u := c.baseURL.JoinPath(roomPath(roomId), "messages", messageId, "reactions")
+	u.RawPath = u.EscapedPath() + "/" + url.PathEscape(emoji)
+	u.Path += "/" + emoji
+
+	return u
+}
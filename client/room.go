@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/worsediscord/server/api"
+)
+
+func (c *Client) CreateRoom(ctx context.Context, request api.RoomCreateRequest) (api.RoomResponse, error) {
+	var response api.RoomResponse
+	err := c.do(ctx, c.baseURL, http.MethodPost, "/api/rooms", request, &response)
+
+	return response, err
+}
+
+func (c *Client) Rooms(ctx context.Context) iter.Seq2[api.RoomResponse, error] {
+	return list[api.RoomResponse](ctx, c, c.baseURL.JoinPath("/api/rooms"))
+}
+
+func (c *Client) ListRooms(ctx context.Context) ([]api.RoomResponse, error) {
+	return collect(c.Rooms(ctx))
+}
+
+func (c *Client) GetRoom(ctx context.Context, id int64) (api.RoomResponse, error) {
+	var response api.RoomResponse
+	err := c.do(ctx, c.baseURL, http.MethodGet, roomPath(id), nil, &response)
+
+	return response, err
+}
+
+// UpdateRoom changes the settings of a room the logged in user is an admin of. Nil fields are left as they are.
+func (c *Client) UpdateRoom(ctx context.Context, id int64, request api.RoomUpdateRequest) (api.RoomResponse, error) {
+	var response api.RoomResponse
+	err := c.do(ctx, c.baseURL, http.MethodPatch, roomPath(id), request, &response)
+
+	return response, err
+}
+
+// PromoteRoomAdmin makes a user an admin of a room the logged in user is an admin of.
+func (c *Client) PromoteRoomAdmin(ctx context.Context, id int64, request api.RoomMemberRequest) error {
+	return c.do(ctx, c.baseURL, http.MethodPost, roomPath(id)+"/admins", request, nil)
+}
+
+// DemoteRoomAdmin revokes room admin from a user. The logged in user must own the room or be demoting themselves.
+func (c *Client) DemoteRoomAdmin(ctx context.Context, id int64, username string) error {
+	return c.do(ctx, c.baseURL, http.MethodDelete, roomPath(id)+"/admins/"+url.PathEscape(username), nil, nil)
+}
+
+// TransferRoom makes a user the owner of a room the logged in user owns.
+func (c *Client) TransferRoom(ctx context.Context, id int64, request api.RoomMemberRequest) error {
+	return c.do(ctx, c.baseURL, http.MethodPut, roomPath(id)+"/owner", request, nil)
+}
+
+// JoinRoom adds the logged in user to a public room.
+func (c *Client) JoinRoom(ctx context.Context, id int64) (api.RoomResponse, error) {
+	var response api.RoomResponse
+	err := c.do(ctx, c.baseURL, http.MethodPost, roomPath(id)+"/join", nil, &response)
+
+	return response, err
+}
+
+// KickRoomMember removes a user from a room the logged in user is an admin of. They may join again.
+func (c *Client) KickRoomMember(ctx context.Context, id int64, username string) error {
+	return c.do(ctx, c.baseURL, http.MethodDelete, roomPath(id)+"/members/"+url.PathEscape(username), nil, nil)
+}
+
+// BanRoomMember removes a user from a room the logged in user is an admin of and keeps them out until the ban expires.
+func (c *Client) BanRoomMember(ctx context.Context, id int64, username string, request api.RoomRestrictRequest) error {
+	return c.do(ctx, c.baseURL, http.MethodPut, roomPath(id)+"/bans/"+url.PathEscape(username), request, nil)
+}
+
+func (c *Client) UnbanRoomMember(ctx context.Context, id int64, username string) error {
+	return c.do(ctx, c.baseURL, http.MethodDelete, roomPath(id)+"/bans/"+url.PathEscape(username), nil, nil)
+}
+
+// Bans iterates over the active bans of a room the logged in user is an admin of.
+func (c *Client) Bans(ctx context.Context, id int64) iter.Seq2[api.RestrictionResponse, error] {
+	return list[api.RestrictionResponse](ctx, c, c.baseURL.JoinPath(roomPath(id), "bans"))
+}
+
+func (c *Client) ListBans(ctx context.Context, id int64) ([]api.RestrictionResponse, error) {
+	return collect(c.Bans(ctx, id))
+}
+
+// MuteRoomMember keeps a user from posting in a room the logged in user is an admin of until the mute expires.
+func (c *Client) MuteRoomMember(ctx context.Context, id int64, username string, request api.RoomRestrictRequest) error {
+	return c.do(ctx, c.baseURL, http.MethodPut, roomPath(id)+"/mutes/"+url.PathEscape(username), request, nil)
+}
+
+func (c *Client) UnmuteRoomMember(ctx context.Context, id int64, username string) error {
+	return c.do(ctx, c.baseURL, http.MethodDelete, roomPath(id)+"/mutes/"+url.PathEscape(username), nil, nil)
+}
+
+// Mutes iterates over the active mutes of a room the logged in user is an admin of.
+func (c *Client) Mutes(ctx context.Context, id int64) iter.Seq2[api.RestrictionResponse, error] {
+	return list[api.RestrictionResponse](ctx, c, c.baseURL.JoinPath(roomPath(id), "mutes"))
+}
+
+func (c *Client) ListMutes(ctx context.Context, id int64) ([]api.RestrictionResponse, error) {
+	return collect(c.Mutes(ctx, id))
+}
+
+// DeleteRoom deletes a room the logged in user is an admin of.
+func (c *Client) DeleteRoom(ctx context.Context, id int64) error {
+	return c.do(ctx, c.baseURL, http.MethodDelete, roomPath(id), nil, nil)
+}
+
+// PinMessage pins a message in a room. The logged in user must be a room admin or allowed to pin by one.
+func (c *Client) PinMessage(ctx context.Context, roomId int64, messageId string) error {
+	return c.do(ctx, c.baseURL, http.MethodPut, roomPath(roomId)+"/pins/"+messageId, nil, nil)
+}
+
+func (c *Client) UnpinMessage(ctx context.Context, roomId int64, messageId string) error {
+	return c.do(ctx, c.baseURL, http.MethodDelete, roomPath(roomId)+"/pins/"+messageId, nil, nil)
+}
+
+// Pins iterates over the messages pinned in a room, oldest pin first.
+func (c *Client) Pins(ctx context.Context, roomId int64) iter.Seq2[api.PinResponse, error] {
+	return list[api.PinResponse](ctx, c, c.baseURL.JoinPath(roomPath(roomId), "pins"))
+}
+
+func (c *Client) ListPins(ctx context.Context, roomId int64) ([]api.PinResponse, error) {
+	return collect(c.Pins(ctx, roomId))
+}
+
+// SetPinSettings replaces who may pin messages in a room the logged in user is an admin of, and how many.
+func (c *Client) SetPinSettings(ctx context.Context, roomId int64, request api.PinSettingsRequest) error {
+	return c.do(ctx, c.baseURL, http.MethodPut, roomPath(roomId)+"/pin-settings", request, nil)
+}
+
+func roomPath(id int64) string {
+	return "/api/rooms/" + strconv.FormatInt(id, 10)
+}
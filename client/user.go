@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+
+	"github.com/worsediscord/server/api"
+)
+
+// CreateUser registers a new user. It doesn't log in as them.
+func (c *Client) CreateUser(ctx context.Context, request api.UserCreateRequest) error {
+	return c.do(ctx, c.baseURL, http.MethodPost, "/api/users", request, nil)
+}
+
+// Login starts a session as username. The credentials are kept so that the session can be refreshed.
+func (c *Client) Login(ctx context.Context, username, password string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	token, err := c.login(ctx, username, password)
+	if err != nil {
+		return err
+	}
+
+	c.token = token
+	c.username = username
+	c.password = password
+
+	return nil
+}
+
+// Logout forgets the session and credentials. The session itself stays valid until it expires.
+func (c *Client) Logout() {
+	c.SetToken("")
+}
+
+func (c *Client) login(ctx context.Context, username, password string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL.JoinPath("/api/users/login").String(), nil)
+	if err != nil {
+		return "", err
+	}
+	request.SetBasicAuth(username, password)
+
+	var response api.UserLoginResponse
+	if _, err = c.roundTrip(request, &response); err != nil {
+		return "", err
+	}
+
+	return response.Token, nil
+}
+
+func (c *Client) Users(ctx context.Context) iter.Seq2[api.UserResponse, error] {
+	return list[api.UserResponse](ctx, c, c.baseURL.JoinPath("/api/users"))
+}
+
+func (c *Client) ListUsers(ctx context.Context) ([]api.UserResponse, error) {
+	return collect(c.Users(ctx))
+}
+
+func (c *Client) GetUser(ctx context.Context, username string) (api.UserResponse, error) {
+	var response api.UserResponse
+	err := c.do(ctx, c.baseURL, http.MethodGet, "/api/users/"+url.PathEscape(username), nil, &response)
+
+	return response, err
+}
+
+// DeleteUser deletes username, which must be the logged in user. Their sessions are revoked, including the client's.
+func (c *Client) DeleteUser(ctx context.Context, username string) error {
+	return c.do(ctx, c.baseURL, http.MethodDelete, "/api/users/"+url.PathEscape(username), nil, nil)
+}
+
+// DirectMessage returns the direct message room between the logged in user, username and any users in the request,
+// creating it if it doesn't exist.
+func (c *Client) DirectMessage(ctx context.Context, username string, request api.DirectMessageRequest) (api.RoomResponse, error) {
+	var response api.RoomResponse
+	err := c.do(ctx, c.baseURL, http.MethodPost, "/api/users/"+url.PathEscape(username)+"/dm", request, &response)
+
+	return response, err
+}
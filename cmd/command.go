@@ -1,5 +1,10 @@
 package cmd
 
+import (
+	"flag"
+	"fmt"
+)
+
 type Command interface {
 	Name() string
 	Description() string
@@ -7,6 +12,56 @@ type Command interface {
 	Run() error
 }
 
+// Parent is implemented by commands that have subcommands, forming a tree of commands.
+type Parent interface {
+	Command
+	Subcommands() []Command
+}
+
+// Flagged is implemented by commands that can return their flag.FlagSet without parsing anything. Flags are read from
+// it to generate completions.
+type Flagged interface {
+	Command
+	FlagSet() *flag.FlagSet
+}
+
+// UnknownCommandError is returned when a Parent is asked to run a subcommand it doesn't have.
+type UnknownCommandError struct {
+	Name string
+}
+
+func (u *UnknownCommandError) Error() string {
+	return fmt.Sprintf("unknown command %q", u.Name)
+}
+
+// Dispatch parses and runs the subcommand of parent named by args[0]. If args is empty the help of parent is printed.
+// If no subcommand matches, the help of parent is printed and an *UnknownCommandError is returned.
+func Dispatch(helpPrefix string, parent Parent, args []string) error {
+	var fs *flag.FlagSet
+	if f, ok := parent.(Flagged); ok {
+		fs = f.FlagSet()
+	}
+
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), HelpString(helpPrefix, parent, fs, parent.Subcommands()...))
+		return nil
+	}
+
+	for _, subcommand := range parent.Subcommands() {
+		if args[0] == subcommand.Name() {
+			if err := subcommand.Parse(args[1:]); err != nil {
+				return err
+			}
+
+			return subcommand.Run()
+		}
+	}
+
+	_, _ = fmt.Fprint(flag.CommandLine.Output(), HelpString(helpPrefix, parent, fs, parent.Subcommands()...))
+
+	return &UnknownCommandError{Name: args[0]}
+}
+
 // StringSliceValue implements flag.Value.
 type StringSliceValue []string
 
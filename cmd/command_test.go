@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+type testCmd struct {
+	name string
+	port int
+	ran  bool
+}
+
+func (t *testCmd) Name() string {
+	return t.name
+}
+
+func (t *testCmd) Description() string {
+	return "A test command"
+}
+
+func (t *testCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.IntVar(&t.port, "p", 8080, "Port to listen on")
+	fs.IntVar(&t.port, "port", 8080, LongFlagUsage("p"))
+
+	return fs
+}
+
+func (t *testCmd) Parse(args []string) error {
+	return t.FlagSet().Parse(args)
+}
+
+func (t *testCmd) Run() error {
+	t.ran = true
+	return nil
+}
+
+func TestDispatch(t *testing.T) {
+	output := flag.CommandLine.Output()
+	flag.CommandLine.SetOutput(io.Discard)
+	defer flag.CommandLine.SetOutput(output)
+
+	tests := map[string]struct {
+		args        []string
+		expectedRan bool
+		expectedErr error
+	}{
+		"subcommand": {
+			args:        []string{"user", "create", "-p", "9090"},
+			expectedRan: true,
+		},
+		"no args": {
+			args: []string{"user"},
+		},
+		"unknown": {
+			args:        []string{"user", "destroy"},
+			expectedErr: &UnknownCommandError{},
+		},
+		"unknown group": {
+			args:        []string{"nope"},
+			expectedErr: &UnknownCommandError{},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			create := &testCmd{name: "create"}
+			root := NewGroup("wdscmd", "", "", NewGroup("user", "", "wdscmd ", create))
+
+			err := Dispatch("", root, input.args)
+
+			var unknownCommandErr *UnknownCommandError
+			if input.expectedErr != nil && !errors.As(err, &unknownCommandErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if input.expectedErr == nil && err != nil {
+				t.Fatalf("got error %v, expected nil", err)
+			}
+
+			if create.ran != input.expectedRan {
+				t.Fatalf("got ran %v, expected %v", create.ran, input.expectedRan)
+			}
+		})
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	root := NewGroup("wdscmd", "", "", NewGroup("user", "Manage users", "wdscmd ", &testCmd{name: "create"}))
+
+	tests := map[string]struct {
+		shell            string
+		expectedContains []string
+		expectedErr      error
+	}{
+		"bash": {
+			shell:            "bash",
+			expectedContains: []string{"complete -F _wdscmd wdscmd", "'wdscmd user create')", "'-p --port'"},
+		},
+		"zsh": {
+			shell:            "zsh",
+			expectedContains: []string{"#compdef wdscmd", "'user:Manage users'", "'--port:Port to listen on'"},
+		},
+		"fish": {
+			shell:            "fish",
+			expectedContains: []string{"-a 'user' -d 'Manage users'", "-s p -l port"},
+		},
+		"unsupported": {
+			shell:       "tcsh",
+			expectedErr: ErrUnsupportedShell,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+
+			if err := WriteCompletion(&b, input.shell, root); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			for _, s := range input.expectedContains {
+				if !strings.Contains(b.String(), s) {
+					t.Fatalf("expected completion to contain %q, got:\n%s", s, b.String())
+				}
+			}
+		})
+	}
+}
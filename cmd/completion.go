@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ErrUnsupportedShell is returned when a completion script is requested for a shell that isn't supported.
+var ErrUnsupportedShell = errors.New("unsupported shell")
+
+// Shells lists the shells that completion scripts can be generated for.
+var Shells = []string{"bash", "zsh", "fish"}
+
+var nonIdentifierRegex = regexp.MustCompile("[^a-zA-Z0-9_]")
+
+// completionNode is a flattened view of a command in the tree.
+type completionNode struct {
+	path        string
+	description string
+	flags       []helpFlag
+	subcommands []Command
+}
+
+// WriteCompletion writes a completion script for shell that completes the commands and flags under root.
+func WriteCompletion(w io.Writer, shell string, root Command) error {
+	nodes := completionNodes(root.Name(), root)
+
+	switch shell {
+	case "bash":
+		return writeBashCompletion(w, root.Name(), nodes)
+	case "zsh":
+		return writeZshCompletion(w, root.Name(), nodes)
+	case "fish":
+		return writeFishCompletion(w, root.Name(), nodes)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedShell, shell)
+	}
+}
+
+// completionNodes walks the tree depth first, starting at command.
+func completionNodes(path string, command Command) []completionNode {
+	node := completionNode{path: path, description: command.Description()}
+
+	if f, ok := command.(Flagged); ok {
+		if fs := f.FlagSet(); fs != nil {
+			node.flags = collectFlags(fs)
+		}
+	}
+
+	if p, ok := command.(Parent); ok {
+		node.subcommands = p.Subcommands()
+	}
+
+	nodes := []completionNode{node}
+	for _, subcommand := range node.subcommands {
+		nodes = append(nodes, completionNodes(path+" "+subcommand.Name(), subcommand)...)
+	}
+
+	return nodes
+}
+
+// flagNames returns every spelling of the flags, e.g. "-p" and "--port".
+func (c completionNode) flagNames() []string {
+	var names []string
+
+	for _, f := range c.flags {
+		if f.shortFlag != "" {
+			names = append(names, "-"+f.shortFlag)
+		}
+
+		if f.longFlag != "" {
+			names = append(names, "--"+f.longFlag)
+		}
+	}
+
+	return names
+}
+
+// parentPaths returns the paths of every node that has a parent, used to decide when a word descends into the tree.
+func parentPaths(nodes []completionNode) []string {
+	paths := make([]string, 0, len(nodes))
+
+	for _, node := range nodes[1:] {
+		paths = append(paths, node.path)
+	}
+
+	return paths
+}
+
+func writeBashCompletion(w io.Writer, name string, nodes []completionNode) error {
+	fn := "_" + nonIdentifierRegex.ReplaceAllString(name, "_")
+
+	var b strings.Builder
+
+	_, _ = fmt.Fprintf(&b, "# bash completion for %s\n\n", name)
+	_, _ = fmt.Fprintf(&b, "%s() {\n", fn)
+	_, _ = fmt.Fprintf(&b, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\" cmdpath=%s word i\n\n", shellQuote(name))
+	_, _ = fmt.Fprint(&b, "    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+	_, _ = fmt.Fprint(&b, "        word=\"${COMP_WORDS[i]}\"\n")
+	_, _ = fmt.Fprint(&b, "        case \"$cmdpath $word\" in\n")
+	_, _ = fmt.Fprintf(&b, "            %s) cmdpath=\"$cmdpath $word\" ;;\n", quoteAll(parentPaths(nodes), "|"))
+	_, _ = fmt.Fprint(&b, "        esac\n")
+	_, _ = fmt.Fprint(&b, "    done\n\n")
+	_, _ = fmt.Fprint(&b, "    case \"$cmdpath\" in\n")
+
+	for _, node := range nodes {
+		var words []string
+		for _, subcommand := range node.subcommands {
+			words = append(words, subcommand.Name())
+		}
+		words = append(words, node.flagNames()...)
+
+		_, _ = fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -W %s -- \"$cur\")) ;;\n", shellQuote(node.path), shellQuote(strings.Join(words, " ")))
+	}
+
+	_, _ = fmt.Fprint(&b, "    esac\n")
+	_, _ = fmt.Fprint(&b, "}\n\n")
+	_, _ = fmt.Fprintf(&b, "complete -F %s %s\n", fn, name)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeZshCompletion(w io.Writer, name string, nodes []completionNode) error {
+	fn := "_" + nonIdentifierRegex.ReplaceAllString(name, "_")
+
+	var b strings.Builder
+
+	_, _ = fmt.Fprintf(&b, "#compdef %s\n\n", name)
+	_, _ = fmt.Fprintf(&b, "%s() {\n", fn)
+	_, _ = fmt.Fprintf(&b, "    local cmdpath=%s word i\n", shellQuote(name))
+	_, _ = fmt.Fprint(&b, "    local -a candidates\n\n")
+	_, _ = fmt.Fprint(&b, "    for ((i = 2; i < CURRENT; i++)); do\n")
+	_, _ = fmt.Fprint(&b, "        word=\"${words[i]}\"\n")
+	_, _ = fmt.Fprint(&b, "        case \"$cmdpath $word\" in\n")
+	_, _ = fmt.Fprintf(&b, "            (%s) cmdpath=\"$cmdpath $word\" ;;\n", quoteAll(parentPaths(nodes), "|"))
+	_, _ = fmt.Fprint(&b, "        esac\n")
+	_, _ = fmt.Fprint(&b, "    done\n\n")
+	_, _ = fmt.Fprint(&b, "    case \"$cmdpath\" in\n")
+
+	for _, node := range nodes {
+		var candidates []string
+		for _, subcommand := range node.subcommands {
+			candidates = append(candidates, zshCandidate(subcommand.Name(), subcommand.Description()))
+		}
+
+		for _, f := range node.flags {
+			if f.shortFlag != "" {
+				candidates = append(candidates, zshCandidate("-"+f.shortFlag, f.usage))
+			}
+
+			if f.longFlag != "" {
+				candidates = append(candidates, zshCandidate("--"+f.longFlag, f.usage))
+			}
+		}
+
+		_, _ = fmt.Fprintf(&b, "        (%s) candidates=(%s) ;;\n", shellQuote(node.path), strings.Join(candidates, " "))
+	}
+
+	_, _ = fmt.Fprint(&b, "    esac\n\n")
+	_, _ = fmt.Fprint(&b, "    _describe 'command' candidates\n")
+	_, _ = fmt.Fprint(&b, "}\n\n")
+	_, _ = fmt.Fprintf(&b, "compdef %s %s\n", fn, name)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeFishCompletion(w io.Writer, name string, nodes []completionNode) error {
+	fn := "__" + nonIdentifierRegex.ReplaceAllString(name, "_") + "_cmdpath"
+
+	var b strings.Builder
+
+	_, _ = fmt.Fprintf(&b, "# fish completion for %s\n\n", name)
+	_, _ = fmt.Fprintf(&b, "function %s\n", fn)
+	_, _ = fmt.Fprintf(&b, "    set -l cmdpath %s\n", shellQuote(name))
+	_, _ = fmt.Fprint(&b, "    for word in (commandline -opc)[2..-1]\n")
+	_, _ = fmt.Fprint(&b, "        switch \"$cmdpath $word\"\n")
+	_, _ = fmt.Fprintf(&b, "            case %s\n", quoteAll(parentPaths(nodes), " "))
+	_, _ = fmt.Fprint(&b, "                set cmdpath \"$cmdpath $word\"\n")
+	_, _ = fmt.Fprint(&b, "        end\n")
+	_, _ = fmt.Fprint(&b, "    end\n")
+	_, _ = fmt.Fprint(&b, "    echo $cmdpath\n")
+	_, _ = fmt.Fprint(&b, "end\n\n")
+	_, _ = fmt.Fprintf(&b, "complete -c %s -f\n", name)
+
+	for _, node := range nodes {
+		condition := shellQuote(fmt.Sprintf("test (%s) = %s", fn, shellQuote(node.path)))
+
+		for _, subcommand := range node.subcommands {
+			_, _ = fmt.Fprintf(&b, "complete -c %s -n %s -a %s -d %s\n", name, condition, shellQuote(subcommand.Name()), shellQuote(subcommand.Description()))
+		}
+
+		for _, f := range node.flags {
+			_, _ = fmt.Fprintf(&b, "complete -c %s -n %s", name, condition)
+
+			if f.shortFlag != "" {
+				_, _ = fmt.Fprintf(&b, " -s %s", f.shortFlag)
+			}
+
+			if f.longFlag != "" {
+				_, _ = fmt.Fprintf(&b, " -l %s", f.longFlag)
+			}
+
+			_, _ = fmt.Fprintf(&b, " -d %s\n", shellQuote(f.usage))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// zshCandidate formats a _describe candidate, escaping the colon that separates the value from its description.
+func zshCandidate(value string, description string) string {
+	return shellQuote(strings.ReplaceAll(value, ":", `\:`) + ":" + description)
+}
+
+// shellQuote single quotes s for bash, zsh and fish.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quoteAll single quotes each string and joins them with sep.
+func quoteAll(values []string, sep string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, shellQuote(v))
+	}
+
+	return strings.Join(quoted, sep)
+}
+
+type CompletionCmd struct {
+	Shell string
+
+	root       Command
+	name       string
+	helpPrefix string
+	output     io.Writer
+}
+
+// NewCompletionCmd returns a command that writes completion scripts for root to output.
+func NewCompletionCmd(name string, helpPrefix string, root Command, output io.Writer) *CompletionCmd {
+	if len(name) == 0 {
+		name = "completion"
+	}
+
+	return &CompletionCmd{
+		root:       root,
+		name:       name,
+		helpPrefix: helpPrefix,
+		output:     output,
+	}
+}
+
+func (c *CompletionCmd) Name() string {
+	return c.name
+}
+
+func (c *CompletionCmd) Description() string {
+	return "Generate a shell completion script (" + strings.Join(Shells, " | ") + ")"
+}
+
+func (c *CompletionCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.Name(), flag.ExitOnError)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), HelpString(c.helpPrefix, c, fs))
+	}
+
+	return fs
+}
+
+func (c *CompletionCmd) Parse(args []string) error {
+	fs := c.FlagSet()
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("%w: expected one of %s", ErrUnsupportedShell, strings.Join(Shells, ", "))
+	}
+
+	c.Shell = fs.Arg(0)
+
+	return nil
+}
+
+func (c *CompletionCmd) Run() error {
+	return WriteCompletion(c.output, c.Shell, c.root)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvBound is implemented by commands whose flags are bound to environment variables named by EnvName. Flags with a
+// long version, see LongFlagUsage, are bound by their long name only. The values are read by ParseWithEnv and shown by
+// HelpString.
+type EnvBound interface {
+	Command
+
+	// CommandPath returns the full path of the command that its environment variables are named after, e.g.
+	// "wdscmd start".
+	CommandPath() string
+}
+
+// EnvName returns the environment variable bound to a flag of the command at commandPath, e.g. "wdscmd start" and
+// "tls-cert" become WDSCMD_START_TLS_CERT.
+func EnvName(commandPath string, flagName string) string {
+	return strings.ToUpper(nonIdentifierRegex.ReplaceAllString(commandPath+"_"+flagName, "_"))
+}
+
+// ParseWithEnv parses args with fs, the flag.FlagSet of command, then sets every flag that wasn't given on the command
+// line from its environment variable, so flags always take precedence. A *StringSliceValue is set once per comma
+// separated element.
+func ParseWithEnv(command EnvBound, fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	for _, h := range collectFlags(fs) {
+		if set[h.shortFlag] || set[h.longFlag] {
+			continue
+		}
+
+		name := h.name()
+		env := EnvName(command.CommandPath(), name)
+
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+
+		values := []string{value}
+		if _, isSlice := fs.Lookup(name).Value.(*StringSliceValue); isSlice {
+			values = strings.Split(value, ",")
+		}
+
+		for _, v := range values {
+			if err := fs.Set(name, strings.TrimSpace(v)); err != nil {
+				return fmt.Errorf("invalid value %q for %s: %w", value, env, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// envName returns the environment variable bound to h, or an empty string if command isn't EnvBound.
+func envName(command Command, h helpFlag) string {
+	bound, ok := command.(EnvBound)
+	if !ok {
+		return ""
+	}
+
+	return EnvName(bound.CommandPath(), h.name())
+}
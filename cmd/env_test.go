@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// envTestCmd is a testCmd whose flags are bound to environment variables.
+type envTestCmd struct {
+	testCmd
+}
+
+func (e *envTestCmd) CommandPath() string {
+	return "wdscmd " + e.Name()
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]struct {
+		commandPath  string
+		flagName     string
+		expectedName string
+	}{
+		"simple": {
+			commandPath:  "wdscmd start",
+			flagName:     "port",
+			expectedName: "WDSCMD_START_PORT",
+		},
+		"dashes": {
+			commandPath:  "wdscmd user reset-password",
+			flagName:     "admin-user",
+			expectedName: "WDSCMD_USER_RESET_PASSWORD_ADMIN_USER",
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := EnvName(input.commandPath, input.flagName); got != input.expectedName {
+				t.Fatalf("got %s, expected %s", got, input.expectedName)
+			}
+		})
+	}
+}
+
+func TestParseWithEnv(t *testing.T) {
+	tests := map[string]struct {
+		args           []string
+		env            map[string]string
+		expectedPort   string
+		expectedAdmins StringSliceValue
+		expectedErr    bool
+	}{
+		"default": {
+			expectedPort: "8069",
+		},
+		"env": {
+			env:            map[string]string{"WDSCMD_START_PORT": "9090", "WDSCMD_START_ADMIN": "spiderman, venom"},
+			expectedPort:   "9090",
+			expectedAdmins: StringSliceValue{"spiderman", "venom"},
+		},
+		"short flag takes precedence": {
+			args:         []string{"-p", "7070"},
+			env:          map[string]string{"WDSCMD_START_PORT": "9090"},
+			expectedPort: "7070",
+		},
+		"long flag takes precedence": {
+			args:           []string{"--port", "7070", "--admin", "venom"},
+			env:            map[string]string{"WDSCMD_START_PORT": "9090", "WDSCMD_START_ADMIN": "spiderman"},
+			expectedPort:   "7070",
+			expectedAdmins: StringSliceValue{"venom"},
+		},
+		"invalid": {
+			env:         map[string]string{"WDSCMD_START_H2C": "maybe"},
+			expectedErr: true,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			for k, v := range input.env {
+				t.Setenv(k, v)
+			}
+
+			var port string
+			var h2c bool
+			var admins StringSliceValue
+
+			fs := flag.NewFlagSet("start", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			fs.StringVar(&port, "p", "8069", "TCP Port to listen on.")
+			fs.StringVar(&port, "port", "8069", LongFlagUsage("p"))
+			fs.BoolVar(&h2c, "h2c", false, "Enable HTTP/2 over plaintext connections.")
+			fs.Var(&admins, "admin", "Username to grant the server administrator role.")
+
+			err := ParseWithEnv(&envTestCmd{testCmd{name: "start"}}, fs, input.args)
+			if (err != nil) != input.expectedErr {
+				t.Fatalf("got error %v, expected error %v", err, input.expectedErr)
+			}
+
+			if input.expectedErr {
+				return
+			}
+
+			if port != input.expectedPort {
+				t.Fatalf("got port %s, expected %s", port, input.expectedPort)
+			}
+
+			if !reflect.DeepEqual(admins, input.expectedAdmins) {
+				t.Fatalf("got admins %v, expected %v", admins, input.expectedAdmins)
+			}
+		})
+	}
+}
+
+func TestHelpString_Env(t *testing.T) {
+	fs := flag.NewFlagSet("start", flag.ContinueOnError)
+	fs.String("p", "8069", "TCP Port to listen on.")
+	fs.String("port", "8069", LongFlagUsage("p"))
+
+	if help := HelpString("wdscmd ", &testCmd{name: "start"}, fs); strings.Contains(help, "WDSCMD_START_PORT") {
+		t.Fatalf("expected unbound help to not contain the env var, got:\n%s", help)
+	}
+
+	if help := HelpString("wdscmd ", &envTestCmd{testCmd{name: "start"}}, fs); !strings.Contains(help, "WDSCMD_START_PORT") {
+		t.Fatalf("expected help to contain the env var, got:\n%s", help)
+	}
+}
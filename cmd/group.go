@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Group is a Command that only dispatches to its subcommands, e.g. the "user" in "wdscmd user create".
+type Group struct {
+	name        string
+	description string
+	helpPrefix  string
+	args        []string
+	subcommands []Command
+}
+
+// NewGroup returns a Group. helpPrefix is the path of parent commands, the same as passed to HelpString.
+func NewGroup(name string, description string, helpPrefix string, subcommands ...Command) *Group {
+	return &Group{
+		name:        name,
+		description: description,
+		helpPrefix:  helpPrefix,
+		subcommands: subcommands,
+	}
+}
+
+func (g *Group) Name() string {
+	return g.name
+}
+
+func (g *Group) Description() string {
+	return g.description
+}
+
+// HelpPrefix returns the help prefix that subcommands of the group should use.
+func (g *Group) HelpPrefix() string {
+	return g.helpPrefix + g.name + " "
+}
+
+func (g *Group) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(g.Name(), flag.ExitOnError)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), HelpString(g.helpPrefix, g, fs, g.subcommands...))
+	}
+
+	return fs
+}
+
+func (g *Group) Parse(args []string) error {
+	fs := g.FlagSet()
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	g.args = fs.Args()
+
+	return nil
+}
+
+// Run runs the subcommand named by the first argument, see Dispatch.
+func (g *Group) Run() error {
+	return Dispatch(g.helpPrefix, g, g.args)
+}
+
+func (g *Group) Subcommands() []Command {
+	return g.subcommands
+}
+
+func (g *Group) AddSubcommands(subcommands ...Command) {
+	g.subcommands = append(g.subcommands, subcommands...)
+}
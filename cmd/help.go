@@ -18,6 +18,15 @@ type helpFlag struct {
 	usage     string
 }
 
+// name returns the long name of the flag if it has one, otherwise the short name.
+func (h helpFlag) name() string {
+	if h.longFlag != "" {
+		return h.longFlag
+	}
+
+	return h.shortFlag
+}
+
 // LongFlagUsage returns a formatted string to be used in flag.Flag's usage string as a long version.
 func LongFlagUsage(shortFlagName string) string {
 	return fmt.Sprintf("%s%s%s", helpLongFlagTag, helpFlagDelim, shortFlagName)
@@ -48,6 +57,43 @@ func HelpString(commandPrefix string, command Command, fs *flag.FlagSet, subcomm
 		return helpString.String()
 	}
 
+	helpFlags := collectFlags(fs)
+
+	// If for some reason fs wasn't nil, but we didn't find any formal flags, return early
+	if len(helpFlags) == 0 {
+		_ = w.Flush()
+		return helpString.String()
+	}
+
+	_, _ = fmt.Fprintf(w, "\nOptions:\n")
+	for _, h := range helpFlags {
+		_, _ = fmt.Fprint(w, padding)
+
+		if h.shortFlag != "" {
+			_, _ = fmt.Fprintf(w, "-%s", h.shortFlag)
+		} else {
+			// pad four spaces for: - + [short flag] + , + ' '
+			_, _ = fmt.Fprint(w, "    ")
+		}
+
+		if h.longFlag != "" {
+			if h.shortFlag != "" {
+				_, _ = fmt.Fprint(w, ", ")
+			}
+
+			_, _ = fmt.Fprintf(w, "--%s", h.longFlag)
+		}
+
+		_, _ = fmt.Fprintf(w, "\t%s\t%s\n", envName(command, h), h.usage)
+	}
+
+	_ = w.Flush()
+
+	return helpString.String()
+}
+
+// collectFlags pairs up the short and long versions of each flag in fs, as marked by LongFlagUsage.
+func collectFlags(fs *flag.FlagSet) []helpFlag {
 	var helpFlags []helpFlag
 	var orphanedFlags []*flag.Flag
 	fs.VisitAll(func(f *flag.Flag) {
@@ -71,12 +117,6 @@ func HelpString(commandPrefix string, command Command, fs *flag.FlagSet, subcomm
 		}
 	})
 
-	// If for some reason fs wasn't nil, but we didn't find any formal flags, return early
-	if len(helpFlags) == 0 {
-		_ = w.Flush()
-		return helpString.String()
-	}
-
 	// Loop through any orphaned flags once more to see if we just got unlucky with ordering
 	for _, f := range orphanedFlags {
 		if strings.HasPrefix(f.Usage, helpLongFlagTag) {
@@ -90,31 +130,7 @@ func HelpString(commandPrefix string, command Command, fs *flag.FlagSet, subcomm
 		}
 	}
 
-	_, _ = fmt.Fprintf(w, "\nOptions:\n")
-	for _, h := range helpFlags {
-		_, _ = fmt.Fprint(w, padding)
-
-		if h.shortFlag != "" {
-			_, _ = fmt.Fprintf(w, "-%s", h.shortFlag)
-		} else {
-			// pad four spaces for: - + [short flag] + , + ' '
-			_, _ = fmt.Fprint(w, "    ")
-		}
-
-		if h.longFlag != "" {
-			if h.shortFlag != "" {
-				_, _ = fmt.Fprint(w, ", ")
-			}
-
-			_, _ = fmt.Fprintf(w, "--%s", h.longFlag)
-		}
-
-		_, _ = fmt.Fprintf(w, "\t\t%s\n", h.usage)
-	}
-
-	_ = w.Flush()
-
-	return helpString.String()
+	return helpFlags
 }
 
 func getShortFlag(longFlagUsage string) string {
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/worsediscord/server/cmd"
+	"github.com/worsediscord/server/services/audit"
+)
+
+func NewAuditCmd(name string, helpPrefix string) *cmd.Group {
+	if len(name) == 0 {
+		name = "audit"
+	}
+
+	group := cmd.NewGroup(name, "Inspect the audit log", helpPrefix)
+	group.AddSubcommands(
+		NewAuditVerifyCmd("verify", group.HelpPrefix()),
+	)
+
+	return group
+}
+
+type AuditVerifyCmd struct {
+	File string
+
+	name       string
+	helpPrefix string
+}
+
+func NewAuditVerifyCmd(name string, helpPrefix string) *AuditVerifyCmd {
+	if len(name) == 0 {
+		name = "verify"
+	}
+
+	return &AuditVerifyCmd{
+		File:       "audit.log",
+		name:       name,
+		helpPrefix: helpPrefix,
+	}
+}
+
+func (a *AuditVerifyCmd) Name() string {
+	return a.name
+}
+
+func (a *AuditVerifyCmd) Description() string {
+	return "Verify the hash chain of an audit log file"
+}
+
+func (a *AuditVerifyCmd) CommandPath() string {
+	return a.helpPrefix + a.Name()
+}
+
+func (a *AuditVerifyCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(a.Name(), flag.ExitOnError)
+
+	fs.StringVar(&a.File, "f", a.File, "Path to the audit log file.")
+	fs.StringVar(&a.File, "file", a.File, cmd.LongFlagUsage("f"))
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(a.helpPrefix, a, fs))
+	}
+
+	return fs
+}
+
+func (a *AuditVerifyCmd) Parse(args []string) error {
+	return cmd.ParseWithEnv(a, a.FlagSet(), args)
+}
+
+func (a *AuditVerifyCmd) Run() error {
+	entries, err := audit.ReadFile(a.File)
+	if err != nil {
+		return err
+	}
+
+	if err = audit.Verify(entries); err != nil {
+		return fmt.Errorf("audit log %s failed verification: %w", a.File, err)
+	}
+
+	fmt.Printf("%s: %d entries verified\n", a.File, len(entries))
+
+	return nil
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/worsediscord/server/api"
+	"github.com/worsediscord/server/cmd"
+	"github.com/worsediscord/server/services/audit"
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/cascade"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/services/wal"
+)
+
+// ErrNoPersistentStorage is returned when an offline command is pointed at storage that doesn't outlive the server.
+var ErrNoPersistentStorage = errors.New("the configured storage backend doesn't persist data")
+
+// AdminBackend performs administrative operations, either directly against storage or through a server's admin API.
+type AdminBackend interface {
+	CreateUser(ctx context.Context, username string, password string) error
+	ListUsers(ctx context.Context) ([]api.AdminUserResponse, error)
+	DeleteUser(ctx context.Context, username string) error
+	ResetPassword(ctx context.Context, username string, password string) error
+	SetServerAdmin(ctx context.Context, username string, admin bool) error
+
+	ListRooms(ctx context.Context) ([]api.AdminRoomResponse, error)
+	DeleteRoom(ctx context.Context, id int64) error
+	AddRoomAdmin(ctx context.Context, id int64, username string) error
+
+	RevokeSession(ctx context.Context, id string) error
+	Close() error
+}
+
+// AdminOpts holds the flags shared by every administrative command.
+type AdminOpts struct {
+	Server   string
+	Token    string
+	Username string
+	Password string
+
+	// DeletedUserMessages decides what happens to the messages of users deleted directly in storage. A running server
+	// uses its own setting.
+	DeletedUserMessages string
+
+	Storage *StorageOpts
+}
+
+func NewAdminOpts() *AdminOpts {
+	return &AdminOpts{DeletedUserMessages: "anonymize", Storage: NewStorageOpts()}
+}
+
+func (o *AdminOpts) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Server, "s", o.Server, "URL of a running server to manage through its admin API. Storage is opened directly if empty.")
+	fs.StringVar(&o.Server, "server", o.Server, cmd.LongFlagUsage("s"))
+	fs.StringVar(&o.Token, "token", o.Token, "API key of a server administrator, used with --server.")
+	fs.StringVar(&o.Username, "admin-user", o.Username, "Username of a server administrator to log in as, used with --server.")
+	fs.StringVar(&o.Password, "admin-password", o.Password, "Password for --admin-user.")
+	fs.StringVar(&o.DeletedUserMessages, "deleted-user-messages", o.DeletedUserMessages, "What happens to the messages of users deleted without --server (anonymize | delete)")
+
+	o.Storage.AddFlags(fs)
+}
+
+// Backend returns the AdminBackend selected by the flags. The caller must Close it.
+func (o *AdminOpts) Backend(ctx context.Context) (AdminBackend, error) {
+	if o.Server != "" {
+		return newRemoteBackend(ctx, o.Server, o.Token, o.Username, o.Password)
+	}
+
+	if !o.Storage.Persistent() {
+		return nil, fmt.Errorf("%w, use --server to manage a running server", ErrNoPersistentStorage)
+	}
+
+	deletedUserMessages, err := cascade.ParsePolicy(o.DeletedUserMessages)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --deleted-user-messages: %w", err)
+	}
+
+	services, err := o.Storage.Open()
+	if errors.Is(err, wal.ErrLocked) {
+		return nil, fmt.Errorf("%w, use --server to manage a running server", err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &localBackend{services: services, deletedUserMessages: deletedUserMessages}, nil
+}
+
+// localBackend operates directly on storage while the server is stopped.
+type localBackend struct {
+	services            *Services
+	deletedUserMessages cascade.Policy
+}
+
+func (l *localBackend) CreateUser(ctx context.Context, username string, password string) error {
+	if err := l.services.User.Create(ctx, user.CreateUserOpts{Username: username, Password: password}); err != nil {
+		return err
+	}
+
+	return l.record(ctx, audit.ActionUserCreate, username)
+}
+
+func (l *localBackend) ListUsers(ctx context.Context) ([]api.AdminUserResponse, error) {
+	users, err := l.services.User.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]api.AdminUserResponse, 0, len(users))
+	for _, u := range users {
+		response = append(response, api.AdminUserResponse{Username: u.Username, Nickname: u.Nickname, Admin: u.Admin, Disabled: u.Disabled})
+	}
+
+	return response, nil
+}
+
+func (l *localBackend) DeleteUser(ctx context.Context, username string) error {
+	if _, err := l.services.User.GetUserById(ctx, user.GetUserByIdOpts{Id: username}); err != nil {
+		return err
+	}
+
+	if err := l.deleter().DeleteUser(ctx, user.DeleteUserOpts{Id: username}); err != nil {
+		return err
+	}
+
+	if err := l.revokeUserSessions(username); err != nil {
+		return err
+	}
+
+	return l.record(ctx, audit.ActionUserDelete, username)
+}
+
+func (l *localBackend) ResetPassword(ctx context.Context, username string, password string) error {
+	if err := l.services.User.Update(ctx, user.UpdateUserOpts{Id: username, Password: &password}); err != nil {
+		return err
+	}
+
+	if err := l.revokeUserSessions(username); err != nil {
+		return err
+	}
+
+	return l.record(ctx, audit.ActionPasswordReset, username)
+}
+
+func (l *localBackend) SetServerAdmin(ctx context.Context, username string, admin bool) error {
+	if err := l.services.User.Update(ctx, user.UpdateUserOpts{Id: username, Admin: &admin}); err != nil {
+		return err
+	}
+
+	action := audit.ActionUserDemote
+	if admin {
+		action = audit.ActionUserPromote
+	}
+
+	return l.record(ctx, action, username)
+}
+
+func (l *localBackend) ListRooms(ctx context.Context) ([]api.AdminRoomResponse, error) {
+	rooms, err := l.services.Room.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]api.AdminRoomResponse, 0, len(rooms))
+	for _, r := range rooms {
+		response = append(response, api.AdminRoomResponse{Id: r.Id, Name: r.Name, Users: r.Users, Admins: r.Admins})
+	}
+
+	return response, nil
+}
+
+func (l *localBackend) DeleteRoom(ctx context.Context, id int64) error {
+	if err := l.deleter().DeleteRoom(ctx, room.DeleteRoomOpts{Id: id, Force: true}); err != nil {
+		return err
+	}
+
+	return l.record(ctx, audit.ActionRoomDelete, strconv.FormatInt(id, 10))
+}
+
+func (l *localBackend) AddRoomAdmin(ctx context.Context, id int64, username string) error {
+	if _, err := l.services.User.GetUserById(ctx, user.GetUserByIdOpts{Id: username}); err != nil {
+		return err
+	}
+
+	if err := l.services.Room.Promote(ctx, room.PromoteRoomOpts{Id: id, TargetId: username, Now: time.Now().UnixMilli(), Force: true}); err != nil {
+		return err
+	}
+
+	return l.record(ctx, audit.ActionRoomPromote, strconv.FormatInt(id, 10)+"/"+username)
+}
+
+func (l *localBackend) RevokeSession(ctx context.Context, id string) error {
+	keys, err := l.services.Auth.ListKeys()
+	if err != nil {
+		return err
+	}
+
+	n := slices.IndexFunc(keys, func(key auth.ApiKey) bool { return key.Id() == id })
+	if n == -1 {
+		return auth.ErrNotFound
+	}
+
+	if err = l.services.Auth.RevokeKey(keys[n].Token()); err != nil {
+		return err
+	}
+
+	return l.record(ctx, audit.ActionSessionRevoke, id)
+}
+
+func (l *localBackend) Close() error {
+	return l.services.Close()
+}
+
+// deleter returns a cascade.Deleter over the opened storage.
+func (l *localBackend) deleter() *cascade.Deleter {
+	return &cascade.Deleter{
+		User:     l.services.User,
+		Room:     l.services.Room,
+		Message:  l.services.Message,
+		Reaction: l.services.Reaction,
+		Pin:      l.services.Pin,
+		Invite:   l.services.Invite,
+		Messages: l.deletedUserMessages,
+	}
+}
+
+func (l *localBackend) revokeUserSessions(username string) error {
+	keys, err := l.services.Auth.ListKeys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if key.Payload() == username {
+			if err = l.services.Auth.RevokeKey(key.Token()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *localBackend) record(ctx context.Context, action string, target string) error {
+	_, err := l.services.Audit.Append(ctx, audit.AppendOpts{
+		Actor:    "system",
+		Action:   action,
+		Target:   target,
+		Metadata: map[string]string{"source": "cli"},
+	})
+
+	return err
+}
+
+// remoteBackend operates on a running server through its admin API.
+type remoteBackend struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func newRemoteBackend(ctx context.Context, server string, token string, username string, password string) (*remoteBackend, error) {
+	baseURL, err := url.JoinPath(server, "/api")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &remoteBackend{baseURL: baseURL, token: token, client: http.DefaultClient}
+
+	if r.token != "" {
+		return r, nil
+	}
+
+	if username == "" {
+		return nil, errors.New("--token or --admin-user is required with --server")
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL+"/users/login", nil)
+	if err != nil {
+		return nil, err
+	}
+	request.SetBasicAuth(username, password)
+
+	var response api.UserLoginResponse
+	if err = r.send(request, &response); err != nil {
+		return nil, fmt.Errorf("failed to log in: %w", err)
+	}
+
+	r.token = response.Token
+
+	return r, nil
+}
+
+func (r *remoteBackend) CreateUser(ctx context.Context, username string, password string) error {
+	return r.do(ctx, http.MethodPost, "/users", api.UserCreateRequest{Username: username, Password: password}, nil)
+}
+
+func (r *remoteBackend) ListUsers(ctx context.Context) ([]api.AdminUserResponse, error) {
+	var response []api.AdminUserResponse
+	return response, r.do(ctx, http.MethodGet, "/admin/users", nil, &response)
+}
+
+func (r *remoteBackend) DeleteUser(ctx context.Context, username string) error {
+	return r.do(ctx, http.MethodDelete, "/admin/users/"+url.PathEscape(username), nil, nil)
+}
+
+func (r *remoteBackend) ResetPassword(ctx context.Context, username string, password string) error {
+	path := "/admin/users/" + url.PathEscape(username) + "/password"
+	return r.do(ctx, http.MethodPost, path, api.AdminPasswordResetRequest{Password: password}, nil)
+}
+
+func (r *remoteBackend) SetServerAdmin(ctx context.Context, username string, admin bool) error {
+	return r.do(ctx, http.MethodPatch, "/admin/users/"+url.PathEscape(username), api.AdminUserUpdateRequest{Admin: &admin}, nil)
+}
+
+func (r *remoteBackend) ListRooms(ctx context.Context) ([]api.AdminRoomResponse, error) {
+	var response []api.AdminRoomResponse
+	return response, r.do(ctx, http.MethodGet, "/admin/rooms", nil, &response)
+}
+
+func (r *remoteBackend) DeleteRoom(ctx context.Context, id int64) error {
+	return r.do(ctx, http.MethodDelete, "/admin/rooms/"+strconv.FormatInt(id, 10), nil, nil)
+}
+
+func (r *remoteBackend) AddRoomAdmin(ctx context.Context, id int64, username string) error {
+	path := "/admin/rooms/" + strconv.FormatInt(id, 10) + "/admins"
+	return r.do(ctx, http.MethodPost, path, api.AdminRoomPromoteRequest{Username: username}, nil)
+}
+
+func (r *remoteBackend) RevokeSession(ctx context.Context, id string) error {
+	return r.do(ctx, http.MethodDelete, "/admin/sessions/"+url.PathEscape(id), nil, nil)
+}
+
+func (r *remoteBackend) Close() error {
+	return nil
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response into v, if v isn't nil.
+func (r *remoteBackend) do(ctx context.Context, method string, path string, body any, v any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(b)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("x-api-key", r.token)
+
+	return r.send(request, v)
+}
+
+func (r *remoteBackend) send(request *http.Request, v any) error {
+	response, err := r.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s", request.Method, request.URL.Path, strings.ToLower(http.StatusText(response.StatusCode)))
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(v)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/worsediscord/server/cmd"
+	"github.com/worsediscord/server/services/snapshot"
+)
+
+type BackupCmd struct {
+	Output   string
+	Sessions bool
+	Storage  *StorageOpts
+
+	name       string
+	helpPrefix string
+}
+
+func NewBackupCmd(name string, helpPrefix string) *BackupCmd {
+	if len(name) == 0 {
+		name = "backup"
+	}
+
+	return &BackupCmd{Storage: NewStorageOpts(), name: name, helpPrefix: helpPrefix}
+}
+
+func (b *BackupCmd) Name() string {
+	return b.name
+}
+
+func (b *BackupCmd) Description() string {
+	return "Write every user, room and message to a snapshot file"
+}
+
+func (b *BackupCmd) CommandPath() string {
+	return b.helpPrefix + b.Name()
+}
+
+func (b *BackupCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(b.Name(), flag.ExitOnError)
+
+	fs.StringVar(&b.Output, "o", b.Output, "Path to write the snapshot to.")
+	fs.StringVar(&b.Output, "output", b.Output, cmd.LongFlagUsage("o"))
+	fs.BoolVar(&b.Sessions, "sessions", b.Sessions, "Include active sessions. Anyone with the snapshot can use them.")
+	b.Storage.AddFlags(fs)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(b.helpPrefix, b, fs))
+	}
+
+	return fs
+}
+
+func (b *BackupCmd) Parse(args []string) error {
+	fs := b.FlagSet()
+
+	if err := cmd.ParseWithEnv(b, fs, args); err != nil {
+		return err
+	}
+
+	if b.Output == "" {
+		return errors.New("--output is required")
+	}
+
+	return nil
+}
+
+func (b *BackupCmd) Run() error {
+	if !b.Storage.Persistent() {
+		return fmt.Errorf("%w, there is nothing to back up", ErrNoPersistentStorage)
+	}
+
+	services, err := b.Storage.Open()
+	if err != nil {
+		return err
+	}
+	defer services.Close()
+
+	snap, err := snapshot.Export(context.Background(), services.Snapshot(b.Sessions))
+	if err != nil {
+		return err
+	}
+
+	if err = snapshot.WriteFile(b.Output, snap); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: %d users, %d rooms, %d messages, %d sessions\n", b.Output, len(snap.Users), len(snap.Rooms), len(snap.Messages), len(snap.Sessions))
+
+	return nil
+}
+
+type RestoreCmd struct {
+	File     string
+	Sessions bool
+	Storage  *StorageOpts
+
+	name       string
+	helpPrefix string
+}
+
+func NewRestoreCmd(name string, helpPrefix string) *RestoreCmd {
+	if len(name) == 0 {
+		name = "restore"
+	}
+
+	return &RestoreCmd{Sessions: true, Storage: NewStorageOpts(), name: name, helpPrefix: helpPrefix}
+}
+
+func (r *RestoreCmd) Name() string {
+	return r.name
+}
+
+func (r *RestoreCmd) Description() string {
+	return "Load a snapshot file written by backup into storage"
+}
+
+func (r *RestoreCmd) CommandPath() string {
+	return r.helpPrefix + r.Name()
+}
+
+func (r *RestoreCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(r.Name(), flag.ExitOnError)
+
+	fs.StringVar(&r.File, "f", r.File, "Path to the snapshot to restore. Records with the same id are replaced.")
+	fs.StringVar(&r.File, "file", r.File, cmd.LongFlagUsage("f"))
+	fs.BoolVar(&r.Sessions, "sessions", r.Sessions, "Restore sessions included in the snapshot.")
+	r.Storage.AddFlags(fs)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(r.helpPrefix, r, fs))
+	}
+
+	return fs
+}
+
+func (r *RestoreCmd) Parse(args []string) error {
+	fs := r.FlagSet()
+
+	if err := cmd.ParseWithEnv(r, fs, args); err != nil {
+		return err
+	}
+
+	if r.File == "" {
+		return errors.New("--file is required")
+	}
+
+	return nil
+}
+
+func (r *RestoreCmd) Run() (err error) {
+	if !r.Storage.Persistent() {
+		return fmt.Errorf("%w, there is nowhere to restore to", ErrNoPersistentStorage)
+	}
+
+	snap, err := snapshot.ReadFile(r.File)
+	if err != nil {
+		return err
+	}
+
+	services, err := r.Storage.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, services.Close())
+	}()
+
+	if err = snapshot.Import(context.Background(), services.Snapshot(r.Sessions), snap); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: restored %d users, %d rooms, %d messages\n", r.File, len(snap.Users), len(snap.Rooms), len(snap.Messages))
+
+	return nil
+}
@@ -1,17 +1,45 @@
 package main
 
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/worsediscord/server/cmd"
+)
+
 func main() {
-	rootCmd := NewRootCmd("wdscli")
+	rootCmd := NewRootCmd("wdscmd")
 
 	rootCmd.AddSubcommands(
 		NewStartCmd("start", rootCmd.Name()+" "),
+		NewUserCmd("user", rootCmd.Name()+" "),
+		NewRoomCmd("room", rootCmd.Name()+" "),
+		NewSessionCmd("session", rootCmd.Name()+" "),
+		NewAuditCmd("audit", rootCmd.Name()+" "),
+		NewBackupCmd("backup", rootCmd.Name()+" "),
+		NewRestoreCmd("restore", rootCmd.Name()+" "),
+		NewSearchCmd("search", rootCmd.Name()+" "),
+		cmd.NewCompletionCmd("completion", rootCmd.Name()+" ", rootCmd, os.Stdout),
 	)
 
 	if err := rootCmd.Parse(nil); err != nil {
-		panic(err)
+		exit(err)
 	}
 
 	if err := rootCmd.Run(); err != nil {
-		panic(err)
+		exit(err)
 	}
 }
+
+// exit reports err and exits non-zero. Unknown commands exit with 2 to match flag parsing errors.
+func exit(err error) {
+	_, _ = fmt.Fprintf(os.Stderr, "error: %s\n", err)
+
+	var unknownCommandErr *cmd.UnknownCommandError
+	if errors.As(err, &unknownCommandErr) {
+		os.Exit(2)
+	}
+
+	os.Exit(1)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/worsediscord/server/cmd"
+)
+
+func NewRoomCmd(name string, helpPrefix string) *cmd.Group {
+	if len(name) == 0 {
+		name = "room"
+	}
+
+	group := cmd.NewGroup(name, "Manage rooms", helpPrefix)
+	group.AddSubcommands(
+		NewRoomListCmd("list", group.HelpPrefix()),
+		NewRoomDeleteCmd("delete", group.HelpPrefix()),
+		NewRoomAddAdminCmd("add-admin", group.HelpPrefix()),
+	)
+
+	return group
+}
+
+type RoomListCmd struct {
+	Admin *AdminOpts
+
+	name       string
+	helpPrefix string
+}
+
+func NewRoomListCmd(name string, helpPrefix string) *RoomListCmd {
+	return &RoomListCmd{Admin: NewAdminOpts(), name: name, helpPrefix: helpPrefix}
+}
+
+func (r *RoomListCmd) Name() string {
+	return r.name
+}
+
+func (r *RoomListCmd) Description() string {
+	return "List rooms"
+}
+
+func (r *RoomListCmd) CommandPath() string {
+	return r.helpPrefix + r.Name()
+}
+
+func (r *RoomListCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(r.Name(), flag.ExitOnError)
+
+	r.Admin.AddFlags(fs)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(r.helpPrefix, r, fs))
+	}
+
+	return fs
+}
+
+func (r *RoomListCmd) Parse(args []string) error {
+	return cmd.ParseWithEnv(r, r.FlagSet(), args)
+}
+
+func (r *RoomListCmd) Run() error {
+	ctx := context.Background()
+
+	backend, err := r.Admin.Backend(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	rooms, err := backend.ListRooms(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tNAME\tMEMBERS\tADMINS")
+
+	for _, room := range rooms {
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%d\t%s\n", room.Id, room.Name, len(room.Users), strings.Join(room.Admins, ","))
+	}
+
+	return w.Flush()
+}
+
+type RoomDeleteCmd struct {
+	Id    int64
+	Admin *AdminOpts
+
+	name       string
+	helpPrefix string
+}
+
+func NewRoomDeleteCmd(name string, helpPrefix string) *RoomDeleteCmd {
+	return &RoomDeleteCmd{Admin: NewAdminOpts(), name: name, helpPrefix: helpPrefix}
+}
+
+func (r *RoomDeleteCmd) Name() string {
+	return r.name
+}
+
+func (r *RoomDeleteCmd) Description() string {
+	return "Delete a room regardless of its admins"
+}
+
+func (r *RoomDeleteCmd) CommandPath() string {
+	return r.helpPrefix + r.Name()
+}
+
+func (r *RoomDeleteCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(r.Name(), flag.ExitOnError)
+
+	fs.Int64Var(&r.Id, "i", r.Id, "Id of the room to delete.")
+	fs.Int64Var(&r.Id, "id", r.Id, cmd.LongFlagUsage("i"))
+	r.Admin.AddFlags(fs)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(r.helpPrefix, r, fs))
+	}
+
+	return fs
+}
+
+func (r *RoomDeleteCmd) Parse(args []string) error {
+	fs := r.FlagSet()
+
+	if err := cmd.ParseWithEnv(r, fs, args); err != nil {
+		return err
+	}
+
+	if r.Id == 0 {
+		return errors.New("--id is required")
+	}
+
+	return nil
+}
+
+func (r *RoomDeleteCmd) Run() error {
+	ctx := context.Background()
+
+	backend, err := r.Admin.Backend(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	return backend.DeleteRoom(ctx, r.Id)
+}
+
+type RoomAddAdminCmd struct {
+	Id       int64
+	Username string
+	Admin    *AdminOpts
+
+	name       string
+	helpPrefix string
+}
+
+func NewRoomAddAdminCmd(name string, helpPrefix string) *RoomAddAdminCmd {
+	return &RoomAddAdminCmd{Admin: NewAdminOpts(), name: name, helpPrefix: helpPrefix}
+}
+
+func (r *RoomAddAdminCmd) Name() string {
+	return r.name
+}
+
+func (r *RoomAddAdminCmd) Description() string {
+	return "Grant a user admin of a room"
+}
+
+func (r *RoomAddAdminCmd) CommandPath() string {
+	return r.helpPrefix + r.Name()
+}
+
+func (r *RoomAddAdminCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(r.Name(), flag.ExitOnError)
+
+	fs.Int64Var(&r.Id, "i", r.Id, "Id of the room.")
+	fs.Int64Var(&r.Id, "id", r.Id, cmd.LongFlagUsage("i"))
+	fs.StringVar(&r.Username, "u", r.Username, "Username to grant admin.")
+	fs.StringVar(&r.Username, "username", r.Username, cmd.LongFlagUsage("u"))
+	r.Admin.AddFlags(fs)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(r.helpPrefix, r, fs))
+	}
+
+	return fs
+}
+
+func (r *RoomAddAdminCmd) Parse(args []string) error {
+	fs := r.FlagSet()
+
+	if err := cmd.ParseWithEnv(r, fs, args); err != nil {
+		return err
+	}
+
+	if r.Id == 0 || r.Username == "" {
+		return errors.New("--id and --username are required")
+	}
+
+	return nil
+}
+
+func (r *RoomAddAdminCmd) Run() error {
+	ctx := context.Background()
+
+	backend, err := r.Admin.Backend(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	return backend.AddRoomAdmin(ctx, r.Id, r.Username)
+}
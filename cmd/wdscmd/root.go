@@ -40,31 +40,30 @@ func (r *RootCmd) Description() string {
 
 }
 
-// Parse parses the global flags of the program. The []string parameter is ignored.
-func (r *RootCmd) Parse([]string) error {
+// FlagSet returns the global flag set of the program.
+func (r *RootCmd) FlagSet() *flag.FlagSet {
 	flag.Usage = func() {
 		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString("", r, flag.CommandLine, r.subcommands...))
 	}
 
+	return flag.CommandLine
+}
+
+// Parse parses the global flags of the program. The []string parameter is ignored.
+func (r *RootCmd) Parse([]string) error {
+	r.FlagSet()
+
 	flag.Parse()
 
 	return nil
 }
 
 func (r *RootCmd) Run() error {
-	for _, subcommand := range r.subcommands {
-		if flag.Arg(0) == subcommand.Name() {
-			if err := subcommand.Parse(flag.Args()[1:]); err != nil {
-				return err
-			}
-
-			if err := subcommand.Run(); err != nil {
-				return err
-			}
-		}
-	}
+	return cmd.Dispatch("", r, flag.Args())
+}
 
-	return nil
+func (r *RootCmd) Subcommands() []cmd.Command {
+	return r.subcommands
 }
 
 func (r *RootCmd) AddSubcommands(subcommands ...cmd.Command) {
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/worsediscord/server/cmd"
+)
+
+func NewSearchCmd(name string, helpPrefix string) *cmd.Group {
+	if len(name) == 0 {
+		name = "search"
+	}
+
+	group := cmd.NewGroup(name, "Manage the message search index", helpPrefix)
+	group.AddSubcommands(
+		NewSearchRebuildCmd("rebuild", group.HelpPrefix()),
+	)
+
+	return group
+}
+
+type SearchRebuildCmd struct {
+	Storage *StorageOpts
+
+	name       string
+	helpPrefix string
+}
+
+func NewSearchRebuildCmd(name string, helpPrefix string) *SearchRebuildCmd {
+	return &SearchRebuildCmd{Storage: NewStorageOpts(), name: name, helpPrefix: helpPrefix}
+}
+
+func (s *SearchRebuildCmd) Name() string {
+	return s.name
+}
+
+func (s *SearchRebuildCmd) Description() string {
+	return "Rebuild the search index from every stored message"
+}
+
+func (s *SearchRebuildCmd) CommandPath() string {
+	return s.helpPrefix + s.Name()
+}
+
+func (s *SearchRebuildCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(s.Name(), flag.ExitOnError)
+
+	s.Storage.AddFlags(fs)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(s.helpPrefix, s, fs))
+	}
+
+	return fs
+}
+
+func (s *SearchRebuildCmd) Parse(args []string) error {
+	return cmd.ParseWithEnv(s, s.FlagSet(), args)
+}
+
+// Run rebuilds the index. Memory storage already builds its index whenever it's opened, so this only matters for
+// storage that keeps its index, like Postgres.
+func (s *SearchRebuildCmd) Run() (err error) {
+	if !s.Storage.Persistent() {
+		return fmt.Errorf("%w, there is nothing to index", ErrNoPersistentStorage)
+	}
+
+	services, err := s.Storage.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, services.Close())
+	}()
+
+	ctx := context.Background()
+
+	messages, err := services.Message.Export(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = services.Search.Rebuild(ctx, messages); err != nil {
+		return err
+	}
+
+	fmt.Printf("indexed %d messages\n", len(messages))
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/worsediscord/server/cmd"
+)
+
+func NewSessionCmd(name string, helpPrefix string) *cmd.Group {
+	if len(name) == 0 {
+		name = "session"
+	}
+
+	group := cmd.NewGroup(name, "Manage sessions", helpPrefix)
+	group.AddSubcommands(
+		NewSessionRevokeCmd("revoke", group.HelpPrefix()),
+	)
+
+	return group
+}
+
+type SessionRevokeCmd struct {
+	Id    string
+	Admin *AdminOpts
+
+	name       string
+	helpPrefix string
+}
+
+func NewSessionRevokeCmd(name string, helpPrefix string) *SessionRevokeCmd {
+	return &SessionRevokeCmd{Admin: NewAdminOpts(), name: name, helpPrefix: helpPrefix}
+}
+
+func (s *SessionRevokeCmd) Name() string {
+	return s.name
+}
+
+func (s *SessionRevokeCmd) Description() string {
+	return "Revoke a session"
+}
+
+func (s *SessionRevokeCmd) CommandPath() string {
+	return s.helpPrefix + s.Name()
+}
+
+func (s *SessionRevokeCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(s.Name(), flag.ExitOnError)
+
+	fs.StringVar(&s.Id, "i", s.Id, "Id of the session to revoke, as listed by GET /api/admin/sessions.")
+	fs.StringVar(&s.Id, "id", s.Id, cmd.LongFlagUsage("i"))
+	s.Admin.AddFlags(fs)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(s.helpPrefix, s, fs))
+	}
+
+	return fs
+}
+
+func (s *SessionRevokeCmd) Parse(args []string) error {
+	fs := s.FlagSet()
+
+	if err := cmd.ParseWithEnv(s, fs, args); err != nil {
+		return err
+	}
+
+	if s.Id == "" {
+		return errors.New("--id is required")
+	}
+
+	return nil
+}
+
+func (s *SessionRevokeCmd) Run() error {
+	ctx := context.Background()
+
+	backend, err := s.Admin.Backend(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	return backend.RevokeSession(ctx, s.Id)
+}
@@ -1,26 +1,50 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/cors"
 	"github.com/worsediscord/server/api"
 	"github.com/worsediscord/server/cmd"
-	"github.com/worsediscord/server/services/auth"
-	"github.com/worsediscord/server/services/message"
-	"github.com/worsediscord/server/services/room"
-	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/services/cascade"
 	"github.com/worsediscord/server/util"
 )
 
+const (
+	certPollInterval = 10 * time.Second
+	shutdownTimeout  = 10 * time.Second
+)
+
 type StartCmd struct {
 	Port string
 
+	TLSCert string
+	TLSKey  string
+	H2C     bool
+
+	AdminPort     string
+	AdminClientCA string
+
+	Admins cmd.StringSliceValue
+
+	Storage *StorageOpts
+
+	TrustedProxies  cmd.StringSliceValue
+	ClientIPHeaders cmd.StringSliceValue
+
+	DeletedUserMessages string
+
 	LogLevel    string
 	LogFormat   string
 	LogRequests bool
@@ -35,12 +59,14 @@ func NewStartCmd(name string, helpPrefix string) *StartCmd {
 	}
 
 	return &StartCmd{
-		Port:        "8069",
-		LogLevel:    "info",
-		LogFormat:   "text",
-		LogRequests: false,
-		name:        name,
-		helpPrefix:  helpPrefix,
+		Port:                "8069",
+		DeletedUserMessages: "anonymize",
+		LogLevel:            "info",
+		LogFormat:           "text",
+		LogRequests:         false,
+		Storage:             NewStorageOpts(),
+		name:                name,
+		helpPrefix:          helpPrefix,
 	}
 }
 
@@ -52,12 +78,32 @@ func (s *StartCmd) Description() string {
 	return "Start a worsediscord server"
 }
 
-func (s *StartCmd) Parse(args []string) error {
+func (s *StartCmd) CommandPath() string {
+	return s.helpPrefix + s.Name()
+}
+
+func (s *StartCmd) FlagSet() *flag.FlagSet {
 	fs := flag.NewFlagSet(s.Name(), flag.ExitOnError)
 
 	fs.StringVar(&s.Port, "p", s.Port, "TCP Port to listen on.")
 	fs.StringVar(&s.Port, "port", s.Port, cmd.LongFlagUsage("p"))
 
+	fs.StringVar(&s.TLSCert, "tls-cert", s.TLSCert, "Path to a PEM encoded TLS certificate. Enables HTTPS.")
+	fs.StringVar(&s.TLSKey, "tls-key", s.TLSKey, "Path to the PEM encoded private key for --tls-cert.")
+	fs.BoolVar(&s.H2C, "h2c", s.H2C, "Enable HTTP/2 over plaintext connections.")
+
+	fs.StringVar(&s.AdminPort, "admin-port", s.AdminPort, "TCP Port for the admin listener. Disabled if empty.")
+	fs.StringVar(&s.AdminClientCA, "admin-client-ca", s.AdminClientCA, "Path to a PEM encoded CA bundle. Requires client certificates on the admin listener.")
+
+	fs.Var(&s.Admins, "admin", "Username of a registered user to grant the server administrator role. May be repeated.")
+
+	s.Storage.AddFlags(fs)
+
+	fs.Var(&s.TrustedProxies, "trusted-proxy", "CIDR or IP of a proxy whose client IP and X-Forwarded-Proto headers are trusted. May be repeated.")
+	fs.Var(&s.ClientIPHeaders, "client-ip-header", "Header to resolve client IPs from, in order of preference. May be repeated.")
+
+	fs.StringVar(&s.DeletedUserMessages, "deleted-user-messages", s.DeletedUserMessages, "What happens to the messages of deleted users (anonymize | delete)")
+
 	fs.StringVar(&s.LogLevel, "log-level", s.LogLevel, "log level")
 	fs.StringVar(&s.LogFormat, "log-format", s.LogFormat, "log format (text | json | disabled)")
 	fs.BoolVar(&s.LogRequests, "log-requests", s.LogRequests, "Enable logging of requests")
@@ -66,21 +112,34 @@ func (s *StartCmd) Parse(args []string) error {
 		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(s.helpPrefix, s, fs))
 	}
 
-	return fs.Parse(args)
+	return fs
 }
 
-func (s *StartCmd) Run() error {
+func (s *StartCmd) Parse(args []string) error {
+	fs := s.FlagSet()
+
+	if err := cmd.ParseWithEnv(s, fs, args); err != nil {
+		return err
+	}
+
+	if (s.TLSCert == "") != (s.TLSKey == "") {
+		return errors.New("--tls-cert and --tls-key must be set together")
+	}
+
+	if s.AdminClientCA != "" && (s.TLSCert == "" || s.AdminPort == "") {
+		return errors.New("--admin-client-ca requires --tls-cert, --tls-key and --admin-port")
+	}
+
+	return nil
+}
+
+func (s *StartCmd) Run() (err error) {
 	var logHandler slog.Handler
 	var middleware []api.Middleware
 
-	userService := user.NewMap()
-	roomService := room.NewMap()
-	messageService := message.NewMap()
-	authService := auth.NewMap()
-
 	corsHandler := cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "x-api-key"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
@@ -88,6 +147,17 @@ func (s *StartCmd) Run() error {
 	})
 	middleware = append(middleware, corsHandler)
 
+	trustedProxies, err := api.ParseTrustedProxies(s.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("invalid trusted proxy: %w", err)
+	}
+	middleware = append(middleware, api.ClientIPMiddleware(trustedProxies, s.ClientIPHeaders))
+
+	deletedUserMessages, err := cascade.ParsePolicy(s.DeletedUserMessages)
+	if err != nil {
+		return fmt.Errorf("invalid --deleted-user-messages: %w", err)
+	}
+
 	switch strings.ToLower(s.LogFormat) {
 	case "json":
 		logHandler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: util.StringToLogLevel(s.LogLevel)})
@@ -105,7 +175,107 @@ func (s *StartCmd) Run() error {
 		}
 	}
 
-	server := api.NewServer(userService, roomService, messageService, authService, logHandler, middleware...)
+	s.Storage.LogHandler = logHandler
+
+	services, err := s.Storage.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, services.Close())
+	}()
+
+	server := api.NewServer(services.User, services.Room, services.Message, services.Auth, logHandler, middleware...)
+	server.AuditService = services.Audit
+	server.ReactionService = services.Reaction
+	server.PinService = services.Pin
+	server.InviteService = services.Invite
+	server.SearchService = services.Search
+	server.AdminListenerOnly = s.AdminPort != ""
+	server.DeletedUserMessages = deletedUserMessages
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err = server.BootstrapAdmins(ctx, s.Admins...); err != nil {
+		return err
+	}
+
+	var tlsConfig *tls.Config
+	if s.TLSCert != "" {
+		reloader, err := util.NewCertReloader(s.TLSCert, s.TLSKey)
+		if err != nil {
+			return err
+		}
+
+		go reloader.Watch(ctx, certPollInterval, logHandler)
+
+		tlsConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(s.H2C)
+
+	servers := []*http.Server{{
+		Addr:      ":" + s.Port,
+		Handler:   server,
+		TLSConfig: tlsConfig,
+		Protocols: protocols,
+	}}
+
+	if s.AdminPort != "" {
+		adminTLSConfig := tlsConfig
+		if s.AdminClientCA != "" {
+			pool, err := util.LoadCertPool(s.AdminClientCA)
+			if err != nil {
+				return err
+			}
+
+			adminTLSConfig = tlsConfig.Clone()
+			adminTLSConfig.ClientCAs = pool
+			adminTLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+
+		servers = append(servers, &http.Server{
+			Addr:      ":" + s.AdminPort,
+			Handler:   server.AdminHandler(),
+			TLSConfig: adminTLSConfig,
+			Protocols: protocols,
+		})
+	}
+
+	errs := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			var err error
+			if srv.TLSConfig != nil {
+				err = srv.ListenAndServeTLS("", "")
+			} else {
+				err = srv.ListenAndServe()
+			}
+
+			if !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	for _, srv := range servers {
+		err = errors.Join(err, srv.Shutdown(shutdownCtx))
+	}
 
-	return http.ListenAndServe(":"+s.Port, server)
+	return err
 }
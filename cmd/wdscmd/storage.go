@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/worsediscord/server/services/audit"
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/postgres"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/search"
+	"github.com/worsediscord/server/services/snapshot"
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/services/wal"
+)
+
+// StorageOpts configures the services backing a server. Every command that touches storage shares these flags so that
+// offline commands open exactly what the server would.
+type StorageOpts struct {
+	Backend      string
+	AuditFile    string
+	SnapshotFile string
+
+	// SnapshotSessions saves active sessions to SnapshotFile or WALDir, so users stay logged in across restarts.
+	SnapshotSessions bool
+
+	WALDir             string
+	WALSync            string
+	WALSyncInterval    time.Duration
+	WALCompactInterval time.Duration
+
+	PostgresDSN             string
+	PostgresMaxConns        int
+	PostgresMinConns        int
+	PostgresMaxConnLifetime time.Duration
+	PostgresMaxConnIdleTime time.Duration
+
+	// LogHandler receives errors from background work, such as compacting the write-ahead log. May be nil.
+	LogHandler slog.Handler
+}
+
+// Services is an opened set of services.
+type Services struct {
+	User     user.Service
+	Room     room.Service
+	Message  message.Service
+	Reaction reaction.Service
+	Pin      pin.Service
+	Invite   invite.Service
+	Auth     auth.Service
+	Audit    audit.Service
+
+	// Search is kept up to date by Message, which indexes every message stored through it.
+	Search search.Service
+
+	closers []io.Closer
+}
+
+func NewStorageOpts() *StorageOpts {
+	return &StorageOpts{
+		Backend:            "memory",
+		WALSync:            string(wal.SyncAlways),
+		WALSyncInterval:    time.Second,
+		WALCompactInterval: 10 * time.Minute,
+	}
+}
+
+func (o *StorageOpts) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Backend, "storage", o.Backend, "Storage backend (memory | postgres)")
+	fs.StringVar(&o.AuditFile, "audit-file", o.AuditFile, "Path to append the audit log to. Kept in memory, up to the last 10000 entries, if empty.")
+	fs.StringVar(&o.SnapshotFile, "snapshot-file", o.SnapshotFile, "Path to load memory storage from when opened and save it to on a clean shutdown. Changes since then are lost if the server crashes, use --wal-dir to keep them. Holds user passwords.")
+	fs.BoolVar(&o.SnapshotSessions, "snapshot-sessions", o.SnapshotSessions, "Save active sessions to --snapshot-file or --wal-dir so users stay logged in across restarts. Anyone with the files can use them.")
+	fs.StringVar(&o.WALDir, "wal-dir", o.WALDir, "Directory to keep a write-ahead log of memory storage in. Replayed when opened.")
+	fs.StringVar(&o.WALSync, "wal-fsync", o.WALSync, "When to sync the write-ahead log to disk (always | interval | never)")
+	fs.DurationVar(&o.WALSyncInterval, "wal-fsync-interval", o.WALSyncInterval, "How often to sync the write-ahead log with --wal-fsync interval.")
+	fs.DurationVar(&o.WALCompactInterval, "wal-compact-interval", o.WALCompactInterval, "How often to compact the write-ahead log into a snapshot. Only compacted on shutdown if 0.")
+	fs.StringVar(&o.PostgresDSN, "postgres-dsn", o.PostgresDSN, "Connection string of the database for --storage postgres.")
+	fs.IntVar(&o.PostgresMaxConns, "postgres-max-conns", o.PostgresMaxConns, "Maximum size of the connection pool. Uses the DSN or pgx default if 0.")
+	fs.IntVar(&o.PostgresMinConns, "postgres-min-conns", o.PostgresMinConns, "Minimum size of the connection pool.")
+	fs.DurationVar(&o.PostgresMaxConnLifetime, "postgres-max-conn-lifetime", o.PostgresMaxConnLifetime, "How long a connection is kept before being replaced. Uses the DSN or pgx default if 0.")
+	fs.DurationVar(&o.PostgresMaxConnIdleTime, "postgres-max-conn-idle-time", o.PostgresMaxConnIdleTime, "How long an idle connection is kept. Uses the DSN or pgx default if 0.")
+}
+
+// Persistent reports whether the configured backend outlives the process.
+func (o *StorageOpts) Persistent() bool {
+	switch strings.ToLower(o.Backend) {
+	case "memory":
+		return o.SnapshotFile != "" || o.WALDir != ""
+	case "postgres":
+		return true
+	default:
+		return false
+	}
+}
+
+// Open opens the configured services. The caller must Close them.
+func (o *StorageOpts) Open() (*Services, error) {
+	s := &Services{}
+
+	switch strings.ToLower(o.Backend) {
+	case "memory":
+		s.User = user.NewMap()
+		s.Room = room.NewMap()
+		s.Message = message.NewMap()
+		s.Reaction = reaction.NewMap()
+		s.Pin = pin.NewMap()
+		s.Invite = invite.NewMap()
+		s.Auth = auth.NewMap()
+
+		if o.SnapshotFile != "" && o.WALDir != "" {
+			return nil, errors.New("--snapshot-file and --wal-dir can't be used together")
+		}
+
+		if o.SnapshotFile != "" {
+			if err := s.load(o.SnapshotFile, o.SnapshotSessions); err != nil {
+				return nil, err
+			}
+
+			s.closers = append(s.closers, &snapshotCloser{name: o.SnapshotFile, sessions: o.SnapshotSessions, services: s})
+		}
+
+		if o.WALDir != "" {
+			if err := s.openWAL(o); err != nil {
+				return nil, err
+			}
+		}
+	case "postgres":
+		if err := s.openPostgres(o); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", o.Backend)
+	}
+
+	if err := s.openSearch(); err != nil {
+		return nil, errors.Join(err, s.Close())
+	}
+
+	if o.AuditFile != "" {
+		auditService, err := audit.NewFile(o.AuditFile)
+		if err != nil {
+			return nil, errors.Join(err, s.Close())
+		}
+
+		s.Audit = auditService
+		s.closers = append(s.closers, auditService)
+	} else {
+		s.Audit = audit.NewMap()
+	}
+
+	return s, nil
+}
+
+// Close releases any resources held by the services.
+func (s *Services) Close() error {
+	var err error
+
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		err = errors.Join(err, s.closers[i].Close())
+	}
+
+	return err
+}
+
+// Snapshot returns the services to export a snapshot from or import one into.
+func (s *Services) Snapshot(sessions bool) snapshot.Services {
+	services := snapshot.Services{User: s.User, Room: s.Room, Message: s.Message, Reaction: s.Reaction, Pin: s.Pin, Invite: s.Invite}
+	if sessions {
+		services.Auth = s.Auth
+	}
+
+	return services
+}
+
+// openPostgres connects to the database and brings its schema up to date.
+func (s *Services) openPostgres(o *StorageOpts) error {
+	if o.PostgresDSN == "" {
+		return errors.New("--postgres-dsn is required with --storage postgres")
+	}
+
+	ctx := context.Background()
+
+	db, err := postgres.Open(ctx, o.PostgresDSN, postgres.PoolOpts{
+		MaxConns:        int32(o.PostgresMaxConns),
+		MinConns:        int32(o.PostgresMinConns),
+		MaxConnLifetime: o.PostgresMaxConnLifetime,
+		MaxConnIdleTime: o.PostgresMaxConnIdleTime,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	if err = db.Migrate(ctx); err != nil {
+		return errors.Join(fmt.Errorf("failed to migrate postgres: %w", err), db.Close())
+	}
+
+	s.User, s.Room, s.Message, s.Reaction, s.Pin, s.Auth = db.Users(), db.Rooms(), db.Messages(), db.Reactions(), db.Pins(), db.Sessions()
+	s.Invite, s.Search = db.Invites(), db.Search()
+	s.closers = append(s.closers, db)
+
+	return nil
+}
+
+// openSearch indexes every message stored from now on. Memory storage doesn't keep an index, so one is built from the
+// messages it was opened with.
+func (s *Services) openSearch() error {
+	if s.Search == nil {
+		ctx := context.Background()
+
+		messages, err := s.Message.Export(ctx)
+		if err != nil {
+			return err
+		}
+
+		s.Search = search.NewMap()
+		if err = s.Search.Rebuild(ctx, messages); err != nil {
+			return fmt.Errorf("failed to build search index: %w", err)
+		}
+	}
+
+	s.Message = search.NewMessageService(s.Message, s.Search)
+
+	return nil
+}
+
+// openWAL restores the services from the write-ahead log and records every later change to it. Sessions are only
+// recorded with --snapshot-sessions.
+func (s *Services) openWAL(o *StorageOpts) error {
+	policy, err := wal.ParseSyncPolicy(o.WALSync)
+	if err != nil {
+		return err
+	}
+
+	opts := wal.Opts{Sync: policy, SyncInterval: o.WALSyncInterval, CompactInterval: o.WALCompactInterval}
+
+	inner := wal.Services{User: s.User, Room: s.Room, Message: s.Message, Reaction: s.Reaction, Pin: s.Pin, Invite: s.Invite}
+	if o.SnapshotSessions {
+		inner.Auth = s.Auth
+	}
+
+	l, err := wal.Open(o.WALDir, inner, opts, o.LogHandler)
+	if err != nil {
+		return fmt.Errorf("failed to open write-ahead log %s: %w", o.WALDir, err)
+	}
+
+	logged := l.Services()
+	s.User, s.Room, s.Message, s.Reaction, s.Pin = logged.User, logged.Room, logged.Message, logged.Reaction, logged.Pin
+	s.Invite = logged.Invite
+	if logged.Auth != nil {
+		s.Auth = logged.Auth
+	}
+	s.closers = append(s.closers, l)
+
+	return nil
+}
+
+// load imports the snapshot at name, if there is one. Sessions in the snapshot are only restored if sessions is set.
+func (s *Services) load(name string, sessions bool) error {
+	snap, err := snapshot.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to load snapshot %s: %w", name, err)
+	}
+
+	return snapshot.Import(context.Background(), s.Snapshot(sessions), snap)
+}
+
+// snapshotCloser saves the services to a snapshot when closed, so that memory storage survives restarts. Sessions are
+// only saved if sessions is set.
+type snapshotCloser struct {
+	name     string
+	sessions bool
+	services *Services
+}
+
+func (c *snapshotCloser) Close() error {
+	snap, err := snapshot.Export(context.Background(), c.services.Snapshot(c.sessions))
+	if err != nil {
+		return err
+	}
+
+	if err = snapshot.WriteFile(c.name, snap); err != nil {
+		return fmt.Errorf("failed to save snapshot %s: %w", c.name, err)
+	}
+
+	return nil
+}
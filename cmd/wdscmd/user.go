@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/worsediscord/server/cmd"
+)
+
+func NewUserCmd(name string, helpPrefix string) *cmd.Group {
+	if len(name) == 0 {
+		name = "user"
+	}
+
+	group := cmd.NewGroup(name, "Manage users", helpPrefix)
+	group.AddSubcommands(
+		NewUserCreateCmd("create", group.HelpPrefix()),
+		NewUserListCmd("list", group.HelpPrefix()),
+		NewUserDeleteCmd("delete", group.HelpPrefix()),
+		NewUserResetPasswordCmd("reset-password", group.HelpPrefix()),
+	)
+
+	return group
+}
+
+type UserCreateCmd struct {
+	Username    string
+	Password    string
+	ServerAdmin bool
+	Admin       *AdminOpts
+
+	name       string
+	helpPrefix string
+}
+
+func NewUserCreateCmd(name string, helpPrefix string) *UserCreateCmd {
+	return &UserCreateCmd{Admin: NewAdminOpts(), name: name, helpPrefix: helpPrefix}
+}
+
+func (u *UserCreateCmd) Name() string {
+	return u.name
+}
+
+func (u *UserCreateCmd) Description() string {
+	return "Create a user"
+}
+
+func (u *UserCreateCmd) CommandPath() string {
+	return u.helpPrefix + u.Name()
+}
+
+func (u *UserCreateCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(u.Name(), flag.ExitOnError)
+
+	fs.StringVar(&u.Username, "u", u.Username, "Username of the user to create.")
+	fs.StringVar(&u.Username, "username", u.Username, cmd.LongFlagUsage("u"))
+	fs.StringVar(&u.Password, "p", u.Password, "Password of the user to create.")
+	fs.StringVar(&u.Password, "password", u.Password, cmd.LongFlagUsage("p"))
+	fs.BoolVar(&u.ServerAdmin, "server-admin", u.ServerAdmin, "Grant the user the server administrator role.")
+	u.Admin.AddFlags(fs)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(u.helpPrefix, u, fs))
+	}
+
+	return fs
+}
+
+func (u *UserCreateCmd) Parse(args []string) error {
+	fs := u.FlagSet()
+
+	if err := cmd.ParseWithEnv(u, fs, args); err != nil {
+		return err
+	}
+
+	if u.Username == "" || u.Password == "" {
+		return errors.New("--username and --password are required")
+	}
+
+	return nil
+}
+
+func (u *UserCreateCmd) Run() error {
+	ctx := context.Background()
+
+	backend, err := u.Admin.Backend(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	if err = backend.CreateUser(ctx, u.Username, u.Password); err != nil {
+		return err
+	}
+
+	if u.ServerAdmin {
+		return backend.SetServerAdmin(ctx, u.Username, true)
+	}
+
+	return nil
+}
+
+type UserListCmd struct {
+	Admin *AdminOpts
+
+	name       string
+	helpPrefix string
+}
+
+func NewUserListCmd(name string, helpPrefix string) *UserListCmd {
+	return &UserListCmd{Admin: NewAdminOpts(), name: name, helpPrefix: helpPrefix}
+}
+
+func (u *UserListCmd) Name() string {
+	return u.name
+}
+
+func (u *UserListCmd) Description() string {
+	return "List users"
+}
+
+func (u *UserListCmd) CommandPath() string {
+	return u.helpPrefix + u.Name()
+}
+
+func (u *UserListCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(u.Name(), flag.ExitOnError)
+
+	u.Admin.AddFlags(fs)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(u.helpPrefix, u, fs))
+	}
+
+	return fs
+}
+
+func (u *UserListCmd) Parse(args []string) error {
+	return cmd.ParseWithEnv(u, u.FlagSet(), args)
+}
+
+func (u *UserListCmd) Run() error {
+	ctx := context.Background()
+
+	backend, err := u.Admin.Backend(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	users, err := backend.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "USERNAME\tNICKNAME\tADMIN\tDISABLED")
+
+	for _, user := range users {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%t\t%t\n", user.Username, user.Nickname, user.Admin, user.Disabled)
+	}
+
+	return w.Flush()
+}
+
+type UserDeleteCmd struct {
+	Username string
+	Admin    *AdminOpts
+
+	name       string
+	helpPrefix string
+}
+
+func NewUserDeleteCmd(name string, helpPrefix string) *UserDeleteCmd {
+	return &UserDeleteCmd{Admin: NewAdminOpts(), name: name, helpPrefix: helpPrefix}
+}
+
+func (u *UserDeleteCmd) Name() string {
+	return u.name
+}
+
+func (u *UserDeleteCmd) Description() string {
+	return "Delete a user and revoke their sessions"
+}
+
+func (u *UserDeleteCmd) CommandPath() string {
+	return u.helpPrefix + u.Name()
+}
+
+func (u *UserDeleteCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(u.Name(), flag.ExitOnError)
+
+	fs.StringVar(&u.Username, "u", u.Username, "Username of the user to delete.")
+	fs.StringVar(&u.Username, "username", u.Username, cmd.LongFlagUsage("u"))
+	u.Admin.AddFlags(fs)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(u.helpPrefix, u, fs))
+	}
+
+	return fs
+}
+
+func (u *UserDeleteCmd) Parse(args []string) error {
+	fs := u.FlagSet()
+
+	if err := cmd.ParseWithEnv(u, fs, args); err != nil {
+		return err
+	}
+
+	if u.Username == "" {
+		return errors.New("--username is required")
+	}
+
+	return nil
+}
+
+func (u *UserDeleteCmd) Run() error {
+	ctx := context.Background()
+
+	backend, err := u.Admin.Backend(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	return backend.DeleteUser(ctx, u.Username)
+}
+
+type UserResetPasswordCmd struct {
+	Username string
+	Password string
+	Admin    *AdminOpts
+
+	name       string
+	helpPrefix string
+}
+
+func NewUserResetPasswordCmd(name string, helpPrefix string) *UserResetPasswordCmd {
+	return &UserResetPasswordCmd{Admin: NewAdminOpts(), name: name, helpPrefix: helpPrefix}
+}
+
+func (u *UserResetPasswordCmd) Name() string {
+	return u.name
+}
+
+func (u *UserResetPasswordCmd) Description() string {
+	return "Set a new password for a user and revoke their sessions"
+}
+
+func (u *UserResetPasswordCmd) CommandPath() string {
+	return u.helpPrefix + u.Name()
+}
+
+func (u *UserResetPasswordCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(u.Name(), flag.ExitOnError)
+
+	fs.StringVar(&u.Username, "u", u.Username, "Username of the user to update.")
+	fs.StringVar(&u.Username, "username", u.Username, cmd.LongFlagUsage("u"))
+	fs.StringVar(&u.Password, "p", u.Password, "New password for the user.")
+	fs.StringVar(&u.Password, "password", u.Password, cmd.LongFlagUsage("p"))
+	u.Admin.AddFlags(fs)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), cmd.HelpString(u.helpPrefix, u, fs))
+	}
+
+	return fs
+}
+
+func (u *UserResetPasswordCmd) Parse(args []string) error {
+	fs := u.FlagSet()
+
+	if err := cmd.ParseWithEnv(u, fs, args); err != nil {
+		return err
+	}
+
+	if u.Username == "" || u.Password == "" {
+		return errors.New("--username and --password are required")
+	}
+
+	return nil
+}
+
+func (u *UserResetPasswordCmd) Run() error {
+	ctx := context.Background()
+
+	backend, err := u.Admin.Backend(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	return backend.ResetPassword(ctx, u.Username, u.Password)
+}
@@ -1,8 +1,19 @@
 module github.com/worsediscord/server
 
-go 1.22.1
+go 1.24
 
 require (
 	github.com/eolso/threadsafe v0.0.0-20240414010420-7b1dc37c440b
 	github.com/go-chi/cors v1.2.1
+	github.com/jackc/pgx/v5 v5.7.1
+	github.com/swaggo/files/v2 v2.0.2
+)
+
+require (
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	golang.org/x/crypto v0.27.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/text v0.18.0 // indirect
 )
@@ -0,0 +1,71 @@
+package servertest
+
+import (
+	"context"
+	"errors"
+
+	"github.com/worsediscord/server/api"
+	"github.com/worsediscord/server/client"
+)
+
+// Client is a client.Client with shorthands for the requests most tests make. The shorthands don't take a context
+// and shadow the methods of the same name, which are still there through Client.Client. Every error from the server
+// is a *client.StatusError.
+type Client struct {
+	*client.Client
+}
+
+// StatusCode returns the status code of err if it's a *client.StatusError, or 0 if it isn't.
+func StatusCode(err error) int {
+	var statusErr *client.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+
+	return 0
+}
+
+func (c *Client) RegisterUser(username, password string) error {
+	return c.Client.CreateUser(context.Background(), api.UserCreateRequest{Username: username, Password: password})
+}
+
+// Login logs in as username and uses the session for every request afterward.
+func (c *Client) Login(username, password string) error {
+	return c.Client.Login(context.Background(), username, password)
+}
+
+func (c *Client) ListUsers() ([]api.UserResponse, error) {
+	return c.Client.ListUsers(context.Background())
+}
+
+func (c *Client) GetUser(username string) (api.UserResponse, error) {
+	return c.Client.GetUser(context.Background(), username)
+}
+
+func (c *Client) DeleteUser(username string) error {
+	return c.Client.DeleteUser(context.Background(), username)
+}
+
+func (c *Client) CreateRoom(name string) (api.RoomResponse, error) {
+	return c.Client.CreateRoom(context.Background(), api.RoomCreateRequest{Name: name})
+}
+
+func (c *Client) ListRooms() ([]api.RoomResponse, error) {
+	return c.Client.ListRooms(context.Background())
+}
+
+func (c *Client) GetRoom(id int64) (api.RoomResponse, error) {
+	return c.Client.GetRoom(context.Background(), id)
+}
+
+func (c *Client) DeleteRoom(id int64) error {
+	return c.Client.DeleteRoom(context.Background(), id)
+}
+
+func (c *Client) PostMessage(roomId int64, content string) error {
+	return c.Client.CreateMessage(context.Background(), roomId, api.MessageCreateRequest{Content: content})
+}
+
+func (c *Client) ListMessages(roomId int64) ([]api.MessageResponse, error) {
+	return c.Client.ListMessages(context.Background(), roomId)
+}
@@ -0,0 +1,1282 @@
+package servertest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/api"
+	"github.com/worsediscord/server/client"
+	"github.com/worsediscord/server/servertest"
+	"github.com/worsediscord/server/services/cascade"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
+)
+
+// register creates username and returns a client logged in as them.
+func register(t *testing.T, s *servertest.Server, username, password string, opts client.Opts) *client.Client {
+	t.Helper()
+
+	ctx := context.Background()
+	c := s.Client(opts)
+
+	if err := c.CreateUser(ctx, api.UserCreateRequest{Username: username, Password: password}); err != nil {
+		t.Fatalf("failed to register %s: %v", username, err)
+	}
+
+	if err := c.Login(ctx, username, password); err != nil {
+		t.Fatalf("failed to log in as %s: %v", username, err)
+	}
+
+	return c
+}
+
+func TestScenario_UserLifecycle(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+
+	created, err := spiderman.CreateRoom(ctx, api.RoomCreateRequest{Name: "the big apple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = spiderman.CreateMessage(ctx, created.Id, api.MessageCreateRequest{Content: "pizza time"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := spiderman.ListMessages(ctx, created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 1 || messages[0].UserId != "spiderman" || messages[0].Content != "pizza time" {
+		t.Fatalf("got messages %v, expected spiderman's pizza time", messages)
+	}
+
+	if err = spiderman.DeleteUser(ctx, "spiderman"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The client can't log back in either, so the rejection isn't hidden by a refresh.
+	if _, err = spiderman.ListRooms(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v after deleting the user", err, client.ErrUnauthorized)
+	}
+
+	if err = spiderman.Login(ctx, "spiderman", "uncleben123"); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v logging in as a deleted user", err, client.ErrBadRequest)
+	}
+}
+
+func TestScenario_Shorthands(t *testing.T) {
+	s := servertest.New(t)
+	spiderman := s.NewClient()
+
+	if err := spiderman.RegisterUser("spiderman", "uncleben123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := spiderman.Login("spiderman", "uncleben123"); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := spiderman.CreateRoom("the big apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = spiderman.PostMessage(created.Id, "pizza time"); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := spiderman.ListMessages(created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 1 || messages[0].UserId != "spiderman" || messages[0].Content != "pizza time" {
+		t.Fatalf("got messages %v, expected spiderman's pizza time", messages)
+	}
+
+	if _, err = spiderman.GetRoom(created.Id + 1); servertest.StatusCode(err) != http.StatusNotFound {
+		t.Fatalf("got error %v, expected status %d getting a missing room", err, http.StatusNotFound)
+	}
+}
+
+func TestScenario_SessionExpiry(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{DisableRefresh: true})
+
+	if _, err := spiderman.GetUser(ctx, "spiderman"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Clock.Advance(time.Hour + time.Second)
+
+	if _, err := spiderman.GetUser(ctx, "spiderman"); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v with an expired session", err, client.ErrUnauthorized)
+	}
+
+	if err := spiderman.Login(ctx, "spiderman", "uncleben123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := spiderman.GetUser(ctx, "spiderman"); err != nil {
+		t.Fatalf("got error %v, expected nil after logging in again", err)
+	}
+}
+
+func TestScenario_SessionRefresh(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+	expired := spiderman.Token()
+
+	s.Clock.Advance(time.Hour + time.Second)
+
+	if _, err := spiderman.GetUser(ctx, "spiderman"); err != nil {
+		t.Fatalf("got error %v, expected the session to be refreshed", err)
+	}
+
+	if spiderman.Token() == expired {
+		t.Fatal("got the expired token, expected a new one")
+	}
+
+	// Being denied access doesn't log in again, since the session is still valid.
+	venom := register(t, s, "venom", "wearevenom", client.Opts{})
+
+	r, err := venom.CreateRoom(ctx, api.RoomCreateRequest{Name: "symbiote planet", Visibility: "private"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed := spiderman.Token()
+
+	if _, err = spiderman.ListMessages(ctx, r.Id); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v reading a private room", err, client.ErrUnauthorized)
+	}
+
+	if spiderman.Token() != refreshed {
+		t.Fatal("got a new token, expected the session to be kept after being denied")
+	}
+}
+
+func TestScenario_SharedRoom(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+	venom := register(t, s, "venom", "wearevenom", client.Opts{})
+
+	created, err := spiderman.CreateRoom(ctx, api.RoomCreateRequest{Name: "the big apple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = spiderman.CreateMessage(ctx, created.Id, api.MessageCreateRequest{Content: "pizza time"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = venom.CreateMessage(ctx, created.Id, api.MessageCreateRequest{Content: "we are venom"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := venom.ListMessages(ctx, created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authors := make(map[string]string)
+	for _, msg := range messages {
+		authors[msg.UserId] = msg.Content
+	}
+
+	expectedAuthors := map[string]string{"spiderman": "pizza time", "venom": "we are venom"}
+	if !reflect.DeepEqual(authors, expectedAuthors) {
+		t.Fatalf("got messages %v, expected %v", authors, expectedAuthors)
+	}
+
+	// Only room admins can delete a room.
+	if err = venom.DeleteRoom(ctx, created.Id); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v", err, client.ErrUnauthorized)
+	}
+
+	if err = spiderman.DeleteRoom(ctx, created.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = venom.GetRoom(ctx, created.Id); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v for a deleted room", err, client.ErrNotFound)
+	}
+
+	rooms, err := venom.ListRooms(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rooms) != 0 {
+		t.Fatalf("got rooms %v, expected none", rooms)
+	}
+}
+
+func TestScenario_Reactions(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+	venom := register(t, s, "venom", "wearevenom", client.Opts{})
+
+	created, err := spiderman.CreateRoom(ctx, api.RoomCreateRequest{Name: "the big apple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = spiderman.CreateMessage(ctx, created.Id, api.MessageCreateRequest{Content: "pizza time"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := venom.ListMessages(ctx, created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("got messages %v, expected 1", messages)
+	}
+
+	id := messages[0].Id
+
+	for _, c := range []*client.Client{venom, spiderman, venom} {
+		if err = c.AddReaction(ctx, created.Id, id, "🍕"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = venom.AddReaction(ctx, created.Id, id, "🕷️"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = venom.AddReaction(ctx, created.Id, id, "pizza time"); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v for an invalid emoji", err, client.ErrBadRequest)
+	}
+
+	messages, err = spiderman.ListMessages(ctx, created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedReactions := []api.ReactionCountResponse{{Emoji: "🍕", Count: 2, Reacted: true}, {Emoji: "🕷️", Count: 1, Reacted: false}}
+	if !reflect.DeepEqual(messages[0].Reactions, expectedReactions) {
+		t.Fatalf("got reactions %v, expected %v", messages[0].Reactions, expectedReactions)
+	}
+
+	reactions, err := spiderman.ListReactions(ctx, created.Id, id, "🍕")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both reactions are likely in the same millisecond, so their order isn't checked.
+	reacted := make(map[string]bool)
+	for _, r := range reactions {
+		reacted[r.UserId] = true
+	}
+
+	if expected := map[string]bool{"spiderman": true, "venom": true}; !reflect.DeepEqual(reacted, expected) {
+		t.Fatalf("got reactions %v, expected one from each of %v", reactions, expected)
+	}
+
+	if err = venom.RemoveReaction(ctx, created.Id, id, "🍕"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = venom.RemoveReaction(ctx, created.Id, id, "🍕"); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v removing a reaction twice", err, client.ErrNotFound)
+	}
+
+	// Only the author or a room admin can delete a message, and its reactions go with it.
+	if err = venom.DeleteMessage(ctx, created.Id, id); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v", err, client.ErrUnauthorized)
+	}
+
+	if err = spiderman.DeleteMessage(ctx, created.Id, id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = spiderman.ListReactions(ctx, created.Id, id, "🕷️"); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v for a deleted message", err, client.ErrNotFound)
+	}
+
+	if remaining, _ := s.Reactions.Export(ctx); len(remaining) != 0 {
+		t.Fatalf("got reactions %v, expected none after the message was deleted", remaining)
+	}
+}
+
+func TestScenario_Threads(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+
+	created, err := spiderman.CreateRoom(ctx, api.RoomCreateRequest{Name: "the big apple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = spiderman.CreateMessage(ctx, created.Id, api.MessageCreateRequest{Content: "pizza time"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := spiderman.ListMessages(ctx, created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := messages[0].Id
+
+	if err = spiderman.CreateMessage(ctx, created.Id, api.MessageCreateRequest{Content: "hello?", ReplyTo: "nobody"}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v replying to a missing message", err, client.ErrBadRequest)
+	}
+
+	// More replies than fit on one page, so the client has to follow the Link header.
+	const replies = 60
+	for i := range replies {
+		if err = spiderman.CreateMessage(ctx, created.Id, api.MessageCreateRequest{Content: fmt.Sprintf("reply %d", i), ReplyTo: root}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	thread, err := spiderman.ListThread(ctx, created.Id, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for _, reply := range thread {
+		if reply.ThreadId != root || reply.ReplyTo == nil || reply.ReplyTo.Id != root || reply.ReplyTo.Content != "pizza time" {
+			t.Fatalf("got reply %v, expected a reply to %s", reply, root)
+		}
+
+		seen[reply.Content] = true
+	}
+
+	if len(thread) != replies || len(seen) != replies {
+		t.Fatalf("got %d replies with %d distinct, expected %d", len(thread), len(seen), replies)
+	}
+
+	// Replying to a reply stays in the same thread.
+	if err = spiderman.CreateMessage(ctx, created.Id, api.MessageCreateRequest{Content: "nested", ReplyTo: thread[0].Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err = spiderman.ListMessages(ctx, created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, msg := range messages {
+		if msg.Id == root && msg.ReplyCount != replies+1 {
+			t.Fatalf("got reply count %d, expected %d", msg.ReplyCount, replies+1)
+		}
+
+		if msg.Content == "nested" && (msg.ThreadId != root || msg.ReplyTo.Id != thread[0].Id) {
+			t.Fatalf("got nested reply %v, expected it in thread %s replying to %s", msg, root, thread[0].Id)
+		}
+	}
+}
+
+func TestScenario_Pins(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+	venom := register(t, s, "venom", "wearevenom", client.Opts{})
+
+	created, err := spiderman.CreateRoom(ctx, api.RoomCreateRequest{Name: "the big apple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{"rules", "links", "faq"} {
+		if err = spiderman.CreateMessage(ctx, created.Id, api.MessageCreateRequest{Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	messages, err := spiderman.ListMessages(ctx, created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make(map[string]string)
+	for _, msg := range messages {
+		ids[msg.Content] = msg.Id
+	}
+
+	// Only admins can pin until they let someone else.
+	if err = venom.PinMessage(ctx, created.Id, ids["rules"]); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v", err, client.ErrUnauthorized)
+	}
+
+	if err = venom.SetPinSettings(ctx, created.Id, api.PinSettingsRequest{Pinners: []string{"venom"}}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v changing pin settings as a member", err, client.ErrUnauthorized)
+	}
+
+	if err = spiderman.SetPinSettings(ctx, created.Id, api.PinSettingsRequest{Pinners: []string{"venom"}, Limit: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := spiderman.GetRoom(ctx, created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got.Pinners, []string{"venom"}) || got.PinLimit != 2 {
+		t.Fatalf("got pinners %v and limit %d, expected [venom] and 2", got.Pinners, got.PinLimit)
+	}
+
+	if err = venom.PinMessage(ctx, created.Id, ids["rules"]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = spiderman.PinMessage(ctx, created.Id, ids["links"]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = spiderman.PinMessage(ctx, created.Id, ids["faq"]); !errors.Is(err, client.ErrConflict) {
+		t.Fatalf("got error %v, expected %v past the pin limit", err, client.ErrConflict)
+	}
+
+	pins, err := venom.ListPins(ctx, created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinnedBy := make(map[string]string)
+	for _, p := range pins {
+		pinnedBy[p.Message.Content] = p.PinnedBy
+	}
+
+	if expected := map[string]string{"rules": "venom", "links": "spiderman"}; !reflect.DeepEqual(pinnedBy, expected) {
+		t.Fatalf("got pins %v, expected %v", pinnedBy, expected)
+	}
+
+	// Deleting a pinned message unpins it, which frees up a spot.
+	if err = spiderman.DeleteMessage(ctx, created.Id, ids["rules"]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = spiderman.PinMessage(ctx, created.Id, ids["faq"]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = venom.UnpinMessage(ctx, created.Id, ids["links"]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = venom.UnpinMessage(ctx, created.Id, ids["links"]); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v unpinning twice", err, client.ErrNotFound)
+	}
+
+	if remaining, _ := s.Pins.Export(ctx); len(remaining) != 1 || remaining[0].MessageId != ids["faq"] {
+		t.Fatalf("got pins %v, expected only faq", remaining)
+	}
+}
+
+func TestScenario_DirectMessages(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+	venom := register(t, s, "venom", "wearevenom", client.Opts{})
+	mj := register(t, s, "mj", "tigerlily", client.Opts{})
+
+	dm, err := spiderman.DirectMessage(ctx, "venom", api.DirectMessageRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dm.Type != "direct" || !reflect.DeepEqual(dm.Users, []string{"spiderman", "venom"}) {
+		t.Fatalf("got room %v, expected a direct message room for spiderman and venom", dm)
+	}
+
+	// Either side gets the same room back.
+	other, err := venom.DirectMessage(ctx, "spiderman", api.DirectMessageRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other.Id != dm.Id {
+		t.Fatalf("got room %d, expected %d", other.Id, dm.Id)
+	}
+
+	group, err := mj.DirectMessage(ctx, "venom", api.DirectMessageRequest{Users: []string{"spiderman"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if group.Id == dm.Id || len(group.Users) != 3 {
+		t.Fatalf("got room %v, expected a new room for all three users", group)
+	}
+
+	if _, err = spiderman.DirectMessage(ctx, "carnage", api.DirectMessageRequest{}); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v", err, client.ErrNotFound)
+	}
+
+	if _, err = spiderman.DirectMessage(ctx, "spiderman", api.DirectMessageRequest{}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v messaging yourself", err, client.ErrBadRequest)
+	}
+
+	if err = spiderman.CreateMessage(ctx, dm.Id, api.MessageCreateRequest{Content: "we need to talk"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := venom.ListMessages(ctx, dm.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 1 || messages[0].Content != "we need to talk" {
+		t.Fatalf("got messages %v, expected the one spiderman sent", messages)
+	}
+
+	// Direct messages are private to their users and aren't listed with the other rooms.
+	if _, err = mj.ListMessages(ctx, dm.Id); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v", err, client.ErrUnauthorized)
+	}
+
+	if err = mj.CreateMessage(ctx, dm.Id, api.MessageCreateRequest{Content: "hi"}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v", err, client.ErrUnauthorized)
+	}
+
+	if _, err = mj.GetRoom(ctx, dm.Id); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v", err, client.ErrUnauthorized)
+	}
+
+	rooms, err := spiderman.ListRooms(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rooms) != 0 {
+		t.Fatalf("got rooms %v, expected direct messages to be hidden", rooms)
+	}
+}
+
+func TestScenario_PrivateRooms(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+	venom := register(t, s, "venom", "wearevenom", client.Opts{})
+	mj := register(t, s, "mj", "tigerlily", client.Opts{})
+
+	hideout, err := spiderman.CreateRoom(ctx, api.RoomCreateRequest{Name: "hideout", Visibility: "private"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hideout.Visibility != "private" {
+		t.Fatalf("got visibility %q, expected private", hideout.Visibility)
+	}
+
+	// Outsiders can't see, read or join a private room.
+	rooms, err := venom.ListRooms(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rooms) != 0 {
+		t.Fatalf("got rooms %v, expected the private room to be hidden", rooms)
+	}
+
+	if _, err = venom.GetRoom(ctx, hideout.Id); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v", err, client.ErrUnauthorized)
+	}
+
+	if _, err = venom.JoinRoom(ctx, hideout.Id); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v", err, client.ErrUnauthorized)
+	}
+
+	if _, err = venom.CreateInvite(ctx, hideout.Id, api.InviteCreateRequest{}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v creating an invite as an outsider", err, client.ErrUnauthorized)
+	}
+
+	once, err := spiderman.CreateInvite(ctx, hideout.Id, api.InviteCreateRequest{MaxUses: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	joined, err := mj.AcceptInvite(ctx, once.Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if joined.Id != hideout.Id {
+		t.Fatalf("got room %d, expected %d", joined.Id, hideout.Id)
+	}
+
+	if err = mj.CreateMessage(ctx, hideout.Id, api.MessageCreateRequest{Content: "nice place"}); err != nil {
+		t.Fatalf("got error %v, expected an invited member to be able to post", err)
+	}
+
+	// The invite only had one use, and accepting it again as a member doesn't spend anything.
+	if _, err = mj.AcceptInvite(ctx, once.Code); err != nil {
+		t.Fatalf("got error %v, expected nil accepting an invite as a member", err)
+	}
+
+	if _, err = venom.AcceptInvite(ctx, once.Code); !errors.Is(err, client.ErrGone) {
+		t.Fatalf("got error %v, expected %v with a used up invite", err, client.ErrGone)
+	}
+
+	expiring, err := spiderman.CreateInvite(ctx, hideout.Id, api.InviteCreateRequest{ExpiresIn: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Clock.Advance(time.Minute)
+
+	if _, err = venom.AcceptInvite(ctx, expiring.Code); !errors.Is(err, client.ErrGone) {
+		t.Fatalf("got error %v, expected %v with an expired invite", err, client.ErrGone)
+	}
+
+	revoked, err := spiderman.CreateInvite(ctx, hideout.Id, api.InviteCreateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invites, err := spiderman.ListInvites(ctx, hideout.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uses := map[string]int{}
+	for _, i := range invites {
+		uses[i.Code] = i.Uses
+	}
+
+	if expected := map[string]int{once.Code: 1, expiring.Code: 0, revoked.Code: 0}; !reflect.DeepEqual(uses, expected) {
+		t.Fatalf("got uses %v, expected %v", uses, expected)
+	}
+
+	if err = spiderman.RevokeInvite(ctx, hideout.Id, revoked.Code); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = venom.AcceptInvite(ctx, revoked.Code); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v with a revoked invite", err, client.ErrNotFound)
+	}
+
+	// Public rooms can be joined without an invite.
+	lobby, err := spiderman.CreateRoom(ctx, api.RoomCreateRequest{Name: "lobby"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lobby, err = venom.JoinRoom(ctx, lobby.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lobby.Visibility != "public" {
+		t.Fatalf("got visibility %q, expected public", lobby.Visibility)
+	}
+
+	rooms, err = venom.ListRooms(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rooms) != 1 || rooms[0].Id != lobby.Id {
+		t.Fatalf("got rooms %v, expected only the public room", rooms)
+	}
+}
+
+func TestScenario_RoomSettings(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+	venom := register(t, s, "venom", "wearevenom", client.Opts{})
+	mj := register(t, s, "mj", "tigerlily", client.Opts{})
+	jjj := register(t, s, "jjj", "dailybugle", client.Opts{})
+
+	if err := s.API.BootstrapAdmins(ctx, "jjj"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := spiderman.CreateRoom(ctx, api.RoomCreateRequest{Name: "the big apple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Owner != "spiderman" {
+		t.Fatalf("got owner %q, expected the creator", r.Owner)
+	}
+
+	for _, c := range []*client.Client{venom, mj} {
+		if _, err = c.JoinRoom(ctx, r.Id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	name := "queens"
+	topic := "friendly neighborhood"
+	updated, err := spiderman.UpdateRoom(ctx, r.Id, api.RoomUpdateRequest{Name: &name, Topic: &topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updated.Name != name || updated.Topic != topic {
+		t.Fatalf("got room %v, expected it renamed with a topic", updated)
+	}
+
+	empty := ""
+	if _, err = spiderman.UpdateRoom(ctx, r.Id, api.RoomUpdateRequest{Name: &empty}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v with an empty name", err, client.ErrBadRequest)
+	}
+
+	if _, err = venom.UpdateRoom(ctx, r.Id, api.RoomUpdateRequest{Topic: &empty}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v for a non-admin", err, client.ErrUnauthorized)
+	}
+
+	if err = spiderman.PromoteRoomAdmin(ctx, r.Id, api.RoomMemberRequest{Username: "jjj"}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v promoting a non-member", err, client.ErrBadRequest)
+	}
+
+	if err = spiderman.PromoteRoomAdmin(ctx, r.Id, api.RoomMemberRequest{Username: "venom"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = venom.PromoteRoomAdmin(ctx, r.Id, api.RoomMemberRequest{Username: "mj"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the owner may demote someone else, and nobody may demote the owner.
+	if err = venom.DemoteRoomAdmin(ctx, r.Id, "mj"); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v demoting as a non-owner", err, client.ErrUnauthorized)
+	}
+
+	if err = venom.DemoteRoomAdmin(ctx, r.Id, "spiderman"); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v demoting the owner", err, client.ErrBadRequest)
+	}
+
+	if err = spiderman.DemoteRoomAdmin(ctx, r.Id, "mj"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = mj.TransferRoom(ctx, r.Id, api.RoomMemberRequest{Username: "mj"}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v transferring as a non-owner", err, client.ErrUnauthorized)
+	}
+
+	if err = spiderman.TransferRoom(ctx, r.Id, api.RoomMemberRequest{Username: "venom"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := spiderman.GetRoom(ctx, r.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Owner != "venom" || !reflect.DeepEqual(got.Admins, []string{"venom", "spiderman"}) {
+		t.Fatalf("got owner %q and admins %v, expected venom to own the room with spiderman still an admin", got.Owner, got.Admins)
+	}
+
+	// The previous owner is an ordinary admin now, so they can step down.
+	if err = spiderman.DemoteRoomAdmin(ctx, r.Id, "spiderman"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = jjj.AdminTransferRoom(ctx, r.Id, api.RoomMemberRequest{Username: "jjj"}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v transferring to a non-member", err, client.ErrBadRequest)
+	}
+
+	// Server admins can hand any room to another member, such as when its owner leaves.
+	if err = jjj.AdminTransferRoom(ctx, r.Id, api.RoomMemberRequest{Username: "mj"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err = mj.GetRoom(ctx, r.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Owner != "mj" || !reflect.DeepEqual(got.Admins, []string{"mj", "venom"}) {
+		t.Fatalf("got owner %q and admins %v, expected mj to own the room with venom still an admin", got.Owner, got.Admins)
+	}
+}
+
+func TestScenario_Moderation(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+	venom := register(t, s, "venom", "wearevenom", client.Opts{})
+	carnage := register(t, s, "carnage", "cletuskasady", client.Opts{})
+	mj := register(t, s, "mj", "tigerlily", client.Opts{})
+
+	r, err := spiderman.CreateRoom(ctx, api.RoomCreateRequest{Name: "the big apple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*client.Client{venom, carnage, mj} {
+		if _, err = c.JoinRoom(ctx, r.Id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = venom.KickRoomMember(ctx, r.Id, "mj"); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v kicking as a member", err, client.ErrUnauthorized)
+	}
+
+	if err = spiderman.BanRoomMember(ctx, r.Id, "spiderman", api.RoomRestrictRequest{}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v banning the owner", err, client.ErrBadRequest)
+	}
+
+	// Muted users can still read the room, but not post until the mute runs out.
+	if err = spiderman.MuteRoomMember(ctx, r.Id, "carnage", api.RoomRestrictRequest{Reason: "too loud", ExpiresIn: 60}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = carnage.CreateMessage(ctx, r.Id, api.MessageCreateRequest{Content: "let there be carnage"}); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("got error %v, expected %v posting while muted", err, client.ErrForbidden)
+	}
+
+	if _, err = carnage.ListMessages(ctx, r.Id); err != nil {
+		t.Fatalf("got error %v reading while muted, expected none", err)
+	}
+
+	mutes, err := spiderman.ListMutes(ctx, r.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mutes) != 1 || mutes[0].Username != "carnage" || mutes[0].Reason != "too loud" || mutes[0].CreatedBy != "spiderman" {
+		t.Fatalf("got mutes %v, expected carnage muted by spiderman", mutes)
+	}
+
+	if _, err = venom.ListMutes(ctx, r.Id); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v listing mutes as a member", err, client.ErrUnauthorized)
+	}
+
+	s.Clock.Advance(time.Minute)
+
+	if err = carnage.CreateMessage(ctx, r.Id, api.MessageCreateRequest{Content: "let there be carnage"}); err != nil {
+		t.Fatalf("got error %v posting after the mute ran out, expected none", err)
+	}
+
+	// Banned users are removed from the room and kept out until the ban runs out.
+	if err = spiderman.BanRoomMember(ctx, r.Id, "venom", api.RoomRestrictRequest{Reason: "we are venom", ExpiresIn: 120}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Rooms.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got.Users, []string{"spiderman", "carnage", "mj"}) {
+		t.Fatalf("got users %v, expected venom removed", got.Users)
+	}
+
+	if _, err = venom.JoinRoom(ctx, r.Id); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("got error %v, expected %v joining while banned", err, client.ErrForbidden)
+	}
+
+	if err = venom.CreateMessage(ctx, r.Id, api.MessageCreateRequest{Content: "we are venom"}); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("got error %v, expected %v posting while banned", err, client.ErrForbidden)
+	}
+
+	bans, err := spiderman.ListBans(ctx, r.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiresAt := s.Clock.Now().Add(2 * time.Minute).UnixMilli()
+	if len(bans) != 1 || bans[0].Username != "venom" || bans[0].Reason != "we are venom" || bans[0].ExpiresAt != expiresAt {
+		t.Fatalf("got bans %v, expected venom banned until %d", bans, expiresAt)
+	}
+
+	s.Clock.Advance(2 * time.Minute)
+
+	if _, err = venom.JoinRoom(ctx, r.Id); err != nil {
+		t.Fatalf("got error %v joining after the ban ran out, expected none", err)
+	}
+
+	if bans, err = spiderman.ListBans(ctx, r.Id); err != nil || len(bans) != 0 {
+		t.Fatalf("got bans %v and error %v, expected the expired ban left out", bans, err)
+	}
+
+	// Bans without an expiry last until they are lifted.
+	if err = spiderman.BanRoomMember(ctx, r.Id, "venom", api.RoomRestrictRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = spiderman.UnbanRoomMember(ctx, r.Id, "venom"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = venom.JoinRoom(ctx, r.Id); err != nil {
+		t.Fatalf("got error %v joining after being unbanned, expected none", err)
+	}
+
+	// Kicked users may come straight back.
+	if err = spiderman.KickRoomMember(ctx, r.Id, "mj"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = mj.JoinRoom(ctx, r.Id); err != nil {
+		t.Fatalf("got error %v joining after being kicked, expected none", err)
+	}
+}
+
+func TestScenario_Search(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+	venom := register(t, s, "venom", "wearevenom", client.Opts{})
+	strange := register(t, s, "strange", "timestone14", client.Opts{})
+
+	apple, err := spiderman.CreateRoom(ctx, api.RoomCreateRequest{Name: "the big apple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sanctum, err := strange.CreateRoom(ctx, api.RoomCreateRequest{Name: "sanctum", Visibility: "private"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = venom.JoinRoom(ctx, apple.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []struct {
+		c       *client.Client
+		roomId  int64
+		content string
+	}{
+		{c: spiderman, roomId: apple.Id, content: "Pizza time!"},
+		{c: venom, roomId: apple.Id, content: "We are Venom. Time to eat."},
+		{c: strange, roomId: sanctum.Id, content: "Time and time again."},
+	} {
+		if err = m.c.CreateMessage(ctx, m.roomId, api.MessageCreateRequest{Content: m.content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	contents := func(c *client.Client, q string, filter client.SearchFilter) []string {
+		t.Helper()
+
+		results, err := c.SearchMessages(ctx, q, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found := make([]string, 0, len(results))
+		for _, r := range results {
+			found = append(found, r.Message.Content)
+		}
+
+		return found
+	}
+
+	// Outsiders only find messages in rooms they can read, and the best match comes first.
+	if got, expected := contents(spiderman, "time", client.SearchFilter{}), []string{"Pizza time!", "We are Venom. Time to eat."}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %v, expected %v", got, expected)
+	}
+
+	expected := []string{"Time and time again.", "Pizza time!", "We are Venom. Time to eat."}
+	if got := contents(strange, "time", client.SearchFilter{}); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %v, expected %v", got, expected)
+	}
+
+	if got, expected := contents(venom, `"time to" piz*`, client.SearchFilter{}), []string{}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %v, expected %v", got, expected)
+	}
+
+	if got, expected := contents(venom, `"we are" ven*`, client.SearchFilter{Author: "venom"}), []string{"We are Venom. Time to eat."}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %v, expected %v", got, expected)
+	}
+
+	if _, err = spiderman.SearchMessages(ctx, "time", client.SearchFilter{RoomId: sanctum.Id}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v searching a private room", err, client.ErrUnauthorized)
+	}
+
+	if _, err = spiderman.SearchMessages(ctx, "?!", client.SearchFilter{}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v searching without terms", err, client.ErrBadRequest)
+	}
+
+	// Deleted messages, and the messages of deleted rooms, are no longer found.
+	messages, err := spiderman.ListMessages(ctx, apple.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = spiderman.DeleteMessage(ctx, apple.Id, messages[0].Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, expected := contents(strange, "pizza", client.SearchFilter{}), []string{}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %v after deleting the message, expected %v", got, expected)
+	}
+
+	if err = spiderman.DeleteRoom(ctx, apple.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, expected := contents(strange, "time", client.SearchFilter{}), expected[:1]; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %v after deleting the room, expected %v", got, expected)
+	}
+}
+
+func TestScenario_DeleteCascade(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+	venom := register(t, s, "venom", "wearevenom", client.Opts{})
+	mj := register(t, s, "mj", "tigerlily", client.Opts{})
+
+	r, err := spiderman.CreateRoom(ctx, api.RoomCreateRequest{Name: "the big apple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*client.Client{venom, mj} {
+		if _, err = c.JoinRoom(ctx, r.Id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = spiderman.CreateMessage(ctx, r.Id, api.MessageCreateRequest{Content: "pizza time"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = venom.CreateMessage(ctx, r.Id, api.MessageCreateRequest{Content: "we are venom"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := spiderman.ListMessages(ctx, r.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = venom.AddReaction(ctx, r.Id, messages[0].Id, "🍕"); err != nil {
+		t.Fatal(err)
+	}
+
+	dm, err := venom.DirectMessage(ctx, "spiderman", api.DirectMessageRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = venom.CreateMessage(ctx, dm.Id, api.MessageCreateRequest{Content: "eyes up, tiger"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = venom.DeleteUser(ctx, "venom"); err != nil {
+		t.Fatal(err)
+	}
+
+	// venom leaves the room, but their message stays without an author.
+	got, err := s.Rooms.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got.Users, []string{"spiderman", "mj"}) {
+		t.Fatalf("got users %v, expected venom to be gone", got.Users)
+	}
+
+	messages, err = spiderman.ListMessages(ctx, r.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 2 || messages[1].Content != "we are venom" || messages[1].UserId != "" {
+		t.Fatalf("got messages %v, expected venom's message without an author", messages)
+	}
+
+	if len(messages[0].Reactions) != 0 {
+		t.Fatalf("got reactions %v, expected venom's reaction to be gone", messages[0].Reactions)
+	}
+
+	// spiderman keeps the direct messages, but venom is no longer in them, so someone registering the same name later
+	// can't read them.
+	gotDM, err := spiderman.GetRoom(ctx, dm.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(gotDM.Users, []string{"spiderman"}) {
+		t.Fatalf("got users %v, expected only spiderman to be left", gotDM.Users)
+	}
+
+	impostor := register(t, s, "venom", "notvenom123", client.Opts{})
+
+	if _, err = impostor.ListMessages(ctx, dm.Id); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v for the deleted user's direct messages", err, client.ErrUnauthorized)
+	}
+
+	reopened, err := impostor.DirectMessage(ctx, "spiderman", api.DirectMessageRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reopened.Id == dm.Id {
+		t.Fatalf("got room %d, expected a new direct message room", reopened.Id)
+	}
+
+	// Deleting messages instead removes them along with their reactions.
+	s.API.DeletedUserMessages = cascade.PolicyDelete
+
+	if err = mj.CreateMessage(ctx, r.Id, api.MessageCreateRequest{Content: "face it tiger"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = mj.DeleteUser(ctx, "mj"); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err = spiderman.ListMessages(ctx, r.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("got messages %v, expected mj's message to be deleted", messages)
+	}
+
+	if err = spiderman.DeleteRoom(ctx, r.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing in the room outlives it.
+	left, err := s.Messages.List(ctx, message.ListMessageOpts{RoomId: r.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(left) != 0 {
+		t.Fatalf("got messages %v, expected none left in the deleted room", left)
+	}
+}
+
+func TestScenario_Unauthenticated(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	c := s.Client(client.Opts{})
+
+	if _, err := c.ListRooms(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v", err, client.ErrUnauthorized)
+	}
+
+	c.SetToken("spiderman")
+	if _, err := c.ListRooms(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v with a made up token", err, client.ErrUnauthorized)
+	}
+
+	if err := c.Login(ctx, "spiderman", "uncleben123"); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v logging in as an unknown user", err, client.ErrBadRequest)
+	}
+}
+
+func TestScenario_Admin(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+
+	// Names that haven't been registered can't be bootstrapped, or whoever registered one first would become an admin.
+	if err := s.API.BootstrapAdmins(ctx, "spiderman"); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v bootstrapping an unregistered admin", err, user.ErrNotFound)
+	}
+
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+	venom := register(t, s, "venom", "wearevenom", client.Opts{})
+
+	if err := s.API.BootstrapAdmins(ctx, "spiderman"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := venom.AdminListUsers(ctx); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("got error %v, expected %v for a regular user", err, client.ErrForbidden)
+	}
+
+	users, err := spiderman.AdminListUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 2 {
+		t.Fatalf("got users %v, expected spiderman and venom", users)
+	}
+
+	disabled := true
+	if err = spiderman.AdminUpdateUser(ctx, "venom", api.AdminUserUpdateRequest{Disabled: &disabled}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = venom.ListRooms(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v for a disabled user", err, client.ErrUnauthorized)
+	}
+
+	entries, err := spiderman.AdminListAudit(ctx, client.AuditFilter{Actor: "spiderman"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) == 0 {
+		t.Fatal("got no audit entries, expected spiderman's actions to be recorded")
+	}
+}
+
+func TestScenario_Docs(t *testing.T) {
+	s := servertest.New(t)
+
+	raw, err := s.Client(client.Opts{}).OpenAPI(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spec struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+	}
+	if err = json.Unmarshal(raw, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spec.Servers) != 1 || spec.Servers[0].URL != s.URL+"/api" {
+		t.Fatalf("got servers %v, expected %s/api", spec.Servers, s.URL)
+	}
+}
+
+func TestScenario_Validation(t *testing.T) {
+	ctx := context.Background()
+	s := servertest.New(t)
+	c := s.Client(client.Opts{})
+
+	err := c.CreateUser(ctx, api.UserCreateRequest{Username: "spider man", Password: "mj"})
+	if !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v", err, client.ErrBadRequest)
+	}
+
+	var statusErr *client.StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("got error %#v, expected a StatusError", err)
+	}
+
+	expectedFields := []api.FieldError{
+		{In: "body", Field: "password", Message: "must be at least 8 characters long"},
+		{In: "body", Field: "username", Message: "must match ^[a-zA-Z0-9_.]+$"},
+	}
+	if !reflect.DeepEqual(statusErr.Fields, expectedFields) {
+		t.Fatalf("got fields %v, expected %v", statusErr.Fields, expectedFields)
+	}
+
+	spiderman := register(t, s, "spiderman", "uncleben123", client.Opts{})
+
+	if err = spiderman.CreateMessage(ctx, 1, api.MessageCreateRequest{}); !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("got error %v, expected %v for an empty message", err, client.ErrBadRequest)
+	}
+}
@@ -0,0 +1,94 @@
+// Package servertest runs a complete server for end-to-end tests. Requests go through the same handlers and middleware
+// as they do in production, with in-memory services behind them.
+package servertest
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/api"
+	"github.com/worsediscord/server/client"
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/search"
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/util"
+)
+
+type Server struct {
+	// URL is the base URL of the server, e.g. http://127.0.0.1:1234.
+	URL string
+
+	// API is the server being tested. It can be configured further, e.g. with BootstrapAdmins, before making requests.
+	API *api.Server
+
+	// Clock controls when sessions expire. It starts at the current time. The session store also expires keys by the
+	// system clock, so it should only ever be moved forward.
+	Clock *Clock
+
+	Users     *user.Map
+	Rooms     *room.Map
+	Messages  *message.Map
+	Reactions *reaction.Map
+	Pins      *pin.Map
+	Sessions  *auth.Map
+
+	// Index holds every message created through the API, which indexes them as it stores them in Messages.
+	Index *search.Map
+
+	server *httptest.Server
+}
+
+// New starts a server that is closed once t and its subtests have finished.
+func New(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		Clock:     NewClock(time.Now()),
+		Users:     user.NewMap(),
+		Rooms:     room.NewMap(),
+		Messages:  message.NewMap(),
+		Reactions: reaction.NewMap(),
+		Pins:      pin.NewMap(),
+		Sessions:  auth.NewMap(),
+		Index:     search.NewMap(),
+	}
+
+	messages := search.NewMessageService(s.Messages, s.Index)
+
+	s.API = api.NewServer(s.Users, s.Rooms, messages, s.Sessions, util.NopLogHandler, api.ClientIPMiddleware(nil, nil))
+	s.API.Clock = s.Clock
+	s.API.ReactionService = s.Reactions
+	s.API.PinService = s.Pins
+	s.API.SearchService = s.Index
+
+	s.server = httptest.NewServer(s.API)
+	s.URL = s.server.URL
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+// Client returns a new, logged out client for the server. opts.HTTPClient defaults to one that trusts the server.
+func (s *Server) Client(opts client.Opts) *client.Client {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = s.server.Client()
+	}
+
+	c, err := client.New(s.URL, opts)
+	if err != nil {
+		// The URL comes from httptest, so this can't happen.
+		panic(err)
+	}
+
+	return c
+}
+
+// NewClient returns a new, logged out Client for the server, with the shorthands for common requests.
+func (s *Server) NewClient() *Client {
+	return &Client{Client: s.Client(client.Opts{})}
+}
@@ -0,0 +1,34 @@
+package audit
+
+// Actions recorded by the server.
+const (
+	ActionLogin         = "user.login"
+	ActionLoginFailed   = "user.login_failed"
+	ActionUserCreate    = "user.create"
+	ActionUserDelete    = "user.delete"
+	ActionUserPromote   = "user.promote"
+	ActionUserDemote    = "user.demote"
+	ActionUserDisable   = "user.disable"
+	ActionUserEnable    = "user.enable"
+	ActionPasswordReset = "user.password_reset"
+	ActionSessionRevoke = "session.revoke"
+	ActionRoomCreate    = "room.create"
+	ActionRoomDelete    = "room.delete"
+	ActionRoomUpdate    = "room.update"
+	ActionRoomPromote   = "room.promote"
+	ActionRoomDemote    = "room.demote"
+	ActionRoomTransfer  = "room.transfer"
+	ActionRoomPins      = "room.pins"
+	ActionRoomJoin      = "room.join"
+	ActionRoomKick      = "room.kick"
+	ActionRoomBan       = "room.ban"
+	ActionRoomUnban     = "room.unban"
+	ActionRoomMute      = "room.mute"
+	ActionRoomUnmute    = "room.unmute"
+	ActionInviteCreate  = "invite.create"
+	ActionInviteRevoke  = "invite.revoke"
+	ActionInviteAccept  = "invite.accept"
+	ActionMessageDelete = "message.delete"
+	ActionMessagePin    = "message.pin"
+	ActionMessageUnpin  = "message.unpin"
+)
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+type Entry struct {
+	Sequence  int64             `json:"sequence"`
+	Timestamp int64             `json:"timestamp"`
+	Actor     string            `json:"actor"`
+	Action    string            `json:"action"`
+	Target    string            `json:"target,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash"`
+}
+
+// ComputeHash returns the hash of every field of the entry except Hash itself. Because PrevHash is included, each
+// entry's hash also covers every entry before it.
+func (e Entry) ComputeHash() string {
+	e.Hash = ""
+
+	// json.Marshal sorts map keys, so the encoding is stable.
+	b, _ := json.Marshal(e)
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks that entries form an unbroken hash chain starting from the first entry.
+func Verify(entries []*Entry) error {
+	var prev *Entry
+
+	for _, e := range entries {
+		if err := follows(prev, e); err != nil {
+			return err
+		}
+
+		prev = e
+	}
+
+	return nil
+}
+
+// follows checks that e is the entry after prev in the hash chain. prev may be nil for the first entry.
+func follows(prev *Entry, e *Entry) error {
+	var sequence int64
+	var prevHash string
+
+	if prev != nil {
+		sequence = prev.Sequence + 1
+		prevHash = prev.Hash
+	}
+
+	if e.Sequence != sequence {
+		return &VerifyError{Sequence: sequence, Err: ErrSequenceGap}
+	}
+
+	if e.PrevHash != prevHash {
+		return &VerifyError{Sequence: e.Sequence, Err: ErrChainBroken}
+	}
+
+	if e.ComputeHash() != e.Hash {
+		return &VerifyError{Sequence: e.Sequence, Err: ErrHashMismatch}
+	}
+
+	return nil
+}
+
+// next builds the entry that follows prev from opts. prev may be nil for the first entry.
+func next(prev *Entry, opts AppendOpts, timestamp int64) *Entry {
+	e := &Entry{
+		Timestamp: timestamp,
+		Actor:     opts.Actor,
+		Action:    opts.Action,
+		Target:    opts.Target,
+		Metadata:  opts.Metadata,
+	}
+
+	if prev != nil {
+		e.Sequence = prev.Sequence + 1
+		e.PrevHash = prev.Hash
+	}
+
+	e.Hash = e.ComputeHash()
+
+	return e
+}
+
+// filter returns the entries matching opts.
+func filter(entries []*Entry, opts ListEntryOpts) []*Entry {
+	matched := make([]*Entry, 0)
+
+	for _, e := range entries {
+		if matches(e, opts) {
+			matched = append(matched, e)
+		}
+	}
+
+	return matched
+}
+
+// matches reports whether e matches opts.
+func matches(e *Entry, opts ListEntryOpts) bool {
+	if opts.Actor != "" && e.Actor != opts.Actor {
+		return false
+	}
+
+	if opts.Action != "" && e.Action != opts.Action {
+		return false
+	}
+
+	return e.Timestamp >= opts.Since
+}
@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrSequenceGap  = errors.New("entry is out of sequence")
+	ErrChainBroken  = errors.New("entry does not reference the previous entry's hash")
+	ErrHashMismatch = errors.New("entry hash does not match its contents")
+)
+
+// VerifyError reports the first entry that failed verification.
+type VerifyError struct {
+	Sequence int64
+	Err      error
+}
+
+func (v *VerifyError) Error() string {
+	return fmt.Sprintf("entry %d: %s", v.Sequence, v.Err)
+}
+
+func (v *VerifyError) Unwrap() error {
+	return v.Err
+}
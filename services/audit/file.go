@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// File is a Service that appends entries to a file as JSON lines. Every entry is synced to disk before Append returns.
+// Only the last entry is kept in memory, List reads the file.
+type File struct {
+	name string
+	file *os.File
+	last *Entry
+	lock sync.RWMutex
+}
+
+// NewFile opens or creates the audit log at name. Existing entries are verified first, and an error is returned if they
+// do not form an unbroken hash chain so that nothing is appended to a tampered log.
+func NewFile(name string) (*File, error) {
+	var last *Entry
+
+	err := scanFile(name, func(e *Entry) error {
+		if err := follows(last, e); err != nil {
+			return err
+		}
+
+		last = e
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("audit log %s failed verification: %w", name, err)
+	}
+
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{name: name, file: f, last: last}, nil
+}
+
+// ReadFile reads every entry from the audit log at name.
+func ReadFile(name string) ([]*Entry, error) {
+	entries := make([]*Entry, 0)
+
+	err := scanFile(name, func(e *Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// scanFile calls fn with each entry of the audit log at name in order, stopping at the first error.
+func scanFile(name string, fn func(*Entry) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		var e Entry
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if err = fn(&e); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (f *File) Append(_ context.Context, opts AppendOpts) (*Entry, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	e := next(f.last, opts, time.Now().UnixMilli())
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = f.file.Write(append(b, '\n')); err != nil {
+		return nil, err
+	}
+
+	if err = f.file.Sync(); err != nil {
+		return nil, err
+	}
+
+	f.last = e
+
+	return e, nil
+}
+
+func (f *File) List(_ context.Context, opts ListEntryOpts) ([]*Entry, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	matched := make([]*Entry, 0)
+
+	err := scanFile(f.name, func(e *Entry) error {
+		if matches(e, opts) {
+			matched = append(matched, e)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return matched, nil
+}
+
+// Close closes the underlying file.
+func (f *File) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFile_Append(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.log")
+
+	f, err := NewFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = f.Append(nil, AppendOpts{Actor: "spiderman", Action: ActionLogin}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening should continue the existing chain
+	f, err = NewFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err = f.Append(nil, AppendOpts{Actor: "spiderman", Action: ActionRoomCreate}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, expected 2", len(entries))
+	}
+
+	if err = Verify(entries); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFile_List(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.log")
+
+	f, err := NewFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, opts := range []AppendOpts{
+		{Actor: "spiderman", Action: ActionLogin},
+		{Actor: "venom", Action: ActionLoginFailed},
+		{Actor: "spiderman", Action: ActionRoomCreate},
+	} {
+		if _, err = f.Append(nil, opts); err != nil {
+			t.Fatalf("failed to prepopulate file: %v", err)
+		}
+	}
+
+	tests := map[string]struct {
+		opts          ListEntryOpts
+		expectedCount int
+	}{
+		"all": {
+			opts:          ListEntryOpts{},
+			expectedCount: 3,
+		},
+		"actor": {
+			opts:          ListEntryOpts{Actor: "spiderman"},
+			expectedCount: 2,
+		},
+		"action": {
+			opts:          ListEntryOpts{Action: ActionLoginFailed},
+			expectedCount: 1,
+		},
+		"future": {
+			opts:          ListEntryOpts{Since: 1 << 62},
+			expectedCount: 0,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			entries, err := f.List(nil, input.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(entries) != input.expectedCount {
+				t.Fatalf("got %d entries, expected %d", len(entries), input.expectedCount)
+			}
+		})
+	}
+}
+
+func TestNewFile_Tampered(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.log")
+
+	f, err := NewFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []AppendOpts{
+		{Actor: "spiderman", Action: ActionLogin},
+		{Actor: "spiderman", Action: ActionRoomCreate},
+	} {
+		if _, err = f.Append(nil, opts); err != nil {
+			t.Fatalf("failed to prepopulate file: %v", err)
+		}
+	}
+
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(name, bytes.Replace(b, []byte(`"spiderman"`), []byte(`"venom"`), 1), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewFile(name); !errors.Is(err, ErrHashMismatch) {
+		t.Fatalf("got error %v, expected %v", err, ErrHashMismatch)
+	}
+
+	// Nothing may have been appended to the tampered log
+	entries, err := ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, expected 2", len(entries))
+	}
+}
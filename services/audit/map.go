@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MapRetention is how many entries a Map keeps. Older entries are dropped, so use a File to keep all of them.
+const MapRetention = 10_000
+
+type Map struct {
+	entries   []*Entry
+	retention int
+	lock      sync.RWMutex
+}
+
+func NewMap() *Map {
+	return &Map{retention: MapRetention}
+}
+
+func (m *Map) Append(_ context.Context, opts AppendOpts) (*Entry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var prev *Entry
+	if len(m.entries) > 0 {
+		prev = m.entries[len(m.entries)-1]
+	}
+
+	e := next(prev, opts, time.Now().UnixMilli())
+	m.entries = append(m.entries, e)
+	if len(m.entries) > m.retention {
+		// The dropped entries are released once append grows the backing array.
+		m.entries = m.entries[len(m.entries)-m.retention:]
+	}
+
+	return e, nil
+}
+
+func (m *Map) List(_ context.Context, opts ListEntryOpts) ([]*Entry, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return filter(m.entries, opts), nil
+}
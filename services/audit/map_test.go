@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewMap(t *testing.T) {
+	if NewMap() == nil {
+		t.Fatal("constructor returned nil")
+	}
+}
+
+func TestMap_Append(t *testing.T) {
+	m := NewMap()
+
+	first, err := m.Append(nil, AppendOpts{Actor: "spiderman", Action: ActionLogin})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := m.Append(nil, AppendOpts{Actor: "spiderman", Action: ActionRoomCreate, Target: "100000000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Sequence != 0 || second.Sequence != 1 {
+		t.Fatalf("got sequences %d and %d, expected 0 and 1", first.Sequence, second.Sequence)
+	}
+
+	if second.PrevHash != first.Hash {
+		t.Fatalf("got prev hash %q, expected %q", second.PrevHash, first.Hash)
+	}
+}
+
+func TestMap_Retention(t *testing.T) {
+	m := NewMap()
+	m.retention = 2
+
+	for _, action := range []string{ActionLogin, ActionRoomCreate, ActionRoomDelete} {
+		if _, err := m.Append(nil, AppendOpts{Actor: "spiderman", Action: action}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := m.List(nil, ListEntryOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 || entries[0].Sequence != 1 || entries[1].Sequence != 2 {
+		t.Fatalf("got entries %v, expected sequences 1 and 2", entries)
+	}
+
+	// The chain carries on past the dropped entries.
+	if entries[1].PrevHash != entries[0].Hash {
+		t.Fatalf("got prev hash %q, expected %q", entries[1].PrevHash, entries[0].Hash)
+	}
+}
+
+func TestMap_List(t *testing.T) {
+	m := NewMap()
+
+	for _, opts := range []AppendOpts{
+		{Actor: "spiderman", Action: ActionLogin},
+		{Actor: "venom", Action: ActionLoginFailed},
+		{Actor: "spiderman", Action: ActionRoomCreate},
+	} {
+		if _, err := m.Append(nil, opts); err != nil {
+			t.Fatalf("failed to prepopulate map: %v", err)
+		}
+	}
+
+	tests := map[string]struct {
+		opts          ListEntryOpts
+		expectedCount int
+	}{
+		"all": {
+			opts:          ListEntryOpts{},
+			expectedCount: 3,
+		},
+		"actor": {
+			opts:          ListEntryOpts{Actor: "spiderman"},
+			expectedCount: 2,
+		},
+		"action": {
+			opts:          ListEntryOpts{Action: ActionLoginFailed},
+			expectedCount: 1,
+		},
+		"future": {
+			opts:          ListEntryOpts{Since: 1 << 62},
+			expectedCount: 0,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			entries, err := m.List(nil, input.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(entries) != input.expectedCount {
+				t.Fatalf("got %d entries, expected %d", len(entries), input.expectedCount)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	build := func() []*Entry {
+		m := NewMap()
+		for _, action := range []string{ActionLogin, ActionRoomCreate, ActionRoomDelete} {
+			if _, err := m.Append(nil, AppendOpts{Actor: "spiderman", Action: action}); err != nil {
+				t.Fatalf("failed to prepopulate map: %v", err)
+			}
+		}
+
+		entries, _ := m.List(nil, ListEntryOpts{})
+		return entries
+	}
+
+	tests := map[string]struct {
+		tamper      func([]*Entry) []*Entry
+		expectedErr error
+	}{
+		"valid": {
+			tamper:      func(e []*Entry) []*Entry { return e },
+			expectedErr: nil,
+		},
+		"modified": {
+			tamper:      func(e []*Entry) []*Entry { e[1].Actor = "venom"; return e },
+			expectedErr: ErrHashMismatch,
+		},
+		"rehashed": {
+			tamper: func(e []*Entry) []*Entry {
+				e[1].Actor = "venom"
+				e[1].Hash = e[1].ComputeHash()
+				return e
+			},
+			expectedErr: ErrChainBroken,
+		},
+		"removed": {
+			tamper:      func(e []*Entry) []*Entry { return append(e[:1], e[2:]...) },
+			expectedErr: ErrSequenceGap,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := Verify(input.tamper(build())); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %q, expected %q", err, input.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package audit
+
+type AppendOpts struct {
+	Actor    string
+	Action   string
+	Target   string
+	Metadata map[string]string
+}
+
+type ListEntryOpts struct {
+	Actor  string
+	Action string
+
+	// Only entries at or after this time since epoch in milliseconds are listed.
+	Since int64
+}
@@ -0,0 +1,9 @@
+package audit
+
+import "context"
+
+// Service is an append-only store of audit entries.
+type Service interface {
+	Append(context.Context, AppendOpts) (*Entry, error)
+	List(context.Context, ListEntryOpts) ([]*Entry, error)
+}
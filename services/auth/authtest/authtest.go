@@ -0,0 +1,237 @@
+// Package authtest checks that an auth.Service behaves the same as auth.Map.
+package authtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/auth"
+)
+
+// Run runs the conformance suite against the services returned by newService. Every subtest calls newService once with
+// its own *testing.T, which newService should fail if it can't return an empty service. Keys are issued with usernames as their payload, the same as the server.
+func Run(t *testing.T, newService func(*testing.T) auth.Service) {
+	t.Helper()
+
+	tests := map[string]func(*testing.T, auth.Service){
+		"RetrieveKey":        testRetrieveKey,
+		"RevokeKey":          testRevokeKey,
+		"ListKeys":           testListKeys,
+		"ExportImport":       testExportImport,
+		"ConcurrentRegister": testConcurrentRegister,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newService(t))
+		})
+	}
+}
+
+func testRetrieveKey(t *testing.T, s auth.Service) {
+	valid := auth.NewApiKey(32, time.Minute, "spiderman")
+	expired := auth.RestoreApiKey("expired", time.Now().Add(-time.Minute), "venom")
+
+	for _, key := range []auth.ApiKey{valid, expired} {
+		if err := s.RegisterKey(key.Token(), key); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	tests := map[string]struct {
+		token           string
+		expectedPayload any
+		expectedErr     error
+	}{
+		"valid": {
+			token:           valid.Token(),
+			expectedPayload: "spiderman",
+			expectedErr:     nil,
+		},
+		"expired": {
+			token:           expired.Token(),
+			expectedPayload: nil,
+			expectedErr:     auth.ErrNotFound,
+		},
+		"not found": {
+			token:           "missing",
+			expectedPayload: nil,
+			expectedErr:     auth.ErrNotFound,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			key, err := s.RetrieveKey(input.token)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if key.Payload() != input.expectedPayload {
+				t.Fatalf("got payload %v, expected %v", key.Payload(), input.expectedPayload)
+			}
+		})
+	}
+}
+
+func testRevokeKey(t *testing.T, s auth.Service) {
+	key := auth.NewApiKey(32, time.Minute, "spiderman")
+	if err := s.RegisterKey(key.Token(), key); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := map[string]struct {
+		token       string
+		expectedErr error
+	}{
+		"valid": {
+			token:       key.Token(),
+			expectedErr: nil,
+		},
+		"missing key": {
+			token:       "missing",
+			expectedErr: nil,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := s.RevokeKey(input.token); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if _, err := s.RetrieveKey(input.token); !errors.Is(err, auth.ErrNotFound) {
+				t.Fatalf("got error %v, expected %v after revoke", err, auth.ErrNotFound)
+			}
+		})
+	}
+}
+
+func testListKeys(t *testing.T, s auth.Service) {
+	keys, err := s.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys == nil || len(keys) != 0 {
+		t.Fatalf("got %v, expected an empty, non-nil slice", keys)
+	}
+
+	registered := []auth.ApiKey{
+		auth.NewApiKey(32, time.Minute, "spiderman"),
+		auth.NewApiKey(32, time.Hour, "venom"),
+		auth.RestoreApiKey("expired", time.Now().Add(-time.Minute), "carnage"),
+	}
+
+	for _, key := range registered {
+		if err = s.RegisterKey(key.Token(), key); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	keys, err = s.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{registered[0].Id(), registered[1].Id()}
+	slices.Sort(expected)
+
+	if got := ids(keys); !slices.Equal(got, expected) {
+		t.Fatalf("got %v, expected %v", got, expected)
+	}
+}
+
+func testExportImport(t *testing.T, s auth.Service) {
+	ctx := context.Background()
+
+	// Stores are free to truncate expiry times, so they're compared to the second.
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	keys := []auth.ApiKey{
+		auth.RestoreApiKey("spiderman-token", expiresAt, "spiderman"),
+		auth.RestoreApiKey("expired-token", time.Now().Add(-time.Minute), "venom"),
+	}
+
+	if err := s.Import(ctx, keys); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := s.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exported) != 1 || exported[0].Token() != "spiderman-token" {
+		t.Fatalf("got %v, expected only spiderman-token", exported)
+	}
+
+	key, err := s.RetrieveKey("spiderman-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key.Payload() != "spiderman" {
+		t.Fatalf("got payload %v, expected spiderman", key.Payload())
+	}
+
+	if !key.ExpiresAt().Truncate(time.Second).Equal(expiresAt) {
+		t.Fatalf("got expiry %v, expected %v", key.ExpiresAt(), expiresAt)
+	}
+
+	if _, err = s.RetrieveKey("expired-token"); !errors.Is(err, auth.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v", err, auth.ErrNotFound)
+	}
+}
+
+func testConcurrentRegister(t *testing.T, s auth.Service) {
+	const workers = 16
+
+	var wg sync.WaitGroup
+	keys := make([]auth.ApiKey, workers)
+
+	for i := 0; i < workers; i++ {
+		keys[i] = auth.NewApiKey(32, time.Minute, fmt.Sprintf("symbiote%d", i))
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := s.RegisterKey(keys[i].Token(), keys[i]); err != nil {
+				t.Errorf("failed to register key %d: %v", i, err)
+				return
+			}
+
+			if _, err := s.RetrieveKey(keys[i].Token()); err != nil {
+				t.Errorf("failed to retrieve key %d: %v", i, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	listed, err := s.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(listed) != workers {
+		t.Fatalf("got %d keys, expected %d", len(listed), workers)
+	}
+}
+
+func ids(keys []auth.ApiKey) []string {
+	got := make([]string, 0, len(keys))
+	for _, key := range keys {
+		got = append(got, key.Id())
+	}
+
+	slices.Sort(got)
+
+	return got
+}
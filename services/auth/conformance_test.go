@@ -0,0 +1,12 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/auth/authtest"
+)
+
+func TestMap_Conformance(t *testing.T) {
+	authtest.Run(t, func(*testing.T) auth.Service { return auth.NewMap() })
+}
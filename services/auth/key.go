@@ -2,6 +2,8 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 )
 
@@ -12,10 +14,24 @@ type ApiKey struct {
 }
 
 func NewApiKey(len int, d time.Duration, v any) ApiKey {
+	return NewApiKeyExpiringAt(len, time.Now().Add(d), v)
+}
+
+// NewApiKeyExpiringAt returns a new key that expires at expiresAt rather than after a duration.
+func NewApiKeyExpiringAt(len int, expiresAt time.Time, v any) ApiKey {
 	return ApiKey{
 		payload:   v,
 		token:     string(randBytes(len)),
-		expiresAt: time.Now().Add(d),
+		expiresAt: expiresAt,
+	}
+}
+
+// RestoreApiKey returns a previously issued key, e.g. one read from a backup.
+func RestoreApiKey(token string, expiresAt time.Time, v any) ApiKey {
+	return ApiKey{
+		payload:   v,
+		token:     token,
+		expiresAt: expiresAt,
 	}
 }
 
@@ -31,6 +47,12 @@ func (a ApiKey) ExpiresAt() time.Time {
 	return a.expiresAt
 }
 
+// Id returns an identifier for the key that is safe to display, unlike the token itself.
+func (a ApiKey) Id() string {
+	sum := sha256.Sum256([]byte(a.token))
+	return hex.EncodeToString(sum[:8])
+}
+
 func randBytes(length int) []byte {
 	const validChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
 
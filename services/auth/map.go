@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"time"
 
 	"github.com/eolso/threadsafe"
@@ -28,15 +29,46 @@ func (m *Map) RegisterKey(s string, key ApiKey) error {
 }
 
 func (m *Map) RetrieveKey(s string) (ApiKey, error) {
+	// Keys are removed once they expire, but that happens asynchronously.
 	key, ok := m.data.Get(s)
-	if !ok {
+	if !ok || !time.Now().Before(key.ExpiresAt()) {
 		return ApiKey{}, ErrNotFound
 	}
 
 	return key, nil
 }
 
+func (m *Map) ListKeys() ([]ApiKey, error) {
+	return m.Export(context.Background())
+}
+
 func (m *Map) RevokeKey(s string) error {
 	m.data.Delete(s)
 	return nil
 }
+
+// Export returns every key that hasn't expired.
+func (m *Map) Export(_ context.Context) ([]ApiKey, error) {
+	keys := make([]ApiKey, 0)
+
+	for _, key := range m.data.Values() {
+		if time.Now().Before(key.ExpiresAt()) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// Import stores every key that hasn't expired under its token. Unlike RegisterKey it doesn't start a goroutine per key
+// to remove it once it expires, since a snapshot can hold any number of them. Expired keys are skipped by RetrieveKey
+// and Export regardless.
+func (m *Map) Import(_ context.Context, keys []ApiKey) error {
+	for _, key := range keys {
+		if time.Now().Before(key.ExpiresAt()) {
+			m.data.Set(key.Token(), key)
+		}
+	}
+
+	return nil
+}
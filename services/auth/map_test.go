@@ -57,3 +57,42 @@ func TestMap_RevokeKey(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestMap_ListKeys(t *testing.T) {
+	m := NewMap()
+
+	key := NewApiKey(8, time.Minute, "spiderman")
+	if err := m.RegisterKey(key.Token(), key); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := m.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 1 || keys[0].Id() != key.Id() {
+		t.Fatalf("got keys %v, expected [%v]", keys, key)
+	}
+}
+
+func TestMap_Import(t *testing.T) {
+	m := NewMap()
+
+	keys := []ApiKey{
+		RestoreApiKey("valid", time.Now().Add(time.Minute), "spiderman"),
+		RestoreApiKey("expired", time.Now().Add(-time.Minute), "venom"),
+	}
+
+	if err := m.Import(nil, keys); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.RetrieveKey("valid"); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+
+	if _, err := m.RetrieveKey("expired"); err != ErrNotFound {
+		t.Fatalf("got error %v, expected %v", err, ErrNotFound)
+	}
+}
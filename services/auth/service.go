@@ -1,7 +1,12 @@
 package auth
 
+import "context"
+
 type Service interface {
 	RegisterKey(string, ApiKey) error
 	RetrieveKey(string) (ApiKey, error)
 	RevokeKey(string) error
+	ListKeys() ([]ApiKey, error)
+	Export(context.Context) ([]ApiKey, error)
+	Import(context.Context, []ApiKey) error
 }
@@ -0,0 +1,232 @@
+// Package cascade deletes users and rooms along with everything that refers to them in the other services.
+package cascade
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
+)
+
+// Policy decides what happens to the messages of a deleted user.
+type Policy string
+
+const (
+	// PolicyAnonymize keeps the messages of a deleted user, but with no author. It's the zero value.
+	PolicyAnonymize Policy = ""
+
+	// PolicyDelete deletes the messages of a deleted user, along with their reactions and pins.
+	PolicyDelete Policy = "delete"
+)
+
+// ParsePolicy returns the Policy named s, which is either "anonymize" or "delete".
+func ParsePolicy(s string) (Policy, error) {
+	switch s {
+	case "anonymize":
+		return PolicyAnonymize, nil
+	case "delete":
+		return PolicyDelete, nil
+	default:
+		return "", ErrInvalidPolicy
+	}
+}
+
+// Deleter deletes users and rooms across services. Everything that refers to a user or room is removed before the user
+// or room itself, and every step can safely be repeated, so a deletion that fails partway can be retried.
+type Deleter struct {
+	User     user.Service
+	Room     room.Service
+	Message  message.Service
+	Reaction reaction.Service
+	Pin      pin.Service
+	Invite   invite.Service
+
+	// Messages decides what happens to the messages of deleted users.
+	Messages Policy
+}
+
+// DeleteRoom deletes a room along with its messages, their reactions and pins, and the room's invites. The caller is
+// authorized the same way room.Service.Delete does it, before anything is deleted.
+func (d *Deleter) DeleteRoom(ctx context.Context, opts room.DeleteRoomOpts) error {
+	r, err := d.Room.GetRoomById(ctx, room.GetRoomByIdOpts{Id: opts.Id})
+	if err != nil {
+		return err
+	}
+
+	if !opts.Force && !slices.Contains(r.Admins, opts.UserId) {
+		return room.ErrUnauthorized
+	}
+
+	purged, err := d.Message.Purge(ctx, message.PurgeMessageOpts{RoomId: r.Id})
+	if err != nil {
+		return err
+	}
+
+	if err = d.clearMessages(ctx, purged); err != nil {
+		return err
+	}
+
+	// Pins and messages are stored separately, so pins are removed by room too in case a message was already gone.
+	pins, err := d.Pin.List(ctx, pin.ListPinOpts{RoomId: r.Id})
+	if err != nil {
+		return err
+	}
+
+	if err = d.removePins(ctx, pins); err != nil {
+		return err
+	}
+
+	invites, err := d.Invite.List(ctx, invite.ListInviteOpts{RoomId: r.Id})
+	if err != nil {
+		return err
+	}
+
+	if err = d.revokeInvites(ctx, invites); err != nil {
+		return err
+	}
+
+	if err = d.Room.Delete(ctx, opts); err != nil && !errors.Is(err, room.ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUser deletes a user. They leave every room they were in, and a room they were the last member of is deleted,
+// so that no room is left without an owner. Their bans and mutes are lifted, and their messages are handled according
+// to d.Messages. Their reactions, the pins they made and the invites they created are removed.
+func (d *Deleter) DeleteUser(ctx context.Context, opts user.DeleteUserOpts) error {
+	rooms, err := d.Room.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rooms {
+		if err = d.leave(ctx, r, opts.Id); err != nil && !errors.Is(err, room.ErrNotFound) {
+			return err
+		}
+	}
+
+	if d.Messages == PolicyDelete {
+		purged, err := d.Message.Purge(ctx, message.PurgeMessageOpts{UserId: opts.Id})
+		if err != nil {
+			return err
+		}
+
+		if err = d.clearMessages(ctx, purged); err != nil {
+			return err
+		}
+	} else if err = d.Message.Anonymize(ctx, message.AnonymizeMessageOpts{UserId: opts.Id}); err != nil {
+		return err
+	}
+
+	if err = d.Reaction.Clear(ctx, reaction.ClearReactionOpts{UserId: opts.Id}); err != nil {
+		return err
+	}
+
+	pins, err := d.Pin.List(ctx, pin.ListPinOpts{})
+	if err != nil {
+		return err
+	}
+
+	pins = slices.DeleteFunc(pins, func(p *pin.Pin) bool {
+		return p.UserId != opts.Id
+	})
+
+	if err = d.removePins(ctx, pins); err != nil {
+		return err
+	}
+
+	invites, err := d.Invite.List(ctx, invite.ListInviteOpts{})
+	if err != nil {
+		return err
+	}
+
+	invites = slices.DeleteFunc(invites, func(i *invite.Invite) bool {
+		return i.UserId != opts.Id
+	})
+
+	if err = d.revokeInvites(ctx, invites); err != nil {
+		return err
+	}
+
+	return d.User.Delete(ctx, opts)
+}
+
+// leave removes userId from r, deleting r instead if nobody else is in it. Named rooms pass ownership on when their
+// owner leaves, and userId's bans and mutes in them are lifted so they don't carry over to a new user of the same name.
+func (d *Deleter) leave(ctx context.Context, r *room.Room, userId string) error {
+	if slices.Contains(r.Users, userId) {
+		if !slices.ContainsFunc(r.Users, func(id string) bool { return id != userId }) {
+			return d.DeleteRoom(ctx, room.DeleteRoomOpts{Id: r.Id, Force: true})
+		}
+
+		if err := d.Room.Leave(ctx, room.LeaveRoomOpts{Id: r.Id, UserId: userId, Force: r.IsDirect()}); err != nil {
+			return err
+		}
+	}
+
+	restricted := func(restriction room.Restriction) bool {
+		return restriction.UserId == userId
+	}
+
+	if slices.ContainsFunc(r.Bans, restricted) {
+		if err := d.Room.Unban(ctx, room.UnbanRoomOpts{Id: r.Id, TargetId: userId, Force: true}); err != nil {
+			return err
+		}
+	}
+
+	if slices.ContainsFunc(r.Mutes, restricted) {
+		if err := d.Room.Unmute(ctx, room.UnmuteRoomOpts{Id: r.Id, TargetId: userId, Force: true}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clearMessages removes the reactions to and pins of the messages with ids.
+func (d *Deleter) clearMessages(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := d.Reaction.Clear(ctx, reaction.ClearReactionOpts{MessageIds: ids}); err != nil {
+		return err
+	}
+
+	pins, err := d.Pin.List(ctx, pin.ListPinOpts{})
+	if err != nil {
+		return err
+	}
+
+	return d.removePins(ctx, slices.DeleteFunc(pins, func(p *pin.Pin) bool {
+		return !slices.Contains(ids, p.MessageId)
+	}))
+}
+
+func (d *Deleter) removePins(ctx context.Context, pins []*pin.Pin) error {
+	for _, p := range pins {
+		if err := d.Pin.Remove(ctx, pin.RemovePinOpts{MessageId: p.MessageId}); err != nil && !errors.Is(err, pin.ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Deleter) revokeInvites(ctx context.Context, invites []*invite.Invite) error {
+	for _, i := range invites {
+		if err := d.Invite.Revoke(ctx, invite.RevokeInviteOpts{Code: i.Code}); err != nil && !errors.Is(err, invite.ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
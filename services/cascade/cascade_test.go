@@ -0,0 +1,341 @@
+package cascade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
+)
+
+// state is what's left in every service after a deletion, flattened so that it can be compared in one go.
+type state struct {
+	Users        []string
+	Rooms        []string
+	Restrictions []string
+	Messages     []string
+	Reactions    []string
+	Pins         []string
+	Invites      []string
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		expected    Policy
+		expectedErr error
+	}{
+		"anonymize": {input: "anonymize", expected: PolicyAnonymize},
+		"delete":    {input: "delete", expected: PolicyDelete},
+		"empty":     {input: "", expectedErr: ErrInvalidPolicy},
+		"unknown":   {input: "archive", expectedErr: ErrInvalidPolicy},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParsePolicy(input.input)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if got != input.expected {
+				t.Fatalf("got %q, expected %q", got, input.expected)
+			}
+		})
+	}
+}
+
+func TestDeleter_DeleteRoom(t *testing.T) {
+	tests := map[string]struct {
+		opts          room.DeleteRoomOpts
+		expectedState state
+		expectedErr   error
+	}{
+		"admin": {
+			opts: room.DeleteRoomOpts{Id: 1, UserId: "spiderman"},
+			expectedState: state{
+				Users:        []string{"mj", "spiderman", "venom"},
+				Rooms:        []string{"2 [mj] [mj]", "3 [spiderman venom] []", "4 [mj spiderman venom] []"},
+				Restrictions: []string{"2 ban venom"},
+				Messages:     []string{"c/mj", "d/venom"},
+				Reactions:    []string{"c/venom"},
+				Pins:         []string{"c"},
+				Invites:      []string{"y"},
+			},
+		},
+		"member": {
+			opts:          room.DeleteRoomOpts{Id: 1, UserId: "venom"},
+			expectedState: seedState,
+			expectedErr:   room.ErrUnauthorized,
+		},
+		"forced direct": {
+			opts: room.DeleteRoomOpts{Id: 3, Force: true},
+			expectedState: state{
+				Users:        []string{"mj", "spiderman", "venom"},
+				Rooms:        []string{"1 [spiderman venom mj] [spiderman]", "2 [mj] [mj]", "4 [mj spiderman venom] []"},
+				Restrictions: []string{"1 mute venom", "2 ban venom"},
+				Messages:     []string{"a/spiderman", "b/venom", "c/mj", "e/mj"},
+				Reactions:    []string{"a/venom", "c/venom", "e/spiderman"},
+				Pins:         []string{"a", "b", "c"},
+				Invites:      []string{"x", "y"},
+			},
+		},
+		"not found": {
+			opts:          room.DeleteRoomOpts{Id: 5, Force: true},
+			expectedState: seedState,
+			expectedErr:   room.ErrNotFound,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := seed(t, PolicyAnonymize)
+
+			if err := d.DeleteRoom(context.Background(), input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if got := snapshot(t, d); !reflect.DeepEqual(got, input.expectedState) {
+				t.Fatalf("got %+v, expected %+v", got, input.expectedState)
+			}
+
+			// Deleting again finds nothing left to delete.
+			if err := d.DeleteRoom(context.Background(), input.opts); input.expectedErr == nil && !errors.Is(err, room.ErrNotFound) {
+				t.Fatalf("got error %v deleting again, expected %v", err, room.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestDeleter_DeleteUser(t *testing.T) {
+	tests := map[string]struct {
+		policy        Policy
+		opts          user.DeleteUserOpts
+		expectedState state
+	}{
+		// venom leaves every room and their bans and mutes are lifted, but their message in the big apple stays without
+		// an author. The pin they made goes with them.
+		"anonymize": {
+			policy: PolicyAnonymize,
+			opts:   user.DeleteUserOpts{Id: "venom"},
+			expectedState: state{
+				Users:     []string{"mj", "spiderman"},
+				Rooms:     []string{"1 [spiderman mj] [spiderman]", "2 [mj] [mj]", "3 [spiderman] []", "4 [mj spiderman] []"},
+				Messages:  []string{"a/spiderman", "b/", "c/mj", "d/", "e/mj"},
+				Reactions: []string{"e/spiderman"},
+				Pins:      []string{"a", "c"},
+				Invites:   []string{"x"},
+			},
+		},
+		"delete": {
+			policy: PolicyDelete,
+			opts:   user.DeleteUserOpts{Id: "venom"},
+			expectedState: state{
+				Users:     []string{"mj", "spiderman"},
+				Rooms:     []string{"1 [spiderman mj] [spiderman]", "2 [mj] [mj]", "3 [spiderman] []", "4 [mj spiderman] []"},
+				Messages:  []string{"a/spiderman", "c/mj", "e/mj"},
+				Reactions: []string{"e/spiderman"},
+				Pins:      []string{"a", "c"},
+				Invites:   []string{"x"},
+			},
+		},
+		// The earliest remaining member of the big apple becomes its owner.
+		"owner": {
+			policy: PolicyAnonymize,
+			opts:   user.DeleteUserOpts{Id: "spiderman"},
+			expectedState: state{
+				Users:        []string{"mj", "venom"},
+				Rooms:        []string{"1 [venom mj] [venom]", "2 [mj] [mj]", "3 [venom] []", "4 [mj venom] []"},
+				Restrictions: []string{"1 mute venom", "2 ban venom"},
+				Messages:     []string{"a/", "b/venom", "c/mj", "d/venom", "e/mj"},
+				Reactions:    []string{"a/venom", "c/venom"},
+				Pins:         []string{"b", "c"},
+				Invites:      []string{"y"},
+			},
+		},
+		// A room whose last member is deleted goes too, rather than being left without an owner.
+		"last member": {
+			policy: PolicyAnonymize,
+			opts:   user.DeleteUserOpts{Id: "mj"},
+			expectedState: state{
+				Users:        []string{"spiderman", "venom"},
+				Rooms:        []string{"1 [spiderman venom] [spiderman]", "3 [spiderman venom] []", "4 [spiderman venom] []"},
+				Restrictions: []string{"1 mute venom"},
+				Messages:     []string{"a/spiderman", "b/venom", "d/venom", "e/"},
+				Reactions:    []string{"a/venom", "e/spiderman"},
+				Pins:         []string{"a", "b"},
+				Invites:      []string{"x"},
+			},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := seed(t, input.policy)
+
+			if err := d.DeleteUser(context.Background(), input.opts); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := snapshot(t, d); !reflect.DeepEqual(got, input.expectedState) {
+				t.Fatalf("got %+v, expected %+v", got, input.expectedState)
+			}
+
+			// Deleting again is harmless, so a deletion that failed partway can be retried.
+			if err := d.DeleteUser(context.Background(), input.opts); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := snapshot(t, d); !reflect.DeepEqual(got, input.expectedState) {
+				t.Fatalf("got %+v deleting again, expected %+v", got, input.expectedState)
+			}
+		})
+	}
+}
+
+// seedState is the state of the services returned by seed.
+var seedState = state{
+	Users:        []string{"mj", "spiderman", "venom"},
+	Rooms:        []string{"1 [spiderman venom mj] [spiderman]", "2 [mj] [mj]", "3 [spiderman venom] []", "4 [mj spiderman venom] []"},
+	Restrictions: []string{"1 mute venom", "2 ban venom"},
+	Messages:     []string{"a/spiderman", "b/venom", "c/mj", "d/venom", "e/mj"},
+	Reactions:    []string{"a/venom", "c/venom", "e/spiderman"},
+	Pins:         []string{"a", "b", "c"},
+	Invites:      []string{"x", "y"},
+}
+
+// seed returns a Deleter over maps holding seedState.
+func seed(t *testing.T, policy Policy) *Deleter {
+	t.Helper()
+
+	ctx := context.Background()
+
+	d := &Deleter{
+		User:     user.NewMap(),
+		Room:     room.NewMap(),
+		Message:  message.NewMap(),
+		Reaction: reaction.NewMap(),
+		Pin:      pin.NewMap(),
+		Invite:   invite.NewMap(),
+		Messages: policy,
+	}
+
+	err := errors.Join(
+		d.User.Import(ctx, []*user.User{{Username: "spiderman"}, {Username: "venom"}, {Username: "mj"}}),
+		d.Room.Import(ctx, []*room.Room{
+			{Id: 1, Name: "the big apple", Users: []string{"spiderman", "venom", "mj"}, Admins: []string{"spiderman"}, Pinners: []string{"venom"}, Mutes: []room.Restriction{{UserId: "venom", CreatedBy: "spiderman"}}},
+			{Id: 2, Name: "the daily bugle", Users: []string{"mj"}, Admins: []string{"mj"}, Bans: []room.Restriction{{UserId: "venom", CreatedBy: "mj"}}},
+			{Id: 3, Type: room.TypeDirect, Users: []string{"spiderman", "venom"}},
+			{Id: 4, Type: room.TypeDirect, Users: []string{"mj", "spiderman", "venom"}},
+		}),
+		d.Message.Import(ctx, []*message.Message{
+			{Id: "a", UserId: "spiderman", RoomId: 1, Content: "pizza time", Timestamp: 1000},
+			{Id: "b", UserId: "venom", RoomId: 1, Content: "we are venom", Timestamp: 2000},
+			{Id: "c", UserId: "mj", RoomId: 2, Content: "face it tiger", Timestamp: 3000},
+			{Id: "d", UserId: "venom", RoomId: 3, Content: "eyes up, tiger", Timestamp: 4000},
+			{Id: "e", UserId: "mj", RoomId: 1, Content: "you just hit the jackpot", Timestamp: 5000},
+		}),
+		d.Reaction.Import(ctx, []*reaction.Reaction{
+			{MessageId: "a", UserId: "venom", Emoji: "🍕", Timestamp: 1},
+			{MessageId: "c", UserId: "venom", Emoji: "🕷️", Timestamp: 2},
+			{MessageId: "e", UserId: "spiderman", Emoji: "🎰", Timestamp: 3},
+		}),
+		d.Pin.Import(ctx, []*pin.Pin{
+			{MessageId: "a", RoomId: 1, UserId: "spiderman", Timestamp: 1},
+			{MessageId: "b", RoomId: 1, UserId: "venom", Timestamp: 2},
+			{MessageId: "c", RoomId: 2, UserId: "mj", Timestamp: 3},
+		}),
+		d.Invite.Import(ctx, []*invite.Invite{
+			{Code: "x", RoomId: 1, UserId: "spiderman", CreatedAt: 1},
+			{Code: "y", RoomId: 2, UserId: "venom", CreatedAt: 2},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to prepopulate services: %v", err)
+	}
+
+	return d
+}
+
+func snapshot(t *testing.T, d *Deleter) state {
+	t.Helper()
+
+	ctx := context.Background()
+	var s state
+
+	users, err := d.User.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, u := range users {
+		s.Users = append(s.Users, u.Username)
+	}
+
+	rooms, err := d.Room.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range rooms {
+		s.Rooms = append(s.Rooms, fmt.Sprintf("%d %v %v", r.Id, r.Users, r.Admins))
+
+		for _, ban := range r.Bans {
+			s.Restrictions = append(s.Restrictions, fmt.Sprintf("%d ban %s", r.Id, ban.UserId))
+		}
+
+		for _, mute := range r.Mutes {
+			s.Restrictions = append(s.Restrictions, fmt.Sprintf("%d mute %s", r.Id, mute.UserId))
+		}
+	}
+
+	messages, err := d.Message.List(ctx, message.ListMessageOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, msg := range messages {
+		s.Messages = append(s.Messages, msg.Id+"/"+msg.UserId)
+	}
+
+	reactions, err := d.Reaction.List(ctx, reaction.ListReactionOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range reactions {
+		s.Reactions = append(s.Reactions, r.MessageId+"/"+r.UserId)
+	}
+
+	pins, err := d.Pin.List(ctx, pin.ListPinOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range pins {
+		s.Pins = append(s.Pins, p.MessageId)
+	}
+
+	invites, err := d.Invite.List(ctx, invite.ListInviteOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, i := range invites {
+		s.Invites = append(s.Invites, i.Code)
+	}
+
+	for _, values := range [][]string{s.Users, s.Rooms, s.Restrictions, s.Messages, s.Reactions, s.Pins, s.Invites} {
+		slices.Sort(values)
+	}
+
+	return s
+}
@@ -0,0 +1,5 @@
+package cascade
+
+import "errors"
+
+var ErrInvalidPolicy = errors.New(`policy must be "anonymize" or "delete"`)
@@ -1,6 +1,8 @@
 package fake
 
 import (
+	"context"
+
 	"github.com/worsediscord/server/services/auth"
 )
 
@@ -11,6 +13,14 @@ type AuthService struct {
 	ExpectedRetrieveKeyError  error
 
 	ExpectedRevokeKeyError error
+
+	ExpectedListKeysApiKeys []auth.ApiKey
+	ExpectedListKeysError   error
+
+	ExpectedExportApiKeys []auth.ApiKey
+	ExpectedExportError   error
+
+	ExpectedImportError error
 }
 
 func (f *AuthService) RegisterKey(_ string, _ auth.ApiKey) error {
@@ -24,3 +34,15 @@ func (f *AuthService) RetrieveKey(_ string) (auth.ApiKey, error) {
 func (f *AuthService) RevokeKey(_ string) error {
 	return f.ExpectedRevokeKeyError
 }
+
+func (f *AuthService) ListKeys() ([]auth.ApiKey, error) {
+	return f.ExpectedListKeysApiKeys, f.ExpectedListKeysError
+}
+
+func (f *AuthService) Export(_ context.Context) ([]auth.ApiKey, error) {
+	return f.ExpectedExportApiKeys, f.ExpectedExportError
+}
+
+func (f *AuthService) Import(_ context.Context, _ []auth.ApiKey) error {
+	return f.ExpectedImportError
+}
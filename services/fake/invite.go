@@ -0,0 +1,72 @@
+package fake
+
+import (
+	"context"
+
+	"github.com/worsediscord/server/services/invite"
+)
+
+// InviteService returns the Expected values for each method and records the arguments of every call, in order.
+type InviteService struct {
+	ExpectedCreateInvite *invite.Invite
+	ExpectedCreateError  error
+
+	ExpectedGetInviteByCodeInvite *invite.Invite
+	ExpectedGetInviteByCodeError  error
+
+	ExpectedUseInvite *invite.Invite
+	ExpectedUseError  error
+
+	ExpectedRevokeError error
+
+	ExpectedListInvites []*invite.Invite
+	ExpectedListError   error
+
+	ExpectedExportInvites []*invite.Invite
+	ExpectedExportError   error
+
+	ExpectedImportError error
+
+	CreateCalls          []invite.CreateInviteOpts
+	GetInviteByCodeCalls []invite.GetInviteByCodeOpts
+	UseCalls             []invite.UseInviteOpts
+	RevokeCalls          []invite.RevokeInviteOpts
+	ListCalls            []invite.ListInviteOpts
+	ExportCalls          int
+	ImportCalls          [][]*invite.Invite
+}
+
+func (f *InviteService) Create(_ context.Context, opts invite.CreateInviteOpts) (*invite.Invite, error) {
+	f.CreateCalls = append(f.CreateCalls, opts)
+	return f.ExpectedCreateInvite, f.ExpectedCreateError
+}
+
+func (f *InviteService) GetInviteByCode(_ context.Context, opts invite.GetInviteByCodeOpts) (*invite.Invite, error) {
+	f.GetInviteByCodeCalls = append(f.GetInviteByCodeCalls, opts)
+	return f.ExpectedGetInviteByCodeInvite, f.ExpectedGetInviteByCodeError
+}
+
+func (f *InviteService) Use(_ context.Context, opts invite.UseInviteOpts) (*invite.Invite, error) {
+	f.UseCalls = append(f.UseCalls, opts)
+	return f.ExpectedUseInvite, f.ExpectedUseError
+}
+
+func (f *InviteService) Revoke(_ context.Context, opts invite.RevokeInviteOpts) error {
+	f.RevokeCalls = append(f.RevokeCalls, opts)
+	return f.ExpectedRevokeError
+}
+
+func (f *InviteService) List(_ context.Context, opts invite.ListInviteOpts) ([]*invite.Invite, error) {
+	f.ListCalls = append(f.ListCalls, opts)
+	return f.ExpectedListInvites, f.ExpectedListError
+}
+
+func (f *InviteService) Export(_ context.Context) ([]*invite.Invite, error) {
+	f.ExportCalls++
+	return f.ExpectedExportInvites, f.ExpectedExportError
+}
+
+func (f *InviteService) Import(_ context.Context, invites []*invite.Invite) error {
+	f.ImportCalls = append(f.ImportCalls, invites)
+	return f.ExpectedImportError
+}
@@ -0,0 +1,80 @@
+package fake
+
+import (
+	"context"
+
+	"github.com/worsediscord/server/services/message"
+)
+
+// MessageService returns the Expected values for each method and records the arguments of every call, in order.
+type MessageService struct {
+	ExpectedCreateMessage *message.Message
+	ExpectedCreateError   error
+
+	ExpectedGetMessageByIdMessage *message.Message
+	ExpectedGetMessageByIdError   error
+
+	ExpectedListMessages []*message.Message
+	ExpectedListError    error
+
+	ExpectedDeleteError error
+
+	ExpectedPurgeIds   []string
+	ExpectedPurgeError error
+
+	ExpectedAnonymizeError error
+
+	ExpectedExportMessages []*message.Message
+	ExpectedExportError    error
+
+	ExpectedImportError error
+
+	CreateCalls         []message.CreateMessageOpts
+	GetMessageByIdCalls []message.GetMessageByIdOpts
+	ListCalls           []message.ListMessageOpts
+	DeleteCalls         []message.DeleteMessageOpts
+	PurgeCalls          []message.PurgeMessageOpts
+	AnonymizeCalls      []message.AnonymizeMessageOpts
+	ExportCalls         int
+	ImportCalls         [][]*message.Message
+}
+
+func (f *MessageService) Create(_ context.Context, opts message.CreateMessageOpts) (*message.Message, error) {
+	f.CreateCalls = append(f.CreateCalls, opts)
+	return f.ExpectedCreateMessage, f.ExpectedCreateError
+}
+
+func (f *MessageService) GetMessageById(_ context.Context, opts message.GetMessageByIdOpts) (*message.Message, error) {
+	f.GetMessageByIdCalls = append(f.GetMessageByIdCalls, opts)
+	return f.ExpectedGetMessageByIdMessage, f.ExpectedGetMessageByIdError
+}
+
+func (f *MessageService) List(_ context.Context, opts message.ListMessageOpts) ([]*message.Message, error) {
+	f.ListCalls = append(f.ListCalls, opts)
+	return f.ExpectedListMessages, f.ExpectedListError
+}
+
+func (f *MessageService) Delete(_ context.Context, opts message.DeleteMessageOpts) error {
+	f.DeleteCalls = append(f.DeleteCalls, opts)
+	return f.ExpectedDeleteError
+}
+
+func (f *MessageService) Purge(_ context.Context, opts message.PurgeMessageOpts) ([]string, error) {
+	f.PurgeCalls = append(f.PurgeCalls, opts)
+	return f.ExpectedPurgeIds, f.ExpectedPurgeError
+}
+
+func (f *MessageService) Anonymize(_ context.Context, opts message.AnonymizeMessageOpts) error {
+	f.AnonymizeCalls = append(f.AnonymizeCalls, opts)
+	return f.ExpectedAnonymizeError
+}
+
+func (f *MessageService) Export(_ context.Context) ([]*message.Message, error) {
+	f.ExportCalls++
+	return f.ExpectedExportMessages, f.ExpectedExportError
+}
+
+func (f *MessageService) Import(_ context.Context, messages []*message.Message) error {
+	f.ImportCalls = append(f.ImportCalls, messages)
+	return f.ExpectedImportError
+}
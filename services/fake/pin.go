@@ -0,0 +1,54 @@
+package fake
+
+import (
+	"context"
+
+	"github.com/worsediscord/server/services/pin"
+)
+
+// PinService returns the Expected values for each method and records the arguments of every call, in order.
+type PinService struct {
+	ExpectedAddPin   *pin.Pin
+	ExpectedAddError error
+
+	ExpectedRemoveError error
+
+	ExpectedListPins  []*pin.Pin
+	ExpectedListError error
+
+	ExpectedExportPins  []*pin.Pin
+	ExpectedExportError error
+
+	ExpectedImportError error
+
+	AddCalls    []pin.AddPinOpts
+	RemoveCalls []pin.RemovePinOpts
+	ListCalls   []pin.ListPinOpts
+	ExportCalls int
+	ImportCalls [][]*pin.Pin
+}
+
+func (f *PinService) Add(_ context.Context, opts pin.AddPinOpts) (*pin.Pin, error) {
+	f.AddCalls = append(f.AddCalls, opts)
+	return f.ExpectedAddPin, f.ExpectedAddError
+}
+
+func (f *PinService) Remove(_ context.Context, opts pin.RemovePinOpts) error {
+	f.RemoveCalls = append(f.RemoveCalls, opts)
+	return f.ExpectedRemoveError
+}
+
+func (f *PinService) List(_ context.Context, opts pin.ListPinOpts) ([]*pin.Pin, error) {
+	f.ListCalls = append(f.ListCalls, opts)
+	return f.ExpectedListPins, f.ExpectedListError
+}
+
+func (f *PinService) Export(_ context.Context) ([]*pin.Pin, error) {
+	f.ExportCalls++
+	return f.ExpectedExportPins, f.ExpectedExportError
+}
+
+func (f *PinService) Import(_ context.Context, pins []*pin.Pin) error {
+	f.ImportCalls = append(f.ImportCalls, pins)
+	return f.ExpectedImportError
+}
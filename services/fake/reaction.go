@@ -0,0 +1,62 @@
+package fake
+
+import (
+	"context"
+
+	"github.com/worsediscord/server/services/reaction"
+)
+
+// ReactionService returns the Expected values for each method and records the arguments of every call, in order.
+type ReactionService struct {
+	ExpectedAddReaction *reaction.Reaction
+	ExpectedAddError    error
+
+	ExpectedRemoveError error
+
+	ExpectedListReactions []*reaction.Reaction
+	ExpectedListError     error
+
+	ExpectedClearError error
+
+	ExpectedExportReactions []*reaction.Reaction
+	ExpectedExportError     error
+
+	ExpectedImportError error
+
+	AddCalls    []reaction.AddReactionOpts
+	RemoveCalls []reaction.RemoveReactionOpts
+	ListCalls   []reaction.ListReactionOpts
+	ClearCalls  []reaction.ClearReactionOpts
+	ExportCalls int
+	ImportCalls [][]*reaction.Reaction
+}
+
+func (f *ReactionService) Add(_ context.Context, opts reaction.AddReactionOpts) (*reaction.Reaction, error) {
+	f.AddCalls = append(f.AddCalls, opts)
+	return f.ExpectedAddReaction, f.ExpectedAddError
+}
+
+func (f *ReactionService) Remove(_ context.Context, opts reaction.RemoveReactionOpts) error {
+	f.RemoveCalls = append(f.RemoveCalls, opts)
+	return f.ExpectedRemoveError
+}
+
+func (f *ReactionService) List(_ context.Context, opts reaction.ListReactionOpts) ([]*reaction.Reaction, error) {
+	f.ListCalls = append(f.ListCalls, opts)
+	return f.ExpectedListReactions, f.ExpectedListError
+}
+
+func (f *ReactionService) Clear(_ context.Context, opts reaction.ClearReactionOpts) error {
+	f.ClearCalls = append(f.ClearCalls, opts)
+	return f.ExpectedClearError
+}
+
+func (f *ReactionService) Export(_ context.Context) ([]*reaction.Reaction, error) {
+	f.ExportCalls++
+	return f.ExpectedExportReactions, f.ExpectedExportError
+}
+
+func (f *ReactionService) Import(_ context.Context, reactions []*reaction.Reaction) error {
+	f.ImportCalls = append(f.ImportCalls, reactions)
+	return f.ExpectedImportError
+}
@@ -0,0 +1,169 @@
+package fake
+
+import (
+	"context"
+
+	"github.com/worsediscord/server/services/room"
+)
+
+// RoomService returns the Expected values for each method and records the arguments of every call, in order.
+type RoomService struct {
+	ExpectedCreateRoom  *room.Room
+	ExpectedCreateError error
+
+	ExpectedCreateDirectRoom  *room.Room
+	ExpectedCreateDirectError error
+
+	ExpectedGetRoomByIdRoom  *room.Room
+	ExpectedGetRoomByIdError error
+
+	ExpectedListRooms []*room.Room
+	ExpectedListError error
+
+	ExpectedDeleteError error
+
+	ExpectedUpdateRoom  *room.Room
+	ExpectedUpdateError error
+
+	ExpectedJoinError error
+
+	ExpectedLeaveError error
+
+	ExpectedKickError error
+
+	ExpectedBanError error
+
+	ExpectedUnbanError error
+
+	ExpectedMuteError error
+
+	ExpectedUnmuteError error
+
+	ExpectedPromoteError error
+
+	ExpectedDemoteError error
+
+	ExpectedTransferError error
+
+	ExpectedConfigurePinsError error
+
+	ExpectedExportRooms []*room.Room
+	ExpectedExportError error
+
+	ExpectedImportError error
+
+	CreateCalls        []room.CreateRoomOpts
+	CreateDirectCalls  []room.CreateDirectRoomOpts
+	GetRoomByIdCalls   []room.GetRoomByIdOpts
+	ListCalls          int
+	DeleteCalls        []room.DeleteRoomOpts
+	UpdateCalls        []room.UpdateRoomOpts
+	JoinCalls          []room.JoinRoomOpts
+	LeaveCalls         []room.LeaveRoomOpts
+	KickCalls          []room.KickRoomOpts
+	BanCalls           []room.BanRoomOpts
+	UnbanCalls         []room.UnbanRoomOpts
+	MuteCalls          []room.MuteRoomOpts
+	UnmuteCalls        []room.UnmuteRoomOpts
+	PromoteCalls       []room.PromoteRoomOpts
+	DemoteCalls        []room.DemoteRoomOpts
+	TransferCalls      []room.TransferRoomOpts
+	ConfigurePinsCalls []room.ConfigurePinsRoomOpts
+	ExportCalls        int
+	ImportCalls        [][]*room.Room
+}
+
+func (f *RoomService) Create(_ context.Context, opts room.CreateRoomOpts) (*room.Room, error) {
+	f.CreateCalls = append(f.CreateCalls, opts)
+	return f.ExpectedCreateRoom, f.ExpectedCreateError
+}
+
+func (f *RoomService) CreateDirect(_ context.Context, opts room.CreateDirectRoomOpts) (*room.Room, error) {
+	f.CreateDirectCalls = append(f.CreateDirectCalls, opts)
+	return f.ExpectedCreateDirectRoom, f.ExpectedCreateDirectError
+}
+
+func (f *RoomService) GetRoomById(_ context.Context, opts room.GetRoomByIdOpts) (*room.Room, error) {
+	f.GetRoomByIdCalls = append(f.GetRoomByIdCalls, opts)
+	return f.ExpectedGetRoomByIdRoom, f.ExpectedGetRoomByIdError
+}
+
+func (f *RoomService) List(_ context.Context) ([]*room.Room, error) {
+	f.ListCalls++
+	return f.ExpectedListRooms, f.ExpectedListError
+}
+
+func (f *RoomService) Delete(_ context.Context, opts room.DeleteRoomOpts) error {
+	f.DeleteCalls = append(f.DeleteCalls, opts)
+	return f.ExpectedDeleteError
+}
+
+func (f *RoomService) Update(_ context.Context, opts room.UpdateRoomOpts) (*room.Room, error) {
+	f.UpdateCalls = append(f.UpdateCalls, opts)
+	return f.ExpectedUpdateRoom, f.ExpectedUpdateError
+}
+
+func (f *RoomService) Join(_ context.Context, opts room.JoinRoomOpts) error {
+	f.JoinCalls = append(f.JoinCalls, opts)
+	return f.ExpectedJoinError
+}
+
+func (f *RoomService) Leave(_ context.Context, opts room.LeaveRoomOpts) error {
+	f.LeaveCalls = append(f.LeaveCalls, opts)
+	return f.ExpectedLeaveError
+}
+
+func (f *RoomService) Kick(_ context.Context, opts room.KickRoomOpts) error {
+	f.KickCalls = append(f.KickCalls, opts)
+	return f.ExpectedKickError
+}
+
+func (f *RoomService) Ban(_ context.Context, opts room.BanRoomOpts) error {
+	f.BanCalls = append(f.BanCalls, opts)
+	return f.ExpectedBanError
+}
+
+func (f *RoomService) Unban(_ context.Context, opts room.UnbanRoomOpts) error {
+	f.UnbanCalls = append(f.UnbanCalls, opts)
+	return f.ExpectedUnbanError
+}
+
+func (f *RoomService) Mute(_ context.Context, opts room.MuteRoomOpts) error {
+	f.MuteCalls = append(f.MuteCalls, opts)
+	return f.ExpectedMuteError
+}
+
+func (f *RoomService) Unmute(_ context.Context, opts room.UnmuteRoomOpts) error {
+	f.UnmuteCalls = append(f.UnmuteCalls, opts)
+	return f.ExpectedUnmuteError
+}
+
+func (f *RoomService) Promote(_ context.Context, opts room.PromoteRoomOpts) error {
+	f.PromoteCalls = append(f.PromoteCalls, opts)
+	return f.ExpectedPromoteError
+}
+
+func (f *RoomService) Demote(_ context.Context, opts room.DemoteRoomOpts) error {
+	f.DemoteCalls = append(f.DemoteCalls, opts)
+	return f.ExpectedDemoteError
+}
+
+func (f *RoomService) Transfer(_ context.Context, opts room.TransferRoomOpts) error {
+	f.TransferCalls = append(f.TransferCalls, opts)
+	return f.ExpectedTransferError
+}
+
+func (f *RoomService) ConfigurePins(_ context.Context, opts room.ConfigurePinsRoomOpts) error {
+	f.ConfigurePinsCalls = append(f.ConfigurePinsCalls, opts)
+	return f.ExpectedConfigurePinsError
+}
+
+func (f *RoomService) Export(_ context.Context) ([]*room.Room, error) {
+	f.ExportCalls++
+	return f.ExpectedExportRooms, f.ExpectedExportError
+}
+
+func (f *RoomService) Import(_ context.Context, rooms []*room.Room) error {
+	f.ImportCalls = append(f.ImportCalls, rooms)
+	return f.ExpectedImportError
+}
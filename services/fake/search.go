@@ -0,0 +1,45 @@
+package fake
+
+import (
+	"context"
+
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/search"
+)
+
+// SearchService returns the Expected values for each method and records the arguments of every call, in order.
+type SearchService struct {
+	ExpectedIndexError error
+
+	ExpectedRemoveError error
+
+	ExpectedSearchResults []*search.Result
+	ExpectedSearchError   error
+
+	ExpectedRebuildError error
+
+	IndexCalls   []search.IndexOpts
+	RemoveCalls  []search.RemoveOpts
+	SearchCalls  []search.SearchOpts
+	RebuildCalls [][]*message.Message
+}
+
+func (f *SearchService) Index(_ context.Context, opts search.IndexOpts) error {
+	f.IndexCalls = append(f.IndexCalls, opts)
+	return f.ExpectedIndexError
+}
+
+func (f *SearchService) Remove(_ context.Context, opts search.RemoveOpts) error {
+	f.RemoveCalls = append(f.RemoveCalls, opts)
+	return f.ExpectedRemoveError
+}
+
+func (f *SearchService) Search(_ context.Context, opts search.SearchOpts) ([]*search.Result, error) {
+	f.SearchCalls = append(f.SearchCalls, opts)
+	return f.ExpectedSearchResults, f.ExpectedSearchError
+}
+
+func (f *SearchService) Rebuild(_ context.Context, messages []*message.Message) error {
+	f.RebuildCalls = append(f.RebuildCalls, messages)
+	return f.ExpectedRebuildError
+}
@@ -16,6 +16,13 @@ type UserService struct {
 	ExpectedListError error
 
 	ExpectedDeleteError error
+
+	ExpectedUpdateError error
+
+	ExpectedExportUsers []*user.User
+	ExpectedExportError error
+
+	ExpectedImportError error
 }
 
 func (f *UserService) Create(_ context.Context, _ user.CreateUserOpts) error {
@@ -33,3 +40,15 @@ func (f *UserService) List(_ context.Context) ([]*user.User, error) {
 func (f *UserService) Delete(_ context.Context, _ user.DeleteUserOpts) error {
 	return f.ExpectedDeleteError
 }
+
+func (f *UserService) Update(_ context.Context, _ user.UpdateUserOpts) error {
+	return f.ExpectedUpdateError
+}
+
+func (f *UserService) Export(_ context.Context) ([]*user.User, error) {
+	return f.ExpectedExportUsers, f.ExpectedExportError
+}
+
+func (f *UserService) Import(_ context.Context, _ []*user.User) error {
+	return f.ExpectedImportError
+}
@@ -0,0 +1,12 @@
+package invite_test
+
+import (
+	"testing"
+
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/invite/invitetest"
+)
+
+func TestMap_Conformance(t *testing.T) {
+	invitetest.Run(t, func(*testing.T) invite.Service { return invite.NewMap() })
+}
@@ -0,0 +1,10 @@
+package invite
+
+import "errors"
+
+var (
+	ErrNotFound = errors.New("no invite found")
+	ErrExpired  = errors.New("invite has expired")
+	ErrUsedUp   = errors.New("invite has no uses left")
+	ErrInvalid  = errors.New("invite max uses and expiry can't be negative")
+)
@@ -0,0 +1,43 @@
+package invite
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
+
+// Invite lets users join a room they couldn't join otherwise.
+type Invite struct {
+	Code   string
+	RoomId int64
+
+	// UserId is who created the invite.
+	UserId    string
+	CreatedAt int64
+
+	// ExpiresAt is when the invite stops working in milliseconds since epoch, or zero if it never expires.
+	ExpiresAt int64
+
+	// MaxUses is how many times the invite can be used, or zero for no limit.
+	MaxUses int
+	Uses    int
+}
+
+// Expired reports whether the invite has expired at now, in milliseconds since epoch.
+func (i *Invite) Expired(now int64) bool {
+	return i.ExpiresAt > 0 && now >= i.ExpiresAt
+}
+
+// UsedUp reports whether the invite has been used as many times as it allows.
+func (i *Invite) UsedUp() bool {
+	return i.MaxUses > 0 && i.Uses >= i.MaxUses
+}
+
+// NewCode returns a random invite code. Codes are what let users into private rooms, so they must not be guessable.
+func NewCode() (string, error) {
+	code := make([]byte, 9)
+	if _, err := rand.Read(code); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(code), nil
+}
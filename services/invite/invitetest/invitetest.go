@@ -0,0 +1,359 @@
+// Package invitetest checks that an invite.Service behaves the same as invite.Map.
+package invitetest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/invite"
+)
+
+// Run runs the conformance suite against the services returned by newService. Every subtest calls newService once with
+// its own *testing.T, which newService should fail if it can't return an empty service.
+func Run(t *testing.T, newService func(*testing.T) invite.Service) {
+	t.Helper()
+
+	tests := map[string]func(*testing.T, invite.Service){
+		"Create":          testCreate,
+		"GetInviteByCode": testGetInviteByCode,
+		"Use":             testUse,
+		"Revoke":          testRevoke,
+		"List":            testList,
+		"ExportImport":    testExportImport,
+		"ConcurrentUse":   testConcurrentUse,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newService(t))
+		})
+	}
+}
+
+func testCreate(t *testing.T, s invite.Service) {
+	ctx := context.Background()
+
+	i, err := s.Create(ctx, invite.CreateInviteOpts{RoomId: 1, UserId: "spiderman", MaxUses: 2, ExpiresAt: 5000, Now: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if i.Code == "" {
+		t.Fatal("got an empty code")
+	}
+
+	expected := &invite.Invite{Code: i.Code, RoomId: 1, UserId: "spiderman", CreatedAt: 1000, ExpiresAt: 5000, MaxUses: 2}
+	if !equal(i, expected) {
+		t.Fatalf("got %v, expected %v", i, expected)
+	}
+
+	// Every invite gets its own code, even to the same room.
+	other, err := s.Create(ctx, invite.CreateInviteOpts{RoomId: 1, UserId: "spiderman"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other.Code == i.Code {
+		t.Fatalf("got code %s for both invites, expected unique codes", i.Code)
+	}
+
+	if _, err = s.Create(ctx, invite.CreateInviteOpts{RoomId: 1, UserId: "spiderman", MaxUses: -1}); !errors.Is(err, invite.ErrInvalid) {
+		t.Fatalf("got error %v, expected %v", err, invite.ErrInvalid)
+	}
+}
+
+func testGetInviteByCode(t *testing.T, s invite.Service) {
+	ctx := context.Background()
+
+	created, err := s.Create(ctx, invite.CreateInviteOpts{RoomId: 1, UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := map[string]struct {
+		code        string
+		expected    *invite.Invite
+		expectedErr error
+	}{
+		"valid": {
+			code:     created.Code,
+			expected: created,
+		},
+		"not found": {
+			code:        "nope",
+			expectedErr: invite.ErrNotFound,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := s.GetInviteByCode(ctx, invite.GetInviteByCodeOpts{Code: input.code})
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if !equal(got, input.expected) {
+				t.Fatalf("got %v, expected %v", got, input.expected)
+			}
+		})
+	}
+}
+
+func testUse(t *testing.T, s invite.Service) {
+	ctx := context.Background()
+
+	seed := []*invite.Invite{
+		{Code: "once", RoomId: 1, UserId: "spiderman", CreatedAt: 1000, MaxUses: 1},
+		{Code: "expiring", RoomId: 1, UserId: "spiderman", CreatedAt: 1000, ExpiresAt: 5000},
+		{Code: "forever", RoomId: 1, UserId: "spiderman", CreatedAt: 1000, Uses: 7},
+	}
+
+	if err := s.Import(ctx, seed); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		opts         invite.UseInviteOpts
+		expectedUses int
+		expectedErr  error
+	}{
+		{
+			name:         "first use",
+			opts:         invite.UseInviteOpts{Code: "once", Now: 2000},
+			expectedUses: 1,
+		},
+		{
+			name:        "used up",
+			opts:        invite.UseInviteOpts{Code: "once", Now: 2000},
+			expectedErr: invite.ErrUsedUp,
+		},
+		{
+			name:         "before expiry",
+			opts:         invite.UseInviteOpts{Code: "expiring", Now: 4999},
+			expectedUses: 1,
+		},
+		{
+			name:        "expired",
+			opts:        invite.UseInviteOpts{Code: "expiring", Now: 5000},
+			expectedErr: invite.ErrExpired,
+		},
+		{
+			name:         "no limits",
+			opts:         invite.UseInviteOpts{Code: "forever", Now: 1 << 50},
+			expectedUses: 8,
+		},
+		{
+			name:        "not found",
+			opts:        invite.UseInviteOpts{Code: "nope", Now: 2000},
+			expectedErr: invite.ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			got, err := s.Use(ctx, input.opts)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got.Uses != input.expectedUses {
+				t.Fatalf("got %d uses, expected %d", got.Uses, input.expectedUses)
+			}
+
+			stored, err := s.GetInviteByCode(ctx, invite.GetInviteByCodeOpts{Code: input.opts.Code})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equal(stored, got) {
+				t.Fatalf("got stored invite %v, expected %v", stored, got)
+			}
+		})
+	}
+}
+
+func testRevoke(t *testing.T, s invite.Service) {
+	ctx := context.Background()
+
+	created, err := s.Create(ctx, invite.CreateInviteOpts{RoomId: 1, UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	if err = s.Revoke(ctx, invite.RevokeInviteOpts{Code: created.Code}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Revoke(ctx, invite.RevokeInviteOpts{Code: created.Code}); !errors.Is(err, invite.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v revoking it twice", err, invite.ErrNotFound)
+	}
+
+	if _, err = s.Use(ctx, invite.UseInviteOpts{Code: created.Code, Now: time.Now().UnixMilli()}); !errors.Is(err, invite.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v using a revoked invite", err, invite.ErrNotFound)
+	}
+}
+
+func testList(t *testing.T, s invite.Service) {
+	ctx := context.Background()
+
+	invites, err := s.List(ctx, invite.ListInviteOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if invites == nil || len(invites) != 0 {
+		t.Fatalf("got %v, expected an empty, non-nil slice", invites)
+	}
+
+	seed := []*invite.Invite{
+		{Code: "b", RoomId: 1, UserId: "spiderman", CreatedAt: 2000},
+		{Code: "a", RoomId: 1, UserId: "venom", CreatedAt: 1000},
+		{Code: "c", RoomId: 2, UserId: "venom", CreatedAt: 3000},
+	}
+
+	if err = s.Import(ctx, seed); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := map[string]struct {
+		opts     invite.ListInviteOpts
+		expected []*invite.Invite
+	}{
+		"everything": {
+			opts:     invite.ListInviteOpts{},
+			expected: []*invite.Invite{seed[1], seed[0], seed[2]},
+		},
+		"room": {
+			opts:     invite.ListInviteOpts{RoomId: 1},
+			expected: []*invite.Invite{seed[1], seed[0]},
+		},
+		"no matches": {
+			opts:     invite.ListInviteOpts{RoomId: 3},
+			expected: []*invite.Invite{},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := s.List(ctx, input.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equalAll(got, input.expected) {
+				t.Fatalf("got %v, expected %v", got, input.expected)
+			}
+		})
+	}
+}
+
+func testExportImport(t *testing.T, s invite.Service) {
+	ctx := context.Background()
+
+	invites := []*invite.Invite{
+		{Code: "a", RoomId: 1, UserId: "spiderman", CreatedAt: 1000, ExpiresAt: 9000, MaxUses: 3, Uses: 1},
+		{Code: "b", RoomId: 2, UserId: "venom", CreatedAt: 2000},
+	}
+
+	if err := s.Import(ctx, invites); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := s.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equalAll(exported, invites) {
+		t.Fatalf("got %v, expected %v", exported, invites)
+	}
+
+	// Importing an invite with a code that already exists replaces it.
+	replacement := &invite.Invite{Code: "b", RoomId: 2, UserId: "venom", CreatedAt: 3000, Uses: 4}
+	if err = s.Import(ctx, []*invite.Invite{replacement}); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err = s.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []*invite.Invite{invites[0], replacement}; !equalAll(exported, expected) {
+		t.Fatalf("got %v, expected %v", exported, expected)
+	}
+}
+
+func testConcurrentUse(t *testing.T, s invite.Service) {
+	ctx := context.Background()
+
+	const workers = 16
+	const maxUses = 5
+
+	created, err := s.Create(ctx, invite.CreateInviteOpts{RoomId: 1, UserId: "spiderman", MaxUses: maxUses})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	used := 0
+
+	// Every worker uses the same invite, so all but maxUses of them fail.
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := s.Use(ctx, invite.UseInviteOpts{Code: created.Code, Now: time.Now().UnixMilli()})
+			if errors.Is(err, invite.ErrUsedUp) {
+				return
+			} else if err != nil {
+				t.Errorf("failed to use invite: %v", err)
+				return
+			}
+
+			lock.Lock()
+			used++
+			lock.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if used != maxUses {
+		t.Fatalf("got %d successful uses, expected %d", used, maxUses)
+	}
+
+	got, err := s.GetInviteByCode(ctx, invite.GetInviteByCodeOpts{Code: created.Code})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Uses != maxUses {
+		t.Fatalf("got %d uses, expected %d", got.Uses, maxUses)
+	}
+}
+
+// equal compares invites by value, since services are free to return copies.
+func equal(a, b *invite.Invite) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func equalAll(a, b []*invite.Invite) bool {
+	return slices.EqualFunc(a, b, equal)
+}
@@ -0,0 +1,131 @@
+package invite
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/eolso/threadsafe"
+)
+
+type Map struct {
+	data *threadsafe.Map[string, *Invite]
+
+	// lock serializes uses so that an invite can't be used more than MaxUses times. Stored invites are replaced rather
+	// than modified, since callers may be reading them.
+	lock sync.Mutex
+}
+
+func NewMap() *Map {
+	return &Map{
+		data: threadsafe.NewMap[string, *Invite](),
+	}
+}
+
+func (m *Map) Create(_ context.Context, opts CreateInviteOpts) (*Invite, error) {
+	if opts.MaxUses < 0 || opts.ExpiresAt < 0 {
+		return nil, ErrInvalid
+	}
+
+	code, err := NewCode()
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Invite{
+		Code:      code,
+		RoomId:    opts.RoomId,
+		UserId:    opts.UserId,
+		CreatedAt: opts.Now,
+		ExpiresAt: opts.ExpiresAt,
+		MaxUses:   opts.MaxUses,
+	}
+
+	m.data.Set(code, i)
+
+	return i, nil
+}
+
+func (m *Map) GetInviteByCode(_ context.Context, opts GetInviteByCodeOpts) (*Invite, error) {
+	i, ok := m.data.Get(opts.Code)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return i, nil
+}
+
+// Use counts a use of the invite and returns it with the use counted.
+func (m *Map) Use(_ context.Context, opts UseInviteOpts) (*Invite, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	i, ok := m.data.Get(opts.Code)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if i.Expired(opts.Now) {
+		return nil, ErrExpired
+	}
+
+	if i.UsedUp() {
+		return nil, ErrUsedUp
+	}
+
+	used := *i
+	used.Uses++
+	m.data.Set(opts.Code, &used)
+
+	return &used, nil
+}
+
+func (m *Map) Revoke(_ context.Context, opts RevokeInviteOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.data.Get(opts.Code); !ok {
+		return ErrNotFound
+	}
+
+	m.data.Delete(opts.Code)
+
+	return nil
+}
+
+// List returns invites oldest first.
+func (m *Map) List(_ context.Context, opts ListInviteOpts) ([]*Invite, error) {
+	invites := make([]*Invite, 0)
+	for _, i := range m.data.Values() {
+		if opts.RoomId != 0 && i.RoomId != opts.RoomId {
+			continue
+		}
+
+		invites = append(invites, i)
+	}
+
+	slices.SortFunc(invites, func(a, b *Invite) int {
+		return cmp.Or(cmp.Compare(a.CreatedAt, b.CreatedAt), cmp.Compare(a.Code, b.Code))
+	})
+
+	return invites, nil
+}
+
+// Export returns every invite to every room.
+func (m *Map) Export(ctx context.Context) ([]*Invite, error) {
+	return m.List(ctx, ListInviteOpts{})
+}
+
+// Import stores invites as they are, replacing any invite with the same code.
+func (m *Map) Import(_ context.Context, invites []*Invite) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, i := range invites {
+		imported := *i
+		m.data.Set(i.Code, &imported)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package invite
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewMap(t *testing.T) {
+	if NewMap() == nil {
+		t.Fatal("constructor returned nil")
+	}
+}
+
+func TestMap_Use(t *testing.T) {
+	m := NewMap()
+
+	if err := m.Import(nil, []*Invite{{Code: "twice", RoomId: 1, UserId: "spiderman", MaxUses: 2, ExpiresAt: 5000}}); err != nil {
+		t.Fatalf("failed to prepopulate map: %v", err)
+	}
+
+	original, _ := m.data.Get("twice")
+
+	tests := []struct {
+		name         string
+		opts         UseInviteOpts
+		expectedErr  error
+		expectedUses int
+	}{
+		{
+			name:         "valid",
+			opts:         UseInviteOpts{Code: "twice", Now: 1000},
+			expectedErr:  nil,
+			expectedUses: 1,
+		},
+		{
+			name:         "expired",
+			opts:         UseInviteOpts{Code: "twice", Now: 6000},
+			expectedErr:  ErrExpired,
+			expectedUses: 1,
+		},
+		{
+			name:         "last use",
+			opts:         UseInviteOpts{Code: "twice", Now: 2000},
+			expectedErr:  nil,
+			expectedUses: 2,
+		},
+		{
+			name:         "used up",
+			opts:         UseInviteOpts{Code: "twice", Now: 3000},
+			expectedErr:  ErrUsedUp,
+			expectedUses: 2,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if _, err := m.Use(nil, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %q, expected %q", err, input.expectedErr)
+			}
+
+			if got, _ := m.data.Get("twice"); got.Uses != input.expectedUses {
+				t.Fatalf("got %d uses, expected %d", got.Uses, input.expectedUses)
+			}
+		})
+	}
+
+	// Invites handed out earlier are never modified.
+	if original.Uses != 0 {
+		t.Fatalf("got %d uses on the original invite, expected 0", original.Uses)
+	}
+}
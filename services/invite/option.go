@@ -0,0 +1,31 @@
+package invite
+
+// CreateInviteOpts creates an invite to a room. Zero MaxUses and ExpiresAt mean no limit. Now is when it's created in
+// milliseconds since epoch.
+type CreateInviteOpts struct {
+	RoomId    int64
+	UserId    string
+	MaxUses   int
+	ExpiresAt int64
+	Now       int64
+}
+
+type GetInviteByCodeOpts struct {
+	Code string
+}
+
+// UseInviteOpts uses an invite once. Now is when it's used in milliseconds since epoch, which expiry is checked
+// against.
+type UseInviteOpts struct {
+	Code string
+	Now  int64
+}
+
+type RevokeInviteOpts struct {
+	Code string
+}
+
+// ListInviteOpts filters the invites listed. Zero values match everything.
+type ListInviteOpts struct {
+	RoomId int64
+}
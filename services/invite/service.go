@@ -0,0 +1,13 @@
+package invite
+
+import "context"
+
+type Service interface {
+	Create(context.Context, CreateInviteOpts) (*Invite, error)
+	GetInviteByCode(context.Context, GetInviteByCodeOpts) (*Invite, error)
+	Use(context.Context, UseInviteOpts) (*Invite, error)
+	Revoke(context.Context, RevokeInviteOpts) error
+	List(context.Context, ListInviteOpts) ([]*Invite, error)
+	Export(context.Context) ([]*Invite, error)
+	Import(context.Context, []*Invite) error
+}
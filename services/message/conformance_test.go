@@ -0,0 +1,12 @@
+package message_test
+
+import (
+	"testing"
+
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/message/messagetest"
+)
+
+func TestMap_Conformance(t *testing.T) {
+	messagetest.Run(t, func(*testing.T) message.Service { return message.NewMap() })
+}
@@ -3,5 +3,7 @@ package message
 import "errors"
 
 var (
-	ErrNotFound = errors.New("no message found")
+	ErrNotFound      = errors.New("no message found")
+	ErrReplyNotFound = errors.New("message being replied to was not found in the room")
+	ErrInvalidPurge  = errors.New("purging messages needs a room or a user")
 )
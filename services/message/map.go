@@ -1,9 +1,13 @@
 package message
 
 import (
+	"cmp"
 	"context"
 	"encoding/base64"
 	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/eolso/threadsafe"
@@ -11,6 +15,13 @@ import (
 
 type Map struct {
 	data *threadsafe.Map[string, *Message]
+
+	// seq keeps ids unique when a room receives several messages in the same millisecond.
+	seq atomic.Int64
+
+	// lock serializes creating and deleting replies so that the counts kept on thread roots aren't lost. Stored messages
+	// are never modified in place, since callers may be reading them.
+	lock sync.Mutex
 }
 
 func NewMap() *Map {
@@ -20,7 +31,10 @@ func NewMap() *Map {
 }
 
 func (m *Map) Create(_ context.Context, opts CreateMessageOpts) (*Message, error) {
-	id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%d%d", opts.RoomId, time.Now().UnixMilli())))
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%d-%d-%d", opts.RoomId, time.Now().UnixMilli(), m.seq.Add(1))))
 
 	msg := Message{
 		Id:        id,
@@ -28,6 +42,24 @@ func (m *Map) Create(_ context.Context, opts CreateMessageOpts) (*Message, error
 		RoomId:    opts.RoomId,
 		Content:   opts.Content,
 		Timestamp: time.Now().UnixMilli(),
+		ReplyTo:   opts.ReplyTo,
+	}
+
+	if opts.ReplyTo != "" {
+		parent, ok := m.data.Get(opts.ReplyTo)
+		if !ok || parent.RoomId != opts.RoomId {
+			return nil, ErrReplyNotFound
+		}
+
+		msg.ThreadId = cmp.Or(parent.ThreadId, parent.Id)
+
+		if root, ok := m.data.Get(msg.ThreadId); ok {
+			updated := *root
+			updated.ReplyCount++
+			updated.LastReplyTimestamp = max(root.LastReplyTimestamp, msg.Timestamp)
+
+			m.data.Set(root.Id, &updated)
+		}
 	}
 
 	m.data.Set(id, &msg)
@@ -44,6 +76,7 @@ func (m *Map) GetMessageById(_ context.Context, opts GetMessageByIdOpts) (*Messa
 	return msg, nil
 }
 
+// List returns messages oldest first.
 func (m *Map) List(_ context.Context, opts ListMessageOpts) ([]*Message, error) {
 	messages := make([]*Message, 0)
 
@@ -56,11 +89,140 @@ func (m *Map) List(_ context.Context, opts ListMessageOpts) ([]*Message, error)
 		if len(opts.UserId) > 0 && msg.UserId != opts.UserId {
 			matchesFilter = false
 		}
+		if len(opts.ThreadId) > 0 && msg.ThreadId != opts.ThreadId {
+			matchesFilter = false
+		}
+		if opts.After != (Cursor{}) && !opts.After.Before(msg) {
+			matchesFilter = false
+		}
 
 		if matchesFilter {
 			messages = append(messages, msg)
 		}
 	}
 
+	slices.SortFunc(messages, func(a, b *Message) int {
+		return cmp.Or(cmp.Compare(a.Timestamp, b.Timestamp), cmp.Compare(a.Id, b.Id))
+	})
+
+	if opts.Limit > 0 && len(messages) > opts.Limit {
+		messages = messages[:opts.Limit]
+	}
+
 	return messages, nil
 }
+
+// Delete deletes a message. Deleting a reply updates the counts kept on the root of its thread, while deleting a root
+// leaves its replies in place.
+func (m *Map) Delete(_ context.Context, opts DeleteMessageOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	// threadsafe.Map.Pull deadlocks on its own lock, so the message is looked up and deleted separately.
+	msg, ok := m.data.Get(opts.Id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	m.data.Delete(opts.Id)
+
+	if msg.ThreadId != "" {
+		m.recount(msg.ThreadId)
+	}
+
+	return nil
+}
+
+// recount updates the counts kept on the root of the thread with threadId, if the root still exists. m.lock must be
+// held.
+func (m *Map) recount(threadId string) {
+	root, ok := m.data.Get(threadId)
+	if !ok {
+		return
+	}
+
+	updated := *root
+	updated.ReplyCount = 0
+	updated.LastReplyTimestamp = 0
+
+	for _, reply := range m.data.Values() {
+		if reply.ThreadId == root.Id {
+			updated.ReplyCount++
+			updated.LastReplyTimestamp = max(updated.LastReplyTimestamp, reply.Timestamp)
+		}
+	}
+
+	m.data.Set(root.Id, &updated)
+}
+
+// Purge deletes every message matching opts and returns their ids, in no particular order. The counts kept on the roots
+// of threads that lost replies are recounted.
+func (m *Map) Purge(_ context.Context, opts PurgeMessageOpts) ([]string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ids := make([]string, 0)
+	threads := make(map[string]bool)
+
+	for _, msg := range m.data.Values() {
+		if opts.RoomId != 0 && msg.RoomId != opts.RoomId {
+			continue
+		}
+
+		if opts.UserId != "" && msg.UserId != opts.UserId {
+			continue
+		}
+
+		m.data.Delete(msg.Id)
+		ids = append(ids, msg.Id)
+
+		if msg.ThreadId != "" {
+			threads[msg.ThreadId] = true
+		}
+	}
+
+	for threadId := range threads {
+		m.recount(threadId)
+	}
+
+	return ids, nil
+}
+
+// Anonymize clears the author of every message by opts.UserId.
+func (m *Map) Anonymize(_ context.Context, opts AnonymizeMessageOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, msg := range m.data.Values() {
+		if msg.UserId != opts.UserId {
+			continue
+		}
+
+		updated := *msg
+		updated.UserId = ""
+
+		m.data.Set(msg.Id, &updated)
+	}
+
+	return nil
+}
+
+// Export returns every message in every room, oldest first.
+func (m *Map) Export(ctx context.Context) ([]*Message, error) {
+	return m.List(ctx, ListMessageOpts{})
+}
+
+// Import stores messages as they are, replacing any message with the same id. The counts kept on thread roots are
+// imported rather than recounted.
+func (m *Map) Import(_ context.Context, messages []*Message) error {
+	for _, msg := range messages {
+		imported := *msg
+		m.data.Set(msg.Id, &imported)
+	}
+
+	return nil
+}
@@ -120,3 +120,46 @@ func TestMap_List(t *testing.T) {
 	}
 
 }
+
+func TestMap_Delete(t *testing.T) {
+	m := NewMap()
+
+	msg, err := m.Create(nil, CreateMessageOpts{UserId: "spiderman", RoomId: 100000000000, Content: "pizza time"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate map: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		opts        DeleteMessageOpts
+		expectedErr error
+	}{
+		{
+			name:        "valid",
+			opts:        DeleteMessageOpts{Id: msg.Id},
+			expectedErr: nil,
+		},
+		{
+			name:        "already deleted",
+			opts:        DeleteMessageOpts{Id: msg.Id},
+			expectedErr: ErrNotFound,
+		},
+		{
+			name:        "not found",
+			opts:        DeleteMessageOpts{Id: "missing"},
+			expectedErr: ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := m.Delete(nil, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %q, expected %q", err, input.expectedErr)
+			}
+
+			if m.data.Len() != 0 {
+				t.Fatalf("got %d messages, expected none", m.data.Len())
+			}
+		})
+	}
+}
@@ -1,9 +1,40 @@
 package message
 
 type Message struct {
-	Id        string
-	UserId    string
+	Id string
+
+	// UserId is the author of the message. It's empty once the author has been anonymized, which can't be mistaken for
+	// a user since usernames can't be empty.
+	UserId string
+
 	RoomId    int64
 	Content   string
 	Timestamp int64
+
+	// ReplyTo is the id of the message this one replies to, if any.
+	ReplyTo string
+
+	// ThreadId is the id of the message at the root of the thread this one is a reply in. Replies to a reply join the
+	// same thread.
+	ThreadId string
+
+	// ReplyCount and LastReplyTimestamp are kept on the root of a thread.
+	ReplyCount         int
+	LastReplyTimestamp int64
+}
+
+// Cursor is the position of a message in a list, for paging through it.
+type Cursor struct {
+	Timestamp int64
+	Id        string
+}
+
+// Cursor returns the position of m.
+func (m *Message) Cursor() Cursor {
+	return Cursor{Timestamp: m.Timestamp, Id: m.Id}
+}
+
+// Before reports whether the message at c is listed before m.
+func (c Cursor) Before(m *Message) bool {
+	return c.Timestamp < m.Timestamp || (c.Timestamp == m.Timestamp && c.Id < m.Id)
 }
@@ -0,0 +1,586 @@
+// Package messagetest checks that a message.Service behaves the same as message.Map.
+package messagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/message"
+)
+
+// Run runs the conformance suite against the services returned by newService. Every subtest calls newService once with
+// its own *testing.T, which newService should fail if it can't return an empty service.
+func Run(t *testing.T, newService func(*testing.T) message.Service) {
+	t.Helper()
+
+	tests := map[string]func(*testing.T, message.Service){
+		"Create":           testCreate,
+		"GetMessageById":   testGetMessageById,
+		"List":             testList,
+		"Delete":           testDelete,
+		"Reply":            testReply,
+		"DeleteReply":      testDeleteReply,
+		"Purge":            testPurge,
+		"Anonymize":        testAnonymize,
+		"ListPages":        testListPages,
+		"ExportImport":     testExportImport,
+		"ConcurrentCreate": testConcurrentCreate,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newService(t))
+		})
+	}
+}
+
+func testCreate(t *testing.T, s message.Service) {
+	ctx := context.Background()
+
+	before := time.Now().UnixMilli()
+
+	msg, err := s.Create(ctx, message.CreateMessageOpts{UserId: "spiderman", RoomId: 1, Content: "pizza time"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Id == "" {
+		t.Fatal("got an empty id")
+	}
+
+	if msg.Timestamp < before || msg.Timestamp > time.Now().UnixMilli() {
+		t.Fatalf("got timestamp %d, expected one between %d and now", msg.Timestamp, before)
+	}
+
+	expected := &message.Message{Id: msg.Id, UserId: "spiderman", RoomId: 1, Content: "pizza time", Timestamp: msg.Timestamp}
+	if !equal(msg, expected) {
+		t.Fatalf("got %v, expected %v", msg, expected)
+	}
+
+	// The same message sent twice is two messages.
+	again, err := s.Create(ctx, message.CreateMessageOpts{UserId: "spiderman", RoomId: 1, Content: "pizza time"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again.Id == msg.Id {
+		t.Fatalf("got id %q for both messages, expected unique ids", msg.Id)
+	}
+}
+
+func testGetMessageById(t *testing.T, s message.Service) {
+	ctx := context.Background()
+
+	msg, err := s.Create(ctx, message.CreateMessageOpts{UserId: "spiderman", RoomId: 1, Content: "pizza time"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := map[string]struct {
+		opts            message.GetMessageByIdOpts
+		expectedMessage *message.Message
+		expectedErr     error
+	}{
+		"valid": {
+			opts:            message.GetMessageByIdOpts{Id: msg.Id},
+			expectedMessage: msg,
+			expectedErr:     nil,
+		},
+		"not found": {
+			opts:            message.GetMessageByIdOpts{Id: "missing"},
+			expectedMessage: nil,
+			expectedErr:     message.ErrNotFound,
+		},
+		"empty id": {
+			opts:            message.GetMessageByIdOpts{Id: ""},
+			expectedMessage: nil,
+			expectedErr:     message.ErrNotFound,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := s.GetMessageById(ctx, input.opts)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if !equal(got, input.expectedMessage) {
+				t.Fatalf("got %v, expected %v", got, input.expectedMessage)
+			}
+		})
+	}
+}
+
+func testList(t *testing.T, s message.Service) {
+	ctx := context.Background()
+
+	messages, err := s.List(ctx, message.ListMessageOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if messages == nil || len(messages) != 0 {
+		t.Fatalf("got %v, expected an empty, non-nil slice", messages)
+	}
+
+	seed := []message.CreateMessageOpts{
+		{UserId: "spiderman", RoomId: 1, Content: "pizza time"},
+		{UserId: "venom", RoomId: 1, Content: "we are venom"},
+		{UserId: "spiderman", RoomId: 2, Content: "thwip"},
+	}
+
+	for _, opts := range seed {
+		if _, err = s.Create(ctx, opts); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	tests := map[string]struct {
+		opts             message.ListMessageOpts
+		expectedContents []string
+	}{
+		"everything": {
+			opts:             message.ListMessageOpts{},
+			expectedContents: []string{"pizza time", "thwip", "we are venom"},
+		},
+		"room": {
+			opts:             message.ListMessageOpts{RoomId: 1},
+			expectedContents: []string{"pizza time", "we are venom"},
+		},
+		"user": {
+			opts:             message.ListMessageOpts{UserId: "spiderman"},
+			expectedContents: []string{"pizza time", "thwip"},
+		},
+		"room and user": {
+			opts:             message.ListMessageOpts{RoomId: 1, UserId: "venom"},
+			expectedContents: []string{"we are venom"},
+		},
+		"no matches": {
+			opts:             message.ListMessageOpts{RoomId: 3},
+			expectedContents: []string{},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := s.List(ctx, input.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if contents := contents(got); !slices.Equal(contents, input.expectedContents) {
+				t.Fatalf("got %v, expected %v", contents, input.expectedContents)
+			}
+		})
+	}
+}
+
+func testDelete(t *testing.T, s message.Service) {
+	ctx := context.Background()
+
+	msg, err := s.Create(ctx, message.CreateMessageOpts{UserId: "spiderman", RoomId: 1, Content: "pizza time"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	kept, err := s.Create(ctx, message.CreateMessageOpts{UserId: "venom", RoomId: 1, Content: "we are venom"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	if err = s.Delete(ctx, message.DeleteMessageOpts{Id: msg.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = s.GetMessageById(ctx, message.GetMessageByIdOpts{Id: msg.Id}); !errors.Is(err, message.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v for a deleted message", err, message.ErrNotFound)
+	}
+
+	if err = s.Delete(ctx, message.DeleteMessageOpts{Id: msg.Id}); !errors.Is(err, message.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v deleting it twice", err, message.ErrNotFound)
+	}
+
+	if got, _ := s.GetMessageById(ctx, message.GetMessageByIdOpts{Id: kept.Id}); !equal(got, kept) {
+		t.Fatalf("got %v, expected %v to be untouched", got, kept)
+	}
+}
+
+func testReply(t *testing.T, s message.Service) {
+	ctx := context.Background()
+
+	root, err := s.Create(ctx, message.CreateMessageOpts{UserId: "spiderman", RoomId: 1, Content: "pizza time"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	reply, err := s.Create(ctx, message.CreateMessageOpts{UserId: "venom", RoomId: 1, Content: "we are venom", ReplyTo: root.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reply.ReplyTo != root.Id || reply.ThreadId != root.Id {
+		t.Fatalf("got reply to %q in thread %q, expected %q for both", reply.ReplyTo, reply.ThreadId, root.Id)
+	}
+
+	// Replying to a reply joins the same thread.
+	nested, err := s.Create(ctx, message.CreateMessageOpts{UserId: "spiderman", RoomId: 1, Content: "no you aren't", ReplyTo: reply.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nested.ReplyTo != reply.Id || nested.ThreadId != root.Id {
+		t.Fatalf("got reply to %q in thread %q, expected reply to %q in thread %q", nested.ReplyTo, nested.ThreadId, reply.Id, root.Id)
+	}
+
+	got, err := s.GetMessageById(ctx, message.GetMessageByIdOpts{Id: root.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.ReplyCount != 2 || got.LastReplyTimestamp != nested.Timestamp {
+		t.Fatalf("got %d replies, last at %d, expected 2, last at %d", got.ReplyCount, got.LastReplyTimestamp, nested.Timestamp)
+	}
+
+	thread, err := s.List(ctx, message.ListMessageOpts{ThreadId: root.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if contents := contents(thread); !slices.Equal(contents, []string{"no you aren't", "we are venom"}) {
+		t.Fatalf("got thread %v, expected both replies", contents)
+	}
+
+	invalid := map[string]message.CreateMessageOpts{
+		"missing":    {UserId: "venom", RoomId: 1, Content: "we are venom", ReplyTo: "missing"},
+		"other room": {UserId: "venom", RoomId: 2, Content: "we are venom", ReplyTo: root.Id},
+	}
+
+	for name, opts := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.Create(ctx, opts); !errors.Is(err, message.ErrReplyNotFound) {
+				t.Fatalf("got error %v, expected %v", err, message.ErrReplyNotFound)
+			}
+		})
+	}
+}
+
+func testDeleteReply(t *testing.T, s message.Service) {
+	ctx := context.Background()
+
+	root, err := s.Create(ctx, message.CreateMessageOpts{UserId: "spiderman", RoomId: 1, Content: "pizza time"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	first, err := s.Create(ctx, message.CreateMessageOpts{UserId: "venom", RoomId: 1, Content: "we are venom", ReplyTo: root.Id})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	second, err := s.Create(ctx, message.CreateMessageOpts{UserId: "spiderman", RoomId: 1, Content: "no you aren't", ReplyTo: root.Id})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	if err = s.Delete(ctx, message.DeleteMessageOpts{Id: second.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.GetMessageById(ctx, message.GetMessageByIdOpts{Id: root.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.ReplyCount != 1 || got.LastReplyTimestamp != first.Timestamp {
+		t.Fatalf("got %d replies, last at %d, expected 1, last at %d", got.ReplyCount, got.LastReplyTimestamp, first.Timestamp)
+	}
+
+	// Deleting the root leaves its replies.
+	if err = s.Delete(ctx, message.DeleteMessageOpts{Id: root.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ = s.GetMessageById(ctx, message.GetMessageByIdOpts{Id: first.Id}); !equal(got, first) {
+		t.Fatalf("got %v, expected %v to be untouched", got, first)
+	}
+}
+
+func testListPages(t *testing.T, s message.Service) {
+	ctx := context.Background()
+
+	var messages []*message.Message
+	for i := range 5 {
+		msg := &message.Message{Id: fmt.Sprintf("message%d", i), UserId: "spiderman", RoomId: 1, Content: fmt.Sprintf("thwip %d", i), Timestamp: 1000}
+		if i >= 3 {
+			msg.Timestamp = 2000
+		}
+
+		messages = append(messages, msg)
+	}
+
+	if err := s.Import(ctx, messages); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	var got []*message.Message
+	opts := message.ListMessageOpts{RoomId: 1, Limit: 2}
+
+	for {
+		page, err := s.List(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(page) > opts.Limit {
+			t.Fatalf("got %d messages, expected at most %d", len(page), opts.Limit)
+		}
+
+		if len(page) == 0 {
+			break
+		}
+
+		got = append(got, page...)
+		opts.After = page[len(page)-1].Cursor()
+	}
+
+	if !slices.EqualFunc(got, messages, equal) {
+		t.Fatalf("got %v, expected %v", got, messages)
+	}
+}
+
+func testExportImport(t *testing.T, s message.Service) {
+	ctx := context.Background()
+
+	messages := []*message.Message{
+		{Id: "first", UserId: "spiderman", RoomId: 1, Content: "pizza time", Timestamp: 1000, ReplyCount: 1, LastReplyTimestamp: 2000},
+		{Id: "second", UserId: "venom", RoomId: 1, Content: "we are venom", Timestamp: 2000, ReplyTo: "first", ThreadId: "first"},
+	}
+
+	if err := s.Import(ctx, messages); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := s.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exported) != len(messages) {
+		t.Fatalf("got %d messages, expected %d", len(exported), len(messages))
+	}
+
+	for _, expected := range messages {
+		got, err := s.GetMessageById(ctx, message.GetMessageByIdOpts{Id: expected.Id})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !equal(got, expected) {
+			t.Fatalf("got %v, expected %v", got, expected)
+		}
+	}
+
+	// Importing a message that exists replaces it.
+	replacement := &message.Message{Id: "second", UserId: "venom", RoomId: 1, Content: "we are carnage", Timestamp: 2000, ReplyTo: "first", ThreadId: "first"}
+	if err = s.Import(ctx, []*message.Message{replacement}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := s.GetMessageById(ctx, message.GetMessageByIdOpts{Id: "second"}); !equal(got, replacement) {
+		t.Fatalf("got %v, expected %v", got, replacement)
+	}
+}
+
+func testPurge(t *testing.T, s message.Service) {
+	ctx := context.Background()
+
+	seed := []*message.Message{
+		{Id: "a", UserId: "spiderman", RoomId: 1, Content: "pizza time", Timestamp: 1000, ReplyCount: 2, LastReplyTimestamp: 3000},
+		{Id: "b", UserId: "venom", RoomId: 1, Content: "we are venom", Timestamp: 2000, ReplyTo: "a", ThreadId: "a"},
+		{Id: "c", UserId: "spiderman", RoomId: 1, Content: "no you aren't", Timestamp: 3000, ReplyTo: "b", ThreadId: "a"},
+		{Id: "d", UserId: "venom", RoomId: 2, Content: "eyes, lungs, pancreas", Timestamp: 4000},
+		{Id: "e", UserId: "spiderman", RoomId: 2, Content: "thwip", Timestamp: 5000},
+	}
+
+	if err := s.Import(ctx, seed); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		opts              message.PurgeMessageOpts
+		expectedIds       []string
+		expectedRemaining []string
+		expectedRoot      *message.Message
+		expectedErr       error
+	}{
+		{
+			name:              "no filter",
+			opts:              message.PurgeMessageOpts{},
+			expectedRemaining: []string{"a", "b", "c", "d", "e"},
+			expectedErr:       message.ErrInvalidPurge,
+		},
+		{
+			name:              "user in a room",
+			opts:              message.PurgeMessageOpts{RoomId: 2, UserId: "venom"},
+			expectedIds:       []string{"d"},
+			expectedRemaining: []string{"a", "b", "c", "e"},
+		},
+		// Purging venom's reply leaves one reply in the thread, so the count on its root goes down.
+		{
+			name:              "user",
+			opts:              message.PurgeMessageOpts{UserId: "venom"},
+			expectedIds:       []string{"b"},
+			expectedRemaining: []string{"a", "c", "e"},
+			expectedRoot:      &message.Message{Id: "a", UserId: "spiderman", RoomId: 1, Content: "pizza time", Timestamp: 1000, ReplyCount: 1, LastReplyTimestamp: 3000},
+		},
+		{
+			name:              "room",
+			opts:              message.PurgeMessageOpts{RoomId: 1},
+			expectedIds:       []string{"a", "c"},
+			expectedRemaining: []string{"e"},
+		},
+		{
+			name:              "no matches",
+			opts:              message.PurgeMessageOpts{RoomId: 3},
+			expectedIds:       []string{},
+			expectedRemaining: []string{"e"},
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			ids, err := s.Purge(ctx, input.opts)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			slices.Sort(ids)
+			if err == nil && (ids == nil || !slices.Equal(ids, input.expectedIds)) {
+				t.Fatalf("got ids %v, expected %v", ids, input.expectedIds)
+			}
+
+			remaining, err := s.Export(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0, len(remaining))
+			for _, msg := range remaining {
+				got = append(got, msg.Id)
+			}
+
+			if !slices.Equal(got, input.expectedRemaining) {
+				t.Fatalf("got remaining messages %v, expected %v", got, input.expectedRemaining)
+			}
+
+			if input.expectedRoot == nil {
+				return
+			}
+
+			if root, _ := s.GetMessageById(ctx, message.GetMessageByIdOpts{Id: input.expectedRoot.Id}); !equal(root, input.expectedRoot) {
+				t.Fatalf("got root %v, expected %v", root, input.expectedRoot)
+			}
+		})
+	}
+}
+
+func testAnonymize(t *testing.T, s message.Service) {
+	ctx := context.Background()
+
+	seed := []*message.Message{
+		{Id: "a", UserId: "spiderman", RoomId: 1, Content: "pizza time", Timestamp: 1000},
+		{Id: "b", UserId: "venom", RoomId: 1, Content: "we are venom", Timestamp: 2000},
+		{Id: "c", UserId: "venom", RoomId: 2, Content: "eyes, lungs, pancreas", Timestamp: 3000},
+	}
+
+	if err := s.Import(ctx, seed); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	if err := s.Anonymize(ctx, message.AnonymizeMessageOpts{UserId: "venom"}); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := s.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*message.Message{
+		seed[0],
+		{Id: "b", RoomId: 1, Content: "we are venom", Timestamp: 2000},
+		{Id: "c", RoomId: 2, Content: "eyes, lungs, pancreas", Timestamp: 3000},
+	}
+
+	if !slices.EqualFunc(exported, expected, equal) {
+		t.Fatalf("got %v, expected %v", exported, expected)
+	}
+
+	venom, err := s.List(ctx, message.ListMessageOpts{UserId: "venom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(venom) != 0 {
+		t.Fatalf("got messages %v, expected none left by venom", venom)
+	}
+}
+
+func testConcurrentCreate(t *testing.T, s message.Service) {
+	ctx := context.Background()
+
+	const workers = 16
+
+	var wg sync.WaitGroup
+
+	// Every message goes to the same room, so most of them share a millisecond.
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := s.Create(ctx, message.CreateMessageOpts{UserId: "spiderman", RoomId: 1, Content: fmt.Sprintf("thwip %d", i)}); err != nil {
+				t.Errorf("failed to create message %d: %v", i, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	messages, err := s.List(ctx, message.ListMessageOpts{RoomId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != workers {
+		t.Fatalf("got %d messages, expected %d", len(messages), workers)
+	}
+}
+
+// equal compares messages by value, since services are free to return copies.
+func equal(a, b *message.Message) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func contents(messages []*message.Message) []string {
+	got := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		got = append(got, msg.Content)
+	}
+
+	slices.Sort(got)
+
+	return got
+}
@@ -4,13 +4,49 @@ type CreateMessageOpts struct {
 	UserId  string
 	RoomId  int64
 	Content string
+
+	// ReplyTo is the id of a message in the same room to reply to. Optional.
+	ReplyTo string
 }
 
 type GetMessageByIdOpts struct {
 	Id string
 }
 
+type DeleteMessageOpts struct {
+	Id string
+}
+
+// PurgeMessageOpts deletes every message in RoomId, by UserId, or both if both are set. At least one must be set.
+type PurgeMessageOpts struct {
+	RoomId int64
+	UserId string
+}
+
+func (p PurgeMessageOpts) Validate() error {
+	if p.RoomId == 0 && p.UserId == "" {
+		return ErrInvalidPurge
+	}
+
+	return nil
+}
+
+// AnonymizeMessageOpts removes UserId as the author of their messages, leaving the messages themselves in place.
+type AnonymizeMessageOpts struct {
+	UserId string
+}
+
+// ListMessageOpts filters the messages listed, oldest first. Zero values match everything.
 type ListMessageOpts struct {
 	UserId string
 	RoomId int64
+
+	// ThreadId lists the replies in the thread rooted at the message with this id.
+	ThreadId string
+
+	// After lists only the messages after a previously listed one.
+	After Cursor
+
+	// Limit is the most messages listed. Unlimited if 0.
+	Limit int
 }
@@ -6,4 +6,9 @@ type Service interface {
 	Create(context.Context, CreateMessageOpts) (*Message, error)
 	GetMessageById(context.Context, GetMessageByIdOpts) (*Message, error)
 	List(context.Context, ListMessageOpts) ([]*Message, error)
+	Delete(context.Context, DeleteMessageOpts) error
+	Purge(context.Context, PurgeMessageOpts) ([]string, error)
+	Anonymize(context.Context, AnonymizeMessageOpts) error
+	Export(context.Context) ([]*Message, error)
+	Import(context.Context, []*Message) error
 }
@@ -0,0 +1,12 @@
+package pin_test
+
+import (
+	"testing"
+
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/pin/pintest"
+)
+
+func TestMap_Conformance(t *testing.T) {
+	pintest.Run(t, func(*testing.T) pin.Service { return pin.NewMap() })
+}
@@ -0,0 +1,8 @@
+package pin
+
+import "errors"
+
+var (
+	ErrNotFound     = errors.New("no pin found")
+	ErrLimitReached = errors.New("room has reached its pin limit")
+)
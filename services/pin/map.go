@@ -0,0 +1,101 @@
+package pin
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Map struct {
+	data map[string]*Pin
+	lock sync.RWMutex
+}
+
+func NewMap() *Map {
+	return &Map{
+		data: make(map[string]*Pin),
+	}
+}
+
+// Add pins a message. Pinning a message that's already pinned returns the existing pin unchanged, even if the room is
+// at its limit.
+func (m *Map) Add(_ context.Context, opts AddPinOpts) (*Pin, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if p, ok := m.data[opts.MessageId]; ok {
+		return p, nil
+	}
+
+	if opts.Limit > 0 {
+		pinned := 0
+		for _, p := range m.data {
+			if p.RoomId == opts.RoomId {
+				pinned++
+			}
+		}
+
+		if pinned >= opts.Limit {
+			return nil, ErrLimitReached
+		}
+	}
+
+	p := &Pin{RoomId: opts.RoomId, MessageId: opts.MessageId, UserId: opts.UserId, Timestamp: time.Now().UnixMilli()}
+	m.data[opts.MessageId] = p
+
+	return p, nil
+}
+
+func (m *Map) Remove(_ context.Context, opts RemovePinOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.data[opts.MessageId]; !ok {
+		return ErrNotFound
+	}
+
+	delete(m.data, opts.MessageId)
+
+	return nil
+}
+
+// List returns pins oldest first.
+func (m *Map) List(_ context.Context, opts ListPinOpts) ([]*Pin, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	pins := make([]*Pin, 0)
+	for _, p := range m.data {
+		if opts.RoomId != 0 && p.RoomId != opts.RoomId {
+			continue
+		}
+
+		pins = append(pins, p)
+	}
+
+	slices.SortFunc(pins, func(a, b *Pin) int {
+		return cmp.Or(cmp.Compare(a.Timestamp, b.Timestamp), cmp.Compare(a.MessageId, b.MessageId))
+	})
+
+	return pins, nil
+}
+
+// Export returns every pin in every room.
+func (m *Map) Export(ctx context.Context) ([]*Pin, error) {
+	return m.List(ctx, ListPinOpts{})
+}
+
+// Import stores pins as they are, replacing any pin of the same message. Imported pins don't count against any limit.
+func (m *Map) Import(_ context.Context, pins []*Pin) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, p := range pins {
+		imported := *p
+		m.data[p.MessageId] = &imported
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package pin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewMap(t *testing.T) {
+	if NewMap() == nil {
+		t.Fatal("constructor returned nil")
+	}
+}
+
+func TestMap_Add(t *testing.T) {
+	m := NewMap()
+
+	if err := m.Import(nil, []*Pin{{RoomId: 1, MessageId: "rules", UserId: "spiderman", Timestamp: 1}}); err != nil {
+		t.Fatalf("failed to prepopulate map: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		opts          AddPinOpts
+		expectedErr   error
+		expectedCount int
+	}{
+		{
+			name:          "valid",
+			opts:          AddPinOpts{RoomId: 2, MessageId: "links", UserId: "venom", Limit: 1},
+			expectedErr:   nil,
+			expectedCount: 2,
+		},
+		{
+			name:          "already pinned",
+			opts:          AddPinOpts{RoomId: 1, MessageId: "rules", UserId: "venom", Limit: 1},
+			expectedErr:   nil,
+			expectedCount: 2,
+		},
+		{
+			name:          "limit reached",
+			opts:          AddPinOpts{RoomId: 1, MessageId: "faq", UserId: "spiderman", Limit: 1},
+			expectedErr:   ErrLimitReached,
+			expectedCount: 2,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if _, err := m.Add(nil, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %q, expected %q", err, input.expectedErr)
+			}
+
+			if len(m.data) != input.expectedCount {
+				t.Fatalf("got %d pins, expected %d", len(m.data), input.expectedCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package pin
+
+// AddPinOpts pins a message. Limit is the most pins the room may have, or zero for no limit.
+type AddPinOpts struct {
+	RoomId    int64
+	MessageId string
+	UserId    string
+	Limit     int
+}
+
+type RemovePinOpts struct {
+	MessageId string
+}
+
+// ListPinOpts filters the pins listed. Zero values match everything.
+type ListPinOpts struct {
+	RoomId int64
+}
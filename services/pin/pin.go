@@ -0,0 +1,11 @@
+package pin
+
+// Pin marks a message as important in its room. A message can only be pinned once.
+type Pin struct {
+	RoomId    int64
+	MessageId string
+
+	// UserId is who pinned the message.
+	UserId    string
+	Timestamp int64
+}
@@ -0,0 +1,297 @@
+// Package pintest checks that a pin.Service behaves the same as pin.Map.
+package pintest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/pin"
+)
+
+// Run runs the conformance suite against the services returned by newService. Every subtest calls newService once with
+// its own *testing.T, which newService should fail if it can't return an empty service.
+func Run(t *testing.T, newService func(*testing.T) pin.Service) {
+	t.Helper()
+
+	tests := map[string]func(*testing.T, pin.Service){
+		"Add":           testAdd,
+		"Limit":         testLimit,
+		"Remove":        testRemove,
+		"List":          testList,
+		"ExportImport":  testExportImport,
+		"ConcurrentAdd": testConcurrentAdd,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newService(t))
+		})
+	}
+}
+
+func testAdd(t *testing.T, s pin.Service) {
+	ctx := context.Background()
+
+	before := time.Now().UnixMilli()
+
+	p, err := s.Add(ctx, pin.AddPinOpts{RoomId: 1, MessageId: "rules", UserId: "spiderman"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Timestamp < before || p.Timestamp > time.Now().UnixMilli() {
+		t.Fatalf("got timestamp %d, expected one between %d and now", p.Timestamp, before)
+	}
+
+	expected := &pin.Pin{RoomId: 1, MessageId: "rules", UserId: "spiderman", Timestamp: p.Timestamp}
+	if !equal(p, expected) {
+		t.Fatalf("got %v, expected %v", p, expected)
+	}
+
+	// Pinning a message twice keeps the original pin, even when someone else pins it.
+	again, err := s.Add(ctx, pin.AddPinOpts{RoomId: 1, MessageId: "rules", UserId: "venom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equal(again, p) {
+		t.Fatalf("got %v pinning it twice, expected %v", again, p)
+	}
+
+	if pins, _ := s.List(ctx, pin.ListPinOpts{}); len(pins) != 1 {
+		t.Fatalf("got %d pins, expected 1", len(pins))
+	}
+}
+
+func testLimit(t *testing.T, s pin.Service) {
+	ctx := context.Background()
+
+	for _, opts := range []pin.AddPinOpts{
+		{RoomId: 1, MessageId: "rules", UserId: "spiderman", Limit: 2},
+		{RoomId: 1, MessageId: "links", UserId: "spiderman", Limit: 2},
+		{RoomId: 2, MessageId: "elsewhere", UserId: "venom", Limit: 1},
+	} {
+		if _, err := s.Add(ctx, opts); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		opts        pin.AddPinOpts
+		expectedErr error
+	}{
+		{
+			name:        "full",
+			opts:        pin.AddPinOpts{RoomId: 1, MessageId: "faq", UserId: "spiderman", Limit: 2},
+			expectedErr: pin.ErrLimitReached,
+		},
+		{
+			name:        "already pinned",
+			opts:        pin.AddPinOpts{RoomId: 1, MessageId: "rules", UserId: "spiderman", Limit: 2},
+			expectedErr: nil,
+		},
+		{
+			name:        "higher limit",
+			opts:        pin.AddPinOpts{RoomId: 1, MessageId: "faq", UserId: "spiderman", Limit: 3},
+			expectedErr: nil,
+		},
+		{
+			name:        "no limit",
+			opts:        pin.AddPinOpts{RoomId: 2, MessageId: "more", UserId: "venom"},
+			expectedErr: nil,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if _, err := s.Add(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+		})
+	}
+}
+
+func testRemove(t *testing.T, s pin.Service) {
+	ctx := context.Background()
+
+	for _, opts := range []pin.AddPinOpts{
+		{RoomId: 1, MessageId: "rules", UserId: "spiderman"},
+		{RoomId: 1, MessageId: "links", UserId: "venom"},
+	} {
+		if _, err := s.Add(ctx, opts); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	if err := s.Remove(ctx, pin.RemovePinOpts{MessageId: "rules"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Remove(ctx, pin.RemovePinOpts{MessageId: "rules"}); !errors.Is(err, pin.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v removing it twice", err, pin.ErrNotFound)
+	}
+
+	pins, err := s.List(ctx, pin.ListPinOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := messageIds(pins); !slices.Equal(ids, []string{"links"}) {
+		t.Fatalf("got pins of %v, expected [links]", ids)
+	}
+}
+
+func testList(t *testing.T, s pin.Service) {
+	ctx := context.Background()
+
+	pins, err := s.List(ctx, pin.ListPinOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pins == nil || len(pins) != 0 {
+		t.Fatalf("got %v, expected an empty, non-nil slice", pins)
+	}
+
+	seed := []*pin.Pin{
+		{RoomId: 1, MessageId: "rules", UserId: "spiderman", Timestamp: 2000},
+		{RoomId: 1, MessageId: "links", UserId: "venom", Timestamp: 1000},
+		{RoomId: 2, MessageId: "elsewhere", UserId: "venom", Timestamp: 3000},
+	}
+
+	if err = s.Import(ctx, seed); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := map[string]struct {
+		opts     pin.ListPinOpts
+		expected []*pin.Pin
+	}{
+		"everything": {
+			opts:     pin.ListPinOpts{},
+			expected: []*pin.Pin{seed[1], seed[0], seed[2]},
+		},
+		"room": {
+			opts:     pin.ListPinOpts{RoomId: 1},
+			expected: []*pin.Pin{seed[1], seed[0]},
+		},
+		"no matches": {
+			opts:     pin.ListPinOpts{RoomId: 3},
+			expected: []*pin.Pin{},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := s.List(ctx, input.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equalAll(got, input.expected) {
+				t.Fatalf("got %v, expected %v", got, input.expected)
+			}
+		})
+	}
+}
+
+func testExportImport(t *testing.T, s pin.Service) {
+	ctx := context.Background()
+
+	pins := []*pin.Pin{
+		{RoomId: 1, MessageId: "rules", UserId: "spiderman", Timestamp: 1000},
+		{RoomId: 1, MessageId: "links", UserId: "venom", Timestamp: 2000},
+	}
+
+	if err := s.Import(ctx, pins); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := s.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equalAll(exported, pins) {
+		t.Fatalf("got %v, expected %v", exported, pins)
+	}
+
+	// Importing a pin of a message that's already pinned replaces it.
+	replacement := &pin.Pin{RoomId: 1, MessageId: "links", UserId: "mj", Timestamp: 3000}
+	if err = s.Import(ctx, []*pin.Pin{replacement}); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err = s.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []*pin.Pin{pins[0], replacement}; !equalAll(exported, expected) {
+		t.Fatalf("got %v, expected %v", exported, expected)
+	}
+}
+
+func testConcurrentAdd(t *testing.T, s pin.Service) {
+	ctx := context.Background()
+
+	const workers = 16
+	const limit = 5
+
+	var wg sync.WaitGroup
+
+	// Every worker pins a different message in the same room, so all but limit of them fail.
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			opts := pin.AddPinOpts{RoomId: 1, MessageId: fmt.Sprintf("message%d", i), UserId: "spiderman", Limit: limit}
+			if _, err := s.Add(ctx, opts); err != nil && !errors.Is(err, pin.ErrLimitReached) {
+				t.Errorf("failed to add pin %d: %v", i, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	pins, err := s.List(ctx, pin.ListPinOpts{RoomId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pins) != limit {
+		t.Fatalf("got %d pins, expected %d", len(pins), limit)
+	}
+}
+
+// equal compares pins by value, since services are free to return copies.
+func equal(a, b *pin.Pin) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func equalAll(a, b []*pin.Pin) bool {
+	return slices.EqualFunc(a, b, equal)
+}
+
+func messageIds(pins []*pin.Pin) []string {
+	got := make([]string, 0, len(pins))
+	for _, p := range pins {
+		got = append(got, p.MessageId)
+	}
+
+	slices.Sort(got)
+
+	return got
+}
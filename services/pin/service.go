@@ -0,0 +1,11 @@
+package pin
+
+import "context"
+
+type Service interface {
+	Add(context.Context, AddPinOpts) (*Pin, error)
+	Remove(context.Context, RemovePinOpts) error
+	List(context.Context, ListPinOpts) ([]*Pin, error)
+	Export(context.Context) ([]*Pin, error)
+	Import(context.Context, []*Pin) error
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/worsediscord/server/services/auth"
+)
+
+// AuthService is an auth.Service backed by the sessions table. Only keys with a username as their payload can be
+// registered, which is every key the server issues. Expired keys are never returned and are removed as new keys are
+// registered.
+type AuthService struct {
+	pool *pgxpool.Pool
+}
+
+func (a *AuthService) RegisterKey(s string, key auth.ApiKey) error {
+	ctx := context.Background()
+
+	username, ok := key.Payload().(string)
+	if !ok {
+		return ErrUnsupportedPayload
+	}
+
+	if _, err := a.pool.Exec(ctx, "DELETE FROM sessions WHERE expires_at <= now()"); err != nil {
+		return err
+	}
+
+	_, err := a.pool.Exec(ctx, `
+		INSERT INTO sessions (token, username, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (token) DO UPDATE SET username = EXCLUDED.username, expires_at = EXCLUDED.expires_at`,
+		s, username, key.ExpiresAt())
+
+	return err
+}
+
+func (a *AuthService) RetrieveKey(s string) (auth.ApiKey, error) {
+	var username string
+	var expiresAt time.Time
+
+	err := a.pool.QueryRow(context.Background(), `
+		SELECT username, expires_at FROM sessions WHERE token = $1 AND expires_at > now()`,
+		s).Scan(&username, &expiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return auth.ApiKey{}, auth.ErrNotFound
+	} else if err != nil {
+		return auth.ApiKey{}, err
+	}
+
+	return auth.RestoreApiKey(s, expiresAt, username), nil
+}
+
+func (a *AuthService) RevokeKey(s string) error {
+	_, err := a.pool.Exec(context.Background(), "DELETE FROM sessions WHERE token = $1", s)
+	return err
+}
+
+func (a *AuthService) ListKeys() ([]auth.ApiKey, error) {
+	return a.Export(context.Background())
+}
+
+// Export returns every key that hasn't expired.
+func (a *AuthService) Export(ctx context.Context) ([]auth.ApiKey, error) {
+	rows, err := a.pool.Query(ctx, "SELECT token, username, expires_at FROM sessions WHERE expires_at > now() ORDER BY expires_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]auth.ApiKey, 0)
+	for rows.Next() {
+		var token, username string
+		var expiresAt time.Time
+
+		if err = rows.Scan(&token, &username, &expiresAt); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, auth.RestoreApiKey(token, expiresAt, username))
+	}
+
+	return keys, rows.Err()
+}
+
+// Import registers every key that hasn't expired under its token.
+func (a *AuthService) Import(ctx context.Context, keys []auth.ApiKey) error {
+	return inTx(ctx, a.pool, func(tx pgx.Tx) error {
+		for _, key := range keys {
+			username, ok := key.Payload().(string)
+			if !ok {
+				return ErrUnsupportedPayload
+			}
+
+			if !time.Now().Before(key.ExpiresAt()) {
+				continue
+			}
+
+			_, err := tx.Exec(ctx, `
+				INSERT INTO sessions (token, username, expires_at) VALUES ($1, $2, $3)
+				ON CONFLICT (token) DO UPDATE SET username = EXCLUDED.username, expires_at = EXCLUDED.expires_at`,
+				key.Token(), username, key.ExpiresAt())
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/auth"
+)
+
+func TestAuthService(t *testing.T) {
+	a := newDB(t).Sessions()
+
+	valid := auth.NewApiKey(16, time.Hour, "spiderman")
+	expired := auth.RestoreApiKey("expired", time.Now().Add(-time.Minute), "venom")
+
+	for _, key := range []auth.ApiKey{valid, expired} {
+		if err := a.RegisterKey(key.Token(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := a.RegisterKey("batman", auth.NewApiKey(16, time.Hour, 42)); !errors.Is(err, ErrUnsupportedPayload) {
+		t.Fatalf("got error %v, expected %v", err, ErrUnsupportedPayload)
+	}
+
+	key, err := a.RetrieveKey(valid.Token())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key.Payload() != "spiderman" || key.Token() != valid.Token() {
+		t.Fatalf("got key for %v, expected spiderman", key.Payload())
+	}
+
+	if _, err = a.RetrieveKey(expired.Token()); !errors.Is(err, auth.ErrNotFound) {
+		t.Fatalf("got error %v retrieving expired key, expected %v", err, auth.ErrNotFound)
+	}
+
+	keys, err := a.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 1 {
+		t.Fatalf("got %d keys, expected 1", len(keys))
+	}
+
+	if err = a.RevokeKey(valid.Token()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.RetrieveKey(valid.Token()); !errors.Is(err, auth.ErrNotFound) {
+		t.Fatalf("got error %v retrieving revoked key, expected %v", err, auth.ErrNotFound)
+	}
+
+	if err = a.Import(context.Background(), []auth.ApiKey{valid, expired}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.RetrieveKey(valid.Token()); err != nil {
+		t.Fatalf("got error %v retrieving imported key, expected nil", err)
+	}
+}
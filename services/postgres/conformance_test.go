@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/auth/authtest"
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/invite/invitetest"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/message/messagetest"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/pin/pintest"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/reaction/reactiontest"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/room/roomtest"
+	"github.com/worsediscord/server/services/search"
+	"github.com/worsediscord/server/services/search/searchtest"
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/services/user/usertest"
+)
+
+func TestConformance(t *testing.T) {
+	db := newDB(t)
+
+	empty := func(t *testing.T) *DB {
+		if err := reset(context.Background(), db); err != nil {
+			t.Fatal(err)
+		}
+
+		return db
+	}
+
+	usertest.Run(t, func(t *testing.T) user.Service { return empty(t).Users() })
+	roomtest.Run(t, func(t *testing.T) room.Service { return empty(t).Rooms() })
+	messagetest.Run(t, func(t *testing.T) message.Service { return empty(t).Messages() })
+	reactiontest.Run(t, func(t *testing.T) reaction.Service { return empty(t).Reactions() })
+	pintest.Run(t, func(t *testing.T) pin.Service { return empty(t).Pins() })
+	invitetest.Run(t, func(t *testing.T) invite.Service { return empty(t).Invites() })
+	authtest.Run(t, func(t *testing.T) auth.Service { return empty(t).Sessions() })
+	searchtest.Run(t, func(t *testing.T) search.Service { return empty(t).Search() })
+}
@@ -0,0 +1,226 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// migrationLockId is the advisory lock held while migrating, so that instances starting together take turns.
+const migrationLockId = 0x776473
+
+// PoolOpts configures the connection pool. Zero values keep the pgxpool defaults, or whatever the DSN sets.
+type PoolOpts struct {
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+}
+
+// DB is a pool of connections to a Postgres database that every service shares.
+type DB struct {
+	pool *pgxpool.Pool
+}
+
+// Open connects to the database at dsn. Migrate should be called before the services are used.
+func Open(ctx context.Context, dsn string, opts PoolOpts) (*DB, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxConns > 0 {
+		config.MaxConns = opts.MaxConns
+	}
+
+	if opts.MinConns > 0 {
+		config.MinConns = opts.MinConns
+	}
+
+	if opts.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = opts.MaxConnLifetime
+	}
+
+	if opts.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = opts.MaxConnIdleTime
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return &DB{pool: pool}, nil
+}
+
+func (d *DB) Users() *UserService {
+	return &UserService{pool: d.pool}
+}
+
+func (d *DB) Rooms() *RoomService {
+	return &RoomService{pool: d.pool}
+}
+
+func (d *DB) Messages() *MessageService {
+	return &MessageService{pool: d.pool}
+}
+
+func (d *DB) Reactions() *ReactionService {
+	return &ReactionService{pool: d.pool}
+}
+
+func (d *DB) Pins() *PinService {
+	return &PinService{pool: d.pool}
+}
+
+func (d *DB) Invites() *InviteService {
+	return &InviteService{pool: d.pool}
+}
+
+func (d *DB) Search() *SearchService {
+	return &SearchService{pool: d.pool}
+}
+
+func (d *DB) Sessions() *AuthService {
+	return &AuthService{pool: d.pool}
+}
+
+// Close closes every connection in the pool.
+func (d *DB) Close() error {
+	d.pool.Close()
+	return nil
+}
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// Migrate applies every migration that hasn't been applied yet, in order, in a single transaction.
+func (d *DB) Migrate(ctx context.Context) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockId); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return err
+	}
+
+	var current int
+	if err = tx.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return err
+	}
+
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].version
+	}
+
+	if current > latest {
+		return fmt.Errorf("%w: database is at version %d, expected at most %d", ErrSchemaTooNew, current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		if _, err = tx.Exec(ctx, m.sql); err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.name, err)
+		}
+
+		if _, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// loadMigrations returns the embedded migrations sorted by version. Files are named <version>_<description>.sql.
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s is missing a version", entry.Name())
+		}
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has an invalid version: %w", entry.Name(), err)
+		}
+
+		b, err := migrationFS.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{version: version, name: entry.Name(), sql: string(b)})
+	}
+
+	slices.SortFunc(migrations, func(a, b migration) int {
+		return a.version - b.version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version == migrations[i-1].version {
+			return nil, fmt.Errorf("migrations %s and %s share a version", migrations[i-1].name, migrations[i].name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// inTx runs fn in a transaction, committing if it returns nil.
+func inTx(ctx context.Context, pool *pgxpool.Pool, fn func(pgx.Tx) error) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,8 @@
+package postgres
+
+import "errors"
+
+var (
+	ErrSchemaTooNew       = errors.New("database schema is newer than this server")
+	ErrUnsupportedPayload = errors.New("only api keys with a username as their payload can be stored")
+)
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/worsediscord/server/services/invite"
+)
+
+// InviteService is an invite.Service backed by the invites table.
+type InviteService struct {
+	pool *pgxpool.Pool
+}
+
+const inviteColumns = "code, room_id, user_id, created_at, expires_at, max_uses, uses"
+
+func (i *InviteService) Create(ctx context.Context, opts invite.CreateInviteOpts) (*invite.Invite, error) {
+	if opts.MaxUses < 0 || opts.ExpiresAt < 0 {
+		return nil, invite.ErrInvalid
+	}
+
+	code, err := invite.NewCode()
+	if err != nil {
+		return nil, err
+	}
+
+	created := &invite.Invite{
+		Code:      code,
+		RoomId:    opts.RoomId,
+		UserId:    opts.UserId,
+		CreatedAt: opts.Now,
+		ExpiresAt: opts.ExpiresAt,
+		MaxUses:   opts.MaxUses,
+	}
+
+	_, err = i.pool.Exec(ctx, "INSERT INTO invites ("+inviteColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		created.Code, created.RoomId, created.UserId, created.CreatedAt, created.ExpiresAt, created.MaxUses, created.Uses)
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (i *InviteService) GetInviteByCode(ctx context.Context, opts invite.GetInviteByCodeOpts) (*invite.Invite, error) {
+	return scanInvite(i.pool.QueryRow(ctx, "SELECT "+inviteColumns+" FROM invites WHERE code = $1", opts.Code))
+}
+
+// Use counts a use of the invite. The invite's row is locked while it's checked, so concurrent uses can't go past its
+// max uses.
+func (i *InviteService) Use(ctx context.Context, opts invite.UseInviteOpts) (*invite.Invite, error) {
+	var used *invite.Invite
+
+	err := inTx(ctx, i.pool, func(tx pgx.Tx) error {
+		var err error
+		used, err = scanInvite(tx.QueryRow(ctx, "SELECT "+inviteColumns+" FROM invites WHERE code = $1 FOR UPDATE", opts.Code))
+		if err != nil {
+			return err
+		}
+
+		if used.Expired(opts.Now) {
+			return invite.ErrExpired
+		}
+
+		if used.UsedUp() {
+			return invite.ErrUsedUp
+		}
+
+		used.Uses++
+		_, err = tx.Exec(ctx, "UPDATE invites SET uses = $2 WHERE code = $1", opts.Code, used.Uses)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return used, nil
+}
+
+func (i *InviteService) Revoke(ctx context.Context, opts invite.RevokeInviteOpts) error {
+	tag, err := i.pool.Exec(ctx, "DELETE FROM invites WHERE code = $1", opts.Code)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return invite.ErrNotFound
+	}
+
+	return nil
+}
+
+// List returns invites oldest first.
+func (i *InviteService) List(ctx context.Context, opts invite.ListInviteOpts) ([]*invite.Invite, error) {
+	rows, err := i.pool.Query(ctx, `
+		SELECT `+inviteColumns+` FROM invites
+		WHERE $1::BIGINT = 0 OR room_id = $1
+		ORDER BY created_at, code`,
+		opts.RoomId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := make([]*invite.Invite, 0)
+	for rows.Next() {
+		inv, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		invites = append(invites, inv)
+	}
+
+	return invites, rows.Err()
+}
+
+func (i *InviteService) Export(ctx context.Context) ([]*invite.Invite, error) {
+	return i.List(ctx, invite.ListInviteOpts{})
+}
+
+// Import stores invites as they are, replacing any invite with the same code.
+func (i *InviteService) Import(ctx context.Context, invites []*invite.Invite) error {
+	return inTx(ctx, i.pool, func(tx pgx.Tx) error {
+		for _, inv := range invites {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO invites (`+inviteColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (code) DO UPDATE
+				SET room_id = EXCLUDED.room_id, user_id = EXCLUDED.user_id, created_at = EXCLUDED.created_at,
+					expires_at = EXCLUDED.expires_at, max_uses = EXCLUDED.max_uses, uses = EXCLUDED.uses`,
+				inv.Code, inv.RoomId, inv.UserId, inv.CreatedAt, inv.ExpiresAt, inv.MaxUses, inv.Uses)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// scanInvite scans a row of inviteColumns, returning invite.ErrNotFound if there isn't one.
+func scanInvite(row pgx.Row) (*invite.Invite, error) {
+	var inv invite.Invite
+
+	err := row.Scan(&inv.Code, &inv.RoomId, &inv.UserId, &inv.CreatedAt, &inv.ExpiresAt, &inv.MaxUses, &inv.Uses)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, invite.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &inv, nil
+}
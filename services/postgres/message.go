@@ -0,0 +1,284 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/worsediscord/server/services/message"
+)
+
+// messageColumns are the columns scanned by scanMessage, in order.
+const messageColumns = "id, user_id, room_id, content, timestamp, reply_to, thread_id, reply_count, last_reply_timestamp"
+
+// MessageService is a message.Service backed by the messages table.
+type MessageService struct {
+	pool *pgxpool.Pool
+}
+
+func (m *MessageService) Create(ctx context.Context, opts message.CreateMessageOpts) (*message.Message, error) {
+	// Several instances may write to the same room in the same millisecond, so ids are random rather than derived from
+	// the timestamp like message.Map.
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	msg := &message.Message{
+		Id:        base64.RawURLEncoding.EncodeToString(id),
+		UserId:    opts.UserId,
+		RoomId:    opts.RoomId,
+		Content:   opts.Content,
+		Timestamp: time.Now().UnixMilli(),
+		ReplyTo:   opts.ReplyTo,
+	}
+
+	err := inTx(ctx, m.pool, func(tx pgx.Tx) error {
+		if opts.ReplyTo != "" {
+			var roomId int64
+			var threadId string
+
+			// The parent is locked so that it can't be deleted out from under the reply.
+			err := tx.QueryRow(ctx, "SELECT room_id, thread_id FROM messages WHERE id = $1 FOR UPDATE", opts.ReplyTo).Scan(&roomId, &threadId)
+			if errors.Is(err, pgx.ErrNoRows) || (err == nil && roomId != opts.RoomId) {
+				return message.ErrReplyNotFound
+			} else if err != nil {
+				return err
+			}
+
+			msg.ThreadId = cmp.Or(threadId, opts.ReplyTo)
+
+			_, err = tx.Exec(ctx, `
+				UPDATE messages SET reply_count = reply_count + 1, last_reply_timestamp = GREATEST(last_reply_timestamp, $2)
+				WHERE id = $1`,
+				msg.ThreadId, msg.Timestamp)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO messages (id, user_id, room_id, content, timestamp, reply_to, thread_id) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			msg.Id, msg.UserId, msg.RoomId, msg.Content, msg.Timestamp, msg.ReplyTo, msg.ThreadId)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (m *MessageService) GetMessageById(ctx context.Context, opts message.GetMessageByIdOpts) (*message.Message, error) {
+	row := m.pool.QueryRow(ctx, "SELECT "+messageColumns+" FROM messages WHERE id = $1", opts.Id)
+
+	msg, err := scanMessage(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, message.ErrNotFound
+	}
+
+	return msg, err
+}
+
+// List returns messages oldest first. Filtering by room uses the index on (room_id, timestamp), and filtering by thread
+// the index on (thread_id, timestamp).
+func (m *MessageService) List(ctx context.Context, opts message.ListMessageOpts) ([]*message.Message, error) {
+	var conditions []string
+	var args []any
+
+	if opts.RoomId != 0 {
+		args = append(args, opts.RoomId)
+		conditions = append(conditions, fmt.Sprintf("room_id = $%d", len(args)))
+	}
+
+	if len(opts.UserId) > 0 {
+		args = append(args, opts.UserId)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+
+	if len(opts.ThreadId) > 0 {
+		args = append(args, opts.ThreadId)
+		conditions = append(conditions, fmt.Sprintf("thread_id = $%d", len(args)))
+	}
+
+	if opts.After != (message.Cursor{}) {
+		args = append(args, opts.After.Timestamp, opts.After.Id)
+		conditions = append(conditions, fmt.Sprintf("(timestamp, id) > ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	query := "SELECT " + messageColumns + " FROM messages"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY timestamp, id"
+
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	return m.query(ctx, query, args...)
+}
+
+// Delete deletes a message. Deleting a reply updates the counts kept on the root of its thread, while deleting a root
+// leaves its replies in place.
+func (m *MessageService) Delete(ctx context.Context, opts message.DeleteMessageOpts) error {
+	return inTx(ctx, m.pool, func(tx pgx.Tx) error {
+		var threadId string
+
+		err := tx.QueryRow(ctx, "DELETE FROM messages WHERE id = $1 RETURNING thread_id", opts.Id).Scan(&threadId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return message.ErrNotFound
+		} else if err != nil {
+			return err
+		}
+
+		if threadId == "" {
+			return nil
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE messages SET
+				reply_count          = (SELECT COUNT(*) FROM messages WHERE thread_id = $1),
+				last_reply_timestamp = (SELECT COALESCE(MAX(timestamp), 0) FROM messages WHERE thread_id = $1)
+			WHERE id = $1`,
+			threadId)
+
+		return err
+	})
+}
+
+// Purge deletes every message matching opts and returns their ids. The counts kept on the roots of threads that lost
+// replies are recounted in the same transaction.
+func (m *MessageService) Purge(ctx context.Context, opts message.PurgeMessageOpts) ([]string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0)
+
+	err := inTx(ctx, m.pool, func(tx pgx.Tx) error {
+		ids = ids[:0]
+
+		rows, err := tx.Query(ctx, `
+			DELETE FROM messages
+			WHERE ($1::BIGINT = 0 OR room_id = $1) AND ($2::TEXT = '' OR user_id = $2)
+			RETURNING id, thread_id`,
+			opts.RoomId, opts.UserId)
+		if err != nil {
+			return err
+		}
+
+		threads := make(map[string]bool)
+		for rows.Next() {
+			var id, threadId string
+			if err = rows.Scan(&id, &threadId); err != nil {
+				rows.Close()
+				return err
+			}
+
+			ids = append(ids, id)
+			if threadId != "" {
+				threads[threadId] = true
+			}
+		}
+		rows.Close()
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for threadId := range threads {
+			_, err = tx.Exec(ctx, `
+				UPDATE messages SET
+					reply_count          = (SELECT COUNT(*) FROM messages WHERE thread_id = $1),
+					last_reply_timestamp = (SELECT COALESCE(MAX(timestamp), 0) FROM messages WHERE thread_id = $1)
+				WHERE id = $1`,
+				threadId)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Anonymize clears the author of every message by opts.UserId.
+func (m *MessageService) Anonymize(ctx context.Context, opts message.AnonymizeMessageOpts) error {
+	_, err := m.pool.Exec(ctx, "UPDATE messages SET user_id = '' WHERE user_id = $1", opts.UserId)
+	return err
+}
+
+func (m *MessageService) Export(ctx context.Context) ([]*message.Message, error) {
+	return m.query(ctx, "SELECT "+messageColumns+" FROM messages ORDER BY timestamp, id")
+}
+
+// Import stores messages as they are, replacing any message with the same id.
+func (m *MessageService) Import(ctx context.Context, messages []*message.Message) error {
+	return inTx(ctx, m.pool, func(tx pgx.Tx) error {
+		for _, msg := range messages {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO messages (id, user_id, room_id, content, timestamp, reply_to, thread_id, reply_count, last_reply_timestamp)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				ON CONFLICT (id) DO UPDATE SET
+					user_id              = EXCLUDED.user_id,
+					room_id              = EXCLUDED.room_id,
+					content              = EXCLUDED.content,
+					timestamp            = EXCLUDED.timestamp,
+					reply_to             = EXCLUDED.reply_to,
+					thread_id            = EXCLUDED.thread_id,
+					reply_count          = EXCLUDED.reply_count,
+					last_reply_timestamp = EXCLUDED.last_reply_timestamp`,
+				msg.Id, msg.UserId, msg.RoomId, msg.Content, msg.Timestamp, msg.ReplyTo, msg.ThreadId, msg.ReplyCount, msg.LastReplyTimestamp)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *MessageService) query(ctx context.Context, query string, args ...any) ([]*message.Message, error) {
+	rows, err := m.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]*message.Message, 0)
+	for rows.Next() {
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+func scanMessage(row pgx.Row) (*message.Message, error) {
+	var msg message.Message
+	err := row.Scan(&msg.Id, &msg.UserId, &msg.RoomId, &msg.Content, &msg.Timestamp, &msg.ReplyTo, &msg.ThreadId, &msg.ReplyCount, &msg.LastReplyTimestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/worsediscord/server/services/message"
+)
+
+func TestMessageService(t *testing.T) {
+	m := newDB(t).Messages()
+	ctx := context.Background()
+
+	var created []*message.Message
+	for _, opts := range []message.CreateMessageOpts{
+		{UserId: "spiderman", RoomId: 1, Content: "with great power"},
+		{UserId: "venom", RoomId: 1, Content: "we are venom"},
+		{UserId: "spiderman", RoomId: 2, Content: "comes great responsibility"},
+	} {
+		msg, err := m.Create(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		created = append(created, msg)
+	}
+
+	got, err := m.GetMessageById(ctx, message.GetMessageByIdOpts{Id: created[0].Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, created[0]) {
+		t.Fatalf("got message %v, expected %v", got, created[0])
+	}
+
+	if _, err = m.GetMessageById(ctx, message.GetMessageByIdOpts{Id: "nope"}); !errors.Is(err, message.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v", err, message.ErrNotFound)
+	}
+
+	tests := map[string]struct {
+		opts          message.ListMessageOpts
+		expectedCount int
+	}{
+		"all":           {opts: message.ListMessageOpts{}, expectedCount: 3},
+		"room":          {opts: message.ListMessageOpts{RoomId: 1}, expectedCount: 2},
+		"user":          {opts: message.ListMessageOpts{UserId: "spiderman"}, expectedCount: 2},
+		"room and user": {opts: message.ListMessageOpts{RoomId: 1, UserId: "venom"}, expectedCount: 1},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			messages, err := m.List(ctx, input.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(messages) != input.expectedCount {
+				t.Fatalf("got %d messages, expected %d", len(messages), input.expectedCount)
+			}
+		})
+	}
+}
+
+func TestMessageService_ImportExport(t *testing.T) {
+	m := newDB(t).Messages()
+	ctx := context.Background()
+
+	imported := []*message.Message{
+		{Id: "a", UserId: "spiderman", RoomId: 1, Content: "with great power", Timestamp: 1},
+		{Id: "b", UserId: "venom", RoomId: 1, Content: "we are venom", Timestamp: 2},
+	}
+
+	if err := m.Import(ctx, imported); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := m.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(exported, imported) {
+		t.Fatalf("got messages %v, expected %v", exported, imported)
+	}
+}
@@ -0,0 +1,51 @@
+CREATE TABLE users (
+    username TEXT PRIMARY KEY,
+    nickname TEXT NOT NULL,
+    password TEXT NOT NULL,
+    admin    BOOLEAN NOT NULL DEFAULT false,
+    disabled BOOLEAN NOT NULL DEFAULT false
+);
+
+-- Room ids start at the same offset as room.Map so ids look the same across backends.
+CREATE SEQUENCE room_id_seq START WITH 100000000000;
+
+CREATE TABLE rooms (
+    id   BIGINT PRIMARY KEY DEFAULT nextval('room_id_seq'),
+    name TEXT NOT NULL
+);
+
+ALTER SEQUENCE room_id_seq OWNED BY rooms.id;
+
+-- member_seq and admin_seq keep Room.Users and Room.Admins in the order users joined and were promoted.
+CREATE SEQUENCE room_member_seq;
+
+CREATE TABLE room_members (
+    room_id    BIGINT NOT NULL REFERENCES rooms (id) ON DELETE CASCADE,
+    username   TEXT   NOT NULL,
+    member_seq BIGINT NOT NULL DEFAULT nextval('room_member_seq'),
+    admin_seq  BIGINT,
+    PRIMARY KEY (room_id, username)
+);
+
+CREATE INDEX room_members_username_idx ON room_members (username);
+
+CREATE TABLE messages (
+    id        TEXT PRIMARY KEY,
+    user_id   TEXT   NOT NULL,
+    room_id   BIGINT NOT NULL,
+    content   TEXT   NOT NULL,
+    timestamp BIGINT NOT NULL
+);
+
+-- Message history is read a page at a time, newest first, per room.
+CREATE INDEX messages_room_id_timestamp_idx ON messages (room_id, timestamp DESC, id DESC);
+CREATE INDEX messages_user_id_timestamp_idx ON messages (user_id, timestamp DESC);
+
+CREATE TABLE sessions (
+    token      TEXT PRIMARY KEY,
+    username   TEXT        NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX sessions_expires_at_idx ON sessions (expires_at);
+CREATE INDEX sessions_username_idx ON sessions (username);
@@ -0,0 +1,12 @@
+-- Reactions aren't tied to messages with a foreign key, the same as messages aren't tied to rooms. They're cleared by the
+-- server when a message is deleted.
+CREATE TABLE reactions (
+    message_id TEXT   NOT NULL,
+    user_id    TEXT   NOT NULL,
+    emoji      TEXT   NOT NULL,
+    timestamp  BIGINT NOT NULL,
+    PRIMARY KEY (message_id, user_id, emoji)
+);
+
+-- Reactions are read for a page of messages at a time.
+CREATE INDEX reactions_message_id_timestamp_idx ON reactions (message_id, timestamp);
@@ -0,0 +1,9 @@
+-- reply_count and last_reply_timestamp are kept on thread roots, so a page of messages doesn't need to count replies.
+ALTER TABLE messages
+    ADD COLUMN reply_to             TEXT    NOT NULL DEFAULT '',
+    ADD COLUMN thread_id            TEXT    NOT NULL DEFAULT '',
+    ADD COLUMN reply_count          INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN last_reply_timestamp BIGINT  NOT NULL DEFAULT 0;
+
+-- Threads are read a page at a time, oldest first.
+CREATE INDEX messages_thread_id_timestamp_idx ON messages (thread_id, timestamp, id) WHERE thread_id <> '';
@@ -0,0 +1,13 @@
+ALTER TABLE rooms
+    ADD COLUMN pinners   TEXT[]  NOT NULL DEFAULT '{}',
+    ADD COLUMN pin_limit INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE pins (
+    message_id TEXT   PRIMARY KEY,
+    room_id    BIGINT NOT NULL,
+    user_id    TEXT   NOT NULL,
+    timestamp  BIGINT NOT NULL
+);
+
+-- Pins are listed and counted per room, oldest first.
+CREATE INDEX pins_room_idx ON pins (room_id, timestamp);
@@ -0,0 +1,3 @@
+ALTER TABLE rooms
+    ADD COLUMN type       TEXT NOT NULL DEFAULT '',
+    ADD COLUMN direct_key TEXT UNIQUE;
@@ -0,0 +1,14 @@
+ALTER TABLE rooms ADD COLUMN visibility TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE invites (
+    code       TEXT    PRIMARY KEY,
+    room_id    BIGINT  NOT NULL,
+    user_id    TEXT    NOT NULL,
+    created_at BIGINT  NOT NULL,
+    expires_at BIGINT  NOT NULL DEFAULT 0,
+    max_uses   INTEGER NOT NULL DEFAULT 0,
+    uses       INTEGER NOT NULL DEFAULT 0
+);
+
+-- Invites are listed per room, oldest first.
+CREATE INDEX invites_room_idx ON invites (room_id, created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE rooms
+    ADD COLUMN topic TEXT NOT NULL DEFAULT '',
+    ADD COLUMN icon  TEXT NOT NULL DEFAULT '';
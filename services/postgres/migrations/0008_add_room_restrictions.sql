@@ -0,0 +1,4 @@
+-- Bans and mutes are read along with the rest of the room, so they are kept on it like pinners.
+ALTER TABLE rooms
+    ADD COLUMN bans  JSONB NOT NULL DEFAULT '[]',
+    ADD COLUMN mutes JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,20 @@
+-- The search index is kept apart from messages so that it can be rebuilt from them. Messages stored before this
+-- migration aren't found until the index is rebuilt with wdscmd search rebuild.
+CREATE TABLE search_documents (
+    message_id TEXT    PRIMARY KEY,
+    room_id    BIGINT  NOT NULL,
+    user_id    TEXT    NOT NULL,
+    timestamp  BIGINT  NOT NULL,
+    length     INTEGER NOT NULL
+);
+
+CREATE TABLE search_postings (
+    term       TEXT      NOT NULL,
+    message_id TEXT      NOT NULL REFERENCES search_documents (message_id) ON DELETE CASCADE,
+    positions  INTEGER[] NOT NULL,
+    PRIMARY KEY (term, message_id)
+);
+
+-- Prefix queries match terms with LIKE, which can only use an index built with text_pattern_ops.
+CREATE INDEX search_postings_term_pattern_idx ON search_postings (term text_pattern_ops);
+CREATE INDEX search_postings_message_id_idx ON search_postings (message_id);
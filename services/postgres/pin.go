@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/worsediscord/server/services/pin"
+)
+
+// PinService is a pin.Service backed by the pins table.
+type PinService struct {
+	pool *pgxpool.Pool
+}
+
+// Add pins a message. Pinning a message that's already pinned returns the existing pin unchanged, even if the room is
+// at its limit.
+func (p *PinService) Add(ctx context.Context, opts pin.AddPinOpts) (*pin.Pin, error) {
+	var added *pin.Pin
+
+	err := inTx(ctx, p.pool, func(tx pgx.Tx) error {
+		// Pins in the same room take turns so that two of them can't both take the last spot.
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", opts.RoomId); err != nil {
+			return err
+		}
+
+		var existing pin.Pin
+		err := tx.QueryRow(ctx, "SELECT room_id, message_id, user_id, timestamp FROM pins WHERE message_id = $1", opts.MessageId).
+			Scan(&existing.RoomId, &existing.MessageId, &existing.UserId, &existing.Timestamp)
+		if err == nil {
+			added = &existing
+			return nil
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+
+		if opts.Limit > 0 {
+			var pinned int
+			if err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM pins WHERE room_id = $1", opts.RoomId).Scan(&pinned); err != nil {
+				return err
+			}
+
+			if pinned >= opts.Limit {
+				return pin.ErrLimitReached
+			}
+		}
+
+		added = &pin.Pin{RoomId: opts.RoomId, MessageId: opts.MessageId, UserId: opts.UserId, Timestamp: time.Now().UnixMilli()}
+		_, err = tx.Exec(ctx, "INSERT INTO pins (room_id, message_id, user_id, timestamp) VALUES ($1, $2, $3, $4)",
+			added.RoomId, added.MessageId, added.UserId, added.Timestamp)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+func (p *PinService) Remove(ctx context.Context, opts pin.RemovePinOpts) error {
+	tag, err := p.pool.Exec(ctx, "DELETE FROM pins WHERE message_id = $1", opts.MessageId)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pin.ErrNotFound
+	}
+
+	return nil
+}
+
+// List returns pins oldest first.
+func (p *PinService) List(ctx context.Context, opts pin.ListPinOpts) ([]*pin.Pin, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT room_id, message_id, user_id, timestamp FROM pins
+		WHERE $1::BIGINT = 0 OR room_id = $1
+		ORDER BY timestamp, message_id`,
+		opts.RoomId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pins := make([]*pin.Pin, 0)
+	for rows.Next() {
+		var pn pin.Pin
+		if err = rows.Scan(&pn.RoomId, &pn.MessageId, &pn.UserId, &pn.Timestamp); err != nil {
+			return nil, err
+		}
+
+		pins = append(pins, &pn)
+	}
+
+	return pins, rows.Err()
+}
+
+func (p *PinService) Export(ctx context.Context) ([]*pin.Pin, error) {
+	return p.List(ctx, pin.ListPinOpts{})
+}
+
+// Import stores pins as they are, replacing any pin of the same message. Imported pins don't count against any limit.
+func (p *PinService) Import(ctx context.Context, pins []*pin.Pin) error {
+	return inTx(ctx, p.pool, func(tx pgx.Tx) error {
+		for _, pn := range pins {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO pins (room_id, message_id, user_id, timestamp) VALUES ($1, $2, $3, $4)
+				ON CONFLICT (message_id) DO UPDATE
+				SET room_id = EXCLUDED.room_id, user_id = EXCLUDED.user_id, timestamp = EXCLUDED.timestamp`,
+				pn.RoomId, pn.MessageId, pn.UserId, pn.Timestamp)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
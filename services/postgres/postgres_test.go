@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// testDSNEnv points the tests at an existing database. Everything in its public schema is dropped.
+const testDSNEnv = "WORSEDISCORD_TEST_POSTGRES_DSN"
+
+var testDSN string
+
+func TestMain(m *testing.M) {
+	dsn, stop, err := startPostgres()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "postgres tests will be skipped: %v\n", err)
+	}
+	testDSN = dsn
+
+	code := m.Run()
+	stop()
+
+	os.Exit(code)
+}
+
+// startPostgres returns the DSN in testDSNEnv if it's set. Otherwise, it starts a throwaway cluster listening on a unix
+// socket, using initdb and pg_ctl from PATH.
+func startPostgres() (string, func(), error) {
+	if dsn := os.Getenv(testDSNEnv); dsn != "" {
+		return dsn, func() {}, nil
+	}
+
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		return "", func() {}, fmt.Errorf("set %s or put initdb and pg_ctl on PATH: %w", testDSNEnv, err)
+	}
+
+	pgCtl, err := exec.LookPath("pg_ctl")
+	if err != nil {
+		return "", func() {}, fmt.Errorf("set %s or put initdb and pg_ctl on PATH: %w", testDSNEnv, err)
+	}
+
+	dir, err := os.MkdirTemp("", "wds-pg")
+	if err != nil {
+		return "", func() {}, err
+	}
+
+	data := filepath.Join(dir, "data")
+	cleanup := func() { _ = os.RemoveAll(dir) }
+
+	if out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "--auth=trust", "--no-sync").CombinedOutput(); err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("initdb failed: %w: %s", err, out)
+	}
+
+	options := fmt.Sprintf("-k %s -c listen_addresses='' -F", dir)
+	if out, err := exec.Command(pgCtl, "-D", data, "-o", options, "-w", "start").CombinedOutput(); err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("pg_ctl start failed: %w: %s", err, out)
+	}
+
+	stop := func() {
+		_ = exec.Command(pgCtl, "-D", data, "-m", "immediate", "stop").Run()
+		cleanup()
+	}
+
+	return fmt.Sprintf("host=%s user=postgres dbname=postgres sslmode=disable", dir), stop, nil
+}
+
+// newDB returns a migrated, empty database, or skips the test if there's no Postgres to test against.
+func newDB(t *testing.T) *DB {
+	t.Helper()
+
+	if testDSN == "" {
+		t.Skipf("no postgres available, set %s or put initdb and pg_ctl on PATH", testDSNEnv)
+	}
+
+	ctx := context.Background()
+
+	db, err := Open(ctx, testDSN, PoolOpts{MaxConns: 4})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if err = reset(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// reset drops everything in db and migrates it again.
+func reset(ctx context.Context, db *DB) error {
+	if _, err := db.pool.Exec(ctx, "DROP SCHEMA public CASCADE; CREATE SCHEMA public"); err != nil {
+		return fmt.Errorf("failed to reset schema: %w", err)
+	}
+
+	if err := db.Migrate(ctx); err != nil {
+		return fmt.Errorf("failed to migrate: %w", err)
+	}
+
+	return nil
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("expected at least one migration")
+	}
+
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Fatalf("got version %d for %s, expected %d", m.version, m.name, i+1)
+		}
+	}
+}
+
+func TestDB_Migrate(t *testing.T) {
+	db := newDB(t)
+	ctx := context.Background()
+
+	// Migrating an up to date database is a no-op.
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.pool.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES (9999, 'from the future')"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Migrate(ctx); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("got error %v, expected %v", err, ErrSchemaTooNew)
+	}
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/worsediscord/server/services/reaction"
+)
+
+// ReactionService is a reaction.Service backed by the reactions table.
+type ReactionService struct {
+	pool *pgxpool.Pool
+}
+
+// Add reacts to a message. Adding a reaction that already exists returns it with its original timestamp.
+func (r *ReactionService) Add(ctx context.Context, opts reaction.AddReactionOpts) (*reaction.Reaction, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	added := &reaction.Reaction{MessageId: opts.MessageId, UserId: opts.UserId, Emoji: opts.Emoji}
+
+	// The no-op update makes RETURNING produce the existing row on a conflict.
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO reactions (message_id, user_id, emoji, timestamp) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (message_id, user_id, emoji) DO UPDATE SET timestamp = reactions.timestamp
+		RETURNING timestamp`,
+		opts.MessageId, opts.UserId, opts.Emoji, time.Now().UnixMilli()).Scan(&added.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+func (r *ReactionService) Remove(ctx context.Context, opts reaction.RemoveReactionOpts) error {
+	tag, err := r.pool.Exec(ctx, "DELETE FROM reactions WHERE message_id = $1 AND user_id = $2 AND emoji = $3",
+		opts.MessageId, opts.UserId, opts.Emoji)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return reaction.ErrNotFound
+	}
+
+	return nil
+}
+
+// List returns reactions oldest first.
+func (r *ReactionService) List(ctx context.Context, opts reaction.ListReactionOpts) ([]*reaction.Reaction, error) {
+	var conditions []string
+	var args []any
+
+	if len(opts.MessageIds) > 0 {
+		args = append(args, opts.MessageIds)
+		conditions = append(conditions, fmt.Sprintf("message_id = ANY($%d)", len(args)))
+	}
+
+	if opts.UserId != "" {
+		args = append(args, opts.UserId)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+
+	if opts.Emoji != "" {
+		args = append(args, opts.Emoji)
+		conditions = append(conditions, fmt.Sprintf("emoji = $%d", len(args)))
+	}
+
+	query := "SELECT message_id, user_id, emoji, timestamp FROM reactions"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY timestamp, message_id, user_id, emoji"
+
+	return r.query(ctx, query, args...)
+}
+
+func (r *ReactionService) Clear(ctx context.Context, opts reaction.ClearReactionOpts) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	messageIds := opts.MessageIds
+	if messageIds == nil {
+		messageIds = []string{}
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		DELETE FROM reactions
+		WHERE (cardinality($1::TEXT[]) = 0 OR message_id = ANY($1)) AND ($2::TEXT = '' OR user_id = $2)`,
+		messageIds, opts.UserId)
+
+	return err
+}
+
+func (r *ReactionService) Export(ctx context.Context) ([]*reaction.Reaction, error) {
+	return r.List(ctx, reaction.ListReactionOpts{})
+}
+
+// Import stores reactions as they are, replacing any reaction by the same user with the same emoji to the same message.
+func (r *ReactionService) Import(ctx context.Context, reactions []*reaction.Reaction) error {
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		for _, rct := range reactions {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO reactions (message_id, user_id, emoji, timestamp) VALUES ($1, $2, $3, $4)
+				ON CONFLICT (message_id, user_id, emoji) DO UPDATE SET timestamp = EXCLUDED.timestamp`,
+				rct.MessageId, rct.UserId, rct.Emoji, rct.Timestamp)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *ReactionService) query(ctx context.Context, query string, args ...any) ([]*reaction.Reaction, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := make([]*reaction.Reaction, 0)
+	for rows.Next() {
+		var rct reaction.Reaction
+		if err = rows.Scan(&rct.MessageId, &rct.UserId, &rct.Emoji, &rct.Timestamp); err != nil {
+			return nil, err
+		}
+
+		reactions = append(reactions, &rct)
+	}
+
+	return reactions, rows.Err()
+}
@@ -0,0 +1,700 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/worsediscord/server/services/room"
+)
+
+// RoomService is a room.Service backed by the rooms and room_members tables.
+type RoomService struct {
+	pool *pgxpool.Pool
+}
+
+func (r *RoomService) Create(ctx context.Context, opts room.CreateRoomOpts) (*room.Room, error) {
+	created := &room.Room{Name: opts.Name, Visibility: opts.Visibility, Users: []string{opts.UserId}, Admins: []string{opts.UserId}}
+
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, "INSERT INTO rooms (name, visibility) VALUES ($1, $2) RETURNING id", opts.Name, string(opts.Visibility)).
+			Scan(&created.Id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO room_members (room_id, username, admin_seq) VALUES ($1, $2, nextval('room_member_seq'))`,
+			created.Id, opts.UserId)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// CreateDirect returns the direct message room for the users, inserting it if it doesn't exist. The unique direct_key
+// column keeps concurrent calls from creating the same room twice.
+func (r *RoomService) CreateDirect(ctx context.Context, opts room.CreateDirectRoomOpts) (*room.Room, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	key := room.DirectKey(opts.UserIds)
+
+	var id int64
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			INSERT INTO rooms (name, type, direct_key) VALUES ('', $1, $2)
+			ON CONFLICT (direct_key) DO NOTHING RETURNING id`,
+			string(room.TypeDirect), key).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tx.QueryRow(ctx, "SELECT id FROM rooms WHERE direct_key = $1", key).Scan(&id)
+		} else if err != nil {
+			return err
+		}
+
+		for _, username := range opts.UserIds {
+			if _, err = tx.Exec(ctx, "INSERT INTO room_members (room_id, username) VALUES ($1, $2)", id, username); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetRoomById(ctx, room.GetRoomByIdOpts{Id: id})
+}
+
+func (r *RoomService) GetRoomById(ctx context.Context, opts room.GetRoomByIdOpts) (*room.Room, error) {
+	rooms, err := r.list(ctx, &opts.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rooms) == 0 {
+		return nil, room.ErrNotFound
+	}
+
+	return rooms[0], nil
+}
+
+func (r *RoomService) List(ctx context.Context) ([]*room.Room, error) {
+	return r.list(ctx, nil)
+}
+
+func (r *RoomService) Delete(ctx context.Context, opts room.DeleteRoomOpts) error {
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := authorize(ctx, tx, opts.Id, opts.UserId, opts.Force); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, "DELETE FROM rooms WHERE id = $1", opts.Id)
+		return err
+	})
+}
+
+// Update changes the room's settings. Nil fields in opts are passed as NULL and left as they are by COALESCE.
+func (r *RoomService) Update(ctx context.Context, opts room.UpdateRoomOpts) (*room.Room, error) {
+	if opts.Name != nil && *opts.Name == "" {
+		return nil, room.ErrInvalidName
+	}
+
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := lockGroupRoom(ctx, tx, opts.Id); err != nil {
+			return err
+		}
+
+		if err := authorize(ctx, tx, opts.Id, opts.UserId, opts.Force); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, `
+			UPDATE rooms SET name = COALESCE($2, name), topic = COALESCE($3, topic), icon = COALESCE($4, icon)
+			WHERE id = $1`,
+			opts.Id, opts.Name, opts.Topic, opts.Icon)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetRoomById(ctx, room.GetRoomByIdOpts{Id: opts.Id})
+}
+
+func (r *RoomService) Join(ctx context.Context, opts room.JoinRoomOpts) error {
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := lockGroupRoom(ctx, tx, opts.Id); err != nil {
+			return err
+		}
+
+		if err := checkBanned(ctx, tx, opts.Id, opts.UserId, opts.Now); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO room_members (room_id, username) VALUES ($1, $2)
+			ON CONFLICT (room_id, username) DO NOTHING`,
+			opts.Id, opts.UserId)
+
+		return err
+	})
+}
+
+// Leave removes the user from the room. If no admins remain in a named room, the member with the lowest member_seq is
+// promoted, which makes them the owner. A direct message room that a user left loses its direct_key, so that
+// CreateDirect no longer returns it.
+func (r *RoomService) Leave(ctx context.Context, opts room.LeaveRoomOpts) error {
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		lock := lockGroupRoom
+		if opts.Force {
+			lock = lockRoom
+		}
+
+		if err := lock(ctx, tx, opts.Id); err != nil {
+			return err
+		}
+
+		removed, err := removeMember(ctx, tx, opts.Id, opts.UserId)
+		if err != nil || !removed {
+			return err
+		}
+
+		if _, err = tx.Exec(ctx, "UPDATE rooms SET direct_key = NULL WHERE id = $1", opts.Id); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE room_members SET admin_seq = nextval('room_member_seq')
+			WHERE room_id = $1 AND username = (SELECT username FROM room_members WHERE room_id = $1 ORDER BY member_seq LIMIT 1)
+			AND NOT EXISTS (SELECT 1 FROM room_members WHERE room_id = $1 AND admin_seq IS NOT NULL)
+			AND EXISTS (SELECT 1 FROM rooms WHERE id = $1 AND type <> $2)`,
+			opts.Id, string(room.TypeDirect))
+
+		return err
+	})
+}
+
+func (r *RoomService) Kick(ctx context.Context, opts room.KickRoomOpts) error {
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := moderate(ctx, tx, opts.Id, opts.UserId, opts.TargetId, opts.Force); err != nil {
+			return err
+		}
+
+		_, err := removeMember(ctx, tx, opts.Id, opts.TargetId)
+		return err
+	})
+}
+
+// Ban removes the user from the room like Kick and records the ban. Bans that expired by opts.Now are dropped.
+func (r *RoomService) Ban(ctx context.Context, opts room.BanRoomOpts) error {
+	if opts.ExpiresAt < 0 {
+		return room.ErrInvalidUntil
+	}
+
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := moderate(ctx, tx, opts.Id, opts.UserId, opts.TargetId, opts.Force); err != nil {
+			return err
+		}
+
+		if _, err := removeMember(ctx, tx, opts.Id, opts.TargetId); err != nil {
+			return err
+		}
+
+		return restrict(ctx, tx, opts.Id, "bans", opts.TargetId, opts.Now, &room.Restriction{
+			UserId:    opts.TargetId,
+			Reason:    opts.Reason,
+			CreatedBy: opts.UserId,
+			CreatedAt: opts.Now,
+			ExpiresAt: opts.ExpiresAt,
+		})
+	})
+}
+
+func (r *RoomService) Unban(ctx context.Context, opts room.UnbanRoomOpts) error {
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := lockGroupRoom(ctx, tx, opts.Id); err != nil {
+			return err
+		}
+
+		if err := authorize(ctx, tx, opts.Id, opts.UserId, opts.Force); err != nil {
+			return err
+		}
+
+		return restrict(ctx, tx, opts.Id, "bans", opts.TargetId, 0, nil)
+	})
+}
+
+// Mute records the mute of the user, who stays in the room. Mutes that expired by opts.Now are dropped.
+func (r *RoomService) Mute(ctx context.Context, opts room.MuteRoomOpts) error {
+	if opts.ExpiresAt < 0 {
+		return room.ErrInvalidUntil
+	}
+
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := moderate(ctx, tx, opts.Id, opts.UserId, opts.TargetId, opts.Force); err != nil {
+			return err
+		}
+
+		return restrict(ctx, tx, opts.Id, "mutes", opts.TargetId, opts.Now, &room.Restriction{
+			UserId:    opts.TargetId,
+			Reason:    opts.Reason,
+			CreatedBy: opts.UserId,
+			CreatedAt: opts.Now,
+			ExpiresAt: opts.ExpiresAt,
+		})
+	})
+}
+
+func (r *RoomService) Unmute(ctx context.Context, opts room.UnmuteRoomOpts) error {
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := lockGroupRoom(ctx, tx, opts.Id); err != nil {
+			return err
+		}
+
+		if err := authorize(ctx, tx, opts.Id, opts.UserId, opts.Force); err != nil {
+			return err
+		}
+
+		return restrict(ctx, tx, opts.Id, "mutes", opts.TargetId, 0, nil)
+	})
+}
+
+func (r *RoomService) Promote(ctx context.Context, opts room.PromoteRoomOpts) error {
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := lockGroupRoom(ctx, tx, opts.Id); err != nil {
+			return err
+		}
+
+		if err := authorize(ctx, tx, opts.Id, opts.UserId, opts.Force); err != nil {
+			return err
+		}
+
+		if err := checkBanned(ctx, tx, opts.Id, opts.TargetId, opts.Now); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `
+			UPDATE room_members SET admin_seq = COALESCE(admin_seq, nextval('room_member_seq'))
+			WHERE room_id = $1 AND username = $2`,
+			opts.Id, opts.TargetId)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return room.ErrNotMember
+		}
+
+		return nil
+	})
+}
+
+func (r *RoomService) Demote(ctx context.Context, opts room.DemoteRoomOpts) error {
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := lockGroupRoom(ctx, tx, opts.Id); err != nil {
+			return err
+		}
+
+		owner, err := roomOwner(ctx, tx, opts.Id)
+		if err != nil {
+			return err
+		}
+
+		if opts.TargetId == owner {
+			return room.ErrOwner
+		}
+
+		if !opts.Force && opts.UserId != owner && opts.UserId != opts.TargetId {
+			return room.ErrUnauthorized
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE room_members SET admin_seq = NULL WHERE room_id = $1 AND username = $2", opts.Id, opts.TargetId)
+		return err
+	})
+}
+
+// Transfer moves the new owner ahead of every other admin by giving them an admin_seq below the room's lowest one.
+func (r *RoomService) Transfer(ctx context.Context, opts room.TransferRoomOpts) error {
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := lockGroupRoom(ctx, tx, opts.Id); err != nil {
+			return err
+		}
+
+		owner, err := roomOwner(ctx, tx, opts.Id)
+		if err != nil {
+			return err
+		}
+
+		if !opts.Force && opts.UserId != owner {
+			return room.ErrUnauthorized
+		}
+
+		if opts.TargetId == owner {
+			return nil
+		}
+
+		if err = checkBanned(ctx, tx, opts.Id, opts.TargetId, opts.Now); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `
+			UPDATE room_members SET admin_seq = COALESCE(
+				(SELECT MIN(admin_seq) - 1 FROM room_members WHERE room_id = $1), nextval('room_member_seq'))
+			WHERE room_id = $1 AND username = $2`,
+			opts.Id, opts.TargetId)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return room.ErrNotMember
+		}
+
+		return nil
+	})
+}
+
+func (r *RoomService) ConfigurePins(ctx context.Context, opts room.ConfigurePinsRoomOpts) error {
+	if opts.Limit < 0 {
+		return room.ErrInvalidLimit
+	}
+
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := lockGroupRoom(ctx, tx, opts.Id); err != nil {
+			return err
+		}
+
+		if err := authorize(ctx, tx, opts.Id, opts.UserId, opts.Force); err != nil {
+			return err
+		}
+
+		pinners := opts.Pinners
+		if pinners == nil {
+			pinners = []string{}
+		}
+
+		_, err := tx.Exec(ctx, "UPDATE rooms SET pinners = $2, pin_limit = $3 WHERE id = $1", opts.Id, pinners, opts.Limit)
+		return err
+	})
+}
+
+func (r *RoomService) Export(ctx context.Context) ([]*room.Room, error) {
+	return r.List(ctx)
+}
+
+// Import stores rooms as they are, replacing any room with the same id and its members. Rooms created afterward are
+// given ids past the largest imported one.
+func (r *RoomService) Import(ctx context.Context, rooms []*room.Room) error {
+	if len(rooms) == 0 {
+		return nil
+	}
+
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		for _, rm := range rooms {
+			pinners := rm.Pinners
+			if pinners == nil {
+				pinners = []string{}
+			}
+
+			bans := rm.Bans
+			if bans == nil {
+				bans = []room.Restriction{}
+			}
+
+			mutes := rm.Mutes
+			if mutes == nil {
+				mutes = []room.Restriction{}
+			}
+
+			var key *string
+			if rm.IsDirect() {
+				k := room.DirectKey(rm.Users)
+				key = &k
+			}
+
+			_, err := tx.Exec(ctx, `
+				INSERT INTO rooms (id, name, pinners, pin_limit, type, direct_key, visibility, topic, icon, bans, mutes)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+				ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, pinners = EXCLUDED.pinners, pin_limit = EXCLUDED.pin_limit,
+					type = EXCLUDED.type, direct_key = EXCLUDED.direct_key, visibility = EXCLUDED.visibility,
+					topic = EXCLUDED.topic, icon = EXCLUDED.icon, bans = EXCLUDED.bans, mutes = EXCLUDED.mutes`,
+				rm.Id, rm.Name, pinners, rm.PinLimit, string(rm.Type), key, string(rm.Visibility), rm.Topic, rm.Icon, bans, mutes)
+			if err != nil {
+				return err
+			}
+
+			if _, err = tx.Exec(ctx, "DELETE FROM room_members WHERE room_id = $1", rm.Id); err != nil {
+				return err
+			}
+
+			for _, username := range rm.Users {
+				if _, err = tx.Exec(ctx, "INSERT INTO room_members (room_id, username) VALUES ($1, $2) ON CONFLICT DO NOTHING", rm.Id, username); err != nil {
+					return err
+				}
+			}
+
+			for _, username := range rm.Admins {
+				_, err = tx.Exec(ctx, `
+					INSERT INTO room_members (room_id, username, admin_seq) VALUES ($1, $2, nextval('room_member_seq'))
+					ON CONFLICT (room_id, username) DO UPDATE SET admin_seq = EXCLUDED.admin_seq`,
+					rm.Id, username)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err := tx.Exec(ctx, `
+			SELECT setval('room_id_seq', GREATEST(MAX(id), (SELECT last_value FROM room_id_seq)))
+			FROM rooms`)
+
+		return err
+	})
+}
+
+// list returns the room with id, or every room if id is nil.
+func (r *RoomService) list(ctx context.Context, id *int64) ([]*room.Room, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT r.id, r.name, r.type, r.visibility, r.topic, r.icon, r.pinners, r.pin_limit, r.bans, r.mutes, m.username, m.admin_seq
+		FROM rooms r LEFT JOIN room_members m ON m.room_id = r.id
+		WHERE $1::BIGINT IS NULL OR r.id = $1
+		ORDER BY r.id, m.member_seq`,
+		id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type admin struct {
+		username string
+		seq      int64
+	}
+
+	rooms := make([]*room.Room, 0)
+	admins := make(map[int64][]admin)
+
+	for rows.Next() {
+		var roomId int64
+		var name string
+		var roomType string
+		var visibility string
+		var topic string
+		var icon string
+		var pinners []string
+		var pinLimit int
+		var bans []room.Restriction
+		var mutes []room.Restriction
+		var username *string
+		var adminSeq *int64
+
+		if err = rows.Scan(&roomId, &name, &roomType, &visibility, &topic, &icon, &pinners, &pinLimit, &bans, &mutes, &username, &adminSeq); err != nil {
+			return nil, err
+		}
+
+		if len(rooms) == 0 || rooms[len(rooms)-1].Id != roomId {
+			if len(pinners) == 0 {
+				pinners = nil
+			}
+
+			if len(bans) == 0 {
+				bans = nil
+			}
+
+			if len(mutes) == 0 {
+				mutes = nil
+			}
+
+			rooms = append(rooms, &room.Room{Id: roomId, Name: name, Type: room.Type(roomType), Visibility: room.Visibility(visibility), Topic: topic, Icon: icon, Users: []string{}, Admins: []string{}, Pinners: pinners, PinLimit: pinLimit, Bans: bans, Mutes: mutes})
+		}
+
+		if username == nil {
+			continue
+		}
+
+		current := rooms[len(rooms)-1]
+		current.Users = append(current.Users, *username)
+
+		if adminSeq != nil {
+			admins[roomId] = append(admins[roomId], admin{username: *username, seq: *adminSeq})
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, rm := range rooms {
+		slices.SortFunc(admins[rm.Id], func(a, b admin) int {
+			return int(a.seq - b.seq)
+		})
+
+		for _, a := range admins[rm.Id] {
+			rm.Admins = append(rm.Admins, a.username)
+		}
+	}
+
+	return rooms, nil
+}
+
+// lockRoom locks the room with id until the end of the transaction.
+func lockRoom(ctx context.Context, tx pgx.Tx, id int64) error {
+	var locked int64
+	if err := tx.QueryRow(ctx, "SELECT id FROM rooms WHERE id = $1 FOR UPDATE", id).Scan(&locked); errors.Is(err, pgx.ErrNoRows) {
+		return room.ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// lockGroupRoom locks the room with id like lockRoom, returning room.ErrDirect if it is a direct message room.
+func lockGroupRoom(ctx context.Context, tx pgx.Tx, id int64) error {
+	var roomType string
+	if err := tx.QueryRow(ctx, "SELECT type FROM rooms WHERE id = $1 FOR UPDATE", id).Scan(&roomType); errors.Is(err, pgx.ErrNoRows) {
+		return room.ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if room.Type(roomType) == room.TypeDirect {
+		return room.ErrDirect
+	}
+
+	return nil
+}
+
+// checkBanned returns room.ErrBanned if userId is banned from the room with id at now.
+func checkBanned(ctx context.Context, tx pgx.Tx, id int64, userId string, now int64) error {
+	var bans []room.Restriction
+	if err := tx.QueryRow(ctx, "SELECT bans FROM rooms WHERE id = $1", id).Scan(&bans); err != nil {
+		return err
+	}
+
+	if (&room.Room{Bans: bans}).Banned(userId, now) {
+		return room.ErrBanned
+	}
+
+	return nil
+}
+
+// roomOwner returns the admin of the room with id that has the lowest admin_seq, or an empty string if it has no
+// admins.
+func roomOwner(ctx context.Context, tx pgx.Tx, id int64) (string, error) {
+	var username string
+	err := tx.QueryRow(ctx, `
+		SELECT username FROM room_members WHERE room_id = $1 AND admin_seq IS NOT NULL
+		ORDER BY admin_seq LIMIT 1`,
+		id).Scan(&username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+
+	return username, err
+}
+
+// authorize locks the room with id and checks that userId is one of its admins, unless force is set.
+func authorize(ctx context.Context, tx pgx.Tx, id int64, userId string, force bool) error {
+	if err := lockRoom(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if force {
+		return nil
+	}
+
+	var isAdmin bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM room_members WHERE room_id = $1 AND username = $2 AND admin_seq IS NOT NULL)`,
+		id, userId).Scan(&isAdmin)
+	if err != nil {
+		return err
+	}
+
+	if !isAdmin {
+		return room.ErrUnauthorized
+	}
+
+	return nil
+}
+
+// moderate locks the group room with id and checks that userId may kick, ban or mute targetId in it, like room.Map
+// does.
+func moderate(ctx context.Context, tx pgx.Tx, id int64, userId string, targetId string, force bool) error {
+	if err := lockGroupRoom(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := authorize(ctx, tx, id, userId, force); err != nil {
+		return err
+	}
+
+	owner, err := roomOwner(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if targetId == owner {
+		return room.ErrOwner
+	}
+
+	if force || userId == owner {
+		return nil
+	}
+
+	var isAdmin bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM room_members WHERE room_id = $1 AND username = $2 AND admin_seq IS NOT NULL)`,
+		id, targetId).Scan(&isAdmin)
+	if err != nil {
+		return err
+	}
+
+	if isAdmin {
+		return room.ErrUnauthorized
+	}
+
+	return nil
+}
+
+// removeMember removes userId from the members, admins and pinners of the room with id. It reports whether userId was
+// a member.
+func removeMember(ctx context.Context, tx pgx.Tx, id int64, userId string) (bool, error) {
+	tag, err := tx.Exec(ctx, "DELETE FROM room_members WHERE room_id = $1 AND username = $2", id, userId)
+	if err != nil || tag.RowsAffected() == 0 {
+		return false, err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE rooms SET pinners = array_remove(pinners, $2) WHERE id = $1", id, userId)
+	return true, err
+}
+
+// restrict drops the restrictions of userId, and those that expired by now unless it's zero, from column of the room
+// with id. added is appended afterward if it isn't nil. column must be bans or mutes.
+func restrict(ctx context.Context, tx pgx.Tx, id int64, column string, userId string, now int64, added *room.Restriction) error {
+	restrictions := []room.Restriction{}
+	if added != nil {
+		restrictions = append(restrictions, *added)
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE rooms SET `+column+` = (
+			SELECT COALESCE(jsonb_agg(r), '[]') FROM jsonb_array_elements(`+column+`) r
+			WHERE r->>'UserId' <> $2
+			AND ($3::BIGINT = 0 OR (r->>'ExpiresAt')::BIGINT = 0 OR (r->>'ExpiresAt')::BIGINT > $3)
+		) || $4::JSONB
+		WHERE id = $1`,
+		id, userId, now, restrictions)
+
+	return err
+}
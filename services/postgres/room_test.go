@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/worsediscord/server/services/room"
+)
+
+func TestRoomService(t *testing.T) {
+	r := newDB(t).Rooms()
+	ctx := context.Background()
+
+	created, err := r.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.Id != 100000000000 {
+		t.Fatalf("got id %d, expected %d", created.Id, 100000000000)
+	}
+
+	for _, username := range []string{"venom", "batman", "venom"} {
+		if err = r.Join(ctx, room.JoinRoomOpts{Id: created.Id, UserId: username}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = r.Promote(ctx, room.PromoteRoomOpts{Id: created.Id, UserId: "spiderman", TargetId: "batman"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = r.Promote(ctx, room.PromoteRoomOpts{Id: created.Id, TargetId: "robin", Force: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = r.Promote(ctx, room.PromoteRoomOpts{Id: created.Id, UserId: "venom", TargetId: "venom"}); !errors.Is(err, room.ErrUnauthorized) {
+		t.Fatalf("got error %v, expected %v", err, room.ErrUnauthorized)
+	}
+
+	got, err := r.GetRoomById(ctx, room.GetRoomByIdOpts{Id: created.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &room.Room{
+		Id:     created.Id,
+		Name:   "the big apple",
+		Users:  []string{"spiderman", "venom", "batman", "robin"},
+		Admins: []string{"spiderman", "batman", "robin"},
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got room %v, expected %v", got, expected)
+	}
+
+	tests := map[string]struct {
+		opts        room.DeleteRoomOpts
+		expectedErr error
+	}{
+		"unauthorized": {
+			opts:        room.DeleteRoomOpts{Id: created.Id, UserId: "venom"},
+			expectedErr: room.ErrUnauthorized,
+		},
+		"not found": {
+			opts:        room.DeleteRoomOpts{Id: 1, UserId: "spiderman"},
+			expectedErr: room.ErrNotFound,
+		},
+		"valid": {
+			opts:        room.DeleteRoomOpts{Id: created.Id, UserId: "batman"},
+			expectedErr: nil,
+		},
+	}
+
+	for _, name := range []string{"unauthorized", "not found", "valid"} {
+		input := tests[name]
+		t.Run(name, func(t *testing.T) {
+			if err := r.Delete(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %q, expected %q", err, input.expectedErr)
+			}
+		})
+	}
+
+	rooms, err := r.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rooms) != 0 {
+		t.Fatalf("got rooms %v, expected none", rooms)
+	}
+}
+
+func TestRoomService_ImportExport(t *testing.T) {
+	r := newDB(t).Rooms()
+	ctx := context.Background()
+
+	imported := []*room.Room{
+		{Id: 100000000001, Name: "gotham", Users: []string{"batman"}, Admins: []string{"batman"}},
+		{Id: 100000000004, Name: "the big apple", Users: []string{"spiderman", "venom"}, Admins: []string{"venom", "spiderman"}},
+	}
+
+	if err := r.Import(ctx, imported); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := r.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(exported, imported) {
+		t.Fatalf("got rooms %v, expected %v", exported, imported)
+	}
+
+	created, err := r.Create(ctx, room.CreateRoomOpts{Name: "metropolis", UserId: "superman"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.Id != 100000000005 {
+		t.Fatalf("got id %d, expected %d", created.Id, 100000000005)
+	}
+}
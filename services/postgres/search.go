@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/search"
+)
+
+// SearchService is a search.Service backed by the search_documents and search_postings tables.
+type SearchService struct {
+	pool *pgxpool.Pool
+}
+
+func (s *SearchService) Index(ctx context.Context, opts search.IndexOpts) error {
+	return inTx(ctx, s.pool, func(tx pgx.Tx) error {
+		return indexMessages(ctx, tx, opts.Messages)
+	})
+}
+
+// Remove removes messages from the index. Their postings go with them through the foreign key.
+func (s *SearchService) Remove(ctx context.Context, opts search.RemoveOpts) error {
+	if len(opts.MessageIds) == 0 {
+		return nil
+	}
+
+	_, err := s.pool.Exec(ctx, "DELETE FROM search_documents WHERE message_id = ANY($1)", opts.MessageIds)
+
+	return err
+}
+
+// Search reads the index in a single repeatable read transaction, so that messages indexed or removed meanwhile don't
+// skew the ranking.
+func (s *SearchService) Search(ctx context.Context, opts search.SearchOpts) ([]*search.Result, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	results, err := search.Find(ctx, searchCorpus{tx: tx}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, tx.Commit(ctx)
+}
+
+// Rebuild replaces everything indexed with messages.
+func (s *SearchService) Rebuild(ctx context.Context, messages []*message.Message) error {
+	return inTx(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "TRUNCATE search_documents, search_postings"); err != nil {
+			return err
+		}
+
+		return indexMessages(ctx, tx, messages)
+	})
+}
+
+// indexMessages indexes messages in tx, replacing any that are already indexed.
+func indexMessages(ctx context.Context, tx pgx.Tx, messages []*message.Message) error {
+	for _, msg := range messages {
+		if _, err := tx.Exec(ctx, "DELETE FROM search_documents WHERE message_id = $1", msg.Id); err != nil {
+			return err
+		}
+
+		doc, terms := search.NewDocument(msg)
+
+		_, err := tx.Exec(ctx, "INSERT INTO search_documents (message_id, room_id, user_id, timestamp, length) VALUES ($1, $2, $3, $4, $5)",
+			doc.MessageId, doc.RoomId, doc.UserId, doc.Timestamp, doc.Length)
+		if err != nil {
+			return err
+		}
+
+		for term, positions := range search.Positions(terms) {
+			_, err = tx.Exec(ctx, "INSERT INTO search_postings (term, message_id, positions) VALUES ($1, $2, $3)", term, msg.Id, positions)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// searchCorpus reads the index for search.Find within a transaction.
+type searchCorpus struct {
+	tx pgx.Tx
+}
+
+// Postings matches prefixes with LIKE. Terms are only letters and digits, so they never hold a LIKE wildcard.
+func (c searchCorpus) Postings(ctx context.Context, term string, prefix bool) (search.Postings, error) {
+	condition := "term = $1"
+	if prefix {
+		condition, term = "term LIKE $1", term+"%"
+	}
+
+	rows, err := c.tx.Query(ctx, "SELECT message_id, positions FROM search_postings WHERE "+condition, term)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	postings := make(search.Postings)
+	for rows.Next() {
+		var id string
+		var positions []int
+		if err = rows.Scan(&id, &positions); err != nil {
+			return nil, err
+		}
+
+		postings[id] = append(postings[id], positions...)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A prefix can match several terms of the same message.
+	if prefix {
+		for _, positions := range postings {
+			slices.Sort(positions)
+		}
+	}
+
+	return postings, nil
+}
+
+func (c searchCorpus) Documents(ctx context.Context, ids []string) ([]*search.Document, error) {
+	rows, err := c.tx.Query(ctx, "SELECT message_id, room_id, user_id, timestamp, length FROM search_documents WHERE message_id = ANY($1)", ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := make([]*search.Document, 0, len(ids))
+	for rows.Next() {
+		var doc search.Document
+		if err = rows.Scan(&doc.MessageId, &doc.RoomId, &doc.UserId, &doc.Timestamp, &doc.Length); err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, &doc)
+	}
+
+	return docs, rows.Err()
+}
+
+func (c searchCorpus) Stats(ctx context.Context) (int, int, error) {
+	var count, length int
+	err := c.tx.QueryRow(ctx, "SELECT COUNT(*), COALESCE(SUM(length), 0) FROM search_documents").Scan(&count, &length)
+
+	return count, length, err
+}
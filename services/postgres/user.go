@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/worsediscord/server/services/user"
+)
+
+// UserService is a user.Service backed by the users table.
+type UserService struct {
+	pool *pgxpool.Pool
+}
+
+func (u *UserService) Create(ctx context.Context, opts user.CreateUserOpts) error {
+	// Conflicts are reported before invalid input, the same as user.Map.
+	if _, err := u.GetUserById(ctx, user.GetUserByIdOpts{Id: opts.Username}); err == nil {
+		return user.ErrConflict
+	} else if !errors.Is(err, user.ErrNotFound) {
+		return err
+	}
+
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	tag, err := u.pool.Exec(ctx, `
+		INSERT INTO users (username, nickname, password) VALUES ($1, $1, $2)
+		ON CONFLICT (username) DO NOTHING`,
+		opts.Username, opts.Password)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return user.ErrConflict
+	}
+
+	return nil
+}
+
+func (u *UserService) GetUserById(ctx context.Context, opts user.GetUserByIdOpts) (*user.User, error) {
+	row := u.pool.QueryRow(ctx, "SELECT username, nickname, password, admin, disabled FROM users WHERE username = $1", opts.Id)
+
+	usr, err := scanUser(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, user.ErrNotFound
+	}
+
+	return usr, err
+}
+
+func (u *UserService) List(ctx context.Context) ([]*user.User, error) {
+	rows, err := u.pool.Query(ctx, "SELECT username, nickname, password, admin, disabled FROM users ORDER BY username")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*user.User, 0)
+	for rows.Next() {
+		usr, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, usr)
+	}
+
+	return users, rows.Err()
+}
+
+func (u *UserService) Update(ctx context.Context, opts user.UpdateUserOpts) error {
+	if err := opts.Validate(); err != nil {
+		// Missing users are reported before invalid input, the same as user.Map.
+		if _, getErr := u.GetUserById(ctx, user.GetUserByIdOpts{Id: opts.Id}); getErr != nil {
+			return getErr
+		}
+
+		return err
+	}
+
+	tag, err := u.pool.Exec(ctx, `
+		UPDATE users SET
+			password = COALESCE($2, password),
+			admin    = COALESCE($3, admin),
+			disabled = COALESCE($4, disabled)
+		WHERE username = $1`,
+		opts.Id, opts.Password, opts.Admin, opts.Disabled)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return user.ErrNotFound
+	}
+
+	return nil
+}
+
+func (u *UserService) Delete(ctx context.Context, opts user.DeleteUserOpts) error {
+	_, err := u.pool.Exec(ctx, "DELETE FROM users WHERE username = $1", opts.Id)
+	return err
+}
+
+func (u *UserService) Export(ctx context.Context) ([]*user.User, error) {
+	return u.List(ctx)
+}
+
+// Import stores users as they are, replacing any user with the same username.
+func (u *UserService) Import(ctx context.Context, users []*user.User) error {
+	return inTx(ctx, u.pool, func(tx pgx.Tx) error {
+		for _, usr := range users {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO users (username, nickname, password, admin, disabled) VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (username) DO UPDATE SET
+					nickname = EXCLUDED.nickname,
+					password = EXCLUDED.password,
+					admin    = EXCLUDED.admin,
+					disabled = EXCLUDED.disabled`,
+				usr.Username, usr.Nickname, usr.Password, usr.Admin, usr.Disabled)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func scanUser(row pgx.Row) (*user.User, error) {
+	var usr user.User
+	if err := row.Scan(&usr.Username, &usr.Nickname, &usr.Password, &usr.Admin, &usr.Disabled); err != nil {
+		return nil, err
+	}
+
+	return &usr, nil
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/worsediscord/server/services/user"
+)
+
+func TestUserService_Create(t *testing.T) {
+	u := newDB(t).Users()
+
+	tests := map[string]struct {
+		opts        user.CreateUserOpts
+		expectedErr error
+	}{
+		"initial valid": {
+			opts:        user.CreateUserOpts{Username: "spiderman", Password: "uncleben123"},
+			expectedErr: nil,
+		},
+		"duplicate user": {
+			opts:        user.CreateUserOpts{Username: "spiderman", Password: "ben"},
+			expectedErr: user.ErrConflict,
+		},
+		"invalid user": {
+			opts:        user.CreateUserOpts{Username: "", Password: "uncleben123"},
+			expectedErr: user.ErrInvalidUsername,
+		},
+		"invalid password": {
+			opts:        user.CreateUserOpts{Username: "spiderman2", Password: "ben"},
+			expectedErr: user.ErrInvalidPassword,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := u.Create(context.Background(), input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %q, expected %q", err, input.expectedErr)
+			}
+		})
+	}
+}
+
+func TestUserService_Update(t *testing.T) {
+	u := newDB(t).Users()
+	ctx := context.Background()
+
+	if err := u.Create(ctx, user.CreateUserOpts{Username: "spiderman", Password: "uncleben123"}); err != nil {
+		t.Fatalf("failed to prepopulate users: %v", err)
+	}
+
+	admin, password, short := true, "maryjane123", "mj"
+
+	tests := map[string]struct {
+		opts         user.UpdateUserOpts
+		expectedUser *user.User
+		expectedErr  error
+	}{
+		"admin": {
+			opts:         user.UpdateUserOpts{Id: "spiderman", Admin: &admin},
+			expectedUser: &user.User{Username: "spiderman", Nickname: "spiderman", Password: "uncleben123", Admin: true},
+		},
+		"password": {
+			opts:         user.UpdateUserOpts{Id: "spiderman", Password: &password},
+			expectedUser: &user.User{Username: "spiderman", Nickname: "spiderman", Password: "maryjane123", Admin: true},
+		},
+		"invalid password": {
+			opts:        user.UpdateUserOpts{Id: "spiderman", Password: &short},
+			expectedErr: user.ErrInvalidPassword,
+		},
+		"not found": {
+			opts:        user.UpdateUserOpts{Id: "venom", Password: &short},
+			expectedErr: user.ErrNotFound,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := u.Update(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %q, expected %q", err, input.expectedErr)
+			}
+
+			if input.expectedUser == nil {
+				return
+			}
+
+			got, err := u.GetUserById(ctx, user.GetUserByIdOpts{Id: input.opts.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, input.expectedUser) {
+				t.Fatalf("got user %v, expected %v", got, input.expectedUser)
+			}
+		})
+	}
+}
+
+func TestUserService_ImportExport(t *testing.T) {
+	u := newDB(t).Users()
+	ctx := context.Background()
+
+	imported := []*user.User{
+		{Username: "spiderman", Nickname: "spidey", Password: "uncleben123", Admin: true},
+		{Username: "venom", Nickname: "venom", Password: "wearevenom", Disabled: true},
+	}
+
+	if err := u.Import(ctx, imported); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := u.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(exported, imported) {
+		t.Fatalf("got users %v, expected %v", exported, imported)
+	}
+
+	if err = u.Delete(ctx, user.DeleteUserOpts{Id: "venom"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = u.GetUserById(ctx, user.GetUserByIdOpts{Id: "venom"}); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v", err, user.ErrNotFound)
+	}
+}
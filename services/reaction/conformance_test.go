@@ -0,0 +1,12 @@
+package reaction_test
+
+import (
+	"testing"
+
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/reaction/reactiontest"
+)
+
+func TestMap_Conformance(t *testing.T) {
+	reactiontest.Run(t, func(*testing.T) reaction.Service { return reaction.NewMap() })
+}
@@ -0,0 +1,106 @@
+package reaction
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	keycap              = '\u20e3'
+	variationSelector16 = '\ufe0f'
+)
+
+// emojiTable holds the code points that are emoji on their own, from the Unicode emoji data. Whole blocks are included
+// where most of the block is emoji, so that newer emoji are accepted before this table is updated.
+var emojiTable = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00a9, Hi: 0x00a9, Stride: 1},
+		{Lo: 0x00ae, Hi: 0x00ae, Stride: 1},
+		{Lo: 0x203c, Hi: 0x203c, Stride: 1},
+		{Lo: 0x2049, Hi: 0x2049, Stride: 1},
+		{Lo: 0x2122, Hi: 0x2122, Stride: 1},
+		{Lo: 0x2139, Hi: 0x2139, Stride: 1},
+		{Lo: 0x2194, Hi: 0x2199, Stride: 1},
+		{Lo: 0x21a9, Hi: 0x21aa, Stride: 1},
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x2328, Hi: 0x2328, Stride: 1},
+		{Lo: 0x23cf, Hi: 0x23cf, Stride: 1},
+		{Lo: 0x23e9, Hi: 0x23f3, Stride: 1},
+		{Lo: 0x23f8, Hi: 0x23fa, Stride: 1},
+		{Lo: 0x24c2, Hi: 0x24c2, Stride: 1},
+		{Lo: 0x25aa, Hi: 0x25ab, Stride: 1},
+		{Lo: 0x25b6, Hi: 0x25b6, Stride: 1},
+		{Lo: 0x25c0, Hi: 0x25c0, Stride: 1},
+		{Lo: 0x25fb, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2600, Hi: 0x27bf, Stride: 1},
+		{Lo: 0x2934, Hi: 0x2935, Stride: 1},
+		{Lo: 0x2b05, Hi: 0x2b07, Stride: 1},
+		{Lo: 0x2b1b, Hi: 0x2b1c, Stride: 1},
+		{Lo: 0x2b50, Hi: 0x2b50, Stride: 1},
+		{Lo: 0x2b55, Hi: 0x2b55, Stride: 1},
+		{Lo: 0x3030, Hi: 0x3030, Stride: 1},
+		{Lo: 0x303d, Hi: 0x303d, Stride: 1},
+		{Lo: 0x3297, Hi: 0x3297, Stride: 1},
+		{Lo: 0x3299, Hi: 0x3299, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		// Mahjong tiles through Symbols and Pictographs Extended-A, which includes flags and skin tones.
+		{Lo: 0x1f000, Hi: 0x1faff, Stride: 1},
+	},
+	LatinOffset: 2,
+}
+
+// componentTable holds the code points that only appear within an emoji: the zero width joiner, the keycap, variation
+// selectors and the tags of subdivision flags.
+var componentTable = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x200d, Hi: 0x200d, Stride: 1},
+		{Lo: keycap, Hi: keycap, Stride: 1},
+		{Lo: 0xfe0e, Hi: variationSelector16, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0xe0020, Hi: 0xe007f, Stride: 1},
+	},
+}
+
+// validateEmoji checks that emoji is a single emoji or a short sequence of them. Every code point must be an emoji or an
+// emoji component, and digits, # and * are only allowed as the base of a keycap.
+func validateEmoji(emoji string) error {
+	if emoji == "" || utf8.RuneCountInString(emoji) > maxEmojiLength || !utf8.ValidString(emoji) {
+		return ErrInvalidEmoji
+	}
+
+	runes := []rune(emoji)
+	pictographic := false
+
+	for i, r := range runes {
+		switch {
+		case unicode.Is(emojiTable, r):
+			pictographic = true
+		case r == '#' || r == '*' || ('0' <= r && r <= '9'):
+			if !isKeycap(runes[i+1:]) {
+				return ErrInvalidEmoji
+			}
+
+			pictographic = true
+		case unicode.Is(componentTable, r):
+		default:
+			return ErrInvalidEmoji
+		}
+	}
+
+	if !pictographic {
+		return ErrInvalidEmoji
+	}
+
+	return nil
+}
+
+// isKeycap reports whether rest, which follows a keycap base, completes the keycap.
+func isKeycap(rest []rune) bool {
+	if len(rest) > 0 && rest[0] == variationSelector16 {
+		rest = rest[1:]
+	}
+
+	return len(rest) > 0 && rest[0] == keycap
+}
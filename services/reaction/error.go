@@ -0,0 +1,9 @@
+package reaction
+
+import "errors"
+
+var (
+	ErrNotFound     = errors.New("no reaction found")
+	ErrInvalidEmoji = errors.New("emoji is invalid")
+	ErrInvalidClear = errors.New("clearing reactions needs messages or a user")
+)
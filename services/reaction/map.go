@@ -0,0 +1,183 @@
+package reaction
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/eolso/threadsafe"
+)
+
+type Map struct {
+	// data holds the reactions to each message by message id, so that listing the reactions of a page of messages
+	// doesn't scan every reaction.
+	data *threadsafe.Map[string, []*Reaction]
+
+	// lock serializes writes so that concurrent reactions to the same message aren't lost. Stored slices are replaced
+	// rather than modified, since callers may be reading them.
+	lock sync.Mutex
+}
+
+func NewMap() *Map {
+	return &Map{
+		data: threadsafe.NewMap[string, []*Reaction](),
+	}
+}
+
+// Add reacts to a message. Adding a reaction that already exists returns it unchanged.
+func (m *Map) Add(_ context.Context, opts AddReactionOpts) (*Reaction, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	reactions, _ := m.data.Get(opts.MessageId)
+	if i := index(reactions, opts.UserId, opts.Emoji); i >= 0 {
+		return reactions[i], nil
+	}
+
+	r := &Reaction{MessageId: opts.MessageId, UserId: opts.UserId, Emoji: opts.Emoji, Timestamp: time.Now().UnixMilli()}
+	m.data.Set(opts.MessageId, append(slices.Clip(reactions), r))
+
+	return r, nil
+}
+
+func (m *Map) Remove(_ context.Context, opts RemoveReactionOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	reactions, _ := m.data.Get(opts.MessageId)
+	i := index(reactions, opts.UserId, opts.Emoji)
+	if i < 0 {
+		return ErrNotFound
+	}
+
+	m.set(opts.MessageId, slices.Delete(slices.Clone(reactions), i, i+1))
+
+	return nil
+}
+
+// List returns reactions oldest first.
+func (m *Map) List(_ context.Context, opts ListReactionOpts) ([]*Reaction, error) {
+	var candidates [][]*Reaction
+	if len(opts.MessageIds) > 0 {
+		ids := slices.Compact(slices.Sorted(slices.Values(opts.MessageIds)))
+		for _, id := range ids {
+			if reactions, ok := m.data.Get(id); ok {
+				candidates = append(candidates, reactions)
+			}
+		}
+	} else {
+		candidates = m.data.Values()
+	}
+
+	reactions := make([]*Reaction, 0)
+	for _, r := range slices.Concat(candidates...) {
+		if opts.UserId != "" && r.UserId != opts.UserId {
+			continue
+		}
+
+		if opts.Emoji != "" && r.Emoji != opts.Emoji {
+			continue
+		}
+
+		reactions = append(reactions, r)
+	}
+
+	sortReactions(reactions)
+
+	return reactions, nil
+}
+
+func (m *Map) Clear(_ context.Context, opts ClearReactionOpts) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ids := opts.MessageIds
+	if len(ids) == 0 {
+		ids = m.data.Keys()
+	}
+
+	for _, id := range ids {
+		reactions, ok := m.data.Get(id)
+		if !ok {
+			continue
+		}
+
+		if opts.UserId == "" {
+			m.data.Delete(id)
+			continue
+		}
+
+		m.set(id, slices.DeleteFunc(slices.Clone(reactions), func(r *Reaction) bool {
+			return r.UserId == opts.UserId
+		}))
+	}
+
+	return nil
+}
+
+// Export returns every reaction to every message.
+func (m *Map) Export(ctx context.Context) ([]*Reaction, error) {
+	return m.List(ctx, ListReactionOpts{})
+}
+
+// Import stores reactions as they are, replacing any reaction by the same user with the same emoji to the same message.
+func (m *Map) Import(_ context.Context, reactions []*Reaction) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, r := range reactions {
+		imported := *r
+
+		existing, _ := m.data.Get(r.MessageId)
+		updated := slices.Clone(existing)
+		if i := index(existing, r.UserId, r.Emoji); i >= 0 {
+			updated[i] = &imported
+		} else {
+			updated = append(updated, &imported)
+		}
+
+		m.data.Set(r.MessageId, updated)
+	}
+
+	return nil
+}
+
+// set stores the reactions to messageId, removing the message if it has none left. m.lock must be held.
+func (m *Map) set(messageId string, reactions []*Reaction) {
+	if len(reactions) == 0 {
+		m.data.Delete(messageId)
+		return
+	}
+
+	m.data.Set(messageId, reactions)
+}
+
+// index returns the index of userId's reaction with emoji in reactions, or -1 if there isn't one.
+func index(reactions []*Reaction, userId string, emoji string) int {
+	return slices.IndexFunc(reactions, func(r *Reaction) bool {
+		return r.UserId == userId && r.Emoji == emoji
+	})
+}
+
+// sortReactions orders reactions by timestamp. Reactions in the same millisecond are ordered by message, user and then
+// emoji so that the order is stable.
+func sortReactions(reactions []*Reaction) {
+	slices.SortFunc(reactions, func(a, b *Reaction) int {
+		return cmp.Or(
+			cmp.Compare(a.Timestamp, b.Timestamp),
+			cmp.Compare(a.MessageId, b.MessageId),
+			cmp.Compare(a.UserId, b.UserId),
+			cmp.Compare(a.Emoji, b.Emoji),
+		)
+	})
+}
@@ -0,0 +1,165 @@
+package reaction
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewMap(t *testing.T) {
+	if NewMap() == nil {
+		t.Fatal("constructor returned nil")
+	}
+}
+
+func TestMap_Add(t *testing.T) {
+	m := NewMap()
+
+	tests := map[string]struct {
+		opts        AddReactionOpts
+		expectedErr error
+	}{
+		"valid": {
+			opts:        AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "🕷️"},
+			expectedErr: nil,
+		},
+		"empty emoji": {
+			opts:        AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: ""},
+			expectedErr: ErrInvalidEmoji,
+		},
+		"keycap": {
+			opts:        AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "1️⃣"},
+			expectedErr: nil,
+		},
+		"flag": {
+			opts:        AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "🇺🇸"},
+			expectedErr: nil,
+		},
+		"skin tone": {
+			opts:        AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "👍🏽"},
+			expectedErr: nil,
+		},
+		"joined": {
+			opts:        AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "👨‍👩‍👧"},
+			expectedErr: nil,
+		},
+		"text": {
+			opts:        AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "pizza"},
+			expectedErr: ErrInvalidEmoji,
+		},
+		"text after an emoji": {
+			opts:        AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "🍕pizza"},
+			expectedErr: ErrInvalidEmoji,
+		},
+		"digit": {
+			opts:        AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "1"},
+			expectedErr: ErrInvalidEmoji,
+		},
+		"only a joiner": {
+			opts:        AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "\u200d\ufe0f"},
+			expectedErr: ErrInvalidEmoji,
+		},
+		"whitespace": {
+			opts:        AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "🕷️ 🕸️"},
+			expectedErr: ErrInvalidEmoji,
+		},
+		"too long": {
+			opts:        AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: strings.Repeat("🕷", maxEmojiLength+1)},
+			expectedErr: ErrInvalidEmoji,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := m.Add(nil, input.opts)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %q, expected %q", err, input.expectedErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			again, err := m.Add(nil, input.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if again != r {
+				t.Fatalf("got reaction %#v adding it twice, expected %#v", again, r)
+			}
+		})
+	}
+}
+
+func TestMap_Remove(t *testing.T) {
+	m := NewMap()
+
+	if _, err := m.Add(nil, AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "🍕"}); err != nil {
+		t.Fatalf("failed to prepopulate map: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		opts        RemoveReactionOpts
+		expectedErr error
+	}{
+		{
+			name:        "valid",
+			opts:        RemoveReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "🍕"},
+			expectedErr: nil,
+		},
+		{
+			name:        "already removed",
+			opts:        RemoveReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "🍕"},
+			expectedErr: ErrNotFound,
+		},
+		{
+			name:        "different user",
+			opts:        RemoveReactionOpts{MessageId: "message", UserId: "venom", Emoji: "🍕"},
+			expectedErr: ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := m.Remove(nil, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %q, expected %q", err, input.expectedErr)
+			}
+
+			// Messages without reactions aren't kept in the index.
+			if m.data.Len() != 0 {
+				t.Fatalf("got reactions to %d messages, expected none", m.data.Len())
+			}
+		})
+	}
+}
+
+func TestMap_Clear(t *testing.T) {
+	m := NewMap()
+
+	kept := &Reaction{MessageId: "kept", UserId: "venom", Emoji: "🍕", Timestamp: 1}
+
+	err := m.Import(nil, []*Reaction{
+		{MessageId: "cleared", UserId: "spiderman", Emoji: "🍕", Timestamp: 1},
+		{MessageId: "cleared", UserId: "venom", Emoji: "🕷️", Timestamp: 2},
+		kept,
+	})
+	if err != nil {
+		t.Fatalf("failed to prepopulate map: %v", err)
+	}
+
+	if err = m.Clear(nil, ClearReactionOpts{MessageIds: []string{"cleared"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	reactions, err := m.Export(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []*Reaction{kept}; !reflect.DeepEqual(reactions, expected) {
+		t.Fatalf("got reactions %v, expected %v", reactions, expected)
+	}
+}
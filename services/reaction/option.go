@@ -0,0 +1,42 @@
+package reaction
+
+// maxEmojiLength is long enough for emoji built from several code points, such as flags and families.
+const maxEmojiLength = 32
+
+type AddReactionOpts struct {
+	MessageId string
+	UserId    string
+	Emoji     string
+}
+
+func (a AddReactionOpts) Validate() error {
+	return validateEmoji(a.Emoji)
+}
+
+type RemoveReactionOpts struct {
+	MessageId string
+	UserId    string
+	Emoji     string
+}
+
+// ListReactionOpts filters the reactions listed. Zero values match everything.
+type ListReactionOpts struct {
+	MessageIds []string
+	UserId     string
+	Emoji      string
+}
+
+// ClearReactionOpts removes every reaction to any of MessageIds, by UserId, or both if both are set. At least one must be
+// set.
+type ClearReactionOpts struct {
+	MessageIds []string
+	UserId     string
+}
+
+func (c ClearReactionOpts) Validate() error {
+	if len(c.MessageIds) == 0 && c.UserId == "" {
+		return ErrInvalidClear
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+package reaction
+
+// Reaction is a single user reacting to a message with an emoji. A user can react to the same message with several
+// emoji, but only once with each.
+type Reaction struct {
+	MessageId string
+	UserId    string
+	Emoji     string
+	Timestamp int64
+}
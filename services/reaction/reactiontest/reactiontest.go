@@ -0,0 +1,355 @@
+// Package reactiontest checks that a reaction.Service behaves the same as reaction.Map.
+package reactiontest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/reaction"
+)
+
+// Run runs the conformance suite against the services returned by newService. Every subtest calls newService once with
+// its own *testing.T, which newService should fail if it can't return an empty service.
+func Run(t *testing.T, newService func(*testing.T) reaction.Service) {
+	t.Helper()
+
+	tests := map[string]func(*testing.T, reaction.Service){
+		"Add":           testAdd,
+		"Remove":        testRemove,
+		"List":          testList,
+		"Clear":         testClear,
+		"ExportImport":  testExportImport,
+		"ConcurrentAdd": testConcurrentAdd,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newService(t))
+		})
+	}
+}
+
+func testAdd(t *testing.T, s reaction.Service) {
+	ctx := context.Background()
+
+	before := time.Now().UnixMilli()
+
+	r, err := s.Add(ctx, reaction.AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "🕷️"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Timestamp < before || r.Timestamp > time.Now().UnixMilli() {
+		t.Fatalf("got timestamp %d, expected one between %d and now", r.Timestamp, before)
+	}
+
+	expected := &reaction.Reaction{MessageId: "message", UserId: "spiderman", Emoji: "🕷️", Timestamp: r.Timestamp}
+	if !equal(r, expected) {
+		t.Fatalf("got %v, expected %v", r, expected)
+	}
+
+	// Reacting twice with the same emoji is a no-op.
+	again, err := s.Add(ctx, reaction.AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "🕷️"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equal(again, r) {
+		t.Fatalf("got %v adding it twice, expected %v", again, r)
+	}
+
+	if reactions, _ := s.List(ctx, reaction.ListReactionOpts{}); len(reactions) != 1 {
+		t.Fatalf("got %d reactions, expected 1", len(reactions))
+	}
+
+	invalid := map[string]string{
+		"empty":      "",
+		"whitespace": "🕷️ 🕸️",
+		"text":       "lol",
+		"too long":   strings.Repeat("🕷", 33),
+	}
+
+	for name, emoji := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.Add(ctx, reaction.AddReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: emoji})
+			if !errors.Is(err, reaction.ErrInvalidEmoji) {
+				t.Fatalf("got error %v, expected %v", err, reaction.ErrInvalidEmoji)
+			}
+		})
+	}
+}
+
+func testRemove(t *testing.T, s reaction.Service) {
+	ctx := context.Background()
+
+	for _, opts := range []reaction.AddReactionOpts{
+		{MessageId: "message", UserId: "spiderman", Emoji: "🍕"},
+		{MessageId: "message", UserId: "venom", Emoji: "🍕"},
+	} {
+		if _, err := s.Add(ctx, opts); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	if err := s.Remove(ctx, reaction.RemoveReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "🍕"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.Remove(ctx, reaction.RemoveReactionOpts{MessageId: "message", UserId: "spiderman", Emoji: "🍕"})
+	if !errors.Is(err, reaction.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v removing it twice", err, reaction.ErrNotFound)
+	}
+
+	reactions, err := s.List(ctx, reaction.ListReactionOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if users := userIds(reactions); !slices.Equal(users, []string{"venom"}) {
+		t.Fatalf("got reactions from %v, expected [venom]", users)
+	}
+}
+
+func testList(t *testing.T, s reaction.Service) {
+	ctx := context.Background()
+
+	reactions, err := s.List(ctx, reaction.ListReactionOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reactions == nil || len(reactions) != 0 {
+		t.Fatalf("got %v, expected an empty, non-nil slice", reactions)
+	}
+
+	seed := []*reaction.Reaction{
+		{MessageId: "first", UserId: "spiderman", Emoji: "🍕", Timestamp: 3000},
+		{MessageId: "first", UserId: "venom", Emoji: "🍕", Timestamp: 1000},
+		{MessageId: "first", UserId: "venom", Emoji: "🕷️", Timestamp: 2000},
+		{MessageId: "second", UserId: "spiderman", Emoji: "🕷️", Timestamp: 4000},
+		{MessageId: "third", UserId: "batman", Emoji: "🦇", Timestamp: 5000},
+	}
+
+	if err = s.Import(ctx, seed); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := map[string]struct {
+		opts     reaction.ListReactionOpts
+		expected []*reaction.Reaction
+	}{
+		"everything": {
+			opts:     reaction.ListReactionOpts{},
+			expected: []*reaction.Reaction{seed[1], seed[2], seed[0], seed[3], seed[4]},
+		},
+		"message": {
+			opts:     reaction.ListReactionOpts{MessageIds: []string{"first"}},
+			expected: []*reaction.Reaction{seed[1], seed[2], seed[0]},
+		},
+		"messages": {
+			opts:     reaction.ListReactionOpts{MessageIds: []string{"first", "second"}},
+			expected: []*reaction.Reaction{seed[1], seed[2], seed[0], seed[3]},
+		},
+		"user": {
+			opts:     reaction.ListReactionOpts{UserId: "spiderman"},
+			expected: []*reaction.Reaction{seed[0], seed[3]},
+		},
+		"emoji": {
+			opts:     reaction.ListReactionOpts{MessageIds: []string{"first"}, Emoji: "🍕"},
+			expected: []*reaction.Reaction{seed[1], seed[0]},
+		},
+		"no matches": {
+			opts:     reaction.ListReactionOpts{MessageIds: []string{"fourth"}},
+			expected: []*reaction.Reaction{},
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := s.List(ctx, input.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equalAll(got, input.expected) {
+				t.Fatalf("got %v, expected %v", got, input.expected)
+			}
+		})
+	}
+}
+
+func testClear(t *testing.T, s reaction.Service) {
+	ctx := context.Background()
+
+	for _, opts := range []reaction.AddReactionOpts{
+		{MessageId: "cleared", UserId: "spiderman", Emoji: "🍕"},
+		{MessageId: "cleared", UserId: "venom", Emoji: "🕷️"},
+		{MessageId: "also cleared", UserId: "spiderman", Emoji: "🍕"},
+		{MessageId: "kept", UserId: "venom", Emoji: "🍕"},
+		{MessageId: "kept", UserId: "carnage", Emoji: "🩸"},
+		{MessageId: "kept", UserId: "mj", Emoji: "🍕"},
+	} {
+		if _, err := s.Add(ctx, opts); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		opts        reaction.ClearReactionOpts
+		expected    []string
+		expectedErr error
+	}{
+		{
+			name:     "messages",
+			opts:     reaction.ClearReactionOpts{MessageIds: []string{"cleared", "also cleared"}},
+			expected: []string{"kept/carnage", "kept/mj", "kept/venom"},
+		},
+		// Clearing a message without reactions isn't an error.
+		{
+			name:     "messages again",
+			opts:     reaction.ClearReactionOpts{MessageIds: []string{"cleared"}},
+			expected: []string{"kept/carnage", "kept/mj", "kept/venom"},
+		},
+		{
+			name:     "user",
+			opts:     reaction.ClearReactionOpts{UserId: "carnage"},
+			expected: []string{"kept/mj", "kept/venom"},
+		},
+		{
+			name:     "message and user",
+			opts:     reaction.ClearReactionOpts{MessageIds: []string{"kept"}, UserId: "venom"},
+			expected: []string{"kept/mj"},
+		},
+		{
+			name:        "nothing",
+			opts:        reaction.ClearReactionOpts{},
+			expected:    []string{"kept/mj"},
+			expectedErr: reaction.ErrInvalidClear,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := s.Clear(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			reactions, err := s.List(ctx, reaction.ListReactionOpts{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0, len(reactions))
+			for _, r := range reactions {
+				got = append(got, r.MessageId+"/"+r.UserId)
+			}
+
+			slices.Sort(got)
+
+			if !slices.Equal(got, input.expected) {
+				t.Fatalf("got %v, expected %v", got, input.expected)
+			}
+		})
+	}
+}
+
+func testExportImport(t *testing.T, s reaction.Service) {
+	ctx := context.Background()
+
+	reactions := []*reaction.Reaction{
+		{MessageId: "message", UserId: "spiderman", Emoji: "🍕", Timestamp: 1000},
+		{MessageId: "message", UserId: "venom", Emoji: "🕷️", Timestamp: 2000},
+	}
+
+	if err := s.Import(ctx, reactions); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := s.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equalAll(exported, reactions) {
+		t.Fatalf("got %v, expected %v", exported, reactions)
+	}
+
+	// Importing a reaction that exists replaces it.
+	replacement := &reaction.Reaction{MessageId: "message", UserId: "venom", Emoji: "🕷️", Timestamp: 3000}
+	if err = s.Import(ctx, []*reaction.Reaction{replacement}); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err = s.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []*reaction.Reaction{reactions[0], replacement}; !equalAll(exported, expected) {
+		t.Fatalf("got %v, expected %v", exported, expected)
+	}
+}
+
+func testConcurrentAdd(t *testing.T, s reaction.Service) {
+	ctx := context.Background()
+
+	const workers = 16
+
+	var wg sync.WaitGroup
+
+	// Half of the workers add a reaction that another worker is adding too.
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			opts := reaction.AddReactionOpts{MessageId: "message", UserId: fmt.Sprintf("spiderman%d", i%(workers/2)), Emoji: "🍕"}
+			if _, err := s.Add(ctx, opts); err != nil {
+				t.Errorf("failed to add reaction %d: %v", i, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	reactions, err := s.List(ctx, reaction.ListReactionOpts{MessageIds: []string{"message"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reactions) != workers/2 {
+		t.Fatalf("got %d reactions, expected %d", len(reactions), workers/2)
+	}
+}
+
+// equal compares reactions by value, since services are free to return copies.
+func equal(a, b *reaction.Reaction) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func equalAll(a, b []*reaction.Reaction) bool {
+	return slices.EqualFunc(a, b, equal)
+}
+
+func userIds(reactions []*reaction.Reaction) []string {
+	got := make([]string, 0, len(reactions))
+	for _, r := range reactions {
+		got = append(got, r.UserId)
+	}
+
+	slices.Sort(got)
+
+	return got
+}
@@ -0,0 +1,12 @@
+package reaction
+
+import "context"
+
+type Service interface {
+	Add(context.Context, AddReactionOpts) (*Reaction, error)
+	Remove(context.Context, RemoveReactionOpts) error
+	List(context.Context, ListReactionOpts) ([]*Reaction, error)
+	Clear(context.Context, ClearReactionOpts) error
+	Export(context.Context) ([]*Reaction, error)
+	Import(context.Context, []*Reaction) error
+}
@@ -0,0 +1,12 @@
+package room_test
+
+import (
+	"testing"
+
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/room/roomtest"
+)
+
+func TestMap_Conformance(t *testing.T) {
+	roomtest.Run(t, func(*testing.T) room.Service { return room.NewMap() })
+}
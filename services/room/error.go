@@ -5,4 +5,13 @@ import "errors"
 var (
 	ErrNotFound     = errors.New("no room found")
 	ErrUnauthorized = errors.New("operation is not authorized")
+	ErrInvalidLimit = errors.New("limit must not be negative")
+	ErrInvalidName  = errors.New("room name must not be empty")
+	ErrOwner        = errors.New("operation is not allowed on the room owner")
+	ErrBanned       = errors.New("user is banned from the room")
+	ErrNotMember    = errors.New("user is not a member of the room")
+	ErrInvalidUntil = errors.New("restriction expiry must not be negative")
+
+	ErrInvalidDirect = errors.New("direct messages need between 2 and 10 users")
+	ErrDirect        = errors.New("operation is not allowed in a direct message room")
 )
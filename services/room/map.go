@@ -1,8 +1,10 @@
 package room
 
 import (
+	"cmp"
 	"context"
 	"slices"
+	"sync"
 
 	"github.com/eolso/threadsafe"
 )
@@ -13,6 +15,13 @@ type Map struct {
 	data        *threadsafe.Map[int64, *Room]
 	padding     int64
 	roomCounter int64
+
+	// direct finds direct message rooms by the DirectKey of their users.
+	direct map[string]int64
+
+	// lock serializes writes so that ids are unique and concurrent joins aren't lost. Stored rooms are never modified in
+	// place, since callers may be reading them.
+	lock sync.Mutex
 }
 
 func NewMap() *Map {
@@ -20,14 +29,45 @@ func NewMap() *Map {
 		data:        threadsafe.NewMap[int64, *Room](),
 		padding:     padding,
 		roomCounter: 0,
+		direct:      make(map[string]int64),
 	}
 }
 
 func (m *Map) Create(_ context.Context, opts CreateRoomOpts) (*Room, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	id := m.padding + m.roomCounter
+	r := &Room{Name: opts.Name, Id: id, Visibility: opts.Visibility, Users: []string{opts.UserId}, Admins: []string{opts.UserId}}
+
+	m.data.Set(id, r)
+	m.roomCounter += 1
+
+	return r, nil
+}
+
+// CreateDirect returns the direct message room between opts.UserIds, creating it if there isn't one yet.
+func (m *Map) CreateDirect(_ context.Context, opts CreateDirectRoomOpts) (*Room, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	key := DirectKey(opts.UserIds)
+	// The index isn't cleaned up when Import replaces a room, so the room it points at is checked.
+	if id, ok := m.direct[key]; ok {
+		if r, ok := m.data.Get(id); ok && r.IsDirect() && DirectKey(r.Users) == key {
+			return r, nil
+		}
+	}
+
 	id := m.padding + m.roomCounter
-	r := &Room{Name: opts.Name, Id: id, Users: []string{opts.UserId}, Admins: []string{opts.UserId}}
+	r := &Room{Id: id, Type: TypeDirect, Users: opts.UserIds, Admins: []string{}}
 
 	m.data.Set(id, r)
+	m.direct[key] = id
 	m.roomCounter += 1
 
 	return r, nil
@@ -42,11 +82,20 @@ func (m *Map) GetRoomById(_ context.Context, opts GetRoomByIdOpts) (*Room, error
 	return r, nil
 }
 
+// List returns rooms ordered by id.
 func (m *Map) List(_ context.Context) ([]*Room, error) {
-	return m.data.Values(), nil
+	rooms := m.data.Values()
+	slices.SortFunc(rooms, func(a, b *Room) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+
+	return rooms, nil
 }
 
 func (m *Map) Delete(_ context.Context, opts DeleteRoomOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	r, ok := m.data.Get(opts.Id)
 	if !ok {
 		return ErrNotFound
@@ -57,22 +106,448 @@ func (m *Map) Delete(_ context.Context, opts DeleteRoomOpts) error {
 	}
 
 	m.data.Delete(opts.Id)
+	if r.IsDirect() {
+		delete(m.direct, DirectKey(r.Users))
+	}
+
 	return nil
 }
 
+func (m *Map) Update(_ context.Context, opts UpdateRoomOpts) (*Room, error) {
+	if opts.Name != nil && *opts.Name == "" {
+		return nil, ErrInvalidName
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r, ok := m.data.Get(opts.Id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if r.IsDirect() {
+		return nil, ErrDirect
+	}
+
+	if !opts.Force && !slices.Contains(r.Admins, opts.UserId) {
+		return nil, ErrUnauthorized
+	}
+
+	updated := *r
+	if opts.Name != nil {
+		updated.Name = *opts.Name
+	}
+
+	if opts.Topic != nil {
+		updated.Topic = *opts.Topic
+	}
+
+	if opts.Icon != nil {
+		updated.Icon = *opts.Icon
+	}
+
+	m.data.Set(r.Id, &updated)
+
+	return &updated, nil
+}
+
 func (m *Map) Join(_ context.Context, opts JoinRoomOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	r, ok := m.data.Get(opts.Id)
 	if !ok {
 		return ErrNotFound
 	}
 
+	if r.IsDirect() {
+		return ErrDirect
+	}
+
+	if r.Banned(opts.UserId, opts.Now) {
+		return ErrBanned
+	}
+
 	if slices.Contains(r.Users, opts.UserId) {
 		return nil
 	}
 
-	// This is still pretty vulnerable to race conditions
-	r.Users = append(r.Users, opts.UserId)
-	m.data.Set(r.Id, r)
+	updated := *r
+	updated.Users = append(slices.Clone(r.Users), opts.UserId)
+
+	m.data.Set(r.Id, &updated)
+
+	return nil
+}
+
+// Leave removes opts.UserId from the room. If that leaves the room with members but no admins, its earliest remaining
+// member becomes its owner. Leaving a room the user isn't in does nothing.
+func (m *Map) Leave(_ context.Context, opts LeaveRoomOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r, ok := m.data.Get(opts.Id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	if r.IsDirect() && !opts.Force {
+		return ErrDirect
+	}
+
+	if !slices.Contains(r.Users, opts.UserId) {
+		return nil
+	}
+
+	updated := withoutUser(r, opts.UserId)
+	if !r.IsDirect() && len(updated.Admins) == 0 && len(updated.Users) > 0 {
+		updated.Admins = []string{updated.Users[0]}
+	}
+
+	m.data.Set(r.Id, &updated)
+
+	return nil
+}
+
+func (m *Map) Kick(_ context.Context, opts KickRoomOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r, err := m.moderate(opts.Id, opts.UserId, opts.TargetId, opts.Force)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(r.Users, opts.TargetId) {
+		return nil
+	}
+
+	updated := withoutUser(r, opts.TargetId)
+	m.data.Set(r.Id, &updated)
+
+	return nil
+}
+
+// Ban removes opts.TargetId from the room like Kick and records the ban. Bans that expired by opts.Now are dropped.
+func (m *Map) Ban(_ context.Context, opts BanRoomOpts) error {
+	if opts.ExpiresAt < 0 {
+		return ErrInvalidUntil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r, err := m.moderate(opts.Id, opts.UserId, opts.TargetId, opts.Force)
+	if err != nil {
+		return err
+	}
+
+	updated := withoutUser(r, opts.TargetId)
+	updated.Bans = append(lift(r.Bans, opts.TargetId, opts.Now), Restriction{
+		UserId:    opts.TargetId,
+		Reason:    opts.Reason,
+		CreatedBy: opts.UserId,
+		CreatedAt: opts.Now,
+		ExpiresAt: opts.ExpiresAt,
+	})
+
+	m.data.Set(r.Id, &updated)
+
+	return nil
+}
+
+func (m *Map) Unban(_ context.Context, opts UnbanRoomOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r, err := m.authorize(opts.Id, opts.UserId, opts.Force)
+	if err != nil {
+		return err
+	}
+
+	updated := *r
+	updated.Bans = lift(r.Bans, opts.TargetId, 0)
+
+	m.data.Set(r.Id, &updated)
+
+	return nil
+}
+
+// Mute records the mute of opts.TargetId, who stays in the room. Mutes that expired by opts.Now are dropped.
+func (m *Map) Mute(_ context.Context, opts MuteRoomOpts) error {
+	if opts.ExpiresAt < 0 {
+		return ErrInvalidUntil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r, err := m.moderate(opts.Id, opts.UserId, opts.TargetId, opts.Force)
+	if err != nil {
+		return err
+	}
+
+	updated := *r
+	updated.Mutes = append(lift(r.Mutes, opts.TargetId, opts.Now), Restriction{
+		UserId:    opts.TargetId,
+		Reason:    opts.Reason,
+		CreatedBy: opts.UserId,
+		CreatedAt: opts.Now,
+		ExpiresAt: opts.ExpiresAt,
+	})
+
+	m.data.Set(r.Id, &updated)
+
+	return nil
+}
+
+func (m *Map) Unmute(_ context.Context, opts UnmuteRoomOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r, err := m.authorize(opts.Id, opts.UserId, opts.Force)
+	if err != nil {
+		return err
+	}
+
+	updated := *r
+	updated.Mutes = lift(r.Mutes, opts.TargetId, 0)
+
+	m.data.Set(r.Id, &updated)
+
+	return nil
+}
+
+// authorize returns the group room with id if userId is one of its admins or force is set. m.lock must be held.
+func (m *Map) authorize(id int64, userId string, force bool) (*Room, error) {
+	r, ok := m.data.Get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if r.IsDirect() {
+		return nil, ErrDirect
+	}
+
+	if !force && !slices.Contains(r.Admins, userId) {
+		return nil, ErrUnauthorized
+	}
+
+	return r, nil
+}
+
+// moderate returns the group room with id if userId may kick, ban or mute targetId in it. Admins may moderate members,
+// but only the owner may moderate other admins, and nobody may moderate the owner. m.lock must be held.
+func (m *Map) moderate(id int64, userId string, targetId string, force bool) (*Room, error) {
+	r, err := m.authorize(id, userId, force)
+	if err != nil {
+		return nil, err
+	}
+
+	if targetId == r.Owner() {
+		return nil, ErrOwner
+	}
+
+	if !force && slices.Contains(r.Admins, targetId) && userId != r.Owner() {
+		return nil, ErrUnauthorized
+	}
+
+	return r, nil
+}
+
+// withoutUser returns a copy of r with userId removed from its users, admins and pinners.
+func withoutUser(r *Room, userId string) Room {
+	isUser := func(id string) bool {
+		return id == userId
+	}
+
+	updated := *r
+	updated.Users = slices.DeleteFunc(slices.Clone(r.Users), isUser)
+	updated.Admins = slices.DeleteFunc(slices.Clone(r.Admins), isUser)
+	updated.Pinners = slices.DeleteFunc(slices.Clone(r.Pinners), isUser)
+
+	return updated
+}
+
+// lift returns a copy of restrictions without those of userId or those that expired by now. A zero now keeps every
+// restriction that isn't userId's.
+func lift(restrictions []Restriction, userId string, now int64) []Restriction {
+	return slices.DeleteFunc(slices.Clone(restrictions), func(r Restriction) bool {
+		return r.UserId == userId || (now != 0 && !r.Active(now))
+	})
+}
+
+func (m *Map) Promote(_ context.Context, opts PromoteRoomOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r, ok := m.data.Get(opts.Id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	if r.IsDirect() {
+		return ErrDirect
+	}
+
+	if !opts.Force && !slices.Contains(r.Admins, opts.UserId) {
+		return ErrUnauthorized
+	}
+
+	if r.Banned(opts.TargetId, opts.Now) {
+		return ErrBanned
+	}
+
+	if !slices.Contains(r.Users, opts.TargetId) {
+		return ErrNotMember
+	}
+
+	if slices.Contains(r.Admins, opts.TargetId) {
+		return nil
+	}
+
+	updated := *r
+	updated.Admins = append(slices.Clone(r.Admins), opts.TargetId)
+
+	m.data.Set(r.Id, &updated)
+
+	return nil
+}
+
+func (m *Map) Demote(_ context.Context, opts DemoteRoomOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r, ok := m.data.Get(opts.Id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	if r.IsDirect() {
+		return ErrDirect
+	}
+
+	if opts.TargetId == r.Owner() {
+		return ErrOwner
+	}
+
+	if !opts.Force && opts.UserId != r.Owner() && opts.UserId != opts.TargetId {
+		return ErrUnauthorized
+	}
+
+	if !slices.Contains(r.Admins, opts.TargetId) {
+		return nil
+	}
+
+	updated := *r
+	updated.Admins = slices.DeleteFunc(slices.Clone(r.Admins), func(admin string) bool {
+		return admin == opts.TargetId
+	})
+
+	m.data.Set(r.Id, &updated)
+
+	return nil
+}
+
+func (m *Map) Transfer(_ context.Context, opts TransferRoomOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r, ok := m.data.Get(opts.Id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	if r.IsDirect() {
+		return ErrDirect
+	}
+
+	if !opts.Force && opts.UserId != r.Owner() {
+		return ErrUnauthorized
+	}
+
+	if opts.TargetId == r.Owner() {
+		return nil
+	}
+
+	if r.Banned(opts.TargetId, opts.Now) {
+		return ErrBanned
+	}
+
+	if !slices.Contains(r.Users, opts.TargetId) {
+		return ErrNotMember
+	}
+
+	updated := *r
+	updated.Admins = append([]string{opts.TargetId}, slices.DeleteFunc(slices.Clone(r.Admins), func(admin string) bool {
+		return admin == opts.TargetId
+	})...)
+
+	m.data.Set(r.Id, &updated)
+
+	return nil
+}
+
+func (m *Map) ConfigurePins(_ context.Context, opts ConfigurePinsRoomOpts) error {
+	if opts.Limit < 0 {
+		return ErrInvalidLimit
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r, ok := m.data.Get(opts.Id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	if r.IsDirect() {
+		return ErrDirect
+	}
+
+	if !opts.Force && !slices.Contains(r.Admins, opts.UserId) {
+		return ErrUnauthorized
+	}
+
+	updated := *r
+	updated.Pinners = slices.Clone(opts.Pinners)
+	updated.PinLimit = opts.Limit
+
+	m.data.Set(r.Id, &updated)
+
+	return nil
+}
+
+// Export returns every room along with its members and admins.
+func (m *Map) Export(ctx context.Context) ([]*Room, error) {
+	return m.List(ctx)
+}
+
+// Import stores rooms as they are, replacing any room with the same id. Rooms created afterward are given ids past the
+// largest imported one.
+func (m *Map) Import(_ context.Context, rooms []*Room) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, r := range rooms {
+		imported := *r
+		imported.Users = slices.Clone(r.Users)
+		imported.Admins = slices.Clone(r.Admins)
+		imported.Pinners = slices.Clone(r.Pinners)
+		imported.Bans = slices.Clone(r.Bans)
+		imported.Mutes = slices.Clone(r.Mutes)
+
+		m.data.Set(r.Id, &imported)
+
+		if r.IsDirect() {
+			m.direct[DirectKey(r.Users)] = r.Id
+		}
+
+		if r.Id >= m.padding+m.roomCounter {
+			m.roomCounter = r.Id - m.padding + 1
+		}
+	}
 
 	return nil
 }
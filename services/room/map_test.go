@@ -200,3 +200,113 @@ func TestMap_Join(t *testing.T) {
 		})
 	}
 }
+
+func TestMap_Promote(t *testing.T) {
+	m := NewMap()
+
+	createdRoom, err := m.Create(nil, CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate map: %v", err)
+	}
+
+	for _, userId := range []string{"batman", "robin"} {
+		if err = m.Join(nil, JoinRoomOpts{Id: createdRoom.Id, UserId: userId}); err != nil {
+			t.Fatalf("failed to prepopulate map: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		opts           PromoteRoomOpts
+		expectedAdmins []string
+		expectedErr    error
+	}{
+		{
+			name:           "valid",
+			opts:           PromoteRoomOpts{Id: createdRoom.Id, UserId: "spiderman", TargetId: "batman"},
+			expectedAdmins: []string{"spiderman", "batman"},
+			expectedErr:    nil,
+		},
+		{
+			name:           "already admin",
+			opts:           PromoteRoomOpts{Id: createdRoom.Id, UserId: "spiderman", TargetId: "batman"},
+			expectedAdmins: []string{"spiderman", "batman"},
+			expectedErr:    nil,
+		},
+		{
+			name:           "unauthorized",
+			opts:           PromoteRoomOpts{Id: createdRoom.Id, UserId: "joker", TargetId: "joker"},
+			expectedAdmins: []string{"spiderman", "batman"},
+			expectedErr:    ErrUnauthorized,
+		},
+		{
+			name:           "not a member",
+			opts:           PromoteRoomOpts{Id: createdRoom.Id, UserId: "spiderman", TargetId: "joker"},
+			expectedAdmins: []string{"spiderman", "batman"},
+			expectedErr:    ErrNotMember,
+		},
+		{
+			name:           "forced",
+			opts:           PromoteRoomOpts{Id: createdRoom.Id, TargetId: "robin", Force: true},
+			expectedAdmins: []string{"spiderman", "batman", "robin"},
+			expectedErr:    nil,
+		},
+		{
+			name:        "not found",
+			opts:        PromoteRoomOpts{Id: 1, UserId: "spiderman", TargetId: "batman"},
+			expectedErr: ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := m.Promote(nil, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %q, expected %q", err, input.expectedErr)
+			}
+
+			if input.expectedAdmins == nil {
+				return
+			}
+
+			r, err := m.GetRoomById(nil, GetRoomByIdOpts{Id: input.opts.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(r.Admins, input.expectedAdmins) {
+				t.Fatalf("got admins %v, expected %v", r.Admins, input.expectedAdmins)
+			}
+		})
+	}
+}
+
+func TestMap_Import(t *testing.T) {
+	m := NewMap()
+
+	imported := []*Room{
+		{Id: padding + 4, Name: "the big apple", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman"}},
+		{Id: padding + 1, Name: "gotham", Users: []string{"batman"}, Admins: []string{"batman"}},
+	}
+
+	if err := m.Import(nil, imported); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := m.GetRoomById(nil, GetRoomByIdOpts{Id: padding + 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(r, imported[0]) {
+		t.Fatalf("got room %#v, expected %#v", r, imported[0])
+	}
+
+	createdRoom, err := m.Create(nil, CreateRoomOpts{Name: "metropolis", UserId: "superman"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if createdRoom.Id != padding+5 {
+		t.Fatalf("got id %d, expected %d", createdRoom.Id, padding+5)
+	}
+}
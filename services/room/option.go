@@ -1,8 +1,31 @@
 package room
 
+import "slices"
+
 type CreateRoomOpts struct {
-	Name   string
-	UserId string
+	Name       string
+	UserId     string
+	Visibility Visibility
+}
+
+// CreateDirectRoomOpts finds or creates the direct message room between UserIds.
+type CreateDirectRoomOpts struct {
+	UserIds []string
+}
+
+// Validate sorts and deduplicates UserIds, and checks that there are enough of them but not too many.
+func (c *CreateDirectRoomOpts) Validate() error {
+	userIds := slices.Clone(c.UserIds)
+	slices.Sort(userIds)
+	userIds = slices.Compact(userIds)
+
+	if len(userIds) < 2 || len(userIds) > MaxDirectUsers || slices.Contains(userIds, "") {
+		return ErrInvalidDirect
+	}
+
+	c.UserIds = userIds
+
+	return nil
 }
 
 type GetRoomByIdOpts struct {
@@ -15,7 +38,118 @@ type DeleteRoomOpts struct {
 	Force  bool
 }
 
+// PromoteRoomOpts grants TargetId admin of the room. TargetId must be a member of it and not banned from it at Now.
+// UserId must already be an admin unless Force is set.
+type PromoteRoomOpts struct {
+	Id       int64
+	UserId   string
+	TargetId string
+	Now      int64
+	Force    bool
+}
+
+// UpdateRoomOpts changes the settings of the room. Nil fields are left as they are. UserId must be an admin unless Force
+// is set.
+type UpdateRoomOpts struct {
+	Id     int64
+	UserId string
+	Name   *string
+	Topic  *string
+	Icon   *string
+	Force  bool
+}
+
+// DemoteRoomOpts revokes admin of the room from TargetId, who stays a member. UserId must be the owner or TargetId
+// themselves unless Force is set. The owner can't be demoted, only replaced with TransferRoomOpts.
+type DemoteRoomOpts struct {
+	Id       int64
+	UserId   string
+	TargetId string
+	Force    bool
+}
+
+// TransferRoomOpts makes TargetId the owner of the room, making them an admin if needed. TargetId must be a member of
+// it and not banned from it at Now. The previous owner stays an admin. UserId must be the owner unless Force is set.
+type TransferRoomOpts struct {
+	Id       int64
+	UserId   string
+	TargetId string
+	Now      int64
+	Force    bool
+}
+
+// JoinRoomOpts adds UserId to the room, unless they are banned from it at Now.
 type JoinRoomOpts struct {
 	Id     int64
 	UserId string
+	Now    int64
+}
+
+// LeaveRoomOpts removes UserId from the room, along with any admin role or pin permission they had there.
+type LeaveRoomOpts struct {
+	Id     int64
+	UserId string
+
+	// Force lets UserId leave a direct message room, which is otherwise refused. It's meant for deleting the user. The
+	// room is no longer returned by CreateDirect afterward, since it no longer has the users it was created for.
+	Force bool
+}
+
+// KickRoomOpts removes TargetId from the room. They may join again. UserId must be an admin, and only the owner may kick
+// other admins, unless Force is set. Nobody may kick the owner.
+type KickRoomOpts struct {
+	Id       int64
+	UserId   string
+	TargetId string
+	Force    bool
+}
+
+// BanRoomOpts removes TargetId from the room and keeps them out until ExpiresAt, or for good if it's zero. Any earlier
+// ban of TargetId is replaced. UserId is authorized like KickRoomOpts.
+type BanRoomOpts struct {
+	Id        int64
+	UserId    string
+	TargetId  string
+	Reason    string
+	Now       int64
+	ExpiresAt int64
+	Force     bool
+}
+
+// UnbanRoomOpts lifts the ban of TargetId. UserId must be an admin unless Force is set.
+type UnbanRoomOpts struct {
+	Id       int64
+	UserId   string
+	TargetId string
+	Force    bool
+}
+
+// MuteRoomOpts keeps TargetId from posting in the room until ExpiresAt, or for good if it's zero. Any earlier mute of
+// TargetId is replaced. UserId is authorized like KickRoomOpts.
+type MuteRoomOpts struct {
+	Id        int64
+	UserId    string
+	TargetId  string
+	Reason    string
+	Now       int64
+	ExpiresAt int64
+	Force     bool
+}
+
+// UnmuteRoomOpts lifts the mute of TargetId. UserId must be an admin unless Force is set.
+type UnmuteRoomOpts struct {
+	Id       int64
+	UserId   string
+	TargetId string
+	Force    bool
+}
+
+// ConfigurePinsRoomOpts replaces who may pin messages in the room and how many can be pinned. UserId must be an admin
+// unless Force is set.
+type ConfigurePinsRoomOpts struct {
+	Id      int64
+	UserId  string
+	Pinners []string
+	Limit   int
+	Force   bool
 }
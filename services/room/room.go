@@ -1,8 +1,169 @@
 package room
 
+import (
+	"encoding/json"
+	"slices"
+)
+
+const (
+	// DefaultPinLimit is the most messages a room can have pinned when its PinLimit isn't set.
+	DefaultPinLimit = 50
+
+	// MaxDirectUsers is the most users a direct message room can have.
+	MaxDirectUsers = 10
+)
+
+// Type distinguishes named rooms from direct messages.
+type Type string
+
+const (
+	// TypeRoom is a named room that shows up in listings. It's the zero value, so rooms from before types existed are
+	// named rooms.
+	TypeRoom Type = ""
+
+	// TypeDirect is a conversation between a fixed set of users. It has no name and no admins, and nobody can join it.
+	TypeDirect Type = "direct"
+)
+
+// Visibility decides who can find and read a named room.
+type Visibility string
+
+const (
+	// VisibilityPublic rooms are listed for everyone and anyone can read or join them. It's the zero value, so rooms
+	// from before visibility existed are public.
+	VisibilityPublic Visibility = ""
+
+	// VisibilityPrivate rooms are only listed for and readable by their users. Others join through an invite.
+	VisibilityPrivate Visibility = "private"
+)
+
 type Room struct {
-	Id     int64
-	Name   string
-	Users  []string
+	Id         int64
+	Name       string
+	Type       Type
+	Visibility Visibility
+
+	// Topic describes what the room is for.
+	Topic string
+
+	// Icon is shown next to the room's name, such as an emoji or an image URL.
+	Icon string
+
+	Users []string
+
+	// Admins are in the order they were promoted, except that the room's owner always comes first.
 	Admins []string
+
+	// Pinners may pin messages in the room without being admins.
+	Pinners []string
+
+	// PinLimit is the most messages the room can have pinned. Zero means DefaultPinLimit.
+	PinLimit int
+
+	// Bans keep users out of the room. Banned users can't join or post.
+	Bans []Restriction
+
+	// Mutes let users stay in the room and read it, but not post.
+	Mutes []Restriction
+}
+
+// Restriction is a ban or mute of a user in a room. Expired restrictions are kept until they are lifted or replaced, but
+// no longer apply.
+type Restriction struct {
+	UserId string
+
+	// Reason is why the user was restricted, as given by the admin that did it.
+	Reason string
+
+	// CreatedBy is the user that restricted them.
+	CreatedBy string
+
+	// CreatedAt and ExpiresAt are in milliseconds since epoch. A zero ExpiresAt never expires.
+	CreatedAt int64
+	ExpiresAt int64
+}
+
+// Active reports whether the restriction still applies at now.
+func (r Restriction) Active(now int64) bool {
+	return r.ExpiresAt == 0 || now < r.ExpiresAt
+}
+
+// Banned reports whether userId is banned from the room at now.
+func (r *Room) Banned(userId string, now int64) bool {
+	return restricted(r.Bans, userId, now)
+}
+
+// Muted reports whether userId is muted in the room at now.
+func (r *Room) Muted(userId string, now int64) bool {
+	return restricted(r.Mutes, userId, now)
+}
+
+func restricted(restrictions []Restriction, userId string, now int64) bool {
+	return slices.ContainsFunc(restrictions, func(r Restriction) bool {
+		return r.UserId == userId && r.Active(now)
+	})
+}
+
+// IsDirect reports whether the room is a direct message room.
+func (r *Room) IsDirect() bool {
+	return r.Type == TypeDirect
+}
+
+// Owner returns the user that owns the room, or an empty string if it has none. Direct message rooms have no owner.
+func (r *Room) Owner() string {
+	if len(r.Admins) == 0 {
+		return ""
+	}
+
+	return r.Admins[0]
+}
+
+// MaxPins returns how many messages the room can have pinned.
+func (r *Room) MaxPins() int {
+	if r.PinLimit > 0 {
+		return r.PinLimit
+	}
+
+	return DefaultPinLimit
+}
+
+// IsPrivate reports whether the room is a private room.
+func (r *Room) IsPrivate() bool {
+	return r.Visibility == VisibilityPrivate
+}
+
+// CanRead reports whether userId may read and post messages in the room. Direct message rooms and private rooms are
+// only readable by their users.
+func (r *Room) CanRead(userId string) bool {
+	return (!r.IsDirect() && !r.IsPrivate()) || slices.Contains(r.Users, userId)
+}
+
+// CanPin reports whether userId may pin and unpin messages in the room. Every user of a direct message room may pin,
+// since it has no admins.
+func (r *Room) CanPin(userId string) bool {
+	if r.IsDirect() {
+		return slices.Contains(r.Users, userId)
+	}
+
+	return slices.Contains(r.Admins, userId) || slices.Contains(r.Pinners, userId)
+}
+
+// CanInvite reports whether userId may create, list and revoke invites to the room. Only admins may, so direct message
+// rooms have no invites.
+func (r *Room) CanInvite(userId string) bool {
+	return !r.IsDirect() && slices.Contains(r.Admins, userId)
+}
+
+// CanModerate reports whether userId may list the bans and mutes of the room. Only admins may, so direct message rooms
+// can't be moderated.
+func (r *Room) CanModerate(userId string) bool {
+	return !r.IsDirect() && slices.Contains(r.Admins, userId)
+}
+
+// DirectKey identifies the direct message room between a set of users, regardless of their order. userIds must already
+// be normalized by CreateDirectRoomOpts.Validate.
+func DirectKey(userIds []string) string {
+	// Usernames may contain any character, so they're joined as a json array rather than with a separator.
+	b, _ := json.Marshal(userIds)
+	return string(b)
 }
@@ -0,0 +1,1584 @@
+// Package roomtest checks that a room.Service behaves the same as room.Map.
+package roomtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/worsediscord/server/services/room"
+)
+
+// Run runs the conformance suite against the services returned by newService. Every subtest calls newService once with
+// its own *testing.T, which newService should fail if it can't return an empty service.
+func Run(t *testing.T, newService func(*testing.T) room.Service) {
+	t.Helper()
+
+	tests := map[string]func(*testing.T, room.Service){
+		"Create":           testCreate,
+		"CreateDirect":     testCreateDirect,
+		"DirectRules":      testDirectRules,
+		"GetRoomById":      testGetRoomById,
+		"List":             testList,
+		"Delete":           testDelete,
+		"Join":             testJoin,
+		"Leave":            testLeave,
+		"LeaveDirect":      testLeaveDirect,
+		"Kick":             testKick,
+		"Ban":              testBan,
+		"Mute":             testMute,
+		"Update":           testUpdate,
+		"Promote":          testPromote,
+		"Demote":           testDemote,
+		"Transfer":         testTransfer,
+		"BannedTarget":     testBannedTarget,
+		"ConfigurePins":    testConfigurePins,
+		"ExportImport":     testExportImport,
+		"ConcurrentCreate": testConcurrentCreate,
+		"ConcurrentJoin":   testConcurrentJoin,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newService(t))
+		})
+	}
+}
+
+func testCreate(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	first, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &room.Room{Id: first.Id, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}}
+	if !equal(first, expected) {
+		t.Fatalf("got %v, expected %v", first, expected)
+	}
+
+	// Room names aren't unique.
+	second, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "venom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second.Id == first.Id {
+		t.Fatalf("got id %d for both rooms, expected unique ids", first.Id)
+	}
+
+	private, err := s.Create(ctx, room.CreateRoomOpts{Name: "sanctum", UserId: "strange", Visibility: room.VisibilityPrivate})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = &room.Room{Id: private.Id, Name: "sanctum", Visibility: room.VisibilityPrivate, Users: []string{"strange"}, Admins: []string{"strange"}}
+	if !equal(private, expected) {
+		t.Fatalf("got %v, expected %v", private, expected)
+	}
+
+	got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: private.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equal(got, expected) {
+		t.Fatalf("got %v, expected %v", got, expected)
+	}
+}
+
+func testCreateDirect(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	first, err := s.CreateDirect(ctx, room.CreateDirectRoomOpts{UserIds: []string{"venom", "spiderman"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &room.Room{Id: first.Id, Type: room.TypeDirect, Users: []string{"spiderman", "venom"}}
+	if !equal(first, expected) {
+		t.Fatalf("got %v, expected %v", first, expected)
+	}
+
+	tests := map[string]struct {
+		opts        room.CreateDirectRoomOpts
+		expectedId  func(id int64) bool
+		expectedErr error
+	}{
+		"same users": {
+			opts:       room.CreateDirectRoomOpts{UserIds: []string{"spiderman", "venom"}},
+			expectedId: func(id int64) bool { return id == first.Id },
+		},
+		"duplicate users": {
+			opts:       room.CreateDirectRoomOpts{UserIds: []string{"venom", "spiderman", "venom"}},
+			expectedId: func(id int64) bool { return id == first.Id },
+		},
+		"group": {
+			opts:       room.CreateDirectRoomOpts{UserIds: []string{"spiderman", "venom", "mj"}},
+			expectedId: func(id int64) bool { return id != first.Id },
+		},
+		"alone": {
+			opts:        room.CreateDirectRoomOpts{UserIds: []string{"spiderman", "spiderman"}},
+			expectedErr: room.ErrInvalidDirect,
+		},
+		"too many": {
+			opts:        room.CreateDirectRoomOpts{UserIds: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}},
+			expectedErr: room.ErrInvalidDirect,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := s.CreateDirect(ctx, input.opts)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !input.expectedId(got.Id) {
+				t.Fatalf("got room %v, first direct room is %d", got, first.Id)
+			}
+		})
+	}
+
+	// Deleting the room lets the same users start over.
+	if err = s.Delete(ctx, room.DeleteRoomOpts{Id: first.Id, Force: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := s.CreateDirect(ctx, room.CreateDirectRoomOpts{UserIds: []string{"spiderman", "venom"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again.Id == first.Id {
+		t.Fatalf("got deleted room %d back, expected a new one", first.Id)
+	}
+}
+
+func testDirectRules(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.CreateDirect(ctx, room.CreateDirectRoomOpts{UserIds: []string{"spiderman", "venom"}})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := map[string]struct {
+		call        func() error
+		expectedErr error
+	}{
+		"join": {
+			call:        func() error { return s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: "carnage"}) },
+			expectedErr: room.ErrDirect,
+		},
+		"promote": {
+			call: func() error {
+				return s.Promote(ctx, room.PromoteRoomOpts{Id: r.Id, TargetId: "spiderman", Force: true})
+			},
+			expectedErr: room.ErrDirect,
+		},
+		"update": {
+			call: func() error {
+				name := "the symbiote"
+				_, err := s.Update(ctx, room.UpdateRoomOpts{Id: r.Id, Name: &name, Force: true})
+				return err
+			},
+			expectedErr: room.ErrDirect,
+		},
+		"demote": {
+			call:        func() error { return s.Demote(ctx, room.DemoteRoomOpts{Id: r.Id, TargetId: "venom", Force: true}) },
+			expectedErr: room.ErrDirect,
+		},
+		"transfer": {
+			call:        func() error { return s.Transfer(ctx, room.TransferRoomOpts{Id: r.Id, TargetId: "venom", Force: true}) },
+			expectedErr: room.ErrDirect,
+		},
+		"configure pins": {
+			call:        func() error { return s.ConfigurePins(ctx, room.ConfigurePinsRoomOpts{Id: r.Id, Limit: 1, Force: true}) },
+			expectedErr: room.ErrDirect,
+		},
+		"leave": {
+			call:        func() error { return s.Leave(ctx, room.LeaveRoomOpts{Id: r.Id, UserId: "venom"}) },
+			expectedErr: room.ErrDirect,
+		},
+		"kick": {
+			call:        func() error { return s.Kick(ctx, room.KickRoomOpts{Id: r.Id, TargetId: "venom", Force: true}) },
+			expectedErr: room.ErrDirect,
+		},
+		"ban": {
+			call:        func() error { return s.Ban(ctx, room.BanRoomOpts{Id: r.Id, TargetId: "venom", Force: true}) },
+			expectedErr: room.ErrDirect,
+		},
+		"mute": {
+			call:        func() error { return s.Mute(ctx, room.MuteRoomOpts{Id: r.Id, TargetId: "venom", Force: true}) },
+			expectedErr: room.ErrDirect,
+		},
+		"delete as a user": {
+			call:        func() error { return s.Delete(ctx, room.DeleteRoomOpts{Id: r.Id, UserId: "spiderman"}) },
+			expectedErr: room.ErrUnauthorized,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := input.call(); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equal(got, r) {
+				t.Fatalf("got %v, expected it unchanged from %v", got, r)
+			}
+		})
+	}
+}
+
+func testGetRoomById(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	created, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := map[string]struct {
+		opts         room.GetRoomByIdOpts
+		expectedRoom *room.Room
+		expectedErr  error
+	}{
+		"valid": {
+			opts:         room.GetRoomByIdOpts{Id: created.Id},
+			expectedRoom: &room.Room{Id: created.Id, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}},
+			expectedErr:  nil,
+		},
+		"not found": {
+			opts:         room.GetRoomByIdOpts{Id: created.Id + 1},
+			expectedRoom: nil,
+			expectedErr:  room.ErrNotFound,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := s.GetRoomById(ctx, input.opts)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if !equal(r, input.expectedRoom) {
+				t.Fatalf("got %v, expected %v", r, input.expectedRoom)
+			}
+		})
+	}
+}
+
+func testList(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	rooms, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rooms == nil || len(rooms) != 0 {
+		t.Fatalf("got %v, expected an empty, non-nil slice", rooms)
+	}
+
+	expected := make([]int64, 0)
+	for _, name := range []string{"the big apple", "queens", "the daily bugle"} {
+		r, err := s.Create(ctx, room.CreateRoomOpts{Name: name, UserId: "spiderman"})
+		if err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+
+		expected = append(expected, r.Id)
+	}
+
+	rooms, err = s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slices.Sort(expected)
+	if got := ids(rooms); !slices.Equal(got, expected) {
+		t.Fatalf("got %v, expected %v", got, expected)
+	}
+}
+
+func testDelete(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		userId      string
+		missing     bool
+		force       bool
+		expectedErr error
+	}{
+		"admin": {
+			userId:      "spiderman",
+			expectedErr: nil,
+		},
+		"member": {
+			userId:      "venom",
+			expectedErr: room.ErrUnauthorized,
+		},
+		"stranger": {
+			userId:      "carnage",
+			expectedErr: room.ErrUnauthorized,
+		},
+		"forced": {
+			userId:      "carnage",
+			force:       true,
+			expectedErr: nil,
+		},
+		"not found": {
+			userId:      "spiderman",
+			missing:     true,
+			expectedErr: room.ErrNotFound,
+		},
+		"not found when forced": {
+			userId:      "spiderman",
+			missing:     true,
+			force:       true,
+			expectedErr: room.ErrNotFound,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+			if err != nil {
+				t.Fatalf("failed to prepopulate service: %v", err)
+			}
+
+			if err = s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: "venom"}); err != nil {
+				t.Fatalf("failed to prepopulate service: %v", err)
+			}
+
+			id := r.Id
+			if input.missing {
+				if err = s.Delete(ctx, room.DeleteRoomOpts{Id: id, Force: true}); err != nil {
+					t.Fatalf("failed to prepopulate service: %v", err)
+				}
+			}
+
+			err = s.Delete(ctx, room.DeleteRoomOpts{Id: id, UserId: input.userId, Force: input.force})
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			_, err = s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: id})
+			deleted := errors.Is(err, room.ErrNotFound)
+			if expected := input.expectedErr == nil || input.missing; deleted != expected {
+				t.Fatalf("got deleted %v, expected %v", deleted, expected)
+			}
+		})
+	}
+}
+
+func testJoin(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		opts          room.JoinRoomOpts
+		expectedUsers []string
+		expectedErr   error
+	}{
+		{
+			name:          "new member",
+			opts:          room.JoinRoomOpts{Id: r.Id, UserId: "venom"},
+			expectedUsers: []string{"spiderman", "venom"},
+			expectedErr:   nil,
+		},
+		{
+			name:          "existing member",
+			opts:          room.JoinRoomOpts{Id: r.Id, UserId: "venom"},
+			expectedUsers: []string{"spiderman", "venom"},
+			expectedErr:   nil,
+		},
+		{
+			name:          "creator",
+			opts:          room.JoinRoomOpts{Id: r.Id, UserId: "spiderman"},
+			expectedUsers: []string{"spiderman", "venom"},
+			expectedErr:   nil,
+		},
+		{
+			name:          "not found",
+			opts:          room.JoinRoomOpts{Id: r.Id + 1, UserId: "venom"},
+			expectedUsers: []string{"spiderman", "venom"},
+			expectedErr:   room.ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := s.Join(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(got.Users, input.expectedUsers) {
+				t.Fatalf("got users %v, expected %v", got.Users, input.expectedUsers)
+			}
+
+			if !slices.Equal(got.Admins, []string{"spiderman"}) {
+				t.Fatalf("got admins %v, expected [spiderman]", got.Admins)
+			}
+		})
+	}
+}
+
+func testLeave(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	for _, userId := range []string{"venom", "carnage", "mysterio"} {
+		if err = s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: userId}); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	if err = s.Promote(ctx, room.PromoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom"}); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	if err = s.ConfigurePins(ctx, room.ConfigurePinsRoomOpts{Id: r.Id, UserId: "spiderman", Pinners: []string{"carnage", "venom"}}); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		opts         room.LeaveRoomOpts
+		expectedRoom *room.Room
+		expectedErr  error
+	}{
+		{
+			name:         "not a member",
+			opts:         room.LeaveRoomOpts{Id: r.Id, UserId: "mj"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "carnage", "mysterio"}, Admins: []string{"spiderman", "venom"}, Pinners: []string{"carnage", "venom"}},
+		},
+		{
+			name:         "pinner",
+			opts:         room.LeaveRoomOpts{Id: r.Id, UserId: "carnage"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mysterio"}, Admins: []string{"spiderman", "venom"}, Pinners: []string{"venom"}},
+		},
+		// The next admin in line becomes the owner.
+		{
+			name:         "owner",
+			opts:         room.LeaveRoomOpts{Id: r.Id, UserId: "spiderman"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"venom", "mysterio"}, Admins: []string{"venom"}, Pinners: []string{"venom"}},
+		},
+		// With no admins left, the earliest remaining member becomes the owner.
+		{
+			name:         "last admin",
+			opts:         room.LeaveRoomOpts{Id: r.Id, UserId: "venom"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"mysterio"}, Admins: []string{"mysterio"}},
+		},
+		{
+			name:         "last member",
+			opts:         room.LeaveRoomOpts{Id: r.Id, UserId: "mysterio"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple"},
+		},
+		{
+			name:         "not found",
+			opts:         room.LeaveRoomOpts{Id: r.Id + 1, UserId: "mysterio"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple"},
+			expectedErr:  room.ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := s.Leave(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equal(got, input.expectedRoom) {
+				t.Fatalf("got %v, expected %v", got, input.expectedRoom)
+			}
+		})
+	}
+}
+
+func testLeaveDirect(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.CreateDirect(ctx, room.CreateDirectRoomOpts{UserIds: []string{"spiderman", "venom", "mj"}})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	if err = s.Leave(ctx, room.LeaveRoomOpts{Id: r.Id, UserId: "venom", Force: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nobody is promoted, since direct message rooms have no admins.
+	expected := &room.Room{Id: r.Id, Type: room.TypeDirect, Users: []string{"mj", "spiderman"}}
+	if !equal(got, expected) {
+		t.Fatalf("got %v, expected %v", got, expected)
+	}
+
+	// The room no longer belongs to either set of users.
+	for _, userIds := range [][]string{{"spiderman", "venom", "mj"}, {"spiderman", "mj"}} {
+		again, err := s.CreateDirect(ctx, room.CreateDirectRoomOpts{UserIds: userIds})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if again.Id == r.Id {
+			t.Fatalf("got room %d back for %v, expected a new one", r.Id, userIds)
+		}
+	}
+}
+
+func testKick(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	for _, userId := range []string{"venom", "carnage", "mysterio"} {
+		if err = s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: userId}); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	for _, userId := range []string{"venom", "carnage"} {
+		if err = s.Promote(ctx, room.PromoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: userId}); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	if err = s.ConfigurePins(ctx, room.ConfigurePinsRoomOpts{Id: r.Id, UserId: "spiderman", Pinners: []string{"mysterio"}}); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		opts         room.KickRoomOpts
+		expectedRoom *room.Room
+		expectedErr  error
+	}{
+		{
+			name:         "by a member",
+			opts:         room.KickRoomOpts{Id: r.Id, UserId: "mysterio", TargetId: "venom"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "carnage", "mysterio"}, Admins: []string{"spiderman", "venom", "carnage"}, Pinners: []string{"mysterio"}},
+			expectedErr:  room.ErrUnauthorized,
+		},
+		{
+			name:         "admin by an admin",
+			opts:         room.KickRoomOpts{Id: r.Id, UserId: "venom", TargetId: "carnage"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "carnage", "mysterio"}, Admins: []string{"spiderman", "venom", "carnage"}, Pinners: []string{"mysterio"}},
+			expectedErr:  room.ErrUnauthorized,
+		},
+		{
+			name:         "owner",
+			opts:         room.KickRoomOpts{Id: r.Id, UserId: "venom", TargetId: "spiderman", Force: true},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "carnage", "mysterio"}, Admins: []string{"spiderman", "venom", "carnage"}, Pinners: []string{"mysterio"}},
+			expectedErr:  room.ErrOwner,
+		},
+		{
+			name:         "member by an admin",
+			opts:         room.KickRoomOpts{Id: r.Id, UserId: "venom", TargetId: "mysterio"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "carnage"}, Admins: []string{"spiderman", "venom", "carnage"}},
+		},
+		{
+			name:         "admin by the owner",
+			opts:         room.KickRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "carnage"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman", "venom"}},
+		},
+		{
+			name:         "not a member",
+			opts:         room.KickRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "carnage"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman", "venom"}},
+		},
+		{
+			name:         "forced",
+			opts:         room.KickRoomOpts{Id: r.Id, TargetId: "venom", Force: true},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}},
+		},
+		{
+			name:         "not found",
+			opts:         room.KickRoomOpts{Id: r.Id + 1, UserId: "spiderman", TargetId: "venom"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}},
+			expectedErr:  room.ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := s.Kick(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equal(got, input.expectedRoom) {
+				t.Fatalf("got %v, expected %v", got, input.expectedRoom)
+			}
+		})
+	}
+
+	// Kicked users may come back.
+	if err = s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: "mysterio"}); err != nil {
+		t.Fatalf("got error %v rejoining, expected none", err)
+	}
+}
+
+func testBan(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	for _, userId := range []string{"venom", "carnage"} {
+		if err = s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: userId}); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	forever := room.Restriction{UserId: "venom", Reason: "we are venom", CreatedBy: "spiderman", CreatedAt: 1000}
+	until3000 := room.Restriction{UserId: "carnage", CreatedBy: "spiderman", CreatedAt: 1000, ExpiresAt: 3000}
+	until5000 := room.Restriction{UserId: "carnage", Reason: "again", CreatedBy: "spiderman", CreatedAt: 2000, ExpiresAt: 5000}
+	mysterio := room.Restriction{UserId: "mysterio", CreatedBy: "spiderman", CreatedAt: 6000, ExpiresAt: 7000}
+
+	tests := []struct {
+		name         string
+		call         func() error
+		expectedRoom *room.Room
+		expectedErr  error
+	}{
+		{
+			name: "by a member",
+			call: func() error {
+				return s.Ban(ctx, room.BanRoomOpts{Id: r.Id, UserId: "venom", TargetId: "carnage", Now: 1000})
+			},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "carnage"}, Admins: []string{"spiderman"}},
+			expectedErr:  room.ErrUnauthorized,
+		},
+		{
+			name: "negative expiry",
+			call: func() error {
+				return s.Ban(ctx, room.BanRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "carnage", Now: 1000, ExpiresAt: -1})
+			},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "carnage"}, Admins: []string{"spiderman"}},
+			expectedErr:  room.ErrInvalidUntil,
+		},
+		{
+			name: "owner",
+			call: func() error {
+				return s.Ban(ctx, room.BanRoomOpts{Id: r.Id, TargetId: "spiderman", Now: 1000, Force: true})
+			},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "carnage"}, Admins: []string{"spiderman"}},
+			expectedErr:  room.ErrOwner,
+		},
+		{
+			name: "member",
+			call: func() error {
+				return s.Ban(ctx, room.BanRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom", Reason: "we are venom", Now: 1000})
+			},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "carnage"}, Admins: []string{"spiderman"}, Bans: []room.Restriction{forever}},
+		},
+		{
+			name:         "banned join",
+			call:         func() error { return s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: "venom", Now: 1000000}) },
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "carnage"}, Admins: []string{"spiderman"}, Bans: []room.Restriction{forever}},
+			expectedErr:  room.ErrBanned,
+		},
+		{
+			name: "until",
+			call: func() error {
+				return s.Ban(ctx, room.BanRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "carnage", Now: 1000, ExpiresAt: 3000})
+			},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}, Bans: []room.Restriction{forever, until3000}},
+		},
+		// A second ban replaces the first.
+		{
+			name: "again",
+			call: func() error {
+				return s.Ban(ctx, room.BanRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "carnage", Reason: "again", Now: 2000, ExpiresAt: 5000})
+			},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}, Bans: []room.Restriction{forever, until5000}},
+		},
+		{
+			name:         "join before expiry",
+			call:         func() error { return s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: "carnage", Now: 4999}) },
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}, Bans: []room.Restriction{forever, until5000}},
+			expectedErr:  room.ErrBanned,
+		},
+		{
+			name:         "join after expiry",
+			call:         func() error { return s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: "carnage", Now: 5000}) },
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "carnage"}, Admins: []string{"spiderman"}, Bans: []room.Restriction{forever, until5000}},
+		},
+		// Banning someone who isn't a member keeps them from joining, and drops expired bans.
+		{
+			name: "stranger",
+			call: func() error {
+				return s.Ban(ctx, room.BanRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "mysterio", Now: 6000, ExpiresAt: 7000})
+			},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "carnage"}, Admins: []string{"spiderman"}, Bans: []room.Restriction{forever, mysterio}},
+		},
+		{
+			name:         "unban by a member",
+			call:         func() error { return s.Unban(ctx, room.UnbanRoomOpts{Id: r.Id, UserId: "carnage", TargetId: "venom"}) },
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "carnage"}, Admins: []string{"spiderman"}, Bans: []room.Restriction{forever, mysterio}},
+			expectedErr:  room.ErrUnauthorized,
+		},
+		{
+			name: "unban",
+			call: func() error {
+				return s.Unban(ctx, room.UnbanRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom"})
+			},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "carnage"}, Admins: []string{"spiderman"}, Bans: []room.Restriction{mysterio}},
+		},
+		{
+			name:         "unbanned join",
+			call:         func() error { return s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: "venom", Now: 6000}) },
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "carnage", "venom"}, Admins: []string{"spiderman"}, Bans: []room.Restriction{mysterio}},
+		},
+		{
+			name:         "forced unban",
+			call:         func() error { return s.Unban(ctx, room.UnbanRoomOpts{Id: r.Id, TargetId: "mysterio", Force: true}) },
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "carnage", "venom"}, Admins: []string{"spiderman"}},
+		},
+		{
+			name: "not found",
+			call: func() error {
+				return s.Ban(ctx, room.BanRoomOpts{Id: r.Id + 1, UserId: "spiderman", TargetId: "venom", Now: 1000})
+			},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "carnage", "venom"}, Admins: []string{"spiderman"}},
+			expectedErr:  room.ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := input.call(); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equal(got, input.expectedRoom) {
+				t.Fatalf("got %v, expected %v", got, input.expectedRoom)
+			}
+		})
+	}
+}
+
+func testMute(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	for _, userId := range []string{"venom", "carnage"} {
+		if err = s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: userId}); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	if err = s.Promote(ctx, room.PromoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom"}); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	users := []string{"spiderman", "venom", "carnage"}
+	admins := []string{"spiderman", "venom"}
+	carnage := room.Restriction{UserId: "carnage", Reason: "too loud", CreatedBy: "venom", CreatedAt: 1000, ExpiresAt: 2000}
+	venom := room.Restriction{UserId: "venom", CreatedBy: "spiderman", CreatedAt: 3000}
+
+	tests := []struct {
+		name          string
+		call          func() error
+		expectedMutes []room.Restriction
+		expectedErr   error
+	}{
+		{
+			name: "by a member",
+			call: func() error {
+				return s.Mute(ctx, room.MuteRoomOpts{Id: r.Id, UserId: "carnage", TargetId: "venom", Now: 1000})
+			},
+			expectedErr: room.ErrUnauthorized,
+		},
+		{
+			name: "admin by an admin",
+			call: func() error {
+				return s.Mute(ctx, room.MuteRoomOpts{Id: r.Id, UserId: "venom", TargetId: "spiderman", Now: 1000})
+			},
+			expectedErr: room.ErrOwner,
+		},
+		// Muted users stay in the room.
+		{
+			name: "member",
+			call: func() error {
+				return s.Mute(ctx, room.MuteRoomOpts{Id: r.Id, UserId: "venom", TargetId: "carnage", Reason: "too loud", Now: 1000, ExpiresAt: 2000})
+			},
+			expectedMutes: []room.Restriction{carnage},
+		},
+		// The expired mute of carnage is dropped.
+		{
+			name: "admin by the owner",
+			call: func() error {
+				return s.Mute(ctx, room.MuteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom", Now: 3000})
+			},
+			expectedMutes: []room.Restriction{venom},
+		},
+		{
+			name: "unmute by a member",
+			call: func() error {
+				return s.Unmute(ctx, room.UnmuteRoomOpts{Id: r.Id, UserId: "carnage", TargetId: "venom"})
+			},
+			expectedMutes: []room.Restriction{venom},
+			expectedErr:   room.ErrUnauthorized,
+		},
+		{
+			name: "unmute",
+			call: func() error {
+				return s.Unmute(ctx, room.UnmuteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom"})
+			},
+		},
+		{
+			name: "unmute again",
+			call: func() error {
+				return s.Unmute(ctx, room.UnmuteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom"})
+			},
+		},
+		{
+			name: "not found",
+			call: func() error {
+				return s.Mute(ctx, room.MuteRoomOpts{Id: r.Id + 1, UserId: "spiderman", TargetId: "venom", Now: 1000})
+			},
+			expectedErr: room.ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := input.call(); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := &room.Room{Id: r.Id, Name: "the big apple", Users: users, Admins: admins, Mutes: input.expectedMutes}
+			if !equal(got, expected) {
+				t.Fatalf("got %v, expected %v", got, expected)
+			}
+		})
+	}
+}
+
+func testPromote(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	for _, userId := range []string{"venom", "mysterio"} {
+		if err = s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: userId}); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		opts         room.PromoteRoomOpts
+		expectedRoom *room.Room
+		expectedErr  error
+	}{
+		{
+			name:         "by a member",
+			opts:         room.PromoteRoomOpts{Id: r.Id, UserId: "venom", TargetId: "venom"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mysterio"}, Admins: []string{"spiderman"}},
+			expectedErr:  room.ErrUnauthorized,
+		},
+		{
+			name:         "member",
+			opts:         room.PromoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mysterio"}, Admins: []string{"spiderman", "venom"}},
+			expectedErr:  nil,
+		},
+		{
+			name:         "existing admin",
+			opts:         room.PromoteRoomOpts{Id: r.Id, UserId: "venom", TargetId: "spiderman"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mysterio"}, Admins: []string{"spiderman", "venom"}},
+			expectedErr:  nil,
+		},
+		{
+			name:         "stranger",
+			opts:         room.PromoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "carnage"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mysterio"}, Admins: []string{"spiderman", "venom"}},
+			expectedErr:  room.ErrNotMember,
+		},
+		{
+			name:         "forced stranger",
+			opts:         room.PromoteRoomOpts{Id: r.Id, UserId: "", TargetId: "carnage", Force: true},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mysterio"}, Admins: []string{"spiderman", "venom"}},
+			expectedErr:  room.ErrNotMember,
+		},
+		{
+			name:         "forced",
+			opts:         room.PromoteRoomOpts{Id: r.Id, UserId: "", TargetId: "mysterio", Force: true},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mysterio"}, Admins: []string{"spiderman", "venom", "mysterio"}},
+			expectedErr:  nil,
+		},
+		{
+			name:         "not found",
+			opts:         room.PromoteRoomOpts{Id: r.Id + 1, UserId: "spiderman", TargetId: "venom"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mysterio"}, Admins: []string{"spiderman", "venom", "mysterio"}},
+			expectedErr:  room.ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := s.Promote(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equal(got, input.expectedRoom) {
+				t.Fatalf("got %v, expected %v", got, input.expectedRoom)
+			}
+		})
+	}
+}
+
+func testUpdate(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	if err = s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: "venom"}); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	text := func(s string) *string { return &s }
+
+	tests := []struct {
+		name         string
+		opts         room.UpdateRoomOpts
+		expectedRoom *room.Room
+		expectedErr  error
+	}{
+		{
+			name:         "by a member",
+			opts:         room.UpdateRoomOpts{Id: r.Id, UserId: "venom", Name: text("the symbiote")},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman"}},
+			expectedErr:  room.ErrUnauthorized,
+		},
+		{
+			name:         "everything",
+			opts:         room.UpdateRoomOpts{Id: r.Id, UserId: "spiderman", Name: text("queens"), Topic: text("friendly neighborhood"), Icon: text("🕷️")},
+			expectedRoom: &room.Room{Id: r.Id, Name: "queens", Topic: "friendly neighborhood", Icon: "🕷️", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman"}},
+			expectedErr:  nil,
+		},
+		{
+			name:         "only the topic",
+			opts:         room.UpdateRoomOpts{Id: r.Id, UserId: "spiderman", Topic: text("")},
+			expectedRoom: &room.Room{Id: r.Id, Name: "queens", Icon: "🕷️", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman"}},
+			expectedErr:  nil,
+		},
+		{
+			name:         "empty name",
+			opts:         room.UpdateRoomOpts{Id: r.Id, UserId: "spiderman", Name: text("")},
+			expectedRoom: &room.Room{Id: r.Id, Name: "queens", Icon: "🕷️", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman"}},
+			expectedErr:  room.ErrInvalidName,
+		},
+		{
+			name:         "forced",
+			opts:         room.UpdateRoomOpts{Id: r.Id, Icon: text(""), Force: true},
+			expectedRoom: &room.Room{Id: r.Id, Name: "queens", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman"}},
+			expectedErr:  nil,
+		},
+		{
+			name:         "not found",
+			opts:         room.UpdateRoomOpts{Id: r.Id + 1, UserId: "spiderman", Name: text("brooklyn")},
+			expectedRoom: &room.Room{Id: r.Id, Name: "queens", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman"}},
+			expectedErr:  room.ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			updated, err := s.Update(ctx, input.opts)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if err == nil && !equal(updated, input.expectedRoom) {
+				t.Fatalf("got updated room %v, expected %v", updated, input.expectedRoom)
+			}
+
+			got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equal(got, input.expectedRoom) {
+				t.Fatalf("got %v, expected %v", got, input.expectedRoom)
+			}
+		})
+	}
+}
+
+func testDemote(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	for _, target := range []string{"venom", "carnage", "mysterio"} {
+		if err = s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: target}); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+
+		if err = s.Promote(ctx, room.PromoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: target}); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	users := []string{"spiderman", "venom", "carnage", "mysterio"}
+
+	tests := []struct {
+		name           string
+		opts           room.DemoteRoomOpts
+		expectedAdmins []string
+		expectedErr    error
+	}{
+		{
+			name:           "by another admin",
+			opts:           room.DemoteRoomOpts{Id: r.Id, UserId: "venom", TargetId: "carnage"},
+			expectedAdmins: []string{"spiderman", "venom", "carnage", "mysterio"},
+			expectedErr:    room.ErrUnauthorized,
+		},
+		{
+			name:           "by the owner",
+			opts:           room.DemoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "carnage"},
+			expectedAdmins: []string{"spiderman", "venom", "mysterio"},
+			expectedErr:    nil,
+		},
+		{
+			name:           "themselves",
+			opts:           room.DemoteRoomOpts{Id: r.Id, UserId: "venom", TargetId: "venom"},
+			expectedAdmins: []string{"spiderman", "mysterio"},
+			expectedErr:    nil,
+		},
+		{
+			name:           "not an admin",
+			opts:           room.DemoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom"},
+			expectedAdmins: []string{"spiderman", "mysterio"},
+			expectedErr:    nil,
+		},
+		{
+			name:           "the owner",
+			opts:           room.DemoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "spiderman"},
+			expectedAdmins: []string{"spiderman", "mysterio"},
+			expectedErr:    room.ErrOwner,
+		},
+		{
+			name:           "forced",
+			opts:           room.DemoteRoomOpts{Id: r.Id, TargetId: "mysterio", Force: true},
+			expectedAdmins: []string{"spiderman"},
+			expectedErr:    nil,
+		},
+		{
+			name:           "not found",
+			opts:           room.DemoteRoomOpts{Id: r.Id + 1, UserId: "spiderman", TargetId: "venom"},
+			expectedAdmins: []string{"spiderman"},
+			expectedErr:    room.ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := s.Demote(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Demoted admins stay members.
+			expected := &room.Room{Id: r.Id, Name: "the big apple", Users: users, Admins: input.expectedAdmins}
+			if !equal(got, expected) {
+				t.Fatalf("got %v, expected %v", got, expected)
+			}
+		})
+	}
+}
+
+func testTransfer(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	for _, userId := range []string{"venom", "mj"} {
+		if err = s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: userId}); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	if err = s.Promote(ctx, room.PromoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom"}); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		opts         room.TransferRoomOpts
+		expectedRoom *room.Room
+		expectedErr  error
+	}{
+		{
+			name:         "by an admin",
+			opts:         room.TransferRoomOpts{Id: r.Id, UserId: "venom", TargetId: "venom"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mj"}, Admins: []string{"spiderman", "venom"}},
+			expectedErr:  room.ErrUnauthorized,
+		},
+		{
+			name:         "to an admin",
+			opts:         room.TransferRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mj"}, Admins: []string{"venom", "spiderman"}},
+			expectedErr:  nil,
+		},
+		{
+			name:         "to the owner",
+			opts:         room.TransferRoomOpts{Id: r.Id, UserId: "venom", TargetId: "venom"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mj"}, Admins: []string{"venom", "spiderman"}},
+			expectedErr:  nil,
+		},
+		{
+			name:         "to a member",
+			opts:         room.TransferRoomOpts{Id: r.Id, UserId: "venom", TargetId: "mj"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mj"}, Admins: []string{"mj", "venom", "spiderman"}},
+			expectedErr:  nil,
+		},
+		{
+			name:         "to a stranger",
+			opts:         room.TransferRoomOpts{Id: r.Id, UserId: "mj", TargetId: "jjj"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mj"}, Admins: []string{"mj", "venom", "spiderman"}},
+			expectedErr:  room.ErrNotMember,
+		},
+		{
+			name:         "forced to a stranger",
+			opts:         room.TransferRoomOpts{Id: r.Id, TargetId: "jjj", Force: true},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mj"}, Admins: []string{"mj", "venom", "spiderman"}},
+			expectedErr:  room.ErrNotMember,
+		},
+		{
+			name:         "forced",
+			opts:         room.TransferRoomOpts{Id: r.Id, TargetId: "spiderman", Force: true},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mj"}, Admins: []string{"spiderman", "mj", "venom"}},
+			expectedErr:  nil,
+		},
+		{
+			name:         "not found",
+			opts:         room.TransferRoomOpts{Id: r.Id + 1, UserId: "spiderman", TargetId: "mj"},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman", "venom", "mj"}, Admins: []string{"spiderman", "mj", "venom"}},
+			expectedErr:  room.ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := s.Transfer(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equal(got, input.expectedRoom) {
+				t.Fatalf("got %v, expected %v", got, input.expectedRoom)
+			}
+
+			if got.Owner() != input.expectedRoom.Admins[0] {
+				t.Fatalf("got owner %s, expected %s", got.Owner(), input.expectedRoom.Admins[0])
+			}
+		})
+	}
+}
+
+func testBannedTarget(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	if err = s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: "venom"}); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	if err = s.Ban(ctx, room.BanRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom", Now: 1000, ExpiresAt: 2000}); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		call        func() error
+		expectedErr error
+	}{
+		{
+			name: "promote",
+			call: func() error {
+				return s.Promote(ctx, room.PromoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom", Now: 1500})
+			},
+			expectedErr: room.ErrBanned,
+		},
+		{
+			name: "forced promote",
+			call: func() error {
+				return s.Promote(ctx, room.PromoteRoomOpts{Id: r.Id, TargetId: "venom", Now: 1500, Force: true})
+			},
+			expectedErr: room.ErrBanned,
+		},
+		{
+			name: "transfer",
+			call: func() error {
+				return s.Transfer(ctx, room.TransferRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom", Now: 1500})
+			},
+			expectedErr: room.ErrBanned,
+		},
+		{
+			name: "forced transfer",
+			call: func() error {
+				return s.Transfer(ctx, room.TransferRoomOpts{Id: r.Id, TargetId: "venom", Now: 1500, Force: true})
+			},
+			expectedErr: room.ErrBanned,
+		},
+		{
+			name: "expired ban",
+			call: func() error {
+				return s.Promote(ctx, room.PromoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom", Now: 2000})
+			},
+			expectedErr: room.ErrNotMember,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := input.call(); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(got.Users, []string{"spiderman"}) || !slices.Equal(got.Admins, []string{"spiderman"}) {
+				t.Fatalf("got users %v and admins %v, expected only spiderman", got.Users, got.Admins)
+			}
+		})
+	}
+}
+
+func testConfigurePins(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		opts         room.ConfigurePinsRoomOpts
+		expectedRoom *room.Room
+		expectedErr  error
+	}{
+		{
+			name:         "by a member",
+			opts:         room.ConfigurePinsRoomOpts{Id: r.Id, UserId: "venom", Pinners: []string{"venom"}},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}},
+			expectedErr:  room.ErrUnauthorized,
+		},
+		{
+			name:         "admin",
+			opts:         room.ConfigurePinsRoomOpts{Id: r.Id, UserId: "spiderman", Pinners: []string{"mj", "venom"}, Limit: 3},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}, Pinners: []string{"mj", "venom"}, PinLimit: 3},
+			expectedErr:  nil,
+		},
+		{
+			name:         "negative limit",
+			opts:         room.ConfigurePinsRoomOpts{Id: r.Id, UserId: "spiderman", Limit: -1},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}, Pinners: []string{"mj", "venom"}, PinLimit: 3},
+			expectedErr:  room.ErrInvalidLimit,
+		},
+		{
+			name:         "forced reset",
+			opts:         room.ConfigurePinsRoomOpts{Id: r.Id, Force: true},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}},
+			expectedErr:  nil,
+		},
+		{
+			name:         "not found",
+			opts:         room.ConfigurePinsRoomOpts{Id: r.Id + 1, UserId: "spiderman", Limit: 1},
+			expectedRoom: &room.Room{Id: r.Id, Name: "the big apple", Users: []string{"spiderman"}, Admins: []string{"spiderman"}},
+			expectedErr:  room.ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := s.ConfigurePins(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equal(got, input.expectedRoom) {
+				t.Fatalf("got %v, expected %v", got, input.expectedRoom)
+			}
+		})
+	}
+}
+
+func testExportImport(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	created, err := s.Create(ctx, room.CreateRoomOpts{Name: "queens", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	rooms := []*room.Room{
+		{Id: created.Id + 10, Name: "the big apple", Users: []string{"spiderman", "venom"}, Admins: []string{"spiderman"}},
+		{Id: created.Id + 20, Name: "the daily bugle", Topic: "pictures of spider-man", Icon: "📰", Users: []string{"jjj", "robbie"}, Admins: []string{"robbie", "jjj"}, Pinners: []string{"robbie"}, PinLimit: 5},
+		{Id: created.Id + 30, Type: room.TypeDirect, Users: []string{"mj", "spiderman"}},
+		{Id: created.Id + 25, Name: "sanctum", Visibility: room.VisibilityPrivate, Users: []string{"strange"}, Admins: []string{"strange"}, Bans: []room.Restriction{{UserId: "dormammu", Reason: "bargaining", CreatedBy: "strange", CreatedAt: 1000}}, Mutes: []room.Restriction{{UserId: "wong", CreatedBy: "strange", CreatedAt: 2000, ExpiresAt: 3000}}},
+	}
+
+	if err = s.Import(ctx, rooms); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := s.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exported) != len(rooms)+1 {
+		t.Fatalf("got %d rooms, expected %d", len(exported), len(rooms)+1)
+	}
+
+	for _, expected := range rooms {
+		got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: expected.Id})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !equal(got, expected) {
+			t.Fatalf("got %v, expected %v", got, expected)
+		}
+	}
+
+	// Rooms created after an import never reuse an imported id.
+	next, err := s.Create(ctx, room.CreateRoomOpts{Name: "queens", UserId: "spiderman"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if next.Id <= created.Id+30 {
+		t.Fatalf("got id %d, expected an id past %d", next.Id, created.Id+30)
+	}
+
+	// Imported direct message rooms are found again rather than duplicated.
+	direct, err := s.CreateDirect(ctx, room.CreateDirectRoomOpts{UserIds: []string{"spiderman", "mj"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if direct.Id != created.Id+30 {
+		t.Fatalf("got direct room %d, expected the imported one %d", direct.Id, created.Id+30)
+	}
+}
+
+func testConcurrentCreate(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	const workers = 16
+
+	var wg sync.WaitGroup
+	created := make(chan int64, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			r, err := s.Create(ctx, room.CreateRoomOpts{Name: fmt.Sprintf("room%d", i), UserId: "spiderman"})
+			if err != nil {
+				t.Errorf("failed to create room%d: %v", i, err)
+				return
+			}
+
+			created <- r.Id
+		}()
+	}
+
+	wg.Wait()
+	close(created)
+
+	unique := make(map[int64]bool)
+	for id := range created {
+		unique[id] = true
+	}
+
+	if len(unique) != workers {
+		t.Fatalf("got %d unique ids, expected %d", len(unique), workers)
+	}
+
+	rooms, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rooms) != workers {
+		t.Fatalf("got %d rooms, expected %d", len(rooms), workers)
+	}
+}
+
+func testConcurrentJoin(t *testing.T, s room.Service) {
+	ctx := context.Background()
+
+	r, err := s.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	const workers = 16
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+
+		// Every worker joins twice to make sure concurrent joins stay idempotent.
+		for range 2 {
+			go func() {
+				defer wg.Done()
+
+				if err := s.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: fmt.Sprintf("symbiote%d", i)}); err != nil {
+					t.Errorf("symbiote%d failed to join: %v", i, err)
+				}
+			}()
+		}
+	}
+
+	wg.Wait()
+
+	got, err := s.GetRoomById(ctx, room.GetRoomByIdOpts{Id: r.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Users) != workers+1 {
+		t.Fatalf("got %d users, expected %d: %v", len(got.Users), workers+1, got.Users)
+	}
+
+	for i := 0; i < workers; i++ {
+		if !slices.Contains(got.Users, fmt.Sprintf("symbiote%d", i)) {
+			t.Fatalf("symbiote%d is missing from %v", i, got.Users)
+		}
+	}
+}
+
+// equal compares rooms by value, since services are free to return copies.
+func equal(a, b *room.Room) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Id == b.Id && a.Name == b.Name && slices.Equal(a.Users, b.Users) && slices.Equal(a.Admins, b.Admins) &&
+		slices.Equal(a.Pinners, b.Pinners) && a.PinLimit == b.PinLimit && a.Type == b.Type &&
+		a.Visibility == b.Visibility && a.Topic == b.Topic && a.Icon == b.Icon && slices.Equal(a.Bans, b.Bans) &&
+		slices.Equal(a.Mutes, b.Mutes)
+}
+
+func ids(rooms []*room.Room) []int64 {
+	got := make([]int64, 0, len(rooms))
+	for _, r := range rooms {
+		got = append(got, r.Id)
+	}
+
+	slices.Sort(got)
+
+	return got
+}
@@ -4,9 +4,23 @@ import "context"
 
 type Service interface {
 	Create(context.Context, CreateRoomOpts) (*Room, error)
+	CreateDirect(context.Context, CreateDirectRoomOpts) (*Room, error)
 	GetRoomById(context.Context, GetRoomByIdOpts) (*Room, error)
 	List(context.Context) ([]*Room, error)
 	Delete(context.Context, DeleteRoomOpts) error
+	Update(context.Context, UpdateRoomOpts) (*Room, error)
 
 	Join(context.Context, JoinRoomOpts) error
+	Leave(context.Context, LeaveRoomOpts) error
+	Kick(context.Context, KickRoomOpts) error
+	Ban(context.Context, BanRoomOpts) error
+	Unban(context.Context, UnbanRoomOpts) error
+	Mute(context.Context, MuteRoomOpts) error
+	Unmute(context.Context, UnmuteRoomOpts) error
+	Promote(context.Context, PromoteRoomOpts) error
+	Demote(context.Context, DemoteRoomOpts) error
+	Transfer(context.Context, TransferRoomOpts) error
+	ConfigurePins(context.Context, ConfigurePinsRoomOpts) error
+	Export(context.Context) ([]*Room, error)
+	Import(context.Context, []*Room) error
 }
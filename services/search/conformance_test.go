@@ -0,0 +1,12 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/worsediscord/server/services/search"
+	"github.com/worsediscord/server/services/search/searchtest"
+)
+
+func TestMap_Conformance(t *testing.T) {
+	searchtest.Run(t, func(*testing.T) search.Service { return search.NewMap() })
+}
@@ -0,0 +1,5 @@
+package search
+
+import "errors"
+
+var ErrInvalidQuery = errors.New("query has no terms to search for")
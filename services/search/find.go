@@ -0,0 +1,145 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+)
+
+// The parameters of BM25, which ranks results. k1 limits how much repeating a term adds to a message's score, and b is
+// how much a long message is penalized for having more room to repeat it.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Postings maps the ids of messages to the positions a term appears at in them, in ascending order.
+type Postings map[string][]int
+
+// Corpus is what Find reads from an index. Implementations search the index as it is when Find is called, so they
+// shouldn't let it change partway through.
+type Corpus interface {
+	// Postings returns where term appears, or where any term starting with term appears if prefix is set.
+	Postings(ctx context.Context, term string, prefix bool) (Postings, error)
+
+	// Documents returns the indexed documents of the messages with ids, in any order.
+	Documents(ctx context.Context, ids []string) ([]*Document, error)
+
+	// Stats returns how many messages are indexed and the sum of their lengths.
+	Stats(ctx context.Context) (count int, length int, err error)
+}
+
+// Find searches c for the messages matching opts, most relevant first. Results are ranked with BM25, treating each
+// clause of the query as a term, and ties go to the newest message.
+func Find(ctx context.Context, c Corpus, opts SearchOpts) ([]*Result, error) {
+	q, err := ParseQuery(opts.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*Result, 0)
+
+	if opts.RoomIds != nil && len(opts.RoomIds) == 0 {
+		return results, nil
+	}
+
+	// matches[i] maps the ids of the messages matching the ith clause to how often they match it.
+	matches := make([]map[string]int, len(q.Clauses))
+	for i, clause := range q.Clauses {
+		if matches[i], err = match(ctx, c, clause); err != nil {
+			return nil, err
+		}
+
+		if len(matches[i]) == 0 {
+			return results, nil
+		}
+	}
+
+	ids := make([]string, 0)
+	for id := range matches[0] {
+		if !slices.ContainsFunc(matches[1:], func(m map[string]int) bool { return m[id] == 0 }) {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return results, nil
+	}
+
+	docs, err := c.Documents(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	count, length, err := c.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	avgLength := float64(length) / float64(count)
+
+	for _, doc := range docs {
+		if !opts.matches(doc) {
+			continue
+		}
+
+		result := &Result{MessageId: doc.MessageId, RoomId: doc.RoomId, Timestamp: doc.Timestamp}
+		for _, m := range matches {
+			result.Score += bm25(m[doc.MessageId], len(m), count, doc.Length, avgLength)
+		}
+
+		results = append(results, result)
+	}
+
+	slices.SortFunc(results, func(x, y *Result) int {
+		return cmp.Or(cmp.Compare(y.Score, x.Score), cmp.Compare(y.Timestamp, x.Timestamp), cmp.Compare(x.MessageId, y.MessageId))
+	})
+
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+
+	return results, nil
+}
+
+// match returns the ids of the messages matching clause, mapped to how many times it appears in them.
+func match(ctx context.Context, c Corpus, clause Clause) (map[string]int, error) {
+	postings := make([]Postings, len(clause.Terms))
+	for i, term := range clause.Terms {
+		var err error
+		if postings[i], err = c.Postings(ctx, term, clause.Prefix && i == len(clause.Terms)-1); err != nil {
+			return nil, err
+		}
+	}
+
+	matched := make(map[string]int)
+
+	for id, starts := range postings[0] {
+		for _, start := range starts {
+			if followedBy(postings[1:], id, start) {
+				matched[id]++
+			}
+		}
+	}
+
+	return matched, nil
+}
+
+// followedBy reports whether the rest of a phrase follows the term at start in the message with id.
+func followedBy(rest []Postings, id string, start int) bool {
+	for i, p := range rest {
+		if _, found := slices.BinarySearch(p[id], start+i+1); !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bm25 scores a message of length terms that a clause matches tf times, where df of the count indexed messages match it.
+func bm25(tf int, df int, count int, length int, avgLength float64) float64 {
+	idf := math.Log(1 + (float64(count-df)+0.5)/(float64(df)+0.5))
+
+	return idf * float64(tf) * (k1 + 1) / (float64(tf) + k1*(1-b+b*float64(length)/avgLength))
+}
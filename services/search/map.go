@@ -0,0 +1,163 @@
+package search
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/worsediscord/server/services/message"
+)
+
+type Map struct {
+	documents map[string]*Document
+
+	// postings maps every term to where it appears.
+	postings map[string]Postings
+
+	// terms holds the distinct terms of every message, so that removing one doesn't have to look through every term.
+	terms map[string][]string
+
+	// length is the sum of the lengths of every document.
+	length int
+
+	lock sync.RWMutex
+}
+
+func NewMap() *Map {
+	return &Map{
+		documents: make(map[string]*Document),
+		postings:  make(map[string]Postings),
+		terms:     make(map[string][]string),
+	}
+}
+
+func (m *Map) Index(_ context.Context, opts IndexOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, msg := range opts.Messages {
+		m.remove(msg.Id)
+		m.add(msg)
+	}
+
+	return nil
+}
+
+func (m *Map) Remove(_ context.Context, opts RemoveOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, id := range opts.MessageIds {
+		m.remove(id)
+	}
+
+	return nil
+}
+
+func (m *Map) Search(ctx context.Context, opts SearchOpts) ([]*Result, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return Find(ctx, mapCorpus{m}, opts)
+}
+
+// Rebuild replaces everything indexed with messages.
+func (m *Map) Rebuild(_ context.Context, messages []*message.Message) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	clear(m.documents)
+	clear(m.postings)
+	clear(m.terms)
+	m.length = 0
+
+	for _, msg := range messages {
+		m.remove(msg.Id)
+		m.add(msg)
+	}
+
+	return nil
+}
+
+// add indexes msg, which mustn't already be indexed. m.lock must be held.
+func (m *Map) add(msg *message.Message) {
+	doc, terms := NewDocument(msg)
+
+	positions := Positions(terms)
+	for term, p := range positions {
+		if m.postings[term] == nil {
+			m.postings[term] = make(Postings)
+		}
+
+		m.postings[term][msg.Id] = p
+		m.terms[msg.Id] = append(m.terms[msg.Id], term)
+	}
+
+	m.documents[msg.Id] = doc
+	m.length += doc.Length
+}
+
+// remove removes the message with id from the index, if it's indexed. m.lock must be held.
+func (m *Map) remove(id string) {
+	doc, ok := m.documents[id]
+	if !ok {
+		return
+	}
+
+	for _, term := range m.terms[id] {
+		delete(m.postings[term], id)
+
+		if len(m.postings[term]) == 0 {
+			delete(m.postings, term)
+		}
+	}
+
+	delete(m.documents, id)
+	delete(m.terms, id)
+	m.length -= doc.Length
+}
+
+// mapCorpus reads a Map for Find. The Map's lock must be held while it's used.
+type mapCorpus struct {
+	m *Map
+}
+
+// Postings looks through every term for prefixes, since terms aren't kept in order.
+func (c mapCorpus) Postings(_ context.Context, term string, prefix bool) (Postings, error) {
+	if !prefix {
+		return c.m.postings[term], nil
+	}
+
+	merged := make(Postings)
+	for t, p := range c.m.postings {
+		if !strings.HasPrefix(t, term) {
+			continue
+		}
+
+		for id, positions := range p {
+			merged[id] = append(merged[id], positions...)
+		}
+	}
+
+	for _, positions := range merged {
+		slices.Sort(positions)
+	}
+
+	return merged, nil
+}
+
+func (c mapCorpus) Documents(_ context.Context, ids []string) ([]*Document, error) {
+	docs := make([]*Document, 0, len(ids))
+	for _, id := range ids {
+		if doc, ok := c.m.documents[id]; ok {
+			docs = append(docs, doc)
+		}
+	}
+
+	return docs, nil
+}
+
+func (c mapCorpus) Stats(_ context.Context) (int, int, error) {
+	return len(c.m.documents), c.m.length, nil
+}
@@ -0,0 +1,75 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/worsediscord/server/services/message"
+)
+
+func TestNewMap(t *testing.T) {
+	if NewMap() == nil {
+		t.Fatal("constructor returned nil")
+	}
+}
+
+func TestMap_Remove(t *testing.T) {
+	m := NewMap()
+
+	err := m.Index(context.Background(), IndexOpts{Messages: []*message.Message{
+		{Id: "a", RoomId: 1, Content: "pizza time"},
+		{Id: "b", RoomId: 1, Content: "time to eat"},
+	}})
+	if err != nil {
+		t.Fatalf("failed to prepopulate map: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		ids            []string
+		expectedTerms  int
+		expectedDocs   int
+		expectedLength int
+	}{
+		{name: "shared terms stay", ids: []string{"a"}, expectedTerms: 3, expectedDocs: 1, expectedLength: 3},
+		{name: "unknown", ids: []string{"a", "c"}, expectedTerms: 3, expectedDocs: 1, expectedLength: 3},
+		{name: "last", ids: []string{"b"}, expectedTerms: 0, expectedDocs: 0, expectedLength: 0},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := m.Remove(context.Background(), RemoveOpts{MessageIds: input.ids}); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(m.postings) != input.expectedTerms {
+				t.Fatalf("got %d terms, expected %d", len(m.postings), input.expectedTerms)
+			}
+
+			if len(m.documents) != input.expectedDocs || len(m.terms) != input.expectedDocs {
+				t.Fatalf("got %d documents, expected %d", len(m.documents), input.expectedDocs)
+			}
+
+			if m.length != input.expectedLength {
+				t.Fatalf("got length %d, expected %d", m.length, input.expectedLength)
+			}
+		})
+	}
+}
+
+func TestBM25(t *testing.T) {
+	// Repeating a term scores higher, but less so each time.
+	once, twice, thrice := bm25(1, 1, 10, 5, 5), bm25(2, 1, 10, 5, 5), bm25(3, 1, 10, 5, 5)
+	if !(once < twice && twice < thrice && twice-once > thrice-twice) {
+		t.Fatalf("got %v, %v and %v, expected increasing scores with diminishing returns", once, twice, thrice)
+	}
+
+	// Rarer terms and shorter messages score higher.
+	if rare, common := bm25(1, 1, 10, 5, 5), bm25(1, 9, 10, 5, 5); rare <= common {
+		t.Fatalf("got %v for a rare term, expected more than %v for a common one", rare, common)
+	}
+
+	if short, long := bm25(1, 1, 10, 2, 5), bm25(1, 1, 10, 8, 5); short <= long {
+		t.Fatalf("got %v for a short message, expected more than %v for a long one", short, long)
+	}
+}
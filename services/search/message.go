@@ -0,0 +1,86 @@
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/worsediscord/server/services/message"
+)
+
+// MessageService keeps an index up to date with every change made through it. Reads go straight to the underlying
+// service. Messages are indexed after they're stored, so a message that fails to be indexed can't be found until the
+// index is rebuilt.
+type MessageService struct {
+	message.Service
+	index Service
+}
+
+func NewMessageService(messages message.Service, index Service) *MessageService {
+	return &MessageService{Service: messages, index: index}
+}
+
+func (m *MessageService) Create(ctx context.Context, opts message.CreateMessageOpts) (*message.Message, error) {
+	created, err := m.Service.Create(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.index.Index(ctx, IndexOpts{Messages: []*message.Message{created}}); err != nil {
+		return nil, fmt.Errorf("message %s was created but not indexed: %w", created.Id, err)
+	}
+
+	return created, nil
+}
+
+func (m *MessageService) Delete(ctx context.Context, opts message.DeleteMessageOpts) error {
+	if err := m.Service.Delete(ctx, opts); err != nil {
+		return err
+	}
+
+	return m.index.Remove(ctx, RemoveOpts{MessageIds: []string{opts.Id}})
+}
+
+func (m *MessageService) Purge(ctx context.Context, opts message.PurgeMessageOpts) ([]string, error) {
+	ids, err := m.Service.Purge(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.index.Remove(ctx, RemoveOpts{MessageIds: ids}); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Anonymize indexes every message by opts.UserId again, now without an author.
+func (m *MessageService) Anonymize(ctx context.Context, opts message.AnonymizeMessageOpts) error {
+	authored, err := m.Service.List(ctx, message.ListMessageOpts{UserId: opts.UserId})
+	if err != nil {
+		return err
+	}
+
+	if err = m.Service.Anonymize(ctx, opts); err != nil {
+		return err
+	}
+
+	anonymized := make([]*message.Message, 0, len(authored))
+	for _, msg := range authored {
+		updated, err := m.Service.GetMessageById(ctx, message.GetMessageByIdOpts{Id: msg.Id})
+		if err != nil {
+			return err
+		}
+
+		anonymized = append(anonymized, updated)
+	}
+
+	return m.index.Index(ctx, IndexOpts{Messages: anonymized})
+}
+
+func (m *MessageService) Import(ctx context.Context, messages []*message.Message) error {
+	if err := m.Service.Import(ctx, messages); err != nil {
+		return err
+	}
+
+	return m.index.Index(ctx, IndexOpts{Messages: messages})
+}
@@ -0,0 +1,92 @@
+package search
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/worsediscord/server/services/message"
+)
+
+func TestMessageService(t *testing.T) {
+	ctx := context.Background()
+	index := NewMap()
+	m := NewMessageService(message.NewMap(), index)
+
+	err := m.Import(ctx, []*message.Message{
+		{Id: "a", UserId: "spiderman", RoomId: 1, Content: "pizza time", Timestamp: 1000},
+		{Id: "b", UserId: "venom", RoomId: 2, Content: "we are venom", Timestamp: 2000},
+	})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	created, err := m.Create(ctx, message.CreateMessageOpts{UserId: "venom", RoomId: 1, Content: "time to eat"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		change   func() error
+		opts     SearchOpts
+		expected []string
+	}{
+		{
+			name:     "created",
+			change:   func() error { return nil },
+			opts:     SearchOpts{Query: "time"},
+			expected: []string{"a", created.Id},
+		},
+		{
+			name:     "anonymized",
+			change:   func() error { return m.Anonymize(ctx, message.AnonymizeMessageOpts{UserId: "venom"}) },
+			opts:     SearchOpts{Query: "venom"},
+			expected: []string{"b"},
+		},
+		{
+			name:     "deleted",
+			change:   func() error { return m.Delete(ctx, message.DeleteMessageOpts{Id: "a"}) },
+			opts:     SearchOpts{Query: "time"},
+			expected: []string{created.Id},
+		},
+		{
+			name: "purged",
+			change: func() error {
+				_, err := m.Purge(ctx, message.PurgeMessageOpts{RoomId: 1})
+				return err
+			},
+			opts:     SearchOpts{Query: "time"},
+			expected: []string{},
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := input.change(); err != nil {
+				t.Fatal(err)
+			}
+
+			results, err := index.Search(ctx, input.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0, len(results))
+			for _, r := range results {
+				got = append(got, r.MessageId)
+			}
+
+			slices.Sort(got)
+			slices.Sort(input.expected)
+			if !slices.Equal(got, input.expected) {
+				t.Fatalf("got %v, expected %v", got, input.expected)
+			}
+		})
+	}
+
+	// Anonymized messages are no longer found by their author.
+	if results, _ := index.Search(ctx, SearchOpts{Query: "venom", UserId: "venom"}); len(results) != 0 {
+		t.Fatalf("got %d results by venom, expected 0", len(results))
+	}
+}
@@ -0,0 +1,57 @@
+package search
+
+import (
+	"slices"
+
+	"github.com/worsediscord/server/services/message"
+)
+
+// IndexOpts adds messages to the index. A message that's already indexed is replaced, which is how an edited message is
+// indexed again.
+type IndexOpts struct {
+	Messages []*message.Message
+}
+
+// RemoveOpts removes messages from the index. Ids that aren't indexed are ignored.
+type RemoveOpts struct {
+	MessageIds []string
+}
+
+// SearchOpts searches for the messages matching Query, as parsed by ParseQuery, most relevant first. The other fields
+// filter the messages searched, and zero values match everything.
+type SearchOpts struct {
+	Query string
+
+	// RoomIds are the rooms to search. Every room is searched if nil, and none if empty.
+	RoomIds []int64
+
+	UserId string
+
+	// Before and After are exclusive bounds on the timestamp of the messages searched.
+	Before int64
+	After  int64
+
+	// Limit is the most results returned. Unlimited if 0.
+	Limit int
+}
+
+// matches reports whether d passes the filters of o.
+func (o SearchOpts) matches(d *Document) bool {
+	if o.RoomIds != nil && !slices.Contains(o.RoomIds, d.RoomId) {
+		return false
+	}
+
+	if o.UserId != "" && d.UserId != o.UserId {
+		return false
+	}
+
+	if o.Before != 0 && d.Timestamp >= o.Before {
+		return false
+	}
+
+	if o.After != 0 && d.Timestamp <= o.After {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,50 @@
+package search
+
+import "strings"
+
+// Clause is part of a query that a message must match. Its terms must appear in order, one after another.
+type Clause struct {
+	Terms []string
+
+	// Prefix matches any term starting with the last of Terms, rather than only the term itself.
+	Prefix bool
+}
+
+// Query is a parsed search. A message matches if it matches every clause.
+type Query struct {
+	Clauses []Clause
+}
+
+// ParseQuery parses s into a Query. Every word is a clause, and text in double quotes is a single clause matching the
+// phrase. A word or phrase ending in * matches terms starting with its last term. Words that tokenize into several
+// terms, like spider-man, are matched as a phrase. An unterminated quote runs to the end of s.
+func ParseQuery(s string) (Query, error) {
+	var q Query
+
+	for i, part := range strings.Split(s, `"`) {
+		if i%2 == 1 {
+			q.add(part)
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			q.add(word)
+		}
+	}
+
+	if len(q.Clauses) == 0 {
+		return Query{}, ErrInvalidQuery
+	}
+
+	return q, nil
+}
+
+// add adds a clause matching text, unless it has no terms.
+func (q *Query) add(text string) {
+	terms := Tokenize(text)
+	if len(terms) == 0 {
+		return
+	}
+
+	q.Clauses = append(q.Clauses, Clause{Terms: terms, Prefix: strings.HasSuffix(strings.TrimSpace(text), "*")})
+}
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected []string
+	}{
+		"words":       {input: "Pizza time!", expected: []string{"pizza", "time"}},
+		"punctuation": {input: "Spider-Man, eh?", expected: []string{"spider", "man", "eh"}},
+		"digits":      {input: "room 42b", expected: []string{"room", "42b"}},
+		"unicode":     {input: "Déjà vu", expected: []string{"déjà", "vu"}},
+		"empty":       {input: " ?! ", expected: []string{}},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := Tokenize(input.input); !slices.Equal(got, input.expected) {
+				t.Fatalf("got %q, expected %q", got, input.expected)
+			}
+		})
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		expected    Query
+		expectedErr error
+	}{
+		"words": {
+			input:    "pizza  Time",
+			expected: Query{Clauses: []Clause{{Terms: []string{"pizza"}}, {Terms: []string{"time"}}}},
+		},
+		"phrase": {
+			input:    `venom "we are venom"`,
+			expected: Query{Clauses: []Clause{{Terms: []string{"venom"}}, {Terms: []string{"we", "are", "venom"}}}},
+		},
+		"prefix": {
+			input:    `spider* "new yor*"`,
+			expected: Query{Clauses: []Clause{{Terms: []string{"spider"}, Prefix: true}, {Terms: []string{"new", "yor"}, Prefix: true}}},
+		},
+		"hyphenated": {
+			input:    "spider-man",
+			expected: Query{Clauses: []Clause{{Terms: []string{"spider", "man"}}}},
+		},
+		"unterminated": {
+			input:    `tiger "hit the`,
+			expected: Query{Clauses: []Clause{{Terms: []string{"tiger"}}, {Terms: []string{"hit", "the"}}}},
+		},
+		"empty phrase": {
+			input:    `"" jackpot`,
+			expected: Query{Clauses: []Clause{{Terms: []string{"jackpot"}}}},
+		},
+		"no terms": {
+			input:       `* "?"`,
+			expectedErr: ErrInvalidQuery,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseQuery(input.input)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if !reflect.DeepEqual(got, input.expected) {
+				t.Fatalf("got %+v, expected %+v", got, input.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Package search finds messages by their content. Messages are kept in an inverted index, which maps every term to the
+// messages it appears in and where, so that queries can match phrases and prefixes and rank what they match.
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/worsediscord/server/services/message"
+)
+
+// Document is what the index knows about a message besides its terms.
+type Document struct {
+	MessageId string
+	RoomId    int64
+	UserId    string
+	Timestamp int64
+
+	// Length is how many terms the message has.
+	Length int
+}
+
+// Result is a message matching a query.
+type Result struct {
+	MessageId string
+	RoomId    int64
+	Timestamp int64
+
+	// Score is how relevant the message is to the query. Higher is more relevant.
+	Score float64
+}
+
+// Tokenize splits s into lowercase terms of letters and digits. A term's position is its index.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// NewDocument returns the document of msg along with its terms.
+func NewDocument(msg *message.Message) (*Document, []string) {
+	terms := Tokenize(msg.Content)
+
+	return &Document{
+		MessageId: msg.Id,
+		RoomId:    msg.RoomId,
+		UserId:    msg.UserId,
+		Timestamp: msg.Timestamp,
+		Length:    len(terms),
+	}, terms
+}
+
+// Positions returns where each term appears in terms, in ascending order. The postings of a message are built from it.
+func Positions(terms []string) map[string][]int {
+	positions := make(map[string][]int)
+	for i, term := range terms {
+		positions[term] = append(positions[term], i)
+	}
+
+	return positions
+}
@@ -0,0 +1,201 @@
+// Package searchtest checks that a search.Service behaves the same as search.Map.
+package searchtest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/search"
+)
+
+// Run runs the conformance suite against the services returned by newService. Every subtest calls newService once with
+// its own *testing.T, which newService should fail if it can't return an empty service.
+func Run(t *testing.T, newService func(*testing.T) search.Service) {
+	t.Helper()
+
+	tests := map[string]func(*testing.T, search.Service){
+		"Search":  testSearch,
+		"Filters": testFilters,
+		"Reindex": testReindex,
+		"Remove":  testRemove,
+		"Rebuild": testRebuild,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newService(t))
+		})
+	}
+}
+
+// seed is indexed by every test.
+var seed = []*message.Message{
+	{Id: "a", UserId: "spiderman", RoomId: 1, Content: "Pizza time!", Timestamp: 1000},
+	{Id: "b", UserId: "venom", RoomId: 1, Content: "We are Venom. Time to eat.", Timestamp: 2000},
+	{Id: "c", UserId: "mj", RoomId: 2, Content: "Face it, tiger, you just hit the jackpot", Timestamp: 3000},
+	{Id: "d", UserId: "spiderman", RoomId: 1, Content: "time time time, always running out of time", Timestamp: 4000},
+	{Id: "e", UserId: "mj", RoomId: 3, Content: "Spider-Man saves the day again", Timestamp: 5000},
+	{Id: "f", UserId: "venom", RoomId: 1, Content: "spiders everywhere, spiderman is late for pizza", Timestamp: 6000},
+}
+
+func testSearch(t *testing.T, s search.Service) {
+	index(t, s, seed)
+
+	tests := map[string]struct {
+		query       string
+		expected    []string
+		expectedErr error
+	}{
+		// d says time the most, and a is shorter than b.
+		"term":             {query: "time", expected: []string{"d", "a", "b"}},
+		"case insensitive": {query: "TIME", expected: []string{"d", "a", "b"}},
+		"every term":       {query: "time pizza", expected: []string{"a"}},
+		"phrase":           {query: `"time to"`, expected: []string{"b"}},
+		"phrase in order":  {query: `"to time"`, expected: []string{}},
+		"hyphenated":       {query: "spider-man", expected: []string{"e"}},
+		"prefix":           {query: "spider*", expected: []string{"f", "e"}},
+		"phrase prefix":    {query: `"spider ma*"`, expected: []string{"e"}},
+		"whole term":       {query: "spider", expected: []string{"e"}},
+		"unterminated":     {query: `jackpot "hit the`, expected: []string{"c"}},
+		"no matches":       {query: "goblin", expected: []string{}},
+		"no terms":         {query: `"!?" *`, expectedErr: search.ErrInvalidQuery},
+		"empty":            {query: "", expectedErr: search.ErrInvalidQuery},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := find(s, search.SearchOpts{Query: input.query})
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if input.expectedErr == nil && !slices.Equal(got, input.expected) {
+				t.Fatalf("got %v, expected %v", got, input.expected)
+			}
+		})
+	}
+
+	// Results carry what they were filtered on, and the most relevant has the highest score.
+	results, err := s.Search(context.Background(), search.SearchOpts{Query: "time"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := results[0]; r.MessageId != "d" || r.RoomId != 1 || r.Timestamp != 4000 || r.Score <= results[1].Score {
+		t.Fatalf("got %+v, expected d in room 1 at 4000 scoring more than %+v", r, results[1])
+	}
+}
+
+func testFilters(t *testing.T, s search.Service) {
+	index(t, s, seed)
+
+	tests := map[string]struct {
+		opts     search.SearchOpts
+		expected []string
+	}{
+		"rooms":       {opts: search.SearchOpts{Query: "pizza", RoomIds: []int64{1, 2}}, expected: []string{"a", "f"}},
+		"other rooms": {opts: search.SearchOpts{Query: "pizza", RoomIds: []int64{2, 3}}, expected: []string{}},
+		"no rooms":    {opts: search.SearchOpts{Query: "pizza", RoomIds: []int64{}}, expected: []string{}},
+		"user":        {opts: search.SearchOpts{Query: "time", UserId: "venom"}, expected: []string{"b"}},
+		"before":      {opts: search.SearchOpts{Query: "time", Before: 4000}, expected: []string{"a", "b"}},
+		"after":       {opts: search.SearchOpts{Query: "time", After: 1000}, expected: []string{"d", "b"}},
+		"between":     {opts: search.SearchOpts{Query: "time", After: 1000, Before: 4000}, expected: []string{"b"}},
+		"limit":       {opts: search.SearchOpts{Query: "time", Limit: 2}, expected: []string{"d", "a"}},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := find(s, input.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(got, input.expected) {
+				t.Fatalf("got %v, expected %v", got, input.expected)
+			}
+		})
+	}
+}
+
+func testReindex(t *testing.T, s search.Service) {
+	index(t, s, seed)
+
+	// Indexing a message again replaces what it said before.
+	index(t, s, []*message.Message{{Id: "a", UserId: "spiderman", RoomId: 1, Content: "spaghetti time", Timestamp: 1000}})
+
+	expectResults(t, s, "pizza", []string{"f"})
+	expectResults(t, s, "spaghetti", []string{"a"})
+	expectResults(t, s, "time", []string{"d", "a", "b"})
+}
+
+func testRemove(t *testing.T, s search.Service) {
+	index(t, s, seed)
+
+	if err := s.Remove(context.Background(), search.RemoveOpts{MessageIds: []string{"a", "b", "unknown"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectResults(t, s, "time", []string{"d"})
+	expectResults(t, s, "pizza", []string{"f"})
+
+	// Removing them again is harmless.
+	if err := s.Remove(context.Background(), search.RemoveOpts{MessageIds: []string{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testRebuild(t *testing.T, s search.Service) {
+	index(t, s, seed)
+
+	if err := s.Rebuild(context.Background(), seed[2:3]); err != nil {
+		t.Fatal(err)
+	}
+
+	expectResults(t, s, "time", []string{})
+	expectResults(t, s, "tiger", []string{"c"})
+
+	if err := s.Rebuild(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expectResults(t, s, "tiger", []string{})
+}
+
+func index(t *testing.T, s search.Service, messages []*message.Message) {
+	t.Helper()
+
+	if err := s.Index(context.Background(), search.IndexOpts{Messages: messages}); err != nil {
+		t.Fatalf("failed to index messages: %v", err)
+	}
+}
+
+func expectResults(t *testing.T, s search.Service, query string, expected []string) {
+	t.Helper()
+
+	got, err := find(s, search.SearchOpts{Query: query})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(got, expected) {
+		t.Fatalf("got %v searching for %q, expected %v", got, query, expected)
+	}
+}
+
+// find returns the ids of the messages matching opts, in the order they rank.
+func find(s search.Service, opts search.SearchOpts) ([]string, error) {
+	results, err := s.Search(context.Background(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.MessageId)
+	}
+
+	return ids, nil
+}
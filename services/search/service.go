@@ -0,0 +1,14 @@
+package search
+
+import (
+	"context"
+
+	"github.com/worsediscord/server/services/message"
+)
+
+type Service interface {
+	Index(context.Context, IndexOpts) error
+	Remove(context.Context, RemoveOpts) error
+	Search(context.Context, SearchOpts) ([]*Result, error)
+	Rebuild(context.Context, []*message.Message) error
+}
@@ -0,0 +1,8 @@
+package snapshot
+
+import "errors"
+
+var (
+	ErrInvalidSnapshot    = errors.New("not a valid snapshot")
+	ErrUnsupportedVersion = errors.New("unsupported snapshot version")
+)
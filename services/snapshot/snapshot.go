@@ -0,0 +1,236 @@
+package snapshot
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
+)
+
+// Version is the snapshot format written by Write. Read accepts any version up to and including it. Version 2 added
+// reactions, version 3 added pins, version 4 added direct message rooms, version 5 added private rooms and invites,
+// version 6 added room topics and icons, and version 7 added room bans and mutes.
+const Version = 7
+
+// Portable is implemented by every service that can be snapshotted.
+type Portable[T any] interface {
+	Export(context.Context) ([]T, error)
+	Import(context.Context, []T) error
+}
+
+// Snapshot is a point in time copy of every service. Room membership is kept on the rooms themselves.
+type Snapshot struct {
+	Version   int                  `json:"version"`
+	CreatedAt int64                `json:"createdAt"`
+	Users     []*user.User         `json:"users"`
+	Rooms     []*room.Room         `json:"rooms"`
+	Messages  []*message.Message   `json:"messages"`
+	Reactions []*reaction.Reaction `json:"reactions,omitempty"`
+	Pins      []*pin.Pin           `json:"pins,omitempty"`
+	Invites   []*invite.Invite     `json:"invites,omitempty"`
+	Sessions  []Session            `json:"sessions,omitempty"`
+}
+
+// Session is an exported auth.ApiKey. The payload of every key issued by the server is a username.
+type Session struct {
+	Token     string `json:"token"`
+	Username  string `json:"username"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// Services are the services that a Snapshot is exported from and imported into. Auth may be nil if sessions aren't
+// wanted, and Reaction, Pin and Invite may be nil if reactions, pins and invites aren't.
+type Services struct {
+	User     Portable[*user.User]
+	Room     Portable[*room.Room]
+	Message  Portable[*message.Message]
+	Reaction Portable[*reaction.Reaction]
+	Pin      Portable[*pin.Pin]
+	Invite   Portable[*invite.Invite]
+	Auth     Portable[auth.ApiKey]
+}
+
+// Export copies every service into a Snapshot.
+func Export(ctx context.Context, services Services) (*Snapshot, error) {
+	var err error
+
+	s := &Snapshot{Version: Version, CreatedAt: time.Now().UnixMilli()}
+
+	if s.Users, err = services.User.Export(ctx); err != nil {
+		return nil, fmt.Errorf("failed to export users: %w", err)
+	}
+
+	if s.Rooms, err = services.Room.Export(ctx); err != nil {
+		return nil, fmt.Errorf("failed to export rooms: %w", err)
+	}
+
+	if s.Messages, err = services.Message.Export(ctx); err != nil {
+		return nil, fmt.Errorf("failed to export messages: %w", err)
+	}
+
+	if services.Reaction != nil {
+		if s.Reactions, err = services.Reaction.Export(ctx); err != nil {
+			return nil, fmt.Errorf("failed to export reactions: %w", err)
+		}
+	}
+
+	if services.Pin != nil {
+		if s.Pins, err = services.Pin.Export(ctx); err != nil {
+			return nil, fmt.Errorf("failed to export pins: %w", err)
+		}
+	}
+
+	if services.Invite != nil {
+		if s.Invites, err = services.Invite.Export(ctx); err != nil {
+			return nil, fmt.Errorf("failed to export invites: %w", err)
+		}
+	}
+
+	if services.Auth == nil {
+		return s, nil
+	}
+
+	keys, err := services.Auth.Export(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export sessions: %w", err)
+	}
+
+	for _, key := range keys {
+		username, ok := key.Payload().(string)
+		if !ok {
+			continue
+		}
+
+		s.Sessions = append(s.Sessions, Session{Token: key.Token(), Username: username, ExpiresAt: key.ExpiresAt().UnixMilli()})
+	}
+
+	return s, nil
+}
+
+// Import copies a Snapshot into every service. Reactions are skipped if services.Reaction is nil, pins if services.Pin
+// is, invites if services.Invite is, and sessions if services.Auth is.
+func Import(ctx context.Context, services Services, s *Snapshot) error {
+	if err := services.User.Import(ctx, s.Users); err != nil {
+		return fmt.Errorf("failed to import users: %w", err)
+	}
+
+	if err := services.Room.Import(ctx, s.Rooms); err != nil {
+		return fmt.Errorf("failed to import rooms: %w", err)
+	}
+
+	if err := services.Message.Import(ctx, s.Messages); err != nil {
+		return fmt.Errorf("failed to import messages: %w", err)
+	}
+
+	if services.Reaction != nil {
+		if err := services.Reaction.Import(ctx, s.Reactions); err != nil {
+			return fmt.Errorf("failed to import reactions: %w", err)
+		}
+	}
+
+	if services.Pin != nil {
+		if err := services.Pin.Import(ctx, s.Pins); err != nil {
+			return fmt.Errorf("failed to import pins: %w", err)
+		}
+	}
+
+	if services.Invite != nil {
+		if err := services.Invite.Import(ctx, s.Invites); err != nil {
+			return fmt.Errorf("failed to import invites: %w", err)
+		}
+	}
+
+	if services.Auth == nil {
+		return nil
+	}
+
+	keys := make([]auth.ApiKey, 0, len(s.Sessions))
+	for _, session := range s.Sessions {
+		keys = append(keys, auth.RestoreApiKey(session.Token, time.UnixMilli(session.ExpiresAt), session.Username))
+	}
+
+	if err := services.Auth.Import(ctx, keys); err != nil {
+		return fmt.Errorf("failed to import sessions: %w", err)
+	}
+
+	return nil
+}
+
+// Write writes s to w as gzip compressed JSON.
+func Write(w io.Writer, s *Snapshot) error {
+	zw := gzip.NewWriter(w)
+
+	if err := json.NewEncoder(zw).Encode(s); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// Read reads a Snapshot written by Write.
+func Read(r io.Reader) (*Snapshot, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	defer zr.Close()
+
+	var s Snapshot
+	if err = json.NewDecoder(zr).Decode(&s); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+
+	if s.Version < 1 || s.Version > Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, s.Version)
+	}
+
+	return &s, nil
+}
+
+// WriteFile writes s to name. The file is replaced atomically, so an interrupted write leaves the previous snapshot.
+func WriteFile(name string, s *Snapshot) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err = Write(f, s); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), name)
+}
+
+// ReadFile reads the Snapshot at name.
+func ReadFile(name string) (*Snapshot, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
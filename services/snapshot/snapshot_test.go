@@ -0,0 +1,233 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/user"
+)
+
+func newServices() (Services, *user.Map, *room.Map, *message.Map, *auth.Map) {
+	u, r, m, a := user.NewMap(), room.NewMap(), message.NewMap(), auth.NewMap()
+	return Services{User: u, Room: r, Message: m, Reaction: reaction.NewMap(), Pin: pin.NewMap(), Invite: invite.NewMap(), Auth: a}, u, r, m, a
+}
+
+func populate(t *testing.T, u *user.Map, r *room.Map, m *message.Map, a *auth.Map) {
+	ctx := context.Background()
+
+	if err := u.Create(ctx, user.CreateUserOpts{Username: "spiderman", Password: "uncleben123"}); err != nil {
+		t.Fatalf("failed to prepopulate users: %v", err)
+	}
+
+	createdRoom, err := r.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to prepopulate rooms: %v", err)
+	}
+
+	if err = r.Join(ctx, room.JoinRoomOpts{Id: createdRoom.Id, UserId: "venom"}); err != nil {
+		t.Fatalf("failed to prepopulate rooms: %v", err)
+	}
+
+	if _, err = m.Create(ctx, message.CreateMessageOpts{UserId: "spiderman", RoomId: createdRoom.Id, Content: "with great power"}); err != nil {
+		t.Fatalf("failed to prepopulate messages: %v", err)
+	}
+
+	if err = a.RegisterKey("token", auth.NewApiKey(16, time.Hour, "spiderman")); err != nil {
+		t.Fatalf("failed to prepopulate keys: %v", err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		withAuth         bool
+		expectedSessions int
+	}{
+		"with sessions": {
+			withAuth:         true,
+			expectedSessions: 1,
+		},
+		"without sessions": {
+			withAuth:         false,
+			expectedSessions: 0,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			source, u, r, m, a := newServices()
+			populate(t, u, r, m, a)
+
+			if err := source.Reaction.Import(ctx, []*reaction.Reaction{{MessageId: "message", UserId: "venom", Emoji: "🍕", Timestamp: 1}}); err != nil {
+				t.Fatalf("failed to prepopulate reactions: %v", err)
+			}
+
+			if err := source.Pin.Import(ctx, []*pin.Pin{{RoomId: 1, MessageId: "message", UserId: "spiderman", Timestamp: 1}}); err != nil {
+				t.Fatalf("failed to prepopulate pins: %v", err)
+			}
+
+			if err := source.Invite.Import(ctx, []*invite.Invite{{Code: "code", RoomId: 1, UserId: "spiderman", CreatedAt: 1, MaxUses: 2}}); err != nil {
+				t.Fatalf("failed to prepopulate invites: %v", err)
+			}
+
+			if !input.withAuth {
+				source.Auth = nil
+			}
+
+			s, err := Export(ctx, source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b bytes.Buffer
+			if err = Write(&b, s); err != nil {
+				t.Fatal(err)
+			}
+
+			s, err = Read(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			destination, _, importedRooms, _, importedKeys := newServices()
+			if err = Import(ctx, destination, s); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, pair := range []struct {
+				got      func() (any, error)
+				expected func() (any, error)
+			}{
+				{func() (any, error) { return destination.User.Export(ctx) }, func() (any, error) { return source.User.Export(ctx) }},
+				{func() (any, error) { return destination.Room.Export(ctx) }, func() (any, error) { return source.Room.Export(ctx) }},
+				{func() (any, error) { return destination.Message.Export(ctx) }, func() (any, error) { return source.Message.Export(ctx) }},
+				{func() (any, error) { return destination.Reaction.Export(ctx) }, func() (any, error) { return source.Reaction.Export(ctx) }},
+				{func() (any, error) { return destination.Pin.Export(ctx) }, func() (any, error) { return source.Pin.Export(ctx) }},
+				{func() (any, error) { return destination.Invite.Export(ctx) }, func() (any, error) { return source.Invite.Export(ctx) }},
+			} {
+				got, err := pair.got()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				expected, err := pair.expected()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !reflect.DeepEqual(got, expected) {
+					t.Fatalf("got %v, expected %v", got, expected)
+				}
+			}
+
+			keys, err := importedKeys.ListKeys()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(keys) != input.expectedSessions {
+				t.Fatalf("got %d sessions, expected %d", len(keys), input.expectedSessions)
+			}
+
+			createdRoom, err := importedRooms.Create(ctx, room.CreateRoomOpts{Name: "gotham", UserId: "batman"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if createdRoom.Id != s.Rooms[0].Id+1 {
+				t.Fatalf("got room id %d, expected %d", createdRoom.Id, s.Rooms[0].Id+1)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	var newer bytes.Buffer
+	if err := Write(&newer, &Snapshot{Version: Version + 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Snapshots from before reactions and pins were added are still read.
+	var older bytes.Buffer
+	if err := Write(&older, &Snapshot{Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var garbage bytes.Buffer
+	zw := gzip.NewWriter(&garbage)
+	_, _ = zw.Write([]byte("not json"))
+	_ = zw.Close()
+
+	tests := map[string]struct {
+		data        []byte
+		expectedErr error
+	}{
+		"older version": {
+			data:        older.Bytes(),
+			expectedErr: nil,
+		},
+		"newer version": {
+			data:        newer.Bytes(),
+			expectedErr: ErrUnsupportedVersion,
+		},
+		"not gzip": {
+			data:        []byte("not gzip"),
+			expectedErr: ErrInvalidSnapshot,
+		},
+		"not json": {
+			data:        garbage.Bytes(),
+			expectedErr: ErrInvalidSnapshot,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Read(bytes.NewReader(input.data)); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+		})
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "worsediscord.snapshot")
+
+	if err := WriteFile(name, &Snapshot{Version: Version, CreatedAt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFile(name, &Snapshot{Version: Version, CreatedAt: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.CreatedAt != 2 {
+		t.Fatalf("got created at %d, expected %d", s.CreatedAt, 2)
+	}
+
+	matches, err := filepath.Glob(name + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != 0 {
+		t.Fatalf("got leftover files %v, expected none", matches)
+	}
+}
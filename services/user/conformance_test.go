@@ -0,0 +1,12 @@
+package user_test
+
+import (
+	"testing"
+
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/services/user/usertest"
+)
+
+func TestMap_Conformance(t *testing.T) {
+	usertest.Run(t, func(*testing.T) user.Service { return user.NewMap() })
+}
@@ -2,12 +2,16 @@ package user
 
 import (
 	"context"
+	"sync"
 
 	"github.com/eolso/threadsafe"
 )
 
 type Map struct {
 	data *threadsafe.Map[string, *User]
+
+	// lock serializes writes that read a user before replacing it.
+	lock sync.Mutex
 }
 
 func NewMap() *Map {
@@ -17,6 +21,9 @@ func NewMap() *Map {
 }
 
 func (m *Map) Create(_ context.Context, opts CreateUserOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	if _, ok := m.data.Get(opts.Username); ok {
 		return ErrConflict
 	}
@@ -49,7 +56,57 @@ func (m *Map) List(_ context.Context) ([]*User, error) {
 	return m.data.Values(), nil
 }
 
+func (m *Map) Update(_ context.Context, opts UpdateUserOpts) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	u, ok := m.data.Get(opts.Id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	updated := *u
+
+	if opts.Password != nil {
+		updated.Password = *opts.Password
+	}
+
+	if opts.Admin != nil {
+		updated.Admin = *opts.Admin
+	}
+
+	if opts.Disabled != nil {
+		updated.Disabled = *opts.Disabled
+	}
+
+	m.data.Set(opts.Id, &updated)
+
+	return nil
+}
+
 func (m *Map) Delete(_ context.Context, opts DeleteUserOpts) error {
 	m.data.Delete(opts.Id)
 	return nil
 }
+
+// Export returns every user, including their password.
+func (m *Map) Export(_ context.Context) ([]*User, error) {
+	return m.data.Values(), nil
+}
+
+// Import stores users as they are, replacing any user with the same username.
+func (m *Map) Import(_ context.Context, users []*User) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, u := range users {
+		imported := *u
+		m.data.Set(u.Username, &imported)
+	}
+
+	return nil
+}
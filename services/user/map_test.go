@@ -133,3 +133,55 @@ func TestMap_Delete(t *testing.T) {
 		t.Fatalf("got error %q, expected nil", err)
 	}
 }
+
+func TestMap_Update(t *testing.T) {
+	m := NewMap()
+
+	if err := m.Create(nil, CreateUserOpts{Username: "spiderman", Password: "uncleben123"}); err != nil {
+		t.Fatalf("failed to prepopulate map: %v", err)
+	}
+
+	admin := true
+	shortPassword := "ben"
+
+	tests := map[string]struct {
+		opts         UpdateUserOpts
+		expectedUser *User
+		expectedErr  error
+	}{
+		"valid": {
+			opts:         UpdateUserOpts{Id: "spiderman", Admin: &admin},
+			expectedUser: &User{Username: "spiderman", Nickname: "spiderman", Password: "uncleben123", Admin: true},
+			expectedErr:  nil,
+		},
+		"not found": {
+			opts:        UpdateUserOpts{Id: "antman", Admin: &admin},
+			expectedErr: ErrNotFound,
+		},
+		"invalid password": {
+			opts:        UpdateUserOpts{Id: "spiderman", Password: &shortPassword},
+			expectedErr: ErrInvalidPassword,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := m.Update(nil, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %q, expected %q", err, input.expectedErr)
+			}
+
+			if input.expectedUser == nil {
+				return
+			}
+
+			u, err := m.GetUserById(nil, GetUserByIdOpts{Id: input.opts.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(u, input.expectedUser) {
+				t.Fatalf("got user %#v, expected %#v", u, input.expectedUser)
+			}
+		})
+	}
+}
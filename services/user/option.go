@@ -13,6 +13,14 @@ type DeleteUserOpts struct {
 	Id string
 }
 
+// UpdateUserOpts describes changes to a user. Nil fields are left unchanged.
+type UpdateUserOpts struct {
+	Id       string
+	Password *string
+	Admin    *bool
+	Disabled *bool
+}
+
 func (c CreateUserOpts) Validate() error {
 	if c.Username == "" {
 		return ErrInvalidUsername
@@ -24,3 +32,11 @@ func (c CreateUserOpts) Validate() error {
 
 	return nil
 }
+
+func (u UpdateUserOpts) Validate() error {
+	if u.Password != nil && len(*u.Password) < 8 {
+		return ErrInvalidPassword
+	}
+
+	return nil
+}
@@ -7,4 +7,7 @@ type Service interface {
 	GetUserById(context.Context, GetUserByIdOpts) (*User, error)
 	List(context.Context) ([]*User, error)
 	Delete(context.Context, DeleteUserOpts) error
+	Update(context.Context, UpdateUserOpts) error
+	Export(context.Context) ([]*User, error)
+	Import(context.Context, []*User) error
 }
@@ -4,4 +4,6 @@ type User struct {
 	Username string `json:"username,omitempty"`
 	Nickname string `json:"nickname,omitempty"`
 	Password string `json:"password,omitempty"`
+	Admin    bool   `json:"admin,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
 }
@@ -0,0 +1,357 @@
+// Package usertest checks that a user.Service behaves the same as user.Map.
+package usertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/worsediscord/server/services/user"
+)
+
+// Run runs the conformance suite against the services returned by newService. Every subtest calls newService once with
+// its own *testing.T, which newService should fail if it can't return an empty service.
+func Run(t *testing.T, newService func(*testing.T) user.Service) {
+	t.Helper()
+
+	tests := map[string]func(*testing.T, user.Service){
+		"Create":           testCreate,
+		"GetUserById":      testGetUserById,
+		"List":             testList,
+		"Update":           testUpdate,
+		"Delete":           testDelete,
+		"ExportImport":     testExportImport,
+		"ConcurrentCreate": testConcurrentCreate,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newService(t))
+		})
+	}
+}
+
+func testCreate(t *testing.T, s user.Service) {
+	ctx := context.Background()
+
+	if err := s.Create(ctx, user.CreateUserOpts{Username: "spiderman", Password: "maryjane"}); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := map[string]struct {
+		opts        user.CreateUserOpts
+		expectedErr error
+	}{
+		"valid": {
+			opts:        user.CreateUserOpts{Username: "venom", Password: "eddiebrock"},
+			expectedErr: nil,
+		},
+		"conflict": {
+			opts:        user.CreateUserOpts{Username: "spiderman", Password: "maryjane"},
+			expectedErr: user.ErrConflict,
+		},
+		"conflict before invalid password": {
+			opts:        user.CreateUserOpts{Username: "spiderman", Password: "short"},
+			expectedErr: user.ErrConflict,
+		},
+		"empty username": {
+			opts:        user.CreateUserOpts{Username: "", Password: "maryjane"},
+			expectedErr: user.ErrInvalidUsername,
+		},
+		"short password": {
+			opts:        user.CreateUserOpts{Username: "carnage", Password: "kasady"},
+			expectedErr: user.ErrInvalidPassword,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Create(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+		})
+	}
+
+	if _, err := s.GetUserById(ctx, user.GetUserByIdOpts{Id: "carnage"}); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("got error %v, expected %v for a user that failed validation", err, user.ErrNotFound)
+	}
+}
+
+func testGetUserById(t *testing.T, s user.Service) {
+	ctx := context.Background()
+
+	if err := s.Create(ctx, user.CreateUserOpts{Username: "spiderman", Password: "maryjane"}); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := map[string]struct {
+		opts         user.GetUserByIdOpts
+		expectedUser *user.User
+		expectedErr  error
+	}{
+		"valid": {
+			opts:         user.GetUserByIdOpts{Id: "spiderman"},
+			expectedUser: &user.User{Username: "spiderman", Nickname: "spiderman", Password: "maryjane"},
+			expectedErr:  nil,
+		},
+		"not found": {
+			opts:         user.GetUserByIdOpts{Id: "venom"},
+			expectedUser: nil,
+			expectedErr:  user.ErrNotFound,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			u, err := s.GetUserById(ctx, input.opts)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if !equal(u, input.expectedUser) {
+				t.Fatalf("got %v, expected %v", u, input.expectedUser)
+			}
+		})
+	}
+}
+
+func testList(t *testing.T, s user.Service) {
+	ctx := context.Background()
+
+	users, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if users == nil || len(users) != 0 {
+		t.Fatalf("got %v, expected an empty, non-nil slice", users)
+	}
+
+	for _, username := range []string{"spiderman", "venom", "carnage"} {
+		if err = s.Create(ctx, user.CreateUserOpts{Username: username, Password: "password"}); err != nil {
+			t.Fatalf("failed to prepopulate service: %v", err)
+		}
+	}
+
+	users, err = s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, expected := usernames(users), []string{"carnage", "spiderman", "venom"}; !slices.Equal(got, expected) {
+		t.Fatalf("got %v, expected %v", got, expected)
+	}
+}
+
+func testUpdate(t *testing.T, s user.Service) {
+	ctx := context.Background()
+
+	if err := s.Create(ctx, user.CreateUserOpts{Username: "spiderman", Password: "maryjane"}); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	password := "gwenstacy"
+	short := "mj"
+	admin := true
+
+	tests := []struct {
+		name         string
+		opts         user.UpdateUserOpts
+		expectedUser *user.User
+		expectedErr  error
+	}{
+		{
+			name:         "password",
+			opts:         user.UpdateUserOpts{Id: "spiderman", Password: &password},
+			expectedUser: &user.User{Username: "spiderman", Nickname: "spiderman", Password: "gwenstacy"},
+			expectedErr:  nil,
+		},
+		{
+			name:         "admin leaves password alone",
+			opts:         user.UpdateUserOpts{Id: "spiderman", Admin: &admin},
+			expectedUser: &user.User{Username: "spiderman", Nickname: "spiderman", Password: "gwenstacy", Admin: true},
+			expectedErr:  nil,
+		},
+		{
+			name:         "short password",
+			opts:         user.UpdateUserOpts{Id: "spiderman", Password: &short},
+			expectedUser: &user.User{Username: "spiderman", Nickname: "spiderman", Password: "gwenstacy", Admin: true},
+			expectedErr:  user.ErrInvalidPassword,
+		},
+		{
+			name:         "not found before invalid password",
+			opts:         user.UpdateUserOpts{Id: "venom", Password: &short},
+			expectedUser: nil,
+			expectedErr:  user.ErrNotFound,
+		},
+	}
+
+	for _, input := range tests {
+		t.Run(input.name, func(t *testing.T) {
+			if err := s.Update(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			u, _ := s.GetUserById(ctx, user.GetUserByIdOpts{Id: input.opts.Id})
+			if !equal(u, input.expectedUser) {
+				t.Fatalf("got %v, expected %v", u, input.expectedUser)
+			}
+		})
+	}
+}
+
+func testDelete(t *testing.T, s user.Service) {
+	ctx := context.Background()
+
+	if err := s.Create(ctx, user.CreateUserOpts{Username: "spiderman", Password: "maryjane"}); err != nil {
+		t.Fatalf("failed to prepopulate service: %v", err)
+	}
+
+	tests := map[string]struct {
+		opts        user.DeleteUserOpts
+		expectedErr error
+	}{
+		"valid": {
+			opts:        user.DeleteUserOpts{Id: "spiderman"},
+			expectedErr: nil,
+		},
+		"missing user": {
+			opts:        user.DeleteUserOpts{Id: "venom"},
+			expectedErr: nil,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Delete(ctx, input.opts); !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if _, err := s.GetUserById(ctx, user.GetUserByIdOpts{Id: input.opts.Id}); !errors.Is(err, user.ErrNotFound) {
+				t.Fatalf("got error %v, expected %v after delete", err, user.ErrNotFound)
+			}
+		})
+	}
+
+	// A deleted username can be registered again.
+	if err := s.Create(ctx, user.CreateUserOpts{Username: "spiderman", Password: "maryjane"}); err != nil {
+		t.Fatalf("got error %v, expected nil", err)
+	}
+}
+
+func testExportImport(t *testing.T, s user.Service) {
+	ctx := context.Background()
+
+	users := []*user.User{
+		{Username: "spiderman", Nickname: "spidey", Password: "maryjane", Admin: true},
+		{Username: "venom", Nickname: "venom", Password: "eddiebrock", Disabled: true},
+	}
+
+	if err := s.Import(ctx, users); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := s.Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exported) != len(users) {
+		t.Fatalf("got %d users, expected %d", len(exported), len(users))
+	}
+
+	for _, expected := range users {
+		u, err := s.GetUserById(ctx, user.GetUserByIdOpts{Id: expected.Username})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !equal(u, expected) {
+			t.Fatalf("got %v, expected %v", u, expected)
+		}
+	}
+
+	// Importing a user that exists replaces it.
+	replacement := &user.User{Username: "venom", Nickname: "venom", Password: "eddiebrock"}
+	if err = s.Import(ctx, []*user.User{replacement}); err != nil {
+		t.Fatal(err)
+	}
+
+	if u, _ := s.GetUserById(ctx, user.GetUserByIdOpts{Id: "venom"}); !equal(u, replacement) {
+		t.Fatalf("got %v, expected %v", u, replacement)
+	}
+}
+
+func testConcurrentCreate(t *testing.T, s user.Service) {
+	ctx := context.Background()
+
+	const workers = 16
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*2)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+
+		// Every worker races to create the same user, as well as one of their own.
+		go func() {
+			defer wg.Done()
+			errs <- s.Create(ctx, user.CreateUserOpts{Username: "spiderman", Password: "maryjane"})
+		}()
+
+		go func() {
+			defer wg.Done()
+			if err := s.Create(ctx, user.CreateUserOpts{Username: fmt.Sprintf("symbiote%d", i), Password: "password"}); err != nil {
+				t.Errorf("failed to create symbiote%d: %v", i, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+		} else if !errors.Is(err, user.ErrConflict) {
+			t.Fatalf("got error %v, expected nil or %v", err, user.ErrConflict)
+		}
+	}
+
+	if created != 1 {
+		t.Fatalf("spiderman was created %d times, expected 1", created)
+	}
+
+	users, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != workers+1 {
+		t.Fatalf("got %d users, expected %d", len(users), workers+1)
+	}
+}
+
+// equal compares users by value, since services are free to return copies.
+func equal(a, b *user.User) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func usernames(users []*user.User) []string {
+	names := make([]string, 0, len(users))
+	for _, u := range users {
+		names = append(names, u.Username)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,41 @@
+package wal
+
+import (
+	"testing"
+
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/auth/authtest"
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/invite/invitetest"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/message/messagetest"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/pin/pintest"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/reaction/reactiontest"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/room/roomtest"
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/services/user/usertest"
+)
+
+// TestConformance checks that recording changes doesn't change how the services behave.
+func TestConformance(t *testing.T) {
+	open := func(t *testing.T) Services {
+		l, err := Open(t.TempDir(), newMaps(), Opts{Sync: SyncNever}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = l.Close() })
+
+		return l.Services()
+	}
+
+	usertest.Run(t, func(t *testing.T) user.Service { return open(t).User })
+	roomtest.Run(t, func(t *testing.T) room.Service { return open(t).Room })
+	messagetest.Run(t, func(t *testing.T) message.Service { return open(t).Message })
+	reactiontest.Run(t, func(t *testing.T) reaction.Service { return open(t).Reaction })
+	pintest.Run(t, func(t *testing.T) pin.Service { return open(t).Pin })
+	invitetest.Run(t, func(t *testing.T) invite.Service { return open(t).Invite })
+	authtest.Run(t, func(t *testing.T) auth.Service { return open(t).Auth })
+}
@@ -0,0 +1,10 @@
+package wal
+
+import "errors"
+
+var (
+	ErrInvalidSyncPolicy = errors.New("invalid sync policy")
+	ErrCorruptLog        = errors.New("write-ahead log is corrupt")
+	ErrClosed            = errors.New("write-ahead log is closed")
+	ErrLocked            = errors.New("write-ahead log is in use by another process")
+)
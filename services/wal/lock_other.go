@@ -0,0 +1,14 @@
+//go:build !unix
+
+package wal
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// lockDir only creates the lock file, since file locks aren't supported on this platform. Nothing stops two processes
+// from opening the same log.
+func lockDir(dir string) (*os.File, error) {
+	return os.OpenFile(filepath.Join(dir, lockFileName), os.O_CREATE|os.O_RDWR, 0600)
+}
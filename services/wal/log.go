@@ -0,0 +1,565 @@
+package wal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/snapshot"
+	"github.com/worsediscord/server/services/user"
+	"github.com/worsediscord/server/util"
+)
+
+const (
+	logFileName      = "wal.log"
+	snapshotFileName = "snapshot.gz"
+	lockFileName     = "wal.lock"
+)
+
+// Services are the services that a Log records changes to. Auth may be nil, in which case sessions are neither recorded
+// nor restored. Session tokens are written to disk as they are, so Auth should only be set if sessions are meant to
+// outlive the process.
+type Services struct {
+	User     user.Service
+	Room     room.Service
+	Message  message.Service
+	Reaction reaction.Service
+	Pin      pin.Service
+	Invite   invite.Service
+	Auth     auth.Service
+}
+
+// Log is an append-only log of every change made to a set of services, usually the in-memory maps. The services are
+// restored from the latest snapshot and the log when opened, and the log is periodically compacted into a new snapshot.
+//
+// A change is first made to a staging copy of the services, to work out what it results in, and only applied to the
+// services once its records are written. The staging copy is a second set of in-memory maps, which share strings but
+// not structs with the services, so it roughly doubles the memory they use.
+type Log struct {
+	dir     string
+	opts    Opts
+	inner   Services
+	staging Services
+	logger  *slog.Logger
+
+	file     *os.File
+	lockFile *os.File
+	lock     sync.Mutex
+	dirty    bool
+	closed   bool
+
+	// err is set once a change fails after it was staged, either because its records couldn't be written or because
+	// they couldn't be applied. The staging services are ahead of the services from then on, so every later change is
+	// refused until a compaction catches the log and the staging services up with the services.
+	err error
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Open restores inner from the snapshot and log in dir, creating dir if needed. Changes must be made through the
+// services returned by Services to be recorded. logHandler may be nil. ErrLocked is returned if another process has
+// the log open.
+func Open(dir string, inner Services, opts Opts, logHandler slog.Handler) (*Log, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	if logHandler == nil {
+		logHandler = util.NopLogHandler
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	lockFile, err := lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		dir:      dir,
+		opts:     opts,
+		inner:    inner,
+		logger:   slog.New(logHandler).With(slog.String("component", "wal")),
+		lockFile: lockFile,
+		stop:     make(chan struct{}),
+	}
+
+	if err = l.restore(); err != nil {
+		return nil, errors.Join(err, lockFile.Close())
+	}
+
+	if err = l.resync(); err != nil {
+		return nil, errors.Join(err, lockFile.Close())
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, logFileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Join(err, lockFile.Close())
+	}
+	l.file = f
+
+	l.wg.Add(1)
+	go l.run()
+
+	return l, nil
+}
+
+// Services returns services that record every change to the log before making it.
+func (l *Log) Services() Services {
+	services := Services{
+		User:     &UserService{Service: l.inner.User, log: l},
+		Room:     &RoomService{Service: l.inner.Room, log: l},
+		Message:  &MessageService{Service: l.inner.Message, log: l},
+		Reaction: &ReactionService{Service: l.inner.Reaction, log: l},
+		Pin:      &PinService{Service: l.inner.Pin, log: l},
+		Invite:   &InviteService{Service: l.inner.Invite, log: l},
+	}
+
+	if l.inner.Auth != nil {
+		services.Auth = &AuthService{Service: l.inner.Auth, log: l}
+	}
+
+	return services
+}
+
+// Compact writes a snapshot of the services and truncates the log. Replaying a log over a snapshot taken after it is
+// harmless, so a crash part way through loses nothing.
+func (l *Log) Compact() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+
+	return l.compact()
+}
+
+// Close stops the background sync and compaction, compacts the log one last time and closes it.
+func (l *Log) Close() error {
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		return ErrClosed
+	}
+	close(l.stop)
+	l.lock.Unlock()
+
+	l.wg.Wait()
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	err := l.compact()
+	l.closed = true
+
+	return errors.Join(err, l.file.Close(), l.lockFile.Close())
+}
+
+// commit makes a change to the staging services with stage and appends the records of it while holding the lock, so
+// that the log is in the same order as the changes. The records are applied to the services once they're written. If
+// stage fails nothing is written.
+func (l *Log) commit(stage func() error, records func() ([]Record, error)) error {
+	return l.write(stage, records, func(records []Record) error {
+		for _, r := range records {
+			if err := l.replay(r); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// record appends the records of a change while holding the lock and makes the change with apply once they're written.
+// It's for changes whose records follow from their arguments, which don't need to be staged.
+func (l *Log) record(records func() ([]Record, error), apply func() error) error {
+	return l.write(nil, records, func([]Record) error {
+		return apply()
+	})
+}
+
+func (l *Log) write(stage func() error, records func() ([]Record, error), apply func([]Record) error) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+
+	if l.err != nil {
+		return l.err
+	}
+
+	if stage != nil {
+		if err := stage(); err != nil {
+			return err
+		}
+	}
+
+	recs, err := records()
+	if err != nil {
+		l.err = fmt.Errorf("failed to record change: %w", err)
+		return l.err
+	}
+
+	var b bytes.Buffer
+	for _, r := range recs {
+		line, err := json.Marshal(r)
+		if err != nil {
+			l.err = fmt.Errorf("failed to record change: %w", err)
+			return l.err
+		}
+
+		b.Write(append(line, '\n'))
+	}
+
+	if _, err = l.file.Write(b.Bytes()); err != nil {
+		l.err = fmt.Errorf("failed to append to write-ahead log: %w", err)
+		return l.err
+	}
+
+	switch l.opts.Sync {
+	case SyncAlways:
+		if err = l.file.Sync(); err != nil {
+			l.err = fmt.Errorf("failed to sync write-ahead log: %w", err)
+			return l.err
+		}
+	case SyncInterval:
+		l.dirty = true
+	}
+
+	if err = apply(recs); err != nil {
+		l.err = fmt.Errorf("failed to apply write-ahead log record: %w", err)
+		return l.err
+	}
+
+	return nil
+}
+
+// compact must be called with the lock held.
+func (l *Log) compact() error {
+	snap, err := snapshot.Export(context.Background(), l.snapshotServices())
+	if err != nil {
+		return err
+	}
+
+	if err = snapshot.WriteFile(filepath.Join(l.dir, snapshotFileName), snap); err != nil {
+		return err
+	}
+
+	if err = l.file.Truncate(0); err != nil {
+		return err
+	}
+
+	if err = l.file.Sync(); err != nil {
+		return err
+	}
+
+	if l.err != nil {
+		if err = l.resync(); err != nil {
+			return err
+		}
+	}
+
+	l.dirty = false
+	l.err = nil
+
+	return nil
+}
+
+func (l *Log) run() {
+	defer l.wg.Done()
+
+	var syncTick, compactTick <-chan time.Time
+
+	if l.opts.Sync == SyncInterval {
+		t := time.NewTicker(l.opts.SyncInterval)
+		defer t.Stop()
+		syncTick = t.C
+	}
+
+	if l.opts.CompactInterval > 0 {
+		t := time.NewTicker(l.opts.CompactInterval)
+		defer t.Stop()
+		compactTick = t.C
+	}
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-syncTick:
+			l.sync()
+		case <-compactTick:
+			if err := l.Compact(); err != nil {
+				l.logger.Error("failed to compact write-ahead log", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+func (l *Log) sync() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !l.dirty || l.closed {
+		return
+	}
+
+	if err := l.file.Sync(); err != nil {
+		l.err = fmt.Errorf("failed to sync write-ahead log: %w", err)
+		l.logger.Error("failed to sync write-ahead log", slog.String("error", err.Error()))
+		return
+	}
+
+	l.dirty = false
+}
+
+// restore imports the snapshot and replays the log. A partially written final record, left by a crash, is dropped.
+func (l *Log) restore() error {
+	snap, err := snapshot.ReadFile(filepath.Join(l.dir, snapshotFileName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if snap != nil {
+		if err = snapshot.Import(context.Background(), l.snapshotServices(), snap); err != nil {
+			return err
+		}
+	}
+
+	name := filepath.Join(l.dir, logFileName)
+
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	offset := 0
+	for line := 1; offset < len(data); line++ {
+		n := bytes.IndexByte(data[offset:], '\n')
+		if n == -1 {
+			break
+		}
+
+		var r Record
+		if err = json.Unmarshal(data[offset:offset+n], &r); err != nil {
+			if offset+n+1 == len(data) {
+				break
+			}
+
+			return fmt.Errorf("%w: line %d: %w", ErrCorruptLog, line, err)
+		}
+
+		if err = l.replay(r); err != nil {
+			return fmt.Errorf("%w: line %d: %w", ErrCorruptLog, line, err)
+		}
+
+		offset += n + 1
+	}
+
+	if offset < len(data) {
+		l.logger.Warn("dropping partially written record", slog.Int("bytes", len(data)-offset))
+		return os.Truncate(name, int64(offset))
+	}
+
+	return nil
+}
+
+func (l *Log) replay(r Record) error {
+	ctx := context.Background()
+
+	switch r.Service {
+	case serviceUser:
+		if r.Op == OpDelete {
+			return l.inner.User.Delete(ctx, user.DeleteUserOpts{Id: r.Key})
+		}
+
+		var u user.User
+		if err := json.Unmarshal(r.Value, &u); err != nil {
+			return err
+		}
+
+		return l.inner.User.Import(ctx, []*user.User{&u})
+	case serviceRoom:
+		if r.Op == OpDelete {
+			id, err := strconv.ParseInt(r.Key, 10, 64)
+			if err != nil {
+				return err
+			}
+
+			if err = l.inner.Room.Delete(ctx, room.DeleteRoomOpts{Id: id, Force: true}); !errors.Is(err, room.ErrNotFound) {
+				return err
+			}
+
+			return nil
+		}
+
+		var rm room.Room
+		if err := json.Unmarshal(r.Value, &rm); err != nil {
+			return err
+		}
+
+		return l.inner.Room.Import(ctx, []*room.Room{&rm})
+	case serviceMessage:
+		if r.Op == OpDelete {
+			if err := l.inner.Message.Delete(ctx, message.DeleteMessageOpts{Id: r.Key}); !errors.Is(err, message.ErrNotFound) {
+				return err
+			}
+
+			return nil
+		}
+
+		var msg message.Message
+		if err := json.Unmarshal(r.Value, &msg); err != nil {
+			return err
+		}
+
+		return l.inner.Message.Import(ctx, []*message.Message{&msg})
+	case serviceReaction:
+		if r.Op == OpDelete {
+			var key []string
+			if err := json.Unmarshal([]byte(r.Key), &key); err != nil {
+				return err
+			}
+
+			if len(key) != 3 {
+				return fmt.Errorf("invalid reaction key %q", r.Key)
+			}
+
+			opts := reaction.RemoveReactionOpts{MessageId: key[0], UserId: key[1], Emoji: key[2]}
+			if err := l.inner.Reaction.Remove(ctx, opts); !errors.Is(err, reaction.ErrNotFound) {
+				return err
+			}
+
+			return nil
+		}
+
+		var rct reaction.Reaction
+		if err := json.Unmarshal(r.Value, &rct); err != nil {
+			return err
+		}
+
+		return l.inner.Reaction.Import(ctx, []*reaction.Reaction{&rct})
+	case servicePin:
+		if r.Op == OpDelete {
+			if err := l.inner.Pin.Remove(ctx, pin.RemovePinOpts{MessageId: r.Key}); !errors.Is(err, pin.ErrNotFound) {
+				return err
+			}
+
+			return nil
+		}
+
+		var pn pin.Pin
+		if err := json.Unmarshal(r.Value, &pn); err != nil {
+			return err
+		}
+
+		return l.inner.Pin.Import(ctx, []*pin.Pin{&pn})
+	case serviceInvite:
+		if r.Op == OpDelete {
+			if err := l.inner.Invite.Revoke(ctx, invite.RevokeInviteOpts{Code: r.Key}); !errors.Is(err, invite.ErrNotFound) {
+				return err
+			}
+
+			return nil
+		}
+
+		var inv invite.Invite
+		if err := json.Unmarshal(r.Value, &inv); err != nil {
+			return err
+		}
+
+		return l.inner.Invite.Import(ctx, []*invite.Invite{&inv})
+	case serviceSession:
+		// Sessions recorded while they were kept are dropped once they no longer are.
+		if l.inner.Auth == nil {
+			return nil
+		}
+
+		if r.Op == OpDelete {
+			return l.inner.Auth.RevokeKey(r.Key)
+		}
+
+		var session snapshot.Session
+		if err := json.Unmarshal(r.Value, &session); err != nil {
+			return err
+		}
+
+		key := auth.RestoreApiKey(session.Token, time.UnixMilli(session.ExpiresAt), session.Username)
+
+		return l.inner.Auth.Import(ctx, []auth.ApiKey{key})
+	default:
+		return fmt.Errorf("unknown service %q", r.Service)
+	}
+}
+
+// resync replaces the staging services with a copy of the services.
+func (l *Log) resync() error {
+	ctx := context.Background()
+
+	snap, err := snapshot.Export(ctx, portable(l.inner))
+	if err != nil {
+		return err
+	}
+
+	staging := Services{
+		User:     user.NewMap(),
+		Room:     room.NewMap(),
+		Message:  message.NewMap(),
+		Reaction: reaction.NewMap(),
+		Pin:      pin.NewMap(),
+		Invite:   invite.NewMap(),
+	}
+
+	if err = snapshot.Import(ctx, portable(staging), snap); err != nil {
+		return err
+	}
+
+	l.staging = staging
+
+	return nil
+}
+
+// snapshotServices returns the services that snapshots are taken of.
+func (l *Log) snapshotServices() snapshot.Services {
+	return portable(l.inner)
+}
+
+// portable returns services as snapshot services. Sessions are only included if Auth is set.
+func portable(services Services) snapshot.Services {
+	s := snapshot.Services{
+		User:     services.User,
+		Room:     services.Room,
+		Message:  services.Message,
+		Reaction: services.Reaction,
+		Pin:      services.Pin,
+		Invite:   services.Invite,
+	}
+
+	if services.Auth != nil {
+		s.Auth = services.Auth
+	}
+
+	return s
+}
@@ -0,0 +1,633 @@
+package wal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/snapshot"
+	"github.com/worsediscord/server/services/user"
+)
+
+func newMaps() Services {
+	return Services{User: user.NewMap(), Room: room.NewMap(), Message: message.NewMap(), Reaction: reaction.NewMap(), Pin: pin.NewMap(), Invite: invite.NewMap(), Auth: auth.NewMap()}
+}
+
+var (
+	spidermanKey = auth.NewApiKey(16, time.Hour, "spiderman")
+	venomKey     = auth.NewApiKey(16, time.Hour, "venom")
+)
+
+// crash stops l without compacting, leaving everything in the log.
+func crash(t *testing.T, l *Log) {
+	close(l.stop)
+	l.wg.Wait()
+
+	if err := l.file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.lockFile.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// populate makes one of every change through services and returns the id of the room it creates.
+func populate(t *testing.T, services Services) int64 {
+	ctx := context.Background()
+
+	for _, username := range []string{"spiderman", "venom", "batman"} {
+		if err := services.User.Create(ctx, user.CreateUserOpts{Username: username, Password: "uncleben123"}); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+
+	admin := true
+	if err := services.User.Update(ctx, user.UpdateUserOpts{Id: "spiderman", Admin: &admin}); err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+
+	if err := services.User.Delete(ctx, user.DeleteUserOpts{Id: "batman"}); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+
+	r, err := services.Room.Create(ctx, room.CreateRoomOpts{Name: "the big apple", UserId: "spiderman"})
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+
+	deleted, err := services.Room.Create(ctx, room.CreateRoomOpts{Name: "gotham", UserId: "batman"})
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+
+	if err = services.Room.Delete(ctx, room.DeleteRoomOpts{Id: deleted.Id, UserId: "batman"}); err != nil {
+		t.Fatalf("failed to delete room: %v", err)
+	}
+
+	if err = services.Room.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: "venom"}); err != nil {
+		t.Fatalf("failed to join room: %v", err)
+	}
+
+	if err = services.Room.Promote(ctx, room.PromoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom"}); err != nil {
+		t.Fatalf("failed to promote: %v", err)
+	}
+
+	if err = services.Room.ConfigurePins(ctx, room.ConfigurePinsRoomOpts{Id: r.Id, UserId: "spiderman", Pinners: []string{"mj"}, Limit: 5}); err != nil {
+		t.Fatalf("failed to configure pins: %v", err)
+	}
+
+	topic := "friendly neighborhood"
+	if _, err = services.Room.Update(ctx, room.UpdateRoomOpts{Id: r.Id, UserId: "spiderman", Topic: &topic}); err != nil {
+		t.Fatalf("failed to update room: %v", err)
+	}
+
+	if err = services.Room.Join(ctx, room.JoinRoomOpts{Id: r.Id, UserId: "carnage"}); err != nil {
+		t.Fatalf("failed to join room: %v", err)
+	}
+
+	if err = services.Room.Promote(ctx, room.PromoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "carnage"}); err != nil {
+		t.Fatalf("failed to promote: %v", err)
+	}
+
+	if err = services.Room.Demote(ctx, room.DemoteRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "carnage"}); err != nil {
+		t.Fatalf("failed to demote: %v", err)
+	}
+
+	if err = services.Room.Transfer(ctx, room.TransferRoomOpts{Id: r.Id, UserId: "spiderman", TargetId: "venom"}); err != nil {
+		t.Fatalf("failed to transfer: %v", err)
+	}
+
+	if err = services.Room.Leave(ctx, room.LeaveRoomOpts{Id: r.Id, UserId: "carnage"}); err != nil {
+		t.Fatalf("failed to leave room: %v", err)
+	}
+
+	if err = services.Room.Ban(ctx, room.BanRoomOpts{Id: r.Id, UserId: "venom", TargetId: "carnage", Reason: "maximum carnage", Now: 1000}); err != nil {
+		t.Fatalf("failed to ban: %v", err)
+	}
+
+	if err = services.Room.Mute(ctx, room.MuteRoomOpts{Id: r.Id, UserId: "venom", TargetId: "mj", Now: 1000, ExpiresAt: 2000}); err != nil {
+		t.Fatalf("failed to mute: %v", err)
+	}
+
+	private, err := services.Room.Create(ctx, room.CreateRoomOpts{Name: "sanctum", UserId: "strange", Visibility: room.VisibilityPrivate})
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+
+	if _, err = services.Room.CreateDirect(ctx, room.CreateDirectRoomOpts{UserIds: []string{"venom", "spiderman"}}); err != nil {
+		t.Fatalf("failed to create direct room: %v", err)
+	}
+
+	used, err := services.Invite.Create(ctx, invite.CreateInviteOpts{RoomId: private.Id, UserId: "strange", MaxUses: 3})
+	if err != nil {
+		t.Fatalf("failed to create invite: %v", err)
+	}
+
+	if _, err = services.Invite.Use(ctx, invite.UseInviteOpts{Code: used.Code, Now: time.Now().UnixMilli()}); err != nil {
+		t.Fatalf("failed to use invite: %v", err)
+	}
+
+	revoked, err := services.Invite.Create(ctx, invite.CreateInviteOpts{RoomId: private.Id, UserId: "strange"})
+	if err != nil {
+		t.Fatalf("failed to create invite: %v", err)
+	}
+
+	if err = services.Invite.Revoke(ctx, invite.RevokeInviteOpts{Code: revoked.Code}); err != nil {
+		t.Fatalf("failed to revoke invite: %v", err)
+	}
+
+	msg, err := services.Message.Create(ctx, message.CreateMessageOpts{UserId: "venom", RoomId: r.Id, Content: "we are venom"})
+	if err != nil {
+		t.Fatalf("failed to create message: %v", err)
+	}
+
+	for _, opts := range []reaction.AddReactionOpts{
+		{MessageId: msg.Id, UserId: "spiderman", Emoji: "🕷️"},
+		{MessageId: msg.Id, UserId: "spiderman", Emoji: "🍕"},
+		{MessageId: msg.Id, UserId: "venom", Emoji: "🍕"},
+	} {
+		if _, err = services.Reaction.Add(ctx, opts); err != nil {
+			t.Fatalf("failed to add reaction: %v", err)
+		}
+	}
+
+	if err = services.Reaction.Remove(ctx, reaction.RemoveReactionOpts{MessageId: msg.Id, UserId: "spiderman", Emoji: "🍕"}); err != nil {
+		t.Fatalf("failed to remove reaction: %v", err)
+	}
+
+	if _, err = services.Message.Create(ctx, message.CreateMessageOpts{UserId: "spiderman", RoomId: r.Id, Content: "no you aren't", ReplyTo: msg.Id}); err != nil {
+		t.Fatalf("failed to create reply: %v", err)
+	}
+
+	deletedMsg, err := services.Message.Create(ctx, message.CreateMessageOpts{UserId: "spiderman", RoomId: r.Id, Content: "thwip", ReplyTo: msg.Id})
+	if err != nil {
+		t.Fatalf("failed to create message: %v", err)
+	}
+
+	if _, err = services.Reaction.Add(ctx, reaction.AddReactionOpts{MessageId: deletedMsg.Id, UserId: "venom", Emoji: "🍕"}); err != nil {
+		t.Fatalf("failed to add reaction: %v", err)
+	}
+
+	for _, id := range []string{msg.Id, deletedMsg.Id} {
+		if _, err = services.Pin.Add(ctx, pin.AddPinOpts{RoomId: r.Id, MessageId: id, UserId: "spiderman", Limit: 5}); err != nil {
+			t.Fatalf("failed to pin message: %v", err)
+		}
+	}
+
+	if err = services.Pin.Remove(ctx, pin.RemovePinOpts{MessageId: deletedMsg.Id}); err != nil {
+		t.Fatalf("failed to unpin message: %v", err)
+	}
+
+	if err = services.Message.Delete(ctx, message.DeleteMessageOpts{Id: deletedMsg.Id}); err != nil {
+		t.Fatalf("failed to delete message: %v", err)
+	}
+
+	if err = services.Reaction.Clear(ctx, reaction.ClearReactionOpts{MessageIds: []string{deletedMsg.Id}}); err != nil {
+		t.Fatalf("failed to clear reactions: %v", err)
+	}
+
+	if _, err = services.Message.Create(ctx, message.CreateMessageOpts{UserId: "carnage", RoomId: r.Id, Content: "let there be carnage", ReplyTo: msg.Id}); err != nil {
+		t.Fatalf("failed to create reply: %v", err)
+	}
+
+	if _, err = services.Message.Purge(ctx, message.PurgeMessageOpts{UserId: "carnage"}); err != nil {
+		t.Fatalf("failed to purge messages: %v", err)
+	}
+
+	if _, err = services.Message.Create(ctx, message.CreateMessageOpts{UserId: "mysterio", RoomId: r.Id, Content: "nothing is real"}); err != nil {
+		t.Fatalf("failed to create message: %v", err)
+	}
+
+	if err = services.Message.Anonymize(ctx, message.AnonymizeMessageOpts{UserId: "mysterio"}); err != nil {
+		t.Fatalf("failed to anonymize messages: %v", err)
+	}
+
+	for _, key := range []auth.ApiKey{spidermanKey, venomKey} {
+		if err = services.Auth.RegisterKey(key.Token(), key); err != nil {
+			t.Fatalf("failed to register key: %v", err)
+		}
+	}
+
+	if err = services.Auth.RevokeKey(venomKey.Token()); err != nil {
+		t.Fatalf("failed to revoke key: %v", err)
+	}
+
+	return r.Id
+}
+
+func assertRestored(t *testing.T, original Services, restored Services) {
+	ctx := context.Background()
+
+	expectedUsers, _ := original.User.Export(ctx)
+	users, _ := restored.User.Export(ctx)
+	if len(users) != len(expectedUsers) {
+		t.Fatalf("got users %v, expected %v", users, expectedUsers)
+	}
+
+	for _, u := range expectedUsers {
+		got, err := restored.User.GetUserById(ctx, user.GetUserByIdOpts{Id: u.Username})
+		if err != nil || !reflect.DeepEqual(got, u) {
+			t.Fatalf("got user %v (error %v), expected %v", got, err, u)
+		}
+	}
+
+	expectedRooms, _ := original.Room.Export(ctx)
+	rooms, _ := restored.Room.Export(ctx)
+	if !reflect.DeepEqual(rooms, expectedRooms) {
+		t.Fatalf("got rooms %v, expected %v", rooms, expectedRooms)
+	}
+
+	expectedMessages, _ := original.Message.Export(ctx)
+	messages, _ := restored.Message.Export(ctx)
+	if !reflect.DeepEqual(messages, expectedMessages) {
+		t.Fatalf("got messages %v, expected %v", messages, expectedMessages)
+	}
+
+	expectedReactions, _ := original.Reaction.Export(ctx)
+	reactions, _ := restored.Reaction.Export(ctx)
+	if len(reactions) != 2 || !reflect.DeepEqual(reactions, expectedReactions) {
+		t.Fatalf("got reactions %v, expected %v", reactions, expectedReactions)
+	}
+
+	expectedPins, _ := original.Pin.Export(ctx)
+	pins, _ := restored.Pin.Export(ctx)
+	if len(pins) != 1 || !reflect.DeepEqual(pins, expectedPins) {
+		t.Fatalf("got pins %v, expected %v", pins, expectedPins)
+	}
+
+	expectedInvites, _ := original.Invite.Export(ctx)
+	invites, _ := restored.Invite.Export(ctx)
+	if len(invites) != 1 || invites[0].Uses != 1 || !reflect.DeepEqual(invites, expectedInvites) {
+		t.Fatalf("got invites %v, expected %v", invites, expectedInvites)
+	}
+
+	if _, err := restored.Auth.RetrieveKey(spidermanKey.Token()); err != nil {
+		t.Fatalf("got error %v retrieving key, expected nil", err)
+	}
+
+	if _, err := restored.Auth.RetrieveKey(venomKey.Token()); !errors.Is(err, auth.ErrNotFound) {
+		t.Fatalf("got error %v retrieving revoked key, expected %v", err, auth.ErrNotFound)
+	}
+}
+
+func TestLog_Replay(t *testing.T) {
+	dir := t.TempDir()
+	original := newMaps()
+
+	l, err := Open(dir, original, Opts{Sync: SyncAlways}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	populate(t, l.Services())
+	crash(t, l)
+
+	restored := newMaps()
+
+	l, err = Open(dir, restored, Opts{Sync: SyncAlways}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	assertRestored(t, original, restored)
+}
+
+func TestLog_NoSessions(t *testing.T) {
+	dir := t.TempDir()
+	original := newMaps()
+	original.Auth = nil
+
+	l, err := Open(dir, original, Opts{Sync: SyncAlways}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	services := l.Services()
+	if services.Auth != nil {
+		t.Fatal("got a logged auth service, expected nil")
+	}
+
+	// Sessions still work, they just aren't written anywhere.
+	services.Auth = auth.NewMap()
+	populate(t, services)
+
+	data, err := os.ReadFile(filepath.Join(dir, logFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) == 0 {
+		t.Fatal("got an empty log, expected records")
+	}
+
+	if bytes.Contains(data, []byte(spidermanKey.Token())) {
+		t.Fatal("got a session token in the log")
+	}
+
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := snapshot.ReadFile(filepath.Join(dir, snapshotFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snap.Sessions) != 0 {
+		t.Fatalf("got sessions %v in the snapshot, expected none", snap.Sessions)
+	}
+
+	// Sessions recorded while they were kept are dropped once they no longer are.
+	dir = t.TempDir()
+
+	if l, err = Open(dir, newMaps(), Opts{Sync: SyncAlways}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	populate(t, l.Services())
+	crash(t, l)
+
+	restored := newMaps()
+	restored.Auth = nil
+
+	if l, err = Open(dir, restored, Opts{Sync: SyncAlways}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if snap, err = snapshot.ReadFile(filepath.Join(dir, snapshotFileName)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snap.Sessions) != 0 {
+		t.Fatalf("got sessions %v in the snapshot after restoring, expected none", snap.Sessions)
+	}
+}
+
+func TestLog_WriteFailure(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	original := newMaps()
+
+	l, err := Open(dir, original, Opts{Sync: SyncAlways}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	services := l.Services()
+
+	// A read only file fails every write, the same as a full disk.
+	file := l.file
+	if l.file, err = os.Open(filepath.Join(dir, logFileName)); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := user.CreateUserOpts{Username: "spiderman", Password: "uncleben123"}
+	if err = services.User.Create(ctx, opts); err == nil {
+		t.Fatal("got no error creating a user that couldn't be written")
+	}
+
+	if _, err = original.User.GetUserById(ctx, user.GetUserByIdOpts{Id: "spiderman"}); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("got error %v getting a user that couldn't be written, expected %v", err, user.ErrNotFound)
+	}
+
+	if err = services.User.Create(ctx, user.CreateUserOpts{Username: "venom", Password: "uncleben123"}); err == nil {
+		t.Fatal("got no error creating a user after a failed write")
+	}
+
+	if err = l.file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l.file = file
+
+	// A compaction catches up with the services, which never saw the failed change.
+	if err = l.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = services.User.Create(ctx, opts); err != nil {
+		t.Fatalf("got error %v creating a user after compacting, expected nil", err)
+	}
+
+	if _, err = original.User.GetUserById(ctx, user.GetUserByIdOpts{Id: "spiderman"}); err != nil {
+		t.Fatalf("got error %v getting user, expected nil", err)
+	}
+}
+
+func TestLog_Compact(t *testing.T) {
+	dir := t.TempDir()
+	original := newMaps()
+
+	l, err := Open(dir, original, Opts{Sync: SyncNever}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roomId := populate(t, l.Services())
+
+	if err = l.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, logFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Size() != 0 {
+		t.Fatalf("got log size %d after compaction, expected 0", info.Size())
+	}
+
+	// Changes after a compaction are replayed on top of the snapshot.
+	if err = l.Services().Room.Join(context.Background(), room.JoinRoomOpts{Id: roomId, UserId: "robin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	crash(t, l)
+
+	restored := newMaps()
+
+	l, err = Open(dir, restored, Opts{Sync: SyncNever}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertRestored(t, original, restored)
+
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = l.Close(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got error %v closing twice, expected %v", err, ErrClosed)
+	}
+}
+
+func TestLog_Restore(t *testing.T) {
+	valid := `{"service":"user","op":"put","key":"spiderman","value":{"username":"spiderman","password":"uncleben123"}}` + "\n"
+
+	tests := map[string]struct {
+		data         string
+		expectedData string
+		expectedErr  error
+	}{
+		"valid": {
+			data:         valid,
+			expectedData: valid,
+		},
+		"partial record": {
+			data:         valid + `{"service":"user","op":"put","ke`,
+			expectedData: valid,
+		},
+		"torn final record": {
+			data:         valid + `{"service":"user",` + "\n",
+			expectedData: valid,
+		},
+		"corrupt record": {
+			data:        `{"service":"user",` + "\n" + valid,
+			expectedErr: ErrCorruptLog,
+		},
+		"unknown service": {
+			data:        `{"service":"villain","op":"put","key":"joker"}` + "\n",
+			expectedErr: ErrCorruptLog,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			name := filepath.Join(dir, logFileName)
+
+			if err := os.WriteFile(name, []byte(input.data), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			l, err := Open(dir, newMaps(), Opts{Sync: SyncAlways}, nil)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			crash(t, l)
+
+			data, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != input.expectedData {
+				t.Fatalf("got log %q, expected %q", data, input.expectedData)
+			}
+		})
+	}
+}
+
+func TestLog_Lock(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, newMaps(), Opts{Sync: SyncAlways}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Open(dir, newMaps(), Opts{Sync: SyncAlways}, nil); !errors.Is(err, ErrLocked) {
+		t.Fatalf("got error %v opening a locked log, expected %v", err, ErrLocked)
+	}
+
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = Open(dir, newMaps(), Opts{Sync: SyncAlways}, nil)
+	if err != nil {
+		t.Fatalf("got error %v opening a closed log, expected none", err)
+	}
+	defer l.Close()
+}
+
+func TestLog_SyncInterval(t *testing.T) {
+	l, err := Open(t.TempDir(), newMaps(), Opts{Sync: SyncInterval, SyncInterval: time.Millisecond}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if err = l.Services().User.Create(context.Background(), user.CreateUserOpts{Username: "spiderman", Password: "uncleben123"}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		l.lock.Lock()
+		dirty := l.dirty
+		l.lock.Unlock()
+
+		if !dirty {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("log was never synced")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	tests := map[string]struct {
+		s              string
+		expectedPolicy SyncPolicy
+		expectedErr    error
+	}{
+		"always": {
+			s:              "always",
+			expectedPolicy: SyncAlways,
+		},
+		"interval": {
+			s:              "Interval",
+			expectedPolicy: SyncInterval,
+		},
+		"never": {
+			s:              "never",
+			expectedPolicy: SyncNever,
+		},
+		"invalid": {
+			s:           "sometimes",
+			expectedErr: ErrInvalidSyncPolicy,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy, err := ParseSyncPolicy(input.s)
+			if !errors.Is(err, input.expectedErr) {
+				t.Fatalf("got error %v, expected %v", err, input.expectedErr)
+			}
+
+			if policy != input.expectedPolicy {
+				t.Fatalf("got policy %s, expected %s", policy, input.expectedPolicy)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package wal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SyncPolicy controls when appended records are synced to disk.
+type SyncPolicy string
+
+const (
+	// SyncAlways syncs every record before the call that wrote it returns. Nothing acknowledged is ever lost.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval syncs at most once per Opts.SyncInterval. A crash loses at most that much.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves syncing to the operating system.
+	SyncNever SyncPolicy = "never"
+)
+
+// ParseSyncPolicy parses one of always, interval or never.
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch p := SyncPolicy(strings.ToLower(s)); p {
+	case SyncAlways, SyncInterval, SyncNever:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidSyncPolicy, s)
+	}
+}
+
+type Opts struct {
+	Sync         SyncPolicy
+	SyncInterval time.Duration
+
+	// CompactInterval is how often the log is compacted into a snapshot. The log is only compacted on Close if zero.
+	CompactInterval time.Duration
+}
+
+func (o Opts) Validate() error {
+	if _, err := ParseSyncPolicy(string(o.Sync)); err != nil {
+		return err
+	}
+
+	if o.Sync == SyncInterval && o.SyncInterval <= 0 {
+		return fmt.Errorf("%w: sync interval must be positive", ErrInvalidSyncPolicy)
+	}
+
+	if o.CompactInterval < 0 {
+		return fmt.Errorf("compact interval must not be negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package wal
+
+import "encoding/json"
+
+const (
+	serviceUser     = "user"
+	serviceRoom     = "room"
+	serviceMessage  = "message"
+	serviceReaction = "reaction"
+	servicePin      = "pin"
+	serviceInvite   = "invite"
+	serviceSession  = "session"
+)
+
+const (
+	OpPut    = "put"
+	OpDelete = "delete"
+)
+
+// Record is a single change in the log. A put stores Value under Key, replacing whatever was there, and a delete
+// removes Key. Records hold the result of a call rather than its arguments, so replaying them is idempotent.
+type Record struct {
+	Service string          `json:"service"`
+	Op      string          `json:"op"`
+	Key     string          `json:"key"`
+	Value   json.RawMessage `json:"value,omitempty"`
+}
+
+func putRecord(service string, key string, v any) (Record, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return Record{}, err
+	}
+
+	return Record{Service: service, Op: OpPut, Key: key, Value: b}, nil
+}
+
+func deleteRecord(service string, key string) Record {
+	return Record{Service: service, Op: OpDelete, Key: key}
+}
+
+// reactionKey identifies a reaction by the message, user and emoji, encoded as a json array since any of them may
+// contain a separator.
+func reactionKey(messageId string, userId string, emoji string) (string, error) {
+	b, err := json.Marshal([]string{messageId, userId, emoji})
+	return string(b), err
+}
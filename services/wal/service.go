@@ -0,0 +1,731 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/worsediscord/server/services/auth"
+	"github.com/worsediscord/server/services/invite"
+	"github.com/worsediscord/server/services/message"
+	"github.com/worsediscord/server/services/pin"
+	"github.com/worsediscord/server/services/reaction"
+	"github.com/worsediscord/server/services/room"
+	"github.com/worsediscord/server/services/snapshot"
+	"github.com/worsediscord/server/services/user"
+)
+
+// UserService records every change made through it to a Log. Reads go straight to the underlying service.
+type UserService struct {
+	user.Service
+	log *Log
+}
+
+func (u *UserService) staging() user.Service {
+	return u.log.staging.User
+}
+
+func (u *UserService) Create(ctx context.Context, opts user.CreateUserOpts) error {
+	return u.log.commit(func() error {
+		return u.staging().Create(ctx, opts)
+	}, func() ([]Record, error) {
+		return u.put(ctx, opts.Username)
+	})
+}
+
+func (u *UserService) Update(ctx context.Context, opts user.UpdateUserOpts) error {
+	return u.log.commit(func() error {
+		return u.staging().Update(ctx, opts)
+	}, func() ([]Record, error) {
+		return u.put(ctx, opts.Id)
+	})
+}
+
+func (u *UserService) Delete(ctx context.Context, opts user.DeleteUserOpts) error {
+	return u.log.commit(func() error {
+		return u.staging().Delete(ctx, opts)
+	}, func() ([]Record, error) {
+		return []Record{deleteRecord(serviceUser, opts.Id)}, nil
+	})
+}
+
+func (u *UserService) Import(ctx context.Context, users []*user.User) error {
+	return u.log.commit(func() error {
+		return u.staging().Import(ctx, users)
+	}, func() ([]Record, error) {
+		records := make([]Record, 0, len(users))
+		for _, usr := range users {
+			r, err := putRecord(serviceUser, usr.Username, usr)
+			if err != nil {
+				return nil, err
+			}
+
+			records = append(records, r)
+		}
+
+		return records, nil
+	})
+}
+
+func (u *UserService) put(ctx context.Context, id string) ([]Record, error) {
+	usr, err := u.staging().GetUserById(ctx, user.GetUserByIdOpts{Id: id})
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := putRecord(serviceUser, usr.Username, usr)
+	if err != nil {
+		return nil, err
+	}
+
+	return []Record{r}, nil
+}
+
+// RoomService records every change made through it to a Log. Reads go straight to the underlying service.
+type RoomService struct {
+	room.Service
+	log *Log
+}
+
+func (r *RoomService) staging() room.Service {
+	return r.log.staging.Room
+}
+
+func (r *RoomService) Create(ctx context.Context, opts room.CreateRoomOpts) (*room.Room, error) {
+	var created *room.Room
+
+	err := r.log.commit(func() (err error) {
+		created, err = r.staging().Create(ctx, opts)
+		return err
+	}, func() ([]Record, error) {
+		rec, err := putRecord(serviceRoom, strconv.FormatInt(created.Id, 10), created)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Record{rec}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *RoomService) CreateDirect(ctx context.Context, opts room.CreateDirectRoomOpts) (*room.Room, error) {
+	var created *room.Room
+
+	err := r.log.commit(func() (err error) {
+		created, err = r.staging().CreateDirect(ctx, opts)
+		return err
+	}, func() ([]Record, error) {
+		rec, err := putRecord(serviceRoom, strconv.FormatInt(created.Id, 10), created)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Record{rec}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *RoomService) Delete(ctx context.Context, opts room.DeleteRoomOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().Delete(ctx, opts)
+	}, func() ([]Record, error) {
+		return []Record{deleteRecord(serviceRoom, strconv.FormatInt(opts.Id, 10))}, nil
+	})
+}
+
+func (r *RoomService) Update(ctx context.Context, opts room.UpdateRoomOpts) (*room.Room, error) {
+	var updated *room.Room
+
+	err := r.log.commit(func() (err error) {
+		updated, err = r.staging().Update(ctx, opts)
+		return err
+	}, func() ([]Record, error) {
+		rec, err := putRecord(serviceRoom, strconv.FormatInt(updated.Id, 10), updated)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Record{rec}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (r *RoomService) Join(ctx context.Context, opts room.JoinRoomOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().Join(ctx, opts)
+	}, func() ([]Record, error) {
+		return r.put(ctx, opts.Id)
+	})
+}
+
+func (r *RoomService) Leave(ctx context.Context, opts room.LeaveRoomOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().Leave(ctx, opts)
+	}, func() ([]Record, error) {
+		return r.put(ctx, opts.Id)
+	})
+}
+
+func (r *RoomService) Kick(ctx context.Context, opts room.KickRoomOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().Kick(ctx, opts)
+	}, func() ([]Record, error) {
+		return r.put(ctx, opts.Id)
+	})
+}
+
+func (r *RoomService) Ban(ctx context.Context, opts room.BanRoomOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().Ban(ctx, opts)
+	}, func() ([]Record, error) {
+		return r.put(ctx, opts.Id)
+	})
+}
+
+func (r *RoomService) Unban(ctx context.Context, opts room.UnbanRoomOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().Unban(ctx, opts)
+	}, func() ([]Record, error) {
+		return r.put(ctx, opts.Id)
+	})
+}
+
+func (r *RoomService) Mute(ctx context.Context, opts room.MuteRoomOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().Mute(ctx, opts)
+	}, func() ([]Record, error) {
+		return r.put(ctx, opts.Id)
+	})
+}
+
+func (r *RoomService) Unmute(ctx context.Context, opts room.UnmuteRoomOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().Unmute(ctx, opts)
+	}, func() ([]Record, error) {
+		return r.put(ctx, opts.Id)
+	})
+}
+
+func (r *RoomService) Promote(ctx context.Context, opts room.PromoteRoomOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().Promote(ctx, opts)
+	}, func() ([]Record, error) {
+		return r.put(ctx, opts.Id)
+	})
+}
+
+func (r *RoomService) Demote(ctx context.Context, opts room.DemoteRoomOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().Demote(ctx, opts)
+	}, func() ([]Record, error) {
+		return r.put(ctx, opts.Id)
+	})
+}
+
+func (r *RoomService) Transfer(ctx context.Context, opts room.TransferRoomOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().Transfer(ctx, opts)
+	}, func() ([]Record, error) {
+		return r.put(ctx, opts.Id)
+	})
+}
+
+func (r *RoomService) ConfigurePins(ctx context.Context, opts room.ConfigurePinsRoomOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().ConfigurePins(ctx, opts)
+	}, func() ([]Record, error) {
+		return r.put(ctx, opts.Id)
+	})
+}
+
+func (r *RoomService) Import(ctx context.Context, rooms []*room.Room) error {
+	return r.log.commit(func() error {
+		return r.staging().Import(ctx, rooms)
+	}, func() ([]Record, error) {
+		records := make([]Record, 0, len(rooms))
+		for _, rm := range rooms {
+			rec, err := putRecord(serviceRoom, strconv.FormatInt(rm.Id, 10), rm)
+			if err != nil {
+				return nil, err
+			}
+
+			records = append(records, rec)
+		}
+
+		return records, nil
+	})
+}
+
+func (r *RoomService) put(ctx context.Context, id int64) ([]Record, error) {
+	rm, err := r.staging().GetRoomById(ctx, room.GetRoomByIdOpts{Id: id})
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := putRecord(serviceRoom, strconv.FormatInt(rm.Id, 10), rm)
+	if err != nil {
+		return nil, err
+	}
+
+	return []Record{rec}, nil
+}
+
+// MessageService records every change made through it to a Log. Reads go straight to the underlying service.
+type MessageService struct {
+	message.Service
+	log *Log
+}
+
+func (m *MessageService) staging() message.Service {
+	return m.log.staging.Message
+}
+
+func (m *MessageService) Create(ctx context.Context, opts message.CreateMessageOpts) (*message.Message, error) {
+	var created *message.Message
+
+	err := m.log.commit(func() (err error) {
+		created, err = m.staging().Create(ctx, opts)
+		return err
+	}, func() ([]Record, error) {
+		rec, err := putRecord(serviceMessage, created.Id, created)
+		if err != nil {
+			return nil, err
+		}
+
+		return m.withRoot(ctx, []Record{rec}, created.ThreadId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (m *MessageService) Delete(ctx context.Context, opts message.DeleteMessageOpts) error {
+	var deleted *message.Message
+
+	return m.log.commit(func() (err error) {
+		if deleted, err = m.staging().GetMessageById(ctx, message.GetMessageByIdOpts{Id: opts.Id}); err != nil {
+			return err
+		}
+
+		return m.staging().Delete(ctx, opts)
+	}, func() ([]Record, error) {
+		return m.withRoot(ctx, []Record{deleteRecord(serviceMessage, opts.Id)}, deleted.ThreadId)
+	})
+}
+
+// Purge records the deletion of every purged message, along with the roots of threads that lost replies to it.
+func (m *MessageService) Purge(ctx context.Context, opts message.PurgeMessageOpts) ([]string, error) {
+	var ids []string
+
+	var purged []*message.Message
+
+	err := m.log.commit(func() (err error) {
+		if err = opts.Validate(); err != nil {
+			return err
+		}
+
+		// The purged messages are listed first, since the threads they replied to are lost along with them.
+		if purged, err = m.staging().List(ctx, message.ListMessageOpts{UserId: opts.UserId, RoomId: opts.RoomId}); err != nil {
+			return err
+		}
+
+		ids, err = m.staging().Purge(ctx, opts)
+		return err
+	}, func() (records []Record, err error) {
+		records = make([]Record, 0, len(ids))
+		for _, id := range ids {
+			records = append(records, deleteRecord(serviceMessage, id))
+		}
+
+		threads := make(map[string]bool)
+		for _, msg := range purged {
+			if msg.ThreadId == "" || threads[msg.ThreadId] {
+				continue
+			}
+
+			threads[msg.ThreadId] = true
+			if records, err = m.withRoot(ctx, records, msg.ThreadId); err != nil {
+				return nil, err
+			}
+		}
+
+		return records, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Anonymize records every message by opts.UserId again, now without an author.
+func (m *MessageService) Anonymize(ctx context.Context, opts message.AnonymizeMessageOpts) error {
+	var authored []*message.Message
+
+	return m.log.commit(func() (err error) {
+		if authored, err = m.staging().List(ctx, message.ListMessageOpts{UserId: opts.UserId}); err != nil {
+			return err
+		}
+
+		return m.staging().Anonymize(ctx, opts)
+	}, func() ([]Record, error) {
+		records := make([]Record, 0, len(authored))
+		for _, msg := range authored {
+			anonymized, err := m.staging().GetMessageById(ctx, message.GetMessageByIdOpts{Id: msg.Id})
+			if err != nil {
+				return nil, err
+			}
+
+			rec, err := putRecord(serviceMessage, anonymized.Id, anonymized)
+			if err != nil {
+				return nil, err
+			}
+
+			records = append(records, rec)
+		}
+
+		return records, nil
+	})
+}
+
+// withRoot appends a record of the root of a thread to records, since adding or removing a reply changes its counts.
+// Nothing is appended if threadId is empty or the root has been deleted.
+func (m *MessageService) withRoot(ctx context.Context, records []Record, threadId string) ([]Record, error) {
+	if threadId == "" {
+		return records, nil
+	}
+
+	root, err := m.staging().GetMessageById(ctx, message.GetMessageByIdOpts{Id: threadId})
+	if errors.Is(err, message.ErrNotFound) {
+		return records, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	rec, err := putRecord(serviceMessage, root.Id, root)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(records, rec), nil
+}
+
+func (m *MessageService) Import(ctx context.Context, messages []*message.Message) error {
+	return m.log.commit(func() error {
+		return m.staging().Import(ctx, messages)
+	}, func() ([]Record, error) {
+		records := make([]Record, 0, len(messages))
+		for _, msg := range messages {
+			rec, err := putRecord(serviceMessage, msg.Id, msg)
+			if err != nil {
+				return nil, err
+			}
+
+			records = append(records, rec)
+		}
+
+		return records, nil
+	})
+}
+
+// ReactionService records every change made through it to a Log. Reads go straight to the underlying service.
+type ReactionService struct {
+	reaction.Service
+	log *Log
+}
+
+func (r *ReactionService) staging() reaction.Service {
+	return r.log.staging.Reaction
+}
+
+func (r *ReactionService) Add(ctx context.Context, opts reaction.AddReactionOpts) (*reaction.Reaction, error) {
+	var added *reaction.Reaction
+
+	err := r.log.commit(func() (err error) {
+		added, err = r.staging().Add(ctx, opts)
+		return err
+	}, func() ([]Record, error) {
+		rec, err := reactionRecord(added)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Record{rec}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+func (r *ReactionService) Remove(ctx context.Context, opts reaction.RemoveReactionOpts) error {
+	return r.log.commit(func() error {
+		return r.staging().Remove(ctx, opts)
+	}, func() ([]Record, error) {
+		key, err := reactionKey(opts.MessageId, opts.UserId, opts.Emoji)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Record{deleteRecord(serviceReaction, key)}, nil
+	})
+}
+
+// Clear records a delete for every cleared reaction, so that replaying it doesn't depend on what was there.
+func (r *ReactionService) Clear(ctx context.Context, opts reaction.ClearReactionOpts) error {
+	var cleared []*reaction.Reaction
+
+	return r.log.commit(func() (err error) {
+		if err = opts.Validate(); err != nil {
+			return err
+		}
+
+		if cleared, err = r.staging().List(ctx, reaction.ListReactionOpts{MessageIds: opts.MessageIds, UserId: opts.UserId}); err != nil {
+			return err
+		}
+
+		return r.staging().Clear(ctx, opts)
+	}, func() ([]Record, error) {
+		records := make([]Record, 0, len(cleared))
+		for _, rct := range cleared {
+			key, err := reactionKey(rct.MessageId, rct.UserId, rct.Emoji)
+			if err != nil {
+				return nil, err
+			}
+
+			records = append(records, deleteRecord(serviceReaction, key))
+		}
+
+		return records, nil
+	})
+}
+
+func (r *ReactionService) Import(ctx context.Context, reactions []*reaction.Reaction) error {
+	return r.log.commit(func() error {
+		return r.staging().Import(ctx, reactions)
+	}, func() ([]Record, error) {
+		records := make([]Record, 0, len(reactions))
+		for _, rct := range reactions {
+			rec, err := reactionRecord(rct)
+			if err != nil {
+				return nil, err
+			}
+
+			records = append(records, rec)
+		}
+
+		return records, nil
+	})
+}
+
+func reactionRecord(r *reaction.Reaction) (Record, error) {
+	key, err := reactionKey(r.MessageId, r.UserId, r.Emoji)
+	if err != nil {
+		return Record{}, err
+	}
+
+	return putRecord(serviceReaction, key, r)
+}
+
+// PinService records every change made through it to a Log. Reads go straight to the underlying service.
+type PinService struct {
+	pin.Service
+	log *Log
+}
+
+func (p *PinService) staging() pin.Service {
+	return p.log.staging.Pin
+}
+
+func (p *PinService) Add(ctx context.Context, opts pin.AddPinOpts) (*pin.Pin, error) {
+	var added *pin.Pin
+
+	err := p.log.commit(func() (err error) {
+		added, err = p.staging().Add(ctx, opts)
+		return err
+	}, func() ([]Record, error) {
+		rec, err := putRecord(servicePin, added.MessageId, added)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Record{rec}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+func (p *PinService) Remove(ctx context.Context, opts pin.RemovePinOpts) error {
+	return p.log.commit(func() error {
+		return p.staging().Remove(ctx, opts)
+	}, func() ([]Record, error) {
+		return []Record{deleteRecord(servicePin, opts.MessageId)}, nil
+	})
+}
+
+func (p *PinService) Import(ctx context.Context, pins []*pin.Pin) error {
+	return p.log.commit(func() error {
+		return p.staging().Import(ctx, pins)
+	}, func() ([]Record, error) {
+		records := make([]Record, 0, len(pins))
+		for _, pn := range pins {
+			rec, err := putRecord(servicePin, pn.MessageId, pn)
+			if err != nil {
+				return nil, err
+			}
+
+			records = append(records, rec)
+		}
+
+		return records, nil
+	})
+}
+
+// InviteService records every change made through it to a Log. Reads go straight to the underlying service.
+type InviteService struct {
+	invite.Service
+	log *Log
+}
+
+func (i *InviteService) staging() invite.Service {
+	return i.log.staging.Invite
+}
+
+func (i *InviteService) Create(ctx context.Context, opts invite.CreateInviteOpts) (*invite.Invite, error) {
+	var created *invite.Invite
+
+	err := i.log.commit(func() (err error) {
+		created, err = i.staging().Create(ctx, opts)
+		return err
+	}, func() ([]Record, error) {
+		rec, err := putRecord(serviceInvite, created.Code, created)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Record{rec}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (i *InviteService) Use(ctx context.Context, opts invite.UseInviteOpts) (*invite.Invite, error) {
+	var used *invite.Invite
+
+	err := i.log.commit(func() (err error) {
+		used, err = i.staging().Use(ctx, opts)
+		return err
+	}, func() ([]Record, error) {
+		rec, err := putRecord(serviceInvite, used.Code, used)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Record{rec}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return used, nil
+}
+
+func (i *InviteService) Revoke(ctx context.Context, opts invite.RevokeInviteOpts) error {
+	return i.log.commit(func() error {
+		return i.staging().Revoke(ctx, opts)
+	}, func() ([]Record, error) {
+		return []Record{deleteRecord(serviceInvite, opts.Code)}, nil
+	})
+}
+
+func (i *InviteService) Import(ctx context.Context, invites []*invite.Invite) error {
+	return i.log.commit(func() error {
+		return i.staging().Import(ctx, invites)
+	}, func() ([]Record, error) {
+		records := make([]Record, 0, len(invites))
+		for _, inv := range invites {
+			rec, err := putRecord(serviceInvite, inv.Code, inv)
+			if err != nil {
+				return nil, err
+			}
+
+			records = append(records, rec)
+		}
+
+		return records, nil
+	})
+}
+
+// AuthService records every change made through it to a Log. Only keys with a username as their payload are recorded,
+// the same as snapshot.Export. Sessions aren't staged, since the records of a change follow from its arguments.
+type AuthService struct {
+	auth.Service
+	log *Log
+}
+
+func (a *AuthService) RegisterKey(token string, key auth.ApiKey) error {
+	return a.log.record(func() ([]Record, error) {
+		return sessionRecords(key)
+	}, func() error {
+		return a.Service.RegisterKey(token, key)
+	})
+}
+
+func (a *AuthService) RevokeKey(token string) error {
+	return a.log.record(func() ([]Record, error) {
+		return []Record{deleteRecord(serviceSession, token)}, nil
+	}, func() error {
+		return a.Service.RevokeKey(token)
+	})
+}
+
+func (a *AuthService) Import(ctx context.Context, keys []auth.ApiKey) error {
+	return a.log.record(func() ([]Record, error) {
+		return sessionRecords(keys...)
+	}, func() error {
+		return a.Service.Import(ctx, keys)
+	})
+}
+
+func sessionRecords(keys ...auth.ApiKey) ([]Record, error) {
+	var records []Record
+
+	for _, key := range keys {
+		username, ok := key.Payload().(string)
+		if !ok {
+			continue
+		}
+
+		session := snapshot.Session{Token: key.Token(), Username: username, ExpiresAt: key.ExpiresAt().UnixMilli()}
+
+		rec, err := putRecord(serviceSession, key.Token(), session)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
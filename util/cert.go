@@ -0,0 +1,137 @@
+package util
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// CertReloader serves a TLS certificate loaded from disk and swaps it out when the files change. Only new handshakes
+// pick up a reloaded certificate, so existing connections are left untouched.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	lock     sync.RWMutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+}
+
+// NewCertReloader loads the certificate and key pair at certFile and keyFile.
+func NewCertReloader(certFile string, keyFile string) (*CertReloader, error) {
+	c := &CertReloader{certFile: certFile, keyFile: keyFile}
+
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Reload reads the certificate and key pair from disk. The previously loaded certificate is kept if loading fails.
+func (c *CertReloader) Reload() error {
+	modTimes, err := c.statFiles()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key pair: %w", err)
+	}
+
+	c.lock.Lock()
+	c.cert = &cert
+	c.modTimes = modTimes
+	c.lock.Unlock()
+
+	return nil
+}
+
+// GetCertificate implements the tls.Config GetCertificate callback.
+func (c *CertReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.cert, nil
+}
+
+// Watch reloads the certificate whenever the process receives SIGHUP or either file's modification time changes. Files
+// are polled every interval. Watch blocks until ctx is cancelled.
+func (c *CertReloader) Watch(ctx context.Context, interval time.Duration, logHandler slog.Handler) {
+	logger := slog.New(logHandler).With(slog.String("method", "CertReloader.Watch"))
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logger.Info("received SIGHUP, reloading certificate")
+		case <-ticker.C:
+			modTimes, err := c.statFiles()
+			if err != nil {
+				logger.Error("failed to stat certificate", slog.String("error", err.Error()))
+				continue
+			}
+
+			c.lock.RLock()
+			changed := modTimes != c.modTimes
+			c.lock.RUnlock()
+
+			if !changed {
+				continue
+			}
+
+			logger.Info("certificate changed on disk, reloading")
+		}
+
+		if err := c.Reload(); err != nil {
+			logger.Error("failed to reload certificate", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (c *CertReloader) statFiles() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+
+	for i, name := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return modTimes, err
+		}
+
+		modTimes[i] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
+
+// LoadCertPool reads a PEM encoded bundle of CA certificates from disk.
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.New("no certificates found in " + file)
+	}
+
+	return pool, nil
+}
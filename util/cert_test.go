@@ -0,0 +1,222 @@
+package util
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert generates a self-signed certificate for commonName and writes it and its key to certFile and keyFile. The
+// files' modification time is set to modTime, so that a rewrite within the file system's timestamp resolution is still
+// noticed.
+func writeCert(t *testing.T, certFile string, keyFile string, commonName string, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{certFile, keyFile} {
+		if err = os.Chtimes(name, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// servedName returns the common name of the certificate c serves.
+func servedName(t *testing.T, c *CertReloader) string {
+	t.Helper()
+
+	cert, err := c.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	now := time.Now()
+
+	writeCert(t, certFile, keyFile, "spiderman", now)
+
+	c, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := servedName(t, c); got != "spiderman" {
+		t.Fatalf("got %s, expected spiderman", got)
+	}
+
+	writeCert(t, certFile, keyFile, "venom", now.Add(time.Second))
+
+	if err = c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := servedName(t, c); got != "venom" {
+		t.Fatalf("got %s after reloading, expected venom", got)
+	}
+
+	// A broken certificate is refused and the last good one is kept.
+	if err = os.WriteFile(certFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Reload(); err == nil {
+		t.Fatal("got no error reloading a broken certificate")
+	}
+
+	if got := servedName(t, c); got != "venom" {
+		t.Fatalf("got %s after a failed reload, expected venom", got)
+	}
+
+	if _, err = NewCertReloader(certFile, keyFile); err == nil {
+		t.Fatal("got no error loading a broken certificate")
+	}
+}
+
+func TestCertReloader_Watch(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	now := time.Now()
+
+	writeCert(t, certFile, keyFile, "spiderman", now)
+
+	c, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		c.Watch(ctx, time.Millisecond, NopLogHandler)
+		close(done)
+	}()
+
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// waitFor polls until c serves the certificate for commonName.
+	waitFor := func(commonName string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for servedName(t, c) != commonName {
+			if time.Now().After(deadline) {
+				t.Fatalf("got %s, expected %s to be loaded", servedName(t, c), commonName)
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	writeCert(t, certFile, keyFile, "venom", now.Add(time.Second))
+	waitFor("venom")
+
+	// A broken certificate on disk keeps the last good one, and a fixed one is picked up afterward.
+	if err = os.WriteFile(certFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Chtimes(certFile, now.Add(2*time.Second), now.Add(2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	if got := servedName(t, c); got != "venom" {
+		t.Fatalf("got %s with a broken certificate on disk, expected venom", got)
+	}
+
+	writeCert(t, certFile, keyFile, "carnage", now.Add(3*time.Second))
+	waitFor("carnage")
+}
+
+func TestLoadCertPool(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	writeCert(t, certFile, keyFile, "spiderman", time.Now())
+
+	emptyFile := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(emptyFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		file        string
+		expectedErr bool
+	}{
+		"valid": {
+			file:        certFile,
+			expectedErr: false,
+		},
+		"no certificates": {
+			file:        emptyFile,
+			expectedErr: true,
+		},
+		"missing": {
+			file:        filepath.Join(dir, "missing.pem"),
+			expectedErr: true,
+		},
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			pool, err := LoadCertPool(input.file)
+			if (err != nil) != input.expectedErr {
+				t.Fatalf("got error %v, expected error %v", err, input.expectedErr)
+			}
+
+			if err == nil && pool == nil {
+				t.Fatal("got a nil pool")
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package util
+
+import "time"
+
+// Clock tells the current time. It exists so that tests can control time.
+type Clock interface {
+	Now() time.Time
+}
+
+var SystemClock systemClock
+
+type systemClock struct{}
+
+func (s systemClock) Now() time.Time { return time.Now() }
+
+// ClockFunc adapts a function to a Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time { return f() }